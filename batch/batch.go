@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
@@ -25,6 +26,10 @@ type Processor[T, R any] struct {
 	// Options
 	maxConcurrency int
 	ordered        bool
+	errorPolicy    ErrorPolicy
+	logger         pocket.Logger
+	progress       ProgressFunc
+	limiter        *pocket.AdaptiveLimiter
 }
 
 // Option configures a batch processor.
@@ -33,6 +38,10 @@ type Option func(*options)
 type options struct {
 	maxConcurrency int
 	ordered        bool
+	errorPolicy    ErrorPolicy
+	logger         pocket.Logger
+	progress       ProgressFunc
+	limiter        *pocket.AdaptiveLimiter
 }
 
 // WithConcurrency sets the maximum concurrent workers.
@@ -42,6 +51,15 @@ func WithConcurrency(n int) Option {
 	}
 }
 
+// WithAdaptiveConcurrency adjusts the number of concurrent workers from
+// observed latency and errors, between cfg.Min and cfg.Max. It overrides
+// WithConcurrency.
+func WithAdaptiveConcurrency(cfg pocket.AdaptiveConcurrency) Option {
+	return func(o *options) {
+		o.limiter = pocket.NewAdaptiveLimiter(cfg)
+	}
+}
+
 // WithOrdered ensures results maintain input order.
 func WithOrdered() Option {
 	return func(o *options) {
@@ -75,6 +93,10 @@ func NewProcessor[T, R any](
 
 	p.maxConcurrency = options.maxConcurrency
 	p.ordered = options.ordered
+	p.errorPolicy = options.errorPolicy
+	p.logger = options.logger
+	p.progress = options.progress
+	p.limiter = options.limiter
 
 	return p
 }
@@ -114,41 +136,108 @@ func (p *Processor[T, R]) ToNode(name string) pocket.Node {
 	)
 }
 
-// processItems handles concurrent or sequential processing.
+// processItems handles concurrent or sequential processing and applies the
+// error policy to the results.
 func (p *Processor[T, R]) processItems(ctx context.Context, items []T) ([]R, error) {
-	if p.maxConcurrency <= 1 {
-		return p.processSequential(ctx, items)
+	var (
+		results []R
+		ok      []bool
+		failed  failures
+		err     error
+	)
+
+	progress := newProgressTracker(p.progress, len(items))
+	if p.maxConcurrency <= 1 && p.limiter == nil {
+		results, ok, err = p.processSequential(ctx, items, &failed, progress)
+	} else {
+		results, ok, err = p.processConcurrent(ctx, items, &failed, progress)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := failed.resolve(ctx, p.errorPolicy, p.logger, len(items)); err != nil {
+		return nil, err
 	}
-	return p.processConcurrent(ctx, items)
+
+	if len(failed.items) == 0 {
+		return results, nil
+	}
+
+	// Drop the results of skipped items
+	kept := make([]R, 0, len(results)-len(failed.items))
+	for i, result := range results {
+		if ok[i] {
+			kept = append(kept, result)
+		}
+	}
+	return kept, nil
+}
+
+// handleItemError records an item failure, or returns it under FailFast.
+func (p *Processor[T, R]) handleItemError(idx int, err error, failed *failures) error {
+	if p.errorPolicy == FailFast {
+		return &ItemError{Index: idx, Err: err}
+	}
+	failed.add(idx, err)
+	return nil
+}
+
+// transform runs Transform for one item, holding an adaptive concurrency
+// slot when a limiter is configured.
+func (p *Processor[T, R]) transform(ctx context.Context, item T) (R, error) {
+	if p.limiter == nil {
+		return p.Transform(ctx, item)
+	}
+
+	if err := p.limiter.Acquire(ctx); err != nil {
+		var zero R
+		return zero, err
+	}
+	start := time.Now()
+	result, err := p.Transform(ctx, item)
+	p.limiter.Release(time.Since(start), err)
+	return result, err
 }
 
 // processSequential processes items one by one.
-func (p *Processor[T, R]) processSequential(ctx context.Context, items []T) ([]R, error) {
+func (p *Processor[T, R]) processSequential(
+	ctx context.Context, items []T, failed *failures, progress *progressTracker,
+) ([]R, []bool, error) {
 	results := make([]R, len(items))
+	ok := make([]bool, len(items))
 
 	for i, item := range items {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, nil, ctx.Err()
 		default:
 		}
 
 		result, err := p.Transform(ctx, item)
+		progress.step()
 		if err != nil {
-			return nil, fmt.Errorf("item %d: %w", i, err)
+			if err := p.handleItemError(i, err, failed); err != nil {
+				return nil, nil, err
+			}
+			continue
 		}
 		results[i] = result
+		ok[i] = true
 	}
 
-	return results, nil
+	return results, ok, nil
 }
 
 // processConcurrent processes items with worker pool.
-func (p *Processor[T, R]) processConcurrent(ctx context.Context, items []T) ([]R, error) {
+func (p *Processor[T, R]) processConcurrent(
+	ctx context.Context, items []T, failed *failures, progress *progressTracker,
+) ([]R, []bool, error) {
 	g, ctx := errgroup.WithContext(ctx)
 
 	// Results storage
 	results := make([]R, len(items))
+	ok := make([]bool, len(items))
 	var mu sync.Mutex
 
 	// Work queue
@@ -159,16 +248,25 @@ func (p *Processor[T, R]) processConcurrent(ctx context.Context, items []T) ([]R
 	close(work)
 
 	// Start workers
-	for w := 0; w < p.maxConcurrency && w < len(items); w++ {
+	workers := p.maxConcurrency
+	if p.limiter != nil {
+		workers = p.limiter.MaxConcurrency()
+	}
+	for w := 0; w < workers && w < len(items); w++ {
 		g.Go(func() error {
 			for idx := range work {
-				result, err := p.Transform(ctx, items[idx])
+				result, err := p.transform(ctx, items[idx])
+				progress.step()
 				if err != nil {
-					return fmt.Errorf("item %d: %w", idx, err)
+					if err := p.handleItemError(idx, err, failed); err != nil {
+						return err
+					}
+					continue
 				}
 
 				mu.Lock()
 				results[idx] = result
+				ok[idx] = true
 				mu.Unlock()
 			}
 			return nil
@@ -176,10 +274,10 @@ func (p *Processor[T, R]) processConcurrent(ctx context.Context, items []T) ([]R
 	}
 
 	if err := g.Wait(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return results, nil
+	return results, ok, nil
 }
 
 // MapReduce creates a map-reduce batch processor as a Node.
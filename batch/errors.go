@@ -0,0 +1,145 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/agentstation/pocket"
+)
+
+// ErrorPolicy determines how a batch reacts when individual items fail.
+type ErrorPolicy int
+
+const (
+	// FailFast stops processing at the first failed item and returns its error.
+	FailFast ErrorPolicy = iota
+
+	// CollectErrors processes every item and returns a *BatchError listing
+	// all failures if any item failed. No reduced result is returned.
+	CollectErrors
+
+	// SkipAndLog processes every item, logs failures, and reduces over the
+	// items that succeeded.
+	SkipAndLog
+)
+
+// String returns the policy name.
+func (p ErrorPolicy) String() string {
+	switch p {
+	case FailFast:
+		return "fail-fast"
+	case CollectErrors:
+		return "collect-errors"
+	case SkipAndLog:
+		return "skip-and-log"
+	default:
+		return fmt.Sprintf("ErrorPolicy(%d)", int(p))
+	}
+}
+
+// WithErrorPolicy sets how item failures are handled. The default is FailFast.
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(o *options) {
+		o.errorPolicy = policy
+	}
+}
+
+// WithLogger sets the logger used to report skipped items under SkipAndLog.
+func WithLogger(logger pocket.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// ItemError is the failure of a single item in a batch.
+type ItemError struct {
+	// Index is the position of the item in the batch.
+	Index int
+
+	// Err is the error returned while processing the item.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError reports every item that failed in a batch.
+type BatchError struct {
+	// Total is the number of items in the batch.
+	Total int
+
+	// Failures lists the failed items in index order.
+	Failures []*ItemError
+}
+
+// Error implements the error interface.
+func (e *BatchError) Error() string {
+	if len(e.Failures) == 0 {
+		return fmt.Sprintf("batch: 0 of %d items failed", e.Total)
+	}
+	return fmt.Sprintf("batch: %d of %d items failed; first: %v",
+		len(e.Failures), e.Total, e.Failures[0])
+}
+
+// Unwrap returns the item errors so errors.Is and errors.As can inspect them.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f
+	}
+	return errs
+}
+
+// AsBatchError extracts a *BatchError from err.
+func AsBatchError(err error) (*BatchError, bool) {
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		return batchErr, true
+	}
+	return nil, false
+}
+
+// failures collects item errors from concurrent workers.
+type failures struct {
+	mu    sync.Mutex
+	items []*ItemError
+}
+
+func (f *failures) add(index int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items = append(f.items, &ItemError{Index: index, Err: err})
+}
+
+// resolve applies the error policy once all items have been processed.
+// It returns a non-nil error only under CollectErrors.
+func (f *failures) resolve(ctx context.Context, policy ErrorPolicy, logger pocket.Logger, total int) error {
+	if len(f.items) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(f.items, func(a, b *ItemError) int {
+		return a.Index - b.Index
+	})
+
+	if policy == CollectErrors {
+		return &BatchError{Total: total, Failures: f.items}
+	}
+
+	if logger != nil {
+		for _, item := range f.items {
+			logger.Error(ctx, "batch item skipped", "index", item.Index, "error", item.Err)
+		}
+	}
+	return nil
+}
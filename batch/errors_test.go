@@ -0,0 +1,115 @@
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/batch"
+)
+
+// recordingLogger records the messages logged at error level.
+type recordingLogger struct {
+	errors []string
+}
+
+func (l *recordingLogger) Debug(ctx context.Context, msg string, keysAndValues ...any) {}
+func (l *recordingLogger) Info(ctx context.Context, msg string, keysAndValues ...any)  {}
+func (l *recordingLogger) Error(ctx context.Context, msg string, keysAndValues ...any) {
+	l.errors = append(l.errors, fmt.Sprint(append([]any{msg}, keysAndValues...)...))
+}
+
+func TestErrorPolicies(t *testing.T) {
+	errOdd := errors.New("odd item")
+	extract := func(ctx context.Context, store pocket.StoreReader) ([]int, error) {
+		return []int{1, 2, 3, 4, 5}, nil
+	}
+	evenOnly := func(ctx context.Context, n int) (int, error) {
+		if n%2 == 1 {
+			return 0, errOdd
+		}
+		return n, nil
+	}
+	collect := func(ctx context.Context, results []int) (any, error) {
+		return results, nil
+	}
+
+	for _, concurrency := range []int{1, 3} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			t.Run("fail fast", func(t *testing.T) {
+				node := batch.MapReduce("even", extract, evenOnly, collect,
+					batch.WithConcurrency(concurrency))
+
+				_, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+				var itemErr *batch.ItemError
+				if !errors.As(err, &itemErr) || !errors.Is(err, errOdd) {
+					t.Errorf("Expected item error, got %v", err)
+				}
+				if _, ok := batch.AsBatchError(err); ok {
+					t.Error("Expected no BatchError under FailFast")
+				}
+			})
+
+			t.Run("collect errors", func(t *testing.T) {
+				node := batch.MapReduce("even", extract, evenOnly, collect,
+					batch.WithConcurrency(concurrency),
+					batch.WithErrorPolicy(batch.CollectErrors))
+
+				_, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+				batchErr, ok := batch.AsBatchError(err)
+				if !ok {
+					t.Fatalf("Expected BatchError, got %v", err)
+				}
+				if batchErr.Total != 5 {
+					t.Errorf("Expected total 5, got %d", batchErr.Total)
+				}
+				var indexes []int
+				for _, failure := range batchErr.Failures {
+					indexes = append(indexes, failure.Index)
+				}
+				if want := []int{0, 2, 4}; !reflect.DeepEqual(indexes, want) {
+					t.Errorf("Expected failures %v, got %v", want, indexes)
+				}
+				if !errors.Is(err, errOdd) {
+					t.Error("Expected BatchError to unwrap to the item errors")
+				}
+			})
+
+			t.Run("skip and log", func(t *testing.T) {
+				logger := &recordingLogger{}
+				node := batch.MapReduce("even", extract, evenOnly, collect,
+					batch.WithConcurrency(concurrency),
+					batch.WithErrorPolicy(batch.SkipAndLog),
+					batch.WithLogger(logger))
+
+				result, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+				if err != nil {
+					t.Fatalf("Run failed: %v", err)
+				}
+				if want := []int{2, 4}; !reflect.DeepEqual(result, want) {
+					t.Errorf("Expected %v, got %v", want, result)
+				}
+				if len(logger.errors) != 3 {
+					t.Errorf("Expected 3 skipped items logged, got %d", len(logger.errors))
+				}
+			})
+		})
+	}
+}
+
+func TestErrorPolicyString(t *testing.T) {
+	tests := map[batch.ErrorPolicy]string{
+		batch.FailFast:       "fail-fast",
+		batch.CollectErrors:  "collect-errors",
+		batch.SkipAndLog:     "skip-and-log",
+		batch.ErrorPolicy(9): "ErrorPolicy(9)",
+	}
+	for policy, want := range tests {
+		if got := policy.String(); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
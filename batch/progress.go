@@ -0,0 +1,57 @@
+package batch
+
+import (
+	"sync"
+	"time"
+)
+
+// ProgressFunc receives progress updates as items complete. Total is -1
+// when the number of items is unknown, as with StreamMapReduce, and eta is
+// zero until it can be estimated.
+type ProgressFunc func(done, total int, eta time.Duration)
+
+// WithProgress registers a callback invoked after each item completes,
+// whether it succeeded or failed. Calls are serialized, so the callback does
+// not need to be safe for concurrent use, and done increases by one per call.
+func WithProgress(fn ProgressFunc) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// progressTracker counts completed items and reports them to a ProgressFunc.
+type progressTracker struct {
+	mu    sync.Mutex
+	fn    ProgressFunc
+	total int
+	done  int
+	start time.Time
+}
+
+func newProgressTracker(fn ProgressFunc, total int) *progressTracker {
+	return &progressTracker{
+		fn:    fn,
+		total: total,
+		start: time.Now(),
+	}
+}
+
+// step records one completed item. It is a no-op without a callback.
+func (p *progressTracker) step() {
+	if p == nil || p.fn == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+
+	var eta time.Duration
+	if p.total > 0 {
+		perItem := time.Since(p.start) / time.Duration(p.done)
+		eta = perItem * time.Duration(p.total-p.done)
+	}
+
+	p.fn(p.done, p.total, eta)
+}
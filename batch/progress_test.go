@@ -0,0 +1,72 @@
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/batch"
+)
+
+func TestWithProgress(t *testing.T) {
+	extract := func(ctx context.Context, store pocket.StoreReader) ([]int, error) {
+		return []int{1, 2, 3, 4}, nil
+	}
+
+	type update struct {
+		done, total int
+	}
+
+	for _, concurrency := range []int{1, 4} {
+		var updates []update
+		node := batch.ForEach("visit", extract,
+			func(ctx context.Context, n int) error {
+				if n == 3 {
+					return errors.New("failed item")
+				}
+				return nil
+			},
+			batch.WithConcurrency(concurrency),
+			batch.WithErrorPolicy(batch.SkipAndLog),
+			batch.WithProgress(func(done, total int, eta time.Duration) {
+				if eta < 0 {
+					t.Errorf("Expected non-negative eta, got %v", eta)
+				}
+				updates = append(updates, update{done, total})
+			}))
+
+		if _, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		// Failed items are reported too, and calls are serialized
+		want := []update{{1, 4}, {2, 4}, {3, 4}, {4, 4}}
+		if !reflect.DeepEqual(updates, want) {
+			t.Errorf("concurrency %d: expected %v, got %v", concurrency, want, updates)
+		}
+	}
+}
+
+func TestWithProgressStream(t *testing.T) {
+	var totals []int
+	node := batch.StreamMapReduce("count", sliceSource(1, 2, 3),
+		func(ctx context.Context, n int) (int, error) { return 1, nil },
+		0,
+		func(ctx context.Context, total, n int) (int, error) { return total + n, nil },
+		batch.WithProgress(func(done, total int, eta time.Duration) {
+			if eta != 0 {
+				t.Errorf("Expected no eta for unknown total, got %v", eta)
+			}
+			totals = append(totals, total)
+		}))
+
+	if _, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []int{-1, -1, -1}; !reflect.DeepEqual(totals, want) {
+		t.Errorf("Expected %v, got %v", want, totals)
+	}
+}
@@ -0,0 +1,164 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/agentstation/pocket"
+)
+
+// StreamMapReduce creates a map-reduce node that consumes items from a
+// channel instead of a slice, for datasets that don't fit in memory.
+//
+// At most WithConcurrency items are mapped at once and mapped results are
+// folded into the accumulator as they arrive, so memory stays bounded
+// regardless of the number of items. Because results are reduced in
+// completion order, the reducer should be order-independent unless the
+// concurrency is 1. The source must close the channel when it is exhausted.
+//
+// Item indexes reported under WithErrorPolicy are the order in which items
+// were received from the source.
+func StreamMapReduce[T, R, A any](
+	name string,
+	source func(context.Context, pocket.StoreReader) (<-chan T, error),
+	mapper func(context.Context, T) (R, error),
+	initial A,
+	reducer func(context.Context, A, R) (A, error),
+	opts ...Option,
+) pocket.Node {
+	o := newOptions(opts)
+
+	return pocket.NewNode[any, any](name,
+		pocket.Steps{
+			Prep: func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+				items, err := source(ctx, store)
+				if err != nil {
+					return nil, fmt.Errorf("source: %w", err)
+				}
+				return items, nil
+			},
+			Exec: func(ctx context.Context, prepResult any) (any, error) {
+				items := prepResult.(<-chan T)
+				return streamReduce(ctx, items, mapper, initial, reducer, o)
+			},
+		},
+	)
+}
+
+// streamReduce maps items with bounded concurrency and folds the results.
+func streamReduce[T, R, A any](
+	ctx context.Context,
+	items <-chan T,
+	mapper func(context.Context, T) (R, error),
+	acc A,
+	reducer func(context.Context, A, R) (A, error),
+	o *options,
+) (A, error) {
+	concurrency := o.maxConcurrency
+	if o.limiter != nil {
+		concurrency = o.limiter.MaxConcurrency()
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	// The buffer bounds how many mapped results wait for the reducer
+	results := make(chan R, concurrency)
+
+	var (
+		received atomic.Int64
+		failed   failures
+	)
+	progress := newProgressTracker(o.progress, -1)
+
+	workers, wctx := errgroup.WithContext(gctx)
+	for w := 0; w < concurrency; w++ {
+		workers.Go(func() error {
+			for {
+				var (
+					item T
+					ok   bool
+				)
+				select {
+				case <-wctx.Done():
+					return wctx.Err()
+				case item, ok = <-items:
+				}
+				if !ok {
+					return nil
+				}
+				idx := int(received.Add(1) - 1)
+
+				result, err := limitedMap(wctx, o.limiter, mapper, item)
+				progress.step()
+				if err != nil {
+					if o.errorPolicy == FailFast {
+						return fmt.Errorf("map: %w", &ItemError{Index: idx, Err: err})
+					}
+					failed.add(idx, err)
+					continue
+				}
+
+				select {
+				case <-wctx.Done():
+					return wctx.Err()
+				case results <- result:
+				}
+			}
+		})
+	}
+
+	g.Go(func() error {
+		defer close(results)
+		return workers.Wait()
+	})
+
+	g.Go(func() error {
+		for result := range results {
+			var err error
+			acc, err = reducer(gctx, acc, result)
+			if err != nil {
+				// Returning cancels gctx, which unblocks workers waiting to send
+				return fmt.Errorf("reduce: %w", err)
+			}
+		}
+		return nil
+	})
+
+	var zero A
+	if err := g.Wait(); err != nil {
+		return zero, err
+	}
+	if err := failed.resolve(ctx, o.errorPolicy, o.logger, int(received.Load())); err != nil {
+		return zero, err
+	}
+	return acc, nil
+}
+
+// limitedMap runs the mapper, holding an adaptive concurrency slot when a
+// limiter is configured.
+func limitedMap[T, R any](
+	ctx context.Context,
+	limiter *pocket.AdaptiveLimiter,
+	mapper func(context.Context, T) (R, error),
+	item T,
+) (R, error) {
+	if limiter == nil {
+		return mapper(ctx, item)
+	}
+
+	if err := limiter.Acquire(ctx); err != nil {
+		var zero R
+		return zero, err
+	}
+	start := time.Now()
+	result, err := mapper(ctx, item)
+	limiter.Release(time.Since(start), err)
+	return result, err
+}
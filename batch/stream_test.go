@@ -0,0 +1,88 @@
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/batch"
+)
+
+func TestStreamMapReduce(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i + 1
+	}
+
+	var (
+		active    atomic.Int64
+		maxActive atomic.Int64
+	)
+	square := func(ctx context.Context, n int) (int, error) {
+		current := active.Add(1)
+		defer active.Add(-1)
+		for {
+			peak := maxActive.Load()
+			if current <= peak || maxActive.CompareAndSwap(peak, current) {
+				break
+			}
+		}
+		return n * n, nil
+	}
+	sum := func(ctx context.Context, total, n int) (int, error) {
+		return total + n, nil
+	}
+
+	node := batch.StreamMapReduce("sum-squares", sliceSource(items...), square, 0, sum,
+		batch.WithConcurrency(4))
+
+	result, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result != 338350 {
+		t.Errorf("Expected 338350, got %v", result)
+	}
+	if peak := maxActive.Load(); peak > 4 {
+		t.Errorf("Expected at most 4 concurrent mappers, got %d", peak)
+	}
+}
+
+func TestStreamMapReduceErrors(t *testing.T) {
+	identity := func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	}
+
+	t.Run("source", func(t *testing.T) {
+		source := func(ctx context.Context, store pocket.StoreReader) (<-chan int, error) {
+			return nil, errors.New("unavailable")
+		}
+		node := batch.StreamMapReduce("stream", source, identity, 0,
+			func(ctx context.Context, total, n int) (int, error) { return total + n, nil })
+
+		_, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+		if err == nil || !strings.Contains(err.Error(), "source") {
+			t.Errorf("Expected source error, got %v", err)
+		}
+	})
+
+	t.Run("reduce", func(t *testing.T) {
+		reduceErr := errors.New("too large")
+		node := batch.StreamMapReduce("stream", sliceSource(1, 2, 3, 4), identity, 0,
+			func(ctx context.Context, total, n int) (int, error) {
+				if total+n > 5 {
+					return 0, reduceErr
+				}
+				return total + n, nil
+			},
+			batch.WithConcurrency(1))
+
+		_, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+		if !errors.Is(err, reduceErr) {
+			t.Errorf("Expected reduce error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,313 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/agentstation/pocket"
+)
+
+// ErrSourceConsumed is returned by a window node's Post step when it runs
+// again, such as when retried, after the source was read.
+var ErrSourceConsumed = errors.New("batch: window source already consumed")
+
+// Window creates a node that groups items from a streaming source into
+// count-based windows and runs the process node on each window. A window
+// holds size items and the next window starts slide items later; slide ==
+// size gives non-overlapping windows. When the source channel closes, a
+// trailing partial window is processed so no item is dropped.
+//
+// The process node receives each window as a []T and runs with the graph's
+// store. Up to WithConcurrency windows are processed at once while later
+// items are read, and the node outputs the process node's results as []any
+// in window order. WithErrorPolicy and WithProgress apply per window, with
+// window indexes counting from 0.
+//
+// Windows are processed in the node's Post step, as the process node writes
+// to the graph's store, and they consume the source, so the step can't be
+// retried: each window's writes are applied as it finishes, and a second
+// attempt would find the source drained. A retried Post fails with
+// ErrSourceConsumed.
+func Window[T any](
+	name string,
+	source func(context.Context, pocket.StoreReader) (<-chan T, error),
+	size, slide int,
+	process pocket.Node,
+	opts ...Option,
+) pocket.Node {
+	o := newOptions(opts)
+	return windowNode(name, source, process, o, func(ctx context.Context, items <-chan T, emit func([]T) error) error {
+		if size <= 0 || slide <= 0 {
+			return fmt.Errorf("invalid window: size %d, slide %d", size, slide)
+		}
+		return slideWindows(ctx, items, size, slide, emit)
+	})
+}
+
+// Tumbling creates a node that groups items from a streaming source into
+// fixed, non-overlapping time windows of duration d and runs the process
+// node on each window as soon as it closes. Windows with no items are
+// skipped, and when the source channel closes the pending window is
+// processed. Windows are timed with the run's clock from pocket.ClockFrom,
+// so tests can drive them with a pocket.SimulatedClock.
+//
+// The process node and options are handled as in Window, and as there, Post
+// can't be retried.
+func Tumbling[T any](
+	name string,
+	source func(context.Context, pocket.StoreReader) (<-chan T, error),
+	d time.Duration,
+	process pocket.Node,
+	opts ...Option,
+) pocket.Node {
+	o := newOptions(opts)
+	return windowNode(name, source, process, o, func(ctx context.Context, items <-chan T, emit func([]T) error) error {
+		if d <= 0 {
+			return fmt.Errorf("invalid window duration: %v", d)
+		}
+		return tumble(ctx, items, pocket.ClockFrom(ctx), d, emit)
+	})
+}
+
+// newOptions applies opts over the batch defaults.
+func newOptions(opts []Option) *options {
+	o := &options{
+		maxConcurrency: 10,
+		ordered:        true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// windowSource is a source's channel, read by a single Post attempt.
+type windowSource[T any] struct {
+	items    <-chan T
+	consumed atomic.Bool
+}
+
+// windowNode creates a node that reads the source in Prep and, in Post so
+// the process node shares the graph's store, splits it into windows with
+// split and processes them.
+func windowNode[T any](
+	name string,
+	source func(context.Context, pocket.StoreReader) (<-chan T, error),
+	process pocket.Node,
+	o *options,
+	split func(ctx context.Context, items <-chan T, emit func([]T) error) error,
+) pocket.Node {
+	return pocket.NewNode[any, any](name,
+		pocket.Steps{
+			Prep: func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+				items, err := source(ctx, store)
+				if err != nil {
+					return nil, fmt.Errorf("source: %w", err)
+				}
+				return &windowSource[T]{items: items}, nil
+			},
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+				source := prep.(*windowSource[T])
+				if source.consumed.Swap(true) {
+					return nil, "", ErrSourceConsumed
+				}
+				runner := newWindowRunner[T](ctx, store, process, o)
+				err := split(runner.ctx, source.items, runner.submit)
+				results, waitErr := runner.wait(ctx)
+				if waitErr != nil {
+					// A failed window cancels splitting, so its error comes first
+					return nil, "", waitErr
+				}
+				if err != nil {
+					return nil, "", err
+				}
+				return results, "default", nil
+			},
+		},
+	)
+}
+
+// slideWindows emits count-based windows as the items arrive.
+func slideWindows[T any](
+	ctx context.Context,
+	items <-chan T,
+	size, slide int,
+	emit func([]T) error,
+) error {
+	var (
+		current []T
+		fresh   int // items not yet in an emitted window
+		skip    int // items to drop before the next window when slide > size
+	)
+
+	for {
+		var (
+			item T
+			ok   bool
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case item, ok = <-items:
+		}
+		if !ok {
+			if fresh == 0 {
+				return nil
+			}
+			return emit(current)
+		}
+
+		if skip > 0 {
+			skip--
+			continue
+		}
+		current = append(current, item)
+		fresh++
+		if len(current) < size {
+			continue
+		}
+
+		if err := emit(current); err != nil {
+			return err
+		}
+		fresh = 0
+		if slide >= size {
+			current = nil
+			skip = slide - size
+			continue
+		}
+		// Copy the overlap so emitted windows are never modified
+		current = append([]T(nil), current[slide:]...)
+	}
+}
+
+// tumble emits the items received in each period d of the clock.
+func tumble[T any](
+	ctx context.Context,
+	items <-chan T,
+	clock pocket.Clock,
+	d time.Duration,
+	emit func([]T) error,
+) error {
+	var current []T
+	flush := func() error {
+		if len(current) == 0 {
+			return nil
+		}
+		window := current
+		current = nil
+		return emit(window)
+	}
+
+	tick := clock.After(d)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case item, ok := <-items:
+			if !ok {
+				return flush()
+			}
+			current = append(current, item)
+		case <-tick:
+			tick = clock.After(d)
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// windowRunner runs the process node on windows concurrently and collects
+// the results in window order.
+type windowRunner[T any] struct {
+	ctx      context.Context
+	group    *errgroup.Group
+	store    pocket.Store
+	process  pocket.Node
+	o        *options
+	progress *progressTracker
+
+	mu      sync.Mutex
+	results []any
+	ok      []bool
+	failed  failures
+}
+
+func newWindowRunner[T any](ctx context.Context, store pocket.Store, process pocket.Node, o *options) *windowRunner[T] {
+	group, gctx := errgroup.WithContext(ctx)
+	concurrency := o.maxConcurrency
+	if o.limiter != nil {
+		concurrency = o.limiter.MaxConcurrency()
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	group.SetLimit(concurrency)
+
+	return &windowRunner[T]{
+		ctx:      gctx,
+		group:    group,
+		store:    store,
+		process:  process,
+		o:        o,
+		progress: newProgressTracker(o.progress, -1),
+	}
+}
+
+// submit starts processing a window, waiting for a free worker.
+func (r *windowRunner[T]) submit(window []T) error {
+	r.mu.Lock()
+	idx := len(r.results)
+	r.results = append(r.results, nil)
+	r.ok = append(r.ok, false)
+	r.mu.Unlock()
+
+	r.group.Go(func() error {
+		result, err := limitedMap(r.ctx, r.o.limiter, r.run, window)
+		r.progress.step()
+		if err != nil {
+			if r.o.errorPolicy == FailFast {
+				return fmt.Errorf("process: %w", &ItemError{Index: idx, Err: err})
+			}
+			r.failed.add(idx, err)
+			return nil
+		}
+
+		r.mu.Lock()
+		r.results[idx] = result
+		r.ok[idx] = true
+		r.mu.Unlock()
+		return nil
+	})
+	return r.ctx.Err()
+}
+
+// run runs the process node through its full lifecycle on a window.
+func (r *windowRunner[T]) run(ctx context.Context, window []T) (any, error) {
+	return pocket.NewGraph(r.process, r.store).Run(ctx, window)
+}
+
+// wait waits for the submitted windows and applies the error policy.
+func (r *windowRunner[T]) wait(ctx context.Context) ([]any, error) {
+	if err := r.group.Wait(); err != nil {
+		return nil, err
+	}
+	if err := r.failed.resolve(ctx, r.o.errorPolicy, r.o.logger, len(r.results)); err != nil {
+		return nil, err
+	}
+
+	results := make([]any, 0, len(r.results))
+	for i, result := range r.results {
+		if r.ok[i] {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,237 @@
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/batch"
+)
+
+// sumNode returns a node that sums a window of ints, failing on windows
+// that contain bad.
+func sumNode(bad int) pocket.Node {
+	return pocket.NewNode[any, any]("sum",
+		pocket.Steps{
+			Exec: func(ctx context.Context, prepResult any) (any, error) {
+				total := 0
+				for _, n := range prepResult.([]int) {
+					if n == bad {
+						return nil, errors.New("bad item")
+					}
+					total += n
+				}
+				return total, nil
+			},
+		},
+	)
+}
+
+// sliceSource returns a source that streams items.
+func sliceSource(items ...int) func(context.Context, pocket.StoreReader) (<-chan int, error) {
+	return func(ctx context.Context, store pocket.StoreReader) (<-chan int, error) {
+		ch := make(chan int, len(items))
+		for _, item := range items {
+			ch <- item
+		}
+		close(ch)
+		return ch, nil
+	}
+}
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name        string
+		items       []int
+		size, slide int
+		want        []any
+	}{
+		{"sliding", []int{1, 2, 3, 4, 5, 6}, 3, 2, []any{6, 12, 11}},
+		{"sliding exact", []int{1, 2, 3, 4, 5}, 3, 2, []any{6, 12}},
+		{"non-overlapping", []int{1, 2, 3, 4, 5}, 2, 2, []any{3, 7, 5}},
+		{"gaps", []int{1, 2, 3, 4, 5, 6, 7}, 2, 3, []any{3, 9, 7}},
+		{"empty", nil, 3, 1, []any{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := batch.Window("windows", sliceSource(tt.items...), tt.size, tt.slide, sumNode(-1),
+				batch.WithConcurrency(2))
+
+			result, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestWindowInvalidSize(t *testing.T) {
+	node := batch.Window("windows", sliceSource(1, 2), 0, 1, sumNode(-1))
+
+	if _, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil); err == nil {
+		t.Error("Expected error for zero window size")
+	}
+}
+
+func TestWindowPostRetry(t *testing.T) {
+	ctx := context.Background()
+	store := pocket.NewStore()
+	node := batch.Window("windows", sliceSource(1, 2), 2, 2, sumNode(-1))
+
+	prep, err := node.Prep(ctx, store, nil)
+	if err != nil {
+		t.Fatalf("Prep failed: %v", err)
+	}
+	if result, _, err := node.Post(ctx, store, nil, prep, nil); err != nil || !reflect.DeepEqual(result, []any{3}) {
+		t.Fatalf("Expected [3], got %v, %v", result, err)
+	}
+
+	// A retried Post fails rather than finding no windows
+	if _, _, err := node.Post(ctx, store, nil, prep, nil); !errors.Is(err, batch.ErrSourceConsumed) {
+		t.Errorf("Expected ErrSourceConsumed, got %v", err)
+	}
+}
+
+func TestWindowErrorPolicy(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6}
+
+	t.Run("fail fast", func(t *testing.T) {
+		node := batch.Window("windows", sliceSource(items...), 2, 2, sumNode(3))
+
+		_, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+		var itemErr *batch.ItemError
+		if !errors.As(err, &itemErr) || itemErr.Index != 1 {
+			t.Errorf("Expected item error for window 1, got %v", err)
+		}
+	})
+
+	t.Run("collect errors", func(t *testing.T) {
+		node := batch.Window("windows", sliceSource(items...), 2, 2, sumNode(3),
+			batch.WithErrorPolicy(batch.CollectErrors))
+
+		_, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+		batchErr, ok := batch.AsBatchError(err)
+		if !ok {
+			t.Fatalf("Expected BatchError, got %v", err)
+		}
+		if batchErr.Total != 3 || len(batchErr.Failures) != 1 || batchErr.Failures[0].Index != 1 {
+			t.Errorf("Expected window 1 of 3 to fail, got %v", batchErr)
+		}
+	})
+
+	t.Run("skip and log", func(t *testing.T) {
+		var done []int
+		node := batch.Window("windows", sliceSource(items...), 2, 2, sumNode(3),
+			batch.WithErrorPolicy(batch.SkipAndLog),
+			batch.WithConcurrency(1),
+			batch.WithProgress(func(n, total int, eta time.Duration) {
+				if total != -1 {
+					t.Errorf("Expected unknown total, got %d", total)
+				}
+				done = append(done, n)
+			}))
+
+		result, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if want := []any{3, 11}; !reflect.DeepEqual(result, want) {
+			t.Errorf("Expected %v, got %v", want, result)
+		}
+		if want := []int{1, 2, 3}; !reflect.DeepEqual(done, want) {
+			t.Errorf("Expected progress %v, got %v", want, done)
+		}
+	})
+}
+
+// waitForWaiters waits until the clock has n pending waits.
+func waitForWaiters(t *testing.T, clock *pocket.SimulatedClock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for clock.Waiters() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d clock waiters, got %d", n, clock.Waiters())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTumbling(t *testing.T) {
+	clock := pocket.NewSimulatedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	items := make(chan int)
+	source := func(ctx context.Context, store pocket.StoreReader) (<-chan int, error) {
+		return items, nil
+	}
+
+	var (
+		mu       sync.Mutex
+		progress int
+	)
+	node := batch.Tumbling("per-minute", source, time.Minute, sumNode(-1),
+		batch.WithProgress(func(done, total int, eta time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress = done
+		}))
+	graph := pocket.NewGraph(node, pocket.NewStore(), pocket.WithClock(clock))
+
+	type outcome struct {
+		result any
+		err    error
+	}
+	finished := make(chan outcome, 1)
+	go func() {
+		result, err := graph.Run(context.Background(), nil)
+		finished <- outcome{result, err}
+	}()
+
+	// First window: 1 and 2
+	waitForWaiters(t, clock, 1)
+	items <- 1
+	items <- 2
+	clock.Advance(time.Minute)
+
+	// Second window: 3
+	waitForWaiters(t, clock, 1)
+	items <- 3
+	clock.Advance(time.Minute)
+
+	// An empty window is skipped
+	waitForWaiters(t, clock, 1)
+	clock.Advance(time.Minute)
+
+	// The pending window is processed when the source closes
+	waitForWaiters(t, clock, 1)
+	items <- 4
+	close(items)
+
+	out := <-finished
+	if out.err != nil {
+		t.Fatalf("Run failed: %v", out.err)
+	}
+	if want := []any{3, 3, 4}; !reflect.DeepEqual(out.result, want) {
+		t.Errorf("Expected %v, got %v", want, out.result)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if progress != 3 {
+		t.Errorf("Expected 3 windows reported, got %d", progress)
+	}
+}
+
+func TestTumblingInvalidDuration(t *testing.T) {
+	node := batch.Tumbling("per-minute", sliceSource(1), 0, sumNode(-1))
+
+	if _, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil); err == nil {
+		t.Error("Expected error for zero window duration")
+	}
+}
@@ -3,6 +3,7 @@ package pocket_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/agentstation/pocket"
 )
@@ -36,12 +37,51 @@ func BenchmarkSingleNodeExecution(b *testing.B) {
 	graph := pocket.NewGraph(node, store)
 	ctx := context.Background()
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_, _ = graph.Run(ctx, "test")
 	}
 }
 
+// TestRunAllocations guards the allocations of the execution path: one per
+// run, for its context, and two per node, naming it and its steps' first
+// attempt, even for steps that may be retried.
+func TestRunAllocations(t *testing.T) {
+	var first, last pocket.Node
+	for i := 0; i < 3; i++ {
+		var opts []pocket.Option
+		if i == 1 {
+			opts = append(opts, pocket.WithRetry(2, time.Millisecond))
+		}
+		node := pocket.NewNode[any, any]("step",
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					return input, nil
+				},
+			},
+			opts...,
+		)
+		if first == nil {
+			first = node
+		} else {
+			last.Connect("default", node)
+		}
+		last = node
+	}
+	graph := pocket.NewGraph(first, pocket.NewStore())
+	ctx := context.Background()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := graph.Run(ctx, "test"); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	})
+	if allocs > 1+2*3 {
+		t.Errorf("Expected at most 7 allocations per run, got %v", allocs)
+	}
+}
+
 // Benchmark lifecycle steps.
 func BenchmarkLifecycleSteps(b *testing.B) {
 	node := pocket.NewNode[any, any]("lifecycle",
@@ -83,6 +123,7 @@ func BenchmarkPipelineMultiNode(b *testing.B) {
 	store := pocket.NewStore()
 	ctx := context.Background()
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_, _ = pocket.Pipeline(ctx, nodes, store, "test")
@@ -114,6 +155,46 @@ func BenchmarkRunConcurrentManyNodes(b *testing.B) {
 	}
 }
 
+// Benchmark FanOut over many items.
+func BenchmarkFanOut(b *testing.B) {
+	node := pocket.NewNode[any, any]("bench",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return input, nil
+			},
+		},
+	)
+	store := pocket.NewStore()
+	ctx := context.Background()
+	items := make([]int, 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = pocket.FanOut(ctx, node, store, items)
+	}
+}
+
+// Benchmark a graph nested as a node in another graph.
+func BenchmarkNestedGraph(b *testing.B) {
+	inner := pocket.NewGraph(pocket.NewNode[any, any]("inner",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return input, nil
+			},
+		},
+	), pocket.NewStore())
+
+	graph := pocket.NewGraph(inner.AsNode("nested"), pocket.NewStore())
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = graph.Run(ctx, "test")
+	}
+}
+
 // Benchmark store operations.
 func BenchmarkStoreOperations(b *testing.B) {
 	store := pocket.NewStore()
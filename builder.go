@@ -2,8 +2,11 @@ package pocket
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -72,7 +75,9 @@ func (b *Builder) Build() (*Graph, error) {
 	return NewGraph(b.start, b.store, b.opts...), nil
 }
 
-// RunConcurrent executes multiple nodes concurrently.
+// RunConcurrent executes multiple nodes concurrently. Nodes run without
+// graph options, so no input limits, tenant limits, or Shutdown apply to
+// them; run them in a Graph for those.
 func RunConcurrent(ctx context.Context, nodes []Node, store Store, inputs []any) ([]any, error) {
 	if len(nodes) == 0 {
 		return nil, nil
@@ -96,9 +101,8 @@ func RunConcurrent(ctx context.Context, nodes []Node, store Store, inputs []any)
 		input := inputs[i]
 		g.Go(func() error {
 			// Each concurrent execution gets its own scoped store
-			scopedStore := store.Scope(fmt.Sprintf("concurrent-%d", i))
-			graph := NewGraph(node, scopedStore)
-			result, err := graph.Run(ctx, input)
+			runner := &graph{store: store.Scope("concurrent-" + strconv.Itoa(i))}
+			result, err := runner.run(ctx, node, input)
 			if err != nil {
 				return fmt.Errorf("node %s: %w", node.Name(), err)
 			}
@@ -118,13 +122,104 @@ func RunConcurrent(ctx context.Context, nodes []Node, store Store, inputs []any)
 	return results, nil
 }
 
+// ConcurrentOption configures RunConcurrentMap.
+type ConcurrentOption func(*concurrentOptions)
+
+type concurrentOptions struct {
+	cancelOnError bool
+}
+
+// WithCancelOnError cancels the remaining nodes as soon as one fails.
+// By default every node runs to completion and all failures are reported.
+func WithCancelOnError() ConcurrentOption {
+	return func(o *concurrentOptions) {
+		o.cancelOnError = true
+	}
+}
+
+// RunConcurrentMap executes multiple nodes concurrently with a distinct input
+// per node, keyed by node name. Results are returned keyed by node name and
+// include every node that succeeded, even when others failed. Nodes missing
+// from inputs receive a nil input. Like RunConcurrent, it runs nodes without
+// graph options.
+func RunConcurrentMap(
+	ctx context.Context,
+	nodes []Node,
+	store Store,
+	inputs map[string]any,
+	opts ...ConcurrentOption,
+) (map[string]any, error) {
+	options := &concurrentOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	seen := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if seen[node.Name()] {
+			return nil, fmt.Errorf("duplicate node name %q", node.Name())
+		}
+		seen[node.Name()] = true
+	}
+	for name := range inputs {
+		if !seen[name] {
+			return nil, fmt.Errorf("input for unknown node %q", name)
+		}
+	}
+
+	runCtx := ctx
+	cancel := func() {}
+	if options.cancelOnError {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make(map[string]any, len(nodes))
+		errs    []error
+	)
+
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(node Node) {
+			defer wg.Done()
+
+			// Each concurrent execution gets its own scoped store
+			runner := &graph{store: store.Scope("concurrent-" + node.Name())}
+			result, err := runner.run(runCtx, node, inputs[node.Name()])
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("node %s: %w", node.Name(), err))
+				cancel()
+				return
+			}
+			results[node.Name()] = result
+		}(node)
+	}
+
+	wg.Wait()
+
+	if options.cancelOnError && len(errs) > 0 {
+		// Later errors are usually cancellations caused by the first
+		return results, errs[0]
+	}
+	return results, errors.Join(errs...)
+}
+
 // Pipeline executes nodes sequentially, passing output to input.
+// See ConcurrentPipeline for pipelining a stream of values through stages.
+// Stages run without graph options such as input limits or tenant limits.
 func Pipeline(ctx context.Context, nodes []Node, store Store, input any) (any, error) {
 	current := input
 
+	// One runner serves every stage since they share the store
+	runner := &graph{store: store}
 	for _, node := range nodes {
-		graph := NewGraph(node, store)
-		output, err := graph.Run(ctx, current)
+		output, err := runner.run(ctx, node, current)
 		if err != nil {
 			return nil, fmt.Errorf("pipeline failed at %s: %w", node.Name(), err)
 		}
@@ -134,19 +229,67 @@ func Pipeline(ctx context.Context, nodes []Node, store Store, input any) (any, e
 	return current, nil
 }
 
+// RunNode runs a single node as a graph would, without moving on to its
+// successors, for adapters that route between nodes themselves. The node's
+// options apply, such as retries, timeouts, Steps.Fallback, lifecycle hooks,
+// and WithFallbackNode. It returns the output, the action to route by, and
+// the node whose successors the action selects among: n, or its fallback
+// node when that ran. Like the other helpers, it runs without graph options.
+func RunNode(ctx context.Context, n Node, store Store, input any) (output any, next string, routedBy Node, err error) {
+	runner := &graph{store: store}
+	ctx = newRunContext(ctx, runner)
+
+	output, next, err = runner.executeNode(ctx, n, input)
+	if err == nil {
+		return output, next, n, nil
+	}
+
+	fallback := fallbackNodeOf(n)
+	if fallback == nil || ctx.Err() != nil {
+		return nil, "", nil, err
+	}
+	output, next, fallbackErr := runner.executeNode(context.WithValue(ctx, fallbackErrorKey{}, err), fallback, input)
+	if fallbackErr != nil {
+		return nil, "", nil, fmt.Errorf("fallback node %s failed: primary=%w, fallback=%w", fallback.Name(), err, fallbackErr)
+	}
+	return output, next, fallback, nil
+}
+
 // FanOut executes a node for each input item concurrently.
-func FanOut[T any](ctx context.Context, node Node, store Store, items []T) ([]any, error) {
+// Use WithFanOutConcurrency or WithAdaptiveConcurrency to bound parallelism.
+// Like the other helpers, it runs the node without graph options.
+func FanOut[T any](ctx context.Context, node Node, store Store, items []T, opts ...FanOutOption) ([]any, error) {
+	options := &fanOutOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
+	if options.limiter == nil && options.concurrency > 0 {
+		g.SetLimit(options.concurrency)
+	}
+
 	results := make([]any, len(items))
 	mu := &sync.Mutex{}
 
+	var acquireErr error
 	for i, item := range items {
 		i, item := i, item
+		if options.limiter != nil {
+			if acquireErr = options.limiter.Acquire(ctx); acquireErr != nil {
+				break
+			}
+		}
+
 		g.Go(func() error {
 			// Each item gets its own scoped store
-			scopedStore := store.Scope(fmt.Sprintf("item-%d", i))
-			graph := NewGraph(node, scopedStore)
-			result, err := graph.Run(ctx, item)
+			runner := &graph{store: store.Scope("item-" + strconv.Itoa(i))}
+
+			start := time.Now()
+			result, err := runner.run(ctx, node, item)
+			if options.limiter != nil {
+				options.limiter.Release(time.Since(start), err)
+			}
 			if err != nil {
 				return err
 			}
@@ -162,6 +305,9 @@ func FanOut[T any](ctx context.Context, node Node, store Store, items []T) ([]an
 	if err := g.Wait(); err != nil {
 		return nil, err
 	}
+	if acquireErr != nil {
+		return nil, acquireErr
+	}
 
 	return results, nil
 }
@@ -2,6 +2,7 @@ package pocket_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync/atomic"
 	"testing"
@@ -60,6 +61,85 @@ func TestRunConcurrent(t *testing.T) {
 	}
 }
 
+func TestRunConcurrentMap(t *testing.T) {
+	echo := func(name string) pocket.Node {
+		return pocket.NewNode[any, any](name,
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					return fmt.Sprintf("%s:%v", name, input), nil
+				},
+			},
+		)
+	}
+	failing := pocket.NewNode[any, any]("failing",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return nil, fmt.Errorf("boom")
+			},
+		},
+	)
+	slow := pocket.NewNode[any, any]("slow",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(time.Second):
+					return "done", nil
+				}
+			},
+		},
+	)
+
+	t.Run("per-node inputs", func(t *testing.T) {
+		results, err := pocket.RunConcurrentMap(context.Background(),
+			[]pocket.Node{echo("a"), echo("b")}, pocket.NewStore(),
+			map[string]any{"a": 1, "b": 2},
+		)
+		if err != nil {
+			t.Fatalf("RunConcurrentMap() error = %v", err)
+		}
+		if results["a"] != "a:1" || results["b"] != "b:2" {
+			t.Errorf("results = %v, want a:1 and b:2", results)
+		}
+	})
+
+	t.Run("collects errors and partial results", func(t *testing.T) {
+		results, err := pocket.RunConcurrentMap(context.Background(),
+			[]pocket.Node{echo("a"), failing}, pocket.NewStore(), nil,
+		)
+		if err == nil {
+			t.Fatal("RunConcurrentMap() error = nil, want error")
+		}
+		if results["a"] != "a:<nil>" {
+			t.Errorf(`results["a"] = %v, want a:<nil>`, results["a"])
+		}
+	})
+
+	t.Run("cancel on first error", func(t *testing.T) {
+		start := time.Now()
+		_, err := pocket.RunConcurrentMap(context.Background(),
+			[]pocket.Node{failing, slow}, pocket.NewStore(), nil,
+			pocket.WithCancelOnError(),
+		)
+		if err == nil {
+			t.Fatal("RunConcurrentMap() error = nil, want error")
+		}
+		if elapsed := time.Since(start); elapsed >= time.Second {
+			t.Errorf("elapsed = %v, want slow node cancelled", elapsed)
+		}
+	})
+
+	t.Run("unknown input", func(t *testing.T) {
+		_, err := pocket.RunConcurrentMap(context.Background(),
+			[]pocket.Node{echo("a")}, pocket.NewStore(), map[string]any{"missing": 1},
+		)
+		if err == nil {
+			t.Fatal("RunConcurrentMap() error = nil, want error")
+		}
+	})
+}
+
 func TestPipeline(t *testing.T) {
 	store := pocket.NewStore()
 
@@ -125,6 +205,134 @@ func TestPipeline(t *testing.T) {
 	}
 }
 
+func TestRunNode(t *testing.T) {
+	ctx := context.Background()
+	store := pocket.NewStore()
+
+	completed := 0
+	backup := pocket.NewNode[any, any]("backup",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return fmt.Sprintf("backup after %v", pocket.FallbackError(ctx)), nil
+			},
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+				return exec, "degraded", nil
+			},
+		},
+	)
+	primary := pocket.NewNode[any, any]("primary",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				if input == "fail" {
+					return nil, errors.New("unavailable")
+				}
+				return "primary", nil
+			},
+		},
+		pocket.WithOnComplete(func(ctx context.Context, store pocket.StoreWriter) { completed++ }),
+		pocket.WithFallbackNode(backup),
+	)
+	primary.Connect("default", pocket.NewNode[any, any]("unreachable", pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			t.Error("Expected RunNode not to follow successors")
+			return input, nil
+		},
+	}))
+
+	output, next, routedBy, err := pocket.RunNode(ctx, primary, store, "ok")
+	if err != nil || output != "primary" || next != "default" || routedBy != primary {
+		t.Errorf("Expected primary to route by default, got %v, %q, %v, %v", output, next, routedBy, err)
+	}
+
+	output, next, routedBy, err = pocket.RunNode(ctx, primary, store, "fail")
+	if err != nil || next != "degraded" || routedBy != backup {
+		t.Errorf("Expected the fallback node to route by degraded, got %q, %v, %v", next, routedBy, err)
+	}
+	if want := "backup after exec failed: failed after 1 attempts: unavailable"; output != want {
+		t.Errorf("Expected %q, got %v", want, output)
+	}
+	if completed != 2 {
+		t.Errorf("Expected the completion hook to run twice, got %d", completed)
+	}
+	if pocket.FallbackNode(primary) != backup || pocket.FallbackNode(backup) != nil {
+		t.Error("Expected FallbackNode to return the node set with WithFallbackNode")
+	}
+}
+
+func TestConcurrentPipeline(t *testing.T) {
+	double := pocket.NewNode[any, any]("double",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				time.Sleep(10 * time.Millisecond)
+				return input.(int) * 2, nil
+			},
+		},
+	)
+	addOne := pocket.NewNode[any, any]("add-one",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				time.Sleep(10 * time.Millisecond)
+				return input.(int) + 1, nil
+			},
+		},
+	)
+
+	pipeline := pocket.NewConcurrentPipeline([]pocket.Node{double, addOne}, pocket.NewStore(),
+		pocket.WithStageBuffer(2),
+	)
+
+	inputs := []any{1, 2, 3, 4, 5, 6, 7, 8}
+	start := time.Now()
+	results, err := pipeline.Run(context.Background(), inputs)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	duration := time.Since(start)
+
+	for i, got := range results {
+		if want := inputs[i].(int)*2 + 1; got != want {
+			t.Errorf("results[%d] = %v, want %v", i, got, want)
+		}
+	}
+
+	// Stages overlap, so the total should be well under the sequential time
+	sequential := time.Duration(len(inputs)*2) * 10 * time.Millisecond
+	if duration >= sequential {
+		t.Errorf("duration = %v, want < %v", duration, sequential)
+	}
+
+	metrics := pipeline.Metrics()
+	if len(metrics) != 2 {
+		t.Fatalf("len(Metrics()) = %d, want 2", len(metrics))
+	}
+	for _, m := range metrics {
+		if m.Processed != int64(len(inputs)) {
+			t.Errorf("%s processed = %d, want %d", m.Name, m.Processed, len(inputs))
+		}
+	}
+}
+
+func TestConcurrentPipelineError(t *testing.T) {
+	fail := pocket.NewNode[any, any]("fail",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				if input.(int) == 3 {
+					return nil, fmt.Errorf("bad input")
+				}
+				return input, nil
+			},
+		},
+	)
+
+	pipeline := pocket.NewConcurrentPipeline([]pocket.Node{fail}, pocket.NewStore())
+	if _, err := pipeline.Run(context.Background(), []any{1, 2, 3, 4}); err == nil {
+		t.Fatal("Run() error = nil, want error")
+	}
+	if got := pipeline.Metrics()[0].Errors; got != 1 {
+		t.Errorf("Errors = %d, want 1", got)
+	}
+}
+
 func TestFanOut(t *testing.T) {
 	store := pocket.NewStore()
 
@@ -158,6 +366,113 @@ func TestFanOut(t *testing.T) {
 	}
 }
 
+func TestFanOutConcurrencyLimit(t *testing.T) {
+	var active, peak int32
+
+	track := pocket.NewNode[any, any]("track",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				n := atomic.AddInt32(&active, 1)
+				defer atomic.AddInt32(&active, -1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				return input, nil
+			},
+		},
+	)
+
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+
+	tests := []struct {
+		name string
+		opt  pocket.FanOutOption
+		max  int32
+	}{
+		{name: "fixed", opt: pocket.WithFanOutConcurrency(3), max: 3},
+		{name: "adaptive", opt: pocket.WithAdaptiveConcurrency(pocket.AdaptiveConcurrency{Max: 4}), max: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&peak, 0)
+
+			results, err := pocket.FanOut(context.Background(), track, pocket.NewStore(), items, tt.opt)
+			if err != nil {
+				t.Fatalf("FanOut() error = %v", err)
+			}
+			if len(results) != len(items) {
+				t.Fatalf("len(results) = %d, want %d", len(results), len(items))
+			}
+			if got := atomic.LoadInt32(&peak); got > tt.max {
+				t.Errorf("peak concurrency = %d, want <= %d", got, tt.max)
+			}
+		})
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	limiter := pocket.NewAdaptiveLimiter(pocket.AdaptiveConcurrency{
+		Min:           1,
+		Max:           8,
+		Initial:       4,
+		LatencyTarget: 100 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	if got := limiter.Limit(); got != 4 {
+		t.Fatalf("initial Limit() = %d, want 4", got)
+	}
+
+	// Errors cut the limit multiplicatively
+	if err := limiter.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	limiter.Release(time.Millisecond, fmt.Errorf("rate limited"))
+	if got := limiter.Limit(); got != 2 {
+		t.Errorf("Limit() after error = %d, want 2", got)
+	}
+
+	// Slow calls count as overload
+	if err := limiter.Acquire(ctx); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	limiter.Release(time.Second, nil)
+	if got := limiter.Limit(); got != 1 {
+		t.Errorf("Limit() after slow call = %d, want 1", got)
+	}
+
+	// Successes grow the limit additively up to Max
+	for i := 0; i < 100; i++ {
+		if err := limiter.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+		limiter.Release(time.Millisecond, nil)
+	}
+	if got := limiter.Limit(); got <= 1 || got > 8 {
+		t.Errorf("Limit() after successes = %d, want in (1, 8]", got)
+	}
+
+	// Acquire honors context cancellation when no slots are free
+	for i := 0; i < limiter.Limit(); i++ {
+		if err := limiter.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+	}
+	cancelCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Acquire(cancelCtx); err == nil {
+		t.Error("Acquire() on full limiter error = nil, want context error")
+	}
+}
+
 func TestFanIn(t *testing.T) {
 	store := pocket.NewStore()
 
@@ -0,0 +1,150 @@
+package pocket
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrChaos is the error injected by WithChaos when no error is configured.
+var ErrChaos = errors.New("pocket: injected fault")
+
+// ChaosConfig configures the faults WithChaos injects. Rates are
+// probabilities from 0 to 1, drawn independently for each Exec attempt, so
+// a fault can be followed by a successful retry.
+type ChaosConfig struct {
+	// Nodes names the nodes to inject faults into. Empty means every node.
+	Nodes []string
+
+	// LatencyRate is the probability of delaying Exec by up to Latency.
+	LatencyRate float64
+	Latency     time.Duration
+
+	// ErrorRate is the probability of failing Exec with Error, or ErrChaos
+	// when Error is nil, without running it.
+	ErrorRate float64
+	Error     error
+
+	// CancelRate is the probability of running Exec with a canceled
+	// context. The attempt fails with context.Canceled even if Exec
+	// ignores the context.
+	CancelRate float64
+
+	// Seed makes the faults repeatable for the same sequence of Exec
+	// attempts. Zero uses a random seed.
+	Seed int64
+}
+
+// chaos injects faults into the Exec steps of nodes.
+type chaos struct {
+	config ChaosConfig
+	nodes  map[string]bool
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// WithChaos injects latency, errors, or context cancellations into the
+// Exec step of nodes at random, so tests exercise retry, fallback, and
+// compensation paths. Faults are injected on each attempt, inside any
+// retries, and a failed attempt falls back as a real failure would. Use
+// WithChaos several times for different faults on different nodes. Nodes
+// inside nested graphs need the option on those graphs.
+//
+// Example:
+//
+//	graph := pocket.NewGraph(start, store, pocket.WithChaos(pocket.ChaosConfig{
+//		Nodes:     []string{"charge_payment"},
+//		ErrorRate: 0.2,
+//		Error:     errors.New("payment declined"),
+//		Seed:      42,
+//	}))
+func WithChaos(config ChaosConfig) GraphOption {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	c := &chaos{
+		config: config,
+		//nolint:gosec // Fault injection doesn't need a secure source
+		rng: rand.New(rand.NewSource(seed)),
+	}
+	if len(config.Nodes) > 0 {
+		c.nodes = make(map[string]bool, len(config.Nodes))
+		for _, name := range config.Nodes {
+			c.nodes[name] = true
+		}
+	}
+
+	return func(o *graphOptions) {
+		o.chaos = append(o.chaos, c)
+	}
+}
+
+// wrap returns exec with faults injected for a node.
+func (c *chaos) wrap(name string, logger Logger, exec func(context.Context) (any, error)) func(context.Context) (any, error) {
+	if c.nodes != nil && !c.nodes[name] {
+		return exec
+	}
+
+	return func(ctx context.Context) (any, error) {
+		if delay := c.latency(); delay > 0 {
+			if logger != nil {
+				logger.Debug(ctx, "injecting latency", "name", name, "delay", delay)
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-ClockFrom(ctx).After(delay):
+			}
+		}
+
+		if c.roll(c.config.ErrorRate) {
+			if logger != nil {
+				logger.Debug(ctx, "injecting error", "name", name)
+			}
+			if c.config.Error != nil {
+				return nil, c.config.Error
+			}
+			return nil, ErrChaos
+		}
+
+		if c.roll(c.config.CancelRate) {
+			if logger != nil {
+				logger.Debug(ctx, "injecting cancellation", "name", name)
+			}
+			canceled, cancel := context.WithCancel(ctx)
+			cancel()
+			result, err := exec(canceled)
+			if err == nil {
+				return nil, canceled.Err()
+			}
+			return result, err
+		}
+
+		return exec(ctx)
+	}
+}
+
+// roll reports whether a fault with the given rate occurs.
+func (c *chaos) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Float64() < rate
+}
+
+// latency returns a delay to inject, or zero.
+func (c *chaos) latency() time.Duration {
+	if c.config.Latency <= 0 || !c.roll(c.config.LatencyRate) {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.rng.Int63n(int64(c.config.Latency))) + 1
+}
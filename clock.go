@@ -0,0 +1,154 @@
+package pocket
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits for it to pass. Retries, delays, TTLs,
+// and other timing in Pocket use the clock from ClockFrom, or a store's
+// clock, so tests can replace real time with a SimulatedClock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock returns the clock backed by the time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type clockKey struct{}
+
+// WithClock runs the graph's nodes on a clock, passed through the context
+// of each run. Retry delays, memoization TTLs, and nodes that use
+// ClockFrom, such as delay, follow it. Nested graphs inherit the clock
+// unless they set their own.
+//
+// Example:
+//
+//	clock := pocket.NewSimulatedClock(time.Now())
+//	clock.AutoAdvance(true)
+//	graph := pocket.NewGraph(start, store, pocket.WithClock(clock))
+func WithClock(clock Clock) GraphOption {
+	return func(o *graphOptions) {
+		o.clock = clock
+	}
+}
+
+// ClockFrom returns the clock of a run set with WithClock or
+// WithDeterminism, or the system clock.
+func ClockFrom(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock
+	}
+	return systemClock{}
+}
+
+// SimulatedClock is a Clock whose time only moves when it's advanced, so
+// tests of workflows with long delays, retries, and TTLs run without real
+// sleeps. It is safe for concurrent use.
+//
+// Waits started with After complete when the clock is advanced past their
+// deadline. With AutoAdvance, waits complete at once instead, moving the
+// clock forward by the time waited, so a workflow that sleeps for an hour
+// finishes immediately with the clock an hour later. Concurrent waits each
+// move the clock, so their durations add up.
+type SimulatedClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	auto    bool
+	waiters []simulatedWait
+}
+
+// simulatedWait is a pending After call.
+type simulatedWait struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewSimulatedClock returns a SimulatedClock set to start.
+func NewSimulatedClock(start time.Time) *SimulatedClock {
+	return &SimulatedClock{now: start}
+}
+
+// Now returns the simulated time.
+func (c *SimulatedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the simulated time once the clock
+// has advanced by d.
+func (c *SimulatedClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	if c.auto {
+		c.set(c.now.Add(d))
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, simulatedWait{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d, completing the waits it passes.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(c.now.Add(d))
+}
+
+// Set moves the clock to t, completing the waits it passes. The clock
+// doesn't move backwards.
+func (c *SimulatedClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(t)
+}
+
+// AutoAdvance sets whether waits complete at once, moving the clock
+// forward by the time waited.
+func (c *SimulatedClock) AutoAdvance(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.auto = enabled
+}
+
+// Waiters returns the number of pending waits, so tests can advance the
+// clock once the code under test is waiting.
+func (c *SimulatedClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// set moves the clock to t and completes the waits due. c.mu must be held.
+func (c *SimulatedClock) set(t time.Time) {
+	if t.After(c.now) {
+		c.now = t
+	}
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	clear(c.waiters[len(pending):])
+	c.waiters = pending
+}
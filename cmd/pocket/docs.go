@@ -3,13 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/agentstation/pocket/nodes"
-	"github.com/agentstation/pocket/yaml"
 )
 
 // docsCmd represents the docs command.
@@ -20,32 +20,53 @@ var docsCmd = &cobra.Command{
 
 The documentation includes descriptions, schemas, and examples for each node.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		config := &DocsConfig{
-			Format: output,
-		}
-		return runGenerateDocs(config)
+		return runGenerateDocs(newDocsConfig())
 	},
 }
 
+var (
+	// Docs command flags.
+	docsFile        string
+	docsCategory    string
+	docsBuiltinOnly bool
+)
+
 func init() {
 	rootCmd.AddCommand(docsCmd)
+	addDocsFlags(docsCmd)
+}
+
+// addDocsFlags adds the documentation flags to a command.
+func addDocsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&docsFile, "file", "f", "", "Write documentation to a file instead of stdout")
+	cmd.Flags().StringVar(&docsCategory, "category", "", "Only document nodes in this category")
+	cmd.Flags().BoolVar(&docsBuiltinOnly, "builtin-only", false, "Leave out nodes provided by plugins")
 }
 
 // DocsConfig holds configuration for the docs command.
 type DocsConfig struct {
-	Format   string // "markdown", "json"
-	Output   string // Output file path (empty for stdout)
-	Category string // Filter by category
+	Format      string // "markdown", "json"
+	Output      string // Output file path (empty for stdout)
+	Category    string // Filter by category
+	BuiltinOnly bool   // Leave out plugin nodes
+}
+
+func newDocsConfig() *DocsConfig {
+	return &DocsConfig{
+		Format:      output,
+		Output:      docsFile,
+		Category:    docsCategory,
+		BuiltinOnly: docsBuiltinOnly,
+	}
 }
 
 // runGenerateDocs generates documentation from node metadata.
 func runGenerateDocs(config *DocsConfig) error {
-	// Create a loader and registry to get all nodes
-	loader := yaml.NewLoader()
-	nodes.RegisterAll(loader, false)
-
-	// Get all registered nodes
-	allNodes := getBuiltinNodes()
+	// Get built-in nodes and, unless disabled, installed plugin nodes
+	allNodes, providers := getBuiltinNodes(), map[string]string{}
+	if !config.BuiltinOnly {
+		allNodes, providers = getAllNodes()
+	}
 
 	// Filter by category if specified
 	if config.Category != "" {
@@ -68,20 +89,24 @@ func runGenerateDocs(config *DocsConfig) error {
 
 	switch config.Format {
 	case jsonFormat:
-		return generateJSONDocs(allNodes, config.Output)
+		return generateJSONDocs(allNodes, providers, config.Output)
 	default:
-		return generateMarkdownDocs(allNodes, config.Output)
+		return generateMarkdownDocs(allNodes, providers, config.Output)
 	}
 }
 
 // generateMarkdownDocs generates Markdown documentation.
 //
 //nolint:gocyclo // Complex due to comprehensive documentation generation with multiple sections
-func generateMarkdownDocs(nodeList []nodes.Metadata, output string) error {
+func generateMarkdownDocs(nodeList []nodes.Metadata, providers map[string]string, output string) error {
 	var sb strings.Builder
 
 	sb.WriteString("# Pocket Node Reference\n\n")
-	sb.WriteString("This document provides a comprehensive reference for all built-in nodes in the Pocket framework.\n\n")
+	if len(providers) > 0 {
+		sb.WriteString("This document provides a comprehensive reference for all nodes available to Pocket workflows, including nodes provided by installed plugins.\n\n")
+	} else {
+		sb.WriteString("This document provides a comprehensive reference for all built-in nodes in the Pocket framework.\n\n")
+	}
 	sb.WriteString("## Table of Contents\n\n")
 
 	// Group by category
@@ -115,7 +140,13 @@ func generateMarkdownDocs(nodeList []nodes.Metadata, output string) error {
 			sb.WriteString(fmt.Sprintf("### %s\n\n", node.Type))
 			sb.WriteString(fmt.Sprintf("%s\n\n", node.Description))
 
-			if node.Since != "" {
+			if plugin, ok := providers[node.Type]; ok {
+				sb.WriteString(fmt.Sprintf("**Plugin:** %s", plugin))
+				if node.Since != "" {
+					sb.WriteString(fmt.Sprintf(" (version %s)", node.Since))
+				}
+				sb.WriteString("\n\n")
+			} else if node.Since != "" {
 				sb.WriteString(fmt.Sprintf("**Since:** %s\n\n", node.Since))
 			}
 
@@ -131,27 +162,20 @@ func generateMarkdownDocs(nodeList []nodes.Metadata, output string) error {
 				if props, ok := node.ConfigSchema["properties"].(map[string]interface{}); ok {
 					sb.WriteString("**Properties:**\n\n")
 
-					// Get sorted property names
-					var propNames []string
-					for name := range props {
-						propNames = append(propNames, name)
-					}
-					sort.Strings(propNames)
+					requiredProps := schemaStrings(node.ConfigSchema["required"])
 
-					for _, name := range propNames {
-						prop := props[name].(map[string]interface{})
+					for _, name := range sortedKeys(props) {
+						prop, _ := props[name].(map[string]interface{})
 						desc := ""
 						if d, ok := prop["description"].(string); ok {
 							desc = d
 						}
 
 						required := false
-						if reqList, ok := node.ConfigSchema["required"].([]string); ok {
-							for _, req := range reqList {
-								if req == name {
-									required = true
-									break
-								}
+						for _, req := range requiredProps {
+							if req == name {
+								required = true
+								break
 							}
 						}
 
@@ -161,18 +185,14 @@ func generateMarkdownDocs(nodeList []nodes.Metadata, output string) error {
 						}
 						sb.WriteString(fmt.Sprintf(": %s\n", desc))
 
-						if t, ok := prop["type"].(string); ok {
-							sb.WriteString(fmt.Sprintf("  - Type: `%s`\n", t))
+						if types := schemaStrings(prop["type"]); len(types) > 0 {
+							sb.WriteString(fmt.Sprintf("  - Type: `%s`\n", strings.Join(types, " | ")))
 						}
 						if def, ok := prop["default"]; ok {
 							sb.WriteString(fmt.Sprintf("  - Default: `%v`\n", def))
 						}
-						if enum, ok := prop["enum"].([]interface{}); ok {
-							values := []string{}
-							for _, v := range enum {
-								values = append(values, fmt.Sprintf("`%v`", v))
-							}
-							sb.WriteString(fmt.Sprintf("  - Allowed values: %s\n", strings.Join(values, ", ")))
+						if enum := schemaStrings(prop["enum"]); len(enum) > 0 {
+							sb.WriteString(fmt.Sprintf("  - Allowed values: `%s`\n", strings.Join(enum, "`, `")))
 						}
 					}
 					sb.WriteString("\n")
@@ -211,8 +231,8 @@ func generateMarkdownDocs(nodeList []nodes.Metadata, output string) error {
 					sb.WriteString("config:\n")
 
 					// Convert config to YAML-like format
-					for k, v := range example.Config {
-						writeYAMLValue(&sb, k, v, "  ")
+					for _, k := range sortedKeys(example.Config) {
+						writeYAMLValue(&sb, k, example.Config[k], "  ")
 					}
 					sb.WriteString("```\n\n")
 
@@ -236,14 +256,19 @@ func generateMarkdownDocs(nodeList []nodes.Metadata, output string) error {
 		}
 	}
 
-	// Write output
+	return writeDocs(sb.String(), output)
+}
+
+// writeDocs writes generated documentation to a file, or stdout when
+// output is empty.
+func writeDocs(doc, output string) error {
 	if output == "" {
-		fmt.Print(sb.String())
-	} else {
-		// TODO: Write to file
-		return fmt.Errorf("file output not implemented yet")
+		fmt.Print(doc)
+		return nil
+	}
+	if err := os.WriteFile(output, []byte(doc), 0o600); err != nil {
+		return fmt.Errorf("write documentation: %w", err)
 	}
-
 	return nil
 }
 
@@ -259,22 +284,33 @@ func writeYAMLValue(sb *strings.Builder, key string, value interface{}, indent s
 		}
 	case map[string]interface{}:
 		fmt.Fprintf(sb, "%s%s:\n", indent, key)
-		for k, val := range v {
-			writeYAMLValue(sb, k, val, indent+"  ")
+		for _, k := range sortedKeys(v) {
+			writeYAMLValue(sb, k, v[k], indent+"  ")
 		}
 	default:
 		fmt.Fprintf(sb, "%s%s: %v\n", indent, key, value)
 	}
 }
 
+// nodeDocs is a node's entry in JSON documentation.
+type nodeDocs struct {
+	nodes.Metadata `yaml:",inline"`
+	Plugin         string `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+}
+
 // generateJSONDocs generates JSON documentation.
-func generateJSONDocs(nodeList []nodes.Metadata, output string) error {
+func generateJSONDocs(nodeList []nodes.Metadata, providers map[string]string, output string) error {
+	entries := make([]nodeDocs, len(nodeList))
+	for i, node := range nodeList {
+		entries[i] = nodeDocs{Metadata: node, Plugin: providers[node.Type]}
+	}
+
 	// Create documentation structure
 	doc := map[string]interface{}{
 		"title":       "Pocket Node Reference",
-		"description": "Comprehensive reference for all built-in nodes in the Pocket framework",
+		"description": "Comprehensive reference for all nodes available to Pocket workflows",
 		"version":     "1.0.0",
-		"nodes":       nodeList,
+		"nodes":       entries,
 	}
 
 	// Marshal to JSON
@@ -283,13 +319,5 @@ func generateJSONDocs(nodeList []nodes.Metadata, output string) error {
 		return fmt.Errorf("marshal JSON: %w", err)
 	}
 
-	// Write output
-	if output == "" {
-		fmt.Println(string(data))
-	} else {
-		// TODO: Write to file
-		return fmt.Errorf("file output not implemented yet")
-	}
-
-	return nil
+	return writeDocs(string(data)+"\n", output)
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	goyaml "github.com/goccy/go-yaml"
+)
+
+// overlayPath returns the path of a workflow's overlay file for an
+// environment: workflow.staging.yaml for workflow.yaml and staging.
+func overlayPath(path, env string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + env + ext
+}
+
+// loadWorkflowData reads a workflow file and, for an environment, deep-merges
+// its overlay file onto it, returning the merged YAML.
+func loadWorkflowData(path, env string) ([]byte, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // User-provided workflow file
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if env == "" {
+		return data, nil
+	}
+
+	overlayFile := overlayPath(path, env)
+	overlayData, err := os.ReadFile(overlayFile) //nolint:gosec // Overlay next to the workflow file
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no overlay for environment %s: %s not found", env, overlayFile)
+		}
+		return nil, fmt.Errorf("read overlay: %w", err)
+	}
+
+	var base, overlay map[string]interface{}
+	if err := goyaml.Unmarshal(data, &base); err != nil {
+		return nil, fmt.Errorf("parse YAML: %w", err)
+	}
+	if err := goyaml.Unmarshal(overlayData, &overlay); err != nil {
+		return nil, fmt.Errorf("parse overlay %s: %w", overlayFile, err)
+	}
+	if base == nil {
+		base = make(map[string]interface{})
+	}
+
+	mergeWorkflow(base, overlay)
+	return goyaml.Marshal(base)
+}
+
+// mergeWorkflow deep-merges an overlay onto a workflow document. Maps merge
+// key by key, nodes merge with the base node of the same name or are
+// added, and other values, including other lists, replace the base.
+func mergeWorkflow(base, overlay map[string]interface{}) {
+	for key, value := range overlay {
+		if key == "nodes" {
+			baseNodes, _ := base[key].([]interface{})
+			overlayNodes, ok := value.([]interface{})
+			if ok {
+				base[key] = mergeNodes(baseNodes, overlayNodes)
+				continue
+			}
+		}
+		mergeValue(base, key, value)
+	}
+}
+
+// mergeNodes merges overlay nodes onto base nodes by name.
+func mergeNodes(base, overlay []interface{}) []interface{} {
+	index := make(map[string]int, len(base))
+	for i, n := range base {
+		if node, ok := n.(map[string]interface{}); ok {
+			if name, ok := node["name"].(string); ok {
+				index[name] = i
+			}
+		}
+	}
+
+	merged := append([]interface{}(nil), base...)
+	for _, n := range overlay {
+		node, ok := n.(map[string]interface{})
+		name, _ := node["name"].(string)
+		i, exists := index[name]
+		if !ok || !exists {
+			merged = append(merged, n)
+			continue
+		}
+		baseNode, _ := merged[i].(map[string]interface{})
+		for key, value := range node {
+			mergeValue(baseNode, key, value)
+		}
+	}
+	return merged
+}
+
+// mergeValue sets dst[key] to value, deep-merging maps.
+func mergeValue(dst map[string]interface{}, key string, value interface{}) {
+	src, srcOK := value.(map[string]interface{})
+	existing, dstOK := dst[key].(map[string]interface{})
+	if !srcOK || !dstOK {
+		dst[key] = value
+		return
+	}
+	for k, v := range src {
+		mergeValue(existing, k, v)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	goyaml "github.com/goccy/go-yaml"
+
+	"github.com/agentstation/pocket/yaml"
+)
+
+func TestLoadWorkflowDataOverlay(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "workflow.yaml")
+	writeTestFile(t, path, `name: orders
+start: fetch
+config:
+  api:
+    url: https://api.example.com
+    retries: 3
+nodes:
+  - name: fetch
+    type: http
+    config:
+      url: "{{.workflow.config.api.url}}/orders"
+      method: GET
+  - name: report
+    type: echo
+connections:
+  - from: fetch
+    to: report
+`)
+	writeTestFile(t, filepath.Join(dir, "workflow.staging.yaml"), `config:
+  api:
+    url: https://staging.example.com
+nodes:
+  - name: fetch
+    config:
+      method: POST
+  - name: audit
+    type: echo
+connections:
+  - from: fetch
+    to: audit
+`)
+
+	data, err := loadWorkflowData(path, "staging")
+	if err != nil {
+		t.Fatalf("loadWorkflowData() error = %v", err)
+	}
+	var def yaml.GraphDefinition
+	if err := goyaml.Unmarshal(data, &def); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	api := def.Config["api"].(map[string]interface{})
+	if api["url"] != "https://staging.example.com" || api["retries"] != uint64(3) {
+		t.Errorf("config.api = %v, want the staging url and base retries", api)
+	}
+
+	names := make([]string, len(def.Nodes))
+	for i, n := range def.Nodes {
+		names[i] = n.Name
+	}
+	if want := []string{"fetch", "report", "audit"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("nodes = %v, want %v", names, want)
+	}
+	fetch := def.Nodes[0]
+	if fetch.Type != "http" || fetch.Config["method"] != "POST" || !strings.HasSuffix(fetch.Config["url"].(string), "/orders") {
+		t.Errorf("fetch = %+v, want the base node with the staging method", fetch)
+	}
+	if len(def.Connections) != 1 || def.Connections[0].To != "audit" {
+		t.Errorf("connections = %+v, want the overlay's", def.Connections)
+	}
+
+	t.Run("missing overlay", func(t *testing.T) {
+		_, err := loadWorkflowData(path, "prod")
+		if err == nil || !strings.Contains(err.Error(), "workflow.prod.yaml not found") {
+			t.Errorf("loadWorkflowData() error = %v, want missing overlay error", err)
+		}
+	})
+
+	t.Run("no environment", func(t *testing.T) {
+		data, err := loadWorkflowData(path, "")
+		if err != nil {
+			t.Fatalf("loadWorkflowData() error = %v", err)
+		}
+		original, _ := os.ReadFile(path)
+		if string(data) != string(original) {
+			t.Error("loadWorkflowData() changed the workflow without an environment")
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/agentstation/pocket"
+)
+
+// eventsJSONL is the format of --events that writes one JSON event per
+// line.
+const eventsJSONL = "jsonl"
+
+// runEndEvent is the type of the last event of a run, with its result.
+const runEndEvent = "run_end"
+
+// runEvent is a run event as written by --events.
+type runEvent struct {
+	Time       time.Time   `json:"time"`
+	Type       string      `json:"type"`
+	RunID      string      `json:"run_id,omitempty"`
+	Node       string      `json:"node,omitempty"`
+	Action     string      `json:"action,omitempty"`
+	Next       string      `json:"next,omitempty"`
+	Attempt    int         `json:"attempt,omitempty"`
+	DurationMS float64     `json:"duration_ms,omitempty"`
+	Output     interface{} `json:"output,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// eventWriter writes a run's events as JSON lines, ending with a run_end
+// event, so other tools can follow CLI runs.
+type eventWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func newEventWriter(out io.Writer) *eventWriter {
+	return &eventWriter{out: out}
+}
+
+// handle writes a graph event.
+func (w *eventWriter) handle(_ context.Context, event pocket.Event) {
+	e := runEvent{
+		Time:       event.Time,
+		Type:       string(event.Type),
+		RunID:      event.RunID,
+		Node:       event.Node,
+		Action:     event.Action,
+		Next:       event.Next,
+		Attempt:    event.Attempt,
+		DurationMS: durationMS(event.Duration),
+		Output:     event.Output,
+	}
+	if event.Err != nil {
+		e.Error = event.Err.Error()
+	}
+	w.write(e)
+}
+
+// end writes the run_end event with the run's result.
+func (w *eventWriter) end(duration time.Duration, output interface{}, err error) {
+	e := runEvent{
+		Time:       time.Now(),
+		Type:       runEndEvent,
+		DurationMS: durationMS(duration),
+		Output:     output,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	w.write(e)
+}
+
+func (w *eventWriter) write(e runEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		// Outputs that aren't JSON are written as text
+		e.Output = fmt.Sprint(e.Output)
+		if line, err = json.Marshal(e); err != nil {
+			return
+		}
+	}
+	_, _ = fmt.Fprintf(w.out, "%s\n", line)
+}
+
+func durationMS(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket"
+)
+
+func TestEventWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newEventWriter(&out)
+	ctx := context.Background()
+
+	w.handle(ctx, pocket.Event{Type: pocket.EventNodeStart, Node: "fetch"})
+	w.handle(ctx, pocket.Event{Type: pocket.EventRetry, Node: "fetch", Attempt: 1, Err: errors.New("timeout")})
+	w.handle(ctx, pocket.Event{Type: pocket.EventNodeEnd, Node: "fetch", Duration: 1500 * time.Microsecond, Output: map[string]interface{}{"ok": true}})
+	w.handle(ctx, pocket.Event{Type: pocket.EventRoute, Node: "fetch", Action: "default", Next: "notify"})
+	w.handle(ctx, pocket.Event{Type: pocket.EventNodeEnd, Node: "notify", Output: make(chan int)})
+	w.end(2*time.Millisecond, "done", nil)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 events, got %d:\n%s", len(lines), out.String())
+	}
+
+	var events []runEvent
+	for _, line := range lines {
+		var e runEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+
+	if e := events[1]; e.Type != "retry" || e.Attempt != 1 || e.Error != "timeout" {
+		t.Errorf("Expected retry of attempt 1 with error, got %+v", e)
+	}
+	if e := events[2]; e.DurationMS != 1.5 || e.Output.(map[string]interface{})["ok"] != true {
+		t.Errorf("Expected node_end with duration and output, got %+v", e)
+	}
+	if e := events[3]; e.Type != "route" || e.Action != "default" || e.Next != "notify" {
+		t.Errorf("Expected route to notify, got %+v", e)
+	}
+	if e := events[4]; e.Output == nil {
+		t.Error("Expected output that isn't JSON to be written as text")
+	}
+	if e := events[5]; e.Type != runEndEvent || e.Output != "done" {
+		t.Errorf("Expected run_end with the result, got %+v", e)
+	}
+}
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	goyaml "github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/pocket/nodes"
+	"github.com/agentstation/pocket/yaml"
+)
+
+// lspCmd represents the lsp command.
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server for workflow files",
+	Long: `Run a Language Server Protocol server for workflow YAML files over
+stdin and stdout. Configure your editor to start it for workflow files.
+
+The server provides:
+  - Completion of built-in and plugin node types, with their descriptions
+  - Completion of config properties from each node type's config schema
+  - Completion of node names in start, from, and to fields
+  - Diagnostics for schema violations and routes to missing nodes
+  - Hover documentation for node types and config properties`,
+	Example: `  # Start the language server (normally run by an editor)
+  pocket lsp`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		nodeList, _ := getAllNodes()
+		return newLSPServer(os.Stdin, os.Stdout, nodeList).serve()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
+
+// LSP constants used by the server.
+const (
+	lspSyncFull          = 1
+	lspSeverityError     = 1
+	lspSeverityWarning   = 2
+	lspCompletionField   = 5
+	lspCompletionClass   = 7
+	lspCompletionRefKind = 18
+	lspMethodNotFound    = -32601
+	lspParseError        = -32700
+)
+
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type lspResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  any              `json:"result"`
+}
+
+type lspErrorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   lspError         `json:"error"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspMarkup struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type lspCompletionItem struct {
+	Label         string     `json:"label"`
+	Kind          int        `json:"kind"`
+	Detail        string     `json:"detail,omitempty"`
+	Documentation *lspMarkup `json:"documentation,omitempty"`
+}
+
+type lspHover struct {
+	Contents lspMarkup `json:"contents"`
+}
+
+type lspTextDocument struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type lspDocumentParams struct {
+	TextDocument   lspTextDocument `json:"textDocument"`
+	Position       lspPosition     `json:"position"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// lspServer is a minimal language server for workflow YAML. Documents are
+// synced in full on every change. Positions are treated as byte offsets,
+// which matches the UTF-16 offsets clients send for ASCII text.
+type lspServer struct {
+	in      *bufio.Reader
+	out     io.Writer
+	docs    map[string]string
+	catalog map[string]nodes.Metadata
+}
+
+func newLSPServer(in io.Reader, out io.Writer, nodeList []nodes.Metadata) *lspServer {
+	catalog := make(map[string]nodes.Metadata, len(nodeList))
+	for _, node := range nodeList {
+		catalog[node.Type] = node
+	}
+	return &lspServer{
+		in:      bufio.NewReader(in),
+		out:     out,
+		docs:    make(map[string]string),
+		catalog: catalog,
+	}
+}
+
+// serve handles messages until the client sends exit or closes the input.
+func (s *lspServer) serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if msg == nil {
+				return err
+			}
+			if err := s.write(lspErrorResponse{JSONRPC: "2.0", Error: lspError{Code: lspParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads one message. A non-nil message is returned with an error when
+// the frame was read but its body is not valid JSON.
+func (s *lspServer) read() (*lspMessage, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %w", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+
+	var msg lspMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return &msg, err
+	}
+	return &msg, nil
+}
+
+func (s *lspServer) write(msg any) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *lspServer) reply(msg *lspMessage, result any) error {
+	if msg.ID == nil {
+		return nil
+	}
+	return s.write(lspResponse{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (s *lspServer) handle(msg *lspMessage) error {
+	var params lspDocumentParams
+	if len(msg.Params) > 0 {
+		// Unknown methods may have other shapes; they are ignored below
+		_ = json.Unmarshal(msg.Params, &params)
+	}
+	uri := params.TextDocument.URI
+
+	switch msg.Method {
+	case "initialize":
+		return s.reply(msg, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   lspSyncFull,
+				"completionProvider": map[string]any{"triggerCharacters": []string{":", " "}},
+				"hoverProvider":      true,
+			},
+			"serverInfo": map[string]any{"name": "pocket", "version": version},
+		})
+	case "initialized":
+		return nil
+	case "shutdown":
+		return s.reply(msg, nil)
+	case "textDocument/didOpen":
+		s.docs[uri] = params.TextDocument.Text
+		return s.publishDiagnostics(uri)
+	case "textDocument/didChange":
+		if n := len(params.ContentChanges); n > 0 {
+			s.docs[uri] = params.ContentChanges[n-1].Text
+		}
+		return s.publishDiagnostics(uri)
+	case "textDocument/didClose":
+		delete(s.docs, uri)
+		return s.write(lspNotification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+			Params: map[string]any{"uri": uri, "diagnostics": []lspDiagnostic{}}})
+	case "textDocument/completion":
+		return s.reply(msg, s.complete(s.docs[uri], params.Position))
+	case "textDocument/hover":
+		hover := s.hover(s.docs[uri], params.Position)
+		if hover == nil {
+			return s.reply(msg, nil)
+		}
+		return s.reply(msg, hover)
+	}
+
+	if msg.ID != nil {
+		return s.write(lspErrorResponse{JSONRPC: "2.0", ID: msg.ID,
+			Error: lspError{Code: lspMethodNotFound, Message: "method not found: " + msg.Method}})
+	}
+	return nil
+}
+
+// publishDiagnostics reports the problems in a document.
+func (s *lspServer) publishDiagnostics(uri string) error {
+	text := s.docs[uri]
+	return s.write(lspNotification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics",
+		Params: map[string]any{"uri": uri, "diagnostics": s.diagnose(text)}})
+}
+
+func (s *lspServer) diagnose(text string) []lspDiagnostic {
+	lines := strings.Split(text, "\n")
+	diagnostics := []lspDiagnostic{}
+
+	add := func(v Violation, severity int) {
+		message := v.Message
+		if v.Path != "" {
+			message = v.Path + ": " + message
+		}
+		diagnostics = append(diagnostics, lspDiagnostic{
+			Range:    violationRange(lines, v),
+			Severity: severity,
+			Source:   "pocket",
+			Message:  message,
+		})
+	}
+
+	violations, err := validateWorkflow([]byte(text))
+	if err != nil {
+		add(Violation{Message: err.Error()}, lspSeverityError)
+		return diagnostics
+	}
+	for _, v := range violations {
+		add(v, lspSeverityError)
+	}
+	if len(violations) > 0 {
+		return diagnostics
+	}
+
+	// Node types may come from plugins that aren't installed here, so
+	// unknown ones are warnings
+	for _, v := range s.unknownTypes(text) {
+		add(v, lspSeverityWarning)
+	}
+	return diagnostics
+}
+
+// unknownTypes reports nodes whose type is not in the catalog.
+func (s *lspServer) unknownTypes(text string) []Violation {
+	var graphDef yaml.GraphDefinition
+	if err := goyaml.Unmarshal([]byte(text), &graphDef); err != nil {
+		return nil
+	}
+
+	lines := strings.Split(text, "\n")
+	var violations []Violation
+	for _, node := range graphDef.Nodes {
+		if _, ok := s.catalog[node.Type]; ok {
+			continue
+		}
+		v := Violation{Message: fmt.Sprintf("unknown node type %s", node.Type)}
+		// Find the type line within the node's item
+		for i, line := range lines {
+			if key, value, ok := yamlKeyValue(line); ok && key == "type" && value == node.Type {
+				v.Line, v.Column = i+1, strings.Index(line, value)+1
+				break
+			}
+		}
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// violationRange spans from a violation's position to the end of its line.
+func violationRange(lines []string, v Violation) lspRange {
+	if v.Line <= 0 || v.Line > len(lines) {
+		return lspRange{}
+	}
+	line := v.Line - 1
+	start := max(v.Column-1, 0)
+	return lspRange{
+		Start: lspPosition{Line: line, Character: start},
+		End:   lspPosition{Line: line, Character: max(len(strings.TrimRight(lines[line], "\r")), start)},
+	}
+}
+
+// complete returns completion items for the position, based on the key
+// being edited.
+func (s *lspServer) complete(text string, pos lspPosition) []lspCompletionItem {
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) {
+		return []lspCompletionItem{}
+	}
+	line := lines[pos.Line]
+	prefix := line[:min(pos.Character, len(line))]
+
+	items := []lspCompletionItem{}
+	if key, _, ok := yamlKeyValue(prefix); ok {
+		switch key {
+		case "type":
+			for _, nodeType := range sortedKeys(s.catalog) {
+				meta := s.catalog[nodeType]
+				items = append(items, lspCompletionItem{
+					Label:         nodeType,
+					Kind:          lspCompletionClass,
+					Detail:        meta.Category,
+					Documentation: &lspMarkup{Kind: "markdown", Value: nodeDoc(meta)},
+				})
+			}
+		case "start", "from", "to":
+			for _, name := range nodeNames(lines) {
+				items = append(items, lspCompletionItem{Label: name, Kind: lspCompletionRefKind})
+			}
+		}
+		return items
+	}
+
+	// Completing a key: offer config properties when inside a config block
+	meta, ok := s.configNodeType(lines, pos.Line, indentOf(prefix))
+	if !ok {
+		return items
+	}
+	properties, _ := meta.ConfigSchema["properties"].(map[string]interface{})
+	for _, name := range sortedKeys(properties) {
+		item := lspCompletionItem{Label: name, Kind: lspCompletionField}
+		if prop, ok := properties[name].(map[string]interface{}); ok {
+			item.Detail = strings.Join(schemaStrings(prop["type"]), " | ")
+			if desc, ok := prop["description"].(string); ok {
+				item.Documentation = &lspMarkup{Kind: "markdown", Value: desc}
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// hover documents the node type or config property under the position.
+func (s *lspServer) hover(text string, pos lspPosition) *lspHover {
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) {
+		return nil
+	}
+	line := lines[pos.Line]
+
+	key, value, ok := yamlKeyValue(line)
+	if !ok {
+		return nil
+	}
+	if key == "type" {
+		if meta, ok := s.catalog[value]; ok {
+			return &lspHover{Contents: lspMarkup{Kind: "markdown", Value: nodeDoc(meta)}}
+		}
+		return nil
+	}
+
+	meta, ok := s.configNodeType(lines, pos.Line, indentOf(line))
+	if !ok {
+		return nil
+	}
+	properties, _ := meta.ConfigSchema["properties"].(map[string]interface{})
+	prop, ok := properties[key].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	doc := fmt.Sprintf("**%s** (%s config)", key, meta.Type)
+	if types := schemaStrings(prop["type"]); len(types) > 0 {
+		doc += fmt.Sprintf(" `%s`", strings.Join(types, " | "))
+	}
+	if desc, ok := prop["description"].(string); ok {
+		doc += "\n\n" + desc
+	}
+	if values := schemaStrings(prop["enum"]); len(values) > 0 {
+		doc += "\n\nOne of: `" + strings.Join(values, "`, `") + "`"
+	}
+	if def, ok := prop["default"]; ok {
+		doc += fmt.Sprintf("\n\nDefault: `%v`", def)
+	}
+	return &lspHover{Contents: lspMarkup{Kind: "markdown", Value: doc}}
+}
+
+// configNodeType returns the metadata of the node whose config block
+// contains the given line, found by walking up the indentation.
+func (s *lspServer) configNodeType(lines []string, lineNum, indent int) (nodes.Metadata, bool) {
+	// Find the parent key: the nearest line above that is indented less
+	parent := -1
+	for i := lineNum - 1; i >= 0; i-- {
+		if isBlankYAML(lines[i]) {
+			continue
+		}
+		if indentOf(lines[i]) < indent {
+			parent = i
+			break
+		}
+	}
+	if parent < 0 {
+		return nodes.Metadata{}, false
+	}
+	if key, value, ok := yamlKeyValue(lines[parent]); !ok || key != "config" || value != "" {
+		return nodes.Metadata{}, false
+	}
+
+	// Search the node's item, the lines around config at the same key
+	// indentation, for its type
+	keyIndent := keyColumn(lines[parent])
+	for _, step := range []int{-1, 1} {
+		for i := parent; i >= 0 && i < len(lines); i += step {
+			if isBlankYAML(lines[i]) {
+				continue
+			}
+			col := keyColumn(lines[i])
+			if col < keyIndent {
+				break
+			}
+			itemStart := i != parent && col == keyIndent && strings.HasPrefix(strings.TrimSpace(lines[i]), "- ")
+			if itemStart && step > 0 {
+				// Start of the next item
+				break
+			}
+			if key, value, ok := yamlKeyValue(lines[i]); ok && col == keyIndent && key == "type" {
+				meta, ok := s.catalog[value]
+				return meta, ok
+			}
+			if itemStart {
+				break
+			}
+		}
+	}
+	return nodes.Metadata{}, false
+}
+
+// nodeDoc renders a node type's description and config properties.
+func nodeDoc(meta nodes.Metadata) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** (%s)\n\n%s", meta.Type, meta.Category, meta.Description)
+
+	properties, _ := meta.ConfigSchema["properties"].(map[string]interface{})
+	if len(properties) > 0 {
+		required := map[string]bool{}
+		for _, name := range schemaStrings(meta.ConfigSchema["required"]) {
+			required[name] = true
+		}
+
+		b.WriteString("\n\nConfig:\n")
+		for _, name := range sortedKeys(properties) {
+			fmt.Fprintf(&b, "\n- `%s`", name)
+			if required[name] {
+				b.WriteString(" (required)")
+			}
+			if prop, ok := properties[name].(map[string]interface{}); ok {
+				if desc, ok := prop["description"].(string); ok {
+					b.WriteString(": " + desc)
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// nodeNames returns the names of the nodes defined in the document.
+func nodeNames(lines []string) []string {
+	var graphDef yaml.GraphDefinition
+	if err := goyaml.Unmarshal([]byte(strings.Join(lines, "\n")), &graphDef); err == nil {
+		names := make([]string, 0, len(graphDef.Nodes))
+		for _, node := range graphDef.Nodes {
+			names = append(names, node.Name)
+		}
+		return names
+	}
+
+	// The document may not parse while being edited; fall back to name keys
+	var names []string
+	for _, line := range lines {
+		if key, value, ok := yamlKeyValue(line); ok && key == "name" && strings.HasPrefix(strings.TrimSpace(line), "- ") {
+			names = append(names, value)
+		}
+	}
+	return names
+}
+
+// yamlKeyValue splits a "key: value" line, ignoring a leading "- ".
+func yamlKeyValue(line string) (key, value string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	trimmed = strings.TrimPrefix(trimmed, "- ")
+	key, value, ok = strings.Cut(trimmed, ":")
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+	value = strings.TrimSpace(value)
+	value = strings.Trim(value, `"'`)
+	return key, value, true
+}
+
+// keyColumn returns the column of a line's key, after any "- ".
+func keyColumn(line string) int {
+	col := indentOf(line)
+	rest := line[col:]
+	for strings.HasPrefix(rest, "- ") {
+		rest = strings.TrimLeft(rest[2:], " ")
+		col = len(line) - len(rest)
+	}
+	return col
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func isBlankYAML(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const lspTestWorkflow = `name: demo
+start: fetch
+nodes:
+  - name: fetch
+    type: http
+    config:
+      ` + `
+  - name: show
+    type: echo
+connections:
+  - from: fetch
+    to: missing
+`
+
+// lspExchange sends requests to a server and returns its messages.
+func lspExchange(t *testing.T, requests ...map[string]any) []map[string]any {
+	t.Helper()
+
+	var in bytes.Buffer
+	for _, req := range requests {
+		req["jsonrpc"] = "2.0"
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	var out bytes.Buffer
+	if err := newLSPServer(&in, &out, getBuiltinNodes()).serve(); err != nil {
+		t.Fatalf("serve() error = %v", err)
+	}
+
+	var messages []map[string]any
+	reader := bufio.NewReader(&out)
+	for {
+		header, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return messages
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+		if err != nil {
+			t.Fatalf("Bad header %q", header)
+		}
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			t.Fatal(err)
+		}
+		var msg map[string]any
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func lspPositionParams(line, character int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": "file:///demo.yaml"},
+		"position":     map[string]any{"line": line, "character": character},
+	}
+}
+
+func TestLSPServer(t *testing.T) {
+	messages := lspExchange(t,
+		map[string]any{"id": 1, "method": "initialize", "params": map[string]any{}},
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": "file:///demo.yaml", "text": lspTestWorkflow},
+		}},
+		map[string]any{"id": 2, "method": "textDocument/completion", "params": lspPositionParams(4, 10)},
+		map[string]any{"id": 3, "method": "textDocument/completion", "params": lspPositionParams(6, 6)},
+		map[string]any{"id": 4, "method": "textDocument/completion", "params": lspPositionParams(1, 7)},
+		map[string]any{"id": 5, "method": "textDocument/hover", "params": lspPositionParams(8, 11)},
+		map[string]any{"id": 6, "method": "shutdown"},
+		map[string]any{"method": "exit"},
+	)
+	if len(messages) != 7 {
+		t.Fatalf("Expected 7 messages, got %d: %v", len(messages), messages)
+	}
+
+	labels := func(msg map[string]any) []string {
+		var labels []string
+		for _, item := range msg["result"].([]any) {
+			labels = append(labels, item.(map[string]any)["label"].(string))
+		}
+		return labels
+	}
+
+	t.Run("initialize", func(t *testing.T) {
+		caps := messages[0]["result"].(map[string]any)["capabilities"].(map[string]any)
+		if caps["hoverProvider"] != true || caps["completionProvider"] == nil {
+			t.Errorf("Unexpected capabilities: %v", caps)
+		}
+	})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		params := messages[1]["params"].(map[string]any)
+		diagnostics := params["diagnostics"].([]any)
+		if len(diagnostics) != 1 {
+			t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+		}
+		diag := diagnostics[0].(map[string]any)
+		start := diag["range"].(map[string]any)["start"].(map[string]any)
+		if start["line"] != float64(11) || !strings.Contains(diag["message"].(string), "missing") {
+			t.Errorf("Unexpected diagnostic: %v", diag)
+		}
+	})
+
+	t.Run("node type completion", func(t *testing.T) {
+		got := strings.Join(labels(messages[2]), ",")
+		for _, want := range []string{"echo", "http", "transform"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("Expected %s in completions %s", want, got)
+			}
+		}
+	})
+
+	t.Run("config completion", func(t *testing.T) {
+		got := strings.Join(labels(messages[3]), ",")
+		for _, want := range []string{"url", "method", "headers"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("Expected %s in completions %s", want, got)
+			}
+		}
+	})
+
+	t.Run("node name completion", func(t *testing.T) {
+		if got := labels(messages[4]); strings.Join(got, ",") != "fetch,show" {
+			t.Errorf("Expected node names, got %v", got)
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		contents := messages[5]["result"].(map[string]any)["contents"].(map[string]any)
+		if !strings.Contains(contents["value"].(string), "**echo**") {
+			t.Errorf("Unexpected hover: %v", contents)
+		}
+	})
+}
+
+func TestLSPUnknownNodeType(t *testing.T) {
+	server := newLSPServer(nil, nil, getBuiltinNodes())
+	diagnostics := server.diagnose(`name: demo
+start: first
+nodes:
+  - name: first
+    type: nonexistent
+`)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	if diagnostics[0].Severity != lspSeverityWarning || diagnostics[0].Range.Start.Line != 4 {
+		t.Errorf("Unexpected diagnostic: %+v", diagnostics[0])
+	}
+}
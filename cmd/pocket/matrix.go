@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	goyaml "github.com/goccy/go-yaml"
+
+	"github.com/agentstation/pocket/nodes"
+	"github.com/agentstation/pocket/yaml"
+)
+
+// MatrixRun is the result of running a workflow with one combination of
+// matrix values.
+type MatrixRun struct {
+	Params   map[string]interface{} `json:"params" yaml:"params"`
+	Output   interface{}            `json:"output,omitempty" yaml:"output,omitempty"`
+	Error    string                 `json:"error,omitempty" yaml:"error,omitempty"`
+	Duration time.Duration          `json:"duration" yaml:"duration"`
+}
+
+// loadMatrix reads a matrix file, mapping config keys to the values to
+// run with. A key with a single value rather than a list runs with it in
+// every combination.
+func loadMatrix(path string) (map[string][]interface{}, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // User-provided matrix file
+	if err != nil {
+		return nil, fmt.Errorf("read matrix: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := goyaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse matrix %s: %w", path, err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("matrix %s has no parameters", path)
+	}
+
+	matrix := make(map[string][]interface{}, len(raw))
+	for key, value := range raw {
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("matrix parameter %s has no values", key)
+		}
+		matrix[key] = values
+	}
+	return matrix, nil
+}
+
+// matrixCombinations returns every combination of matrix values, varying
+// the last key in sorted order fastest.
+func matrixCombinations(matrix map[string][]interface{}) []map[string]interface{} {
+	keys := sortedKeys(matrix)
+	combinations := []map[string]interface{}{{}}
+	for _, key := range keys {
+		next := make([]map[string]interface{}, 0, len(combinations)*len(matrix[key]))
+		for _, combination := range combinations {
+			for _, value := range matrix[key] {
+				params := make(map[string]interface{}, len(combination)+1)
+				for k, v := range combination {
+					params[k] = v
+				}
+				params[key] = value
+				next = append(next, params)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// runMatrix runs a workflow once per combination of the values in the
+// matrix file, set as workflow config values, and reports the results.
+func runMatrix(config *RunConfig, graphDef *yaml.GraphDefinition) error {
+	matrix, err := loadMatrix(config.MatrixFile)
+	if err != nil {
+		return err
+	}
+	combinations := matrixCombinations(matrix)
+
+	parallel := config.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	if config.Verbose {
+		log.Printf("Running %d combinations, %d at a time", len(combinations), parallel)
+	}
+
+	loader := yaml.NewLoader()
+	nodes.RegisterAll(loader, config.Verbose)
+
+	runs := make([]MatrixRun, len(combinations))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, params := range combinations {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			runs[i] = runMatrixCombination(config, loader, graphDef, i, params)
+		}()
+	}
+	wg.Wait()
+
+	if err := printMatrixReport(runs, config.Format); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, run := range runs {
+		if run.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d matrix run(s) failed", failed, len(runs))
+	}
+	return nil
+}
+
+// runMatrixCombination runs the workflow with one combination of values,
+// against its own store.
+func runMatrixCombination(config *RunConfig, loader *yaml.Loader, graphDef *yaml.GraphDefinition, index int, params map[string]interface{}) MatrixRun {
+	run := MatrixRun{Params: params}
+
+	// Each run gets its own copy of the config to set values in
+	def := *graphDef
+	def.Config = nil
+	def.MergeConfig(copyConfig(graphDef.Config))
+	for key, value := range params {
+		def.SetConfig(key, value)
+	}
+
+	store, err := newRunStore(config)
+	if err != nil {
+		run.Error = err.Error()
+		return run
+	}
+	graph, err := loader.LoadDefinition(&def, store)
+	if err != nil {
+		run.Error = fmt.Sprintf("load workflow: %v", err)
+		return run
+	}
+
+	ctx := nodes.WithLineHandler(context.Background(), func(node, line string) {
+		fmt.Fprintf(os.Stderr, "[#%d %s] %s\n", index+1, node, line)
+	})
+
+	start := time.Now()
+	run.Output, err = graph.Run(ctx, nil)
+	run.Duration = time.Since(start)
+	if err != nil {
+		run.Error = err.Error()
+	}
+	return run
+}
+
+// copyConfig deep-copies the maps and lists of a workflow config.
+func copyConfig(config map[string]interface{}) map[string]interface{} {
+	if config == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		copied[key] = copyConfigValue(value)
+	}
+	return copied
+}
+
+func copyConfigValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return copyConfig(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = copyConfigValue(item)
+		}
+		return list
+	}
+	return value
+}
+
+// printMatrixReport writes the results of a matrix run in the output
+// format.
+func printMatrixReport(runs []MatrixRun, format string) error {
+	switch format {
+	case jsonFormat:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(runs)
+	case yamlFormat:
+		data, err := goyaml.Marshal(runs)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tPARAMS\tSTATUS\tDURATION\tRESULT")
+	var total time.Duration
+	for i, run := range runs {
+		status, result := "ok", formatMatrixOutput(run.Output)
+		if run.Error != "" {
+			status, result = "failed", run.Error
+		}
+		total += run.Duration
+		fmt.Fprintf(w, "%d\t%s\t%s\t%v\t%s\n", i+1, formatMatrixParams(run.Params), status, run.Duration.Round(time.Millisecond), result)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, run := range runs {
+		if run.Error != "" {
+			failed++
+		}
+	}
+	avg := time.Duration(0)
+	if len(runs) > 0 {
+		avg = total / time.Duration(len(runs))
+	}
+	fmt.Printf("\n%d runs: %d succeeded, %d failed, average %v\n", len(runs), len(runs)-failed, failed, avg.Round(time.Millisecond))
+	return nil
+}
+
+// formatMatrixParams formats the values of a combination in key order.
+func formatMatrixParams(params map[string]interface{}) string {
+	keys := sortedKeys(params)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", key, params[key])
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatMatrixOutput summarizes a run's output on one line.
+func formatMatrixOutput(output interface{}) string {
+	if output == nil {
+		return ""
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		data = []byte(fmt.Sprint(output))
+	}
+	const maxLen = 60
+	if s := string(data); len(s) > maxLen {
+		return s[:maxLen-3] + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/pocket/nodes"
+	"github.com/agentstation/pocket/yaml"
+)
+
+func TestMatrixCombinations(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "params.yaml")
+	writeTestFile(t, path, "model: [small, large]\ntemperature: [0, 0.7]\nprompt: v2\n")
+
+	matrix, err := loadMatrix(path)
+	if err != nil {
+		t.Fatalf("loadMatrix() error = %v", err)
+	}
+
+	got := matrixCombinations(matrix)
+	want := []map[string]interface{}{
+		{"model": "small", "prompt": "v2", "temperature": uint64(0)},
+		{"model": "small", "prompt": "v2", "temperature": 0.7},
+		{"model": "large", "prompt": "v2", "temperature": uint64(0)},
+		{"model": "large", "prompt": "v2", "temperature": 0.7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("matrixCombinations() = %v, want %v", got, want)
+	}
+
+	writeTestFile(t, path, "model: []\n")
+	if _, err := loadMatrix(path); err == nil {
+		t.Error("loadMatrix() succeeded, want error for a parameter without values")
+	}
+}
+
+func TestRunMatrixCombination(t *testing.T) {
+	graphDef := &yaml.GraphDefinition{
+		Name:   "prompt",
+		Start:  "ask",
+		Config: map[string]interface{}{"llm": map[string]interface{}{"model": "default", "retries": 2}},
+		Nodes: []yaml.NodeDefinition{
+			{
+				Name:   "ask",
+				Type:   "echo",
+				Config: map[string]interface{}{"message": "{{.workflow.config.llm.model}} at {{.workflow.config.temperature}}"},
+			},
+		},
+	}
+
+	loader := yaml.NewLoader()
+	nodes.RegisterAll(loader, false)
+	config := &RunConfig{StoreType: "memory"}
+
+	run := runMatrixCombination(config, loader, graphDef, 0, map[string]interface{}{"llm.model": "large", "temperature": 0.7})
+	if run.Error != "" {
+		t.Fatalf("Run error = %s", run.Error)
+	}
+	if msg := run.Output.(map[string]interface{})["message"]; msg != "large at 0.7" {
+		t.Errorf("message = %v, want %q", msg, "large at 0.7")
+	}
+	if model := graphDef.Config["llm"].(map[string]interface{})["model"]; model != "default" {
+		t.Errorf("Base config model = %v, want it unchanged", model)
+	}
+
+	run = runMatrixCombination(config, loader, graphDef, 1, map[string]interface{}{"llm.model": "small"})
+	if !strings.Contains(run.Error, "workflow config temperature is not defined") {
+		t.Errorf("Run error = %q, want undefined config error", run.Error)
+	}
+
+	if got := formatMatrixParams(map[string]interface{}{"b": 2, "a": "x"}); got != "a=x b=2" {
+		t.Errorf("formatMatrixParams() = %q, want %q", got, "a=x b=2")
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// Runtime constants for generated plugins.
+const (
+	wasmRuntime = "wasm"
+	grpcRuntime = "grpc"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Patterns for generated names.
+var (
+	scaffoldName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	packageName  = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// New command flags.
+var (
+	newRuntime  string
+	newModule   string
+	newPackage  string
+	newCategory string
+	newDir      string
+)
+
+// newCmd represents the new command.
+var newCmd = &cobra.Command{
+	Use:   "new",
+	Short: "Generate plugin and node skeletons",
+	Long: `Generate skeletons for new plugins and node types.
+
+Generated code builds and passes its tests as-is, so you can start from a
+working node and change it to do what you need.`,
+	Example: `  # Create a WebAssembly plugin in ./sentiment
+  pocket new plugin sentiment
+
+  # Create a node builder in the current directory
+  pocket new node word-count --package mynodes`,
+}
+
+// newPluginCmd represents the new plugin command.
+var newPluginCmd = &cobra.Command{
+	Use:   "plugin <name>",
+	Short: "Generate a plugin",
+	Long: `Generate a plugin with a manifest, a Go implementation of one node,
+exports for the Pocket host, tests, and a Makefile.
+
+The plugin builds with TinyGo (make build) or Go 1.24+ (make go-build) and
+installs to ~/.pocket/plugins with make install.`,
+	Example: `  # Create ./sentiment
+  pocket new plugin sentiment
+
+  # Choose the directory and Go module path
+  pocket new plugin sentiment --dir plugins/sentiment --module example.com/sentiment`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := &NewConfig{
+			Name:    args[0],
+			Runtime: newRuntime,
+			Module:  newModule,
+			Dir:     newDir,
+		}
+		if config.Dir == "" {
+			config.Dir = config.Name
+		}
+		return runNewPlugin(config)
+	},
+}
+
+// newNodeCmd represents the new node command.
+var newNodeCmd = &cobra.Command{
+	Use:   "node <type>",
+	Short: "Generate a node builder",
+	Long: `Generate a node builder with metadata, a config schema, an example,
+and a test that checks the example against the builder.
+
+Register the builder with a nodes.Registry to use it in workflows.`,
+	Example: `  # Create word_count.go and word_count_test.go
+  pocket new node word-count
+
+  # Generate into another package
+  pocket new node word-count --dir internal/mynodes --package mynodes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := &NewConfig{
+			Name:     args[0],
+			Package:  newPackage,
+			Category: newCategory,
+			Dir:      newDir,
+		}
+		if config.Dir == "" {
+			config.Dir = "."
+		}
+		return runNewNode(config)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(newCmd)
+	newCmd.AddCommand(newPluginCmd)
+	newCmd.AddCommand(newNodeCmd)
+
+	newPluginCmd.Flags().StringVar(&newRuntime, "runtime", wasmRuntime, "Plugin runtime (wasm, grpc)")
+	newPluginCmd.Flags().StringVar(&newModule, "module", "", "Go module path (defaults to the plugin name)")
+	newPluginCmd.Flags().StringVar(&newDir, "dir", "", "Output directory (defaults to ./<name>)")
+
+	newNodeCmd.Flags().StringVar(&newPackage, "package", "nodes", "Go package name")
+	newNodeCmd.Flags().StringVar(&newCategory, "category", "custom", "Node category")
+	newNodeCmd.Flags().StringVar(&newDir, "dir", "", "Output directory (defaults to the current directory)")
+}
+
+// NewConfig holds configuration for the new commands.
+type NewConfig struct {
+	Name     string
+	Runtime  string
+	Module   string
+	Package  string
+	Category string
+	Dir      string
+}
+
+// scaffoldData is the data the templates are rendered with.
+type scaffoldData struct {
+	Name        string
+	Ident       string
+	Description string
+	Module      string
+	Package     string
+	Category    string
+}
+
+// runNewPlugin generates a plugin skeleton.
+func runNewPlugin(config *NewConfig) error {
+	if !scaffoldName.MatchString(config.Name) {
+		return fmt.Errorf("invalid plugin name %q: use lowercase letters, digits, and dashes", config.Name)
+	}
+	switch config.Runtime {
+	case wasmRuntime:
+	case grpcRuntime:
+		return fmt.Errorf("runtime %s is not supported by the plugin loader yet", grpcRuntime)
+	default:
+		return fmt.Errorf("unknown runtime %q (expected %s or %s)", config.Runtime, wasmRuntime, grpcRuntime)
+	}
+
+	if entries, err := os.ReadDir(config.Dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("directory %s already exists and is not empty", config.Dir)
+	}
+
+	data := &scaffoldData{
+		Name:        config.Name,
+		Ident:       goIdent(config.Name),
+		Description: fmt.Sprintf("The %s plugin for Pocket", config.Name),
+		Module:      config.Module,
+	}
+	if data.Module == "" {
+		data.Module = config.Name
+	}
+
+	files, err := renderTemplates("templates/plugin", data, func(name string) string { return name })
+	if err != nil {
+		return err
+	}
+	if err := writeScaffold(config.Dir, files); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created plugin %s in %s\n", config.Name, config.Dir)
+	fmt.Println("\nNext steps:")
+	fmt.Printf("  cd %s\n", config.Dir)
+	fmt.Println("  make test")
+	fmt.Println("  make build install")
+	return nil
+}
+
+// runNewNode generates a node builder and its test.
+func runNewNode(config *NewConfig) error {
+	if !scaffoldName.MatchString(config.Name) {
+		return fmt.Errorf("invalid node type %q: use lowercase letters, digits, and dashes", config.Name)
+	}
+	if !packageName.MatchString(config.Package) {
+		return fmt.Errorf("invalid package name %q", config.Package)
+	}
+
+	data := &scaffoldData{
+		Name:     config.Name,
+		Ident:    goIdent(config.Name),
+		Package:  config.Package,
+		Category: config.Category,
+	}
+	base := strings.ReplaceAll(config.Name, "-", "_")
+	files, err := renderTemplates("templates/node", data, func(name string) string {
+		return strings.Replace(name, "node", base, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	for name := range files {
+		if _, err := os.Stat(filepath.Join(config.Dir, name)); err == nil {
+			return fmt.Errorf("file %s already exists", filepath.Join(config.Dir, name))
+		}
+	}
+	if err := writeScaffold(config.Dir, files); err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(files) {
+		fmt.Printf("Created %s\n", filepath.Join(config.Dir, name))
+	}
+	return nil
+}
+
+// renderTemplates renders every template in a directory, naming each output
+// file by trimming .tmpl and applying rename.
+func renderTemplates(dir string, data *scaffoldData, rename func(string) string) (map[string][]byte, error) {
+	entries, err := fs.ReadDir(templateFS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		tmpl, err := template.ParseFS(templateFS, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("parse template %s: %w", entry.Name(), err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("render template %s: %w", entry.Name(), err)
+		}
+		files[rename(strings.TrimSuffix(entry.Name(), ".tmpl"))] = buf.Bytes()
+	}
+	return files, nil
+}
+
+// writeScaffold writes generated files into a directory, creating it if
+// needed.
+func writeScaffold(dir string, files map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// goIdent converts a dashed name such as word-count to WordCount.
+func goIdent(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "-") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/pocket/plugins/loader"
+)
+
+func TestNewPlugin(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "greeter")
+	captureStdout(t, func() error {
+		return runNewPlugin(&NewConfig{Name: "greeter", Runtime: wasmRuntime, Dir: dir})
+	})
+
+	for _, name := range []string{"go.mod", "Makefile", "README.md", "manifest.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+	parseGoFiles(t, dir, "plugin.go", "exports.go", "plugin_test.go")
+
+	makefile, err := os.ReadFile(filepath.Join(dir, "Makefile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(makefile), "tinygo build") || !strings.Contains(string(makefile), "-target=wasip1") {
+		t.Errorf("Makefile does not build with TinyGo:\n%s", makefile)
+	}
+
+	// The manifest must load without the binary having been built
+	metadata, err := loader.New().Discover(dir)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if len(metadata) != 1 || metadata[0].Name != "greeter" || metadata[0].Nodes[0].Type != "greeter" {
+		t.Errorf("Unexpected manifest: %+v", metadata)
+	}
+
+	if err := runNewPlugin(&NewConfig{Name: "greeter", Runtime: wasmRuntime, Dir: dir}); err == nil {
+		t.Error("Expected error generating into a non-empty directory")
+	}
+}
+
+func TestNewPluginErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config NewConfig
+		want   string
+	}{
+		{"invalid name", NewConfig{Name: "My_Plugin", Runtime: wasmRuntime}, "invalid plugin name"},
+		{"grpc runtime", NewConfig{Name: "greeter", Runtime: grpcRuntime}, "not supported"},
+		{"unknown runtime", NewConfig{Name: "greeter", Runtime: "jvm"}, "unknown runtime"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Dir = filepath.Join(t.TempDir(), "out")
+			err := runNewPlugin(&tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runNewPlugin() error = %v, want %q", err, tt.want)
+			}
+			if _, err := os.Stat(tt.config.Dir); !os.IsNotExist(err) {
+				t.Error("Expected no files to be written")
+			}
+		})
+	}
+}
+
+func TestNewNode(t *testing.T) {
+	dir := t.TempDir()
+	config := &NewConfig{Name: "word-count", Package: "mynodes", Category: "text", Dir: dir}
+	captureStdout(t, func() error {
+		return runNewNode(config)
+	})
+
+	parseGoFiles(t, dir, "word_count.go", "word_count_test.go")
+
+	source, err := os.ReadFile(filepath.Join(dir, "word_count.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package mynodes", "type WordCountNodeBuilder struct", `Type:        "word-count"`, `Category:    "text"`} {
+		if !strings.Contains(string(source), want) {
+			t.Errorf("Expected %q in generated node", want)
+		}
+	}
+
+	if err := runNewNode(config); err == nil {
+		t.Error("Expected error overwriting existing files")
+	}
+	if err := runNewNode(&NewConfig{Name: "word-count", Package: "my-nodes", Dir: dir}); err == nil {
+		t.Error("Expected error for invalid package name")
+	}
+}
+
+func TestGoIdent(t *testing.T) {
+	tests := map[string]string{
+		"greeter":     "Greeter",
+		"word-count":  "WordCount",
+		"http2-proxy": "Http2Proxy",
+	}
+	for name, want := range tests {
+		if got := goIdent(name); got != want {
+			t.Errorf("goIdent(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// parseGoFiles checks that generated Go files are syntactically valid.
+func parseGoFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	for _, name := range names {
+		if _, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.AllErrors); err != nil {
+			t.Errorf("Generated %s does not parse: %v", name, err)
+		}
+	}
+}
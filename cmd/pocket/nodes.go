@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 
@@ -10,7 +11,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/agentstation/pocket/nodes"
-	"github.com/agentstation/pocket/yaml"
+	"github.com/agentstation/pocket/plugins/wasm"
 )
 
 // nodesCmd represents the nodes command.
@@ -20,13 +21,31 @@ var nodesCmd = &cobra.Command{
 	Long: `Explore and manage Pocket node types.
 
 List all available nodes, get detailed information about specific node types,
-or generate documentation for all nodes.`,
+or generate documentation for all nodes. Nodes provided by installed plugins
+are included alongside the built-in nodes.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Default action is to list nodes
-		config := &NodesConfig{
-			Format: output,
-		}
-		return runNodesList(config)
+		return runNodesList(newNodesConfig())
+	},
+}
+
+// nodesListCmd represents the nodes list command.
+var nodesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available node types",
+	Long: `List the built-in node types and the node types provided by installed
+plugins, grouped by category.`,
+	Example: `  # List all nodes
+  pocket nodes list
+
+  # List only data nodes
+  pocket nodes list --category data
+
+  # Output as JSON
+  pocket nodes list --output json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runNodesList(newNodesConfig())
 	},
 }
 
@@ -34,7 +53,7 @@ or generate documentation for all nodes.`,
 var nodesInfoCmd = &cobra.Command{
 	Use:   "info <node-type>",
 	Short: "Show detailed information about a node type",
-	Long: `Display detailed information about a specific node type.
+	Long: `Display detailed information about a built-in or plugin node type.
 
 Shows the node's description, configuration schema, input/output schemas,
 and usage examples.`,
@@ -45,8 +64,9 @@ and usage examples.`,
   pocket nodes info transform --output json`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		nodeType := args[0]
-		return runNodesInfo(nodeType)
+		config := newNodesConfig()
+		config.Type = args[0]
+		return runNodesInfo(config)
 	},
 }
 
@@ -54,53 +74,89 @@ and usage examples.`,
 var nodesDocsCmd = &cobra.Command{
 	Use:   "docs",
 	Short: "Generate node documentation",
-	Long: `Generate comprehensive documentation for all available node types.
+	Long: `Generate comprehensive documentation for all available node types,
+including nodes provided by installed plugins.
 
-The documentation includes descriptions, schemas, and examples for each node.`,
+The documentation includes descriptions, config, input, and output schemas,
+and examples for each node, so it can be published as a catalog of the node
+types available to workflows.`,
 	Example: `  # Generate markdown documentation
   pocket nodes docs
 
   # Generate JSON documentation
-  pocket nodes docs --output json`,
+  pocket nodes docs --output json
+
+  # Write the catalog to a file
+  pocket nodes docs --file NODES.md
+
+  # Document only the I/O nodes
+  pocket nodes docs --category io`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		config := &DocsConfig{
-			Format: output,
-		}
-		return runGenerateDocs(config)
+		return runGenerateDocs(newDocsConfig())
 	},
 }
 
+var (
+	// Nodes command flags.
+	nodesCategory    string
+	nodesBuiltinOnly bool
+)
+
 func init() {
 	rootCmd.AddCommand(nodesCmd)
+	nodesCmd.AddCommand(nodesListCmd)
 	nodesCmd.AddCommand(nodesInfoCmd)
 	nodesCmd.AddCommand(nodesDocsCmd)
+	addDocsFlags(nodesDocsCmd)
+
+	for _, cmd := range []*cobra.Command{nodesCmd, nodesListCmd} {
+		cmd.Flags().StringVar(&nodesCategory, "category", "", "Only list nodes in this category")
+	}
+	for _, cmd := range []*cobra.Command{nodesCmd, nodesListCmd, nodesInfoCmd} {
+		cmd.Flags().BoolVar(&nodesBuiltinOnly, "builtin-only", false, "Leave out nodes provided by plugins")
+	}
 }
 
 // NodesConfig holds configuration for the nodes command.
 type NodesConfig struct {
-	Format string // "table", "json", "yaml"
-	Type   string // Filter by specific node type
+	Format      string // "table", "json", "yaml"
+	Type        string // Filter by specific node type
+	Category    string // Filter by category
+	BuiltinOnly bool   // Leave out plugin nodes
 }
 
-// runNodesList lists all available node types.
-func runNodesList(config *NodesConfig) error {
-	// Create a loader and registry to get all nodes
-	loader := yaml.NewLoader()
-	nodes.RegisterAll(loader, false)
+func newNodesConfig() *NodesConfig {
+	return &NodesConfig{
+		Format:      output,
+		Category:    nodesCategory,
+		BuiltinOnly: nodesBuiltinOnly,
+	}
+}
 
-	// Get all registered nodes
-	allNodes := getBuiltinNodes()
+// catalog returns the node metadata selected by the config, and the plugin
+// providing each plugin node type.
+func (c *NodesConfig) catalog() ([]nodes.Metadata, map[string]string) {
+	allNodes, providers := getBuiltinNodes(), map[string]string{}
+	if !c.BuiltinOnly {
+		allNodes, providers = getAllNodes()
+	}
 
-	// Filter by type if specified
-	if config.Type != "" {
-		filtered := []nodes.Metadata{}
-		for _, node := range allNodes {
-			if node.Type == config.Type {
-				filtered = append(filtered, node)
-			}
+	filtered := allNodes[:0]
+	for _, node := range allNodes {
+		if c.Type != "" && node.Type != c.Type {
+			continue
 		}
-		allNodes = filtered
+		if c.Category != "" && node.Category != c.Category {
+			continue
+		}
+		filtered = append(filtered, node)
 	}
+	return filtered, providers
+}
+
+// runNodesList lists all available node types.
+func runNodesList(config *NodesConfig) error {
+	allNodes, providers := config.catalog()
 
 	// Sort by category then type
 	sort.Slice(allNodes, func(i, j int) bool {
@@ -112,64 +168,92 @@ func runNodesList(config *NodesConfig) error {
 
 	switch config.Format {
 	case jsonFormat:
-		return outputJSON(allNodes)
+		return outputJSON(allNodes, providers)
 	case yamlFormat:
-		return outputYAML(allNodes)
+		return outputYAML(allNodes, providers)
 	default:
-		return outputTable(allNodes)
+		return outputTable(allNodes, providers)
 	}
 }
 
 // runNodesInfo shows detailed information about a specific node type.
-func runNodesInfo(nodeType string) error {
-	allNodes := getBuiltinNodes()
+func runNodesInfo(config *NodesConfig) error {
+	allNodes, providers := config.catalog()
+	if len(allNodes) == 0 {
+		return fmt.Errorf("node type '%s' not found", config.Type)
+	}
+	node := allNodes[0]
 
-	for _, node := range allNodes {
-		if node.Type != nodeType {
-			continue
+	switch config.Format {
+	case jsonFormat:
+		data, err := json.MarshalIndent(nodeDocs{Metadata: node, Plugin: providers[node.Type]}, "", "  ")
+		if err != nil {
+			return err
 		}
+		fmt.Println(string(data))
+		return nil
+	case yamlFormat:
+		data, err := goyaml.Marshal(nodeDocs{Metadata: node, Plugin: providers[node.Type]})
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	}
 
-		// Output detailed node information
-		fmt.Printf("Node Type: %s\n", node.Type)
-		fmt.Printf("Category: %s\n", node.Category)
-		fmt.Printf("Description: %s\n", node.Description)
+	// Output detailed node information
+	fmt.Printf("Node Type: %s\n", node.Type)
+	fmt.Printf("Category: %s\n", node.Category)
+	fmt.Printf("Description: %s\n", node.Description)
+	if plugin, ok := providers[node.Type]; ok {
+		fmt.Printf("Plugin: %s\n", plugin)
 		if node.Since != "" {
-			fmt.Printf("Since: %s\n", node.Since)
+			fmt.Printf("Version: %s\n", node.Since)
 		}
-		fmt.Println()
-
-		// Config schema
-		if len(node.ConfigSchema) > 0 {
-			fmt.Println("Configuration:")
-			schemaJSON, _ := json.MarshalIndent(node.ConfigSchema, "  ", "  ")
-			fmt.Printf("  %s\n", schemaJSON)
-			fmt.Println()
+	} else if node.Since != "" {
+		fmt.Printf("Since: %s\n", node.Since)
+	}
+	fmt.Println()
+
+	// Schemas
+	for _, section := range []struct {
+		title  string
+		schema map[string]interface{}
+	}{
+		{"Configuration", node.ConfigSchema},
+		{"Input", node.InputSchema},
+		{"Output", node.OutputSchema},
+	} {
+		if len(section.schema) == 0 {
+			continue
 		}
+		fmt.Printf("%s:\n", section.title)
+		schemaJSON, _ := json.MarshalIndent(section.schema, "  ", "  ")
+		fmt.Printf("  %s\n", schemaJSON)
+		fmt.Println()
+	}
 
-		// Examples
-		if len(node.Examples) > 0 {
-			fmt.Println("Examples:")
-			for i, example := range node.Examples {
-				fmt.Printf("  %d. %s\n", i+1, example.Name)
-				if example.Description != "" {
-					fmt.Printf("     %s\n", example.Description)
-				}
-				if len(example.Config) > 0 {
-					configYAML, _ := goyaml.Marshal(example.Config)
-					fmt.Printf("     Config:\n")
-					for _, line := range strings.Split(string(configYAML), "\n") {
-						if line != "" {
-							fmt.Printf("       %s\n", line)
-						}
+	// Examples
+	if len(node.Examples) > 0 {
+		fmt.Println("Examples:")
+		for i, example := range node.Examples {
+			fmt.Printf("  %d. %s\n", i+1, example.Name)
+			if example.Description != "" {
+				fmt.Printf("     %s\n", example.Description)
+			}
+			if len(example.Config) > 0 {
+				configYAML, _ := goyaml.Marshal(example.Config)
+				fmt.Printf("     Config:\n")
+				for _, line := range strings.Split(string(configYAML), "\n") {
+					if line != "" {
+						fmt.Printf("       %s\n", line)
 					}
 				}
 			}
 		}
-
-		return nil
 	}
 
-	return fmt.Errorf("node type '%s' not found", nodeType)
+	return nil
 }
 
 // getBuiltinNodes returns metadata for all built-in nodes.
@@ -180,21 +264,64 @@ func getBuiltinNodes() []nodes.Metadata {
 		(&nodes.DelayNodeBuilder{}).Metadata(),
 		(&nodes.RouterNodeBuilder{}).Metadata(),
 		(&nodes.ConditionalNodeBuilder{}).Metadata(),
+		(&nodes.SwitchNodeBuilder{}).Metadata(),
 		(&nodes.TransformNodeBuilder{}).Metadata(),
 		(&nodes.TemplateNodeBuilder{}).Metadata(),
 		(&nodes.JSONPathNodeBuilder{}).Metadata(),
+		(&nodes.JSONDiffNodeBuilder{}).Metadata(),
+		(&nodes.JSONPatchNodeBuilder{}).Metadata(),
 		(&nodes.ValidateNodeBuilder{}).Metadata(),
 		(&nodes.AggregateNodeBuilder{}).Metadata(),
+		(&nodes.ObjectNodeBuilder{}).Metadata(),
+		(&nodes.GenerateNodeBuilder{}).Metadata(),
+		(&nodes.DatetimeNodeBuilder{}).Metadata(),
+		(&nodes.HashNodeBuilder{}).Metadata(),
+		(&nodes.CompressNodeBuilder{}).Metadata(),
+		(&nodes.EncodeNodeBuilder{}).Metadata(),
 		(&nodes.HTTPNodeBuilder{}).Metadata(),
 		(&nodes.FileNodeBuilder{}).Metadata(),
 		(&nodes.ExecNodeBuilder{}).Metadata(),
 		(&nodes.ParallelNodeBuilder{}).Metadata(),
+		(&nodes.TryNodeBuilder{}).Metadata(),
 		(&nodes.LuaNodeBuilder{}).Metadata(),
 	}
 }
 
+// getAllNodes returns metadata for the built-in nodes followed by the nodes
+// declared by plugins in the default plugin paths, and the name of the
+// plugin providing each plugin node type. Plugins are read from their
+// manifests without being loaded. Plugin nodes whose type clashes with a
+// built-in node are skipped.
+func getAllNodes() ([]nodes.Metadata, map[string]string) {
+	allNodes := getBuiltinNodes()
+	providers := make(map[string]string)
+
+	known := make(map[string]bool, len(allNodes))
+	for _, node := range allNodes {
+		known[node.Type] = true
+	}
+
+	discovered, err := newPluginLoader().Discover()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to discover plugins: %v\n", err)
+	}
+	for _, plugin := range discovered {
+		for i := range plugin.Nodes {
+			nodeType := &plugin.Nodes[i]
+			if known[nodeType.Type] {
+				continue
+			}
+			known[nodeType.Type] = true
+			allNodes = append(allNodes, wasm.NodeMetadata(plugin, nodeType))
+			providers[nodeType.Type] = plugin.Name
+		}
+	}
+
+	return allNodes, providers
+}
+
 // outputTable outputs nodes in table format.
-func outputTable(nodeList []nodes.Metadata) error {
+func outputTable(nodeList []nodes.Metadata, providers map[string]string) error {
 	// Group by category
 	categories := make(map[string][]nodes.Metadata)
 	for _, node := range nodeList {
@@ -214,6 +341,10 @@ func outputTable(nodeList []nodes.Metadata) error {
 		fmt.Println(strings.Repeat("-", len(cat)+1))
 
 		for _, node := range categories[cat] {
+			if plugin, ok := providers[node.Type]; ok {
+				fmt.Printf("  %-20s %s (plugin: %s)\n", node.Type, node.Description, plugin)
+				continue
+			}
 			fmt.Printf("  %-20s %s\n", node.Type, node.Description)
 		}
 	}
@@ -225,8 +356,13 @@ func outputTable(nodeList []nodes.Metadata) error {
 }
 
 // outputJSON outputs nodes in JSON format.
-func outputJSON(nodeList []nodes.Metadata) error {
-	data, err := json.MarshalIndent(nodeList, "", "  ")
+func outputJSON(nodeList []nodes.Metadata, providers map[string]string) error {
+	entries := make([]nodeDocs, len(nodeList))
+	for i, node := range nodeList {
+		entries[i] = nodeDocs{Metadata: node, Plugin: providers[node.Type]}
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -235,7 +371,7 @@ func outputJSON(nodeList []nodes.Metadata) error {
 }
 
 // outputYAML outputs nodes in YAML format.
-func outputYAML(nodeList []nodes.Metadata) error {
+func outputYAML(nodeList []nodes.Metadata, providers map[string]string) error {
 	// Convert to YAML-friendly format
 	output := make([]map[string]interface{}, len(nodeList))
 	for i, node := range nodeList {
@@ -247,6 +383,9 @@ func outputYAML(nodeList []nodes.Metadata) error {
 		if node.Since != "" {
 			output[i]["since"] = node.Since
 		}
+		if plugin, ok := providers[node.Type]; ok {
+			output[i]["plugin"] = plugin
+		}
 		if len(node.Examples) > 0 {
 			output[i]["examples"] = len(node.Examples)
 		}
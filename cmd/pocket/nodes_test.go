@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -66,7 +67,7 @@ func TestOutputTable(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := outputTable(testNodes)
+	err := outputTable(testNodes, nil)
 	if err != nil {
 		t.Errorf("outputTable() error = %v", err)
 	}
@@ -111,7 +112,7 @@ func TestOutputJSON(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := outputJSON(testNodes)
+	err := outputJSON(testNodes, nil)
 	if err != nil {
 		t.Errorf("outputJSON() error = %v", err)
 	}
@@ -154,7 +155,7 @@ func TestOutputYAML(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := outputYAML(testNodes)
+	err := outputYAML(testNodes, nil)
 	if err != nil {
 		t.Errorf("outputYAML() error = %v", err)
 	}
@@ -180,3 +181,195 @@ func TestOutputYAML(t *testing.T) {
 		t.Error("Output missing since field")
 	}
 }
+
+// installTestPlugin writes a plugin manifest under a temporary home
+// directory so plugin discovery finds it.
+func installTestPlugin(t *testing.T) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	dir := filepath.Join(home, ".pocket", "plugins", "sentiment")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `name: sentiment
+version: 1.2.0
+description: Sentiment analysis
+runtime: wasm
+binary: plugin.wasm
+nodes:
+  - type: sentiment
+    category: ai
+    description: Scores the sentiment of text
+    configSchema:
+      type: object
+      properties:
+        threshold:
+          type: number
+          description: Minimum score to report
+      required: ["threshold"]
+    inputSchema:
+      type: string
+    outputSchema:
+      type: object
+`
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateDocs(t *testing.T) {
+	installTestPlugin(t)
+
+	t.Run("markdown includes plugins", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "NODES.md")
+		if err := runGenerateDocs(&DocsConfig{Output: file}); err != nil {
+			t.Fatalf("runGenerateDocs() error = %v", err)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := string(data)
+		for _, want := range []string{
+			"### echo",
+			"### sentiment",
+			"**Plugin:** sentiment (version 1.2.0)",
+			"- **threshold** *(required)*: Minimum score to report",
+			"#### Input Schema",
+		} {
+			if !strings.Contains(doc, want) {
+				t.Errorf("Documentation missing %q", want)
+			}
+		}
+	})
+
+	t.Run("json with category filter", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "nodes.json")
+		if err := runGenerateDocs(&DocsConfig{Format: jsonFormat, Output: file, Category: "ai"}); err != nil {
+			t.Fatalf("runGenerateDocs() error = %v", err)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc struct {
+			Nodes []struct {
+				Type   string `json:"type"`
+				Plugin string `json:"plugin"`
+			} `json:"nodes"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatalf("Invalid JSON output: %v", err)
+		}
+		if len(doc.Nodes) != 1 || doc.Nodes[0].Type != "sentiment" || doc.Nodes[0].Plugin != "sentiment" {
+			t.Errorf("Expected only the plugin node, got %+v", doc.Nodes)
+		}
+	})
+
+	t.Run("builtin only", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "NODES.md")
+		if err := runGenerateDocs(&DocsConfig{Output: file, BuiltinOnly: true}); err != nil {
+			t.Fatalf("runGenerateDocs() error = %v", err)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "sentiment") {
+			t.Error("Expected plugin nodes to be left out")
+		}
+	})
+}
+
+// captureStdout returns what fn writes to stdout.
+func captureStdout(t *testing.T, fn func() error) string {
+	t.Helper()
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := fn()
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+func TestNodesListAndInfo(t *testing.T) {
+	installTestPlugin(t)
+
+	t.Run("list filters by category", func(t *testing.T) {
+		output := captureStdout(t, func() error {
+			return runNodesList(&NodesConfig{Format: jsonFormat, Category: "io"})
+		})
+
+		var result []nodes.Metadata
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Invalid JSON output: %v", err)
+		}
+		if len(result) == 0 {
+			t.Fatal("Expected io nodes")
+		}
+		for _, node := range result {
+			if node.Category != "io" {
+				t.Errorf("Expected only io nodes, got %s (%s)", node.Type, node.Category)
+			}
+		}
+	})
+
+	t.Run("list includes plugins", func(t *testing.T) {
+		output := captureStdout(t, func() error {
+			return runNodesList(&NodesConfig{})
+		})
+		if !strings.Contains(output, "sentiment") || !strings.Contains(output, "(plugin: sentiment)") {
+			t.Errorf("Expected plugin node in list:\n%s", output)
+		}
+		if !strings.Contains(output, "echo") {
+			t.Errorf("Expected builtin node in list:\n%s", output)
+		}
+	})
+
+	t.Run("info for plugin node", func(t *testing.T) {
+		output := captureStdout(t, func() error {
+			return runNodesInfo(&NodesConfig{Type: "sentiment"})
+		})
+		for _, want := range []string{"Plugin: sentiment", "Version: 1.2.0", "Configuration:", "Input:", "Output:"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Info missing %q:\n%s", want, output)
+			}
+		}
+	})
+
+	t.Run("info as JSON", func(t *testing.T) {
+		output := captureStdout(t, func() error {
+			return runNodesInfo(&NodesConfig{Type: "http", Format: jsonFormat})
+		})
+
+		var result nodes.Metadata
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			t.Fatalf("Invalid JSON output: %v", err)
+		}
+		if result.Type != "http" || len(result.ConfigSchema) == 0 {
+			t.Errorf("Unexpected info: %+v", result)
+		}
+	})
+
+	t.Run("unknown node", func(t *testing.T) {
+		if err := runNodesInfo(&NodesConfig{Type: "nonexistent"}); err == nil {
+			t.Error("Expected error for unknown node type")
+		}
+	})
+}
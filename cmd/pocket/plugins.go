@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/tabwriter"
 
+	goyaml "github.com/goccy/go-yaml"
 	"github.com/spf13/cobra"
+
+	"github.com/agentstation/pocket/plugins"
+	"github.com/agentstation/pocket/plugins/loader"
 )
 
 // pluginsCmd represents the plugins command.
@@ -36,7 +43,10 @@ plugin interface.`,
 var pluginsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List installed plugins",
-	Long:  `List all installed WebAssembly plugins.`,
+	Long: `List all installed WebAssembly plugins.
+
+Plugins whose requirements aren't met by this Pocket version or the other
+installed plugins are marked incompatible, with the unmet requirements.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return listPlugins(verbose)
 	},
@@ -44,18 +54,24 @@ var pluginsListCmd = &cobra.Command{
 
 // pluginsInstallCmd represents the plugins install command.
 var pluginsInstallCmd = &cobra.Command{
-	Use:   "install <plugin.wasm>",
+	Use:   "install <plugin-dir|plugin.wasm>",
 	Short: "Install a plugin",
 	Long: `Install a WebAssembly plugin.
 
-The plugin file will be copied to the plugins directory and made available
-for use in workflows.`,
-	Example: `  # Install a local plugin
+A plugin directory holding a manifest and its binary is copied to the plugins
+directory, after checking that the installed Pocket version and plugins meet
+the manifest's requirements. A bare .wasm file is copied as-is.`,
+	Example: `  # Install a plugin directory
+  pocket plugins install ./my-plugin
+
+  # Install a local plugin binary
   pocket plugins install ./my-plugin.wasm
 
   # Install with a custom name
   pocket plugins install ./plugin.wasm --name custom-name`,
-	Args: cobra.ExactArgs(1),
+	// Incompatible plugins are not usage errors
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		pluginPath := args[0]
 		name, _ := cmd.Flags().GetString("name")
@@ -89,18 +105,70 @@ var pluginsRemoveCmd = &cobra.Command{
 	},
 }
 
+// pluginsRunCmd represents the plugins run command.
+var pluginsRunCmd = &cobra.Command{
+	Use:   "run <plugin> <node> <prep|exec|post> [input]",
+	Short: "Call a plugin function directly",
+	Long: `Load a plugin and call one lifecycle function of one of its nodes,
+printing the response, including the next route from post.
+
+The plugin is the name of a discovered plugin or a path to a plugin
+directory, manifest, or .wasm file. The input, config, and earlier step
+results are JSON or YAML; pass - as the input to read it from stdin, or
+prefix a flag value with @ to read it from a file.`,
+	Example: `  # Call exec with a prep result
+  pocket plugins run sentiment sentiment exec --prep '{"text": "Great product!"}'
+
+  # Call prep with config and input from stdin
+  echo '{"text": "hello"}' | pocket plugins run ./my-plugin sentiment prep - --config '{"threshold": 0.5}'
+
+  # Call post with the earlier results
+  pocket plugins run sentiment sentiment post '{"text": "hi"}' --exec @exec.json --output json`,
+	// A failing plugin call is not a usage error
+	SilenceUsage: true,
+	Args:         cobra.RangeArgs(3, 4),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := &PluginRunConfig{
+			Plugin:   args[0],
+			Node:     args[1],
+			Function: args[2],
+			Config:   pluginRunFlags.config,
+			Prep:     pluginRunFlags.prep,
+			Exec:     pluginRunFlags.exec,
+			Format:   output,
+		}
+		if len(args) == 4 {
+			config.Input = args[3]
+		}
+		return runPluginFunction(cmd.Context(), config, cmd.InOrStdin())
+	},
+}
+
+// pluginRunFlags holds the plugins run command's flags.
+var pluginRunFlags struct {
+	config string
+	prep   string
+	exec   string
+}
+
 func init() {
 	rootCmd.AddCommand(pluginsCmd)
 	pluginsCmd.AddCommand(pluginsListCmd)
 	pluginsCmd.AddCommand(pluginsInstallCmd)
 	pluginsCmd.AddCommand(pluginsInfoCmd)
 	pluginsCmd.AddCommand(pluginsRemoveCmd)
+	pluginsCmd.AddCommand(pluginsRunCmd)
 
 	// Install command flags
 	pluginsInstallCmd.Flags().String("name", "", "Custom name for the plugin")
 
 	// Remove command flags
 	pluginsRemoveCmd.Flags().BoolP("force", "f", false, "Force removal without confirmation")
+
+	// Run command flags
+	pluginsRunCmd.Flags().StringVarP(&pluginRunFlags.config, "config", "c", "", "Node config as JSON or YAML, or @file")
+	pluginsRunCmd.Flags().StringVar(&pluginRunFlags.prep, "prep", "", "Prep result for exec and post, or @file")
+	pluginsRunCmd.Flags().StringVar(&pluginRunFlags.exec, "exec", "", "Exec result for post, or @file")
 }
 
 // getPluginsDir returns the plugins directory path.
@@ -112,7 +180,8 @@ func getPluginsDir() (string, error) {
 	return filepath.Join(home, ".pocket", "plugins"), nil
 }
 
-// listPlugins lists all installed plugins.
+// listPlugins lists all installed plugins, with the plugins whose
+// requirements aren't met marked as incompatible.
 func listPlugins(_ bool) error {
 	pluginsDir, err := getPluginsDir()
 	if err != nil {
@@ -131,26 +200,52 @@ func listPlugins(_ bool) error {
 	}
 
 	// Filter .wasm files
-	var plugins []os.DirEntry
+	var wasmFiles []os.DirEntry
 	for _, entry := range entries {
 		if !entry.IsDir() && strings.HasSuffix(entry.Name(), wasmExtension) {
-			plugins = append(plugins, entry)
+			wasmFiles = append(wasmFiles, entry)
 		}
 	}
 
-	if len(plugins) == 0 {
+	// Plugins installed with a manifest
+	manifests, err := newPluginLoader().Discover(pluginsDir)
+	if err != nil {
+		return fmt.Errorf("failed to discover plugins: %w", err)
+	}
+
+	if len(wasmFiles) == 0 && len(manifests) == 0 {
 		fmt.Println("No plugins installed.")
-		fmt.Println("\nInstall plugins with: pocket plugins install <plugin.wasm>")
+		fmt.Println("\nInstall plugins with: pocket plugins install <plugin-dir>")
 		return nil
 	}
 
-	fmt.Printf("Installed plugins (%d):\n\n", len(plugins))
+	fmt.Printf("Installed plugins (%d):\n\n", len(wasmFiles)+len(manifests))
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintf(w, "NAME\tSIZE\tMODIFIED\n")
-	_, _ = fmt.Fprintf(w, "----\t----\t--------\n")
+	_, _ = fmt.Fprintf(w, "NAME\tVERSION\tSIZE\tMODIFIED\tSTATUS\n")
+	_, _ = fmt.Fprintf(w, "----\t-------\t----\t--------\t------\n")
+
+	incompatible := make(map[string]error)
+	for i := range manifests {
+		metadata := &manifests[i]
+		status := "ok"
+		if err := plugins.CheckRequirements(*metadata, version, manifests); err != nil {
+			status = "incompatible"
+			incompatible[metadata.Name] = err
+		}
 
-	for _, plugin := range plugins {
+		size, modified := "-", "-"
+		if info, err := os.Stat(metadata.Binary); err == nil {
+			size = formatSize(info.Size())
+			modified = info.ModTime().Format("2006-01-02 15:04")
+		} else {
+			status = "missing binary"
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", metadata.Name, metadata.Version, size, modified, status)
+	}
+
+	for _, plugin := range wasmFiles {
 		info, err := plugin.Info()
 		if err != nil {
 			continue
@@ -160,10 +255,17 @@ func listPlugins(_ bool) error {
 		size := formatSize(info.Size())
 		modified := info.ModTime().Format("2006-01-02 15:04")
 
-		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", name, size, modified)
+		_, _ = fmt.Fprintf(w, "%s\t-\t%s\t%s\t-\n", name, size, modified)
 	}
 
 	_ = w.Flush()
+
+	for _, name := range sortedKeys(incompatible) {
+		fmt.Printf("\n%s is incompatible:\n", name)
+		for _, line := range strings.Split(incompatible[name].Error(), "\n") {
+			fmt.Printf("  - %s\n", line)
+		}
+	}
 	return nil
 }
 
@@ -176,9 +278,13 @@ func installPlugin(pluginPath, customName string, _ bool) error {
 	}
 
 	// Check if file exists
-	if _, err := os.Stat(expandedPath); err != nil {
+	info, err := os.Stat(expandedPath)
+	if err != nil {
 		return fmt.Errorf("plugin file not found: %w", err)
 	}
+	if info.IsDir() {
+		return installPluginDir(expandedPath, customName)
+	}
 
 	// Get plugins directory
 	pluginsDir, err := getPluginsDir()
@@ -222,6 +328,68 @@ func installPlugin(pluginPath, customName string, _ bool) error {
 	return nil
 }
 
+// installPluginDir installs a plugin directory holding a manifest and its
+// binary, after checking that the plugin's requirements are met.
+func installPluginDir(dir, customName string) error {
+	found, err := newPluginLoader().Discover(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read plugin: %w", err)
+	}
+	if len(found) != 1 {
+		return fmt.Errorf("expected one plugin manifest in %s, found %d", dir, len(found))
+	}
+	metadata := found[0]
+
+	pluginsDir, err := getPluginsDir()
+	if err != nil {
+		return fmt.Errorf("failed to get plugins directory: %w", err)
+	}
+
+	// Check requirements against what's already installed
+	installed, err := newPluginLoader().Discover(pluginsDir)
+	if err != nil {
+		return fmt.Errorf("failed to discover plugins: %w", err)
+	}
+	if err := plugins.CheckRequirements(metadata, version, installed); err != nil {
+		return fmt.Errorf("plugin %s %s is incompatible: %w", metadata.Name, metadata.Version, err)
+	}
+
+	binary, err := filepath.Rel(dir, metadata.Binary)
+	if err != nil || strings.HasPrefix(binary, "..") {
+		return fmt.Errorf("plugin binary %s must be inside %s", metadata.Binary, dir)
+	}
+
+	targetName := customName
+	if targetName == "" {
+		targetName = metadata.Name
+	}
+	targetDir := filepath.Join(pluginsDir, targetName)
+	if _, err := os.Stat(targetDir); err == nil {
+		return fmt.Errorf("plugin already exists: %s", targetName)
+	}
+
+	manifest := filepath.Join(dir, "manifest.yaml")
+	if _, err := os.Stat(manifest); err != nil {
+		manifest = filepath.Join(dir, "manifest.json")
+	}
+	for _, file := range []string{filepath.Base(manifest), binary} {
+		data, err := os.ReadFile(filepath.Join(dir, file)) //nolint:gosec // User-provided plugin file
+		if err != nil {
+			return fmt.Errorf("failed to read plugin file: %w", err)
+		}
+		target := filepath.Join(targetDir, file)
+		if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+			return fmt.Errorf("failed to create plugin directory: %w", err)
+		}
+		if err := os.WriteFile(target, data, 0o600); err != nil {
+			return fmt.Errorf("failed to install plugin: %w", err)
+		}
+	}
+
+	fmt.Printf("✅ Installed plugin: %s %s\n", metadata.Name, metadata.Version)
+	return nil
+}
+
 // showPluginInfo displays information about a plugin.
 func showPluginInfo(pluginName string, _ bool) error {
 	pluginsDir, err := getPluginsDir()
@@ -308,3 +476,202 @@ func formatSize(size int64) string {
 	}
 	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
 }
+
+// PluginRunConfig holds configuration for the plugins run command.
+type PluginRunConfig struct {
+	Plugin   string
+	Node     string
+	Function string
+	Input    string
+	Config   string
+	Prep     string
+	Exec     string
+	Format   string
+}
+
+// runPluginFunction calls one lifecycle function of a plugin node and
+// prints the response.
+func runPluginFunction(ctx context.Context, config *PluginRunConfig, stdin io.Reader) error {
+	switch config.Function {
+	case "prep", "exec", "post":
+	default:
+		return fmt.Errorf("unknown function %q (expected prep, exec, or post)", config.Function)
+	}
+
+	req, err := pluginRequest(config, stdin)
+	if err != nil {
+		return err
+	}
+
+	plugin, err := loadPlugin(ctx, config.Plugin)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = plugin.Close(ctx) }()
+
+	if !providesNode(plugin.Metadata(), config.Node) {
+		return fmt.Errorf("plugin %s has no node type %s", plugin.Metadata().Name, config.Node)
+	}
+
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	respJSON, err := plugin.Call(ctx, config.Function, reqJSON)
+	if err != nil {
+		return fmt.Errorf("plugin %s failed: %w", config.Function, err)
+	}
+
+	var resp plugins.Response
+	if err := json.Unmarshal(respJSON, &resp); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if err := printPluginResponse(&resp, config.Format); err != nil {
+		return err
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("plugin %s returned an error", config.Function)
+	}
+	return nil
+}
+
+// pluginRequest builds the request for a plugin call from the run flags.
+func pluginRequest(config *PluginRunConfig, stdin io.Reader) (*plugins.Request, error) {
+	req := &plugins.Request{Node: config.Node, Function: config.Function}
+
+	if config.Config != "" {
+		data, err := readRunValue(config.Config, stdin)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		if err := goyaml.Unmarshal(data, &req.Config); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   *json.RawMessage
+	}{
+		{"input", config.Input, &req.Input},
+		{"prep", config.Prep, &req.PrepResult},
+		{"exec", config.Exec, &req.ExecResult},
+	} {
+		if field.value == "" {
+			continue
+		}
+		data, err := readRunValue(field.value, stdin)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.name, err)
+		}
+		if *field.dst, err = goyaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", field.name, err)
+		}
+	}
+	return req, nil
+}
+
+// readRunValue returns a flag value, reading it from stdin for - and from
+// a file for @path.
+func readRunValue(value string, stdin io.Reader) ([]byte, error) {
+	if value == "-" {
+		return io.ReadAll(stdin)
+	}
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		expandedPath, err := expandPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path: %w", err)
+		}
+		return os.ReadFile(expandedPath) //nolint:gosec // User-provided input file
+	}
+	return []byte(value), nil
+}
+
+// newPluginLoader returns a plugin loader that checks plugin requirements
+// against this build's version.
+func newPluginLoader() plugins.Loader {
+	return loader.New(loader.WithPocketVersion(version))
+}
+
+// loadPlugin loads a plugin from a path, or by name from the plugin
+// directories.
+func loadPlugin(ctx context.Context, nameOrPath string) (plugins.Plugin, error) {
+	l := newPluginLoader()
+
+	expandedPath, err := expandPath(nameOrPath)
+	if err == nil {
+		if _, err := os.Stat(expandedPath); err == nil {
+			return l.Load(ctx, expandedPath)
+		}
+	}
+
+	discovered, err := l.Discover()
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover plugins: %w", err)
+	}
+	for i := range discovered {
+		if discovered[i].Name == nameOrPath {
+			return l.LoadFromMetadata(ctx, discovered[i])
+		}
+	}
+	return nil, fmt.Errorf("plugin not found: %s", nameOrPath)
+}
+
+// providesNode reports whether a plugin provides a node type.
+//
+//nolint:gocritic // hugeParam: metadata is returned by value from Plugin.Metadata
+func providesNode(metadata plugins.Metadata, nodeType string) bool {
+	for _, node := range metadata.Nodes {
+		if node.Type == nodeType {
+			return true
+		}
+	}
+	return false
+}
+
+// printPluginResponse prints a plugin response in the given format.
+func printPluginResponse(resp *plugins.Response, format string) error {
+	var out any
+	if len(resp.Output) > 0 {
+		if err := json.Unmarshal(resp.Output, &out); err != nil {
+			return fmt.Errorf("failed to unmarshal output: %w", err)
+		}
+	}
+
+	switch format {
+	case jsonFormat:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(resp)
+	case yamlFormat:
+		data, err := goyaml.Marshal(struct {
+			Success bool   `yaml:"success"`
+			Error   string `yaml:"error,omitempty"`
+			Output  any    `yaml:"output,omitempty"`
+			Next    string `yaml:"next,omitempty"`
+		}{resp.Success, resp.Error, out, resp.Next})
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	fmt.Printf("Success: %t\n", resp.Success)
+	if resp.Error != "" {
+		fmt.Printf("Error: %s\n", resp.Error)
+	}
+	if resp.Next != "" {
+		fmt.Printf("Next: %s\n", resp.Next)
+	}
+	if out != nil {
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Output:\n%s\n", data)
+	}
+	return nil
+}
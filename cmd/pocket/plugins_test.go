@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginRequest(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "exec.yaml")
+	if err := os.WriteFile(file, []byte("score: 0.9\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := pluginRequest(&PluginRunConfig{
+		Node:     "sentiment",
+		Function: "post",
+		Input:    "-",
+		Config:   "threshold: 0.5",
+		Prep:     `{"text": "hi"}`,
+		Exec:     "@" + file,
+	}, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("pluginRequest() error = %v", err)
+	}
+
+	if req.Node != "sentiment" || req.Function != "post" {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+	if req.Config["threshold"] != 0.5 {
+		t.Errorf("Config = %v", req.Config)
+	}
+	for got, want := range map[string]string{
+		compactJSON(t, req.Input):      `"hello"`,
+		compactJSON(t, req.PrepResult): `{"text":"hi"}`,
+		compactJSON(t, req.ExecResult): `{"score":0.9}`,
+	} {
+		if got != want {
+			t.Errorf("Got %s, want %s", got, want)
+		}
+	}
+
+	if _, err := pluginRequest(&PluginRunConfig{Config: "[1, 2]"}, nil); err == nil {
+		t.Error("Expected error for non-object config")
+	}
+}
+
+func compactJSON(t *testing.T, data json.RawMessage) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", data, err)
+	}
+	return buf.String()
+}
+
+func TestRunPluginFunction(t *testing.T) {
+	t.Run("unknown function", func(t *testing.T) {
+		err := runPluginFunction(context.Background(), &PluginRunConfig{Plugin: "greeter", Node: "greeter", Function: "run"}, nil)
+		if err == nil || !strings.Contains(err.Error(), "unknown function") {
+			t.Errorf("Expected unknown function error, got %v", err)
+		}
+	})
+
+	t.Run("plugin not found", func(t *testing.T) {
+		t.Setenv("HOME", t.TempDir())
+		err := runPluginFunction(context.Background(), &PluginRunConfig{Plugin: "missing", Node: "greeter", Function: "prep"}, nil)
+		if err == nil || !strings.Contains(err.Error(), "plugin not found") {
+			t.Errorf("Expected plugin not found error, got %v", err)
+		}
+	})
+
+	t.Run("calls plugin", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("Skipping plugin build in short mode")
+		}
+
+		dir := t.TempDir()
+		source := filepath.Join("..", "..", "plugins", "plugintest", "testdata", "greeter")
+		cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", filepath.Join(dir, "plugin.wasm"), ".")
+		cmd.Dir = source
+		cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Skipf("Cannot build test plugin: %v\n%s", err, out)
+		}
+		manifest, err := os.ReadFile(filepath.Join(source, "manifest.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), manifest, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		output := captureStdout(t, func() error {
+			return runPluginFunction(context.Background(), &PluginRunConfig{
+				Plugin:   dir,
+				Node:     "greeter",
+				Function: "post",
+				Exec:     `"Hi, there!"`,
+				Format:   jsonFormat,
+			}, nil)
+		})
+		var resp map[string]any
+		if err := json.Unmarshal([]byte(output), &resp); err != nil {
+			t.Fatalf("Invalid JSON output %q: %v", output, err)
+		}
+		if resp["success"] != true || resp["output"] != "Hi, there!" || resp["next"] != "default" {
+			t.Errorf("Unexpected response: %v", resp)
+		}
+
+		stdout := os.Stdout
+		os.Stdout, _ = os.Open(os.DevNull)
+		err = runPluginFunction(context.Background(), &PluginRunConfig{
+			Plugin: dir, Node: "greeter", Function: "prep", Input: "42", Format: jsonFormat,
+		}, nil)
+		os.Stdout = stdout
+		if err == nil {
+			t.Error("Expected error when the plugin fails")
+		}
+
+		err = runPluginFunction(context.Background(), &PluginRunConfig{Plugin: dir, Node: "other", Function: "prep"}, nil)
+		if err == nil || !strings.Contains(err.Error(), "no node type") {
+			t.Errorf("Expected unknown node error, got %v", err)
+		}
+	})
+}
+
+// writePluginDir writes a plugin directory with a manifest and a
+// placeholder binary.
+func writePluginDir(t *testing.T, dir, name, requirements string) {
+	t.Helper()
+	manifest := "name: " + name + `
+version: 1.0.0
+runtime: wasm
+binary: plugin.wasm
+nodes:
+  - type: ` + name + `
+    category: test
+    description: Test node
+` + requirements
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "plugin.wasm"), []byte("wasm"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPluginRequirements(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	src := t.TempDir()
+
+	dependent := filepath.Join(src, "dependent")
+	writePluginDir(t, dependent, "dependent", "requirements:\n  plugins:\n    base: \"^1.0\"\n")
+	base := filepath.Join(src, "base")
+	writePluginDir(t, base, "base", "")
+
+	t.Run("install rejects unmet dependency", func(t *testing.T) {
+		err := installPlugin(dependent, "", false)
+		if err == nil || !strings.Contains(err.Error(), "requires plugin base ^1.0, which is not installed") {
+			t.Errorf("Expected dependency error, got %v", err)
+		}
+	})
+
+	t.Run("install after dependency", func(t *testing.T) {
+		captureStdout(t, func() error { return installPlugin(base, "", false) })
+		captureStdout(t, func() error { return installPlugin(dependent, "", false) })
+
+		for _, file := range []string{"base/manifest.yaml", "base/plugin.wasm", "dependent/manifest.yaml"} {
+			if _, err := os.Stat(filepath.Join(home, ".pocket", "plugins", file)); err != nil {
+				t.Errorf("Expected %s to be installed: %v", file, err)
+			}
+		}
+	})
+
+	t.Run("list marks incompatible plugins", func(t *testing.T) {
+		if err := os.RemoveAll(filepath.Join(home, ".pocket", "plugins", "base")); err != nil {
+			t.Fatal(err)
+		}
+
+		output := captureStdout(t, func() error { return listPlugins(false) })
+		if !strings.Contains(output, "incompatible") || !strings.Contains(output, "requires plugin base ^1.0") {
+			t.Errorf("Expected dependent to be marked incompatible, got:\n%s", output)
+		}
+	})
+}
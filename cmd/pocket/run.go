@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	goyaml "github.com/goccy/go-yaml"
@@ -18,10 +19,19 @@ import (
 
 var (
 	// Run command flags.
-	dryRun     bool
-	storeType  string
-	maxEntries int
-	ttl        time.Duration
+	dryRun      bool
+	storeType   string
+	maxEntries  int
+	ttl         time.Duration
+	configFiles []string
+	configSets  []string
+	env         string
+	matrixFile  string
+	parallel    int
+	watch       bool
+	events      string
+	traceFile   string
+	traceFormat string
 )
 
 // RunConfig holds configuration for the run command.
@@ -32,6 +42,33 @@ type RunConfig struct {
 	StoreType  string
 	MaxEntries int
 	TTL        time.Duration
+
+	// Env names the environment whose overlay file, such as
+	// workflow.staging.yaml, is merged onto the workflow.
+	Env string
+
+	// ConfigFiles are merged onto the workflow config in order, then
+	// ConfigValues, as key=value pairs, are set.
+	ConfigFiles  []string
+	ConfigValues []string
+
+	// MatrixFile lists config values to run the workflow with, once per
+	// combination, Parallel runs at a time.
+	MatrixFile string
+	Parallel   int
+	Format     string
+
+	// Watch renders a live view of the run in the terminal.
+	Watch bool
+
+	// Events is the format to write run events to stdout in, in place of
+	// the result; only jsonl is supported.
+	Events string
+
+	// TraceFile is where to write a timeline of the run's nodes after it
+	// ends, in TraceFormat: chrome or otlp.
+	TraceFile   string
+	TraceFormat string
 }
 
 // runCmd represents the run command.
@@ -41,7 +78,12 @@ var runCmd = &cobra.Command{
 	Long: `Execute a Pocket workflow defined in a YAML file.
 
 The workflow file should define nodes, connections, and a start node.
-Use --dry-run to validate the workflow without executing it.`,
+Use --dry-run to validate the workflow without executing it.
+
+With --env, the overlay file for the environment, such as
+workflow.staging.yaml next to workflow.yaml, is deep-merged onto the
+workflow. Maps merge key by key, nodes merge with the node of the same
+name or are added, and other values replace the workflow's.`,
 	Example: `  # Run a workflow
   pocket run workflow.yaml
 
@@ -52,7 +94,25 @@ Use --dry-run to validate the workflow without executing it.`,
   pocket run workflow.yaml --dry-run
 
   # Use bounded store with TTL
-  pocket run workflow.yaml --store-type bounded --max-entries 1000 --ttl 5m`,
+  pocket run workflow.yaml --store-type bounded --max-entries 1000 --ttl 5m
+
+  # Run with the staging overlay, workflow.staging.yaml
+  pocket run -e staging workflow.yaml
+
+  # Override workflow config values
+  pocket run workflow.yaml --config-file staging.yaml --set api.timeout=60s
+
+  # Run once per combination of config values in a matrix
+  pocket run workflow.yaml --matrix params.yaml --parallel 8
+
+  # Watch the run live in the terminal
+  pocket run workflow.yaml --watch
+
+  # Stream run events as JSON lines for other tools
+  pocket run workflow.yaml --events jsonl | jq .type
+
+  # Write a timeline to open in chrome://tracing or Perfetto
+  pocket run workflow.yaml --trace trace.json`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		workflowPath := args[0]
@@ -64,12 +124,22 @@ Use --dry-run to validate the workflow without executing it.`,
 		}
 
 		config := &RunConfig{
-			FilePath:   expandedPath,
-			Verbose:    verbose,
-			DryRun:     dryRun,
-			StoreType:  storeType,
-			MaxEntries: maxEntries,
-			TTL:        ttl,
+			FilePath:     expandedPath,
+			Verbose:      verbose,
+			DryRun:       dryRun,
+			StoreType:    storeType,
+			MaxEntries:   maxEntries,
+			TTL:          ttl,
+			Env:          env,
+			ConfigFiles:  configFiles,
+			ConfigValues: configSets,
+			MatrixFile:   matrixFile,
+			Parallel:     parallel,
+			Format:       output,
+			Watch:        watch,
+			Events:       events,
+			TraceFile:    traceFile,
+			TraceFormat:  traceFormat,
 		}
 
 		return runWorkflow(config)
@@ -84,12 +154,28 @@ func init() {
 	runCmd.Flags().StringVar(&storeType, "store-type", "memory", "Store type: memory or bounded")
 	runCmd.Flags().IntVar(&maxEntries, "max-entries", 10000, "Max entries for bounded store")
 	runCmd.Flags().DurationVar(&ttl, "ttl", 0, "TTL for store entries (0 = no expiration)")
+	runCmd.Flags().StringVarP(&env, "env", "e", "", "Environment whose overlay file (workflow.<env>.yaml) is merged onto the workflow")
+	runCmd.Flags().StringArrayVar(&configFiles, "config-file", nil, "YAML file of workflow config values to merge (repeatable)")
+	runCmd.Flags().StringArrayVar(&configSets, "set", nil, "Set a workflow config value, as key=value (repeatable)")
+	runCmd.Flags().StringVar(&matrixFile, "matrix", "", "YAML file of config values to run the workflow with, once per combination")
+	runCmd.Flags().IntVar(&parallel, "parallel", 4, "Matrix runs to execute at once")
+	runCmd.Flags().BoolVar(&watch, "watch", false, "Show a live view of the run: nodes, timings, and routes taken")
+	runCmd.Flags().StringVar(&events, "events", "", "Write run events to stdout, one per lifecycle step, in a format: jsonl")
+	runCmd.Flags().StringVar(&traceFile, "trace", "", "Write a timeline of the run's nodes to a file after the run")
+	runCmd.Flags().StringVar(&traceFormat, "trace-format", traceChrome, "Format of the --trace file: chrome or otlp")
 }
 
 // runWorkflow executes a workflow from a YAML file.
 //
 //nolint:gocyclo // Complex due to workflow parsing, validation, and execution handling
 func runWorkflow(config *RunConfig) error {
+	if config.Events != "" && config.Events != eventsJSONL {
+		return fmt.Errorf("unknown events format %q: want %s", config.Events, eventsJSONL)
+	}
+	if config.TraceFile != "" && config.TraceFormat != traceChrome && config.TraceFormat != traceOTLP {
+		return fmt.Errorf("unknown trace format %q: want %s or %s", config.TraceFormat, traceChrome, traceOTLP)
+	}
+
 	// Make path absolute
 	absPath, err := filepath.Abs(config.FilePath)
 	if err != nil {
@@ -108,10 +194,13 @@ func runWorkflow(config *RunConfig) error {
 		return fmt.Errorf("access file: %w", err)
 	}
 
-	// Read the YAML file
-	data, err := os.ReadFile(absPath) //nolint:gosec // User-provided workflow file
+	// Read the YAML file, merging the environment's overlay
+	data, err := loadWorkflowData(absPath, config.Env)
 	if err != nil {
-		return fmt.Errorf("read file: %w", err)
+		return err
+	}
+	if config.Verbose && config.Env != "" {
+		log.Printf("Using environment: %s (%s)", config.Env, overlayPath(absPath, config.Env))
 	}
 
 	// Parse YAML into GraphDefinition
@@ -120,6 +209,11 @@ func runWorkflow(config *RunConfig) error {
 		return fmt.Errorf("parse YAML: %w", err)
 	}
 
+	// Apply config overrides before validating
+	if err := applyConfigOverrides(&graphDef, config.ConfigFiles, config.ConfigValues); err != nil {
+		return err
+	}
+
 	// Validate the graph definition
 	if err := graphDef.Validate(); err != nil {
 		return fmt.Errorf("invalid workflow: %w", err)
@@ -140,40 +234,50 @@ func runWorkflow(config *RunConfig) error {
 		return nil
 	}
 
-	// Create store based on configuration
-	var store pocket.Store
-	switch config.StoreType {
-	case "memory":
-		store = pocket.NewStore()
-		if config.Verbose {
-			log.Println("Using in-memory store")
+	// Run once per combination of matrix values
+	if config.MatrixFile != "" {
+		if config.Watch || config.Events != "" || config.TraceFile != "" {
+			return fmt.Errorf("--watch, --events and --trace can't be used with --matrix")
 		}
-	case "bounded":
-		opts := []pocket.StoreOption{
-			pocket.WithMaxEntries(config.MaxEntries),
-		}
-		if config.TTL > 0 {
-			opts = append(opts, pocket.WithTTL(config.TTL))
-		}
-		if config.Verbose {
-			opts = append(opts, pocket.WithEvictionCallback(func(key string, value any) {
-				log.Printf("Evicted: %s", key)
-			}))
-		}
-		store = pocket.NewStore(opts...)
-		if config.Verbose {
-			log.Printf("Using bounded store (max entries: %d, TTL: %v)", config.MaxEntries, config.TTL)
-		}
-	default:
-		return fmt.Errorf("unknown store type: %s", config.StoreType)
+		return runMatrix(config, &graphDef)
+	}
+
+	// Create store based on configuration
+	store, err := newRunStore(config)
+	if err != nil {
+		return err
 	}
 
 	// Create a loader and register built-in nodes
 	loader := yaml.NewLoader()
 	nodes.RegisterAll(loader, config.Verbose)
 
+	// Show output streamed by nodes as it arrives
+	lineHandler := func(node, line string) {
+		fmt.Fprintf(os.Stderr, "[%s] %s\n", node, line)
+	}
+
+	// Watch the run through its events
+	var opts []pocket.GraphOption
+	var watcher *runWatcher
+	if config.Watch {
+		watcher = newRunWatcher(os.Stderr, &graphDef)
+		opts = append(opts, pocket.WithEventHandler(watcher.handle))
+		lineHandler = watcher.line
+	}
+	var eventOut *eventWriter
+	if config.Events != "" {
+		eventOut = newEventWriter(os.Stdout)
+		opts = append(opts, pocket.WithEventHandler(eventOut.handle))
+	}
+	var timeline *pocket.Timeline
+	if config.TraceFile != "" {
+		timeline = pocket.NewTimeline()
+		opts = append(opts, pocket.WithEventHandler(timeline.Handle))
+	}
+
 	// Load the graph
-	graph, err := loader.LoadDefinition(&graphDef, store)
+	graph, err := loader.LoadDefinition(&graphDef, store, opts...)
 	if err != nil {
 		return fmt.Errorf("load workflow: %w", err)
 	}
@@ -182,8 +286,20 @@ func runWorkflow(config *RunConfig) error {
 		log.Println("Starting workflow execution...")
 	}
 
-	// Create context
-	ctx := context.Background()
+	ctx := nodes.WithLineHandler(context.Background(), lineHandler)
+	stopWatching := func() {}
+	if watcher != nil {
+		refreshCtx, cancel := context.WithCancel(ctx)
+		refreshed := make(chan struct{})
+		go func() {
+			watcher.refresh(refreshCtx, 100*time.Millisecond)
+			close(refreshed)
+		}()
+		stopWatching = func() {
+			cancel()
+			<-refreshed
+		}
+	}
 
 	// TODO: In the future, we could accept input from:
 	// - Command line args
@@ -196,6 +312,26 @@ func runWorkflow(config *RunConfig) error {
 	start := time.Now()
 	result, err := graph.Run(ctx, input)
 	duration := time.Since(start)
+	stopWatching()
+
+	// Write the timeline of failed runs too, to see where they failed
+	if timeline != nil {
+		if traceErr := writeTrace(timeline, config.TraceFile, config.TraceFormat); traceErr != nil {
+			return traceErr
+		}
+		if config.Verbose {
+			log.Printf("Wrote %s trace to %s", config.TraceFormat, config.TraceFile)
+		}
+	}
+
+	// The run_end event holds the result in place of printing it
+	if eventOut != nil {
+		eventOut.end(duration, result, err)
+		if err != nil {
+			return fmt.Errorf("workflow execution failed: %w", err)
+		}
+		return nil
+	}
 
 	if err != nil {
 		return fmt.Errorf("workflow execution failed: %w", err)
@@ -219,3 +355,86 @@ func runWorkflow(config *RunConfig) error {
 
 	return nil
 }
+
+// applyConfigOverrides merges config files onto a workflow's config, then
+// sets key=value pairs. Values are parsed as YAML, so numbers and booleans
+// keep their types.
+func applyConfigOverrides(def *yaml.GraphDefinition, files, values []string) error {
+	for _, file := range files {
+		data, err := os.ReadFile(file) //nolint:gosec // User-provided config file
+		if err != nil {
+			return fmt.Errorf("read config file: %w", err)
+		}
+		var overrides map[string]interface{}
+		if err := goyaml.Unmarshal(data, &overrides); err != nil {
+			return fmt.Errorf("parse config file %s: %w", file, err)
+		}
+		def.MergeConfig(overrides)
+	}
+
+	for _, pair := range values {
+		key, raw, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid config value %q: want key=value", pair)
+		}
+		var value interface{}
+		if err := goyaml.Unmarshal([]byte(raw), &value); err != nil || value == nil {
+			value = raw
+		}
+		def.SetConfig(key, value)
+	}
+	return nil
+}
+
+// newRunStore creates the store for a run from the configuration.
+func newRunStore(config *RunConfig) (pocket.Store, error) {
+	switch config.StoreType {
+	case "memory":
+		if config.Verbose {
+			log.Println("Using in-memory store")
+		}
+		return pocket.NewStore(), nil
+	case "bounded":
+		opts := []pocket.StoreOption{
+			pocket.WithMaxEntries(config.MaxEntries),
+		}
+		if config.TTL > 0 {
+			opts = append(opts, pocket.WithTTL(config.TTL))
+		}
+		if config.Verbose {
+			opts = append(opts, pocket.WithEvictionCallback(func(key string, value any) {
+				log.Printf("Evicted: %s", key)
+			}))
+			log.Printf("Using bounded store (max entries: %d, TTL: %v)", config.MaxEntries, config.TTL)
+		}
+		return pocket.NewStore(opts...), nil
+	default:
+		return nil, fmt.Errorf("unknown store type: %s", config.StoreType)
+	}
+}
+
+// Formats of --trace files.
+const (
+	traceChrome = "chrome"
+	traceOTLP   = "otlp"
+)
+
+// writeTrace writes a run's timeline to a file in a trace format.
+func writeTrace(timeline *pocket.Timeline, path, format string) error {
+	f, err := os.Create(path) //nolint:gosec // User-provided trace file
+	if err != nil {
+		return fmt.Errorf("create trace file: %w", err)
+	}
+	if format == traceOTLP {
+		err = timeline.WriteOTLP(f)
+	} else {
+		err = timeline.WriteChromeTrace(f)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("write trace: %w", err)
+	}
+	return nil
+}
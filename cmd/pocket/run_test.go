@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/nodes"
+	"github.com/agentstation/pocket/yaml"
+)
+
+func TestApplyConfigOverrides(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "staging.yaml")
+	if err := os.WriteFile(file, []byte("api:\n  url: https://staging.example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	def := &yaml.GraphDefinition{
+		Config: map[string]interface{}{
+			"api":    map[string]interface{}{"url": "https://example.com", "retries": 3},
+			"region": "us",
+		},
+	}
+	err := applyConfigOverrides(def, []string{file}, []string{"api.retries=5", "debug=true", "tags.env=staging"})
+	if err != nil {
+		t.Fatalf("applyConfigOverrides() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"api":    map[string]interface{}{"url": "https://staging.example.com", "retries": uint64(5)},
+		"region": "us",
+		"debug":  true,
+		"tags":   map[string]interface{}{"env": "staging"},
+	}
+	if !reflect.DeepEqual(def.Config, want) {
+		t.Errorf("Config = %v, want %v", def.Config, want)
+	}
+
+	if err := applyConfigOverrides(def, nil, []string{"novalue"}); err == nil {
+		t.Error("applyConfigOverrides() succeeded, want error for missing =")
+	}
+}
+
+func TestWorkflowConfig(t *testing.T) {
+	def := &yaml.GraphDefinition{
+		Name:   "configured",
+		Start:  "call",
+		Config: map[string]interface{}{"api": map[string]interface{}{"url": "https://example.com"}},
+		Nodes: []yaml.NodeDefinition{
+			{
+				Name: "call",
+				Type: "echo",
+				Config: map[string]interface{}{
+					"message": "calling {{.workflow.config.api.url}}",
+				},
+				Input: "{{ .workflow.config.api }}",
+			},
+		},
+	}
+
+	loader := yaml.NewLoader()
+	nodes.RegisterAll(loader, false)
+	graph, err := loader.LoadDefinition(def, pocket.NewStore())
+	if err != nil {
+		t.Fatalf("LoadDefinition() error = %v", err)
+	}
+	output, err := graph.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	result := output.(map[string]interface{})
+	if result["message"] != "calling https://example.com" {
+		t.Errorf("message = %v, want the config value filled in", result["message"])
+	}
+	if !reflect.DeepEqual(result["input"], def.Config["api"]) {
+		t.Errorf("input = %v, want the config map", result["input"])
+	}
+	if def.Nodes[0].Config["message"] != "calling {{.workflow.config.api.url}}" {
+		t.Error("LoadDefinition() modified the definition")
+	}
+
+	def.Nodes[0].Config["message"] = "{{.workflow.config.api.token}}"
+	if _, err := loader.LoadDefinition(def, pocket.NewStore()); err == nil || !strings.Contains(err.Error(), "workflow config api.token is not defined") {
+		t.Errorf("LoadDefinition() error = %v, want undefined config error", err)
+	}
+}
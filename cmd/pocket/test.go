@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	goyaml "github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/pocket/nodes"
+	"github.com/agentstation/pocket/pockettest"
+	"github.com/agentstation/pocket/yaml"
+)
+
+// testFileSuffix marks workflow test files when searching directories.
+const testFileSuffix = ".test.yaml"
+
+// updateGolden is the test command's --update flag.
+var updateGolden bool
+
+// testCmd represents the test command.
+var testCmd = &cobra.Command{
+	Use:   "test <tests.yaml|dir>...",
+	Short: "Run workflow test cases",
+	Long: `Run workflows against test cases defined in YAML.
+
+A test file names the workflow it tests, relative to the test file, and
+lists cases with an input, values to seed the store with, and what to
+expect: the output, the route through the workflow's nodes, store values,
+or an error. A case can also compare its output, route, and store against
+a golden file in testdata next to the test file. Use --update to write the
+golden files from the current results.
+
+Directories are searched for files ending in .test.yaml. The command exits
+non-zero if any case fails.`,
+	Example: `  # Run the cases in a test file
+  pocket test router.test.yaml
+
+  # Run every test file under a directory
+  pocket test workflows/
+
+  # Rewrite golden files from the current results
+  pocket test workflows/ --update`,
+	// Failing cases are not usage errors
+	SilenceUsage: true,
+	Args:         cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var paths []string
+		for _, arg := range args {
+			expanded, err := expandPath(arg)
+			if err != nil {
+				return fmt.Errorf("invalid path: %w", err)
+			}
+			found, err := findTestFiles(expanded)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, found...)
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no %s files found", testFileSuffix)
+		}
+
+		config := &TestConfig{
+			Paths:   paths,
+			Update:  updateGolden,
+			Format:  output,
+			Verbose: verbose,
+		}
+		return runTests(config)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+
+	testCmd.Flags().BoolVar(&updateGolden, "update", false, "Write golden files from the current results")
+}
+
+// TestConfig holds configuration for the test command.
+type TestConfig struct {
+	Paths   []string
+	Update  bool
+	Format  string
+	Verbose bool
+}
+
+// WorkflowTestFile is a file of test cases for a workflow.
+type WorkflowTestFile struct {
+	// Workflow is the workflow file, relative to the test file.
+	Workflow string         `yaml:"workflow"`
+	Tests    []WorkflowTest `yaml:"tests"`
+}
+
+// WorkflowTest is a test case for a workflow.
+type WorkflowTest struct {
+	Name  string                 `yaml:"name"`
+	Input interface{}            `yaml:"input,omitempty"`
+	Store map[string]interface{} `yaml:"store,omitempty"`
+
+	// Expect holds the expected results. Only the fields set are checked.
+	Expect TestExpectation `yaml:"expect,omitempty"`
+
+	// Golden names a file in testdata, without its .golden extension,
+	// holding the expected output, route, and store.
+	Golden string `yaml:"golden,omitempty"`
+}
+
+// TestExpectation is what a workflow test case expects.
+type TestExpectation struct {
+	Output interface{}            `yaml:"output,omitempty"`
+	Route  []string               `yaml:"route,omitempty"`
+	Store  map[string]interface{} `yaml:"store,omitempty"`
+
+	// Error is a substring of the expected error. Without it, the
+	// workflow must succeed.
+	Error string `yaml:"error,omitempty"`
+}
+
+// TestResult is the result of a workflow test case.
+type TestResult struct {
+	File     string        `json:"file"`
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Failures []string      `json:"failures,omitempty"`
+	Route    []string      `json:"route"`
+	Duration time.Duration `json:"duration"`
+}
+
+// goldenResult is the content of a golden file.
+type goldenResult struct {
+	Output interface{}            `json:"output"`
+	Route  []string               `json:"route"`
+	Store  map[string]interface{} `json:"store,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// findTestFiles returns path if it's a file, or the test files under it if
+// it's a directory.
+func findTestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("access %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var paths []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), testFileSuffix) {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// runTests runs the cases in each test file and reports the results.
+func runTests(config *TestConfig) error {
+	var results []TestResult
+	for _, path := range config.Paths {
+		fileResults, err := runTestFile(path, config.Update)
+		if err != nil {
+			return err
+		}
+		results = append(results, fileResults...)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.Passed {
+			failed++
+		}
+	}
+
+	if config.Format == jsonFormat {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			switch {
+			case !r.Passed:
+				fmt.Printf("FAIL %s: %s (%v)\n", r.File, r.Name, r.Duration.Round(time.Millisecond))
+				for _, failure := range r.Failures {
+					fmt.Printf("    %s\n", failure)
+				}
+			case config.Verbose:
+				fmt.Printf("PASS %s: %s (%v)\n", r.File, r.Name, r.Duration.Round(time.Millisecond))
+			}
+		}
+		fmt.Printf("%d passed, %d failed\n", len(results)-failed, failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test(s) failed", failed, len(results))
+	}
+	return nil
+}
+
+// runTestFile runs the cases in a test file against its workflow.
+func runTestFile(path string, update bool) ([]TestResult, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // User-provided test file
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	var file WorkflowTestFile
+	if err := goyaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if file.Workflow == "" {
+		return nil, fmt.Errorf("%s: workflow is required", path)
+	}
+
+	dir := filepath.Dir(path)
+	workflowPath := filepath.Join(dir, file.Workflow)
+	graphDef, err := loadWorkflowDefinition(workflowPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	loader := yaml.NewLoader()
+	nodes.RegisterAll(loader, false)
+
+	results := make([]TestResult, 0, len(file.Tests))
+	for i := range file.Tests {
+		tc := &file.Tests[i]
+		if tc.Name == "" {
+			tc.Name = fmt.Sprintf("test %d", i+1)
+		}
+		result, err := runWorkflowTest(loader, graphDef, tc, dir, update)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, tc.Name, err)
+		}
+		result.File = path
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// loadWorkflowDefinition reads and validates a workflow file.
+func loadWorkflowDefinition(path string) (*yaml.GraphDefinition, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // Workflow named by a test file
+	if err != nil {
+		return nil, fmt.Errorf("read workflow: %w", err)
+	}
+
+	var graphDef yaml.GraphDefinition
+	if err := goyaml.Unmarshal(data, &graphDef); err != nil {
+		return nil, fmt.Errorf("parse workflow: %w", err)
+	}
+	if err := graphDef.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid workflow: %w", err)
+	}
+	return &graphDef, nil
+}
+
+// runWorkflowTest runs one case with a fresh store and checks its results.
+// Errors are problems running the case, not failures of it.
+func runWorkflowTest(loader *yaml.Loader, graphDef *yaml.GraphDefinition, tc *WorkflowTest, dir string, update bool) (*TestResult, error) {
+	store := pockettest.NewStore(tc.Store)
+	graph, err := loader.LoadDefinition(graphDef, store)
+	if err != nil {
+		return nil, fmt.Errorf("load workflow: %w", err)
+	}
+
+	start := time.Now()
+	trace := pockettest.Run(context.Background(), graph.Start(), store, tc.Input)
+	result := &TestResult{
+		Name:     tc.Name,
+		Route:    trace.Path(),
+		Duration: time.Since(start),
+	}
+
+	fail := func(format string, args ...interface{}) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+
+	expect := tc.Expect
+	switch {
+	case expect.Error != "":
+		if trace.Err == nil {
+			fail("workflow succeeded, want error containing %q", expect.Error)
+		} else if !strings.Contains(trace.Err.Error(), expect.Error) {
+			fail("error = %v, want it to contain %q", trace.Err, expect.Error)
+		}
+	case trace.Err != nil:
+		fail("error = %v", trace.Err)
+	}
+
+	if expect.Output != nil {
+		if diff := compareValues(trace.Output, expect.Output); diff != "" {
+			fail("output %s", diff)
+		}
+	}
+	if expect.Route != nil && !reflect.DeepEqual(result.Route, expect.Route) {
+		fail("route = %s, want %s", strings.Join(result.Route, " -> "), strings.Join(expect.Route, " -> "))
+	}
+	for _, key := range sortedKeys(expect.Store) {
+		got, ok := store.Get(context.Background(), key)
+		if !ok {
+			fail("store key %s is missing, want %s", key, formatValue(expect.Store[key]))
+			continue
+		}
+		if diff := compareValues(got, expect.Store[key]); diff != "" {
+			fail("store key %s %s", key, diff)
+		}
+	}
+
+	if tc.Golden != "" {
+		if err := checkGoldenResult(dir, tc.Golden, trace, store, update, fail); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Passed = len(result.Failures) == 0
+	return result, nil
+}
+
+// checkGoldenResult compares a run with testdata/<name>.golden, or writes
+// it when update is set.
+func checkGoldenResult(dir, name string, trace *pockettest.Trace, store *pockettest.Store, update bool, fail func(string, ...interface{})) error {
+	golden := goldenResult{
+		Output: trace.Output,
+		Route:  trace.Path(),
+		Store:  make(map[string]interface{}),
+	}
+	for _, key := range store.Keys() {
+		golden.Store[key], _ = store.Get(context.Background(), key)
+	}
+	if trace.Err != nil {
+		golden.Error = trace.Err.Error()
+	}
+
+	got, err := json.MarshalIndent(golden, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode golden result: %w", err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join(dir, "testdata", name+".golden")
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return err
+		}
+		return os.WriteFile(path, got, 0o600)
+	}
+
+	want, err := os.ReadFile(path) //nolint:gosec // Golden file next to the test file
+	if err != nil {
+		fail("read golden file (run with --update to create it): %v", err)
+		return nil
+	}
+	if string(got) != string(want) {
+		fail("result does not match %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+	return nil
+}
+
+// compareValues compares a result with an expectation decoded from YAML,
+// after converting both to the form JSON decoding produces, so numbers of
+// different types compare equal. It returns a description of the
+// difference, or "" if they match.
+func compareValues(got, want interface{}) string {
+	normalizedGot, errGot := normalizeValue(got)
+	normalizedWant, errWant := normalizeValue(want)
+	if errGot == nil && errWant == nil && reflect.DeepEqual(normalizedGot, normalizedWant) {
+		return ""
+	}
+	return fmt.Sprintf("= %s, want %s", formatValue(got), formatValue(want))
+}
+
+// normalizeValue converts a value to the form JSON decoding produces.
+func normalizeValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+// formatValue formats a value for a failure message.
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
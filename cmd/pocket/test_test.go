@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTestFile(t *testing.T) {
+	example := filepath.Join("..", "..", "examples", "cli", "order-router.test.yaml")
+	results, err := runTestFile(example, false)
+	if err != nil {
+		t.Fatalf("runTestFile() error = %v", err)
+	}
+	for _, r := range results {
+		if !r.Passed {
+			t.Errorf("Example %q failed: %v", r.Name, r.Failures)
+		}
+	}
+
+	dir := t.TempDir()
+	workflow, err := os.ReadFile(filepath.Join("..", "..", "examples", "cli", "order-router.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "workflow.yaml"), string(workflow))
+	writeTestFile(t, filepath.Join(dir, "workflow.test.yaml"), `workflow: workflow.yaml
+tests:
+  - name: wrong route
+    input: {type: order}
+    expect:
+      route: [route, refund]
+  - name: wrong output
+    input: {type: order}
+    expect:
+      output: {message: nope}
+  - name: store values
+    input: {type: order}
+    store: {seeded: 1, list: [a, b]}
+    expect:
+      store: {seeded: 1, list: [a, b], missing: true}
+  - name: expected error
+    input: {type: order}
+    expect:
+      error: boom
+  - name: golden
+    input: {type: refund}
+    golden: refund
+`)
+
+	path := filepath.Join(dir, "workflow.test.yaml")
+	if _, err := runTestFile(path, true); err != nil {
+		t.Fatalf("runTestFile() with update error = %v", err)
+	}
+	golden, err := os.ReadFile(filepath.Join(dir, "testdata", "refund.golden"))
+	if err != nil {
+		t.Fatalf("Golden file not written: %v", err)
+	}
+	if !strings.Contains(string(golden), `"Processing refund"`) || !strings.Contains(string(golden), `"route": [`) {
+		t.Errorf("Unexpected golden file:\n%s", golden)
+	}
+
+	results, err = runTestFile(path, false)
+	if err != nil {
+		t.Fatalf("runTestFile() error = %v", err)
+	}
+
+	want := map[string]string{
+		"wrong route":    "route = route -> order, want route -> refund",
+		"wrong output":   `output = {"input":{"type":"order"},"message":"Processing order","node":"order"}, want {"message":"nope"}`,
+		"store values":   "store key missing is missing, want true",
+		"expected error": `workflow succeeded, want error containing "boom"`,
+		"golden":         "",
+	}
+	if len(results) != len(want) {
+		t.Fatalf("runTestFile() = %d results, want %d", len(results), len(want))
+	}
+	for _, r := range results {
+		wantFailure := want[r.Name]
+		switch {
+		case wantFailure == "" && !r.Passed:
+			t.Errorf("%s: failed with %v", r.Name, r.Failures)
+		case wantFailure != "" && (r.Passed || len(r.Failures) != 1 || r.Failures[0] != wantFailure):
+			t.Errorf("%s: failures = %q, want [%q]", r.Name, r.Failures, wantFailure)
+		}
+	}
+
+	// A changed result no longer matches the golden file
+	writeTestFile(t, filepath.Join(dir, "testdata", "refund.golden"), "{}\n")
+	results, err = runTestFile(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if golden := results[len(results)-1]; golden.Passed || !strings.Contains(golden.Failures[0], "does not match") {
+		t.Errorf("golden: failures = %q, want mismatch", golden.Failures)
+	}
+}
+
+func TestFindTestFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "a.test.yaml"), "")
+	writeTestFile(t, filepath.Join(dir, "a.yaml"), "")
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "nested", "b.test.yaml"), "")
+
+	paths, err := findTestFiles(dir)
+	if err != nil {
+		t.Fatalf("findTestFiles() error = %v", err)
+	}
+	if len(paths) != 2 || filepath.Base(paths[0]) != "a.test.yaml" || filepath.Base(paths[1]) != "b.test.yaml" {
+		t.Errorf("findTestFiles() = %v, want a.test.yaml and nested/b.test.yaml", paths)
+	}
+
+	file := filepath.Join(dir, "a.yaml")
+	if paths, _ := findTestFiles(file); len(paths) != 1 || paths[0] != file {
+		t.Errorf("findTestFiles(file) = %v, want [%s]", paths, file)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	goyaml "github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/spf13/cobra"
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/agentstation/pocket/yaml"
+)
+
+// printSchema is the validate command's --print-schema flag.
+var printSchema bool
+
+// validateCmd represents the validate command.
+var validateCmd = &cobra.Command{
+	Use:   "validate <workflow.yaml>",
+	Short: "Check a workflow file for errors",
+	Long: `Check a workflow YAML file against the workflow JSON Schema and the
+rules applied before a run, such as the start node existing.
+
+Each problem is reported with its line and column, and the command exits
+non-zero if any are found, so it can gate workflows in CI. Use
+--print-schema to write the JSON Schema for use in editors.`,
+	Example: `  # Validate a workflow
+  pocket validate workflow.yaml
+
+  # Report problems as JSON
+  pocket validate workflow.yaml --output json
+
+  # Save the schema for editor integration
+  pocket validate --print-schema > workflow.schema.json`,
+	// Problems in the workflow are not usage errors
+	SilenceUsage: true,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if printSchema {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if printSchema {
+			_, err := os.Stdout.Write(yaml.WorkflowSchema())
+			return err
+		}
+
+		expandedPath, err := expandPath(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+
+		config := &ValidateConfig{
+			FilePath: expandedPath,
+			Format:   output,
+		}
+		return runValidate(config)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().BoolVar(&printSchema, "print-schema", false, "Print the workflow JSON Schema and exit")
+}
+
+// ValidateConfig holds configuration for the validate command.
+type ValidateConfig struct {
+	FilePath string
+	Format   string
+}
+
+// Violation is a problem found in a workflow file.
+type Violation struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	msg := v.Message
+	if v.Path != "" {
+		msg = v.Path + ": " + msg
+	}
+	if v.Line == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%d:%d: %s", v.Line, v.Column, msg)
+}
+
+// runValidate validates a workflow file and reports its violations.
+func runValidate(config *ValidateConfig) error {
+	data, err := os.ReadFile(config.FilePath) //nolint:gosec // User-provided workflow file
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	violations, err := validateWorkflow(data)
+	if err != nil {
+		return err
+	}
+
+	if config.Format == jsonFormat {
+		if violations == nil {
+			violations = []Violation{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(violations); err != nil {
+			return err
+		}
+	} else {
+		for _, v := range violations {
+			if v.Line == 0 {
+				fmt.Printf("%s: %s\n", config.FilePath, v)
+			} else {
+				fmt.Printf("%s:%s\n", config.FilePath, v)
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%s: %d problem(s) found", config.FilePath, len(violations))
+	}
+	if config.Format != jsonFormat {
+		fmt.Printf("%s: valid\n", config.FilePath)
+	}
+	return nil
+}
+
+// validateWorkflow checks workflow YAML against the workflow schema and,
+// if it conforms, the graph definition rules. Syntax errors are reported as
+// a single violation.
+func validateWorkflow(data []byte) ([]Violation, error) {
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return []Violation{syntaxViolation(err)}, nil
+	}
+
+	document, err := goyaml.YAMLToJSON(data)
+	if err != nil {
+		return []Violation{syntaxViolation(err)}, nil
+	}
+
+	result, err := gojsonschema.Validate(
+		gojsonschema.NewBytesLoader(yaml.WorkflowSchema()),
+		gojsonschema.NewBytesLoader(document),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("validate schema: %w", err)
+	}
+
+	var violations []Violation
+	for _, resultErr := range result.Errors() {
+		violations = append(violations, schemaViolation(file, resultErr))
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Line != violations[j].Line {
+			return violations[i].Line < violations[j].Line
+		}
+		return violations[i].Column < violations[j].Column
+	})
+	if len(violations) > 0 {
+		return violations, nil
+	}
+
+	var graphDef yaml.GraphDefinition
+	if err := goyaml.Unmarshal(data, &graphDef); err != nil {
+		return []Violation{syntaxViolation(err)}, nil
+	}
+	if violations := referenceViolations(file, &graphDef); len(violations) > 0 {
+		return violations, nil
+	}
+	if err := graphDef.Validate(); err != nil {
+		return []Violation{{Message: err.Error()}}, nil
+	}
+	return nil, nil
+}
+
+// referenceViolations reports duplicate node names and routes to nodes
+// that don't exist, located at the offending reference.
+func referenceViolations(file *ast.File, graphDef *yaml.GraphDefinition) []Violation {
+	var violations []Violation
+	report := func(message string, fields ...string) {
+		v := Violation{Path: strings.Join(fields, "."), Message: message}
+		if node := findYAMLNode(file, fields); node != nil {
+			pos := node.GetToken().Position
+			v.Line, v.Column = pos.Line, pos.Column
+		}
+		violations = append(violations, v)
+	}
+
+	names := make(map[string]bool)
+	for i, node := range graphDef.Nodes {
+		if names[node.Name] {
+			report(fmt.Sprintf("duplicate node name %s", node.Name), "nodes", strconv.Itoa(i), "name")
+		}
+		names[node.Name] = true
+	}
+
+	if !names[graphDef.Start] {
+		report(fmt.Sprintf("start node %s not found", graphDef.Start), "start")
+	}
+	for i, conn := range graphDef.Connections {
+		if !names[conn.From] {
+			report(fmt.Sprintf("connection from node %s not found", conn.From), "connections", strconv.Itoa(i), "from")
+		}
+		if !names[conn.To] {
+			report(fmt.Sprintf("connection to node %s not found", conn.To), "connections", strconv.Itoa(i), "to")
+		}
+	}
+	return violations
+}
+
+// syntaxViolation converts a YAML error into a violation, keeping its
+// position when it has one.
+func syntaxViolation(err error) Violation {
+	var yamlErr goyaml.Error
+	if errors.As(err, &yamlErr) && yamlErr.GetToken() != nil {
+		pos := yamlErr.GetToken().Position
+		return Violation{Line: pos.Line, Column: pos.Column, Message: yamlErr.GetMessage()}
+	}
+	return Violation{Message: err.Error()}
+}
+
+// schemaViolation converts a schema error into a violation located at the
+// YAML node it refers to.
+func schemaViolation(file *ast.File, resultErr gojsonschema.ResultError) Violation {
+	fields := []string{}
+	if field := resultErr.Field(); field != gojsonschema.STRING_CONTEXT_ROOT {
+		fields = strings.Split(field, ".")
+	}
+	// Point unknown properties at the offending key rather than its parent
+	if property, ok := resultErr.Details()["property"].(string); ok && resultErr.Type() == "additional_property_not_allowed" {
+		fields = append(fields, property)
+	}
+
+	v := Violation{
+		Path:    strings.Join(fields, "."),
+		Message: resultErr.Description(),
+	}
+
+	if node := findYAMLNode(file, fields); node != nil {
+		pos := node.GetToken().Position
+		v.Line, v.Column = pos.Line, pos.Column
+	}
+	return v
+}
+
+// findYAMLNode returns the node at a schema field path, or the nearest
+// ancestor that exists.
+func findYAMLNode(file *ast.File, fields []string) ast.Node {
+	for n := len(fields); n >= 0; n-- {
+		var path strings.Builder
+		path.WriteString("$")
+		for _, field := range fields[:n] {
+			if isIndex(field) {
+				fmt.Fprintf(&path, "[%s]", field)
+			} else {
+				fmt.Fprintf(&path, ".'%s'", strings.ReplaceAll(field, "'", `\'`))
+			}
+		}
+
+		p, err := goyaml.PathString(path.String())
+		if err != nil {
+			continue
+		}
+		if node, err := p.FilterFile(file); err == nil && node != nil {
+			return node
+		}
+	}
+	return nil
+}
+
+func isIndex(field string) bool {
+	if field == "" {
+		return false
+	}
+	for _, r := range field {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
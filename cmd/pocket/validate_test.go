@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/pocket/yaml"
+)
+
+func TestValidateWorkflow(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		wantOK bool
+		want   []Violation
+	}{
+		{
+			name: "valid workflow",
+			yaml: `name: demo
+start: first
+nodes:
+  - name: first
+    type: echo
+    timeout: 5s
+    retry:
+      max_attempts: 3
+      delay: 1s
+`,
+			wantOK: true,
+		},
+		{
+			name: "schema violations",
+			yaml: `name: demo
+start: first
+nodes:
+  - name: first
+    type: echo
+    timeout: soon
+    retries: 3
+  - type: echo
+connections:
+  - from: first
+`,
+			want: []Violation{
+				{Line: 6, Column: 14, Path: "nodes.0.timeout"},
+				{Line: 7, Column: 14, Path: "nodes.0.retries"},
+				{Line: 8, Column: 9, Path: "nodes.1"},
+				{Line: 10, Column: 9, Path: "connections.0"},
+			},
+		},
+		{
+			name: "missing required fields",
+			yaml: `description: no name
+`,
+			want: []Violation{
+				{Line: 1, Column: 12},
+				{Line: 1, Column: 12},
+				{Line: 1, Column: 12},
+			},
+		},
+		{
+			name: "syntax error",
+			yaml: `name: [
+`,
+			want: []Violation{{Line: 1, Column: 7}},
+		},
+		{
+			name: "unknown start node",
+			yaml: `name: demo
+start: missing
+nodes:
+  - name: first
+    type: echo
+`,
+			want: []Violation{{Line: 2, Column: 8, Path: "start"}},
+		},
+		{
+			name: "bad references",
+			yaml: `name: demo
+start: first
+nodes:
+  - name: first
+    type: echo
+  - name: first
+    type: echo
+connections:
+  - from: first
+    to: second
+`,
+			want: []Violation{
+				{Line: 6, Column: 11, Path: "nodes.1.name"},
+				{Line: 10, Column: 9, Path: "connections.0.to"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := validateWorkflow([]byte(tt.yaml))
+			if err != nil {
+				t.Fatalf("validateWorkflow() error = %v", err)
+			}
+
+			if tt.wantOK {
+				if len(violations) != 0 {
+					t.Errorf("Expected no violations, got %v", violations)
+				}
+				return
+			}
+
+			if len(violations) != len(tt.want) {
+				t.Fatalf("Expected %d violations, got %v", len(tt.want), violations)
+			}
+			for i, want := range tt.want {
+				got := violations[i]
+				if got.Line != want.Line || got.Column != want.Column || got.Path != want.Path {
+					t.Errorf("Violation %d = %+v, want line %d column %d path %q",
+						i, got, want.Line, want.Column, want.Path)
+				}
+				if got.Message == "" {
+					t.Errorf("Violation %d has no message", i)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkflowSchemaAcceptsExamples(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(yaml.WorkflowSchema(), &schema); err != nil {
+		t.Fatalf("Workflow schema is not valid JSON: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "examples", "cli", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		// Test files sit beside the workflows they test
+		if strings.HasSuffix(file, testFileSuffix) {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		violations, err := validateWorkflow(data)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if len(violations) > 0 {
+			t.Errorf("%s: unexpected violations %v", file, violations)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/yaml"
+)
+
+// ANSI escape sequences for the watch view.
+const (
+	ansiReset   = "\033[0m"
+	ansiBold    = "\033[1m"
+	ansiDim     = "\033[2m"
+	ansiRed     = "\033[31m"
+	ansiGreen   = "\033[32m"
+	ansiCurrent = "\033[1;7;36m" // Bold, reversed cyan
+)
+
+// How many routes and lines of node output the watch view shows.
+const (
+	watchRoutes      = 10
+	watchOutputLines = 5
+)
+
+// watchNode is the state of a node in the watch view.
+type watchNode struct {
+	runs     int
+	running  bool
+	started  time.Time
+	duration time.Duration // Of all runs
+	err      string
+}
+
+// runWatcher renders a live view of a workflow run in the terminal: each
+// node with its status and timing, the current node highlighted, the routes
+// taken, and the latest output streamed by nodes.
+type runWatcher struct {
+	mu     sync.Mutex
+	out    io.Writer
+	now    func() time.Time
+	name   string
+	order  []string
+	nodes  map[string]*watchNode
+	routes []string
+	output []string
+	start  time.Time
+	drawn  int // Lines drawn by the last render, to redraw over
+}
+
+func newRunWatcher(out io.Writer, def *yaml.GraphDefinition) *runWatcher {
+	w := &runWatcher{
+		out:   out,
+		now:   time.Now,
+		name:  def.Name,
+		nodes: make(map[string]*watchNode, len(def.Nodes)),
+	}
+	for _, node := range def.Nodes {
+		w.order = append(w.order, node.Name)
+		w.nodes[node.Name] = &watchNode{}
+	}
+	w.start = w.now()
+	return w
+}
+
+// handle updates the view with a run event.
+func (w *runWatcher) handle(_ context.Context, event pocket.Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	node := w.node(event.Node)
+	switch event.Type {
+	case pocket.EventNodeStart:
+		node.running = true
+		node.started = w.now()
+		node.err = ""
+	case pocket.EventNodeEnd:
+		node.running = false
+		node.runs++
+		node.duration += event.Duration
+		node.err = ""
+	case pocket.EventError:
+		node.running = false
+		node.runs++
+		node.duration += event.Duration
+		node.err = event.Err.Error()
+	case pocket.EventRetry:
+		node.err = fmt.Sprintf("attempt %d failed: %v", event.Attempt, event.Err)
+	case pocket.EventRoute:
+		next := event.Next
+		if next == "" {
+			next = "end"
+		}
+		w.routes = append(w.routes, fmt.Sprintf("%s -[%s]-> %s", event.Node, event.Action, next))
+	}
+	w.render()
+}
+
+// line adds a line of node output to the view.
+func (w *runWatcher) line(node, line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.output = append(w.output, fmt.Sprintf("[%s] %s", node, line))
+	if len(w.output) > watchOutputLines {
+		w.output = w.output[len(w.output)-watchOutputLines:]
+	}
+	w.render()
+}
+
+// refresh redraws the view periodically, so running timers advance, until
+// ctx is done, then draws it a final time.
+func (w *runWatcher) refresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			w.render()
+			w.mu.Unlock()
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			w.render()
+			w.mu.Unlock()
+		}
+	}
+}
+
+// node returns the state of a node, adding nodes not in the definition.
+// Must be called with the lock held.
+func (w *runWatcher) node(name string) *watchNode {
+	node, ok := w.nodes[name]
+	if !ok {
+		node = &watchNode{}
+		w.nodes[name] = node
+		w.order = append(w.order, name)
+	}
+	return node
+}
+
+// render draws the view over the last one. Must be called with the lock
+// held.
+func (w *runWatcher) render() {
+	now := w.now()
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s%s%s  %v\n\n", ansiBold, w.name, ansiReset, now.Sub(w.start).Round(100*time.Millisecond))
+
+	width := 0
+	for _, name := range w.order {
+		width = max(width, len(name))
+	}
+	for _, name := range w.order {
+		node := w.nodes[name]
+		switch {
+		case node.running:
+			fmt.Fprintf(&b, "%s▶ %-*s%s  running %v\n", ansiCurrent, width, name, ansiReset,
+				(node.duration + now.Sub(node.started)).Round(100*time.Millisecond))
+		case node.err != "" && node.runs > 0:
+			fmt.Fprintf(&b, "%s✗ %-*s  failed: %s%s\n", ansiRed, width, name, node.err, ansiReset)
+		case node.runs > 0:
+			fmt.Fprintf(&b, "%s✓%s %-*s  %v", ansiGreen, ansiReset, width, name, node.duration.Round(time.Millisecond))
+			if node.runs > 1 {
+				fmt.Fprintf(&b, " (%d runs)", node.runs)
+			}
+			b.WriteString("\n")
+		default:
+			fmt.Fprintf(&b, "%s· %-*s  pending%s\n", ansiDim, width, name, ansiReset)
+		}
+	}
+
+	if len(w.routes) > 0 {
+		b.WriteString("\nRoutes:\n")
+		routes := w.routes
+		if len(routes) > watchRoutes {
+			fmt.Fprintf(&b, "  ... %d earlier\n", len(routes)-watchRoutes)
+			routes = routes[len(routes)-watchRoutes:]
+		}
+		for _, route := range routes {
+			fmt.Fprintf(&b, "  %s\n", route)
+		}
+	}
+	if len(w.output) > 0 {
+		b.WriteString("\nOutput:\n")
+		for _, line := range w.output {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+
+	// Move up over the last view and clear it
+	if w.drawn > 0 {
+		fmt.Fprintf(w.out, "\033[%dA\033[J", w.drawn)
+	}
+	view := b.String()
+	fmt.Fprint(w.out, view)
+	w.drawn = strings.Count(view, "\n")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/yaml"
+)
+
+func TestRunWatcher(t *testing.T) {
+	def := &yaml.GraphDefinition{
+		Name: "review",
+		Nodes: []yaml.NodeDefinition{
+			{Name: "fetch"}, {Name: "summarize"}, {Name: "publish"},
+		},
+	}
+
+	var out bytes.Buffer
+	w := newRunWatcher(&out, def)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+	w.start = now
+
+	ctx := context.Background()
+	w.handle(ctx, pocket.Event{Type: pocket.EventNodeStart, Node: "fetch"})
+	w.handle(ctx, pocket.Event{Type: pocket.EventNodeEnd, Node: "fetch", Duration: 250 * time.Millisecond})
+	w.handle(ctx, pocket.Event{Type: pocket.EventRoute, Node: "fetch", Action: "default", Next: "summarize"})
+	w.handle(ctx, pocket.Event{Type: pocket.EventNodeStart, Node: "summarize"})
+	w.line("summarize", "thinking...")
+	now = now.Add(2 * time.Second)
+
+	out.Reset()
+	w.mu.Lock()
+	w.render()
+	w.mu.Unlock()
+	view := out.String()
+
+	for _, want := range []string{
+		"review" + ansiReset + "  2s",
+		ansiCurrent + "▶ summarize" + ansiReset + "  running 2s",
+		"fetch      250ms",
+		"· publish    pending",
+		"fetch -[default]-> summarize",
+		"[summarize] thinking...",
+	} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+	if !strings.HasPrefix(view, "\033[") {
+		t.Error("Expected the view to be drawn over the last one")
+	}
+
+	w.handle(ctx, pocket.Event{Type: pocket.EventError, Node: "summarize", Err: errors.New("rate limited")})
+	w.handle(ctx, pocket.Event{Type: pocket.EventRoute, Node: "publish", Action: "done"})
+	if view := out.String(); !strings.Contains(view, "✗ summarize  failed: rate limited") || !strings.Contains(view, "publish -[done]-> end") {
+		t.Errorf("Expected failed node and route to end:\n%s", view)
+	}
+}
@@ -0,0 +1,238 @@
+package compose
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/agentstation/pocket"
+)
+
+// ErrChannelClosed is returned when publishing to a closed channel.
+var ErrChannelClosed = errors.New("compose: channel closed")
+
+// ChannelOption configures a Channel.
+type ChannelOption func(*Channel)
+
+// WithBuffer sets how many values each subscriber can queue before
+// publishers block. The default is 0, so publishing waits for every
+// subscriber to receive the value.
+func WithBuffer(n int) ChannelOption {
+	return func(c *Channel) {
+		if n >= 0 {
+			c.buffer = n
+		}
+	}
+}
+
+// Channel is an in-process pub/sub channel that lets one running graph emit
+// values that other graphs consume, for producer/consumer topologies that
+// don't fit parent-child nesting. Every subscriber receives every value
+// published after it subscribed.
+type Channel struct {
+	name   string
+	buffer int
+
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+
+	// closing is closed by Close to release blocked publishers, and
+	// publishing lets Close wait for them before closing subscriptions.
+	closing    chan struct{}
+	publishing sync.WaitGroup
+}
+
+// NewChannel creates a channel.
+func NewChannel(name string, opts ...ChannelOption) *Channel {
+	c := &Channel{
+		name:    name,
+		subs:    make(map[*Subscription]struct{}),
+		closing: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Name returns the channel name.
+func (c *Channel) Name() string {
+	return c.name
+}
+
+// Subscription receives values from a Channel.
+type Subscription struct {
+	channel *Channel
+	values  chan any
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Subscribe registers a new subscriber. Subscribe before producers start
+// publishing to avoid missing values.
+func (c *Channel) Subscribe() *Subscription {
+	sub := &Subscription{
+		channel: c,
+		values:  make(chan any, c.buffer),
+		done:    make(chan struct{}),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		close(sub.values)
+	} else {
+		c.subs[sub] = struct{}{}
+	}
+	return sub
+}
+
+// Values returns the channel of received values. It is closed when the
+// Channel is closed.
+func (s *Subscription) Values() <-chan any {
+	return s.values
+}
+
+// Next waits for the next value. It returns false once the channel is closed
+// or the subscription is cancelled.
+func (s *Subscription) Next(ctx context.Context) (any, bool, error) {
+	select {
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	case <-s.done:
+		return nil, false, nil
+	case value, ok := <-s.values:
+		return value, ok, nil
+	}
+}
+
+// Unsubscribe stops delivery to the subscription. Publishers blocked on it
+// are released.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		close(s.done)
+
+		s.channel.mu.Lock()
+		delete(s.channel.subs, s)
+		s.channel.mu.Unlock()
+	})
+}
+
+// Publish delivers a value to every subscriber at the time of the call,
+// blocking while a subscriber's buffer is full. A slow subscriber only
+// blocks publishers, not Subscribe, Unsubscribe, or Close.
+func (c *Channel) Publish(ctx context.Context, value any) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return ErrChannelClosed
+	}
+	subs := make([]*Subscription, 0, len(c.subs))
+	for sub := range c.subs {
+		subs = append(subs, sub)
+	}
+	c.publishing.Add(1)
+	c.mu.RUnlock()
+	defer c.publishing.Done()
+
+	for _, sub := range subs {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.closing:
+			return ErrChannelClosed
+		case <-sub.done:
+		case sub.values <- value:
+		}
+	}
+	return nil
+}
+
+// Close closes the channel. Publishers blocked on a full subscriber return
+// ErrChannelClosed, and subscribers drain any buffered values and then see
+// the channel as closed.
+func (c *Channel) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.closing)
+	subs := make([]*Subscription, 0, len(c.subs))
+	for sub := range c.subs {
+		subs = append(subs, sub)
+	}
+	c.mu.Unlock()
+
+	// No publisher may send once a subscription's values are closed
+	c.publishing.Wait()
+	for _, sub := range subs {
+		close(sub.values)
+	}
+}
+
+// Emit creates a node that publishes its input to the channel and passes the
+// input through unchanged.
+func Emit(name string, ch *Channel) pocket.Node {
+	return pocket.NewNode[any, any](name,
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				if err := ch.Publish(ctx, input); err != nil {
+					return nil, fmt.Errorf("emit to %q: %w", ch.Name(), err)
+				}
+				return input, nil
+			},
+		},
+	)
+}
+
+// Receive creates a node that waits for the next value on the subscription
+// and outputs it. It routes to "default" with the value, or to "closed" once
+// the channel is closed, so a receive loop can end cleanly.
+func Receive(name string, sub *Subscription) pocket.Node {
+	type received struct {
+		value any
+		ok    bool
+	}
+
+	return pocket.NewNode[any, any](name,
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				value, ok, err := sub.Next(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return received{value: value, ok: ok}, nil
+			},
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, result any) (any, string, error) {
+				r := result.(received)
+				if !r.ok {
+					return nil, "closed", nil
+				}
+				return r.value, "default", nil
+			},
+		},
+	)
+}
+
+// Consume runs the graph once for every value received on the subscription
+// until the channel is closed, the context is done, or a run fails.
+func Consume(ctx context.Context, sub *Subscription, graph *pocket.Graph) error {
+	for {
+		value, ok, err := sub.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		if _, err := graph.Run(ctx, value); err != nil {
+			return fmt.Errorf("consume from %q: %w", sub.channel.Name(), err)
+		}
+	}
+}
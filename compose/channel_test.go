@@ -0,0 +1,190 @@
+package compose_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/compose"
+)
+
+// within fails the test if fn doesn't return within a second.
+func within(t *testing.T, what string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("%s blocked", what)
+	}
+}
+
+// drain collects every value from a subscription until the channel closes.
+func drain(sub *compose.Subscription) []any {
+	var values []any
+	for v := range sub.Values() {
+		values = append(values, v)
+	}
+	return values
+}
+
+func TestChannelFanOut(t *testing.T) {
+	ch := compose.NewChannel("events", compose.WithBuffer(3))
+	first := ch.Subscribe()
+	second := ch.Subscribe()
+
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		if err := ch.Publish(ctx, i); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	ch.Close()
+
+	want := []any{1, 2, 3}
+	for _, sub := range []*compose.Subscription{first, second} {
+		if got := drain(sub); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestChannelClosed(t *testing.T) {
+	ch := compose.NewChannel("events")
+	ch.Close()
+	ch.Close() // Closing twice is safe
+
+	if err := ch.Publish(context.Background(), 1); !errors.Is(err, compose.ErrChannelClosed) {
+		t.Errorf("Expected ErrChannelClosed, got %v", err)
+	}
+
+	value, ok, err := ch.Subscribe().Next(context.Background())
+	if value != nil || ok || err != nil {
+		t.Errorf("Expected closed subscription, got %v, %v, %v", value, ok, err)
+	}
+}
+
+func TestChannelSlowSubscriber(t *testing.T) {
+	ch := compose.NewChannel("events")
+	slow := ch.Subscribe()
+
+	published := make(chan error, 1)
+	go func() {
+		published <- ch.Publish(context.Background(), "value")
+	}()
+
+	// Wait until the publisher is blocked on the slow subscriber
+	time.Sleep(10 * time.Millisecond)
+
+	var late *compose.Subscription
+	within(t, "Subscribe", func() { late = ch.Subscribe() })
+	within(t, "Close", ch.Close)
+
+	if err := <-published; !errors.Is(err, compose.ErrChannelClosed) {
+		t.Errorf("Expected blocked publisher to get ErrChannelClosed, got %v", err)
+	}
+	for _, sub := range []*compose.Subscription{slow, late} {
+		if _, ok := <-sub.Values(); ok {
+			t.Error("Expected subscription to be closed")
+		}
+	}
+}
+
+func TestChannelUnsubscribe(t *testing.T) {
+	ch := compose.NewChannel("events")
+	sub := ch.Subscribe()
+
+	published := make(chan error, 1)
+	go func() {
+		published <- ch.Publish(context.Background(), "value")
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	sub.Unsubscribe()
+	if err := <-published; err != nil {
+		t.Errorf("Expected publish to an unsubscribed subscriber to succeed, got %v", err)
+	}
+
+	value, ok, err := sub.Next(context.Background())
+	if value != nil || ok || err != nil {
+		t.Errorf("Expected no value after Unsubscribe, got %v, %v, %v", value, ok, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := ch.Subscribe().Next(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestChannelEmitConsume(t *testing.T) {
+	ch := compose.NewChannel("orders")
+	sub := ch.Subscribe()
+
+	var (
+		mu       sync.Mutex
+		consumed []any
+	)
+	record := pocket.NewNode[any, any]("record",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				consumed = append(consumed, input)
+				return input, nil
+			},
+		},
+	)
+	consumer := pocket.NewGraph(record, pocket.NewStore())
+
+	consumeErr := make(chan error, 1)
+	go func() {
+		consumeErr <- compose.Consume(context.Background(), sub, consumer)
+	}()
+
+	emit := compose.Emit("emit", ch)
+	emit.Connect("default", constNode("done", "done"))
+	producer := pocket.NewGraph(emit, pocket.NewStore())
+	for _, order := range []string{"a", "b"} {
+		if _, err := producer.Run(context.Background(), order); err != nil {
+			t.Fatalf("producer Run failed: %v", err)
+		}
+	}
+	ch.Close()
+
+	if err := <-consumeErr; err != nil {
+		t.Fatalf("Consume failed: %v", err)
+	}
+	if want := []any{"a", "b"}; !reflect.DeepEqual(consumed, want) {
+		t.Errorf("Expected %v, got %v", want, consumed)
+	}
+}
+
+func TestReceive(t *testing.T) {
+	ch := compose.NewChannel("events", compose.WithBuffer(1))
+	receive := compose.Receive("receive", ch.Subscribe())
+	receive.Connect("closed", constNode("closed", "closed"))
+	graph := pocket.NewGraph(receive, pocket.NewStore())
+
+	if err := ch.Publish(context.Background(), "hello"); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	result, err := graph.Run(context.Background(), nil)
+	if err != nil || result != "hello" {
+		t.Errorf("Expected hello, got %v, %v", result, err)
+	}
+
+	ch.Close()
+	result, err = graph.Run(context.Background(), nil)
+	if err != nil || result != "closed" {
+		t.Errorf("Expected closed route, got %v, %v", result, err)
+	}
+}
@@ -111,9 +111,42 @@ func NewBuilder(name string, store pocket.Store) *Builder {
 	}
 }
 
+// Isolation decides which store a nested graph runs against, given the
+// builder's store. It is called for every run of the nested graph.
+type Isolation func(parent pocket.Store) pocket.Store
+
+// Isolated runs the nested graph against a fresh, empty store on every run,
+// so it neither sees nor changes parent state.
+func Isolated() Isolation {
+	return func(parent pocket.Store) pocket.Store {
+		return pocket.NewStore()
+	}
+}
+
+// Shared runs the nested graph directly against the builder's store.
+func Shared() Isolation {
+	return func(parent pocket.Store) pocket.Store {
+		return parent
+	}
+}
+
+// ScopedTo runs the nested graph against the builder's store scoped to
+// prefix, so its keys live under "prefix:" in the parent.
+func ScopedTo(prefix string) Isolation {
+	return func(parent pocket.Store) pocket.Store {
+		return parent.Scope(prefix)
+	}
+}
+
 // AddGraph adds a graph as a node in the composition.
-func (b *Builder) AddGraph(name string, graph *pocket.Graph) *Builder {
+//
+// Without an isolation policy the nested graph keeps using the store it was
+// created with. Pass Isolated, Shared, or ScopedTo to choose explicitly.
+func (b *Builder) AddGraph(name string, graph *pocket.Graph, isolation ...Isolation) *Builder {
 	node := graph.AsNode(name)
+	if len(isolation) > 0 {
+		node = b.isolatedNode(name, graph, isolation[len(isolation)-1])
+	}
 	b.nodes = append(b.nodes, node)
 	if b.start == nil {
 		b.start = node
@@ -121,6 +154,21 @@ func (b *Builder) AddGraph(name string, graph *pocket.Graph) *Builder {
 	return b
 }
 
+// isolatedNode wraps a graph so each run uses the store chosen by isolation.
+func (b *Builder) isolatedNode(name string, graph *pocket.Graph, isolation Isolation) pocket.Node {
+	return pocket.NewNode[any, any](name,
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				result, err := graph.WithStore(isolation(b.store)).Run(ctx, input)
+				if err != nil {
+					return nil, fmt.Errorf("graph %q failed: %w", name, err)
+				}
+				return result, nil
+			},
+		},
+	)
+}
+
 // AddGraphWithStore adds a graph with specific store keys for input/output isolation.
 func (b *Builder) AddGraphWithStore(name string, graph *pocket.Graph, inputKey, outputKey string) *Builder {
 	node := AsNodeWithStore(graph, name, inputKey, outputKey)
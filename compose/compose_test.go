@@ -0,0 +1,89 @@
+package compose_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/compose"
+)
+
+// counterGraph returns a graph that increments "count" in its store and
+// outputs the new count.
+func counterGraph(store pocket.Store) *pocket.Graph {
+	increment := pocket.NewNode[any, any]("increment",
+		pocket.Steps{
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+				count, _ := store.Get(ctx, "count")
+				n, _ := count.(int)
+				n++
+				if err := store.Set(ctx, "count", n); err != nil {
+					return nil, "", err
+				}
+				return n, "default", nil
+			},
+		},
+	)
+	return pocket.NewGraph(increment, store)
+}
+
+func TestBuilderIsolation(t *testing.T) {
+	tests := []struct {
+		name      string
+		isolation []compose.Isolation
+		// wantOutputs are the nested graph's outputs over two runs
+		wantOutputs [2]int
+		// parentKey is where the parent store should hold the count, and
+		// wantOwn whether the graph's own store should hold it
+		parentKey string
+		wantOwn   bool
+	}{
+		{"default keeps own store", nil, [2]int{1, 2}, "", true},
+		{"isolated", []compose.Isolation{compose.Isolated()}, [2]int{1, 1}, "", false},
+		{"shared", []compose.Isolation{compose.Shared()}, [2]int{1, 2}, "count", false},
+		{"scoped", []compose.Isolation{compose.ScopedTo("child")}, [2]int{1, 2}, "child:count", false},
+		{"last policy wins", []compose.Isolation{compose.Shared(), compose.ScopedTo("child")}, [2]int{1, 2}, "child:count", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			parent := pocket.NewStore()
+			own := pocket.NewStore()
+
+			graph, err := compose.NewBuilder("parent", parent).
+				AddGraph("child", counterGraph(own), tt.isolation...).
+				Build()
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+
+			for i, want := range tt.wantOutputs {
+				result, err := graph.Run(ctx, nil)
+				if err != nil {
+					t.Fatalf("Run failed: %v", err)
+				}
+				if result != want {
+					t.Errorf("run %d: expected %d, got %v", i+1, want, result)
+				}
+			}
+
+			if tt.parentKey != "" {
+				if count, _ := parent.Get(ctx, tt.parentKey); count != 2 {
+					t.Errorf("Expected parent %s = 2, got %v", tt.parentKey, count)
+				}
+			}
+			for _, key := range []string{"count", "child:count"} {
+				if key == tt.parentKey {
+					continue
+				}
+				if _, exists := parent.Get(ctx, key); exists {
+					t.Errorf("Expected parent store to have no %s", key)
+				}
+			}
+			if _, exists := own.Get(ctx, "count"); exists != tt.wantOwn {
+				t.Errorf("Expected graph's own store to have count: %v", tt.wantOwn)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package compose
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/agentstation/pocket"
+)
+
+// Params holds the values used to instantiate a Template.
+type Params map[string]any
+
+// placeholder matches {{name}} with optional surrounding spaces.
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_.-]*)\s*\}\}`)
+
+// Expand replaces {{name}} placeholders in s with parameter values.
+// It returns an error if a placeholder has no matching parameter.
+func (p Params) Expand(s string) (string, error) {
+	var missing []string
+	result := placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		key := placeholder.FindStringSubmatch(match)[1]
+		value, ok := p[key]
+		if !ok {
+			missing = append(missing, key)
+			return match
+		}
+		return fmt.Sprint(value)
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing template parameters: %s", strings.Join(missing, ", "))
+	}
+	return result, nil
+}
+
+// ExpandAll expands placeholders in every string inside a config value,
+// recursing into maps and slices. A string that is exactly one placeholder is
+// replaced by the parameter value itself, preserving its type.
+func (p Params) ExpandAll(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		if m := placeholder.FindStringSubmatch(v); m != nil && m[0] == v {
+			if param, ok := p[m[1]]; ok {
+				return param, nil
+			}
+		}
+		return p.Expand(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			expanded, err := p.ExpandAll(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = expanded
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			expanded, err := p.ExpandAll(item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+// NodeFactory creates a node for a template instance. The name has already
+// had its placeholders expanded.
+type NodeFactory func(name string, params Params) (pocket.Node, error)
+
+type templateNode struct {
+	name    string
+	factory NodeFactory
+}
+
+type templateRoute struct {
+	from, action, to string
+}
+
+// Template defines a graph skeleton whose node names, node configuration, and
+// routes are filled in from parameters at build time, so one skeleton can be
+// stamped out many times. Each instantiation creates fresh nodes.
+type Template struct {
+	name     string
+	nodes    []templateNode
+	routes   []templateRoute
+	start    string
+	defaults Params
+	required []string
+}
+
+// NewTemplate creates an empty graph template.
+func NewTemplate(name string) *Template {
+	return &Template{
+		name:     name,
+		defaults: Params{},
+	}
+}
+
+// Node adds a node to the template. The name may contain placeholders.
+// The first node added is the start node unless Start is called.
+func (t *Template) Node(name string, factory NodeFactory) *Template {
+	t.nodes = append(t.nodes, templateNode{name: name, factory: factory})
+	if t.start == "" {
+		t.start = name
+	}
+	return t
+}
+
+// Route connects two template nodes. Any of from, action, and to may contain
+// placeholders, so routing targets can be chosen per instance.
+func (t *Template) Route(from, action, to string) *Template {
+	t.routes = append(t.routes, templateRoute{from: from, action: action, to: to})
+	return t
+}
+
+// Start sets the start node by its template name.
+func (t *Template) Start(name string) *Template {
+	t.start = name
+	return t
+}
+
+// Default sets a parameter value used when an instance doesn't provide one.
+func (t *Template) Default(key string, value any) *Template {
+	t.defaults[key] = value
+	return t
+}
+
+// Require declares parameters every instance must provide.
+func (t *Template) Require(keys ...string) *Template {
+	t.required = append(t.required, keys...)
+	return t
+}
+
+// Instantiate builds a new graph from the template and parameters.
+func (t *Template) Instantiate(store pocket.Store, params Params, opts ...pocket.GraphOption) (*pocket.Graph, error) {
+	merged := make(Params, len(t.defaults)+len(params))
+	for k, v := range t.defaults {
+		merged[k] = v
+	}
+	for k, v := range params {
+		merged[k] = v
+	}
+
+	for _, key := range t.required {
+		if _, ok := merged[key]; !ok {
+			return nil, fmt.Errorf("template %q: missing required parameter %q", t.name, key)
+		}
+	}
+
+	if len(t.nodes) == 0 {
+		return nil, fmt.Errorf("template %q: no nodes defined", t.name)
+	}
+
+	// Nodes are keyed by both template name and expanded name so routes can
+	// refer to either
+	nodes := make(map[string]pocket.Node, len(t.nodes)*2)
+	for _, tn := range t.nodes {
+		name, err := merged.Expand(tn.name)
+		if err != nil {
+			return nil, fmt.Errorf("template %q: node %q: %w", t.name, tn.name, err)
+		}
+
+		node, err := tn.factory(name, merged)
+		if err != nil {
+			return nil, fmt.Errorf("template %q: node %q: %w", t.name, name, err)
+		}
+
+		nodes[tn.name] = node
+		nodes[name] = node
+	}
+
+	for _, r := range t.routes {
+		from, action, to, err := r.expand(merged)
+		if err != nil {
+			return nil, fmt.Errorf("template %q: route %s -%s-> %s: %w", t.name, r.from, r.action, r.to, err)
+		}
+
+		fromNode, ok := nodes[from]
+		if !ok {
+			return nil, fmt.Errorf("template %q: node %q not found", t.name, from)
+		}
+		toNode, ok := nodes[to]
+		if !ok {
+			return nil, fmt.Errorf("template %q: node %q not found", t.name, to)
+		}
+		fromNode.Connect(action, toNode)
+	}
+
+	start, err := merged.Expand(t.start)
+	if err != nil {
+		return nil, fmt.Errorf("template %q: start: %w", t.name, err)
+	}
+	startNode, ok := nodes[start]
+	if !ok {
+		return nil, fmt.Errorf("template %q: start node %q not found", t.name, start)
+	}
+
+	return pocket.NewGraph(startNode, store, opts...), nil
+}
+
+func (r templateRoute) expand(params Params) (from, action, to string, err error) {
+	if from, err = params.Expand(r.from); err != nil {
+		return "", "", "", err
+	}
+	if action, err = params.Expand(r.action); err != nil {
+		return "", "", "", err
+	}
+	if to, err = params.Expand(r.to); err != nil {
+		return "", "", "", err
+	}
+	return from, action, to, nil
+}
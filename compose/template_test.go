@@ -0,0 +1,163 @@
+package compose_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/compose"
+)
+
+// constNode returns a node that outputs value.
+func constNode(name string, value any) pocket.Node {
+	return pocket.NewNode[any, any](name,
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return value, nil
+			},
+		},
+	)
+}
+
+func TestParamsExpand(t *testing.T) {
+	params := compose.Params{"dataset": "orders", "limit": 10}
+
+	got, err := params.Expand("load-{{dataset}}-{{ limit }}")
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if got != "load-orders-10" {
+		t.Errorf("Expected load-orders-10, got %s", got)
+	}
+
+	if _, err := params.Expand("{{missing}}"); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected missing parameter error, got %v", err)
+	}
+}
+
+func TestParamsExpandAll(t *testing.T) {
+	params := compose.Params{"table": "orders", "limit": 10}
+	config := map[string]any{
+		"query": "SELECT * FROM {{table}}",
+		"limit": "{{limit}}",
+		"tags":  []any{"{{table}}", 3},
+	}
+
+	got, err := params.ExpandAll(config)
+	if err != nil {
+		t.Fatalf("ExpandAll failed: %v", err)
+	}
+
+	want := map[string]any{
+		"query": "SELECT * FROM orders",
+		"limit": 10, // A whole-string placeholder keeps the parameter's type
+		"tags":  []any{"orders", 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if _, err := params.ExpandAll(map[string]any{"x": []any{"{{nope}}"}}); err == nil {
+		t.Error("Expected error for missing parameter")
+	}
+}
+
+func TestTemplateInstantiate(t *testing.T) {
+	var names []string
+	etl := compose.NewTemplate("etl").
+		Node("extract-{{dataset}}", func(name string, params compose.Params) (pocket.Node, error) {
+			names = append(names, name)
+			return pocket.NewNode[any, any](name,
+				pocket.Steps{
+					Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+						return params["dataset"], params["route"].(string), nil
+					},
+				},
+			), nil
+		}).
+		Node("fast", func(name string, params compose.Params) (pocket.Node, error) {
+			return constNode(name, "fast:"+params["dataset"].(string)), nil
+		}).
+		Node("slow", func(name string, params compose.Params) (pocket.Node, error) {
+			return constNode(name, "slow:"+params["dataset"].(string)), nil
+		}).
+		Route("extract-{{dataset}}", "fast", "fast").
+		Route("extract-{{dataset}}", "slow", "{{slow_target}}").
+		Default("slow_target", "slow").
+		Require("dataset", "route")
+
+	tests := []struct {
+		params compose.Params
+		want   any
+	}{
+		{compose.Params{"dataset": "orders", "route": "fast"}, "fast:orders"},
+		{compose.Params{"dataset": "users", "route": "slow"}, "slow:users"},
+		// Route targets can be chosen per instance
+		{compose.Params{"dataset": "events", "route": "slow", "slow_target": "fast"}, "fast:events"},
+	}
+
+	for _, tt := range tests {
+		graph, err := etl.Instantiate(pocket.NewStore(), tt.params)
+		if err != nil {
+			t.Fatalf("Instantiate(%v) failed: %v", tt.params, err)
+		}
+		result, err := graph.Run(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if result != tt.want {
+			t.Errorf("Expected %v, got %v", tt.want, result)
+		}
+	}
+
+	// Each instance gets fresh nodes with expanded names
+	if want := []string{"extract-orders", "extract-users", "extract-events"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected nodes %v, got %v", want, names)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	factory := func(name string, params compose.Params) (pocket.Node, error) {
+		return constNode(name, nil), nil
+	}
+
+	tests := []struct {
+		name     string
+		template *compose.Template
+		params   compose.Params
+		want     string
+	}{
+		{
+			name:     "missing required parameter",
+			template: compose.NewTemplate("t").Node("a", factory).Require("dataset"),
+			want:     `missing required parameter "dataset"`,
+		},
+		{
+			name:     "no nodes",
+			template: compose.NewTemplate("t"),
+			want:     "no nodes defined",
+		},
+		{
+			name:     "unknown route target",
+			template: compose.NewTemplate("t").Node("a", factory).Route("a", "default", "{{to}}"),
+			params:   compose.Params{"to": "b"},
+			want:     `node "b" not found`,
+		},
+		{
+			name:     "unknown start",
+			template: compose.NewTemplate("t").Node("a", factory).Start("b"),
+			want:     `start node "b" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.template.Instantiate(pocket.NewStore(), tt.params)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
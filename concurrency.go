@@ -0,0 +1,229 @@
+package pocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AdaptiveConcurrency configures an AIMD (additive increase, multiplicative
+// decrease) concurrency controller. Parallelism grows by roughly one slot per
+// round of successful calls and is cut by BackoffRatio whenever a call fails
+// or exceeds LatencyTarget, which keeps fan-outs from hammering rate-limited
+// APIs.
+type AdaptiveConcurrency struct {
+	// Min is the lowest concurrency the controller will use. Defaults to 1.
+	Min int
+
+	// Max is the highest concurrency the controller will use. Defaults to 64.
+	Max int
+
+	// Initial is the starting concurrency. Defaults to Min.
+	Initial int
+
+	// LatencyTarget treats slower calls as overload. Zero disables the
+	// latency signal so only errors reduce concurrency.
+	LatencyTarget time.Duration
+
+	// BackoffRatio multiplies the limit on overload. Defaults to 0.5.
+	BackoffRatio float64
+}
+
+// AdaptiveLimiter bounds in-flight work using an AIMD controller.
+// It is safe for concurrent use.
+type AdaptiveLimiter struct {
+	mu       sync.Mutex
+	cfg      AdaptiveConcurrency
+	limit    float64
+	inflight int
+	wake     chan struct{}
+}
+
+// NewAdaptiveLimiter creates a limiter from the configuration, filling in
+// defaults for zero fields.
+func NewAdaptiveLimiter(cfg AdaptiveConcurrency) *AdaptiveLimiter {
+	if cfg.Min < 1 {
+		cfg.Min = 1
+	}
+	if cfg.Max == 0 {
+		cfg.Max = 64
+	}
+	if cfg.Max < cfg.Min {
+		cfg.Max = cfg.Min
+	}
+	if cfg.Initial < cfg.Min {
+		cfg.Initial = cfg.Min
+	}
+	if cfg.Initial > cfg.Max {
+		cfg.Initial = cfg.Max
+	}
+	if cfg.BackoffRatio <= 0 || cfg.BackoffRatio >= 1 {
+		cfg.BackoffRatio = 0.5
+	}
+
+	return &AdaptiveLimiter{
+		cfg:   cfg,
+		limit: float64(cfg.Initial),
+		wake:  make(chan struct{}),
+	}
+}
+
+// Acquire blocks until a slot is available or the context is done.
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inflight < int(l.limit) {
+			l.inflight++
+			l.mu.Unlock()
+			return nil
+		}
+		wake := l.wake
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wake:
+		}
+	}
+}
+
+// Release frees a slot and feeds the call's latency and error into the
+// controller.
+func (l *AdaptiveLimiter) Release(latency time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+
+	overloaded := err != nil || (l.cfg.LatencyTarget > 0 && latency > l.cfg.LatencyTarget)
+	if overloaded {
+		l.limit *= l.cfg.BackoffRatio
+		if l.limit < float64(l.cfg.Min) {
+			l.limit = float64(l.cfg.Min)
+		}
+	} else {
+		l.limit += 1 / l.limit
+		if l.limit > float64(l.cfg.Max) {
+			l.limit = float64(l.cfg.Max)
+		}
+	}
+
+	// Wake all waiters so they re-check the limit
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// Limit returns the current concurrency limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// MaxConcurrency returns the upper bound on the limit.
+func (l *AdaptiveLimiter) MaxConcurrency() int {
+	return l.cfg.Max
+}
+
+// FanOutOption configures FanOut.
+type FanOutOption func(*fanOutOptions)
+
+type fanOutOptions struct {
+	concurrency int
+	limiter     *AdaptiveLimiter
+}
+
+// WithFanOutConcurrency caps the number of items processed at once.
+// By default every item runs concurrently.
+func WithFanOutConcurrency(n int) FanOutOption {
+	return func(o *fanOutOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithAdaptiveConcurrency lets FanOut adjust its parallelism from observed
+// latency and errors instead of using a fixed worker count.
+func WithAdaptiveConcurrency(cfg AdaptiveConcurrency) FanOutOption {
+	return func(o *fanOutOptions) {
+		o.limiter = NewAdaptiveLimiter(cfg)
+	}
+}
+
+// WithAdaptiveLimiter shares an existing limiter across FanOut calls, so
+// concurrency learned in one call carries over to the next.
+func WithAdaptiveLimiter(l *AdaptiveLimiter) FanOutOption {
+	return func(o *fanOutOptions) {
+		o.limiter = l
+	}
+}
+
+// bulkhead is a named pool of execution slots shared by every node
+// configured with WithBulkhead for that name.
+type bulkhead struct {
+	name  string
+	slots chan struct{}
+	queue int
+
+	mu      sync.Mutex
+	waiting int
+}
+
+var (
+	bulkheadsMu sync.Mutex
+	bulkheads   = make(map[string]*bulkhead)
+)
+
+// bulkheadPool returns the pool registered under name, creating it with the
+// given size and queue length on first use.
+func bulkheadPool(name string, size, queue int) *bulkhead {
+	bulkheadsMu.Lock()
+	defer bulkheadsMu.Unlock()
+
+	if b, ok := bulkheads[name]; ok {
+		return b
+	}
+	b := &bulkhead{
+		name:  name,
+		slots: make(chan struct{}, max(size, 1)),
+		queue: max(queue, 0),
+	}
+	bulkheads[name] = b
+	return b
+}
+
+// acquire takes a slot, waiting in the queue if the pool is busy. It fails
+// immediately with ErrBulkheadFull when the queue is also full.
+func (b *bulkhead) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	b.mu.Lock()
+	if b.waiting >= b.queue {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrBulkheadFull, b.name)
+	}
+	b.waiting++
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.waiting--
+		b.mu.Unlock()
+	}()
+
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *bulkhead) release() {
+	<-b.slots
+}
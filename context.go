@@ -0,0 +1,160 @@
+package pocket
+
+import (
+	"context"
+	"sync"
+)
+
+// WithContextValues adds values to the context of each run, so node
+// functions can read request-scoped dependencies such as a user ID or an
+// API client with ctx.Value instead of passing them through the store. Keys
+// follow the context package's rules and should be of unexported types.
+//
+// Example:
+//
+//	type userKey struct{}
+//	graph := pocket.NewGraph(start, store,
+//		pocket.WithContextValues(map[any]any{userKey{}: "alice"}))
+func WithContextValues(values map[any]any) GraphOption {
+	return func(o *graphOptions) {
+		if o.contextValues == nil {
+			o.contextValues = make(map[any]any, len(values))
+		}
+		for key, value := range values {
+			o.contextValues[key] = value
+		}
+	}
+}
+
+// RunID returns the ID of the run set with WithRunID, or "" if there is
+// none.
+func RunID(ctx context.Context) string {
+	id, _ := RunIDFrom(ctx)
+	return id
+}
+
+// NodeName returns the name of the node being run, or "" outside of a node.
+func NodeName(ctx context.Context) string {
+	if sc := stepContextFrom(ctx); sc != nil {
+		return sc.node
+	}
+	return ""
+}
+
+// Attempt returns the number of the current attempt at the running step,
+// starting at 1 and increasing with each retry, or 0 outside of a node.
+func Attempt(ctx context.Context) int {
+	if sc := stepContextFrom(ctx); sc != nil {
+		return sc.attempt
+	}
+	return 0
+}
+
+type stepContextKey struct{}
+
+// stepContext names the node being run and the attempt at its step. Each
+// node and attempt gets its own, so a step that outlives its timeout keeps
+// reporting the node and attempt it was started for.
+type stepContext struct {
+	context.Context
+	node    string
+	attempt int
+}
+
+// withNode returns the context of running the node named name.
+func withNode(ctx context.Context, name string) context.Context {
+	return &stepContext{Context: ctx, node: name}
+}
+
+// withAttempt returns the context of an attempt at a step of the node being
+// run.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return &stepContext{Context: ctx, node: NodeName(ctx), attempt: attempt}
+}
+
+func stepContextFrom(ctx context.Context) *stepContext {
+	sc, _ := ctx.Value(stepContextKey{}).(*stepContext)
+	return sc
+}
+
+func (c *stepContext) Value(key any) any {
+	if key == (stepContextKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+type runContextKey struct{}
+
+// runContext is the context of a run. It answers for the graph's clock and
+// context values, so a run costs one allocation for them rather than a
+// context.WithValue per value.
+type runContext struct {
+	context.Context
+	graph *graph
+
+	// shutdown ends a run whose parent context can't be canceled, in its
+	// place, and cancel ends a run with a context of its own
+	shutdown context.Context
+	cancel   context.CancelCauseFunc
+
+	mu      sync.Mutex
+	started bool
+}
+
+// newRunContext returns the context of a run of g.
+func newRunContext(ctx context.Context, g *graph) *runContext {
+	return &runContext{Context: ctx, graph: g}
+}
+
+// claim returns the context of a run of g that hasn't started executing,
+// as made for it by Run, and marks it started.
+func (c *runContext) claim(g *graph) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.graph != g || c.started {
+		return false
+	}
+	c.started = true
+	return true
+}
+
+// runContextFrom returns the context of the innermost run ctx belongs to,
+// or nil.
+func runContextFrom(ctx context.Context) *runContext {
+	rc, _ := ctx.Value(runContextKey{}).(*runContext)
+	return rc
+}
+
+func (c *runContext) Done() <-chan struct{} {
+	if c.shutdown != nil {
+		return c.shutdown.Done()
+	}
+	return c.Context.Done()
+}
+
+func (c *runContext) Err() error {
+	if c.shutdown != nil {
+		return c.shutdown.Err()
+	}
+	return c.Context.Err()
+}
+
+func (c *runContext) Value(key any) any {
+	switch key {
+	case runContextKey{}:
+		return c
+	case clockKey{}:
+		if c.graph.opts.clock != nil {
+			return c.graph.opts.clock
+		}
+	}
+	if value, ok := c.graph.opts.contextValues[key]; ok {
+		return value
+	}
+	if value := c.Context.Value(key); value != nil || c.shutdown == nil {
+		return value
+	}
+	// Lets context.Cause and derived contexts find the shutdown context
+	return c.shutdown.Value(key)
+}
@@ -0,0 +1,94 @@
+package pocket
+
+import "reflect"
+
+// deepCopy returns a copy of v that shares no maps, slices, or pointers with
+// the original. Unexported struct fields, channels, and functions are copied
+// shallowly. Cycles through pointers and maps are preserved.
+func deepCopy(v any) any {
+	if v == nil {
+		return nil
+	}
+	c := copier{seen: make(map[copyKey]reflect.Value)}
+	return c.copy(reflect.ValueOf(v)).Interface()
+}
+
+type copyKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+type copier struct {
+	seen map[copyKey]reflect.Value
+}
+
+func (c copier) copy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		key := copyKey{v.Pointer(), v.Type()}
+		if dup, ok := c.seen[key]; ok {
+			return dup
+		}
+		dup := reflect.MakeMapWithSize(v.Type(), v.Len())
+		c.seen[key] = dup
+		iter := v.MapRange()
+		for iter.Next() {
+			dup.SetMapIndex(iter.Key(), c.copy(iter.Value()))
+		}
+		return dup
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		dup := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			dup.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return dup
+
+	case reflect.Array:
+		dup := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			dup.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return dup
+
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		key := copyKey{v.Pointer(), v.Type()}
+		if dup, ok := c.seen[key]; ok {
+			return dup
+		}
+		dup := reflect.New(v.Type().Elem())
+		c.seen[key] = dup
+		dup.Elem().Set(c.copy(v.Elem()))
+		return dup
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		dup := reflect.New(v.Type()).Elem()
+		dup.Set(c.copy(v.Elem()))
+		return dup
+
+	case reflect.Struct:
+		dup := reflect.New(v.Type()).Elem()
+		dup.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := dup.Field(i); field.CanSet() {
+				field.Set(c.copy(v.Field(i)))
+			}
+		}
+		return dup
+
+	default:
+		return v
+	}
+}
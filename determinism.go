@@ -0,0 +1,72 @@
+package pocket
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+)
+
+type randKey struct{}
+
+// determinism seeds the random source of each run.
+type determinism struct {
+	seed int64
+}
+
+// WithDeterminism makes runs reproducible. Each run gets a random source
+// seeded with seed, and the clock, through its context, so nodes that use
+// RandFrom and ClockFrom make the same choices and see the same times on
+// every run with the same input. A nil clock leaves nodes on the system
+// clock; see WithClock. Nested graphs inherit the source and clock unless
+// they set their own.
+//
+// Nodes running concurrently share the source, so their draws are only
+// reproducible if they happen in the same order.
+//
+// Example:
+//
+//	graph := pocket.NewGraph(start, store, pocket.WithDeterminism(42, clock))
+//
+//	// In a node
+//	if r, ok := pocket.RandFrom(ctx); ok {
+//		score = r.Float64()
+//	}
+func WithDeterminism(seed int64, clock Clock) GraphOption {
+	return func(o *graphOptions) {
+		o.determinism = &determinism{seed: seed}
+		if clock != nil {
+			o.clock = clock
+		}
+	}
+}
+
+// context returns a context carrying a fresh random source.
+func (d *determinism) context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, randKey{}, rand.New(&lockedSource{src: rand.NewSource(d.seed)})) //nolint:gosec // Seeded for reproducibility
+}
+
+// RandFrom returns the seeded random source of a run made deterministic
+// with WithDeterminism, if any. It is safe for concurrent use, except for
+// its Read method.
+func RandFrom(ctx context.Context) (*rand.Rand, bool) {
+	r, ok := ctx.Value(randKey{}).(*rand.Rand)
+	return r, ok
+}
+
+// lockedSource makes a rand.Source safe for concurrent use.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
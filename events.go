@@ -0,0 +1,108 @@
+package pocket
+
+import (
+	"context"
+	"time"
+)
+
+// EventType identifies a step of a run reported to event handlers.
+type EventType string
+
+// Event types.
+const (
+	// EventNodeStart is sent before a node runs.
+	EventNodeStart EventType = "node_start"
+
+	// EventNodeEnd is sent after a node succeeds, with its output.
+	EventNodeEnd EventType = "node_end"
+
+	// EventRoute is sent after EventNodeEnd with the action the node
+	// returned and the node it leads to, if any.
+	EventRoute EventType = "route"
+
+	// EventRetry is sent when a step of a node fails and will be retried.
+	EventRetry EventType = "retry"
+
+	// EventError is sent when a node fails. The run ends unless the node
+	// has a fallback node from WithFallbackNode.
+	EventError EventType = "error"
+
+	// EventFallback is sent when a node's Fallback step or fallback node
+	// recovers from a failure, with the error it suppressed.
+	EventFallback EventType = "fallback"
+)
+
+// Event describes a step of a run.
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	// RunID is the run's ID from WithRunID, if any.
+	RunID string
+
+	// Node is the name of the node the event is about.
+	Node string
+
+	// Action is the action a node returned, and Next the name of the node
+	// it routes to, empty when the run ends. Set for EventRoute.
+	Action string
+	Next   string
+
+	// Attempt is the number of the failed attempt. Set for EventRetry.
+	Attempt int
+
+	// Duration is how long the node ran. Set for EventNodeEnd and
+	// EventError.
+	Duration time.Duration
+
+	// Output is the node's output. Set for EventNodeEnd.
+	Output any
+
+	// Err is the error. Set for EventRetry, EventError, and EventFallback.
+	Err error
+}
+
+// EventHandler receives the events of a graph's runs. It is called
+// synchronously from the run, so it should return quickly; handlers of
+// concurrent runs must be safe for concurrent use.
+type EventHandler func(ctx context.Context, event Event)
+
+// WithEventHandler sends the graph's execution events to a handler, for
+// progress displays and monitoring built outside the graph. It can be
+// given more than once.
+func WithEventHandler(handler EventHandler) GraphOption {
+	return func(o *graphOptions) {
+		o.events = append(o.events, handler)
+	}
+}
+
+// observed reports whether anything receives the events of a run of g
+// with ctx.
+func (g *graph) observed(ctx context.Context) bool {
+	if len(g.opts.events) > 0 {
+		return true
+	}
+	reporter, _ := ctx.Value(reporterKey{}).(*runReporter)
+	return reporter != nil && reporter.graph == g
+}
+
+// emit sends an event to the graph's handlers, filling in its time and run
+// ID.
+func (g *graph) emit(ctx context.Context, event Event) {
+	reporter, _ := ctx.Value(reporterKey{}).(*runReporter)
+	if reporter != nil && reporter.graph != g {
+		reporter = nil // Reporting on another graph that runs this one
+	}
+	if len(g.opts.events) == 0 && reporter == nil {
+		return
+	}
+
+	event.Time = ClockFrom(ctx).Now()
+	event.RunID, _ = RunIDFrom(ctx)
+	for _, handler := range g.opts.events {
+		handler(ctx, event)
+	}
+	if reporter != nil {
+		reporter.handle(event)
+	}
+}
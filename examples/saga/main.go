@@ -7,8 +7,8 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math/rand"
 	"strings"
 	"time"
 
@@ -32,9 +32,6 @@ type TransactionState struct {
 }
 
 func main() {
-	// Initialize random number generator
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-
 	// Create store
 	store := pocket.NewStore()
 	ctx := context.Background()
@@ -76,11 +73,6 @@ func main() {
 			o := data["order"].(*Order)
 			fmt.Printf("Reserving inventory for order %s...\n", o.ID)
 
-			// Simulate potential failure
-			if rng.Float32() > 0.7 {
-				return nil, fmt.Errorf("insufficient inventory")
-			}
-
 			// Prepare reservation data for post step
 			return map[string]interface{}{
 				"order":          o,
@@ -144,11 +136,6 @@ func main() {
 			o := data["order"].(*Order)
 			fmt.Printf("Charging payment of $%.2f for order %s...\n", o.Amount, o.ID)
 
-			// Simulate potential failure
-			if rng.Float32() > 0.8 {
-				return nil, fmt.Errorf("payment declined")
-			}
-
 			// Prepare payment data for post step
 			return map[string]interface{}{
 				"order":      o,
@@ -204,11 +191,6 @@ func main() {
 			o := order.(*Order)
 			fmt.Printf("Creating shipment for order %s...\n", o.ID)
 
-			// Simulate potential failure
-			if rng.Float32() > 0.9 {
-				return nil, fmt.Errorf("shipping service unavailable")
-			}
-
 			// Create shipment
 			shipmentID := fmt.Sprintf("SHIP-%s-%d", o.ID, time.Now().Unix())
 
@@ -430,6 +412,25 @@ func main() {
 		},
 	}
 
+	// Simulate failures of the services each step calls
+	failures := []pocket.GraphOption{
+		pocket.WithChaos(pocket.ChaosConfig{
+			Nodes:     []string{"reserve_inventory"},
+			ErrorRate: 0.3,
+			Error:     errors.New("insufficient inventory"),
+		}),
+		pocket.WithChaos(pocket.ChaosConfig{
+			Nodes:     []string{"charge_payment"},
+			ErrorRate: 0.2,
+			Error:     errors.New("payment declined"),
+		}),
+		pocket.WithChaos(pocket.ChaosConfig{
+			Nodes:     []string{"create_shipment"},
+			ErrorRate: 0.1,
+			Error:     errors.New("shipping service unavailable"),
+		}),
+	}
+
 	for _, order := range orders {
 		fmt.Printf("\n📦 Processing Order: %s\n", order.ID)
 		fmt.Println("------------------------")
@@ -441,8 +442,8 @@ func main() {
 			continue
 		}
 
-		// Create saga graph
-		graph := pocket.NewGraph(reserveInventory, store)
+		// Create saga graph, injecting failures to exercise compensation
+		graph := pocket.NewGraph(reserveInventory, store, failures...)
 		result, err := graph.Run(ctx, order)
 
 		if err != nil {
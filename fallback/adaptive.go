@@ -0,0 +1,148 @@
+package fallback
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SelectionOption configures adaptive link selection.
+type SelectionOption func(*adaptiveSelector)
+
+// WithSmoothing sets the EWMA smoothing factor in (0, 1]. Higher values react
+// faster to recent results. The default is 0.2.
+func WithSmoothing(alpha float64) SelectionOption {
+	return func(s *adaptiveSelector) {
+		if alpha > 0 && alpha <= 1 {
+			s.alpha = alpha
+		}
+	}
+}
+
+// WithLatencyWeight sets how strongly latency lowers a link's score relative
+// to its success rate. Zero ranks by success rate alone. The default is 0.5.
+func WithLatencyWeight(w float64) SelectionOption {
+	return func(s *adaptiveSelector) {
+		if w >= 0 {
+			s.latencyWeight = w
+		}
+	}
+}
+
+// linkEWMA holds exponentially weighted averages for one link.
+type linkEWMA struct {
+	success float64
+	latency float64 // seconds
+}
+
+// adaptiveSelector scores links from their recent success rate and latency.
+type adaptiveSelector struct {
+	mu            sync.Mutex
+	alpha         float64
+	latencyWeight float64
+	links         map[string]*linkEWMA
+}
+
+// WithAdaptiveSelection ranks links by an EWMA of their success rate and
+// latency instead of their static order and weights. Sequential strategies
+// try the best-scoring link first and WeightedRandomStrategy uses the scores
+// as weights. Links without history start with a perfect score so they are
+// tried.
+func (c *Chain) WithAdaptiveSelection(opts ...SelectionOption) *Chain {
+	selector := &adaptiveSelector{
+		alpha:         0.2,
+		latencyWeight: 0.5,
+		links:         make(map[string]*linkEWMA),
+	}
+	for _, opt := range opts {
+		opt(selector)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.adaptive = selector
+	return c
+}
+
+// observe folds one execution result into the link's averages.
+func (s *adaptiveSelector) observe(name string, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	success := 1.0
+	if err != nil {
+		success = 0
+	}
+
+	stats, ok := s.links[name]
+	if !ok {
+		s.links[name] = &linkEWMA{success: success, latency: latency.Seconds()}
+		return
+	}
+
+	stats.success = s.alpha*success + (1-s.alpha)*stats.success
+	stats.latency = s.alpha*latency.Seconds() + (1-s.alpha)*stats.latency
+}
+
+// scores returns each link's score in [0, 1]. Latency is normalized
+// against the slowest link so it only matters relative to the alternatives.
+func (s *adaptiveSelector) scores(links []Link) map[string]float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var slowest float64
+	for _, link := range links {
+		if stats, ok := s.links[link.Name]; ok && stats.latency > slowest {
+			slowest = stats.latency
+		}
+	}
+
+	scores := make(map[string]float64, len(links))
+	for _, link := range links {
+		stats, ok := s.links[link.Name]
+		if !ok {
+			scores[link.Name] = 1
+			continue
+		}
+
+		penalty := 0.0
+		if slowest > 0 {
+			penalty = s.latencyWeight * stats.latency / slowest
+		}
+		scores[link.Name] = stats.success / (1 + penalty)
+	}
+	return scores
+}
+
+// rank returns copies of the links sorted by score, best first, with each
+// weight replaced by its score. Ties keep their configured order.
+func (s *adaptiveSelector) rank(links []Link) []Link {
+	scores := s.scores(links)
+
+	ranked := make([]Link, len(links))
+	copy(ranked, links)
+	for i := range ranked {
+		// Keep a small floor so weighted selection can still explore
+		ranked[i].Weight = max(scores[ranked[i].Name], 0.01)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].Name] > scores[ranked[j].Name]
+	})
+	return ranked
+}
+
+// snapshot returns the current averages and score for a link.
+func (s *adaptiveSelector) snapshot(name string, links []Link) (success float64, latency time.Duration, score float64, ok bool) {
+	scores := s.scores(links)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.links[name]
+	if !ok {
+		return 0, 0, 0, false
+	}
+	return stats.success, time.Duration(stats.latency * float64(time.Second)), scores[name], true
+}
@@ -0,0 +1,87 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket"
+)
+
+func TestChainAdaptiveSelection(t *testing.T) {
+	var calls []string
+	chain := NewChain("test").
+		AddLink(Link{
+			Name: "flaky",
+			Handler: func(ctx context.Context, input any) (any, error) {
+				calls = append(calls, "flaky")
+				return nil, errors.New("unavailable")
+			},
+		}).
+		AddLink(Link{
+			Name: "slow",
+			Handler: func(ctx context.Context, input any) (any, error) {
+				calls = append(calls, "slow")
+				time.Sleep(5 * time.Millisecond)
+				return "slow", nil
+			},
+		}).
+		AddLink(Link{
+			Name: "fast",
+			Handler: func(ctx context.Context, input any) (any, error) {
+				calls = append(calls, "fast")
+				return "fast", nil
+			},
+		}).
+		WithAdaptiveSelection(WithSmoothing(0.5))
+
+	store := pocket.NewStore()
+	ctx := context.Background()
+
+	// First run walks the static order: flaky fails, slow succeeds
+	if result, err := chain.Execute(ctx, store, nil); err != nil || result != "slow" {
+		t.Fatalf("first Execute() = %v, %v; want slow", result, err)
+	}
+
+	// Untried links score highest, so fast is tried next and wins
+	if result, err := chain.Execute(ctx, store, nil); err != nil || result != "fast" {
+		t.Fatalf("second Execute() = %v, %v; want fast", result, err)
+	}
+
+	calls = nil
+	if result, err := chain.Execute(ctx, store, nil); err != nil || result != "fast" {
+		t.Fatalf("third Execute() = %v, %v; want fast", result, err)
+	}
+	if len(calls) != 1 || calls[0] != "fast" {
+		t.Errorf("calls = %v, want [fast]", calls)
+	}
+
+	stats := chain.GetMetrics().LinkStats
+	if stats["flaky"].Score >= stats["slow"].Score {
+		t.Errorf("flaky score %v should be below slow score %v", stats["flaky"].Score, stats["slow"].Score)
+	}
+	if stats["slow"].Score >= stats["fast"].Score {
+		t.Errorf("slow score %v should be below fast score %v", stats["slow"].Score, stats["fast"].Score)
+	}
+}
+
+func TestChainAdaptiveIgnoresCanceledLinks(t *testing.T) {
+	chain := NewChain("test").
+		AddLink(Link{Name: "primary"}).
+		WithAdaptiveSelection()
+
+	chain.record(context.Background(), "primary", time.Millisecond, nil)
+	before := *chain.adaptive.links["primary"]
+
+	// A losing parallel link is canceled, as is every link when the caller
+	// gives up; neither should lower its success rate or raise its latency
+	chain.record(context.Background(), "primary", time.Second, context.Canceled)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	chain.record(ctx, "primary", time.Second, errors.New("aborted"))
+
+	if after := *chain.adaptive.links["primary"]; after != before {
+		t.Errorf("averages changed from %+v to %+v", before, after)
+	}
+}
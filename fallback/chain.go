@@ -2,6 +2,7 @@ package fallback
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -15,6 +16,8 @@ type Chain struct {
 	links    []Link
 	strategy Strategy
 	metrics  *Metrics
+	health   *healthTracker
+	adaptive *adaptiveSelector
 	mu       sync.RWMutex
 }
 
@@ -25,6 +28,10 @@ type Link struct {
 	Weight    float64
 	Condition func(ctx context.Context, store pocket.Store, input any) bool
 	Transform func(input any) any
+
+	// HealthCheck probes the link out of band. It is optional and only used
+	// when the chain has health checks enabled.
+	HealthCheck func(ctx context.Context) error
 }
 
 // Strategy defines how the chain executes.
@@ -87,8 +94,80 @@ func (c *Chain) Execute(ctx context.Context, store pocket.Store, input any) (any
 	return c.strategy.Execute(ctx, c, store, input)
 }
 
+// activeLinks returns the links currently in rotation, ranked when
+// adaptive selection is enabled.
+func (c *Chain) activeLinks() []Link {
+	c.mu.RLock()
+	links := c.links
+	health := c.health
+	adaptive := c.adaptive
+	c.mu.RUnlock()
+
+	active := links
+	if health != nil {
+		active = make([]Link, 0, len(links))
+		for _, link := range links {
+			if health.available(link.Name) {
+				active = append(active, link)
+			}
+		}
+
+		if len(active) == 0 {
+			// Everything is down; trying is better than failing outright
+			active = links
+		}
+	}
+
+	if adaptive != nil {
+		return adaptive.rank(active)
+	}
+	return active
+}
+
+// record updates metrics, link health, and adaptive scores after a link
+// executes. A link interrupted by cancellation, such as a losing link of
+// ParallelStrategy, says nothing about its health or latency, so it only
+// counts in the metrics.
+func (c *Chain) record(ctx context.Context, name string, latency time.Duration, err error) {
+	c.metrics.mu.Lock()
+	c.metrics.linkLatencies[name] = append(c.metrics.linkLatencies[name], latency)
+	if err == nil {
+		c.metrics.linkSuccesses[name]++
+	} else {
+		c.metrics.linkFailures[name]++
+	}
+	c.metrics.mu.Unlock()
+
+	if interrupted(ctx, err) {
+		return
+	}
+
+	c.mu.RLock()
+	health := c.health
+	adaptive := c.adaptive
+	c.mu.RUnlock()
+
+	if health != nil {
+		health.record(name, err)
+	}
+	if adaptive != nil {
+		adaptive.observe(name, latency, err)
+	}
+}
+
+// interrupted reports whether a link failed because it was canceled
+// rather than by its own fault.
+func interrupted(ctx context.Context, err error) bool {
+	return err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled))
+}
+
 // GetMetrics returns chain execution metrics.
 func (c *Chain) GetMetrics() MetricsSnapshot {
+	c.mu.RLock()
+	links := c.links
+	adaptive := c.adaptive
+	c.mu.RUnlock()
+
 	c.metrics.mu.RLock()
 	defer c.metrics.mu.RUnlock()
 
@@ -112,6 +191,10 @@ func (c *Chain) GetMetrics() MetricsSnapshot {
 			stats.AvgLatency = total / time.Duration(len(latencies))
 		}
 
+		if adaptive != nil {
+			stats.SuccessEWMA, stats.LatencyEWMA, stats.Score, _ = adaptive.snapshot(name, links)
+		}
+
 		snapshot.LinkStats[name] = stats
 	}
 
@@ -130,15 +213,19 @@ type LinkStats struct {
 	Successes  int64
 	Failures   int64
 	AvgLatency time.Duration
+
+	// SuccessEWMA, LatencyEWMA, and Score are only set when adaptive
+	// selection is enabled.
+	SuccessEWMA float64
+	LatencyEWMA time.Duration
+	Score       float64
 }
 
 // SequentialStrategy executes links in order until one succeeds.
 type SequentialStrategy struct{}
 
 func (s *SequentialStrategy) Execute(ctx context.Context, chain *Chain, store pocket.Store, input any) (any, error) {
-	chain.mu.RLock()
-	links := chain.links
-	chain.mu.RUnlock()
+	links := chain.activeLinks()
 
 	var lastErr error
 
@@ -165,16 +252,8 @@ func (s *SequentialStrategy) Execute(ctx context.Context, chain *Chain, store po
 		// Execute link
 		result, err := link.Handler(ctx, linkInput)
 
-		// Record latency
-		latency := time.Since(start)
-		chain.metrics.mu.Lock()
-		chain.metrics.linkLatencies[link.Name] = append(chain.metrics.linkLatencies[link.Name], latency)
-		if err == nil {
-			chain.metrics.linkSuccesses[link.Name]++
-		} else {
-			chain.metrics.linkFailures[link.Name]++
-		}
-		chain.metrics.mu.Unlock()
+		// Record latency and outcome
+		chain.record(ctx, link.Name, time.Since(start), err)
 
 		if err == nil {
 			// Store which link succeeded
@@ -201,9 +280,7 @@ func NewParallelStrategy(timeout time.Duration) *ParallelStrategy {
 }
 
 func (s *ParallelStrategy) Execute(ctx context.Context, chain *Chain, store pocket.Store, input any) (any, error) {
-	chain.mu.RLock()
-	links := chain.links
-	chain.mu.RUnlock()
+	links := chain.activeLinks()
 
 	if s.timeout > 0 {
 		var cancel context.CancelFunc
@@ -239,16 +316,7 @@ func (s *ParallelStrategy) Execute(ctx context.Context, chain *Chain, store pock
 			chain.metrics.mu.Unlock()
 
 			value, err := l.Handler(ctx, linkInput)
-
-			latency := time.Since(start)
-			chain.metrics.mu.Lock()
-			chain.metrics.linkLatencies[l.Name] = append(chain.metrics.linkLatencies[l.Name], latency)
-			if err == nil {
-				chain.metrics.linkSuccesses[l.Name]++
-			} else {
-				chain.metrics.linkFailures[l.Name]++
-			}
-			chain.metrics.mu.Unlock()
+			chain.record(ctx, l.Name, time.Since(start), err)
 
 			resultCh <- result{value: value, err: err, link: l.Name}
 		}(link)
@@ -287,9 +355,7 @@ func NewWeightedRandomStrategy(maxAttempts int) *WeightedRandomStrategy {
 }
 
 func (s *WeightedRandomStrategy) Execute(ctx context.Context, chain *Chain, store pocket.Store, input any) (any, error) {
-	chain.mu.RLock()
-	links := chain.links
-	chain.mu.RUnlock()
+	links := chain.activeLinks()
 
 	// Calculate total weight
 	var totalWeight float64
@@ -329,7 +395,13 @@ func (s *WeightedRandomStrategy) Execute(ctx context.Context, chain *Chain, stor
 					linkInput = link.Transform(input)
 				}
 
+				start := time.Now()
+				chain.metrics.mu.Lock()
+				chain.metrics.linkExecutions[link.Name]++
+				chain.metrics.mu.Unlock()
+
 				result, err := link.Handler(ctx, linkInput)
+				chain.record(ctx, link.Name, time.Since(start), err)
 				if err == nil {
 					_ = store.Set(ctx, fmt.Sprintf("chain:%s:succeeded_at", chain.name), link.Name)
 					return result, nil
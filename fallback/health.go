@@ -0,0 +1,186 @@
+package fallback
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// HealthConfig configures link health tracking for a chain.
+//
+// A link is taken out of rotation after FailureThreshold consecutive
+// failures, counting both real executions and health-check probes. It returns
+// to rotation as soon as a probe succeeds, or gets a trial execution once
+// Cooldown passes without further failures. Failed probes restart the
+// cooldown, so a link whose probe keeps failing stays out of rotation.
+type HealthConfig struct {
+	// Interval is how often HealthCheck probes run. Defaults to 10 seconds.
+	Interval time.Duration
+
+	// Timeout bounds each probe. Defaults to 5 seconds.
+	Timeout time.Duration
+
+	// FailureThreshold is the number of consecutive failures that disables
+	// a link. Defaults to 3.
+	FailureThreshold int
+
+	// Cooldown is how long a disabled link stays out of rotation after its
+	// last failure before it is tried again. Defaults to 30 seconds.
+	Cooldown time.Duration
+}
+
+// linkHealth is the health state of one link.
+type linkHealth struct {
+	consecutiveFailures int
+	disabled            bool
+	disabledAt          time.Time
+}
+
+// healthTracker records link health and decides which links are in rotation.
+type healthTracker struct {
+	mu     sync.Mutex
+	config HealthConfig
+	links  map[string]*linkHealth
+	now    func() time.Time
+}
+
+func newHealthTracker(config HealthConfig) *healthTracker {
+	if config.Interval <= 0 {
+		config.Interval = 10 * time.Second
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+
+	return &healthTracker{
+		config: config,
+		links:  make(map[string]*linkHealth),
+		now:    time.Now,
+	}
+}
+
+func (h *healthTracker) get(name string) *linkHealth {
+	state, ok := h.links[name]
+	if !ok {
+		state = &linkHealth{}
+		h.links[name] = state
+	}
+	return state
+}
+
+// record updates a link's health from an execution or probe result.
+func (h *healthTracker) record(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state := h.get(name)
+	if err == nil {
+		state.consecutiveFailures = 0
+		state.disabled = false
+		return
+	}
+
+	state.consecutiveFailures++
+	if state.consecutiveFailures >= h.config.FailureThreshold {
+		// Every failure while disabled restarts the cooldown
+		state.disabled = true
+		state.disabledAt = h.now()
+	}
+}
+
+// available reports whether a link is in rotation.
+func (h *healthTracker) available(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, ok := h.links[name]
+	if !ok || !state.disabled {
+		return true
+	}
+	return h.now().Sub(state.disabledAt) >= h.config.Cooldown
+}
+
+// WithHealthChecks enables link health tracking. Unhealthy links are skipped
+// by every strategy until they recover. If every link is unhealthy, the chain
+// falls back to trying all of them. Call StartHealthChecks to run probes.
+func (c *Chain) WithHealthChecks(config HealthConfig) *Chain {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.health = newHealthTracker(config)
+	return c
+}
+
+// StartHealthChecks probes every link that has a HealthCheck at the
+// configured interval until the context is cancelled or the returned stop
+// function is called. It is a no-op unless WithHealthChecks was called.
+func (c *Chain) StartHealthChecks(ctx context.Context) (stop func()) {
+	c.mu.RLock()
+	health := c.health
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	if health == nil {
+		return cancel
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(health.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.probe(ctx, health)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// probe runs every link's HealthCheck once.
+func (c *Chain) probe(ctx context.Context, health *healthTracker) {
+	c.mu.RLock()
+	links := c.links
+	c.mu.RUnlock()
+
+	for _, link := range links {
+		if link.HealthCheck == nil {
+			continue
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, health.config.Timeout)
+		err := link.HealthCheck(probeCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+		health.record(link.Name, err)
+	}
+}
+
+// Healthy reports whether the named link is currently in rotation.
+// Links are always healthy when health tracking is disabled.
+func (c *Chain) Healthy(name string) bool {
+	c.mu.RLock()
+	health := c.health
+	c.mu.RUnlock()
+
+	return health == nil || health.available(name)
+}
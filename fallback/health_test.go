@@ -0,0 +1,137 @@
+package fallback
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket"
+)
+
+func TestChainHealthChecks(t *testing.T) {
+	t.Run("disables link after consecutive failures", func(t *testing.T) {
+		var primaryCalls int32
+		chain := NewChain("test").
+			AddLink(Link{
+				Name: "primary",
+				Handler: func(ctx context.Context, input any) (any, error) {
+					atomic.AddInt32(&primaryCalls, 1)
+					return nil, errors.New("down")
+				},
+			}).
+			AddLink(Link{
+				Name: "secondary",
+				Handler: func(ctx context.Context, input any) (any, error) {
+					return successResult, nil
+				},
+			}).
+			WithHealthChecks(HealthConfig{FailureThreshold: 2, Cooldown: time.Hour})
+
+		store := pocket.NewStore()
+		ctx := context.Background()
+
+		for i := 0; i < 5; i++ {
+			result, err := chain.Execute(ctx, store, nil)
+			if err != nil || result != successResult {
+				t.Fatalf("Execute() = %v, %v; want %s", result, err, successResult)
+			}
+		}
+
+		if got := atomic.LoadInt32(&primaryCalls); got != 2 {
+			t.Errorf("primary calls = %d, want 2", got)
+		}
+		if chain.Healthy("primary") {
+			t.Error("primary should be unhealthy")
+		}
+		if !chain.Healthy("secondary") {
+			t.Error("secondary should be healthy")
+		}
+	})
+
+	t.Run("recovers when probe succeeds", func(t *testing.T) {
+		var up atomic.Bool
+		chain := NewChain("test").
+			AddLink(Link{
+				Name: "primary",
+				Handler: func(ctx context.Context, input any) (any, error) {
+					if up.Load() {
+						return successResult, nil
+					}
+					return nil, errors.New("down")
+				},
+				HealthCheck: func(ctx context.Context) error {
+					if up.Load() {
+						return nil
+					}
+					return errors.New("down")
+				},
+			}).
+			WithHealthChecks(HealthConfig{
+				Interval:         5 * time.Millisecond,
+				FailureThreshold: 1,
+				Cooldown:         time.Hour,
+			})
+
+		stop := chain.StartHealthChecks(context.Background())
+		defer stop()
+
+		_, _ = chain.Execute(context.Background(), pocket.NewStore(), nil)
+		if chain.Healthy("primary") {
+			t.Fatal("primary should be unhealthy after failure")
+		}
+
+		up.Store(true)
+		deadline := time.Now().Add(time.Second)
+		for !chain.Healthy("primary") {
+			if time.Now().After(deadline) {
+				t.Fatal("primary did not recover after successful probe")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	t.Run("ignores canceled links", func(t *testing.T) {
+		chain := NewChain("test").
+			AddLink(Link{
+				Name: "primary",
+				Handler: func(ctx context.Context, input any) (any, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				},
+			}).
+			WithHealthChecks(HealthConfig{FailureThreshold: 1, Cooldown: time.Hour})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		if _, err := chain.Execute(ctx, pocket.NewStore(), nil); err == nil {
+			t.Fatal("Execute() should fail when the caller cancels")
+		}
+		if !chain.Healthy("primary") {
+			t.Error("primary should stay healthy after cancellation")
+		}
+
+		// A losing parallel link is canceled, even with a live caller
+		chain.record(context.Background(), "primary", time.Millisecond, context.Canceled)
+		if !chain.Healthy("primary") {
+			t.Error("primary should stay healthy after losing a parallel race")
+		}
+	})
+
+	t.Run("retries after cooldown", func(t *testing.T) {
+		tracker := newHealthTracker(HealthConfig{FailureThreshold: 1, Cooldown: time.Minute})
+		now := time.Now()
+		tracker.now = func() time.Time { return now }
+
+		tracker.record("link", errors.New("down"))
+		if tracker.available("link") {
+			t.Fatal("link should be unavailable during cooldown")
+		}
+
+		now = now.Add(2 * time.Minute)
+		if !tracker.available("link") {
+			t.Error("link should be available after cooldown")
+		}
+	})
+}
@@ -6,7 +6,9 @@ toolchain go1.24.5
 
 require (
 	github.com/Shopify/go-lua v0.0.0-20250718183320-1e37f32ad7d0
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/goccy/go-yaml v1.18.0
+	github.com/klauspost/compress v1.18.0
 	github.com/ohler55/ojg v1.26.8
 	github.com/spf13/cobra v1.9.1
 	github.com/tetratelabs/wazero v1.9.0
@@ -19,4 +21,5 @@ require (
 	github.com/spf13/pflag v1.0.6 // indirect
 	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
 	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 )
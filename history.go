@@ -0,0 +1,76 @@
+package pocket
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// KeyChange records one write to a store key.
+type KeyChange struct {
+	// Value is the value written, or nil for a deletion.
+	Value any
+
+	// Deleted reports whether the change removed the key.
+	Deleted bool
+
+	// Time is when the change was made.
+	Time time.Time
+
+	// Node is the name of the node that made the change, if it was made by
+	// a node during a graph run.
+	Node string
+
+	// RunID is the ID of the run that made the change, if the run was
+	// started with WithRunID.
+	RunID string
+}
+
+// HistoryReader is implemented by stores that record key history. Use a
+// type assertion to reach it from a Store created with WithHistory.
+type HistoryReader interface {
+	// History returns the recorded changes to a key, oldest first. The
+	// last change is the key's current state.
+	History(ctx context.Context, key string) []KeyChange
+}
+
+// WithHistory records the last limit changes to each key, with when they
+// were made and by which node and run, so questions like "who overwrote the
+// inventory" can be answered from the store. Read it through
+// HistoryReader. Deleting a key records the deletion and keeps its history;
+// keys removed by TTL expiry or eviction lose their history.
+func WithHistory(limit int) StoreOption {
+	return func(c *storeConfig) {
+		c.historyLimit = limit
+	}
+}
+
+// record appends a change to a key's history. Must be called with lock held.
+func (s *store) record(ctx context.Context, key string, change KeyChange) {
+	if s.config.historyLimit <= 0 {
+		return
+	}
+
+	change.Time = s.config.now()
+	change.Node = NodeName(ctx)
+	change.RunID, _ = RunIDFrom(ctx)
+
+	changes := append(s.history[key], change)
+	if len(changes) > s.config.historyLimit {
+		changes = slices.Delete(changes, 0, len(changes)-s.config.historyLimit)
+	}
+	s.history[key] = changes
+}
+
+// History returns the recorded changes to a key, oldest first.
+func (s *store) History(ctx context.Context, key string) []KeyChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.history[s.prefix+key])
+}
+
+// History returns the recorded changes to a key, oldest first.
+func (s *shardedStore) History(ctx context.Context, key string) []KeyChange {
+	fullKey := s.prefix + key
+	return s.shard(fullKey).History(ctx, fullKey)
+}
@@ -0,0 +1,143 @@
+package pocket
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrInputTooLarge is returned by runs whose input exceeds the graph's
+// input limits.
+var ErrInputTooLarge = errors.New("pocket: input exceeds limits")
+
+// InputLimits bound the input a graph accepts. Zero values are unlimited.
+type InputLimits struct {
+	// MaxBytes limits the size of the input: the bytes of its strings plus
+	// 8 for each other value.
+	MaxBytes int
+
+	// MaxStringLength limits the bytes in any one string.
+	MaxStringLength int
+
+	// MaxArrayLength limits the items in any one slice, array, or map.
+	MaxArrayLength int
+
+	// MaxDepth limits the nesting of slices, arrays, maps, and structs.
+	MaxDepth int
+}
+
+// WithInputLimits rejects runs whose input exceeds the limits with
+// ErrInputTooLarge before the start node runs, protecting services that run
+// graphs on untrusted payloads. Checking stops at the first limit exceeded,
+// so oversized inputs are rejected without walking all of them.
+func WithInputLimits(limits InputLimits) GraphOption {
+	return func(o *graphOptions) {
+		o.inputLimits = &limits
+	}
+}
+
+// check walks an input, returning an error at the first limit it exceeds.
+func (l *InputLimits) check(input any) error {
+	c := &limitChecker{limits: l, seen: make(map[uintptr]bool)}
+	return c.walk(reflect.ValueOf(input), "input", 0)
+}
+
+type limitChecker struct {
+	limits *InputLimits
+	size   int
+	seen   map[uintptr]bool // Values reached through references, counted once
+}
+
+func (c *limitChecker) add(n int, path string) error {
+	c.size += n
+	if c.limits.MaxBytes > 0 && c.size > c.limits.MaxBytes {
+		return fmt.Errorf("%w: %s is past %d bytes", ErrInputTooLarge, path, c.limits.MaxBytes)
+	}
+	return nil
+}
+
+func (c *limitChecker) walk(v reflect.Value, path string, depth int) error {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Pointer {
+			if c.seen[v.Pointer()] {
+				return nil
+			}
+			c.seen[v.Pointer()] = true
+		}
+		return c.walk(v.Elem(), path, depth)
+
+	case reflect.String:
+		if n := v.Len(); c.limits.MaxStringLength > 0 && n > c.limits.MaxStringLength {
+			return fmt.Errorf("%w: %s is %d bytes, limit %d", ErrInputTooLarge, path, n, c.limits.MaxStringLength)
+		}
+		return c.add(v.Len(), path)
+
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		if c.limits.MaxDepth > 0 && depth >= c.limits.MaxDepth {
+			return fmt.Errorf("%w: %s is nested deeper than %d", ErrInputTooLarge, path, c.limits.MaxDepth)
+		}
+		if v.Kind() != reflect.Struct {
+			if n := v.Len(); c.limits.MaxArrayLength > 0 && n > c.limits.MaxArrayLength {
+				return fmt.Errorf("%w: %s has %d items, limit %d", ErrInputTooLarge, path, n, c.limits.MaxArrayLength)
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() || c.seen[v.Pointer()] {
+			return nil
+		}
+		c.seen[v.Pointer()] = true
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return c.add(v.Len(), path)
+		}
+		fallthrough
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := c.walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		if v.IsNil() || c.seen[v.Pointer()] {
+			return nil
+		}
+		c.seen[v.Pointer()] = true
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if err := c.walk(iter.Key(), path+"."+key, depth+1); err != nil {
+				return err
+			}
+			if err := c.walk(iter.Value(), path+"."+key, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			if err := c.walk(v.Field(i), path+"."+t.Field(i).Name, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return c.add(8, path)
+}
@@ -0,0 +1,43 @@
+package pocket
+
+import (
+	"context"
+	"reflect"
+)
+
+// MapInputFunc computes the input of a node from the output of the node
+// before it and the store.
+type MapInputFunc func(ctx context.Context, store StoreReader, input any) (any, error)
+
+// mappedNode is a node whose input is mapped before it runs.
+type mappedNode struct {
+	Node
+	mapInput MapInputFunc
+}
+
+// MapInput returns n with its input replaced by the result of fn each time
+// a graph runs it, so a node can take a different shape than its
+// predecessor outputs without a transform node in between. The node keeps
+// its name, options, and successors. Calling its lifecycle methods directly
+// doesn't map the input.
+//
+// Example:
+//
+//	notify := pocket.MapInput(sendEmail, func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+//		order := input.(Order)
+//		return Email{To: order.Customer.Email, Subject: "Order " + order.ID}, nil
+//	})
+func MapInput(n Node, fn MapInputFunc) Node {
+	return &mappedNode{Node: n, mapInput: fn}
+}
+
+// Connect adds a successor to the mapped node.
+func (m *mappedNode) Connect(action string, next Node) Node {
+	m.Node.Connect(action, next)
+	return m
+}
+
+// InputType returns nil, since the mapping accepts any input.
+func (m *mappedNode) InputType() reflect.Type {
+	return nil
+}
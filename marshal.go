@@ -0,0 +1,182 @@
+package pocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CustomNodeType is the workflow node type Marshal gives nodes without a
+// spec from WithSpec. Such nodes are references to code: loaders resolve
+// them by name to nodes defined in Go.
+const CustomNodeType = "custom"
+
+// nodeSpec is the workflow definition of a node.
+type nodeSpec struct {
+	nodeType string
+	config   map[string]any
+}
+
+// WithSpec declares the workflow node type and config a node is equivalent
+// to, so Marshal can export it as a node a YAML loader builds with the
+// builder registered for the type.
+//
+// Example:
+//
+//	greet := pocket.NewNode[any, any]("greet", steps,
+//		pocket.WithSpec("echo", map[string]any{"message": "hello"}))
+func WithSpec(nodeType string, config map[string]any) Option {
+	return func(o *nodeOptions) {
+		o.spec = &nodeSpec{nodeType: nodeType, config: config}
+	}
+}
+
+// Marshal returns the YAML workflow definition of a graph built in Go, for
+// moving between code-first and config-first workflows. Nodes with a spec
+// from WithSpec are exported with its type and config, and other nodes as
+// CustomNodeType references by name. Only the nodes and connections
+// reachable from the start node are exported; options such as retries and
+// fallback nodes, input mappings, and nested graphs' nodes are not.
+func Marshal(g *Graph) ([]byte, error) {
+	if g.start == nil {
+		return nil, ErrNoStartNode
+	}
+
+	var nodes []Node
+	seen := make(map[string]Node)
+	var walk func(n Node) error
+	walk = func(n Node) error {
+		if other, ok := seen[n.Name()]; ok {
+			if other != n {
+				return fmt.Errorf("marshal: two nodes are named %q", n.Name())
+			}
+			return nil
+		}
+		seen[n.Name()] = n
+		nodes = append(nodes, n)
+		successors := n.Successors()
+		for _, action := range slices.Sorted(maps.Keys(successors)) {
+			if err := walk(successors[action]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(g.start); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "name: %s\n", yamlScalar(g.name))
+
+	buf.WriteString("nodes:\n")
+	for _, n := range nodes {
+		spec := specOf(n)
+		fmt.Fprintf(&buf, "  - name: %s\n", yamlScalar(n.Name()))
+		fmt.Fprintf(&buf, "    type: %s\n", yamlScalar(spec.nodeType))
+		if len(spec.config) > 0 {
+			buf.WriteString("    config:")
+			if err := writeYAML(&buf, spec.config, 3); err != nil {
+				return nil, fmt.Errorf("marshal: node %s config: %w", n.Name(), err)
+			}
+		}
+	}
+
+	var connections bytes.Buffer
+	for _, n := range nodes {
+		successors := n.Successors()
+		for _, action := range slices.Sorted(maps.Keys(successors)) {
+			fmt.Fprintf(&connections, "  - from: %s\n", yamlScalar(n.Name()))
+			fmt.Fprintf(&connections, "    to: %s\n", yamlScalar(successors[action].Name()))
+			if action != "default" {
+				fmt.Fprintf(&connections, "    action: %s\n", yamlScalar(action))
+			}
+		}
+	}
+	if connections.Len() > 0 {
+		buf.WriteString("connections:\n")
+		buf.Write(connections.Bytes())
+	}
+
+	fmt.Fprintf(&buf, "start: %s\n", yamlScalar(g.start.Name()))
+	return buf.Bytes(), nil
+}
+
+// specOf returns the spec of a node, or a custom node reference.
+func specOf(n Node) nodeSpec {
+	if m, ok := n.(*mappedNode); ok {
+		n = m.Node
+	}
+	if simple, ok := n.(*node); ok && simple.opts.spec != nil {
+		return *simple.opts.spec
+	}
+	return nodeSpec{nodeType: CustomNodeType}
+}
+
+// writeYAML writes a value after a key, as a block at the indent level for
+// maps and lists and on the same line otherwise.
+func writeYAML(buf *bytes.Buffer, value any, indent int) error {
+	prefix := strings.Repeat("  ", indent)
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			buf.WriteString(" {}\n")
+			return nil
+		}
+		buf.WriteString("\n")
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			fmt.Fprintf(buf, "%s%s:", prefix, yamlScalar(key))
+			if err := writeYAML(buf, v[key], indent+1); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if len(v) == 0 {
+			buf.WriteString(" []\n")
+			return nil
+		}
+		buf.WriteString("\n")
+		for _, item := range v {
+			fmt.Fprintf(buf, "%s-", prefix)
+			if err := writeYAML(buf, item, indent+1); err != nil {
+				return err
+			}
+		}
+	case string:
+		fmt.Fprintf(buf, " %s\n", yamlScalar(v))
+	case nil:
+		buf.WriteString(" null\n")
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		fmt.Fprintf(buf, " %v\n", v)
+	default:
+		// Other values, such as structs, are written in flow style,
+		// which YAML reads as JSON
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, " %s\n", data)
+	}
+	return nil
+}
+
+// plainScalar matches strings that YAML reads as strings without quotes.
+var plainScalar = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_./-]*$`)
+
+// yamlScalar returns a string as a YAML scalar, quoted when YAML would
+// otherwise read it as another type or fail to parse it.
+func yamlScalar(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+		return strconv.Quote(s)
+	}
+	if plainScalar.MatchString(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
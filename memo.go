@@ -0,0 +1,54 @@
+package pocket
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// memoEntry is a cached Exec result in the store.
+type memoEntry struct {
+	value   any
+	expires time.Time // Zero means the entry never expires
+}
+
+// memoStoreKey returns the store key caching a node's Exec result for a
+// prep result.
+func memoStoreKey(n *node, prepResult any) string {
+	if n.opts.memoKey != nil {
+		return "memo:" + n.name + ":" + n.opts.memoKey(prepResult)
+	}
+
+	data, err := json.Marshal(prepResult)
+	if err != nil {
+		// Fall back to Go syntax for values JSON can't represent
+		data = []byte(fmt.Sprintf("%#v", prepResult))
+	}
+	sum := sha256.Sum256(data)
+	return "memo:" + n.name + ":" + hex.EncodeToString(sum[:])
+}
+
+// memoLookup returns a cached Exec result that has not expired.
+func memoLookup(ctx context.Context, store StoreReader, key string) (any, bool) {
+	v, ok := store.Get(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	e, ok := v.(memoEntry)
+	if !ok || (!e.expires.IsZero() && ClockFrom(ctx).Now().After(e.expires)) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// memoSave caches an Exec result for the node's memoization TTL.
+func memoSave(ctx context.Context, store Store, key string, value any, ttl time.Duration) error {
+	e := memoEntry{value: value}
+	if ttl > 0 {
+		e.expires = ClockFrom(ctx).Now().Add(ttl)
+	}
+	return store.Set(ctx, key, e)
+}
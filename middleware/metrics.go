@@ -41,3 +41,20 @@ func Metrics(collector MetricsCollector) Middleware {
 		}
 	}
 }
+
+// StoreOperations returns a hook for pocket.WithOnOperation that reports
+// each store operation to collector as a phase of the node that ran it,
+// named "store." followed by the operation, such as "store.set". Gets that
+// find no value are reported as "store.get.miss". The hook runs after the
+// operation, so collectors can count store phases but not time them.
+func StoreOperations(collector MetricsCollector) func(ctx context.Context, op pocket.StoreOperation) {
+	return func(ctx context.Context, op pocket.StoreOperation) {
+		phase := "store." + string(op.Op)
+		if op.Op == pocket.StoreOpGet && !op.Hit {
+			phase += ".miss"
+		}
+		nodeName := pocket.NodeName(ctx)
+		collector.RecordPhaseStart(nodeName, phase)
+		collector.RecordPhaseEnd(nodeName, phase, nil)
+	}
+}
@@ -22,7 +22,7 @@ func Retry(maxAttempts int, backoff time.Duration) Middleware {
 						select {
 						case <-ctx.Done():
 							return nil, ctx.Err()
-						case <-time.After(backoff * time.Duration(attempt)):
+						case <-pocket.ClockFrom(ctx).After(backoff * time.Duration(attempt)):
 							// Exponential backoff
 						}
 					}
@@ -69,50 +69,53 @@ func Timeout(duration time.Duration) Middleware {
 	}
 }
 
-// RateLimit adds rate limiting to a node using a token bucket.
+// RateLimit adds rate limiting to a node using a token bucket that holds
+// up to burst tokens and refills at rps tokens per second. Each execution
+// takes a token, waiting for one if the bucket is empty. The bucket refills
+// on the run's clock from pocket.ClockFrom.
 func RateLimit(rps, burst int) Middleware {
-	// Simple token bucket implementation
-	tokens := make(chan struct{}, burst)
-
-	// Fill bucket
-	for i := 0; i < burst; i++ {
-		tokens <- struct{}{}
-	}
-
-	// Refill tokens
-	go func() {
-		ticker := time.NewTicker(time.Second / time.Duration(rps))
-		defer ticker.Stop()
-
-		for range ticker.C {
-			select {
-			case tokens <- struct{}{}:
-			default:
-				// Bucket full
+	var (
+		mu     sync.Mutex
+		tokens = float64(burst)
+		last   time.Time
+	)
+
+	// take takes a token at now, or returns how long until one refills
+	take := func(now time.Time) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if now.After(last) {
+			if !last.IsZero() {
+				tokens = min(float64(burst), tokens+now.Sub(last).Seconds()*float64(rps))
 			}
+			last = now
+		}
+		if tokens >= 1 {
+			tokens--
+			return 0
 		}
-	}()
+		return time.Duration((1 - tokens) / float64(rps) * float64(time.Second))
+	}
 
 	return func(node pocket.Node) pocket.Node {
 		return &middlewareNode{
 			inner: node,
 			name:  node.Name(),
 			exec: func(ctx context.Context, input any) (any, error) {
-				select {
-				case <-tokens:
-					// Got token, proceed
-					result, err := node.Exec(ctx, input)
-
-					// Return token on completion
+				clock := pocket.ClockFrom(ctx)
+				for {
+					wait := take(clock.Now())
+					if wait <= 0 {
+						break
+					}
 					select {
-					case tokens <- struct{}{}:
-					default:
+					case <-clock.After(wait):
+					case <-ctx.Done():
+						return nil, ctx.Err()
 					}
-
-					return result, err
-				case <-ctx.Done():
-					return nil, ctx.Err()
 				}
+				return node.Exec(ctx, input)
 			},
 		}
 	}
@@ -133,7 +136,7 @@ func CircuitBreaker(threshold int, timeout time.Duration) Middleware {
 				mu.Lock()
 				// Check circuit state
 				if state == "open" {
-					if time.Since(lastFailure) > timeout {
+					if pocket.ClockFrom(ctx).Now().Sub(lastFailure) > timeout {
 						state = "half-open"
 					} else {
 						mu.Unlock()
@@ -149,7 +152,7 @@ func CircuitBreaker(threshold int, timeout time.Duration) Middleware {
 
 				if err != nil {
 					failures++
-					lastFailure = time.Now()
+					lastFailure = pocket.ClockFrom(ctx).Now()
 
 					if failures >= threshold {
 						state = "open"
@@ -0,0 +1,157 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/agentstation/pocket"
+)
+
+// aggregateBatch is the state of a stateful aggregation, kept in the store
+// between invocations.
+type aggregateBatch struct {
+	Items   []interface{}
+	Started time.Time
+}
+
+// aggregateCollector accumulates inputs across invocations of an aggregate
+// node, grouped by correlation ID, until a batch is complete.
+type aggregateCollector struct {
+	node          string
+	count         int
+	timeout       time.Duration
+	partial       bool
+	correlationID *template.Template
+
+	// Serializes read-modify-write of batches by concurrent runs
+	mu sync.Mutex
+}
+
+func newAggregateCollector(node string, config map[string]interface{}) (*aggregateCollector, error) {
+	c := &aggregateCollector{node: node, timeout: 30 * time.Second}
+
+	n, _ := toFloat(config["count"])
+	if n < 1 {
+		return nil, fmt.Errorf("count must be at least 1")
+	}
+	c.count = int(n)
+
+	if s, ok := config["timeout"].(string); ok && s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		c.timeout = d
+	}
+	c.partial, _ = config["partial"].(bool)
+
+	var err error
+	if c.correlationID, err = optionalTemplate("correlation_id", config["correlation_id"]); err != nil {
+		return nil, fmt.Errorf("invalid correlation_id: %w", err)
+	}
+
+	return c, nil
+}
+
+// aggregateStatus is the state of a batch after an input is added to it.
+type aggregateStatus struct {
+	id       string
+	items    []interface{}
+	complete bool // All expected inputs arrived
+	done     bool // The batch is ready to be emitted
+}
+
+// add records an input in its batch. The batch is done once it is complete,
+// or once it has timed out and partial results are allowed; it is then
+// removed from the store. Timeouts are checked as inputs arrive, since
+// nothing runs between invocations.
+func (c *aggregateCollector) add(ctx context.Context, store pocket.StoreWriter, input any) (aggregateStatus, error) {
+	status := aggregateStatus{id: "default"}
+	if c.correlationID != nil {
+		var buf bytes.Buffer
+		if err := c.correlationID.Execute(&buf, input); err != nil {
+			return status, fmt.Errorf("correlation_id template failed: %w", err)
+		}
+		status.id = buf.String()
+	}
+	key := fmt.Sprintf("aggregate:%s:%s", c.node, status.id)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := pocket.ClockFrom(ctx).Now()
+	batch := aggregateBatch{Started: now}
+	if stored, ok := store.Get(ctx, key); ok {
+		if b, ok := stored.(aggregateBatch); ok {
+			batch = b
+		}
+	}
+	batch.Items = append(batch.Items, input)
+	status.items = batch.Items
+
+	switch {
+	case len(batch.Items) >= c.count:
+		status.complete, status.done = true, true
+	case c.timeout > 0 && now.Sub(batch.Started) > c.timeout:
+		if !c.partial {
+			_ = store.Delete(ctx, key)
+			return status, fmt.Errorf("aggregation %s timed out after %v with %d of %d inputs",
+				status.id, c.timeout, len(batch.Items), c.count)
+		}
+		status.done = true
+	}
+
+	if status.done {
+		return status, store.Delete(ctx, key)
+	}
+	return status, store.Set(ctx, key, batch)
+}
+
+// aggregateItems combines items according to mode.
+func aggregateItems(mode string, key *template.Template, items []interface{}) (interface{}, error) {
+	switch mode {
+	case "array":
+		return items, nil
+
+	case "object":
+		obj := make(map[string]interface{})
+		for i, item := range items {
+			name := fmt.Sprintf("item_%d", i)
+			if key != nil {
+				var buf bytes.Buffer
+				if err := key.Execute(&buf, item); err == nil {
+					name = buf.String()
+				}
+			}
+			obj[name] = item
+		}
+		return obj, nil
+
+	case "merge":
+		merged := make(map[string]interface{})
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				merged = deepMerge(merged, m)
+			}
+		}
+		return merged, nil
+
+	case "concat":
+		var concatenated []interface{}
+		for _, item := range items {
+			if arr, ok := item.([]interface{}); ok {
+				concatenated = append(concatenated, arr...)
+			} else {
+				concatenated = append(concatenated, item)
+			}
+		}
+		return concatenated, nil
+
+	default:
+		return nil, fmt.Errorf("unknown aggregation mode: %s", mode)
+	}
+}
@@ -2,22 +2,40 @@ package nodes
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/hmac"
+	"crypto/md5" // #nosec G501 - Offered for checksums, not security
+	crand "crypto/rand"
+	"crypto/sha1" // #nosec G505 - Offered for legacy signatures
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 	"log"
+	"maps"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"text/template"
 	"time"
 
 	"github.com/Shopify/go-lua"
+	"github.com/klauspost/compress/zstd"
 	"github.com/ohler55/ojg/jp"
 	"github.com/xeipuuv/gojsonschema"
 
@@ -124,9 +142,15 @@ func (b *DelayNodeBuilder) Metadata() Metadata {
 			"properties": map[string]interface{}{
 				"duration": map[string]interface{}{
 					"type":        "string",
-					"description": "Duration to delay (e.g., '1s', '500ms')",
+					"description": "Duration to delay (e.g., '1s', '500ms'). May be a Go template evaluated against the input; a bare number is read as seconds",
 					"default":     "1s",
-					"pattern":     "^[0-9]+[a-z]+$",
+				},
+				"jitter": map[string]interface{}{
+					"type":        "number",
+					"description": "Randomly vary the duration by up to this percentage in either direction",
+					"minimum":     0,
+					"maximum":     100,
+					"default":     0,
 				},
 			},
 		},
@@ -145,6 +169,15 @@ func (b *DelayNodeBuilder) Metadata() Metadata {
 					"duration": "500ms",
 				},
 			},
+			{
+				Name:        "Data-driven backoff",
+				Description: "Wait for the duration the input asks for, varied by 20%",
+				Config: map[string]interface{}{
+					"duration": "{{.retry_after}}",
+					"jitter":   20,
+				},
+				Input: map[string]interface{}{"retry_after": "2s"},
+			},
 		},
 		Since: "1.0.0",
 	}
@@ -153,21 +186,50 @@ func (b *DelayNodeBuilder) Metadata() Metadata {
 // Build creates a delay node from a definition.
 func (b *DelayNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
 	duration := 1 * time.Second
-	if durInterface, ok := def.Config["duration"]; ok {
-		if durStr, ok := durInterface.(string); ok {
-			if d, err := time.ParseDuration(durStr); err == nil {
-				duration = d
+	var durationTmpl *template.Template
+	if durStr, ok := def.Config["duration"].(string); ok {
+		if strings.Contains(durStr, "{{") {
+			tmpl, err := newTemplate(def.Name).Parse(durStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration template: %w", err)
 			}
+			durationTmpl = tmpl
+		} else if d, err := parseDelay(durStr); err == nil {
+			duration = d
 		}
 	}
 
+	jitter, _ := toFloat(def.Config["jitter"])
+	if jitter < 0 || jitter > 100 {
+		return nil, fmt.Errorf("jitter must be between 0 and 100, got %v", jitter)
+	}
+
 	return pocket.NewNode[any, any](def.Name, pocket.Steps{
 		Exec: func(ctx context.Context, input any) (any, error) {
+			delay := duration
+			if durationTmpl != nil {
+				var buf bytes.Buffer
+				if err := durationTmpl.Execute(&buf, input); err != nil {
+					return nil, fmt.Errorf("duration template failed: %w", err)
+				}
+				d, err := parseDelay(strings.TrimSpace(buf.String()))
+				if err != nil {
+					return nil, fmt.Errorf("invalid duration %q: %w", buf.String(), err)
+				}
+				delay = d
+			}
+
+			if jitter > 0 {
+				// Spread evenly across [-jitter%, +jitter%]
+				factor := 1 + (jitter/100)*(2*randFloat64(ctx)-1)
+				delay = time.Duration(float64(delay) * factor)
+			}
+
 			if b.Verbose {
-				log.Printf("[%s] Delaying for %v", def.Name, duration)
+				log.Printf("[%s] Delaying for %v", def.Name, delay)
 			}
 			select {
-			case <-time.After(duration):
+			case <-pocket.ClockFrom(ctx).After(delay):
 				return input, nil
 			case <-ctx.Done():
 				return nil, ctx.Err()
@@ -176,6 +238,60 @@ func (b *DelayNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error)
 	}), nil
 }
 
+// randFloat64 returns a random number in [0, 1), from the run's seeded
+// source when it is deterministic.
+func randFloat64(ctx context.Context) float64 {
+	if r, ok := pocket.RandFrom(ctx); ok {
+		return r.Float64()
+	}
+	return rand.Float64() // #nosec G404 - Not used for security
+}
+
+// randInt63n returns a random number in [0, n), from the run's seeded
+// source when it is deterministic.
+func randInt63n(ctx context.Context, n int64) int64 {
+	if r, ok := pocket.RandFrom(ctx); ok {
+		return r.Int63n(n)
+	}
+	return rand.Int63n(n) // #nosec G404 - Not used for security
+}
+
+// toFloat converts a numeric config value to float64. YAML decoders produce
+// different integer types, so all of them are accepted.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// parseDelay parses a duration string, reading a bare number as seconds.
+func parseDelay(s string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("duration must not be negative")
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration must not be negative")
+	}
+	return d, nil
+}
+
 // RouterNodeBuilder builds router nodes.
 type RouterNodeBuilder struct {
 	Verbose bool
@@ -186,15 +302,24 @@ func (b *RouterNodeBuilder) Metadata() Metadata {
 	return Metadata{
 		Type:        "router",
 		Category:    "core",
-		Description: "Routes to a specific node based on configuration",
+		Description: "Routes to a configured route or one derived from the input",
 		ConfigSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"route": map[string]interface{}{
 					"type":        "string",
-					"description": "The route/action to take",
+					"description": "The route/action to take, or the fallback when route_from yields none",
 					"default":     "default",
 				},
+				"route_from": map[string]interface{}{
+					"type":        "string",
+					"description": "JSONPath (starting with $) or Go template deriving the route from the input",
+				},
+				"routes": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Allowed derived routes; others use the fallback route",
+				},
 			},
 		},
 		Examples: []Example{
@@ -210,6 +335,15 @@ func (b *RouterNodeBuilder) Metadata() Metadata {
 				Description: "Use default route",
 				Config:      map[string]interface{}{},
 			},
+			{
+				Name:        "Route by field",
+				Description: "Route on the input's type field, falling back to unknown",
+				Config: map[string]interface{}{
+					"route_from": "$.type",
+					"routes":     []interface{}{"order", "refund"},
+					"route":      "unknown",
+				},
+			},
 		},
 		Since: "1.0.0",
 	}
@@ -224,16 +358,75 @@ func (b *RouterNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error)
 		}
 	}
 
+	var derive func(input any) (string, error)
+	if from, ok := def.Config["route_from"].(string); ok && from != "" {
+		var err error
+		if derive, err = routeDeriver(from); err != nil {
+			return nil, err
+		}
+	}
+
+	var allowed map[string]bool
+	if raw, ok := def.Config["routes"]; ok {
+		routes, err := stringList(raw)
+		if err != nil {
+			return nil, fmt.Errorf("routes: %w", err)
+		}
+		allowed = make(map[string]bool, len(routes))
+		for _, r := range routes {
+			allowed[r] = true
+		}
+	}
+
 	return pocket.NewNode[any, any](def.Name, pocket.Steps{
 		Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+			next := route
+			if derive != nil {
+				derived, err := derive(exec)
+				if err != nil {
+					return nil, "", err
+				}
+				if derived != "" && (allowed == nil || allowed[derived]) {
+					next = derived
+				} else if b.Verbose {
+					log.Printf("[%s] Derived route %q not usable, falling back", def.Name, derived)
+				}
+			}
+
 			if b.Verbose {
-				log.Printf("[%s] Routing to: %s", def.Name, route)
+				log.Printf("[%s] Routing to: %s", def.Name, next)
 			}
-			return exec, route, nil
+			return exec, next, nil
 		},
 	}), nil
 }
 
+// routeDeriver parses a route_from expression: a JSONPath if it starts
+// with "$", otherwise a Go template.
+func routeDeriver(from string) (func(input any) (string, error), error) {
+	if strings.HasPrefix(from, "$") {
+		expr, err := jp.ParseString(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route_from JSONPath: %w", err)
+		}
+		return func(input any) (string, error) {
+			return cursorString(firstMatch(expr, input)), nil
+		}, nil
+	}
+
+	tmpl, err := newTemplate("route_from").Parse(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid route_from template: %w", err)
+	}
+	return func(input any) (string, error) {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, input); err != nil {
+			return "", fmt.Errorf("route_from template failed: %w", err)
+		}
+		return strings.TrimSpace(buf.String()), nil
+	}, nil
+}
+
 // TransformNodeBuilder builds transform nodes.
 type TransformNodeBuilder struct {
 	Verbose bool
@@ -290,14 +483,14 @@ func (b *TransformNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, err
 			result := map[string]interface{}{
 				"transformed": true,
 				"original":    input,
-				"timestamp":   time.Now().Format(time.RFC3339),
+				"timestamp":   pocket.ClockFrom(ctx).Now().Format(time.RFC3339),
 				"node":        def.Name,
 			}
 
 			// For testing conditional, add a score if the node name suggests it
 			if strings.Contains(def.Name, "score") {
 				// Generate a random score
-				score := rand.Float64() // #nosec G404 - This is for example data generation, not security
+				score := randFloat64(ctx)
 				result["score"] = score
 				if b.Verbose {
 					log.Printf("[%s] Generated score: %.2f", def.Name, score)
@@ -397,7 +590,7 @@ func (b *ConditionalNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, e
 			return nil, fmt.Errorf("condition %d missing 'then'", i)
 		}
 
-		tmpl, err := template.New(fmt.Sprintf("cond_%d", i)).Parse(ifExpr)
+		tmpl, err := newTemplate(fmt.Sprintf("cond_%d", i)).Parse(ifExpr)
 		if err != nil {
 			return nil, fmt.Errorf("condition %d invalid template: %w", i, err)
 		}
@@ -441,6 +634,112 @@ func (b *ConditionalNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, e
 	}), nil
 }
 
+// SwitchNodeBuilder builds switch routing nodes.
+type SwitchNodeBuilder struct {
+	Verbose bool
+}
+
+// Metadata returns the node metadata.
+func (b *SwitchNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "switch",
+		Category:    "core",
+		Description: "Routes by matching a single expression against a case table",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"expression": map[string]interface{}{
+					"type":        "string",
+					"description": "Go template evaluated once against the input",
+				},
+				"cases": map[string]interface{}{
+					"type":        "object",
+					"description": "Map of expression values to routes",
+					"additionalProperties": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"default": map[string]interface{}{
+					"type":        "string",
+					"description": "Route when no case matches",
+					"default":     "default",
+				},
+			},
+			"required": []string{"expression", "cases"},
+		},
+		Examples: []Example{
+			{
+				Name:        "Route by status",
+				Description: "Map an order status to a handler",
+				Config: map[string]interface{}{
+					"expression": "{{.status}}",
+					"cases": map[string]interface{}{
+						"paid":     "fulfill",
+						"pending":  "remind",
+						"refunded": "close",
+					},
+					"default": "review",
+				},
+				Input:  map[string]interface{}{"status": "paid"},
+				Output: map[string]interface{}{"status": "paid"},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// Build creates a switch node from a definition.
+func (b *SwitchNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	expression, ok := def.Config["expression"].(string)
+	if !ok || expression == "" {
+		return nil, fmt.Errorf("expression is required")
+	}
+
+	tmpl, err := newTemplate(def.Name).Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+
+	casesRaw, ok := def.Config["cases"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cases must be an object")
+	}
+
+	cases := make(map[string]string, len(casesRaw))
+	for value, route := range casesRaw {
+		r, ok := route.(string)
+		if !ok {
+			return nil, fmt.Errorf("case %q route must be a string", value)
+		}
+		cases[value] = r
+	}
+
+	defaultRoute := "default"
+	if d, ok := def.Config["default"].(string); ok && d != "" {
+		defaultRoute = d
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, exec); err != nil {
+				return nil, "", fmt.Errorf("expression evaluation failed: %w", err)
+			}
+
+			value := strings.TrimSpace(buf.String())
+			route, ok := cases[value]
+			if !ok {
+				route = defaultRoute
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] Switch value %q, routing to: %s", def.Name, value, route)
+			}
+			return exec, route, nil
+		},
+	}), nil
+}
+
 // TemplateNodeBuilder builds template rendering nodes.
 type TemplateNodeBuilder struct {
 	Verbose bool
@@ -463,6 +762,26 @@ func (b *TemplateNodeBuilder) Metadata() Metadata {
 					"type":        "string",
 					"description": "Path to template file (alternative to inline template)",
 				},
+				"partials": map[string]interface{}{
+					"type":        "object",
+					"description": "Named templates callable with {{template \"name\" .}}, each an inline template or an object with a file",
+					"additionalProperties": map[string]interface{}{
+						"oneOf": []map[string]interface{}{
+							{"type": "string"},
+							{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"file": map[string]interface{}{"type": "string"},
+								},
+								"required": []string{"file"},
+							},
+						},
+					},
+				},
+				"layout": map[string]interface{}{
+					"type":        "string",
+					"description": "Name of the partial to render instead of the main template, which can then define the layout's blocks",
+				},
 				"output_format": map[string]interface{}{
 					"type":        "string",
 					"enum":        []string{"string", "json", "yaml"},
@@ -524,44 +843,60 @@ func (b *TemplateNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, erro
 		outputFormat = "string"
 	}
 
-	// Parse template at build time for validation
-	var tmpl *template.Template
-	var err error
-
+	set := &templateSet{
+		sources: []templateSource{{name: def.Name, text: templateStr, file: templateFile}},
+		entry:   def.Name,
+	}
 	if hasTemplate {
-		tmpl, err = template.New(def.Name).Parse(templateStr)
-		if err != nil {
+		set.sources[0].file = ""
+	}
+
+	if partials, ok := def.Config["partials"].(map[string]interface{}); ok {
+		// Sort so parse errors are reported deterministically
+		names := make([]string, 0, len(partials))
+		for name := range partials {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			switch p := partials[name].(type) {
+			case string:
+				set.sources = append(set.sources, templateSource{name: name, text: p})
+			case map[string]interface{}:
+				file, _ := p["file"].(string)
+				if file == "" {
+					return nil, fmt.Errorf("partial %q: file is required", name)
+				}
+				set.sources = append(set.sources, templateSource{name: name, file: file})
+			default:
+				return nil, fmt.Errorf("partial %q must be a template string or an object with a file", name)
+			}
+		}
+	}
+
+	if layout, ok := def.Config["layout"].(string); ok && layout != "" {
+		set.entry = layout
+	}
+
+	// Inline templates are parsed at build time for validation. Template
+	// files are parsed on first use and re-parsed whenever they change.
+	if !set.hasFiles() {
+		if _, err := set.get(); err != nil {
 			return nil, fmt.Errorf("invalid template: %w", err)
 		}
-	} else {
-		// For file templates, we'll parse at execution time
-		// to allow for dynamic template updates
-		tmpl = nil
 	}
 
 	return pocket.NewNode[any, any](def.Name, pocket.Steps{
 		Exec: func(ctx context.Context, input any) (any, error) {
-			var execTemplate *template.Template
-
-			// Use pre-parsed template or load from file
-			if tmpl != nil {
-				execTemplate = tmpl
-			} else {
-				// Read and parse template file
-				content, err := os.ReadFile(templateFile) // #nosec G304 - Template files are user-configured
-				if err != nil {
-					return nil, fmt.Errorf("failed to read template file: %w", err)
-				}
-
-				execTemplate, err = template.New(def.Name).Parse(string(content))
-				if err != nil {
-					return nil, fmt.Errorf("failed to parse template file: %w", err)
-				}
+			execTemplate, err := set.get()
+			if err != nil {
+				return nil, fmt.Errorf("failed to load template: %w", err)
 			}
 
 			// Execute template
 			var buf bytes.Buffer
-			if err := execTemplate.Execute(&buf, input); err != nil {
+			if err := execTemplate.ExecuteTemplate(&buf, set.entry, input); err != nil {
 				return nil, fmt.Errorf("template execution failed: %w", err)
 			}
 
@@ -595,6 +930,86 @@ func (b *TemplateNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, erro
 	}), nil
 }
 
+// templateSource is one named template, given inline or as a file.
+type templateSource struct {
+	name string
+	text string
+	file string
+}
+
+// templateSet is a main template and its partials parsed together so they
+// can invoke each other with {{template "name" .}}. The parsed set is cached
+// and only re-parsed when one of its files changes.
+type templateSet struct {
+	sources []templateSource // The main template comes first
+	entry   string           // Template to execute
+
+	mu       sync.Mutex
+	cached   *template.Template
+	modTimes map[string]time.Time
+}
+
+func (s *templateSet) hasFiles() bool {
+	for _, src := range s.sources {
+		if src.file != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the parsed template set, re-parsing if a file has changed.
+func (s *templateSet) get() (*template.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	modTimes := make(map[string]time.Time)
+	for _, src := range s.sources {
+		if src.file == "" {
+			continue
+		}
+		info, err := os.Stat(src.file)
+		if err != nil {
+			return nil, fmt.Errorf("template %q: %w", src.name, err)
+		}
+		modTimes[src.file] = info.ModTime()
+	}
+
+	if s.cached != nil && maps.Equal(modTimes, s.modTimes) {
+		return s.cached, nil
+	}
+
+	// Parse partials before the main template so its definitions override
+	// the defaults of any {{block}} in a layout
+	root := newTemplate(s.sources[0].name)
+	for _, src := range slices.Concat(s.sources[1:], s.sources[:1]) {
+		text := src.text
+		if src.file != "" {
+			content, err := os.ReadFile(src.file) // #nosec G304 - Template files are user-configured
+			if err != nil {
+				return nil, fmt.Errorf("template %q: %w", src.name, err)
+			}
+			text = string(content)
+		}
+
+		t := root
+		if src.name != root.Name() {
+			t = root.New(src.name)
+		}
+		if _, err := t.Parse(text); err != nil {
+			return nil, fmt.Errorf("template %q: %w", src.name, err)
+		}
+	}
+
+	if root.Lookup(s.entry) == nil {
+		return nil, fmt.Errorf("layout %q is not defined", s.entry)
+	}
+
+	s.cached = root
+	s.modTimes = modTimes
+	return root, nil
+}
+
 // HTTPNodeBuilder builds HTTP client nodes.
 type HTTPNodeBuilder struct {
 	Verbose bool
@@ -638,6 +1053,154 @@ func (b *HTTPNodeBuilder) Metadata() Metadata {
 						"delay":        map[string]interface{}{"type": "string", "default": "1s"},
 					},
 				},
+				"multipart": map[string]interface{}{
+					"type":        "object",
+					"description": "Send a multipart/form-data body, streaming files from disk",
+					"properties": map[string]interface{}{
+						"fields": map[string]interface{}{
+							"type":        "object",
+							"description": "Form fields (values support templating)",
+						},
+						"files": map[string]interface{}{
+							"type":        "object",
+							"description": "File parts: a path (supports templating) or {path, filename, content_type}",
+						},
+					},
+				},
+				"download": map[string]interface{}{
+					"type":        "object",
+					"description": "Stream a successful response body to a file instead of returning it",
+					"properties": map[string]interface{}{
+						"path":        map[string]interface{}{"type": "string", "description": "Destination path (supports templating)"},
+						"create_dirs": map[string]interface{}{"type": "boolean", "default": false},
+					},
+					"required": []string{"path"},
+				},
+				"paginate": map[string]interface{}{
+					"type":        "object",
+					"description": "Follow a paginated API and concatenate the items from every page",
+					"properties": map[string]interface{}{
+						"type": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"link", "cursor", "page"},
+						},
+						"items": map[string]interface{}{
+							"type":        "string",
+							"default":     "$.body",
+							"description": "JSONPath selecting each page's items",
+						},
+						"cursor_path": map[string]interface{}{
+							"type":        "string",
+							"description": "JSONPath to the next cursor (cursor pagination)",
+						},
+						"param": map[string]interface{}{
+							"type":        "string",
+							"description": "Query parameter for the cursor or page number (default: cursor or page)",
+						},
+						"start_page": map[string]interface{}{"type": "integer", "default": 1},
+						"max_pages":  map[string]interface{}{"type": "integer", "default": defaultMaxPages},
+						"max_items": map[string]interface{}{
+							"type":        "integer",
+							"description": "Stop once this many items are collected",
+						},
+					},
+					"required": []string{"type"},
+				},
+				"base_dir": map[string]interface{}{
+					"type":        "string",
+					"description": "Directory that multipart files and downloads are restricted to (default: current directory)",
+				},
+				"allow_absolute": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Allow absolute multipart and download paths",
+				},
+				"proxy": map[string]interface{}{
+					"type":        "string",
+					"description": "Proxy URL (default: HTTP_PROXY/HTTPS_PROXY from the environment)",
+				},
+				"tls": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"ca_file":              map[string]interface{}{"type": "string", "description": "PEM CA bundle added to the system roots"},
+						"cert_file":            map[string]interface{}{"type": "string", "description": "Client certificate for mTLS"},
+						"key_file":             map[string]interface{}{"type": "string", "description": "Client key for mTLS"},
+						"server_name":          map[string]interface{}{"type": "string", "description": "Server name to verify"},
+						"insecure_skip_verify": map[string]interface{}{"type": "boolean", "default": false},
+					},
+				},
+				"pool": map[string]interface{}{
+					"type":        "object",
+					"description": "Connection pool settings, shared by nodes with the same proxy, tls, and pool config",
+					"properties": map[string]interface{}{
+						"max_idle_conns":          map[string]interface{}{"type": "integer", "default": 100},
+						"max_idle_conns_per_host": map[string]interface{}{"type": "integer", "default": 2},
+						"max_conns_per_host":      map[string]interface{}{"type": "integer", "default": 0},
+						"idle_conn_timeout":       map[string]interface{}{"type": "string", "default": "90s"},
+						"disable_keep_alives":     map[string]interface{}{"type": "boolean", "default": false},
+					},
+				},
+				"auth": map[string]interface{}{
+					"type":        "object",
+					"description": "Request authentication; secrets are read from <name>_env or <name>_file",
+					"properties": map[string]interface{}{
+						"type": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"bearer", "basic", "oauth2"},
+						},
+						"token_env":          map[string]interface{}{"type": "string", "description": "Bearer token variable"},
+						"token_file":         map[string]interface{}{"type": "string", "description": "Bearer token file"},
+						"username":           map[string]interface{}{"type": "string", "description": "Basic auth username"},
+						"password_env":       map[string]interface{}{"type": "string", "description": "Basic auth password variable"},
+						"password_file":      map[string]interface{}{"type": "string", "description": "Basic auth password file"},
+						"token_url":          map[string]interface{}{"type": "string", "description": "OAuth2 token endpoint"},
+						"client_id":          map[string]interface{}{"type": "string", "description": "OAuth2 client ID"},
+						"client_secret_env":  map[string]interface{}{"type": "string", "description": "OAuth2 client secret variable"},
+						"client_secret_file": map[string]interface{}{"type": "string", "description": "OAuth2 client secret file"},
+						"scopes": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]interface{}{"type": "string"},
+							"description": "OAuth2 scopes",
+						},
+					},
+					"required": []string{"type"},
+				},
+				"expect": map[string]interface{}{
+					"type":        "object",
+					"description": "Response checks; setting this enables status routing",
+					"properties": map[string]interface{}{
+						"status": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]interface{}{"type": []string{"integer", "string"}},
+							"description": "Expected status codes or classes such as \"2xx\" (default: 2xx)",
+						},
+						"assertions": map[string]interface{}{
+							"type": "array",
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"path": map[string]interface{}{
+										"type":        "string",
+										"description": "JSONPath into the response, e.g. $.body.id",
+									},
+									"equals": map[string]interface{}{
+										"description": "Value the path must equal",
+									},
+									"exists": map[string]interface{}{
+										"type":        "boolean",
+										"description": "Whether the path must (or must not) match",
+									},
+								},
+								"required": []string{"path"},
+							},
+						},
+					},
+				},
+				"route_by_status": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Route to success, client_error, server_error, or assertion_failed instead of default",
+				},
 			},
 			"required": []string{"url"},
 		},
@@ -647,6 +1210,19 @@ func (b *HTTPNodeBuilder) Metadata() Metadata {
 				"status":  map[string]interface{}{"type": "integer"},
 				"headers": map[string]interface{}{"type": "object"},
 				"body":    map[string]interface{}{"type": []string{"object", "string"}},
+				"items": map[string]interface{}{
+					"type":        "array",
+					"description": "Items from every page, with count and pages, when paginating",
+				},
+				"download": map[string]interface{}{
+					"type":        "object",
+					"description": "Saved file path, size, expected_size, content_type, duration, and bytes_per_second",
+				},
+				"failures": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Failed expectations, when status routing is enabled",
+				},
 			},
 		},
 		Examples: []Example{
@@ -674,9 +1250,22 @@ func (b *HTTPNodeBuilder) Metadata() Metadata {
 					},
 				},
 			},
-		},
-		Since: "1.0.0",
-	}
+			{
+				Name: "Status routing with assertions",
+				Config: map[string]interface{}{
+					"url": "https://api.example.com/users/{{.id}}",
+					"expect": map[string]interface{}{
+						"status": []interface{}{200},
+						"assertions": []interface{}{
+							map[string]interface{}{"path": "$.body.id", "exists": true},
+							map[string]interface{}{"path": "$.body.active", "equals": true},
+						},
+					},
+				},
+			},
+		},
+		Since: "1.0.0",
+	}
 }
 
 // Build creates an HTTP node from a definition.
@@ -722,114 +1311,364 @@ func (b *HTTPNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
 		}
 	}
 
-	return pocket.NewNode[any, any](def.Name, pocket.Steps{
-		Exec: func(ctx context.Context, input any) (any, error) {
-			// Support URL templating with input data
-			finalURL := url
-			if strings.Contains(url, "{{") {
-				tmpl, err := template.New("url").Parse(url)
-				if err != nil {
-					return nil, fmt.Errorf("invalid URL template: %w", err)
-				}
-				var buf bytes.Buffer
-				if err := tmpl.Execute(&buf, input); err != nil {
-					return nil, fmt.Errorf("URL template execution failed: %w", err)
-				}
-				finalURL = buf.String()
+	// The client is shared across executions so connections are reused
+	client, err := newHTTPClient(def.Config, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth httpAuth
+	if raw, ok := def.Config["auth"].(map[string]interface{}); ok {
+		if auth, err = newHTTPAuth(raw, client); err != nil {
+			return nil, fmt.Errorf("invalid auth: %w", err)
+		}
+	}
+
+	var multipartForm *multipartBody
+	var download *httpDownload
+	multipartConfig, hasMultipart := def.Config["multipart"].(map[string]interface{})
+	downloadConfig, hasDownload := def.Config["download"].(map[string]interface{})
+	if hasMultipart || hasDownload {
+		sandbox, err := newFileSandbox(def.Config)
+		if err != nil {
+			return nil, err
+		}
+		if hasMultipart {
+			if multipartForm, err = newMultipartBody(multipartConfig, sandbox); err != nil {
+				return nil, fmt.Errorf("invalid multipart: %w", err)
+			}
+		}
+		if hasDownload {
+			if download, err = newHTTPDownload(downloadConfig, sandbox); err != nil {
+				return nil, fmt.Errorf("invalid download: %w", err)
 			}
+		}
+	}
+
+	var expect *httpExpectation
+	if raw, ok := def.Config["expect"].(map[string]interface{}); ok {
+		if expect, err = newHTTPExpectation(raw); err != nil {
+			return nil, fmt.Errorf("invalid expect: %w", err)
+		}
+	} else if routeByStatus, _ := def.Config["route_by_status"].(bool); routeByStatus {
+		expect = &httpExpectation{}
+	}
+
+	var paginator *httpPaginator
+	if raw, ok := def.Config["paginate"].(map[string]interface{}); ok {
+		if download != nil {
+			return nil, fmt.Errorf("paginate and download cannot be used together")
+		}
+		if paginator, err = newHTTPPaginator(raw); err != nil {
+			return nil, fmt.Errorf("invalid paginate: %w", err)
+		}
+	}
 
-			client := &http.Client{
-				Timeout: timeout,
+	// request sends a request to finalURL, retrying failures
+	request := func(ctx context.Context, input any, finalURL string) (map[string]interface{}, error) {
+		var lastErr error
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			if attempt > 0 {
+				if b.Verbose {
+					log.Printf("[%s] Retry attempt %d/%d", def.Name, attempt+1, maxAttempts)
+				}
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-pocket.ClockFrom(ctx).After(retryDelay):
+				}
 			}
 
-			var lastErr error
-			for attempt := 0; attempt < maxAttempts; attempt++ {
-				if attempt > 0 {
-					if b.Verbose {
-						log.Printf("[%s] Retry attempt %d/%d", def.Name, attempt+1, maxAttempts)
+			// Prepare request body
+			var bodyReader io.Reader
+			contentType := headers["Content-Type"]
+			if multipartForm != nil && method != "GET" && method != "DELETE" {
+				form, formType, err := multipartForm.open(input)
+				if err != nil {
+					return nil, fmt.Errorf("failed to build multipart body: %w", err)
+				}
+				bodyReader, contentType = form, formType
+			} else if body != nil && method != "GET" && method != "DELETE" {
+				switch v := body.(type) {
+				case string:
+					bodyReader = strings.NewReader(v)
+				default:
+					jsonBody, err := json.Marshal(v)
+					if err != nil {
+						return nil, fmt.Errorf("failed to marshal body: %w", err)
 					}
-					time.Sleep(retryDelay)
-				}
-
-				// Prepare request body
-				var bodyReader io.Reader
-				if body != nil && method != "GET" && method != "DELETE" {
-					switch v := body.(type) {
-					case string:
-						bodyReader = strings.NewReader(v)
-					default:
-						jsonBody, err := json.Marshal(v)
-						if err != nil {
-							return nil, fmt.Errorf("failed to marshal body: %w", err)
-						}
-						bodyReader = bytes.NewReader(jsonBody)
-						if headers["Content-Type"] == "" {
-							headers["Content-Type"] = "application/json"
-						}
+					bodyReader = bytes.NewReader(jsonBody)
+					if contentType == "" {
+						contentType = "application/json"
 					}
 				}
+			}
 
-				req, err := http.NewRequestWithContext(ctx, method, finalURL, bodyReader)
-				if err != nil {
-					return nil, err
+			req, err := http.NewRequestWithContext(ctx, method, finalURL, bodyReader)
+			if err != nil {
+				if closer, ok := bodyReader.(io.Closer); ok {
+					_ = closer.Close()
 				}
+				return nil, err
+			}
 
-				// Add headers
-				for k, v := range headers {
-					req.Header.Set(k, v)
-				}
+			// Add headers
+			for k, v := range headers {
+				req.Header.Set(k, v)
+			}
+			if contentType != "" {
+				req.Header.Set("Content-Type", contentType)
+			}
 
-				resp, err := client.Do(req)
-				if err != nil {
+			if auth != nil {
+				if err := auth.apply(ctx, req); err != nil {
 					lastErr = err
 					continue
 				}
+			}
 
-				// Read and close body immediately to avoid defer in loop
-				respBody, err := io.ReadAll(resp.Body)
-				closeErr := resp.Body.Close()
-				if closeErr != nil && b.Verbose {
-					log.Printf("[%s] Failed to close response body: %v", def.Name, closeErr)
-				}
+			resp, err := client.Do(req)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			// Stream successful downloads to disk instead of into memory
+			if download != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				saved, err := download.save(resp, input)
+				_ = resp.Body.Close()
 				if err != nil {
 					lastErr = err
 					continue
 				}
 
-				// Parse JSON if content type is JSON
-				var bodyData interface{} = string(respBody)
-				contentType := resp.Header.Get("Content-Type")
-				if strings.Contains(contentType, "application/json") {
-					var jsonData interface{}
-					if err := json.Unmarshal(respBody, &jsonData); err == nil {
-						bodyData = jsonData
-					}
+				if b.Verbose {
+					log.Printf("[%s] Downloaded %d bytes to %s", def.Name, saved["size"], saved["path"])
 				}
+				return map[string]interface{}{
+					"status":   resp.StatusCode,
+					"headers":  resp.Header,
+					"download": saved,
+				}, nil
+			}
 
-				result := map[string]interface{}{
-					"status":  resp.StatusCode,
-					"headers": resp.Header,
-					"body":    bodyData,
-				}
+			// Read and close body immediately to avoid defer in loop
+			respBody, err := io.ReadAll(resp.Body)
+			closeErr := resp.Body.Close()
+			if closeErr != nil && b.Verbose {
+				log.Printf("[%s] Failed to close response body: %v", def.Name, closeErr)
+			}
+			if err != nil {
+				lastErr = err
+				continue
+			}
 
-				if b.Verbose {
-					log.Printf("[%s] HTTP %s %s - Status: %d", def.Name, method, finalURL, resp.StatusCode)
+			// Parse JSON if content type is JSON
+			var bodyData interface{} = string(respBody)
+			if strings.Contains(resp.Header.Get("Content-Type"), "application/json") {
+				var jsonData interface{}
+				if err := json.Unmarshal(respBody, &jsonData); err == nil {
+					bodyData = jsonData
 				}
+			}
 
-				// Retry on 5xx errors
-				if resp.StatusCode >= 500 && attempt < maxAttempts-1 {
-					lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
-					continue
+			result := map[string]interface{}{
+				"status":  resp.StatusCode,
+				"headers": resp.Header,
+				"body":    bodyData,
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] HTTP %s %s - Status: %d", def.Name, method, finalURL, resp.StatusCode)
+			}
+
+			// Retry on 5xx errors
+			if resp.StatusCode >= 500 && attempt < maxAttempts-1 {
+				lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
+				continue
+			}
+
+			// Retry with fresh credentials if a cached token was rejected
+			if resp.StatusCode == http.StatusUnauthorized && auth != nil && auth.invalidate() && attempt < maxAttempts-1 {
+				lastErr = fmt.Errorf("unauthorized: %d", resp.StatusCode)
+				continue
+			}
+
+			return result, nil
+		}
+
+		return nil, fmt.Errorf("all attempts failed: %w", lastErr)
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			// Support URL templating with input data
+			finalURL := url
+			if strings.Contains(url, "{{") {
+				tmpl, err := newTemplate("url").Parse(url)
+				if err != nil {
+					return nil, fmt.Errorf("invalid URL template: %w", err)
+				}
+				var buf bytes.Buffer
+				if err := tmpl.Execute(&buf, input); err != nil {
+					return nil, fmt.Errorf("URL template execution failed: %w", err)
 				}
+				finalURL = buf.String()
+			}
 
+			if paginator != nil {
+				result, err := paginator.run(finalURL, func(pageURL string) (map[string]interface{}, error) {
+					return request(ctx, input, pageURL)
+				})
+				if err != nil {
+					return nil, err
+				}
+				if b.Verbose {
+					log.Printf("[%s] Fetched %d items from %d pages", def.Name, result["count"], result["pages"])
+				}
 				return result, nil
 			}
 
-			return nil, fmt.Errorf("all attempts failed: %w", lastErr)
+			result, err := request(ctx, input, finalURL)
+			if err != nil {
+				return nil, err
+			}
+			return result, nil
+		},
+		Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+			if expect == nil {
+				return exec, "default", nil
+			}
+
+			result, _ := exec.(map[string]interface{})
+			route, failures := expect.check(result)
+			if len(failures) > 0 {
+				result["failures"] = failures
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] Response routed to %s", def.Name, route)
+			}
+			return result, route, nil
 		},
 	}), nil
 }
 
+// httpExpectation holds the response checks used for status routing.
+type httpExpectation struct {
+	statuses   []string // Exact codes or classes such as "2xx"
+	assertions []httpAssertion
+}
+
+type httpAssertion struct {
+	path      string
+	expr      jp.Expr
+	equals    any
+	hasEquals bool
+	exists    *bool
+}
+
+func newHTTPExpectation(raw map[string]interface{}) (*httpExpectation, error) {
+	expect := &httpExpectation{}
+
+	statuses, _ := raw["status"].([]interface{})
+	for _, s := range statuses {
+		if n, ok := toFloat(s); ok {
+			expect.statuses = append(expect.statuses, strconv.Itoa(int(n)))
+			continue
+		}
+		str, _ := s.(string)
+		str = strings.ToLower(str)
+		if len(str) != 3 || str[0] < '1' || str[0] > '5' {
+			return nil, fmt.Errorf("status %v must be a code or a class such as 2xx", s)
+		}
+		expect.statuses = append(expect.statuses, str)
+	}
+
+	assertions, _ := raw["assertions"].([]interface{})
+	for i, a := range assertions {
+		m, ok := a.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("assertion %d must be an object", i)
+		}
+		path, _ := m["path"].(string)
+		expr, err := jp.ParseString(path)
+		if err != nil {
+			return nil, fmt.Errorf("assertion %d: invalid path: %w", i, err)
+		}
+
+		assertion := httpAssertion{path: path, expr: expr}
+		if v, ok := m["equals"]; ok {
+			if assertion.equals, err = normalizeJSON(v); err != nil {
+				return nil, fmt.Errorf("assertion %d: %w", i, err)
+			}
+			assertion.hasEquals = true
+		}
+		if v, ok := m["exists"].(bool); ok {
+			assertion.exists = &v
+		}
+		expect.assertions = append(expect.assertions, assertion)
+	}
+
+	return expect, nil
+}
+
+// check returns the route for a response and any failed expectations.
+func (e *httpExpectation) check(result map[string]interface{}) (string, []string) {
+	status, _ := result["status"].(int)
+	if !e.statusMatches(status) {
+		failure := []string{fmt.Sprintf("unexpected status %d", status)}
+		switch {
+		case status >= 500:
+			return "server_error", failure
+		case status >= 400:
+			return "client_error", failure
+		default:
+			return "assertion_failed", failure
+		}
+	}
+
+	var failures []string
+	for _, a := range e.assertions {
+		matches := a.expr.Get(result)
+		switch {
+		case a.exists != nil && *a.exists != (len(matches) > 0):
+			if *a.exists {
+				failures = append(failures, fmt.Sprintf("%s: expected to exist", a.path))
+			} else {
+				failures = append(failures, fmt.Sprintf("%s: expected not to exist", a.path))
+			}
+		case a.hasEquals:
+			var actual any
+			if len(matches) > 0 {
+				actual, _ = normalizeJSON(matches[0])
+			}
+			if !reflect.DeepEqual(actual, a.equals) {
+				failures = append(failures, fmt.Sprintf("%s: expected %v, got %v", a.path, a.equals, actual))
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return "assertion_failed", failures
+	}
+	return "success", nil
+}
+
+func (e *httpExpectation) statusMatches(status int) bool {
+	if len(e.statuses) == 0 {
+		return status >= 200 && status < 300
+	}
+
+	code := strconv.Itoa(status)
+	for _, s := range e.statuses {
+		if s == code || (strings.HasSuffix(s, "xx") && s[0] == code[0]) {
+			return true
+		}
+	}
+	return false
+}
+
 // JSONPathNodeBuilder builds JSONPath extraction nodes.
 type JSONPathNodeBuilder struct {
 	Verbose bool
@@ -840,13 +1679,26 @@ func (b *JSONPathNodeBuilder) Metadata() Metadata {
 	return Metadata{
 		Type:        "jsonpath",
 		Category:    "data",
-		Description: "Extracts data from JSON using JSONPath expressions",
+		Description: "Extracts or sets data in JSON using JSONPath expressions",
 		ConfigSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"path": map[string]interface{}{
 					"type":        "string",
-					"description": "JSONPath expression to extract data",
+					"description": "JSONPath expression to extract or set data",
+				},
+				"mode": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"get", "set"},
+					"default":     "get",
+					"description": "Extract matches (get) or write a value at the path and return the modified document (set)",
+				},
+				"value": map[string]interface{}{
+					"description": "Value to write in set mode; strings are Go templates evaluated against the input",
+				},
+				"value_from": map[string]interface{}{
+					"type":        "string",
+					"description": "JSONPath selecting the value to write from the input, keeping its type (set mode)",
 				},
 				"multiple": map[string]interface{}{
 					"type":        "boolean",
@@ -865,7 +1717,7 @@ func (b *JSONPathNodeBuilder) Metadata() Metadata {
 			"required": []string{"path"},
 		},
 		OutputSchema: map[string]interface{}{
-			"description": "Extracted value(s) from the JSONPath query",
+			"description": "Extracted value(s) from the JSONPath query, or the modified document in set mode",
 		},
 		Examples: []Example{
 			{
@@ -907,6 +1759,21 @@ func (b *JSONPathNodeBuilder) Metadata() Metadata {
 				Input:  map[string]interface{}{"other": "data"},
 				Output: "Not found",
 			},
+			{
+				Name:        "Set a field",
+				Description: "Write a templated value into the document",
+				Config: map[string]interface{}{
+					"mode":  "set",
+					"path":  "$.user.greeting",
+					"value": "Hello {{.user.name}}",
+				},
+				Input: map[string]interface{}{
+					"user": map[string]interface{}{"name": "Alice"},
+				},
+				Output: map[string]interface{}{
+					"user": map[string]interface{}{"name": "Alice", "greeting": "Hello Alice"},
+				},
+			},
 		},
 		Since: "1.0.0",
 	}
@@ -925,6 +1792,15 @@ func (b *JSONPathNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, erro
 		return nil, fmt.Errorf("invalid JSONPath expression: %w", err)
 	}
 
+	mode, _ := def.Config["mode"].(string)
+	switch mode {
+	case "", "get":
+	case "set":
+		return b.buildSet(def, expr)
+	default:
+		return nil, fmt.Errorf("unknown mode: %s", mode)
+	}
+
 	multiple, _ := def.Config["multiple"].(bool)
 	defaultValue := def.Config["default"]
 	unwrap := true
@@ -976,76 +1852,361 @@ func (b *JSONPathNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, erro
 	}), nil
 }
 
-// ValidateNodeBuilder builds JSON Schema validation nodes.
-type ValidateNodeBuilder struct {
+// buildSet creates a JSONPath node that writes a value at the path in a
+// copy of its input.
+func (b *JSONPathNodeBuilder) buildSet(def *yaml.NodeDefinition, expr jp.Expr) (pocket.Node, error) {
+	value, hasValue := def.Config["value"]
+	valueFrom, _ := def.Config["value_from"].(string)
+	if hasValue == (valueFrom != "") {
+		return nil, fmt.Errorf("set mode requires exactly one of value or value_from")
+	}
+
+	var valueTmpl *template.Template
+	var fromExpr jp.Expr
+	if valueFrom != "" {
+		var err error
+		if fromExpr, err = jp.ParseString(valueFrom); err != nil {
+			return nil, fmt.Errorf("invalid value_from: %w", err)
+		}
+	} else if s, ok := value.(string); ok && strings.Contains(s, "{{") {
+		var err error
+		if valueTmpl, err = newTemplate("value").Parse(s); err != nil {
+			return nil, fmt.Errorf("invalid value template: %w", err)
+		}
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			// Work on a copy so the input is never modified
+			doc := deepCopy(input)
+			switch doc.(type) {
+			case map[string]interface{}, []interface{}:
+			default:
+				return nil, fmt.Errorf("set requires an object or array input, got %T", input)
+			}
+
+			v := value
+			switch {
+			case fromExpr != nil:
+				matches := fromExpr.Get(input)
+				if len(matches) == 0 {
+					return nil, fmt.Errorf("value_from %s matched nothing", valueFrom)
+				}
+				v = matches[0]
+			case valueTmpl != nil:
+				var buf bytes.Buffer
+				if err := valueTmpl.Execute(&buf, input); err != nil {
+					return nil, fmt.Errorf("value template failed: %w", err)
+				}
+				v = buf.String()
+			}
+
+			if err := expr.Set(doc, deepCopy(v)); err != nil {
+				return nil, fmt.Errorf("set %s: %w", expr, err)
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] Set value at '%s'", def.Name, expr)
+			}
+			return doc, nil
+		},
+	}), nil
+}
+
+// JSONDiffNodeBuilder builds JSON diff nodes.
+type JSONDiffNodeBuilder struct {
 	Verbose bool
 }
 
 // Metadata returns the node metadata.
-func (b *ValidateNodeBuilder) Metadata() Metadata {
+func (b *JSONDiffNodeBuilder) Metadata() Metadata {
 	return Metadata{
-		Type:        "validate",
+		Type:        "jsondiff",
 		Category:    "data",
-		Description: "Validates data against JSON Schema",
+		Description: "Computes an RFC 6902 JSON patch between two values",
 		ConfigSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"schema": map[string]interface{}{
-					"type":        "object",
-					"description": "JSON Schema to validate against",
-				},
-				"schema_file": map[string]interface{}{
+				"from": map[string]interface{}{
 					"type":        "string",
-					"description": "Path to JSON Schema file (alternative to inline schema)",
+					"description": "JSONPath selecting the original value",
+					"default":     "$.from",
 				},
-				"fail_on_error": map[string]interface{}{
-					"type":        "boolean",
-					"default":     true,
-					"description": "Return error on validation failure (true) or continue with validation result (false)",
+				"to": map[string]interface{}{
+					"type":        "string",
+					"description": "JSONPath selecting the updated value",
+					"default":     "$.to",
 				},
 			},
-			"oneOf": []map[string]interface{}{
-				{"required": []string{"schema"}},
-				{"required": []string{"schema_file"}},
-			},
 		},
 		OutputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"valid": map[string]interface{}{"type": "boolean"},
-				"errors": map[string]interface{}{
-					"type": "array",
-					"items": map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"field":       map[string]interface{}{"type": "string"},
-							"type":        map[string]interface{}{"type": "string"},
-							"description": map[string]interface{}{"type": "string"},
-						},
-					},
+				"patch": map[string]interface{}{
+					"type":        "array",
+					"description": "RFC 6902 operations turning from into to",
 				},
-				"data": map[string]interface{}{
-					"description": "The original input data",
+				"changed": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Whether the values differ",
 				},
 			},
 		},
 		Examples: []Example{
 			{
-				Name:        "Validate user object",
-				Description: "Ensure user data matches expected schema",
+				Name:        "Detect changes",
+				Description: "Compare a stored record with a fresh one",
 				Config: map[string]interface{}{
-					"schema": map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"name":  map[string]interface{}{"type": "string"},
-							"email": map[string]interface{}{"type": "string", "format": "email"},
-							"age":   map[string]interface{}{"type": "integer", "minimum": 0},
-						},
-						"required": []string{"name", "email"},
-					},
+					"from": "$.cached",
+					"to":   "$.latest",
 				},
 				Input: map[string]interface{}{
-					"name":  "Alice",
+					"cached": map[string]interface{}{"status": "open"},
+					"latest": map[string]interface{}{"status": "closed"},
+				},
+				Output: map[string]interface{}{
+					"patch": []interface{}{
+						map[string]interface{}{"op": "replace", "path": "/status", "value": "closed"},
+					},
+					"changed": true,
+				},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// Build creates a JSON diff node from a definition.
+func (b *JSONDiffNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	fromExpr, err := jsonPathConfig(def.Config, "from", "$.from")
+	if err != nil {
+		return nil, err
+	}
+	toExpr, err := jsonPathConfig(def.Config, "to", "$.to")
+	if err != nil {
+		return nil, err
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			from, err := normalizeJSON(firstMatch(fromExpr, input))
+			if err != nil {
+				return nil, fmt.Errorf("normalize from: %w", err)
+			}
+			to, err := normalizeJSON(firstMatch(toExpr, input))
+			if err != nil {
+				return nil, fmt.Errorf("normalize to: %w", err)
+			}
+
+			patch := diffJSON("", from, to)
+			if patch == nil {
+				patch = []interface{}{}
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] Diff produced %d operations", def.Name, len(patch))
+			}
+
+			return map[string]interface{}{
+				"patch":   patch,
+				"changed": len(patch) > 0,
+			}, nil
+		},
+	}), nil
+}
+
+// JSONPatchNodeBuilder builds JSON patch nodes.
+type JSONPatchNodeBuilder struct {
+	Verbose bool
+}
+
+// Metadata returns the node metadata.
+func (b *JSONPatchNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "jsonpatch",
+		Category:    "data",
+		Description: "Applies an RFC 6902 JSON patch to a document",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"document": map[string]interface{}{
+					"type":        "string",
+					"description": "JSONPath selecting the document to patch",
+					"default":     "$.document",
+				},
+				"patch": map[string]interface{}{
+					"description": "JSONPath selecting the patch from the input, or an inline array of operations",
+					"default":     "$.patch",
+				},
+			},
+		},
+		OutputSchema: map[string]interface{}{
+			"description": "The patched document",
+		},
+		Examples: []Example{
+			{
+				Name:        "Apply proposed edits",
+				Description: "Apply a patch proposed by an earlier step",
+				Config: map[string]interface{}{
+					"document": "$.config",
+					"patch":    "$.edits",
+				},
+				Input: map[string]interface{}{
+					"config": map[string]interface{}{"retries": 3},
+					"edits": []interface{}{
+						map[string]interface{}{"op": "replace", "path": "/retries", "value": 5},
+					},
+				},
+				Output: map[string]interface{}{"retries": 5},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// Build creates a JSON patch node from a definition.
+func (b *JSONPatchNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	docExpr, err := jsonPathConfig(def.Config, "document", "$.document")
+	if err != nil {
+		return nil, err
+	}
+
+	// An inline patch is fixed at build time; otherwise it comes from the input
+	var inlinePatch []interface{}
+	var patchExpr jp.Expr
+	if ops, ok := def.Config["patch"].([]interface{}); ok {
+		normalized, err := normalizeJSON(ops)
+		if err != nil {
+			return nil, fmt.Errorf("invalid patch: %w", err)
+		}
+		inlinePatch = normalized.([]interface{})
+	} else if patchExpr, err = jsonPathConfig(def.Config, "patch", "$.patch"); err != nil {
+		return nil, err
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			ops := inlinePatch
+			if ops == nil {
+				normalized, err := normalizeJSON(firstMatch(patchExpr, input))
+				if err != nil {
+					return nil, fmt.Errorf("normalize patch: %w", err)
+				}
+				var ok bool
+				if ops, ok = normalized.([]interface{}); !ok {
+					return nil, fmt.Errorf("patch must be an array, got %T", normalized)
+				}
+			}
+
+			doc, err := normalizeJSON(firstMatch(docExpr, input))
+			if err != nil {
+				return nil, fmt.Errorf("normalize document: %w", err)
+			}
+
+			patched, err := applyPatch(doc, ops)
+			if err != nil {
+				return nil, fmt.Errorf("apply patch: %w", err)
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] Applied %d operations", def.Name, len(ops))
+			}
+			return patched, nil
+		},
+	}), nil
+}
+
+// jsonPathConfig parses a JSONPath config value, using def if it is unset.
+func jsonPathConfig(config map[string]interface{}, key, def string) (jp.Expr, error) {
+	path, _ := config[key].(string)
+	if path == "" {
+		path = def
+	}
+	expr, err := jp.ParseString(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s JSONPath: %w", key, err)
+	}
+	return expr, nil
+}
+
+// firstMatch returns the first value the expression selects, or nil.
+func firstMatch(expr jp.Expr, input any) any {
+	if results := expr.Get(input); len(results) > 0 {
+		return results[0]
+	}
+	return nil
+}
+
+// ValidateNodeBuilder builds JSON Schema validation nodes.
+type ValidateNodeBuilder struct {
+	Verbose bool
+}
+
+// Metadata returns the node metadata.
+func (b *ValidateNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "validate",
+		Category:    "data",
+		Description: "Validates data against JSON Schema",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"schema": map[string]interface{}{
+					"type":        "object",
+					"description": "JSON Schema to validate against",
+				},
+				"schema_file": map[string]interface{}{
+					"type":        "string",
+					"description": "Path to JSON Schema file (alternative to inline schema)",
+				},
+				"fail_on_error": map[string]interface{}{
+					"type":        "boolean",
+					"default":     true,
+					"description": "Return error on validation failure (true) or continue with validation result (false)",
+				},
+			},
+			"oneOf": []map[string]interface{}{
+				{"required": []string{"schema"}},
+				{"required": []string{"schema_file"}},
+			},
+		},
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"valid": map[string]interface{}{"type": "boolean"},
+				"errors": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"field":       map[string]interface{}{"type": "string"},
+							"type":        map[string]interface{}{"type": "string"},
+							"description": map[string]interface{}{"type": "string"},
+						},
+					},
+				},
+				"data": map[string]interface{}{
+					"description": "The original input data",
+				},
+			},
+		},
+		Examples: []Example{
+			{
+				Name:        "Validate user object",
+				Description: "Ensure user data matches expected schema",
+				Config: map[string]interface{}{
+					"schema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"name":  map[string]interface{}{"type": "string"},
+							"email": map[string]interface{}{"type": "string", "format": "email"},
+							"age":   map[string]interface{}{"type": "integer", "minimum": 0},
+						},
+						"required": []string{"name", "email"},
+					},
+				},
+				Input: map[string]interface{}{
+					"name":  "Alice",
 					"email": "alice@example.com",
 					"age":   30,
 				},
@@ -1206,7 +2367,7 @@ func (b *AggregateNodeBuilder) Metadata() Metadata {
 				"count": map[string]interface{}{
 					"type":        "integer",
 					"minimum":     1,
-					"description": "Number of inputs to collect before continuing",
+					"description": "Number of inputs to collect across runs before continuing",
 				},
 				"timeout": map[string]interface{}{
 					"type":        "string",
@@ -1218,6 +2379,10 @@ func (b *AggregateNodeBuilder) Metadata() Metadata {
 					"default":     false,
 					"description": "Allow partial results if timeout occurs",
 				},
+				"correlation_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Template grouping inputs into separate batches (with count)",
+				},
 			},
 		},
 		OutputSchema: map[string]interface{}{
@@ -1234,6 +2399,18 @@ func (b *AggregateNodeBuilder) Metadata() Metadata {
 					"type":        "boolean",
 					"description": "Whether all expected inputs were received",
 				},
+				"expected": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of inputs the batch waits for (with count)",
+				},
+				"pending": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Whether the batch is still collecting; such runs take the pending route",
+				},
+				"correlation_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Batch the input was added to (with count)",
+				},
 			},
 		},
 		Examples: []Example{
@@ -1288,15 +2465,25 @@ func (b *AggregateNodeBuilder) Metadata() Metadata {
 }
 
 // Build creates an aggregate node from a definition.
-//
-//nolint:gocyclo // Configuration parsing requires handling multiple aggregation strategies
 func (b *AggregateNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
 	mode, _ := def.Config["mode"].(string)
 	if mode == "" {
 		mode = "array"
 	}
 
-	keyTemplate, _ := def.Config["key"].(string)
+	keyTemplate, err := optionalTemplate("key", def.Config["key"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid key template: %w", err)
+	}
+
+	// With a count, inputs are collected across invocations
+	if _, ok := def.Config["count"]; ok {
+		collector, err := newAggregateCollector(def.Name, def.Config)
+		if err != nil {
+			return nil, err
+		}
+		return b.buildCollecting(def.Name, mode, keyTemplate, collector), nil
+	}
 
 	return pocket.NewNode[any, any](def.Name, pocket.Steps{
 		Exec: func(ctx context.Context, input any) (any, error) {
@@ -1326,51 +2513,9 @@ func (b *AggregateNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, err
 				log.Printf("[%s] Aggregating %d items in %s mode", def.Name, len(items), mode)
 			}
 
-			// Aggregate based on mode
-			var result interface{}
-			switch mode {
-			case "array":
-				result = items
-
-			case "object":
-				obj := make(map[string]interface{})
-				for i, item := range items {
-					key := fmt.Sprintf("item_%d", i)
-					if keyTemplate != "" {
-						// Execute key template
-						tmpl, err := template.New("key").Parse(keyTemplate)
-						if err == nil {
-							var buf bytes.Buffer
-							if err := tmpl.Execute(&buf, item); err == nil {
-								key = buf.String()
-							}
-						}
-					}
-					obj[key] = item
-				}
-				result = obj
-
-			case "merge":
-				result = make(map[string]interface{})
-				for _, item := range items {
-					if m, ok := item.(map[string]interface{}); ok {
-						result = deepMerge(result.(map[string]interface{}), m)
-					}
-				}
-
-			case "concat":
-				var concatenated []interface{}
-				for _, item := range items {
-					if arr, ok := item.([]interface{}); ok {
-						concatenated = append(concatenated, arr...)
-					} else {
-						concatenated = append(concatenated, item)
-					}
-				}
-				result = concatenated
-
-			default:
-				return nil, fmt.Errorf("unknown aggregation mode: %s", mode)
+			result, err := aggregateItems(mode, keyTemplate, items)
+			if err != nil {
+				return nil, err
 			}
 
 			response := map[string]interface{}{
@@ -1384,6 +2529,51 @@ func (b *AggregateNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, err
 	}), nil
 }
 
+// buildCollecting creates an aggregate node that adds each input to a batch
+// in the store. Runs that leave the batch incomplete take the "pending"
+// route; the run that completes it continues on "default" with the
+// aggregated batch.
+func (b *AggregateNodeBuilder) buildCollecting(name, mode string, key *template.Template, collector *aggregateCollector) pocket.Node {
+	return pocket.NewNode[any, any](name, pocket.Steps{
+		Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+			status, err := collector.add(ctx, store, input)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if !status.done {
+				if b.Verbose {
+					log.Printf("[%s] Collected %d of %d inputs for %s", name, len(status.items), collector.count, status.id)
+				}
+				return map[string]interface{}{
+					"correlation_id": status.id,
+					"count":          len(status.items),
+					"expected":       collector.count,
+					"complete":       false,
+					"pending":        true,
+				}, "pending", nil
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] Aggregating %d items for %s in %s mode", name, len(status.items), status.id, mode)
+			}
+
+			result, err := aggregateItems(mode, key, status.items)
+			if err != nil {
+				return nil, "", err
+			}
+			return map[string]interface{}{
+				"correlation_id": status.id,
+				"data":           result,
+				"count":          len(status.items),
+				"expected":       collector.count,
+				"complete":       status.complete,
+				"pending":        false,
+			}, "default", nil
+		},
+	})
+}
+
 // deepMerge recursively merges two maps.
 func deepMerge(dst, src map[string]interface{}) map[string]interface{} {
 	for key, srcVal := range src {
@@ -1402,147 +2592,3030 @@ func deepMerge(dst, src map[string]interface{}) map[string]interface{} {
 	return dst
 }
 
-// FileNodeBuilder builds file I/O nodes with sandboxing.
-type FileNodeBuilder struct {
+// ObjectNodeBuilder builds object manipulation nodes.
+type ObjectNodeBuilder struct {
 	Verbose bool
 }
 
 // Metadata returns the node metadata.
-func (b *FileNodeBuilder) Metadata() Metadata {
+func (b *ObjectNodeBuilder) Metadata() Metadata {
+	pathList := map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": "string"},
+	}
+
 	return Metadata{
-		Type:        "file",
-		Category:    "io",
-		Description: "Reads or writes files with path restrictions",
+		Type:        "object",
+		Category:    "data",
+		Description: "Picks, omits, renames, sets, and deletes fields at dotted paths and deep-merges objects",
 		ConfigSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"operation": map[string]interface{}{
-					"type":        "string",
-					"enum":        []string{"read", "write", "append", "exists", "list"},
-					"default":     "read",
-					"description": "File operation to perform",
-				},
-				"path": map[string]interface{}{
-					"type":        "string",
-					"description": "File path (relative to working directory or absolute if allowed)",
+				"operations": map[string]interface{}{
+					"type":        "array",
+					"description": "Operations applied in order, each an object with a single key",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"pick":   mergeSchema(pathList, "Keep only these paths"),
+							"omit":   mergeSchema(pathList, "Remove these paths"),
+							"delete": map[string]interface{}{"type": "string", "description": "Remove one path"},
+							"rename": map[string]interface{}{
+								"type":                 "object",
+								"description":          "Map of old paths to new paths",
+								"additionalProperties": map[string]interface{}{"type": "string"},
+							},
+							"set": map[string]interface{}{
+								"type":        "object",
+								"description": "Map of paths to values; string values are Go templates evaluated against the node input",
+							},
+							"merge": mergeSchema(pathList, "JSONPaths selecting objects to deep-merge in, later ones winning"),
+						},
+						"minProperties": 1,
+						"maxProperties": 1,
+					},
 				},
-				"content": map[string]interface{}{
-					"type":        "string",
-					"description": "Content to write (for write/append operations)",
+			},
+			"required": []string{"operations"},
+		},
+		OutputSchema: map[string]interface{}{
+			"type":        "object",
+			"description": "The resulting object",
+		},
+		Examples: []Example{
+			{
+				Name:        "Shape a record",
+				Description: "Merge defaults, rename a field, and drop secrets",
+				Config: map[string]interface{}{
+					"operations": []interface{}{
+						map[string]interface{}{"merge": []interface{}{"$.defaults", "$.user"}},
+						map[string]interface{}{"rename": map[string]interface{}{"name": "profile.display_name"}},
+						map[string]interface{}{"omit": []interface{}{"password", "defaults", "user"}},
+						map[string]interface{}{"set": map[string]interface{}{"profile.source": "signup"}},
+					},
 				},
-				"encoding": map[string]interface{}{
-					"type":        "string",
-					"default":     "utf-8",
-					"description": "File encoding",
+				Input: map[string]interface{}{
+					"defaults": map[string]interface{}{"theme": "light"},
+					"user":     map[string]interface{}{"name": "Ada", "password": "secret"},
 				},
-				"base_dir": map[string]interface{}{
-					"type":        "string",
-					"description": "Base directory for sandboxing (defaults to current working directory)",
+				Output: map[string]interface{}{
+					"theme":   "light",
+					"profile": map[string]interface{}{"display_name": "Ada", "source": "signup"},
 				},
-				"allow_absolute": map[string]interface{}{
-					"type":        "boolean",
-					"default":     false,
-					"description": "Allow absolute paths outside base directory",
-				},
-				"create_dirs": map[string]interface{}{
-					"type":        "boolean",
-					"default":     false,
-					"description": "Create parent directories if they don't exist",
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// objectOp transforms the working object. input is the node's original input.
+type objectOp func(obj map[string]interface{}, input any) (map[string]interface{}, error)
+
+// Build creates an object node from a definition.
+func (b *ObjectNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	opsRaw, ok := def.Config["operations"].([]interface{})
+	if !ok || len(opsRaw) == 0 {
+		return nil, fmt.Errorf("operations must be a non-empty array")
+	}
+
+	ops := make([]objectOp, 0, len(opsRaw))
+	for i, raw := range opsRaw {
+		spec, ok := raw.(map[string]interface{})
+		if !ok || len(spec) != 1 {
+			return nil, fmt.Errorf("operation %d must be an object with a single key", i)
+		}
+		for name, arg := range spec {
+			op, err := newObjectOp(def.Name, name, arg)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d (%s): %w", i, name, err)
+			}
+			ops = append(ops, op)
+		}
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			// Work on a copy so the input is never modified
+			obj, _ := deepCopy(input).(map[string]interface{})
+			if obj == nil {
+				obj = make(map[string]interface{})
+			}
+
+			for _, op := range ops {
+				var err error
+				if obj, err = op(obj, input); err != nil {
+					return nil, err
+				}
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] Applied %d operations", def.Name, len(ops))
+			}
+			return obj, nil
+		},
+	}), nil
+}
+
+// newObjectOp parses one object operation.
+//
+//nolint:gocyclo // One case per operation
+func newObjectOp(nodeName, name string, arg interface{}) (objectOp, error) {
+	switch name {
+	case "pick", "omit", "merge":
+		paths, err := stringList(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		switch name {
+		case "pick":
+			return func(obj map[string]interface{}, _ any) (map[string]interface{}, error) {
+				picked := make(map[string]interface{})
+				for _, p := range paths {
+					if v, ok := getDotted(obj, p); ok {
+						setDotted(picked, p, v)
+					}
+				}
+				return picked, nil
+			}, nil
+
+		case "omit":
+			return func(obj map[string]interface{}, _ any) (map[string]interface{}, error) {
+				for _, p := range paths {
+					deleteDotted(obj, p)
+				}
+				return obj, nil
+			}, nil
+
+		default:
+			exprs := make([]jp.Expr, len(paths))
+			for i, p := range paths {
+				expr, err := jp.ParseString(p)
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSONPath %q: %w", p, err)
+				}
+				exprs[i] = expr
+			}
+			return func(obj map[string]interface{}, input any) (map[string]interface{}, error) {
+				for i, expr := range exprs {
+					src, ok := firstMatch(expr, input).(map[string]interface{})
+					if !ok {
+						return nil, fmt.Errorf("merge: %s did not select an object", paths[i])
+					}
+					obj = deepMerge(obj, deepCopy(src).(map[string]interface{}))
+				}
+				return obj, nil
+			}, nil
+		}
+
+	case "delete":
+		path, ok := arg.(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("path must be a string")
+		}
+		return func(obj map[string]interface{}, _ any) (map[string]interface{}, error) {
+			deleteDotted(obj, path)
+			return obj, nil
+		}, nil
+
+	case "rename":
+		renames, ok := arg.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("rename must map old paths to new paths")
+		}
+		// Sort so renames apply in a stable order
+		from := make([]string, 0, len(renames))
+		for k, v := range renames {
+			if _, ok := v.(string); !ok {
+				return nil, fmt.Errorf("new path for %q must be a string", k)
+			}
+			from = append(from, k)
+		}
+		sort.Strings(from)
+		return func(obj map[string]interface{}, _ any) (map[string]interface{}, error) {
+			for _, old := range from {
+				if v, ok := getDotted(obj, old); ok {
+					deleteDotted(obj, old)
+					setDotted(obj, renames[old].(string), v)
+				}
+			}
+			return obj, nil
+		}, nil
+
+	case "set":
+		values, ok := arg.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("set must map paths to values")
+		}
+		templates := make(map[string]*template.Template)
+		for path, v := range values {
+			if s, ok := v.(string); ok && strings.Contains(s, "{{") {
+				tmpl, err := newTemplate(nodeName + "." + path).Parse(s)
+				if err != nil {
+					return nil, fmt.Errorf("invalid template for %q: %w", path, err)
+				}
+				templates[path] = tmpl
+			}
+		}
+		return func(obj map[string]interface{}, input any) (map[string]interface{}, error) {
+			for path, v := range values {
+				if tmpl, ok := templates[path]; ok {
+					var buf bytes.Buffer
+					if err := tmpl.Execute(&buf, input); err != nil {
+						return nil, fmt.Errorf("set %s: %w", path, err)
+					}
+					v = buf.String()
+				}
+				setDotted(obj, path, deepCopy(v))
+			}
+			return obj, nil
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown operation")
+	}
+}
+
+// stringList converts a config list to strings.
+func stringList(v interface{}) ([]string, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list of strings")
+	}
+	out := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("item %d must be a string", i)
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+// getDotted reads a value at a dotted path such as "user.address.city".
+func getDotted(obj map[string]interface{}, path string) (interface{}, bool) {
+	keys := strings.Split(path, ".")
+	current := obj
+	for i, key := range keys {
+		v, ok := current[key]
+		if !ok {
+			return nil, false
+		}
+		if i == len(keys)-1 {
+			return v, true
+		}
+		if current, ok = v.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// setDotted writes a value at a dotted path, creating or replacing
+// intermediate objects as needed.
+func setDotted(obj map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	current := obj
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[key] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+}
+
+// deleteDotted removes the value at a dotted path if it exists.
+func deleteDotted(obj map[string]interface{}, path string) {
+	keys := strings.Split(path, ".")
+	current := obj
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		current = next
+	}
+	delete(current, keys[len(keys)-1])
+}
+
+// deepCopy copies nested maps and slices so they can be modified safely.
+func deepCopy(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = deepCopy(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = deepCopy(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// GenerateNodeBuilder builds value generator nodes.
+type GenerateNodeBuilder struct {
+	Verbose bool
+}
+
+// Metadata returns the node metadata.
+func (b *GenerateNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "generate",
+		Category:    "data",
+		Description: "Generates UUIDs, random numbers, and timestamps into output fields",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"fields": map[string]interface{}{
+					"type":        "object",
+					"description": "Map of output field names to generator specs",
+					"additionalProperties": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"type": map[string]interface{}{
+								"type":        "string",
+								"enum":        []string{"uuid", "int", "float", "timestamp"},
+								"description": "Kind of value to generate",
+							},
+							"min": map[string]interface{}{
+								"type":        "number",
+								"description": "Inclusive lower bound for int and float (default: 0)",
+							},
+							"max": map[string]interface{}{
+								"type":        "number",
+								"description": "Upper bound for int (inclusive) and float (exclusive) (default: 100 for int, 1 for float)",
+							},
+							"format": map[string]interface{}{
+								"type":        "string",
+								"description": "Timestamp format: rfc3339, rfc3339nano, unix, unix_ms, or a Go time layout",
+								"default":     "rfc3339",
+							},
+							"timezone": map[string]interface{}{
+								"type":        "string",
+								"description": "IANA timezone for timestamps (default: UTC)",
+							},
+						},
+						"required": []string{"type"},
+					},
 				},
 			},
-			"required": []string{"operation", "path"},
+			"required": []string{"fields"},
 		},
 		OutputSchema: map[string]interface{}{
+			"type":        "object",
+			"description": "The input object with generated fields added, or just the generated fields for non-object input",
+		},
+		Examples: []Example{
+			{
+				Name:        "Request metadata",
+				Description: "Attach an ID and creation time to a record",
+				Config: map[string]interface{}{
+					"fields": map[string]interface{}{
+						"id":         map[string]interface{}{"type": "uuid"},
+						"created_at": map[string]interface{}{"type": "timestamp"},
+						"sample":     map[string]interface{}{"type": "float", "min": 0, "max": 1},
+					},
+				},
+				Input: map[string]interface{}{"name": "order"},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// generator produces one generated value.
+type generator func(ctx context.Context) (interface{}, error)
+
+// Build creates a generate node from a definition.
+func (b *GenerateNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	fieldsRaw, ok := def.Config["fields"].(map[string]interface{})
+	if !ok || len(fieldsRaw) == 0 {
+		return nil, fmt.Errorf("fields must be a non-empty object")
+	}
+
+	generators := make(map[string]generator, len(fieldsRaw))
+	fields := make([]string, 0, len(fieldsRaw))
+	for field, specRaw := range fieldsRaw {
+		spec, ok := specRaw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field %q must be an object", field)
+		}
+		gen, err := newGenerator(spec)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", field, err)
+		}
+		generators[field] = gen
+		fields = append(fields, field)
+	}
+	// Draw in a fixed order, so seeded runs repeat
+	sort.Strings(fields)
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			output := make(map[string]interface{})
+			if m, ok := input.(map[string]interface{}); ok {
+				for k, v := range m {
+					output[k] = v
+				}
+			}
+
+			for _, field := range fields {
+				value, err := generators[field](ctx)
+				if err != nil {
+					return nil, fmt.Errorf("generate %s: %w", field, err)
+				}
+				output[field] = value
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] Generated %d fields", def.Name, len(generators))
+			}
+			return output, nil
+		},
+	}), nil
+}
+
+// newGenerator creates a generator from a field spec.
+func newGenerator(spec map[string]interface{}) (generator, error) {
+	kind, _ := spec["type"].(string)
+	switch kind {
+	case "uuid":
+		return func(ctx context.Context) (interface{}, error) { return newUUID(ctx) }, nil
+
+	case "int":
+		lo, hi, err := generatorRange(spec, 0, 100)
+		if err != nil {
+			return nil, err
+		}
+		low, high := int64(lo), int64(hi)
+		return func(ctx context.Context) (interface{}, error) {
+			return low + randInt63n(ctx, high-low+1), nil
+		}, nil
+
+	case "float":
+		lo, hi, err := generatorRange(spec, 0, 1)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return lo + randFloat64(ctx)*(hi-lo), nil
+		}, nil
+
+	case "timestamp":
+		format, _ := spec["format"].(string)
+		loc := time.UTC
+		if tz, ok := spec["timezone"].(string); ok && tz != "" {
+			l, err := time.LoadLocation(tz)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timezone: %w", err)
+			}
+			loc = l
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return formatTime(pocket.ClockFrom(ctx).Now().In(loc), format), nil
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown generator type %q", kind)
+	}
+}
+
+// generatorRange reads min and max from a spec.
+func generatorRange(spec map[string]interface{}, defaultMin, defaultMax float64) (lo, hi float64, err error) {
+	lo, hi = defaultMin, defaultMax
+	if v, ok := toFloat(spec["min"]); ok {
+		lo = v
+	}
+	if v, ok := toFloat(spec["max"]); ok {
+		hi = v
+	}
+	if hi < lo {
+		return 0, 0, fmt.Errorf("max %v is less than min %v", hi, lo)
+	}
+	return lo, hi, nil
+}
+
+// formatTime formats a time by format name or Go layout. Unix formats
+// produce integers; everything else produces a string.
+func formatTime(t time.Time, format string) interface{} {
+	switch format {
+	case "", "rfc3339":
+		return t.Format(time.RFC3339)
+	case "rfc3339nano":
+		return t.Format(time.RFC3339Nano)
+	case "unix":
+		return t.Unix()
+	case "unix_ms":
+		return t.UnixMilli()
+	default:
+		return t.Format(format)
+	}
+}
+
+// newUUID returns a random version 4 UUID. Deterministic runs draw it from
+// their seeded source.
+func newUUID(ctx context.Context) (string, error) {
+	var u [16]byte
+	if r, ok := pocket.RandFrom(ctx); ok {
+		binary.BigEndian.PutUint64(u[:8], r.Uint64())
+		binary.BigEndian.PutUint64(u[8:], r.Uint64())
+	} else if _, err := crand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40 // Version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
+
+// DatetimeNodeBuilder builds date and time manipulation nodes.
+type DatetimeNodeBuilder struct {
+	Verbose bool
+}
+
+// Metadata returns the node metadata.
+func (b *DatetimeNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "datetime",
+		Category:    "data",
+		Description: "Parses, formats, converts, and does arithmetic on dates and times",
+		ConfigSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"path": map[string]interface{}{
+				"operation": map[string]interface{}{
 					"type":        "string",
-					"description": "Resolved file path",
+					"enum":        []string{"now", "parse", "format", "convert", "add", "subtract", "diff"},
+					"description": "Operation to perform",
 				},
-				"exists": map[string]interface{}{
-					"type":        "boolean",
-					"description": "Whether the file exists",
+				"value": map[string]interface{}{
+					"type":        "string",
+					"description": "Go template selecting the time from the input (default: the input itself)",
 				},
-				"content": map[string]interface{}{
+				"input_format": map[string]interface{}{
 					"type":        "string",
-					"description": "File content (for read operations)",
+					"description": "Layout of the input time: rfc3339, unix, unix_ms, or a Go time layout (default: detected)",
 				},
-				"size": map[string]interface{}{
-					"type":        "integer",
-					"description": "File size in bytes",
+				"format": map[string]interface{}{
+					"type":        "string",
+					"description": "Output format: rfc3339, rfc3339nano, unix, unix_ms, or a Go time layout",
+					"default":     "rfc3339",
 				},
-				"modified": map[string]interface{}{
+				"timezone": map[string]interface{}{
 					"type":        "string",
-					"format":      "date-time",
-					"description": "Last modification time",
+					"description": "IANA timezone for the output (default: the input's zone, or UTC for now)",
 				},
-				"files": map[string]interface{}{
-					"type":        "array",
-					"description": "List of files (for list operation)",
-					"items": map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"name":     map[string]interface{}{"type": "string"},
-							"path":     map[string]interface{}{"type": "string"},
-							"size":     map[string]interface{}{"type": "integer"},
-							"modified": map[string]interface{}{"type": "string", "format": "date-time"},
-							"isDir":    map[string]interface{}{"type": "boolean"},
-						},
-					},
+				"duration": map[string]interface{}{
+					"type":        "string",
+					"description": "Duration for add and subtract, e.g. '90m' or '7d'. May be a Go template",
+				},
+				"other": map[string]interface{}{
+					"type":        "string",
+					"description": "Go template selecting the time to subtract for diff",
 				},
 			},
+			"required": []string{"operation"},
+		},
+		OutputSchema: map[string]interface{}{
+			"description": "The formatted time; for parse, its components; for diff, the difference in several units",
 		},
 		Examples: []Example{
 			{
-				Name:        "Read file",
-				Description: "Read contents of a text file",
+				Name:        "Due date",
+				Description: "Add a week to a creation date",
 				Config: map[string]interface{}{
-					"operation": "read",
-					"path":      "config.json",
-				},
-				Output: map[string]interface{}{
-					"path":     "/app/config.json",
-					"exists":   true,
-					"content":  "{\"version\": \"1.0.0\"}",
-					"size":     20,
-					"modified": "2024-01-15T10:30:00Z",
+					"operation": "add",
+					"value":     "{{.created_at}}",
+					"duration":  "7d",
+					"format":    "2006-01-02",
 				},
+				Input:  map[string]interface{}{"created_at": "2024-03-01T09:00:00Z"},
+				Output: "2024-03-08",
 			},
 			{
-				Name:        "Write file",
-				Description: "Write content to a file",
+				Name:        "Age in days",
+				Description: "Compute how long ago an event happened",
 				Config: map[string]interface{}{
-					"operation":   "write",
-					"path":        "output/result.txt",
-					"content":     "Processing complete",
-					"create_dirs": true,
+					"operation": "diff",
+					"value":     "{{.now}}",
+					"other":     "{{.created_at}}",
+				},
+				Input: map[string]interface{}{
+					"now":        "2024-03-08T09:00:00Z",
+					"created_at": "2024-03-01T09:00:00Z",
 				},
 				Output: map[string]interface{}{
-					"path":     "/app/output/result.txt",
-					"exists":   true,
-					"size":     19,
-					"modified": "2024-01-15T10:35:00Z",
+					"duration": "168h0m0s",
+					"seconds":  604800.0,
+					"minutes":  10080.0,
+					"hours":    168.0,
+					"days":     7.0,
+				},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// Build creates a datetime node from a definition.
+//
+//nolint:gocyclo // Handles every operation in a single Exec step
+func (b *DatetimeNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	operation, _ := def.Config["operation"].(string)
+	switch operation {
+	case "now", "parse", "format", "convert", "add", "subtract", "diff":
+	default:
+		return nil, fmt.Errorf("unknown operation %q", operation)
+	}
+
+	inputFormat, _ := def.Config["input_format"].(string)
+	outputFormat, _ := def.Config["format"].(string)
+
+	var loc *time.Location
+	if tz, ok := def.Config["timezone"].(string); ok && tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone: %w", err)
+		}
+		loc = l
+	} else if operation == "convert" {
+		return nil, fmt.Errorf("timezone is required for convert")
+	}
+
+	valueTmpl, err := optionalTemplate(def.Name+".value", def.Config["value"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value template: %w", err)
+	}
+	durationTmpl, err := optionalTemplate(def.Name+".duration", def.Config["duration"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration: %w", err)
+	}
+	otherTmpl, err := optionalTemplate(def.Name+".other", def.Config["other"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid other template: %w", err)
+	}
+
+	if (operation == "add" || operation == "subtract") && durationTmpl == nil {
+		return nil, fmt.Errorf("duration is required for %s", operation)
+	}
+	if operation == "diff" && otherTmpl == nil {
+		return nil, fmt.Errorf("other is required for diff")
+	}
+
+	// resolve reads a time from the input, either through a template or
+	// from the input itself
+	resolve := func(tmpl *template.Template, input any) (time.Time, error) {
+		var raw interface{} = input
+		if tmpl != nil {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, input); err != nil {
+				return time.Time{}, err
+			}
+			raw = strings.TrimSpace(buf.String())
+		}
+		return parseTime(raw, inputFormat)
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			var t time.Time
+			if operation == "now" {
+				t = pocket.ClockFrom(ctx).Now().UTC()
+			} else {
+				parsed, err := resolve(valueTmpl, input)
+				if err != nil {
+					return nil, fmt.Errorf("read time: %w", err)
+				}
+				t = parsed
+			}
+
+			switch operation {
+			case "add", "subtract":
+				var buf bytes.Buffer
+				if err := durationTmpl.Execute(&buf, input); err != nil {
+					return nil, fmt.Errorf("duration template failed: %w", err)
+				}
+				d, err := parseCalendarDuration(strings.TrimSpace(buf.String()))
+				if err != nil {
+					return nil, err
+				}
+				if operation == "subtract" {
+					d = -d
+				}
+				t = t.Add(d)
+
+			case "diff":
+				other, err := resolve(otherTmpl, input)
+				if err != nil {
+					return nil, fmt.Errorf("read other time: %w", err)
+				}
+				d := t.Sub(other)
+				return map[string]interface{}{
+					"duration": d.String(),
+					"seconds":  d.Seconds(),
+					"minutes":  d.Minutes(),
+					"hours":    d.Hours(),
+					"days":     d.Hours() / 24,
+				}, nil
+			}
+
+			if loc != nil {
+				t = t.In(loc)
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] %s: %s", def.Name, operation, t.Format(time.RFC3339))
+			}
+
+			if operation == "parse" {
+				return timeComponents(t), nil
+			}
+			return formatTime(t, outputFormat), nil
+		},
+	}), nil
+}
+
+// optionalTemplate parses a template config value, returning nil if unset.
+func optionalTemplate(name string, raw interface{}) (*template.Template, error) {
+	s, ok := raw.(string)
+	if !ok || s == "" {
+		return nil, nil
+	}
+	return newTemplate(name).Parse(s)
+}
+
+// timeLayouts are tried in order when no input format is configured.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	time.DateOnly,
+}
+
+// parseTime reads a time from a string or unix timestamp. Times without a
+// zone are read as UTC.
+func parseTime(raw interface{}, format string) (time.Time, error) {
+	if n, ok := toFloat(raw); ok {
+		if format == "unix_ms" {
+			return time.UnixMilli(int64(n)).UTC(), nil
+		}
+		return time.Unix(int64(n), 0).UTC(), nil
+	}
+
+	s, ok := raw.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected a time string or unix timestamp, got %T", raw)
+	}
+
+	switch format {
+	case "":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(n, 0).UTC(), nil
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+	case "rfc3339":
+		return time.Parse(time.RFC3339, s)
+	case "rfc3339nano":
+		return time.Parse(time.RFC3339Nano, s)
+	case "unix", "unix_ms":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid unix timestamp %q", s)
+		}
+		return parseTime(n, format)
+	default:
+		return time.Parse(format, s)
+	}
+}
+
+// parseCalendarDuration parses a Go duration, also accepting a whole number
+// of days such as "7d".
+func parseCalendarDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// timeComponents breaks a time into fields templates can use directly.
+func timeComponents(t time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"iso":      t.Format(time.RFC3339),
+		"unix":     t.Unix(),
+		"year":     t.Year(),
+		"month":    int(t.Month()),
+		"day":      t.Day(),
+		"hour":     t.Hour(),
+		"minute":   t.Minute(),
+		"second":   t.Second(),
+		"weekday":  t.Weekday().String(),
+		"yearday":  t.YearDay(),
+		"timezone": t.Location().String(),
+	}
+}
+
+// HashNodeBuilder builds hashing and signing nodes.
+type HashNodeBuilder struct {
+	Verbose bool
+}
+
+// Metadata returns the node metadata.
+func (b *HashNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "hash",
+		Category:    "data",
+		Description: "Computes hashes and HMAC signatures and verifies signatures",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"operation": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"hash", "hmac", "verify"},
+					"default":     "hash",
+					"description": "hash computes a digest, hmac signs with a key, verify checks an HMAC signature",
+				},
+				"algorithm": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"sha256", "sha512", "sha1", "md5"},
+					"default":     "sha256",
+					"description": "Hash algorithm",
+				},
+				"value": map[string]interface{}{
+					"type":        "string",
+					"description": "Go template selecting the data to hash (default: the input, JSON-encoded unless it is a string)",
+				},
+				"key_env": map[string]interface{}{
+					"type":        "string",
+					"description": "Environment variable holding the HMAC key",
+				},
+				"key_file": map[string]interface{}{
+					"type":        "string",
+					"description": "File holding the HMAC key",
+				},
+				"signature": map[string]interface{}{
+					"type":        "string",
+					"description": "Go template selecting the signature to verify",
+				},
+				"encoding": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"hex", "base64"},
+					"default":     "hex",
+					"description": "Encoding of the digest and signature",
+				},
+				"prefix": map[string]interface{}{
+					"type":        "string",
+					"description": "Prefix added to digests and stripped from signatures, e.g. 'sha256='",
+				},
+				"fail_on_error": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Return an error when verification fails",
+				},
+			},
+		},
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"hash": map[string]interface{}{
+					"type":        "string",
+					"description": "Encoded digest (hash and hmac)",
+				},
+				"valid": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Whether the signature matched (verify)",
+				},
+				"data": map[string]interface{}{
+					"description": "The original input",
 				},
 			},
+		},
+		Examples: []Example{
 			{
-				Name:        "List directory",
-				Description: "List files in a directory",
+				Name:        "Cache key",
+				Description: "Hash a request into a cache key",
 				Config: map[string]interface{}{
-					"operation": "list",
-					"path":      "data/",
+					"value": "{{.method}} {{.url}}",
 				},
-				Output: map[string]interface{}{
-					"path":   "/app/data",
-					"exists": true,
-					"files": []interface{}{
-						map[string]interface{}{
-							"name":     "file1.txt",
-							"path":     "/app/data/file1.txt",
-							"size":     1024,
-							"modified": "2024-01-14T09:00:00Z",
-							"isDir":    false,
-						},
-					},
+			},
+			{
+				Name:        "Verify a webhook",
+				Description: "Check a GitHub-style webhook signature",
+				Config: map[string]interface{}{
+					"operation":     "verify",
+					"value":         "{{.body}}",
+					"signature":     "{{.signature}}",
+					"key_env":       "WEBHOOK_SECRET",
+					"prefix":        "sha256=",
+					"fail_on_error": true,
+				},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// Build creates a hash node from a definition.
+//
+//nolint:gocyclo // Config parsing for three operations and two key sources
+func (b *HashNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	operation, _ := def.Config["operation"].(string)
+	if operation == "" {
+		operation = "hash"
+	}
+	if operation != "hash" && operation != "hmac" && operation != "verify" {
+		return nil, fmt.Errorf("unknown operation %q", operation)
+	}
+
+	algorithm, _ := def.Config["algorithm"].(string)
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	newHash, err := hashFunc(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding, _ := def.Config["encoding"].(string)
+	if encoding == "" {
+		encoding = "hex"
+	}
+	if encoding != "hex" && encoding != "base64" {
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+
+	prefix, _ := def.Config["prefix"].(string)
+	failOnError, _ := def.Config["fail_on_error"].(bool)
+
+	valueTmpl, err := optionalTemplate(def.Name+".value", def.Config["value"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value template: %w", err)
+	}
+	signatureTmpl, err := optionalTemplate(def.Name+".signature", def.Config["signature"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature template: %w", err)
+	}
+	if operation == "verify" && signatureTmpl == nil {
+		return nil, fmt.Errorf("signature is required for verify")
+	}
+
+	var key []byte
+	if operation != "hash" {
+		if key, err = hmacKey(def.Config); err != nil {
+			return nil, err
+		}
+	}
+
+	digest := func(data []byte) []byte {
+		var h hash.Hash
+		if key != nil {
+			h = hmac.New(newHash, key)
+		} else {
+			h = newHash()
+		}
+		h.Write(data)
+		return h.Sum(nil)
+	}
+
+	encode := func(sum []byte) string {
+		if encoding == "base64" {
+			return base64.StdEncoding.EncodeToString(sum)
+		}
+		return hex.EncodeToString(sum)
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			data, err := inputBytes(valueTmpl, input)
+			if err != nil {
+				return nil, err
+			}
+			sum := digest(data)
+
+			if operation != "verify" {
+				return map[string]interface{}{
+					"hash":      prefix + encode(sum),
+					"algorithm": algorithm,
+					"data":      input,
+				}, nil
+			}
+
+			var buf bytes.Buffer
+			if err := signatureTmpl.Execute(&buf, input); err != nil {
+				return nil, fmt.Errorf("signature template failed: %w", err)
+			}
+			signature := strings.TrimPrefix(strings.TrimSpace(buf.String()), prefix)
+
+			// Compare decoded bytes in constant time
+			var expected []byte
+			if encoding == "base64" {
+				expected, err = base64.StdEncoding.DecodeString(signature)
+			} else {
+				expected, err = hex.DecodeString(signature)
+			}
+			valid := err == nil && hmac.Equal(sum, expected)
+
+			if b.Verbose {
+				log.Printf("[%s] Signature valid: %v", def.Name, valid)
+			}
+
+			response := map[string]interface{}{
+				"valid": valid,
+				"data":  input,
+			}
+			if !valid && failOnError {
+				return response, fmt.Errorf("signature verification failed")
+			}
+			return response, nil
+		},
+	}), nil
+}
+
+// hashFunc returns the constructor for a hash algorithm.
+func hashFunc(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	case "sha1":
+		return sha1.New, nil // #nosec G401 - Needed for legacy signatures
+	case "md5":
+		return md5.New, nil // #nosec G401 - Used for checksums, not security
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", algorithm)
+	}
+}
+
+// hmacKey reads the HMAC key from the environment or a file so secrets stay
+// out of workflow files.
+func hmacKey(config map[string]interface{}) ([]byte, error) {
+	return secretValue(config, "key")
+}
+
+// secretValue reads a secret from the environment variable named by
+// <name>_env or the file named by <name>_file.
+func secretValue(config map[string]interface{}, name string) ([]byte, error) {
+	if env, ok := config[name+"_env"].(string); ok && env != "" {
+		value, exists := os.LookupEnv(env)
+		if !exists || value == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", env)
+		}
+		return []byte(value), nil
+	}
+
+	if path, ok := config[name+"_file"].(string); ok && path != "" {
+		data, err := os.ReadFile(path) // #nosec G304 - Secret files are user-configured
+		if err != nil {
+			return nil, fmt.Errorf("read %s file: %w", name, err)
+		}
+		return bytes.TrimSpace(data), nil
+	}
+
+	return nil, fmt.Errorf("%s_env or %s_file is required", name, name)
+}
+
+// inputBytes selects the bytes to work on: the rendered value template, or the
+// input itself with non-string values JSON-encoded.
+func inputBytes(tmpl *template.Template, input any) ([]byte, error) {
+	if tmpl != nil {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, input); err != nil {
+			return nil, fmt.Errorf("value template failed: %w", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	switch v := input.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("encode input: %w", err)
+		}
+		return data, nil
+	}
+}
+
+// CompressNodeBuilder builds compression nodes.
+type CompressNodeBuilder struct {
+	Verbose bool
+}
+
+// Metadata returns the node metadata.
+func (b *CompressNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "compress",
+		Category:    "data",
+		Description: "Compresses and decompresses data with gzip or zstd",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"operation": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"compress", "decompress"},
+					"default":     "compress",
+					"description": "Whether to compress or decompress",
+				},
+				"algorithm": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"gzip", "zstd"},
+					"default":     "gzip",
+					"description": "Compression algorithm",
+				},
+				"value": map[string]interface{}{
+					"type":        "string",
+					"description": "Go template selecting the data (default: the input)",
+				},
+				"file": map[string]interface{}{
+					"type":        "string",
+					"description": "Read the data from this file instead of the input",
+				},
+			},
+		},
+		OutputSchema: map[string]interface{}{
+			"type":        "string",
+			"description": "Base64-encoded compressed data, or the decompressed data",
+		},
+		Examples: []Example{
+			{
+				Name:        "Compress a payload",
+				Description: "Gzip a request body",
+				Config: map[string]interface{}{
+					"value": "{{.body}}",
+				},
+			},
+			{
+				Name:        "Decompress a file",
+				Description: "Read a zstd-compressed file",
+				Config: map[string]interface{}{
+					"operation": "decompress",
+					"algorithm": "zstd",
+					"file":      "data/events.json.zst",
+				},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// compressConfig is the config of a compress node.
+type compressConfig struct {
+	Operation string `yaml:"operation" validate:"oneof=compress decompress"`
+	Algorithm string `yaml:"algorithm" validate:"oneof=gzip zstd"`
+	Value     string `yaml:"value"`
+	File      string `yaml:"file"`
+}
+
+// Build creates a compress node from a definition.
+func (b *CompressNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	cfg := compressConfig{Operation: "compress", Algorithm: "gzip"}
+	if err := DecodeConfig(def.Config, &cfg); err != nil {
+		return nil, err
+	}
+	operation, algorithm := cfg.Operation, cfg.Algorithm
+
+	source, err := newDataSource(def)
+	if err != nil {
+		return nil, err
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			data, err := source.read(input)
+			if err != nil {
+				return nil, err
+			}
+
+			if operation == "compress" {
+				compressed, err := compressData(algorithm, data)
+				if err != nil {
+					return nil, fmt.Errorf("%s compress: %w", algorithm, err)
+				}
+				if b.Verbose {
+					log.Printf("[%s] Compressed %d bytes to %d with %s", def.Name, len(data), len(compressed), algorithm)
+				}
+				// Compressed bytes travel through workflows as base64 text
+				return base64.StdEncoding.EncodeToString(compressed), nil
+			}
+
+			// Accept base64 text from a compress node as well as raw bytes
+			if source.file == "" {
+				if decoded, err := base64.StdEncoding.DecodeString(string(data)); err == nil {
+					data = decoded
+				}
+			}
+
+			decompressed, err := decompressData(algorithm, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s decompress: %w", algorithm, err)
+			}
+			if b.Verbose {
+				log.Printf("[%s] Decompressed %d bytes to %d with %s", def.Name, len(data), len(decompressed), algorithm)
+			}
+			return string(decompressed), nil
+		},
+	}), nil
+}
+
+func compressData(algorithm string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch algorithm {
+	case "zstd":
+		enc, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		w = enc
+	default:
+		w = gzip.NewWriter(&buf)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompressData(algorithm string, data []byte) ([]byte, error) {
+	var r io.Reader
+	switch algorithm {
+	case "zstd":
+		dec, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		r = dec
+	default:
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	// Bound the output so a small payload can't expand without limit
+	out, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxDecompressedSize {
+		return nil, fmt.Errorf("decompressed data exceeds %d bytes", maxDecompressedSize)
+	}
+	return out, nil
+}
+
+// maxDecompressedSize caps decompressed output at 100MB.
+const maxDecompressedSize = 100 * 1024 * 1024
+
+// EncodeNodeBuilder builds encoding nodes.
+type EncodeNodeBuilder struct {
+	Verbose bool
+}
+
+// Metadata returns the node metadata.
+func (b *EncodeNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "encode",
+		Category:    "data",
+		Description: "Encodes and decodes data as base64, hex, or URL escapes",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"operation": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"encode", "decode"},
+					"default":     "encode",
+					"description": "Whether to encode or decode",
+				},
+				"encoding": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"base64", "base64url", "hex", "url"},
+					"default":     "base64",
+					"description": "Encoding to use",
+				},
+				"value": map[string]interface{}{
+					"type":        "string",
+					"description": "Go template selecting the data (default: the input)",
+				},
+				"file": map[string]interface{}{
+					"type":        "string",
+					"description": "Read the data from this file instead of the input",
+				},
+			},
+		},
+		OutputSchema: map[string]interface{}{
+			"type":        "string",
+			"description": "The encoded or decoded data",
+		},
+		Examples: []Example{
+			{
+				Name:        "Basic auth header",
+				Description: "Base64-encode credentials",
+				Config: map[string]interface{}{
+					"value": "{{.user}}:{{.password}}",
+				},
+				Input:  map[string]interface{}{"user": "alice", "password": "secret"},
+				Output: "YWxpY2U6c2VjcmV0",
+			},
+			{
+				Name:        "Query parameter",
+				Description: "URL-escape a search term",
+				Config: map[string]interface{}{
+					"encoding": "url",
+					"value":    "{{.query}}",
+				},
+				Input:  map[string]interface{}{"query": "a&b c"},
+				Output: "a%26b+c",
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// Build creates an encode node from a definition.
+func (b *EncodeNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	operation, _ := def.Config["operation"].(string)
+	if operation == "" {
+		operation = "encode"
+	}
+	if operation != "encode" && operation != "decode" {
+		return nil, fmt.Errorf("unknown operation %q", operation)
+	}
+
+	encoding, _ := def.Config["encoding"].(string)
+	if encoding == "" {
+		encoding = "base64"
+	}
+
+	var encode func([]byte) string
+	var decode func(string) ([]byte, error)
+	switch encoding {
+	case "base64":
+		encode, decode = base64.StdEncoding.EncodeToString, base64.StdEncoding.DecodeString
+	case "base64url":
+		encode, decode = base64.URLEncoding.EncodeToString, base64.URLEncoding.DecodeString
+	case "hex":
+		encode, decode = hex.EncodeToString, hex.DecodeString
+	case "url":
+		encode = func(data []byte) string { return url.QueryEscape(string(data)) }
+		decode = func(s string) ([]byte, error) {
+			unescaped, err := url.QueryUnescape(s)
+			return []byte(unescaped), err
+		}
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+
+	source, err := newDataSource(def)
+	if err != nil {
+		return nil, err
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			data, err := source.read(input)
+			if err != nil {
+				return nil, err
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] %s %d bytes as %s", def.Name, operation, len(data), encoding)
+			}
+
+			if operation == "encode" {
+				return encode(data), nil
+			}
+
+			decoded, err := decode(strings.TrimSpace(string(data)))
+			if err != nil {
+				return nil, fmt.Errorf("%s decode: %w", encoding, err)
+			}
+			return string(decoded), nil
+		},
+	}), nil
+}
+
+// dataSource reads the bytes a data node works on from a value template,
+// a file, or the input itself.
+type dataSource struct {
+	tmpl *template.Template
+	file string
+}
+
+func newDataSource(def *yaml.NodeDefinition) (*dataSource, error) {
+	tmpl, err := optionalTemplate(def.Name+".value", def.Config["value"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value template: %w", err)
+	}
+	file, _ := def.Config["file"].(string)
+	if tmpl != nil && file != "" {
+		return nil, fmt.Errorf("value and file are mutually exclusive")
+	}
+	return &dataSource{tmpl: tmpl, file: file}, nil
+}
+
+func (s *dataSource) read(input any) ([]byte, error) {
+	if s.file != "" {
+		data, err := os.ReadFile(s.file) // #nosec G304 - Data files are user-configured
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+		return data, nil
+	}
+	return inputBytes(s.tmpl, input)
+}
+
+// FileNodeBuilder builds file I/O nodes with sandboxing.
+type FileNodeBuilder struct {
+	Verbose bool
+}
+
+// Metadata returns the node metadata.
+func (b *FileNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "file",
+		Category:    "io",
+		Description: "Reads or writes files with path restrictions",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"operation": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"read", "write", "append", "exists", "list", "glob", "copy", "move", "delete", "mkdir", "checksum", "watch"},
+					"default":     "read",
+					"description": "File operation to perform",
+				},
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "File path (relative to working directory or absolute if allowed), or a pattern for glob",
+				},
+				"destination": map[string]interface{}{
+					"type":        "string",
+					"description": "Destination path (for copy/move operations)",
+				},
+				"overwrite": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Replace an existing destination (for copy/move operations)",
+				},
+				"recursive": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Delete directories with their contents (for delete operation)",
+				},
+				"algorithm": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"sha256", "sha512", "sha1", "md5"},
+					"default":     "sha256",
+					"description": "Digest algorithm (for checksum operation)",
+				},
+				"pattern": map[string]interface{}{
+					"type":        "string",
+					"default":     "*",
+					"description": "File name pattern to watch for (for watch operation)",
+				},
+				"events": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string", "enum": []string{"create", "write", "remove", "rename"}},
+					"description": "Events to watch for (default: create and write)",
+				},
+				"debounce": map[string]interface{}{
+					"type":        "string",
+					"default":     "500ms",
+					"description": "Quiet period before returning changed files (for watch operation)",
+				},
+				"timeout": map[string]interface{}{
+					"type":        "string",
+					"description": "Stop watching after this long without changes (for watch operation)",
+				},
+				"include_existing": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Return matching files already present (for watch operation)",
+				},
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "Content to write (for write/append operations)",
+				},
+				"encoding": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"utf-8", "base64", "auto"},
+					"default":     "utf-8",
+					"description": "Content encoding; base64 is binary-safe and auto uses it for non-UTF-8 data",
+				},
+				"reference": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Return file metadata and content type without the content (for read operation)",
+				},
+				"chunk_size": map[string]interface{}{
+					"type":        []string{"string", "integer"},
+					"description": "Read at most this many bytes, e.g. \"1MB\" (for read operation)",
+				},
+				"offset": map[string]interface{}{
+					"type":        []string{"integer", "string"},
+					"default":     0,
+					"description": "Byte offset to read a chunk from (supports templating)",
+				},
+				"base_dir": map[string]interface{}{
+					"type":        "string",
+					"description": "Base directory for sandboxing (defaults to current working directory)",
+				},
+				"allow_absolute": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Allow absolute paths outside base directory",
+				},
+				"create_dirs": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Create parent directories if they don't exist",
+				},
+			},
+			"required": []string{"operation", "path"},
+		},
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Resolved file path",
+				},
+				"exists": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Whether the file exists",
+				},
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "File content (for read operations)",
+				},
+				"encoding": map[string]interface{}{
+					"type":        "string",
+					"description": "Encoding of content, utf-8 or base64 (for read operations)",
+				},
+				"content_type": map[string]interface{}{
+					"type":        "string",
+					"description": "Detected media type (for read operations with reference)",
+				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"description": "Offset of the chunk read (for chunked reads)",
+				},
+				"next_offset": map[string]interface{}{
+					"type":        "integer",
+					"description": "Offset of the next chunk (for chunked reads)",
+				},
+				"eof": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Whether the chunk reaches the end of the file (for chunked reads)",
+				},
+				"size": map[string]interface{}{
+					"type":        "integer",
+					"description": "File size in bytes",
+				},
+				"modified": map[string]interface{}{
+					"type":        "string",
+					"format":      "date-time",
+					"description": "Last modification time",
+				},
+				"destination": map[string]interface{}{
+					"type":        "string",
+					"description": "Resolved destination path (for copy/move operations)",
+				},
+				"deleted": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Whether anything was deleted (for delete operation)",
+				},
+				"checksum": map[string]interface{}{
+					"type":        "string",
+					"description": "Hex digest (for checksum operation)",
+				},
+				"count": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of matches (for glob and watch operations)",
+				},
+				"timed_out": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Whether the watch timed out without changes (for watch operation)",
+				},
+				"files": map[string]interface{}{
+					"type":        "array",
+					"description": "List of files (for list and glob operations)",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"name":     map[string]interface{}{"type": "string"},
+							"path":     map[string]interface{}{"type": "string"},
+							"size":     map[string]interface{}{"type": "integer"},
+							"modified": map[string]interface{}{"type": "string", "format": "date-time"},
+							"isDir":    map[string]interface{}{"type": "boolean"},
+						},
+					},
+				},
+			},
+		},
+		Examples: []Example{
+			{
+				Name:        "Read image",
+				Description: "Read a binary file as base64",
+				Config: map[string]interface{}{
+					"operation": "read",
+					"path":      "assets/logo.png",
+					"encoding":  "base64",
+				},
+			},
+			{
+				Name:        "Find logs",
+				Description: "Match files in any subdirectory",
+				Config: map[string]interface{}{
+					"operation": "glob",
+					"path":      "logs/**/*.log",
+				},
+			},
+			{
+				Name:        "Archive artifact",
+				Description: "Move a build artifact into a release directory",
+				Config: map[string]interface{}{
+					"operation":   "move",
+					"path":        "build/app.tar.gz",
+					"destination": "releases/app.tar.gz",
+					"create_dirs": true,
+				},
+			},
+			{
+				Name:        "Read file",
+				Description: "Read contents of a text file",
+				Config: map[string]interface{}{
+					"operation": "read",
+					"path":      "config.json",
+				},
+				Output: map[string]interface{}{
+					"path":     "/app/config.json",
+					"exists":   true,
+					"content":  "{\"version\": \"1.0.0\"}",
+					"size":     20,
+					"modified": "2024-01-15T10:30:00Z",
+				},
+			},
+			{
+				Name:        "Write file",
+				Description: "Write content to a file",
+				Config: map[string]interface{}{
+					"operation":   "write",
+					"path":        "output/result.txt",
+					"content":     "Processing complete",
+					"create_dirs": true,
+				},
+				Output: map[string]interface{}{
+					"path":     "/app/output/result.txt",
+					"exists":   true,
+					"size":     19,
+					"modified": "2024-01-15T10:35:00Z",
+				},
+			},
+			{
+				Name:        "List directory",
+				Description: "List files in a directory",
+				Config: map[string]interface{}{
+					"operation": "list",
+					"path":      "data/",
+				},
+				Output: map[string]interface{}{
+					"path":   "/app/data",
+					"exists": true,
+					"files": []interface{}{
+						map[string]interface{}{
+							"name":     "file1.txt",
+							"path":     "/app/data/file1.txt",
+							"size":     1024,
+							"modified": "2024-01-14T09:00:00Z",
+							"isDir":    false,
+						},
+					},
+				},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// Build creates a file node from a definition.
+//
+//nolint:gocyclo // Complex due to multiple operations and security validations
+func (b *FileNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	operation, _ := def.Config["operation"].(string)
+	if operation == "" {
+		operation = "read"
+	}
+
+	pathStr, ok := def.Config["path"].(string)
+	if !ok || pathStr == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+
+	content, _ := def.Config["content"].(string)
+
+	encoding, _ := def.Config["encoding"].(string)
+	switch encoding {
+	case "":
+		encoding = "utf-8"
+	case "utf-8", "base64", "auto":
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+	reference, _ := def.Config["reference"].(bool)
+
+	var chunkSize int64
+	if raw, ok := def.Config["chunk_size"]; ok {
+		var err error
+		if chunkSize, err = parseByteSize(raw); err != nil {
+			return nil, fmt.Errorf("invalid chunk_size: %w", err)
+		}
+	}
+	offset, _ := toFloat(def.Config["offset"])
+	offsetTmpl, err := optionalTemplate("offset", def.Config["offset"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid offset: %w", err)
+	}
+
+	baseDir, _ := def.Config["base_dir"].(string)
+	if baseDir == "" {
+		var err error
+		baseDir, err = os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+	}
+
+	allowAbsolute, _ := def.Config["allow_absolute"].(bool)
+	createDirs, _ := def.Config["create_dirs"].(bool)
+
+	destination, _ := def.Config["destination"].(string)
+	if (operation == "copy" || operation == "move") && destination == "" {
+		return nil, fmt.Errorf("destination is required for %s", operation)
+	}
+	overwrite, _ := def.Config["overwrite"].(bool)
+	recursive, _ := def.Config["recursive"].(bool)
+
+	algorithm, _ := def.Config["algorithm"].(string)
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	if _, err := hashFunc(algorithm); err != nil {
+		return nil, err
+	}
+
+	var watch *fileWatch
+	if operation == "watch" {
+		var err error
+		if watch, err = newFileWatch(def.Config); err != nil {
+			return nil, err
+		}
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			// Resolve path with sandboxing
+			resolvedPath, err := resolvePath(pathStr, baseDir, allowAbsolute)
+			if err != nil {
+				return nil, fmt.Errorf("path resolution failed: %w", err)
+			}
+
+			var resolvedDest string
+			if destination != "" {
+				if resolvedDest, err = resolvePath(destination, baseDir, allowAbsolute); err != nil {
+					return nil, fmt.Errorf("destination resolution failed: %w", err)
+				}
+				if createDirs {
+					if err := os.MkdirAll(filepath.Dir(resolvedDest), 0o755); err != nil { //nolint:gosec // Standard directory permissions
+						return nil, fmt.Errorf("failed to create directories: %w", err)
+					}
+				}
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] File operation '%s' on path: %s", def.Name, operation, resolvedPath)
+			}
+
+			switch operation {
+			case "read":
+				info, err := os.Stat(resolvedPath)
+				if err != nil {
+					if os.IsNotExist(err) {
+						return map[string]interface{}{
+							"path":   resolvedPath,
+							"exists": false,
+						}, nil
+					}
+					return nil, fmt.Errorf("read failed: %w", err)
+				}
+
+				result := map[string]interface{}{
+					"path":     resolvedPath,
+					"exists":   true,
+					"size":     info.Size(),
+					"modified": info.ModTime().Format(time.RFC3339),
+				}
+
+				// Let later nodes open the file themselves
+				if reference {
+					result["content_type"] = detectContentType(resolvedPath)
+					return result, nil
+				}
+
+				var data []byte
+				if chunkSize > 0 {
+					start := int64(offset)
+					if offsetTmpl != nil {
+						var buf bytes.Buffer
+						if err := offsetTmpl.Execute(&buf, input); err != nil {
+							return nil, fmt.Errorf("offset template failed: %w", err)
+						}
+						if start, err = strconv.ParseInt(strings.TrimSpace(buf.String()), 10, 64); err != nil {
+							return nil, fmt.Errorf("invalid offset: %w", err)
+						}
+					}
+
+					if data, err = readChunk(resolvedPath, start, chunkSize); err != nil {
+						return nil, fmt.Errorf("read failed: %w", err)
+					}
+					next := start + int64(len(data))
+					result["offset"] = start
+					result["next_offset"] = next
+					result["eof"] = next >= info.Size()
+				} else if data, err = os.ReadFile(resolvedPath); err != nil { // #nosec G304 - Path is validated and sandboxed
+					return nil, fmt.Errorf("read failed: %w", err)
+				}
+
+				result["content"], result["encoding"] = encodeContent(data, encoding)
+				return result, nil
+
+			case "write":
+				// Create directories if needed
+				if createDirs {
+					dir := filepath.Dir(resolvedPath)
+					if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec // Standard directory permissions
+						return nil, fmt.Errorf("failed to create directories: %w", err)
+					}
+				}
+
+				// Support template content with input data
+				finalContent := content
+				if strings.Contains(content, "{{") {
+					tmpl, err := newTemplate("content").Parse(content)
+					if err == nil {
+						var buf bytes.Buffer
+						if err := tmpl.Execute(&buf, input); err == nil {
+							finalContent = buf.String()
+						}
+					}
+				}
+
+				data, err := decodeContent(finalContent, encoding)
+				if err != nil {
+					return nil, err
+				}
+
+				if err := os.WriteFile(resolvedPath, data, 0o644); err != nil { //nolint:gosec // Standard file permissions
+					return nil, fmt.Errorf("write failed: %w", err)
+				}
+
+				info, _ := os.Stat(resolvedPath)
+				return map[string]interface{}{
+					"path":     resolvedPath,
+					"exists":   true,
+					"size":     info.Size(),
+					"modified": info.ModTime().Format(time.RFC3339),
+				}, nil
+
+			case "append":
+				// Create directories if needed
+				if createDirs {
+					dir := filepath.Dir(resolvedPath)
+					if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec // Standard directory permissions
+						return nil, fmt.Errorf("failed to create directories: %w", err)
+					}
+				}
+
+				// Support template content
+				finalContent := content
+				if strings.Contains(content, "{{") {
+					tmpl, err := newTemplate("content").Parse(content)
+					if err == nil {
+						var buf bytes.Buffer
+						if err := tmpl.Execute(&buf, input); err == nil {
+							finalContent = buf.String()
+						}
+					}
+				}
+
+				data, err := decodeContent(finalContent, encoding)
+				if err != nil {
+					return nil, err
+				}
+
+				file, err := os.OpenFile(resolvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644) // #nosec G302,G304 - Path is validated and sandboxed
+				if err != nil {
+					return nil, fmt.Errorf("append failed: %w", err)
+				}
+				defer func() {
+					// Check for errors during file close
+					if cerr := file.Close(); err == nil && cerr != nil {
+						err = fmt.Errorf("failed to close file: %w", cerr)
+					}
+				}()
+
+				if _, err := file.Write(data); err != nil {
+					return nil, fmt.Errorf("append write failed: %w", err)
+				}
+
+				info, _ := os.Stat(resolvedPath)
+				return map[string]interface{}{
+					"path":     resolvedPath,
+					"exists":   true,
+					"size":     info.Size(),
+					"modified": info.ModTime().Format(time.RFC3339),
+				}, nil
+
+			case "exists":
+				info, err := os.Stat(resolvedPath)
+				if err != nil {
+					if os.IsNotExist(err) {
+						return map[string]interface{}{
+							"path":   resolvedPath,
+							"exists": false,
+						}, nil
+					}
+					return nil, fmt.Errorf("stat failed: %w", err)
+				}
+
+				return map[string]interface{}{
+					"path":     resolvedPath,
+					"exists":   true,
+					"size":     info.Size(),
+					"modified": info.ModTime().Format(time.RFC3339),
+					"isDir":    info.IsDir(),
+				}, nil
+
+			case "list":
+				entries, err := os.ReadDir(resolvedPath)
+				if err != nil {
+					if os.IsNotExist(err) {
+						return map[string]interface{}{
+							"path":   resolvedPath,
+							"exists": false,
+						}, nil
+					}
+					return nil, fmt.Errorf("list failed: %w", err)
+				}
+
+				files := []interface{}{}
+				for _, entry := range entries {
+					info, err := entry.Info()
+					if err != nil {
+						continue
+					}
+
+					files = append(files, map[string]interface{}{
+						"name":     entry.Name(),
+						"path":     filepath.Join(resolvedPath, entry.Name()),
+						"size":     info.Size(),
+						"modified": info.ModTime().Format(time.RFC3339),
+						"isDir":    entry.IsDir(),
+					})
+				}
+
+				return map[string]interface{}{
+					"path":   resolvedPath,
+					"exists": true,
+					"files":  files,
+				}, nil
+
+			case "glob":
+				files, err := globFiles(resolvedPath)
+				if err != nil {
+					return nil, fmt.Errorf("glob failed: %w", err)
+				}
+				return map[string]interface{}{
+					"path":  resolvedPath,
+					"files": files,
+					"count": len(files),
+				}, nil
+
+			case "copy", "move":
+				if err := checkDestination(resolvedDest, overwrite); err != nil {
+					return nil, fmt.Errorf("%s failed: %w", operation, err)
+				}
+
+				if operation == "copy" {
+					_, err = copyPath(resolvedPath, resolvedDest)
+				} else {
+					err = movePath(resolvedPath, resolvedDest)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("%s failed: %w", operation, err)
+				}
+
+				info, err := os.Stat(resolvedDest)
+				if err != nil {
+					return nil, fmt.Errorf("%s failed: %w", operation, err)
+				}
+				return map[string]interface{}{
+					"path":        resolvedPath,
+					"destination": resolvedDest,
+					"exists":      true,
+					"size":        info.Size(),
+					"isDir":       info.IsDir(),
+				}, nil
+
+			case "delete":
+				if _, err := os.Lstat(resolvedPath); os.IsNotExist(err) {
+					return map[string]interface{}{
+						"path":    resolvedPath,
+						"exists":  false,
+						"deleted": false,
+					}, nil
+				}
+
+				if recursive {
+					err = os.RemoveAll(resolvedPath)
+				} else {
+					err = os.Remove(resolvedPath)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("delete failed: %w", err)
+				}
+				return map[string]interface{}{
+					"path":    resolvedPath,
+					"exists":  false,
+					"deleted": true,
+				}, nil
+
+			case "mkdir":
+				if err := os.MkdirAll(resolvedPath, 0o755); err != nil { //nolint:gosec // Standard directory permissions
+					return nil, fmt.Errorf("mkdir failed: %w", err)
+				}
+				return map[string]interface{}{
+					"path":   resolvedPath,
+					"exists": true,
+					"isDir":  true,
+				}, nil
+
+			case "watch":
+				if b.Verbose {
+					log.Printf("[%s] Watching %s for %s", def.Name, resolvedPath, watch.pattern)
+				}
+				result, err := watch.wait(ctx, resolvedPath)
+				if err != nil {
+					return nil, fmt.Errorf("watch failed: %w", err)
+				}
+				return result, nil
+
+			case "checksum":
+				sum, size, err := checksumFile(resolvedPath, algorithm)
+				if err != nil {
+					return nil, fmt.Errorf("checksum failed: %w", err)
+				}
+				return map[string]interface{}{
+					"path":      resolvedPath,
+					"exists":    true,
+					"size":      size,
+					"algorithm": algorithm,
+					"checksum":  sum,
+				}, nil
+
+			default:
+				return nil, fmt.Errorf("unknown operation: %s", operation)
+			}
+		},
+	}), nil
+}
+
+// resolvePath resolves a file path with sandboxing.
+func resolvePath(path, baseDir string, allowAbsolute bool) (string, error) {
+	// Clean the path
+	cleanPath := filepath.Clean(path)
+
+	// If absolute paths are allowed and path is absolute, return it
+	if allowAbsolute && filepath.IsAbs(cleanPath) {
+		return cleanPath, nil
+	}
+
+	// Otherwise, resolve relative to base directory
+	resolvedPath := filepath.Join(baseDir, cleanPath)
+	absPath, err := filepath.Abs(resolvedPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Ensure the resolved path is within the base directory
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", err
+	}
+
+	// Check if path is within base directory
+	relPath, err := filepath.Rel(absBase, absPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Reject paths that go outside base directory
+	if strings.HasPrefix(relPath, "..") {
+		return "", fmt.Errorf("path '%s' is outside base directory", path)
+	}
+
+	return absPath, nil
+}
+
+// ExecNodeBuilder builds command execution nodes.
+type ExecNodeBuilder struct {
+	Verbose bool
+}
+
+func (b *ExecNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "exec",
+		Category:    "io",
+		Description: "Executes shell commands with restrictions",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"command": map[string]interface{}{
+					"type":        "string",
+					"description": "Command to execute",
+				},
+				"args": map[string]interface{}{
+					"type":        "array",
+					"description": "Command arguments",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"timeout": map[string]interface{}{
+					"type":        "string",
+					"description": "Execution timeout",
+					"default":     "30s",
+				},
+				"allowed_commands": map[string]interface{}{
+					"type":        "array",
+					"description": "List of allowed commands (whitelist)",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"env": map[string]interface{}{
+					"type":        "object",
+					"description": "Environment variables to set",
+					"additionalProperties": map[string]interface{}{
+						"type": "string",
+					},
+				},
+				"working_dir": map[string]interface{}{
+					"type":        "string",
+					"description": "Working directory for command",
+				},
+				"capture_output": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Whether to capture command output",
+					"default":     true,
+				},
+				"stdin": map[string]interface{}{
+					"type":        []string{"boolean", "string"},
+					"description": "Pipe the node input to stdin (true) or a template rendered with the input",
+				},
+				"stream": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Emit stdout lines to the context's line handler as they are written",
+					"default":     false,
+				},
+				"limits": map[string]interface{}{
+					"type":        "object",
+					"description": "Per-process resource limits (Unix), applied with /bin/sh, which a chroot must contain",
+					"properties": map[string]interface{}{
+						"cpu_seconds": map[string]interface{}{"type": "integer", "description": "CPU time limit"},
+						"memory": map[string]interface{}{
+							"type":        []string{"integer", "string"},
+							"description": "Address space limit in bytes or a size such as 512MB",
+						},
+						"open_files": map[string]interface{}{"type": "integer", "description": "Open file descriptor limit"},
+					},
+				},
+				"sandbox": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"chroot": map[string]interface{}{
+							"type":        "string",
+							"description": "Root directory for the command (Unix, requires root)",
+						},
+						"temp_dir": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Run in a fresh temporary directory that is removed afterwards",
+							"default":     false,
+						},
+					},
+				},
+				"inherit_env": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Inherit the parent environment",
+					"default":     true,
+				},
+				"env_deny": map[string]interface{}{
+					"type":        "array",
+					"description": "Patterns of inherited variables to drop, e.g. AWS_* or *_TOKEN",
+					"items":       map[string]interface{}{"type": "string"},
+				},
+			},
+			"required": []string{"command"},
+		},
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"stdout": map[string]interface{}{
+					"type": "string",
+				},
+				"stderr": map[string]interface{}{
+					"type": "string",
+				},
+				"exit_code": map[string]interface{}{
+					"type": "integer",
+				},
+				"duration": map[string]interface{}{
+					"type": "string",
+				},
+			},
+		},
+		Examples: []Example{
+			{
+				Name:        "List files",
+				Description: "List files in current directory",
+				Config: map[string]interface{}{
+					"command": "ls",
+					"args":    []interface{}{"-la"},
+				},
+			},
+			{
+				Name:        "Run with timeout",
+				Description: "Execute command with timeout",
+				Config: map[string]interface{}{
+					"command": "sleep",
+					"args":    []interface{}{"5"},
+					"timeout": "2s",
+				},
+			},
+			{
+				Name:        "Restricted commands",
+				Description: "Only allow specific commands",
+				Config: map[string]interface{}{
+					"command":          "echo",
+					"args":             []interface{}{"Hello, World!"},
+					"allowed_commands": []string{"echo", "ls", "cat"},
+				},
+			},
+			{
+				Name:        "Pipe input",
+				Description: "Send a rendered prompt to a CLI tool and stream its output",
+				Config: map[string]interface{}{
+					"command": "llm",
+					"stdin":   "Summarize: {{.text}}",
+					"stream":  true,
+				},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// Build creates an exec node from a definition.
+//
+//nolint:gocyclo // Complex due to security validations, restrictions, and error handling
+func (b *ExecNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	// Get command configuration
+	command, ok := def.Config["command"].(string)
+	if !ok || command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+
+	// Get arguments
+	var args []string
+	if argsRaw, ok := def.Config["args"].([]interface{}); ok {
+		for i, arg := range argsRaw {
+			argStr, ok := arg.(string)
+			if !ok {
+				return nil, fmt.Errorf("argument %d must be a string", i)
+			}
+			args = append(args, argStr)
+		}
+	}
+
+	// Get timeout
+	timeoutStr, _ := def.Config["timeout"].(string)
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil || timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	// Get allowed commands whitelist
+	var allowedCommands []string
+	if allowed, ok := def.Config["allowed_commands"].([]interface{}); ok {
+		for _, cmd := range allowed {
+			if cmdStr, ok := cmd.(string); ok {
+				allowedCommands = append(allowedCommands, cmdStr)
+			}
+		}
+	}
+
+	// Get environment variables
+	env := make(map[string]string)
+	if envMap, ok := def.Config["env"].(map[string]interface{}); ok {
+		for k, v := range envMap {
+			if vStr, ok := v.(string); ok {
+				env[k] = vStr
+			}
+		}
+	}
+
+	// Get working directory
+	workingDir, _ := def.Config["working_dir"].(string)
+
+	// Get capture output flag
+	captureOutput := true
+	if capture, ok := def.Config["capture_output"].(bool); ok {
+		captureOutput = capture
+	}
+
+	// Get stdin: true pipes the raw input, a string is rendered as a template
+	pipeInput, _ := def.Config["stdin"].(bool)
+	stdinTmpl, err := optionalTemplate("stdin", def.Config["stdin"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid stdin template: %w", err)
+	}
+
+	stream, _ := def.Config["stream"].(bool)
+
+	var limits *execLimits
+	if raw, ok := def.Config["limits"].(map[string]interface{}); ok {
+		if limits, err = newExecLimits(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	// Get sandbox settings
+	var chroot string
+	var tempDir bool
+	if sandbox, ok := def.Config["sandbox"].(map[string]interface{}); ok {
+		chroot, _ = sandbox["chroot"].(string)
+		tempDir, _ = sandbox["temp_dir"].(bool)
+		if tempDir && workingDir != "" {
+			return nil, fmt.Errorf("sandbox.temp_dir and working_dir cannot be used together")
+		}
+		if tempDir && chroot != "" {
+			return nil, fmt.Errorf("sandbox.temp_dir and sandbox.chroot cannot be used together")
+		}
+	}
+	if limits != nil && chroot != "" {
+		if err := limits.checkChroot(chroot); err != nil {
+			return nil, err
+		}
+	}
+
+	inheritEnv := true
+	if inherit, ok := def.Config["inherit_env"].(bool); ok {
+		inheritEnv = inherit
+	}
+	var envDeny []string
+	if raw, ok := def.Config["env_deny"]; ok {
+		if envDeny, err = stringList(raw); err != nil {
+			return nil, fmt.Errorf("env_deny: %w", err)
+		}
+	}
+
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+
+			// Check if command is in whitelist
+			if len(allowedCommands) > 0 {
+				allowed := false
+				for _, allowedCmd := range allowedCommands {
+					if command == allowedCmd {
+						allowed = true
+						break
+					}
+				}
+				if !allowed {
+					return nil, fmt.Errorf("command '%s' is not in allowed list", command)
+				}
+			}
+
+			// Create command with timeout context
+			execCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			// Create command, applying resource limits before it starts
+			name, cmdArgs := command, args
+			if limits != nil {
+				name, cmdArgs = limits.wrap(command, args)
+			}
+			cmd := exec.CommandContext(execCtx, name, cmdArgs...) // #nosec G204 - Command is user-configured with restrictions
+
+			// Set working directory if specified
+			if workingDir != "" {
+				cmd.Dir = workingDir
+			}
+			if tempDir {
+				dir, err := os.MkdirTemp("", "pocket-exec-*")
+				if err != nil {
+					return nil, fmt.Errorf("create temp dir: %w", err)
+				}
+				defer func() { _ = os.RemoveAll(dir) }()
+				cmd.Dir = dir
+			}
+			if chroot != "" {
+				if err := setChroot(cmd, chroot); err != nil {
+					return nil, err
+				}
+			}
+
+			// Set environment variables
+			if len(env) > 0 || !inheritEnv || len(envDeny) > 0 {
+				cmd.Env = commandEnv(inheritEnv, envDeny, env)
+			}
+
+			if pipeInput || stdinTmpl != nil {
+				data, err := inputBytes(stdinTmpl, input)
+				if err != nil {
+					return nil, fmt.Errorf("stdin: %w", err)
+				}
+				cmd.Stdin = bytes.NewReader(data)
+			}
+
+			// Setup output capture
+			var stdout, stderr bytes.Buffer
+			if captureOutput {
+				cmd.Stdout = &stdout
+				cmd.Stderr = &stderr
+			}
+
+			// Record start time
+			startTime := time.Now()
+
+			// Run command
+			var err error
+			if stream {
+				cmd.Stdout = nil
+				handler := lineHandlerFrom(ctx)
+				err = runStreaming(cmd, func(line string) {
+					if captureOutput {
+						stdout.WriteString(line)
+						stdout.WriteByte('\n')
+					}
+					if handler != nil {
+						handler(def.Name, line)
+					}
+				})
+			} else {
+				err = cmd.Run()
+			}
+			duration := time.Since(startTime)
+
+			// Get exit code
+			exitCode := 0
+			if err != nil {
+				if exitErr, ok := err.(*exec.ExitError); ok {
+					exitCode = exitErr.ExitCode()
+					// Check if it was killed due to timeout
+					if execCtx.Err() == context.DeadlineExceeded {
+						return nil, fmt.Errorf("command timed out after %v", timeout)
+					}
+				} else if execCtx.Err() == context.DeadlineExceeded {
+					return nil, fmt.Errorf("command timed out after %v", timeout)
+				} else {
+					return nil, fmt.Errorf("command failed: %w", err)
+				}
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] Command executed: %s %v (exit: %d, duration: %v)",
+					def.Name, command, args, exitCode, duration)
+			}
+
+			result := map[string]interface{}{
+				"command":   command,
+				"args":      args,
+				"exit_code": exitCode,
+				"duration":  duration.String(),
+			}
+
+			if captureOutput {
+				result["stdout"] = stdout.String()
+				result["stderr"] = stderr.String()
+			}
+
+			return result, nil
+		},
+	}), nil
+}
+
+// parallelTaskSchema is the config schema of a parallel node's task.
+var parallelTaskSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"name": map[string]interface{}{
+			"type":        "string",
+			"description": "Task name",
+		},
+		"node": map[string]interface{}{
+			"type":        "string",
+			"description": "Name of a node defined in the workflow",
+		},
+		"type": map[string]interface{}{
+			"type":        "string",
+			"description": "Node type, for an inline node",
+		},
+		"config": map[string]interface{}{
+			"type":        "object",
+			"description": "Node configuration, for an inline node",
+		},
+		"input": map[string]interface{}{
+			"description": "Input for the task; strings support templating (default: the parallel node's input)",
+		},
+	},
+	"required": []string{"name"},
+}
+
+// ParallelNodeBuilder builds parallel execution nodes.
+type ParallelNodeBuilder struct {
+	Verbose bool
+
+	// Registry builds the task nodes. RegisterAll sets it.
+	Registry *Registry
+}
+
+func (b *ParallelNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "parallel",
+		Category:    "flow",
+		Description: "Runs multiple nodes concurrently",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"tasks": map[string]interface{}{
+					"type":        "array",
+					"description": "Nodes to run in parallel",
+					"items":       parallelTaskSchema,
+				},
+				"nodes": map[string]interface{}{
+					"type":        "array",
+					"description": "Deprecated: the former name of tasks",
+					"items":       parallelTaskSchema,
+				},
+				"max_concurrency": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of concurrent tasks",
+					"minimum":     1,
+					"default":     10,
+				},
+				"fail_fast": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Stop all tasks if one fails",
+					"default":     false,
+				},
+				"timeout": map[string]interface{}{
+					"type":        "string",
+					"description": "Overall timeout for all tasks",
+					"default":     "5m",
+				},
+			},
+		},
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"results": map[string]interface{}{
+					"type":        "array",
+					"description": "Results from all tasks",
+				},
+				"errors": map[string]interface{}{
+					"type":        "array",
+					"description": "Errors from failed tasks",
+				},
+				"duration": map[string]interface{}{
+					"type":        "string",
+					"description": "Total execution time",
+				},
+			},
+		},
+		Examples: []Example{
+			{
+				Name:        "Parallel API calls",
+				Description: "Fetch data from multiple APIs concurrently",
+				Config: map[string]interface{}{
+					"tasks": []interface{}{
+						map[string]interface{}{
+							"name": "fetch_users",
+							"type": "http",
+							"config": map[string]interface{}{
+								"url": "https://api.example.com/users",
+							},
+						},
+						map[string]interface{}{
+							"name": "fetch_posts",
+							"type": "http",
+							"config": map[string]interface{}{
+								"url": "https://api.example.com/posts",
+							},
+						},
+					},
+					"max_concurrency": 5,
+				},
+			},
+			{
+				Name:        "Parallel processing with fail-fast",
+				Description: "Run nodes defined elsewhere in the workflow, stopping on the first failure",
+				Config: map[string]interface{}{
+					"tasks": []interface{}{
+						map[string]interface{}{
+							"name": "resize",
+							"node": "resize-image",
+						},
+						map[string]interface{}{
+							"name": "thumbnail",
+							"node": "make-thumbnail",
+						},
+					},
+					"fail_fast": true,
+					"timeout":   "30s",
+				},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
+
+// parallelTask is a node run by a parallel node.
+type parallelTask struct {
+	name  string
+	node  pocket.Node
+	input interface{} // nil to pass the parallel node's input
+}
+
+// parallelTasks builds the nodes run by a parallel node. A task either
+// references a node defined in the workflow by name or defines one inline.
+func (b *ParallelNodeBuilder) parallelTasks(def *yaml.NodeDefinition) ([]parallelTask, error) {
+	raw, ok := def.Config["tasks"]
+	if !ok {
+		// Workflows written before tasks could reference nodes use "nodes"
+		raw = def.Config["nodes"]
+	}
+	tasksRaw, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("tasks must be an array")
+	}
+
+	tasks := make([]parallelTask, 0, len(tasksRaw))
+	for i, taskRaw := range tasksRaw {
+		taskMap, ok := taskRaw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("task %d must be an object", i)
+		}
+
+		name, ok := taskMap["name"].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("task %d missing name", i)
+		}
+
+		var nodeDef *yaml.NodeDefinition
+		if ref, ok := taskMap["node"].(string); ok && ref != "" {
+			var err error
+			if nodeDef, err = referencedDefinition(def, ref); err != nil {
+				return nil, fmt.Errorf("task %s: %w", name, err)
+			}
+		} else {
+			if _, ok := taskMap["type"].(string); !ok {
+				return nil, fmt.Errorf("task %s needs a node reference or an inline type", name)
+			}
+			var err error
+			if nodeDef, err = nestedDefinition(def.Name, "task", taskMap); err != nil {
+				return nil, err
+			}
+			nodeDef.Name = def.Name + "." + name
+		}
+
+		node, err := b.Registry.Build(nodeDef)
+		if err != nil {
+			return nil, fmt.Errorf("build task %s: %w", name, err)
+		}
+
+		task := parallelTask{name: name, node: node, input: taskMap["input"]}
+		if s, ok := task.input.(string); ok {
+			if task.input, err = newTemplate(name).Parse(s); err != nil {
+				return nil, fmt.Errorf("task %s: invalid input template: %w", name, err)
+			}
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+// taskInput returns the input for a task.
+func (t parallelTask) taskInput(input any) (any, error) {
+	switch v := t.input.(type) {
+	case nil:
+		return input, nil
+	case *template.Template:
+		var buf bytes.Buffer
+		if err := v.Execute(&buf, input); err != nil {
+			return nil, fmt.Errorf("input template failed: %w", err)
+		}
+		return buf.String(), nil
+	default:
+		return v, nil
+	}
+}
+
+// Build creates a parallel node from a definition.
+//
+//nolint:gocyclo // Complex due to concurrent execution handling and error aggregation
+func (b *ParallelNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	if b.Registry == nil {
+		return nil, fmt.Errorf("parallel node requires a registry to build task nodes")
+	}
+
+	tasks, err := b.parallelTasks(def)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get other configuration
+	maxConcurrency := 10
+	if mc, ok := toFloat(def.Config["max_concurrency"]); ok && mc >= 1 {
+		maxConcurrency = int(mc)
+	}
+
+	failFast := false
+	if ff, ok := def.Config["fail_fast"].(bool); ok {
+		failFast = ff
+	}
+
+	timeoutStr, _ := def.Config["timeout"].(string)
+	timeout, err := time.ParseDuration(timeoutStr)
+	if err != nil || timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+
+	// Tasks run in Post so they share the graph's store
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+			// Create timeout context
+			execCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			// Start time tracking
+			startTime := time.Now()
+
+			// Create semaphore for concurrency control
+			sem := make(chan struct{}, maxConcurrency)
+
+			// Results collection
+			type taskResult struct {
+				Name   string
+				Result interface{}
+				Error  error
+			}
+
+			resultsChan := make(chan taskResult, len(tasks))
+
+			// Error channel for fail-fast
+			var errChan chan error
+			if failFast {
+				errChan = make(chan error, 1)
+			}
+
+			// Launch tasks
+			var wg sync.WaitGroup
+			for _, task := range tasks {
+				wg.Add(1)
+
+				task := task // Capture loop variable
+				go func() {
+					defer wg.Done()
+
+					// Acquire semaphore
+					select {
+					case sem <- struct{}{}:
+						defer func() { <-sem }()
+					case <-execCtx.Done():
+						resultsChan <- taskResult{
+							Name:  task.name,
+							Error: fmt.Errorf("timeout waiting for concurrency slot"),
+						}
+						return
+					}
+
+					// Check for early termination
+					if failFast {
+						select {
+						case <-execCtx.Done():
+							return
+						default:
+						}
+					}
+
+					// Run the task's node through its full lifecycle
+					taskInput, err := task.taskInput(input)
+					var result interface{}
+					if err == nil {
+						result, err = pocket.NewGraph(task.node, store).Run(execCtx, taskInput)
+					}
+
+					// Send result
+					resultsChan <- taskResult{
+						Name:   task.name,
+						Result: result,
+						Error:  err,
+					}
+
+					// Handle fail-fast
+					if failFast && err != nil {
+						select {
+						case errChan <- err:
+							cancel() // Cancel all other tasks
+						default:
+						}
+					}
+				}()
+			}
+
+			// Wait for all tasks to complete
+			go func() {
+				wg.Wait()
+				close(resultsChan)
+			}()
+
+			// Collect results
+			var results []interface{}
+			var errors []interface{}
+
+			for res := range resultsChan {
+				if res.Error != nil {
+					errors = append(errors, map[string]interface{}{
+						"task":  res.Name,
+						"error": res.Error.Error(),
+					})
+					if b.Verbose {
+						log.Printf("[%s] Task %s failed: %v", def.Name, res.Name, res.Error)
+					}
+				} else {
+					results = append(results, map[string]interface{}{
+						"task":   res.Name,
+						"result": res.Result,
+					})
+					if b.Verbose {
+						log.Printf("[%s] Task %s completed successfully", def.Name, res.Name)
+					}
+				}
+			}
+
+			duration := time.Since(startTime)
+
+			// Check if we should fail
+			if failFast && len(errors) > 0 {
+				return nil, "", fmt.Errorf("parallel execution failed (fail-fast): %d errors", len(errors))
+			}
+
+			if b.Verbose {
+				log.Printf("[%s] Parallel execution completed: %d successful, %d failed, duration: %v",
+					def.Name, len(results), len(errors), duration)
+			}
+
+			return map[string]interface{}{
+				"results":  results,
+				"errors":   errors,
+				"duration": duration.String(),
+				"summary": map[string]interface{}{
+					"total":      len(tasks),
+					"successful": len(results),
+					"failed":     len(errors),
+				},
+			}, "default", nil
+		},
+	}), nil
+}
+
+// TryNodeBuilder builds try/catch/finally nodes.
+type TryNodeBuilder struct {
+	Verbose bool
+
+	// Registry builds the wrapped nodes. RegisterAll sets it.
+	Registry *Registry
+}
+
+// Metadata returns the node metadata.
+func (b *TryNodeBuilder) Metadata() Metadata {
+	nodeSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Name of the wrapped node",
+			},
+			"type": map[string]interface{}{
+				"type":        "string",
+				"description": "Node type",
+			},
+			"config": map[string]interface{}{
+				"type":        "object",
+				"description": "Node configuration",
+			},
+		},
+		"required": []string{"type"},
+	}
+
+	return Metadata{
+		Type:        "try",
+		Category:    "flow",
+		Description: "Runs a wrapped node, routes its failure to a catch route, and always runs a finally route",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"node": map[string]interface{}{
+					"description": "Name of a node defined in the workflow to run, or an inline node definition",
+					"oneOf": []interface{}{
+						map[string]interface{}{"type": "string"},
+						nodeSchema,
+					},
+				},
+				"catch": map[string]interface{}{
+					"type":        "string",
+					"description": "Route taken when the wrapped node fails",
+					"default":     "catch",
+				},
+				"finally": map[string]interface{}{
+					"type":        "string",
+					"description": "Route whose node always runs after the wrapped node, whether it failed or not",
+					"default":     "finally",
+				},
+			},
+			"required": []string{"node"},
+		},
+		OutputSchema: map[string]interface{}{
+			"type":        "object",
+			"description": "The wrapped node's output, or on failure an object with the error and original input",
+			"properties": map[string]interface{}{
+				"error": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"message": map[string]interface{}{"type": "string"},
+						"node":    map[string]interface{}{"type": "string"},
+					},
+				},
+				"input": map[string]interface{}{
+					"description": "Input passed to the wrapped node",
+				},
+			},
+		},
+		Examples: []Example{
+			{
+				Name:        "Catch a failing request",
+				Description: "Route failures of the fetch node to an error handler and always log the attempt",
+				Config: map[string]interface{}{
+					"node":    "fetch",
+					"catch":   "handle_error",
+					"finally": "log_attempt",
 				},
 			},
 		},
@@ -1550,341 +5623,516 @@ func (b *FileNodeBuilder) Metadata() Metadata {
 	}
 }
 
-// Build creates a file node from a definition.
-//
-//nolint:gocyclo // Complex due to multiple operations (read/write/append/list) and security validations
-func (b *FileNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
-	operation, _ := def.Config["operation"].(string)
-	if operation == "" {
-		operation = "read"
+// mergeSchema returns a copy of schema with a description.
+func mergeSchema(schema map[string]interface{}, description string) map[string]interface{} {
+	merged := make(map[string]interface{}, len(schema)+1)
+	for k, v := range schema {
+		merged[k] = v
 	}
+	merged["description"] = description
+	return merged
+}
 
-	pathStr, ok := def.Config["path"].(string)
-	if !ok || pathStr == "" {
-		return nil, fmt.Errorf("path is required")
+// Build creates a try node from a definition.
+func (b *TryNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	if b.Registry == nil {
+		return nil, fmt.Errorf("try node requires a registry to build wrapped nodes")
 	}
 
-	content, _ := def.Config["content"].(string)
-	// Note: encoding config is reserved for future UTF-16/32 support
+	var (
+		bodyDef *yaml.NodeDefinition
+		err     error
+	)
+	if ref, ok := def.Config["node"].(string); ok {
+		bodyDef, err = referencedDefinition(def, ref)
+	} else {
+		bodyDef, err = nestedDefinition(def.Name, "node", def.Config["node"])
+	}
+	if err != nil {
+		return nil, err
+	}
+	body, err := b.Registry.Build(bodyDef)
+	if err != nil {
+		return nil, fmt.Errorf("build wrapped node: %w", err)
+	}
 
-	baseDir, _ := def.Config["base_dir"].(string)
-	if baseDir == "" {
-		var err error
-		baseDir, err = os.Getwd()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get working directory: %w", err)
+	catchRoute := "catch"
+	if c, ok := def.Config["catch"].(string); ok && c != "" {
+		catchRoute = c
+	}
+
+	finallyRoute := "finally"
+	if raw, ok := def.Config["finally"]; ok {
+		f, ok := raw.(string)
+		if !ok || f == "" {
+			return nil, fmt.Errorf("finally must be a route name")
 		}
+		finallyRoute = f
+	}
+	if finallyRoute == catchRoute || finallyRoute == "default" {
+		return nil, fmt.Errorf("finally route %q must differ from the default and catch routes", finallyRoute)
 	}
 
-	allowAbsolute, _ := def.Config["allow_absolute"].(bool)
-	createDirs, _ := def.Config["create_dirs"].(bool)
+	// The wrapped nodes run in Post so they share the graph's store
+	var try pocket.Node
+	try = pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+			output, runErr := pocket.NewGraph(body, store).Run(ctx, input)
+
+			// Successors are read at run time, once the workflow's
+			// connections have been made
+			if finally, ok := try.Successors()[finallyRoute]; ok {
+				payload := map[string]interface{}{
+					"input":  input,
+					"output": output,
+				}
+				if runErr != nil {
+					payload["error"] = tryError(body.Name(), runErr)
+				}
+				if _, err := pocket.NewGraph(finally, store).Run(ctx, payload); err != nil {
+					return nil, "", fmt.Errorf("finally failed: %w", err)
+				}
+			}
 
-	return pocket.NewNode[any, any](def.Name, pocket.Steps{
-		Exec: func(ctx context.Context, input any) (any, error) {
-			// Resolve path with sandboxing
-			resolvedPath, err := resolvePath(pathStr, baseDir, allowAbsolute)
-			if err != nil {
-				return nil, fmt.Errorf("path resolution failed: %w", err)
+			if runErr == nil {
+				return output, "default", nil
+			}
+
+			// Cancellation isn't the wrapped node's failure, so don't catch it
+			if ctx.Err() != nil {
+				return nil, "", ctx.Err()
 			}
 
 			if b.Verbose {
-				log.Printf("[%s] File operation '%s' on path: %s", def.Name, operation, resolvedPath)
+				log.Printf("[%s] Caught error from %s, routing to: %s", def.Name, body.Name(), catchRoute)
 			}
 
-			switch operation {
-			case "read":
-				data, err := os.ReadFile(resolvedPath) // #nosec G304 - Path is validated and sandboxed
-				if err != nil {
-					if os.IsNotExist(err) {
-						return map[string]interface{}{
-							"path":   resolvedPath,
-							"exists": false,
-						}, nil
-					}
-					return nil, fmt.Errorf("read failed: %w", err)
-				}
+			return map[string]interface{}{
+				"error": tryError(body.Name(), runErr),
+				"input": input,
+			}, catchRoute, nil
+		},
+	})
+	return try, nil
+}
 
-				info, _ := os.Stat(resolvedPath)
-				return map[string]interface{}{
-					"path":     resolvedPath,
-					"exists":   true,
-					"content":  string(data),
-					"size":     info.Size(),
-					"modified": info.ModTime().Format(time.RFC3339),
-				}, nil
+// referencedDefinition returns the definition of a node defined in the
+// workflow, for nodes that run another node by name.
+func referencedDefinition(def *yaml.NodeDefinition, ref string) (*yaml.NodeDefinition, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("node reference is empty")
+	}
+	if ref == def.Name {
+		return nil, fmt.Errorf("node %s cannot run itself", ref)
+	}
+	if def.Graph == nil {
+		return nil, fmt.Errorf("node references require a workflow definition")
+	}
+	referenced, ok := def.Graph.Node(ref)
+	if !ok {
+		return nil, fmt.Errorf("node %s not found", ref)
+	}
 
-			case "write":
-				// Create directories if needed
-				if createDirs {
-					dir := filepath.Dir(resolvedPath)
-					if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec // Standard directory permissions
-						return nil, fmt.Errorf("failed to create directories: %w", err)
-					}
-				}
+	// Referenced nodes are built without the graph so that references
+	// between nodes can't recurse
+	copied := *referenced
+	copied.Graph = nil
+	return &copied, nil
+}
 
-				// Support template content with input data
-				finalContent := content
-				if strings.Contains(content, "{{") {
-					tmpl, err := template.New("content").Parse(content)
-					if err == nil {
-						var buf bytes.Buffer
-						if err := tmpl.Execute(&buf, input); err == nil {
-							finalContent = buf.String()
-						}
-					}
-				}
+// nestedDefinition parses a node definition embedded in another node's config.
+func nestedDefinition(parent, key string, raw interface{}) (*yaml.NodeDefinition, error) {
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object", key)
+	}
 
-				if err := os.WriteFile(resolvedPath, []byte(finalContent), 0o644); err != nil { //nolint:gosec // Standard file permissions
-					return nil, fmt.Errorf("write failed: %w", err)
-				}
+	nodeType, ok := m["type"].(string)
+	if !ok || nodeType == "" {
+		return nil, fmt.Errorf("%s.type is required", key)
+	}
 
-				info, _ := os.Stat(resolvedPath)
-				return map[string]interface{}{
-					"path":     resolvedPath,
-					"exists":   true,
-					"size":     info.Size(),
-					"modified": info.ModTime().Format(time.RFC3339),
-				}, nil
+	name, _ := m["name"].(string)
+	if name == "" {
+		name = parent + "." + key
+	}
 
-			case "append":
-				// Create directories if needed
-				if createDirs {
-					dir := filepath.Dir(resolvedPath)
-					if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec // Standard directory permissions
-						return nil, fmt.Errorf("failed to create directories: %w", err)
-					}
-				}
+	config, _ := m["config"].(map[string]interface{})
+	if config == nil {
+		config = map[string]interface{}{}
+	}
 
-				// Support template content
-				finalContent := content
-				if strings.Contains(content, "{{") {
-					tmpl, err := template.New("content").Parse(content)
-					if err == nil {
-						var buf bytes.Buffer
-						if err := tmpl.Execute(&buf, input); err == nil {
-							finalContent = buf.String()
-						}
-					}
-				}
+	return &yaml.NodeDefinition{
+		Name:   name,
+		Type:   nodeType,
+		Config: config,
+	}, nil
+}
 
-				file, err := os.OpenFile(resolvedPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644) // #nosec G302,G304 - Path is validated and sandboxed
-				if err != nil {
-					return nil, fmt.Errorf("append failed: %w", err)
-				}
-				defer func() {
-					// Check for errors during file close
-					if cerr := file.Close(); err == nil && cerr != nil {
-						err = fmt.Errorf("failed to close file: %w", cerr)
-					}
-				}()
+// tryError serializes a wrapped node's error for the catch payload.
+func tryError(node string, err error) map[string]interface{} {
+	return map[string]interface{}{
+		"message": err.Error(),
+		"node":    node,
+	}
+}
 
-				if _, err := file.WriteString(finalContent); err != nil {
-					return nil, fmt.Errorf("append write failed: %w", err)
-				}
+// ExperimentNodeBuilder builds A/B experiment nodes.
+type ExperimentNodeBuilder struct {
+	Verbose bool
 
-				info, _ := os.Stat(resolvedPath)
-				return map[string]interface{}{
-					"path":     resolvedPath,
-					"exists":   true,
-					"size":     info.Size(),
-					"modified": info.ModTime().Format(time.RFC3339),
-				}, nil
+	// Registry builds the arm nodes. RegisterAll sets it.
+	Registry *Registry
+}
 
-			case "exists":
-				info, err := os.Stat(resolvedPath)
-				if err != nil {
-					if os.IsNotExist(err) {
-						return map[string]interface{}{
-							"path":   resolvedPath,
-							"exists": false,
-						}, nil
-					}
-					return nil, fmt.Errorf("stat failed: %w", err)
-				}
+// Metadata returns the node metadata.
+func (b *ExperimentNodeBuilder) Metadata() Metadata {
+	return Metadata{
+		Type:        "experiment",
+		Category:    "flow",
+		Description: "Splits traffic between nodes by weight and records the outcomes of each arm",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"experiment": map[string]interface{}{
+					"type":        "string",
+					"description": "Name the outcomes are recorded under (default: the node name)",
+				},
+				"arms": map[string]interface{}{
+					"type":     "array",
+					"minItems": 2,
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"name": map[string]interface{}{
+								"type":        "string",
+								"description": "Arm name",
+							},
+							"weight": map[string]interface{}{
+								"type":        "number",
+								"minimum":     0,
+								"description": "Relative share of traffic",
+								"default":     1,
+							},
+							"node": map[string]interface{}{
+								"type":        "object",
+								"description": "Node to run for inputs assigned to the arm",
+							},
+							"cost": map[string]interface{}{
+								"type":        []string{"number", "string"},
+								"description": "Cost of a run, or a template computing it",
+							},
+						},
+						"required": []string{"name", "node"},
+					},
+				},
+				"assign_by": map[string]interface{}{
+					"type":        "string",
+					"description": "Template for a key that always gets the same arm, such as a user ID",
+				},
+				"score": map[string]interface{}{
+					"type":        "string",
+					"description": "Template computing a numeric score for a successful run",
+				},
+			},
+			"required": []string{"arms"},
+		},
+		OutputSchema: map[string]interface{}{
+			"description": "The output of the arm the input was assigned to",
+		},
+		Examples: []Example{
+			{
+				Name:        "Compare two prompts",
+				Description: "Send 10% of requests to a new prompt, scoring answers by length",
+				Config: map[string]interface{}{
+					"arms": []map[string]interface{}{
+						{
+							"name":   "control",
+							"weight": 90,
+							"node": map[string]interface{}{
+								"type":   "template",
+								"config": map[string]interface{}{"template": "Summarize: {{.text}}"},
+							},
+						},
+						{
+							"name":   "concise",
+							"weight": 10,
+							"node": map[string]interface{}{
+								"type":   "template",
+								"config": map[string]interface{}{"template": "Summarize in one line: {{.text}}"},
+							},
+						},
+					},
+					"assign_by": "{{.user_id}}",
+					"score":     "{{len .output}}",
+				},
+			},
+		},
+		Since: "1.0.0",
+	}
+}
 
-				return map[string]interface{}{
-					"path":     resolvedPath,
-					"exists":   true,
-					"size":     info.Size(),
-					"modified": info.ModTime().Format(time.RFC3339),
-					"isDir":    info.IsDir(),
-				}, nil
+// Build creates an experiment node from a definition.
+func (b *ExperimentNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	if b.Registry == nil {
+		return nil, fmt.Errorf("experiment node requires a registry to build arm nodes")
+	}
 
-			case "list":
-				entries, err := os.ReadDir(resolvedPath)
-				if err != nil {
-					if os.IsNotExist(err) {
-						return map[string]interface{}{
-							"path":   resolvedPath,
-							"exists": false,
-						}, nil
-					}
-					return nil, fmt.Errorf("list failed: %w", err)
-				}
+	armsRaw, ok := def.Config["arms"].([]interface{})
+	if !ok || len(armsRaw) < 2 {
+		return nil, fmt.Errorf("arms must be an array of at least two arms")
+	}
 
-				files := []interface{}{}
-				for _, entry := range entries {
-					info, err := entry.Info()
-					if err != nil {
-						continue
-					}
+	e := &experiment{name: def.Name}
+	if name, ok := def.Config["experiment"].(string); ok && name != "" {
+		e.name = name
+	}
 
-					files = append(files, map[string]interface{}{
-						"name":     entry.Name(),
-						"path":     filepath.Join(resolvedPath, entry.Name()),
-						"size":     info.Size(),
-						"modified": info.ModTime().Format(time.RFC3339),
-						"isDir":    entry.IsDir(),
-					})
-				}
+	seen := make(map[string]bool, len(armsRaw))
+	for i, raw := range armsRaw {
+		spec, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("arm %d must be an object", i)
+		}
+		arm := experimentArm{weight: 1}
+		arm.name, _ = spec["name"].(string)
+		if arm.name == "" {
+			return nil, fmt.Errorf("arm %d missing 'name'", i)
+		}
+		if seen[arm.name] {
+			return nil, fmt.Errorf("duplicate arm %s", arm.name)
+		}
+		seen[arm.name] = true
 
-				return map[string]interface{}{
-					"path":   resolvedPath,
-					"exists": true,
-					"files":  files,
-				}, nil
+		if w, ok := spec["weight"]; ok {
+			weight, ok := toFloat(w)
+			if !ok || weight < 0 {
+				return nil, fmt.Errorf("arm %s weight must be a non-negative number", arm.name)
+			}
+			arm.weight = weight
+		}
 
-			default:
-				return nil, fmt.Errorf("unknown operation: %s", operation)
+		switch cost := spec["cost"].(type) {
+		case nil:
+		case string:
+			tmpl, err := newTemplate(arm.name + ".cost").Parse(cost)
+			if err != nil {
+				return nil, fmt.Errorf("arm %s invalid cost template: %w", arm.name, err)
+			}
+			arm.cost = tmpl
+		default:
+			fixed, ok := toFloat(cost)
+			if !ok {
+				return nil, fmt.Errorf("arm %s cost must be a number or template", arm.name)
 			}
-		},
-	}), nil
-}
+			arm.fixed = fixed
+		}
 
-// resolvePath resolves a file path with sandboxing.
-func resolvePath(path, baseDir string, allowAbsolute bool) (string, error) {
-	// Clean the path
-	cleanPath := filepath.Clean(path)
+		nodeDef, err := nestedDefinition(def.Name+"."+arm.name, "node", spec["node"])
+		if err != nil {
+			return nil, fmt.Errorf("arm %s: %w", arm.name, err)
+		}
+		if arm.node, err = b.Registry.Build(nodeDef); err != nil {
+			return nil, fmt.Errorf("build arm %s: %w", arm.name, err)
+		}
 
-	// If absolute paths are allowed and path is absolute, return it
-	if allowAbsolute && filepath.IsAbs(cleanPath) {
-		return cleanPath, nil
+		e.arms = append(e.arms, arm)
+		e.total += arm.weight
 	}
-
-	// Otherwise, resolve relative to base directory
-	resolvedPath := filepath.Join(baseDir, cleanPath)
-	absPath, err := filepath.Abs(resolvedPath)
-	if err != nil {
-		return "", err
+	if e.total <= 0 {
+		return nil, fmt.Errorf("arm weights must not all be zero")
 	}
 
-	// Ensure the resolved path is within the base directory
-	absBase, err := filepath.Abs(baseDir)
-	if err != nil {
-		return "", err
+	var err error
+	if e.assignBy, err = optionalTemplate("assign_by", def.Config["assign_by"]); err != nil {
+		return nil, fmt.Errorf("invalid assign_by: %w", err)
 	}
-
-	// Check if path is within base directory
-	relPath, err := filepath.Rel(absBase, absPath)
-	if err != nil {
-		return "", err
+	if e.score, err = optionalTemplate("score", def.Config["score"]); err != nil {
+		return nil, fmt.Errorf("invalid score: %w", err)
 	}
 
-	// Reject paths that go outside base directory
-	if strings.HasPrefix(relPath, "..") {
-		return "", fmt.Errorf("path '%s' is outside base directory", path)
-	}
+	// Arms run in Post so they share the graph's store
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+			i, err := e.assign(ctx, input)
+			if err != nil {
+				return nil, "", err
+			}
+			arm := e.arms[i]
+			if b.Verbose {
+				log.Printf("[%s] Assigned to arm %s", def.Name, arm.name)
+			}
 
-	return absPath, nil
+			clock := pocket.ClockFrom(ctx)
+			start := clock.Now()
+			output, runErr := pocket.NewGraph(arm.node, store).Run(ctx, input)
+			outcome := experimentOutcome{arm: i, failed: runErr != nil, latency: clock.Now().Sub(start)}
+
+			if runErr == nil {
+				if err := e.measure(&outcome, input, output); err != nil {
+					return nil, "", err
+				}
+			}
+			if err := e.record(ctx, store, outcome); err != nil {
+				return nil, "", fmt.Errorf("record outcome: %w", err)
+			}
+
+			if runErr != nil {
+				return nil, "", fmt.Errorf("arm %s: %w", arm.name, runErr)
+			}
+			return output, "default", nil
+		},
+	}), nil
 }
 
-// ExecNodeBuilder builds command execution nodes.
-type ExecNodeBuilder struct {
+// EvaluateNodeBuilder builds output evaluation nodes.
+type EvaluateNodeBuilder struct {
 	Verbose bool
+
+	// Registry builds judge nodes. RegisterAll sets it.
+	Registry *Registry
 }
 
-func (b *ExecNodeBuilder) Metadata() Metadata {
+// Metadata returns the node metadata.
+func (b *EvaluateNodeBuilder) Metadata() Metadata {
 	return Metadata{
-		Type:        "exec",
-		Category:    "io",
-		Description: "Executes shell commands with restrictions",
+		Type:        "evaluate",
+		Category:    "flow",
+		Description: "Scores output against heuristic and judge checks and routes on the score",
 		ConfigSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"command": map[string]interface{}{
+				"target": map[string]interface{}{
 					"type":        "string",
-					"description": "Command to execute",
+					"description": "Template for the text to evaluate (default: the input, as JSON unless it is a string)",
 				},
-				"args": map[string]interface{}{
-					"type":        "array",
-					"description": "Command arguments",
+				"checks": map[string]interface{}{
+					"type":     "array",
+					"minItems": 1,
 					"items": map[string]interface{}{
-						"type": "string",
+						"type": "object",
+						"properties": map[string]interface{}{
+							"name":      map[string]interface{}{"type": "string"},
+							"weight":    map[string]interface{}{"type": "number", "minimum": 0, "default": 1},
+							"regex":     map[string]interface{}{"type": "string", "description": "Pattern the text must match"},
+							"not_regex": map[string]interface{}{"type": "string", "description": "Pattern the text must not match"},
+							"length": map[string]interface{}{
+								"type":        "object",
+								"description": "Bounds on the length of the text in characters",
+								"properties": map[string]interface{}{
+									"min": map[string]interface{}{"type": "integer", "minimum": 0},
+									"max": map[string]interface{}{"type": "integer", "minimum": 0},
+								},
+							},
+							"json": map[string]interface{}{"type": "boolean", "description": "The text must be valid JSON"},
+							"judge": map[string]interface{}{
+								"type":        "object",
+								"description": "Node, such as an LLM call, that scores the text",
+							},
+							"score": map[string]interface{}{
+								"type":        "string",
+								"description": "Template reading a score from 0 to 1 from the judge's output",
+								"default":     "{{.score}}",
+							},
+							"reason": map[string]interface{}{
+								"type":        "string",
+								"description": "Template reading why the judge scored below 1",
+							},
+						},
 					},
 				},
-				"timeout": map[string]interface{}{
-					"type":        "string",
-					"description": "Execution timeout",
-					"default":     "30s",
+				"threshold": map[string]interface{}{
+					"type":        "number",
+					"description": "Score needed to route to pass rather than fail",
+					"default":     0.5,
 				},
-				"allowed_commands": map[string]interface{}{
+				"thresholds": map[string]interface{}{
 					"type":        "array",
-					"description": "List of allowed commands (whitelist)",
+					"description": "Routes for score tiers, checked in order; replaces threshold",
 					"items": map[string]interface{}{
-						"type": "string",
-					},
-				},
-				"env": map[string]interface{}{
-					"type":        "object",
-					"description": "Environment variables to set",
-					"additionalProperties": map[string]interface{}{
-						"type": "string",
+						"type": "object",
+						"properties": map[string]interface{}{
+							"min":   map[string]interface{}{"type": "number"},
+							"route": map[string]interface{}{"type": "string"},
+						},
+						"required": []string{"min", "route"},
 					},
 				},
-				"working_dir": map[string]interface{}{
+				"else": map[string]interface{}{
 					"type":        "string",
-					"description": "Working directory for command",
-				},
-				"capture_output": map[string]interface{}{
-					"type":        "boolean",
-					"description": "Whether to capture command output",
-					"default":     true,
+					"description": "Route for scores below every threshold",
+					"default":     "fail",
 				},
 			},
-			"required": []string{"command"},
+			"required": []string{"checks"},
 		},
 		OutputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"stdout": map[string]interface{}{
-					"type": "string",
+				"score": map[string]interface{}{
+					"type":        "number",
+					"description": "Weighted mean of the check scores",
 				},
-				"stderr": map[string]interface{}{
-					"type": "string",
+				"checks": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"name":    map[string]interface{}{"type": "string"},
+							"score":   map[string]interface{}{"type": "number"},
+							"message": map[string]interface{}{"type": "string"},
+						},
+					},
 				},
-				"exit_code": map[string]interface{}{
-					"type": "integer",
+				"feedback": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Messages of checks that scored below 1",
 				},
-				"duration": map[string]interface{}{
-					"type": "string",
+				"input": map[string]interface{}{
+					"description": "The evaluated input",
 				},
 			},
 		},
 		Examples: []Example{
 			{
-				Name:        "List files",
-				Description: "List files in current directory",
-				Config: map[string]interface{}{
-					"command": "ls",
-					"args":    []interface{}{"-la"},
-				},
-			},
-			{
-				Name:        "Run with timeout",
-				Description: "Execute command with timeout",
+				Name:        "Check a JSON answer",
+				Description: "Require valid JSON without apologies, retrying otherwise",
 				Config: map[string]interface{}{
-					"command": "sleep",
-					"args":    []interface{}{"5"},
-					"timeout": "2s",
+					"target": "{{.answer}}",
+					"checks": []map[string]interface{}{
+						{"json": true},
+						{"name": "no-apology", "not_regex": "(?i)sorry|apolog"},
+					},
+					"threshold": 1,
+					"else":      "retry",
 				},
 			},
 			{
-				Name:        "Restricted commands",
-				Description: "Only allow specific commands",
+				Name:        "LLM as judge",
+				Description: "Grade relevance with a judge model and route by tier",
 				Config: map[string]interface{}{
-					"command":          "echo",
-					"args":             []interface{}{"Hello, World!"},
-					"allowed_commands": []string{"echo", "ls", "cat"},
+					"checks": []map[string]interface{}{
+						{
+							"name": "relevance",
+							"judge": map[string]interface{}{
+								"type": "http",
+								"config": map[string]interface{}{
+									"url":    "https://llm.example.com/grade",
+									"method": "POST",
+									"body":   map[string]interface{}{"answer": "{{.text}}", "question": "{{.input.question}}"},
+								},
+							},
+							"score":  "{{.body.score}}",
+							"reason": "{{.body.reason}}",
+						},
+						{"length": map[string]interface{}{"max": 2000}},
+					},
+					"thresholds": []map[string]interface{}{
+						{"min": 0.9, "route": "publish"},
+						{"min": 0.6, "route": "review"},
+					},
+					"else": "regenerate",
 				},
 			},
 		},
@@ -1892,264 +6140,222 @@ func (b *ExecNodeBuilder) Metadata() Metadata {
 	}
 }
 
-// Build creates an exec node from a definition.
-//
-//nolint:gocyclo // Complex due to security validations, restrictions, and error handling
-func (b *ExecNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
-	// Get command configuration
-	command, ok := def.Config["command"].(string)
-	if !ok || command == "" {
-		return nil, fmt.Errorf("command is required")
+// Build creates an evaluate node from a definition.
+func (b *EvaluateNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	checksRaw, ok := def.Config["checks"].([]interface{})
+	if !ok || len(checksRaw) == 0 {
+		return nil, fmt.Errorf("checks must be a non-empty array")
 	}
 
-	// Get arguments
-	var args []string
-	if argsRaw, ok := def.Config["args"].([]interface{}); ok {
-		for i, arg := range argsRaw {
-			argStr, ok := arg.(string)
-			if !ok {
-				return nil, fmt.Errorf("argument %d must be a string", i)
-			}
-			args = append(args, argStr)
+	checks := make([]evaluateCheck, 0, len(checksRaw))
+	var totalWeight float64
+	for i, raw := range checksRaw {
+		spec, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("check %d must be an object", i)
+		}
+		check, err := newEvaluateCheck(def.Name, i, spec, b.Registry)
+		if err != nil {
+			return nil, err
 		}
+		checks = append(checks, check)
+		totalWeight += check.weight
 	}
-
-	// Get timeout
-	timeoutStr, _ := def.Config["timeout"].(string)
-	timeout, err := time.ParseDuration(timeoutStr)
-	if err != nil || timeout <= 0 {
-		timeout = 30 * time.Second
+	if totalWeight <= 0 {
+		return nil, fmt.Errorf("check weights must not all be zero")
 	}
 
-	// Get allowed commands whitelist
-	var allowedCommands []string
-	if allowed, ok := def.Config["allowed_commands"].([]interface{}); ok {
-		for _, cmd := range allowed {
-			if cmdStr, ok := cmd.(string); ok {
-				allowedCommands = append(allowedCommands, cmdStr)
-			}
-		}
+	target, err := optionalTemplate("target", def.Config["target"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
 	}
 
-	// Get environment variables
-	env := make(map[string]string)
-	if envMap, ok := def.Config["env"].(map[string]interface{}); ok {
-		for k, v := range envMap {
-			if vStr, ok := v.(string); ok {
-				env[k] = vStr
+	var thresholds []evaluateThreshold
+	if raw, ok := def.Config["thresholds"].([]interface{}); ok {
+		for i, t := range raw {
+			spec, ok := t.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("threshold %d must be an object", i)
+			}
+			minScore, ok := toFloat(spec["min"])
+			if !ok {
+				return nil, fmt.Errorf("threshold %d missing 'min'", i)
 			}
+			route, ok := spec["route"].(string)
+			if !ok || route == "" {
+				return nil, fmt.Errorf("threshold %d missing 'route'", i)
+			}
+			thresholds = append(thresholds, evaluateThreshold{min: minScore, route: route})
+		}
+	} else {
+		threshold := 0.5
+		if t, ok := toFloat(def.Config["threshold"]); ok {
+			threshold = t
 		}
+		thresholds = []evaluateThreshold{{min: threshold, route: "pass"}}
 	}
 
-	// Get working directory
-	workingDir, _ := def.Config["working_dir"].(string)
-
-	// Get capture output flag
-	captureOutput := true
-	if capture, ok := def.Config["capture_output"].(bool); ok {
-		captureOutput = capture
+	elseRoute := "fail"
+	if r, ok := def.Config["else"].(string); ok && r != "" {
+		elseRoute = r
 	}
 
+	// Judges run in Post so they share the graph's store
 	return pocket.NewNode[any, any](def.Name, pocket.Steps{
-		Exec: func(ctx context.Context, input any) (any, error) {
-
-			// Check if command is in whitelist
-			if len(allowedCommands) > 0 {
-				allowed := false
-				for _, allowedCmd := range allowedCommands {
-					if command == allowedCmd {
-						allowed = true
-						break
-					}
-				}
-				if !allowed {
-					return nil, fmt.Errorf("command '%s' is not in allowed list", command)
-				}
-			}
-
-			// Create command with timeout context
-			execCtx, cancel := context.WithTimeout(ctx, timeout)
-			defer cancel()
-
-			// Create command
-			cmd := exec.CommandContext(execCtx, command, args...) // #nosec G204 - Command is user-configured with restrictions
-
-			// Set working directory if specified
-			if workingDir != "" {
-				cmd.Dir = workingDir
-			}
-
-			// Set environment variables
-			if len(env) > 0 {
-				cmd.Env = os.Environ() // Start with current environment
-				for k, v := range env {
-					cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
-				}
-			}
-
-			// Setup output capture
-			var stdout, stderr bytes.Buffer
-			if captureOutput {
-				cmd.Stdout = &stdout
-				cmd.Stderr = &stderr
-			}
-
-			// Record start time
-			startTime := time.Now()
-
-			// Run command
-			err := cmd.Run()
-			duration := time.Since(startTime)
-
-			// Get exit code
-			exitCode := 0
+		Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+			text, err := evaluateText(target, input)
 			if err != nil {
-				if exitErr, ok := err.(*exec.ExitError); ok {
-					exitCode = exitErr.ExitCode()
-					// Check if it was killed due to timeout
-					if execCtx.Err() == context.DeadlineExceeded {
-						return nil, fmt.Errorf("command timed out after %v", timeout)
-					}
-				} else if execCtx.Err() == context.DeadlineExceeded {
-					return nil, fmt.Errorf("command timed out after %v", timeout)
-				} else {
-					return nil, fmt.Errorf("command failed: %w", err)
-				}
-			}
-
-			if b.Verbose {
-				log.Printf("[%s] Command executed: %s %v (exit: %d, duration: %v)",
-					def.Name, command, args, exitCode, duration)
+				return nil, "", err
 			}
 
-			result := map[string]interface{}{
-				"command":   command,
-				"args":      args,
-				"exit_code": exitCode,
-				"duration":  duration.String(),
+			results := make([]interface{}, 0, len(checks))
+			feedback := []interface{}{}
+			var score float64
+			for _, check := range checks {
+				s, message, err := check.score(ctx, store, input, text)
+				if err != nil {
+					return nil, "", fmt.Errorf("check %s: %w", check.name, err)
+				}
+				score += s * check.weight
+				result := map[string]interface{}{"name": check.name, "score": s}
+				if message != "" {
+					result["message"] = message
+					feedback = append(feedback, fmt.Sprintf("%s: %s", check.name, message))
+				}
+				results = append(results, result)
 			}
+			score /= totalWeight
 
-			if captureOutput {
-				result["stdout"] = stdout.String()
-				result["stderr"] = stderr.String()
+			route := elseRoute
+			for _, t := range thresholds {
+				if score >= t.min {
+					route = t.route
+					break
+				}
+			}
+			if b.Verbose {
+				log.Printf("[%s] Score %.3f, routing to: %s", def.Name, score, route)
 			}
 
-			return result, nil
+			return map[string]interface{}{
+				"score":    score,
+				"checks":   results,
+				"feedback": feedback,
+				"input":    input,
+			}, route, nil
 		},
 	}), nil
 }
 
-// ParallelNodeBuilder builds parallel execution nodes.
-type ParallelNodeBuilder struct {
+// GuardNodeBuilder builds guardrail nodes.
+type GuardNodeBuilder struct {
 	Verbose bool
+
+	// Registry builds moderation nodes. RegisterAll sets it.
+	Registry *Registry
 }
 
-func (b *ParallelNodeBuilder) Metadata() Metadata {
+// Metadata returns the node metadata.
+func (b *GuardNodeBuilder) Metadata() Metadata {
+	actionSchema := map[string]interface{}{
+		"type": "string",
+		"enum": []string{guardRedact, guardBlock, guardAnnotate},
+	}
+
 	return Metadata{
-		Type:        "parallel",
+		Type:        "guard",
 		Category:    "flow",
-		Description: "Executes multiple operations in parallel",
+		Description: "Redacts, blocks or annotates payloads with PII, banned words, excess length or flagged content",
 		ConfigSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"tasks": map[string]interface{}{
-					"type":        "array",
-					"description": "List of tasks to execute in parallel",
+				"checks": map[string]interface{}{
+					"type":     "array",
+					"minItems": 1,
 					"items": map[string]interface{}{
 						"type": "object",
 						"properties": map[string]interface{}{
-							"name": map[string]interface{}{
-								"type":        "string",
-								"description": "Task name",
+							"name":   map[string]interface{}{"type": "string"},
+							"action": actionSchema,
+							"pii": map[string]interface{}{
+								"type":        []string{"boolean", "array"},
+								"items":       map[string]interface{}{"type": "string", "enum": piiTypes},
+								"description": "PII types to detect, or true for all",
 							},
-							"operation": map[string]interface{}{
+							"pattern": map[string]interface{}{
 								"type":        "string",
-								"description": "Operation to perform",
+								"description": "Regular expression to detect",
 							},
-							"config": map[string]interface{}{
+							"words": map[string]interface{}{
+								"type":        []string{"array", "object"},
+								"description": "Words to detect, or {file: path} with one word per line",
+							},
+							"max_length": map[string]interface{}{
+								"type":        "integer",
+								"minimum":     1,
+								"description": "Maximum length of each string in characters; redact truncates",
+							},
+							"moderation": map[string]interface{}{
 								"type":        "object",
-								"description": "Task-specific configuration",
+								"description": "Node, such as a moderation API call, that flags the payload",
+							},
+							"flagged": map[string]interface{}{
+								"type":        "string",
+								"description": "Template reading whether the moderation node flagged the payload",
+								"default":     "{{.flagged}}",
+							},
+							"reason": map[string]interface{}{
+								"type":        "string",
+								"description": "Template reading why the moderation node flagged the payload",
+							},
+							"replacement": map[string]interface{}{
+								"type":        "string",
+								"description": "Text that replaces redacted matches",
+								"default":     "[REDACTED]",
 							},
 						},
-						"required": []string{"name", "operation"},
 					},
 				},
-				"max_concurrency": map[string]interface{}{
-					"type":        "integer",
-					"description": "Maximum number of concurrent tasks",
-					"minimum":     1,
-					"default":     10,
-				},
-				"fail_fast": map[string]interface{}{
-					"type":        "boolean",
-					"description": "Stop all tasks if one fails",
-					"default":     false,
-				},
-				"timeout": map[string]interface{}{
-					"type":        "string",
-					"description": "Overall timeout for all tasks",
-					"default":     "5m",
-				},
+				"action":       mergeSchema(actionSchema, "Default action of checks"),
+				"block_route":  map[string]interface{}{"type": "string", "description": "Route taken when a check blocks", "default": "blocked"},
+				"annotate_key": map[string]interface{}{"type": "string", "description": "Key the violations of annotate checks are added under", "default": "guard"},
 			},
-			"required": []string{"tasks"},
+			"required": []string{"checks"},
 		},
 		OutputSchema: map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{
-				"results": map[string]interface{}{
-					"type":        "array",
-					"description": "Results from all tasks",
-				},
-				"errors": map[string]interface{}{
-					"type":        "array",
-					"description": "Errors from failed tasks",
-				},
-				"duration": map[string]interface{}{
-					"type":        "string",
-					"description": "Total execution time",
-				},
-			},
+			"description": "The payload, redacted and annotated, or when blocked an object with the input and violations",
 		},
 		Examples: []Example{
 			{
-				Name:        "Parallel API calls",
-				Description: "Fetch data from multiple APIs concurrently",
+				Name:        "Redact PII before an LLM call",
+				Description: "Mask emails and phone numbers and block overly long prompts",
 				Config: map[string]interface{}{
-					"tasks": []interface{}{
-						map[string]interface{}{
-							"name":      "fetch_users",
-							"operation": "http_get",
-							"config": map[string]interface{}{
-								"url": "https://api.example.com/users",
-							},
-						},
-						map[string]interface{}{
-							"name":      "fetch_posts",
-							"operation": "http_get",
-							"config": map[string]interface{}{
-								"url": "https://api.example.com/posts",
-							},
-						},
+					"checks": []map[string]interface{}{
+						{"pii": []string{"email", "phone"}, "replacement": "[PII]"},
+						{"max_length": 8000, "action": "block"},
 					},
-					"max_concurrency": 5,
 				},
 			},
 			{
-				Name:        "Parallel processing with fail-fast",
-				Description: "Process multiple items, stop on first failure",
+				Name:        "Moderate output",
+				Description: "Block answers a moderation API flags and annotate profanity",
 				Config: map[string]interface{}{
-					"tasks": []interface{}{
-						map[string]interface{}{
-							"name":      "process_1",
-							"operation": "transform",
-						},
-						map[string]interface{}{
-							"name":      "process_2",
-							"operation": "transform",
+					"checks": []map[string]interface{}{
+						{
+							"moderation": map[string]interface{}{
+								"type": "http",
+								"config": map[string]interface{}{
+									"url":    "https://api.example.com/moderate",
+									"method": "POST",
+									"body":   map[string]interface{}{"text": "{{.text}}"},
+								},
+							},
+							"flagged": "{{.body.flagged}}",
+							"reason":  "{{.body.category}}",
 						},
+						{"words": []string{"darn", "heck"}, "action": "annotate"},
 					},
-					"fail_fast": true,
-					"timeout":   "30s",
+					"block_route": "refuse",
 				},
 			},
 		},
@@ -2157,241 +6363,99 @@ func (b *ParallelNodeBuilder) Metadata() Metadata {
 	}
 }
 
-// Build creates a parallel node from a definition.
-//
-//nolint:gocyclo // Complex due to concurrent execution handling and error aggregation
-func (b *ParallelNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
-	// Get tasks configuration
-	tasksRaw, ok := def.Config["tasks"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("tasks must be an array")
-	}
-
-	// Parse tasks
-	type Task struct {
-		Name      string
-		Operation string
-		Config    map[string]interface{}
+// Build creates a guard node from a definition.
+func (b *GuardNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	checksRaw, ok := def.Config["checks"].([]interface{})
+	if !ok || len(checksRaw) == 0 {
+		return nil, fmt.Errorf("checks must be a non-empty array")
 	}
 
-	tasks := make([]Task, 0, len(tasksRaw))
-	for i, taskRaw := range tasksRaw {
-		taskMap, ok := taskRaw.(map[string]interface{})
+	action, _ := def.Config["action"].(string)
+	checks := make([]guardCheck, 0, len(checksRaw))
+	for i, raw := range checksRaw {
+		spec, ok := raw.(map[string]interface{})
 		if !ok {
-			return nil, fmt.Errorf("task %d must be an object", i)
-		}
-
-		name, ok := taskMap["name"].(string)
-		if !ok || name == "" {
-			return nil, fmt.Errorf("task %d missing name", i)
+			return nil, fmt.Errorf("check %d must be an object", i)
 		}
-
-		operation, ok := taskMap["operation"].(string)
-		if !ok || operation == "" {
-			return nil, fmt.Errorf("task %d missing operation", i)
+		check, err := newGuardCheck(def.Name, i, spec, action, b.Registry)
+		if err != nil {
+			return nil, err
 		}
-
-		config, _ := taskMap["config"].(map[string]interface{})
-
-		tasks = append(tasks, Task{
-			Name:      name,
-			Operation: operation,
-			Config:    config,
-		})
-	}
-
-	// Get other configuration
-	maxConcurrency := 10
-	if mc, ok := def.Config["max_concurrency"].(float64); ok {
-		maxConcurrency = int(mc)
+		checks = append(checks, check)
 	}
 
-	failFast := false
-	if ff, ok := def.Config["fail_fast"].(bool); ok {
-		failFast = ff
+	blockRoute := "blocked"
+	if r, ok := def.Config["block_route"].(string); ok && r != "" {
+		blockRoute = r
 	}
-
-	timeoutStr, _ := def.Config["timeout"].(string)
-	timeout, err := time.ParseDuration(timeoutStr)
-	if err != nil || timeout <= 0 {
-		timeout = 5 * time.Minute
+	annotateKey := "guard"
+	if k, ok := def.Config["annotate_key"].(string); ok && k != "" {
+		annotateKey = k
 	}
 
+	// Moderation nodes run in Post so they share the graph's store
 	return pocket.NewNode[any, any](def.Name, pocket.Steps{
-		Exec: func(ctx context.Context, input any) (any, error) {
-			// Create timeout context
-			execCtx, cancel := context.WithTimeout(ctx, timeout)
-			defer cancel()
-
-			// Start time tracking
-			startTime := time.Now()
-
-			// Create semaphore for concurrency control
-			sem := make(chan struct{}, maxConcurrency)
-
-			// Results collection
-			type taskResult struct {
-				Name   string
-				Result interface{}
-				Error  error
+		Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+			payload := input
+			var violations []guardViolation
+			blocked := false
+			for i := range checks {
+				var found []guardViolation
+				var err error
+				payload, found, err = checks[i].run(ctx, store, input, payload)
+				if err != nil {
+					return nil, "", fmt.Errorf("check %s: %w", checks[i].name, err)
+				}
+				if len(found) > 0 && checks[i].action == guardBlock {
+					blocked = true
+				}
+				violations = append(violations, found...)
 			}
 
-			resultsChan := make(chan taskResult, len(tasks))
-
-			// Error channel for fail-fast
-			var errChan chan error
-			if failFast {
-				errChan = make(chan error, 1)
+			if blocked {
+				list := make([]interface{}, len(violations))
+				for i, v := range violations {
+					list[i] = v.toMap()
+				}
+				if b.Verbose {
+					log.Printf("[%s] Blocked with %d violation(s), routing to: %s", def.Name, len(violations), blockRoute)
+				}
+				return map[string]interface{}{
+					"input":      input,
+					"violations": list,
+				}, blockRoute, nil
 			}
 
-			// Launch tasks
-			var wg sync.WaitGroup
-			for _, task := range tasks {
-				wg.Add(1)
-
-				task := task // Capture loop variable
-				go func() {
-					defer wg.Done()
-
-					// Acquire semaphore
-					select {
-					case sem <- struct{}{}:
-						defer func() { <-sem }()
-					case <-execCtx.Done():
-						resultsChan <- taskResult{
-							Name:  task.Name,
-							Error: fmt.Errorf("timeout waiting for concurrency slot"),
-						}
-						return
-					}
-
-					// Check for early termination
-					if failFast {
-						select {
-						case <-execCtx.Done():
-							return
-						default:
-						}
-					}
-
-					// Execute task
-					result, err := executeTask(execCtx, task, input)
-
-					// Send result
-					resultsChan <- taskResult{
-						Name:   task.Name,
-						Result: result,
-						Error:  err,
-					}
-
-					// Handle fail-fast
-					if failFast && err != nil {
-						select {
-						case errChan <- err:
-							cancel() // Cancel all other tasks
-						default:
-						}
-					}
-				}()
+			var annotations []interface{}
+			for _, v := range violations {
+				if v.action == guardAnnotate {
+					annotations = append(annotations, v.toMap())
+				}
 			}
-
-			// Wait for all tasks to complete
-			go func() {
-				wg.Wait()
-				close(resultsChan)
-			}()
-
-			// Collect results
-			var results []interface{}
-			var errors []interface{}
-			resultMap := make(map[string]interface{})
-
-			for res := range resultsChan {
-				if res.Error != nil {
-					errors = append(errors, map[string]interface{}{
-						"task":  res.Name,
-						"error": res.Error.Error(),
-					})
-					if b.Verbose {
-						log.Printf("[%s] Task %s failed: %v", def.Name, res.Name, res.Error)
+			if len(annotations) > 0 {
+				if m, ok := payload.(map[string]interface{}); ok {
+					annotated := make(map[string]interface{}, len(m)+1)
+					for k, v := range m {
+						annotated[k] = v
 					}
+					annotated[annotateKey] = annotations
+					payload = annotated
 				} else {
-					results = append(results, map[string]interface{}{
-						"task":   res.Name,
-						"result": res.Result,
-					})
-					resultMap[res.Name] = res.Result
-					if b.Verbose {
-						log.Printf("[%s] Task %s completed successfully", def.Name, res.Name)
+					payload = map[string]interface{}{
+						"payload":   payload,
+						annotateKey: annotations,
 					}
 				}
 			}
-
-			duration := time.Since(startTime)
-
-			// Check if we should fail
-			if failFast && len(errors) > 0 {
-				return nil, fmt.Errorf("parallel execution failed (fail-fast): %d errors", len(errors))
-			}
-
-			if b.Verbose {
-				log.Printf("[%s] Parallel execution completed: %d successful, %d failed, duration: %v",
-					def.Name, len(results), len(errors), duration)
+			if b.Verbose && len(violations) > 0 {
+				log.Printf("[%s] Passed with %d violation(s)", def.Name, len(violations))
 			}
 
-			return map[string]interface{}{
-				"results":  results,
-				"errors":   errors,
-				"duration": duration.String(),
-				"summary": map[string]interface{}{
-					"total":      len(tasks),
-					"successful": len(results),
-					"failed":     len(errors),
-				},
-			}, nil
+			return payload, "default", nil
 		},
 	}), nil
 }
 
-// executeTask simulates task execution - in a real implementation,
-// this would delegate to actual node implementations.
-func executeTask(_ context.Context, task struct {
-	Name      string
-	Operation string
-	Config    map[string]interface{}
-}, input interface{}) (interface{}, error) {
-	// Simulate different operations
-	switch task.Operation {
-	case "http_get":
-		// Simulate HTTP GET
-		time.Sleep(100 * time.Millisecond)
-		return map[string]interface{}{
-			"status": 200,
-			"data":   fmt.Sprintf("Data from %s", task.Name),
-		}, nil
-
-	case "transform":
-		// Simulate data transformation
-		time.Sleep(50 * time.Millisecond)
-		return map[string]interface{}{
-			"transformed": true,
-			"task":        task.Name,
-			"input":       input,
-		}, nil
-
-	case "error":
-		// Simulate error for testing
-		return nil, fmt.Errorf("simulated error for task %s", task.Name)
-
-	default:
-		return map[string]interface{}{
-			"task":      task.Name,
-			"operation": task.Operation,
-			"input":     input,
-		}, nil
-	}
-}
-
 // LuaNodeBuilder builds Lua script nodes.
 type LuaNodeBuilder struct {
 	Verbose bool
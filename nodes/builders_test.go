@@ -1,9 +1,20 @@
 package nodes
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -102,6 +113,104 @@ func TestDelayNode(t *testing.T) {
 	}
 }
 
+func TestDelayNodeSimulatedClock(t *testing.T) {
+	node, err := (&DelayNodeBuilder{}).Build(&yaml.NodeDefinition{
+		Name:   "test-delay",
+		Config: map[string]interface{}{"duration": "6h"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build delay node: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := pocket.NewSimulatedClock(start)
+	graph := pocket.NewGraph(node, pocket.NewStore(), pocket.WithClock(clock))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := graph.Run(context.Background(), "input")
+		done <- err
+	}()
+
+	for clock.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(6 * time.Hour)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected delay to end when the clock advanced")
+	}
+}
+
+func TestDelayNodeTemplatedDuration(t *testing.T) {
+	builder := &DelayNodeBuilder{}
+	def := &yaml.NodeDefinition{
+		Name: "test-delay",
+		Config: map[string]interface{}{
+			"duration": "{{.wait}}",
+			"jitter":   50,
+		},
+	}
+
+	node, err := builder.Build(def)
+	if err != nil {
+		t.Fatalf("Failed to build delay node: %v", err)
+	}
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		wait interface{}
+		min  time.Duration
+		max  time.Duration
+	}{
+		{name: "duration string", wait: "40ms", min: 20 * time.Millisecond, max: 60 * time.Millisecond},
+		{name: "seconds", wait: 0.04, min: 20 * time.Millisecond, max: 60 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := map[string]interface{}{"wait": tt.wait}
+
+			start := time.Now()
+			if _, err := node.Exec(ctx, input); err != nil {
+				t.Fatalf("Exec failed: %v", err)
+			}
+			elapsed := time.Since(start)
+
+			if elapsed < tt.min {
+				t.Errorf("Expected delay of at least %v, got %v", tt.min, elapsed)
+			}
+			if elapsed > tt.max+50*time.Millisecond {
+				t.Errorf("Expected delay of at most %v, got %v", tt.max, elapsed)
+			}
+		})
+	}
+
+	t.Run("invalid duration", func(t *testing.T) {
+		_, err := node.Exec(ctx, map[string]interface{}{"wait": "soon"})
+		if err == nil {
+			t.Error("Expected error for invalid duration")
+		}
+	})
+
+	t.Run("invalid jitter", func(t *testing.T) {
+		_, err := builder.Build(&yaml.NodeDefinition{
+			Name:   "bad-delay",
+			Config: map[string]interface{}{"duration": "1s", "jitter": uint64(150)},
+		})
+		if err == nil {
+			t.Error("Expected error for jitter above 100")
+		}
+	})
+}
+
 func TestConditionalNode(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -159,6 +268,64 @@ func TestConditionalNode(t *testing.T) {
 	}
 }
 
+func TestSwitchNode(t *testing.T) {
+	builder := &SwitchNodeBuilder{}
+	def := &yaml.NodeDefinition{
+		Name: "test-switch",
+		Config: map[string]interface{}{
+			"expression": "{{.status}}",
+			"cases": map[string]interface{}{
+				"paid":    "fulfill",
+				"pending": "remind",
+			},
+			"default": "review",
+		},
+	}
+
+	node, err := builder.Build(def)
+	if err != nil {
+		t.Fatalf("Failed to build switch node: %v", err)
+	}
+
+	tests := []struct {
+		status   string
+		expected string
+	}{
+		{status: "paid", expected: "fulfill"},
+		{status: "pending", expected: "remind"},
+		{status: "unknown", expected: "review"},
+	}
+
+	ctx := context.Background()
+	store := pocket.NewStore()
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			input := map[string]interface{}{"status": tt.status}
+			output, next, err := node.Post(ctx, store, input, input, input)
+			if err != nil {
+				t.Fatalf("Post failed: %v", err)
+			}
+			if next != tt.expected {
+				t.Errorf("Expected route '%s', got '%s'", tt.expected, next)
+			}
+			if output.(map[string]interface{})["status"] != tt.status {
+				t.Errorf("Expected input to pass through, got %v", output)
+			}
+		})
+	}
+
+	t.Run("requires expression", func(t *testing.T) {
+		_, err := builder.Build(&yaml.NodeDefinition{
+			Name:   "bad-switch",
+			Config: map[string]interface{}{"cases": map[string]interface{}{}},
+		})
+		if err == nil {
+			t.Error("Expected error for missing expression")
+		}
+	})
+}
+
 func TestRouterNode(t *testing.T) {
 	builder := &RouterNodeBuilder{}
 	def := &yaml.NodeDefinition{
@@ -187,6 +354,63 @@ func TestRouterNode(t *testing.T) {
 	}
 }
 
+func TestRouterNodeRouteFrom(t *testing.T) {
+	ctx := context.Background()
+	store := pocket.NewStore()
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		input  any
+		want   string
+	}{
+		{
+			name:   "jsonpath",
+			config: map[string]interface{}{"route_from": "$.type"},
+			input:  map[string]interface{}{"type": "refund"},
+			want:   "refund",
+		},
+		{
+			name:   "template",
+			config: map[string]interface{}{"route_from": "{{if gt .amount 100.0}}review{{else}}approve{{end}}"},
+			input:  map[string]interface{}{"amount": 250.0},
+			want:   "review",
+		},
+		{
+			name:   "missing value uses fallback",
+			config: map[string]interface{}{"route_from": "$.type", "route": "unknown"},
+			input:  map[string]interface{}{},
+			want:   "unknown",
+		},
+		{
+			name: "disallowed route uses fallback",
+			config: map[string]interface{}{
+				"route_from": "$.type",
+				"routes":     []interface{}{"order", "refund"},
+				"route":      "unknown",
+			},
+			input: map[string]interface{}{"type": "admin"},
+			want:  "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := (&RouterNodeBuilder{}).Build(&yaml.NodeDefinition{Name: "test-router", Config: tt.config})
+			if err != nil {
+				t.Fatalf("Failed to build router node: %v", err)
+			}
+			_, next, err := node.Post(ctx, store, tt.input, tt.input, tt.input)
+			if err != nil {
+				t.Fatalf("Post failed: %v", err)
+			}
+			if next != tt.want {
+				t.Errorf("Expected route '%s', got '%s'", tt.want, next)
+			}
+		})
+	}
+}
+
 func TestTransformNode(t *testing.T) {
 	builder := &TransformNodeBuilder{}
 	def := &yaml.NodeDefinition{
@@ -401,12 +625,103 @@ func TestTemplateNode(t *testing.T) {
 			t.Errorf("Expected error about missing template, got: %v", err)
 		}
 	})
+
+	t.Run("partials and layout", func(t *testing.T) {
+		dir := t.TempDir()
+		footer := filepath.Join(dir, "footer.tmpl")
+		if err := os.WriteFile(footer, []byte("-- {{.sender}}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		builder := &TemplateNodeBuilder{}
+		def := &yaml.NodeDefinition{
+			Name: "test-layout",
+			Config: map[string]interface{}{
+				"template": `{{define "content"}}Hi {{.name}}{{end}}`,
+				"partials": map[string]interface{}{
+					"base":   `[{{block "content" .}}default{{end}}] {{template "footer" .}}`,
+					"footer": map[string]interface{}{"file": footer},
+				},
+				"layout": "base",
+			},
+		}
+
+		node, err := builder.Build(def)
+		if err != nil {
+			t.Fatalf("Failed to build template node: %v", err)
+		}
+
+		input := map[string]interface{}{"name": "Ada", "sender": "Bob"}
+		result, err := node.Exec(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		if result != "[Hi Ada] -- Bob" {
+			t.Errorf("Expected '[Hi Ada] -- Bob', got '%v'", result)
+		}
+	})
+
+	t.Run("undefined layout", func(t *testing.T) {
+		builder := &TemplateNodeBuilder{}
+		def := &yaml.NodeDefinition{
+			Name: "test-bad-layout",
+			Config: map[string]interface{}{
+				"template": "Hello",
+				"layout":   "missing",
+			},
+		}
+
+		if _, err := builder.Build(def); err == nil {
+			t.Error("Expected error for undefined layout")
+		}
+	})
+
+	t.Run("file template reloads on change", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "greeting.tmpl")
+		if err := os.WriteFile(file, []byte("Hello {{.name}}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		builder := &TemplateNodeBuilder{}
+		def := &yaml.NodeDefinition{
+			Name:   "test-file",
+			Config: map[string]interface{}{"file": file},
+		}
+
+		node, err := builder.Build(def)
+		if err != nil {
+			t.Fatalf("Failed to build template node: %v", err)
+		}
+
+		input := map[string]interface{}{"name": "Ada"}
+		result, err := node.Exec(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		if result != "Hello Ada" {
+			t.Errorf("Expected 'Hello Ada', got '%v'", result)
+		}
+
+		if err := os.WriteFile(file, []byte("Bye {{.name}}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// Ensure the modification time changes on coarse-grained filesystems
+		later := time.Now().Add(time.Second)
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err = node.Exec(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		if result != "Bye Ada" {
+			t.Errorf("Expected 'Bye Ada', got '%v'", result)
+		}
+	})
 }
 
 func TestHTTPNode(t *testing.T) {
-	// Note: These are basic unit tests. For real HTTP testing,
-	// we would use httptest to create a test server.
-
 	t.Run("missing url", func(t *testing.T) {
 		builder := &HTTPNodeBuilder{}
 		def := &yaml.NodeDefinition{
@@ -462,84 +777,591 @@ func TestHTTPNode(t *testing.T) {
 		if meta.Category != "io" {
 			t.Errorf("Expected category 'io', got '%s'", meta.Category)
 		}
-		if len(meta.Examples) != 2 {
-			t.Errorf("Expected 2 examples, got %d", len(meta.Examples))
+		if len(meta.Examples) != 3 {
+			t.Errorf("Expected 3 examples, got %d", len(meta.Examples))
 		}
 	})
-}
-
-func TestJSONPathNode(t *testing.T) {
-	store := pocket.NewStore()
-	ctx := context.Background()
 
-	t.Run("extract simple field", func(t *testing.T) {
-		builder := &JSONPathNodeBuilder{}
-		def := &yaml.NodeDefinition{
-			Name: "test-jsonpath",
-			Config: map[string]interface{}{
-				"path": "$.user.name",
+	t.Run("status routing", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/missing":
+				w.WriteHeader(http.StatusNotFound)
+			case "/broken":
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			_, _ = w.Write([]byte(`{"id": 7, "active": false}`))
+		}))
+		defer server.Close()
+
+		tests := []struct {
+			name     string
+			path     string
+			expect   map[string]interface{}
+			route    string
+			failures int
+		}{
+			{name: "success", path: "/ok", route: "success"},
+			{name: "client error", path: "/missing", route: "client_error", failures: 1},
+			{name: "server error", path: "/broken", route: "server_error", failures: 1},
+			{
+				name:   "expected status",
+				path:   "/missing",
+				expect: map[string]interface{}{"status": []interface{}{uint64(404), "2xx"}},
+				route:  "success",
+			},
+			{
+				name: "passing assertions",
+				path: "/ok",
+				expect: map[string]interface{}{
+					"assertions": []interface{}{
+						map[string]interface{}{"path": "$.body.id", "equals": uint64(7)},
+						map[string]interface{}{"path": "$.body.error", "exists": false},
+					},
+				},
+				route: "success",
+			},
+			{
+				name: "failing assertions",
+				path: "/ok",
+				expect: map[string]interface{}{
+					"assertions": []interface{}{
+						map[string]interface{}{"path": "$.body.active", "equals": true},
+						map[string]interface{}{"path": "$.body.name", "exists": true},
+					},
+				},
+				route:    "assertion_failed",
+				failures: 2,
 			},
 		}
 
-		node, err := builder.Build(def)
-		if err != nil {
-			t.Fatalf("Failed to build JSONPath node: %v", err)
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				config := map[string]interface{}{
+					"url":             server.URL + tt.path,
+					"route_by_status": true,
+					"retry":           map[string]interface{}{"max_attempts": 1},
+				}
+				if tt.expect != nil {
+					config["expect"] = tt.expect
+				}
+
+				node, err := (&HTTPNodeBuilder{}).Build(&yaml.NodeDefinition{Name: "test-http", Config: config})
+				if err != nil {
+					t.Fatalf("Failed to build HTTP node: %v", err)
+				}
+
+				ctx := context.Background()
+				exec, err := node.Exec(ctx, nil)
+				if err != nil {
+					t.Fatalf("Exec failed: %v", err)
+				}
+				output, next, err := node.Post(ctx, pocket.NewStore(), nil, nil, exec)
+				if err != nil {
+					t.Fatalf("Post failed: %v", err)
+				}
+				if next != tt.route {
+					t.Errorf("Expected route '%s', got '%s'", tt.route, next)
+				}
+
+				failures, _ := output.(map[string]interface{})["failures"].([]string)
+				if len(failures) != tt.failures {
+					t.Errorf("Expected %d failures, got %v", tt.failures, failures)
+				}
+			})
 		}
+	})
 
-		input := map[string]interface{}{
-			"user": map[string]interface{}{
-				"name": "Alice",
-				"age":  30,
+	t.Run("auth", func(t *testing.T) {
+		var tokenFetches int
+		tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, secret, _ := r.BasicAuth()
+			if id != "client" || secret != "s3cret" || r.FormValue("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tokenFetches++
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3600}`, tokenFetches)
+		}))
+		defer tokenServer.Close()
+
+		// The API echoes the Authorization header and rejects the first token
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "Bearer token-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+		}))
+		defer api.Close()
+
+		t.Setenv("TEST_API_TOKEN", "abc123")
+		t.Setenv("TEST_CLIENT_SECRET", "s3cret")
+		passwordFile := filepath.Join(t.TempDir(), "password")
+		if err := os.WriteFile(passwordFile, []byte("hunter2\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name     string
+			auth     map[string]interface{}
+			expected string
+		}{
+			{
+				name:     "bearer",
+				auth:     map[string]interface{}{"type": "bearer", "token_env": "TEST_API_TOKEN"},
+				expected: "Bearer abc123",
+			},
+			{
+				name:     "basic",
+				auth:     map[string]interface{}{"type": "basic", "username": "ada", "password_file": passwordFile},
+				expected: "Basic YWRhOmh1bnRlcjI=",
+			},
+			{
+				name: "oauth2 refreshes rejected token",
+				auth: map[string]interface{}{
+					"type":              "oauth2",
+					"token_url":         tokenServer.URL,
+					"client_id":         "client",
+					"client_secret_env": "TEST_CLIENT_SECRET",
+					"scopes":            []interface{}{"read"},
+				},
+				expected: "Bearer token-2",
 			},
 		}
 
-		graph := pocket.NewGraph(node, store)
-		result, err := graph.Run(ctx, input)
-		if err != nil {
-			t.Fatalf("Failed to run graph: %v", err)
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				node, err := (&HTTPNodeBuilder{}).Build(&yaml.NodeDefinition{
+					Name: "test-http",
+					Config: map[string]interface{}{
+						"url":   api.URL,
+						"auth":  tt.auth,
+						"retry": map[string]interface{}{"max_attempts": 2, "delay": "1ms"},
+					},
+				})
+				if err != nil {
+					t.Fatalf("Failed to build HTTP node: %v", err)
+				}
+
+				for i := 0; i < 2; i++ {
+					result, err := node.Exec(context.Background(), nil)
+					if err != nil {
+						t.Fatalf("Exec failed: %v", err)
+					}
+					if body := result.(map[string]interface{})["body"]; body != tt.expected {
+						t.Errorf("Expected '%s', got '%v'", tt.expected, body)
+					}
+				}
+			})
 		}
 
-		if result != "Alice" {
-			t.Errorf("Expected 'Alice', got %v", result)
+		// The refreshed token is cached across executions
+		if tokenFetches != 2 {
+			t.Errorf("Expected 2 token fetches, got %d", tokenFetches)
 		}
 	})
 
-	t.Run("extract from array", func(t *testing.T) {
-		builder := &JSONPathNodeBuilder{}
-		def := &yaml.NodeDefinition{
-			Name: "test-jsonpath",
+	t.Run("auth requires secret", func(t *testing.T) {
+		_, err := (&HTTPNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-http",
 			Config: map[string]interface{}{
-				"path":     "$.items[*].price",
-				"multiple": true,
+				"url":  "https://example.com",
+				"auth": map[string]interface{}{"type": "bearer", "token_env": "TEST_UNSET_TOKEN"},
 			},
+		})
+		if err == nil || !strings.Contains(err.Error(), "TEST_UNSET_TOKEN is not set") {
+			t.Errorf("Expected missing token error, got: %v", err)
 		}
+	})
 
-		node, err := builder.Build(def)
-		if err != nil {
-			t.Fatalf("Failed to build JSONPath node: %v", err)
-		}
+	t.Run("transport", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("secure"))
+		}))
+		defer server.Close()
 
-		input := map[string]interface{}{
-			"items": []interface{}{
-				map[string]interface{}{"name": "Book", "price": 10.99},
-				map[string]interface{}{"name": "Pen", "price": 2.50},
-				map[string]interface{}{"name": "Notebook", "price": 5.00},
-			},
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+			t.Fatal(err)
 		}
 
-		graph := pocket.NewGraph(node, store)
-		result, err := graph.Run(ctx, input)
+		config := map[string]interface{}{
+			"url":  server.URL,
+			"tls":  map[string]interface{}{"ca_file": caFile},
+			"pool": map[string]interface{}{"max_idle_conns_per_host": uint64(10)},
+		}
+		node, err := (&HTTPNodeBuilder{}).Build(&yaml.NodeDefinition{Name: "test-tls", Config: config})
 		if err != nil {
-			t.Fatalf("Failed to run graph: %v", err)
+			t.Fatalf("Failed to build HTTP node: %v", err)
 		}
 
-		prices, ok := result.([]interface{})
-		if !ok {
-			t.Fatalf("Expected array result, got %T", result)
+		result, err := node.Exec(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
 		}
-
-		if len(prices) != 3 {
-			t.Errorf("Expected 3 prices, got %d", len(prices))
+		if body := result.(map[string]interface{})["body"]; body != "secure" {
+			t.Errorf("Expected 'secure', got '%v'", body)
+		}
+
+		// Nodes with the same settings share a transport
+		first, err := newHTTPClient(config, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := newHTTPClient(config, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.Transport != second.Transport {
+			t.Error("Expected clients with the same settings to share a transport")
+		}
+		if first.Transport.(*http.Transport).MaxIdleConnsPerHost != 10 {
+			t.Error("Expected pool settings to be applied")
+		}
+	})
+
+	t.Run("proxy", func(t *testing.T) {
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("proxied " + r.URL.Host))
+		}))
+		defer proxy.Close()
+
+		node, err := (&HTTPNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-proxy",
+			Config: map[string]interface{}{
+				"url":   "http://api.internal/data",
+				"proxy": proxy.URL,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build HTTP node: %v", err)
+		}
+
+		result, err := node.Exec(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		if body := result.(map[string]interface{})["body"]; body != "proxied api.internal" {
+			t.Errorf("Expected 'proxied api.internal', got '%v'", body)
+		}
+	})
+
+	t.Run("invalid tls", func(t *testing.T) {
+		_, err := (&HTTPNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-tls",
+			Config: map[string]interface{}{
+				"url": "https://example.com",
+				"tls": map[string]interface{}{"cert_file": "client.pem"},
+			},
+		})
+		if err == nil || !strings.Contains(err.Error(), "cert_file and key_file must be set together") {
+			t.Errorf("Expected cert/key error, got: %v", err)
+		}
+	})
+
+	t.Run("multipart upload", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			file, header, err := r.FormFile("report")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			data, _ := io.ReadAll(file)
+			_, _ = fmt.Fprintf(w, "%s/%s/%s/%s", r.FormValue("title"), header.Filename,
+				header.Header.Get("Content-Type"), data)
+		}))
+		defer server.Close()
+
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "q3.csv"), []byte("a,b"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		node, err := (&HTTPNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-upload",
+			Config: map[string]interface{}{
+				"url":      server.URL,
+				"method":   "POST",
+				"base_dir": dir,
+				"multipart": map[string]interface{}{
+					"fields": map[string]interface{}{"title": "{{.title}}"},
+					"files": map[string]interface{}{
+						"report": map[string]interface{}{"path": "{{.file}}", "content_type": "text/csv"},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build HTTP node: %v", err)
+		}
+
+		input := map[string]interface{}{"title": "Q3", "file": "q3.csv"}
+		result, err := node.Exec(context.Background(), input)
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		if body := result.(map[string]interface{})["body"]; body != "Q3/q3.csv/text/csv/a,b" {
+			t.Errorf("Expected 'Q3/q3.csv/text/csv/a,b', got '%v'", body)
+		}
+
+		// Files outside the sandbox are rejected
+		input["file"] = "../secret.txt"
+		if _, err := node.Exec(context.Background(), input); err == nil {
+			t.Error("Expected error for file outside base_dir")
+		}
+	})
+
+	t.Run("download", func(t *testing.T) {
+		payload := strings.Repeat("x", 64*1024)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/missing" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte(payload))
+		}))
+		defer server.Close()
+
+		dir := t.TempDir()
+		node, err := (&HTTPNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-download",
+			Config: map[string]interface{}{
+				"url":      server.URL + "/{{.name}}",
+				"base_dir": dir,
+				"download": map[string]interface{}{"path": "files/{{.name}}.bin", "create_dirs": true},
+				"retry":    map[string]interface{}{"max_attempts": 1},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build HTTP node: %v", err)
+		}
+
+		result, err := node.Exec(context.Background(), map[string]interface{}{"name": "data"})
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+
+		download, ok := result.(map[string]interface{})["download"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected download metadata, got %v", result)
+		}
+		if download["size"] != int64(len(payload)) {
+			t.Errorf("Expected size %d, got %v", len(payload), download["size"])
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "files", "data.bin"))
+		if err != nil {
+			t.Fatalf("Failed to read download: %v", err)
+		}
+		if string(data) != payload {
+			t.Error("Downloaded content does not match")
+		}
+
+		// Error responses are returned as usual rather than saved
+		result, err = node.Exec(context.Background(), map[string]interface{}{"name": "missing"})
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		if status := result.(map[string]interface{})["status"]; status != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %v", status)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "files", "missing.bin")); !os.IsNotExist(err) {
+			t.Error("Expected no file for an error response")
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		// Three pages of two items each, exposed through every pagination style
+		pageItems := func(page int) []int {
+			if page < 1 || page > 3 {
+				return []int{}
+			}
+			return []int{page*2 - 1, page * 2}
+		}
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if c := r.URL.Query().Get("cursor"); c != "" {
+				page, _ = strconv.Atoi(strings.TrimPrefix(c, "c"))
+			}
+			if page == 0 {
+				page = 1
+			}
+
+			response := map[string]interface{}{"data": pageItems(page)}
+			if page < 3 {
+				response["next"] = fmt.Sprintf("c%d", page+1)
+				w.Header().Set("Link", fmt.Sprintf(`<%s/items?page=%d>; rel="next", <%s/items?page=3>; rel="last"`,
+					server.URL, page+1, server.URL))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(response)
+		}))
+		defer server.Close()
+
+		tests := []struct {
+			name     string
+			paginate map[string]interface{}
+			expected []interface{}
+			pages    int
+		}{
+			{
+				name:     "link header",
+				paginate: map[string]interface{}{"type": "link", "items": "$.body.data"},
+				expected: []interface{}{1.0, 2.0, 3.0, 4.0, 5.0, 6.0},
+				pages:    3,
+			},
+			{
+				name:     "cursor",
+				paginate: map[string]interface{}{"type": "cursor", "items": "$.body.data", "cursor_path": "$.body.next"},
+				expected: []interface{}{1.0, 2.0, 3.0, 4.0, 5.0, 6.0},
+				pages:    3,
+			},
+			{
+				name:     "page number stops on empty page",
+				paginate: map[string]interface{}{"type": "page", "items": "$.body.data"},
+				expected: []interface{}{1.0, 2.0, 3.0, 4.0, 5.0, 6.0},
+				pages:    4,
+			},
+			{
+				name:     "max items",
+				paginate: map[string]interface{}{"type": "link", "items": "$.body.data", "max_items": uint64(3)},
+				expected: []interface{}{1.0, 2.0, 3.0},
+				pages:    2,
+			},
+			{
+				name:     "max pages",
+				paginate: map[string]interface{}{"type": "page", "items": "$.body.data", "max_pages": uint64(1)},
+				expected: []interface{}{1.0, 2.0},
+				pages:    1,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				node, err := (&HTTPNodeBuilder{}).Build(&yaml.NodeDefinition{
+					Name: "test-paginate",
+					Config: map[string]interface{}{
+						"url":      server.URL + "/items",
+						"paginate": tt.paginate,
+					},
+				})
+				if err != nil {
+					t.Fatalf("Failed to build HTTP node: %v", err)
+				}
+
+				result, err := node.Exec(context.Background(), nil)
+				if err != nil {
+					t.Fatalf("Exec failed: %v", err)
+				}
+				output := result.(map[string]interface{})
+				if !reflect.DeepEqual(output["items"], tt.expected) {
+					t.Errorf("Expected items %v, got %v", tt.expected, output["items"])
+				}
+				if output["pages"] != tt.pages {
+					t.Errorf("Expected %d pages, got %v", tt.pages, output["pages"])
+				}
+			})
+		}
+	})
+
+	t.Run("routes default without expectations", func(t *testing.T) {
+		node, err := (&HTTPNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name:   "test-http",
+			Config: map[string]interface{}{"url": "https://example.com"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build HTTP node: %v", err)
+		}
+
+		result := map[string]interface{}{"status": 404}
+		_, next, err := node.Post(context.Background(), pocket.NewStore(), nil, nil, result)
+		if err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+		if next != "default" {
+			t.Errorf("Expected route 'default', got '%s'", next)
+		}
+	})
+}
+
+func TestJSONPathNode(t *testing.T) {
+	store := pocket.NewStore()
+	ctx := context.Background()
+
+	t.Run("extract simple field", func(t *testing.T) {
+		builder := &JSONPathNodeBuilder{}
+		def := &yaml.NodeDefinition{
+			Name: "test-jsonpath",
+			Config: map[string]interface{}{
+				"path": "$.user.name",
+			},
+		}
+
+		node, err := builder.Build(def)
+		if err != nil {
+			t.Fatalf("Failed to build JSONPath node: %v", err)
+		}
+
+		input := map[string]interface{}{
+			"user": map[string]interface{}{
+				"name": "Alice",
+				"age":  30,
+			},
+		}
+
+		graph := pocket.NewGraph(node, store)
+		result, err := graph.Run(ctx, input)
+		if err != nil {
+			t.Fatalf("Failed to run graph: %v", err)
+		}
+
+		if result != "Alice" {
+			t.Errorf("Expected 'Alice', got %v", result)
+		}
+	})
+
+	t.Run("extract from array", func(t *testing.T) {
+		builder := &JSONPathNodeBuilder{}
+		def := &yaml.NodeDefinition{
+			Name: "test-jsonpath",
+			Config: map[string]interface{}{
+				"path":     "$.items[*].price",
+				"multiple": true,
+			},
+		}
+
+		node, err := builder.Build(def)
+		if err != nil {
+			t.Fatalf("Failed to build JSONPath node: %v", err)
+		}
+
+		input := map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"name": "Book", "price": 10.99},
+				map[string]interface{}{"name": "Pen", "price": 2.50},
+				map[string]interface{}{"name": "Notebook", "price": 5.00},
+			},
+		}
+
+		graph := pocket.NewGraph(node, store)
+		result, err := graph.Run(ctx, input)
+		if err != nil {
+			t.Fatalf("Failed to run graph: %v", err)
+		}
+
+		prices, ok := result.([]interface{})
+		if !ok {
+			t.Fatalf("Expected array result, got %T", result)
+		}
+
+		if len(prices) != 3 {
+			t.Errorf("Expected 3 prices, got %d", len(prices))
 		}
 
 		expected := []float64{10.99, 2.50, 5.00}
@@ -676,6 +1498,64 @@ func TestJSONPathNode(t *testing.T) {
 		}
 	})
 
+	t.Run("set mode", func(t *testing.T) {
+		run := func(t *testing.T, config map[string]interface{}, input any) (any, error) {
+			t.Helper()
+			config["mode"] = "set"
+			node, err := (&JSONPathNodeBuilder{}).Build(&yaml.NodeDefinition{Name: "test-jsonpath", Config: config})
+			if err != nil {
+				t.Fatalf("Failed to build JSONPath node: %v", err)
+			}
+			return pocket.NewGraph(node, store).Run(ctx, input)
+		}
+
+		input := map[string]interface{}{
+			"user":  map[string]interface{}{"name": "Alice"},
+			"items": []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}},
+		}
+
+		result, err := run(t, map[string]interface{}{
+			"path":  "$.user.profile.greeting",
+			"value": "Hello {{.user.name}}",
+		}, input)
+		if err != nil {
+			t.Fatalf("Failed to run graph: %v", err)
+		}
+		greeting, _ := getDotted(result.(map[string]interface{}), "user.profile.greeting")
+		if greeting != "Hello Alice" {
+			t.Errorf("Expected templated value at new path, got %v", result)
+		}
+		if _, ok := input["user"].(map[string]interface{})["profile"]; ok {
+			t.Error("Expected input to be left unmodified")
+		}
+
+		// value_from keeps the selected value's type, and wildcards set every match
+		result, err = run(t, map[string]interface{}{
+			"path":       "$.items[*].owner",
+			"value_from": "$.user",
+		}, input)
+		if err != nil {
+			t.Fatalf("Failed to run graph: %v", err)
+		}
+		for _, item := range result.(map[string]interface{})["items"].([]interface{}) {
+			if !reflect.DeepEqual(item.(map[string]interface{})["owner"], input["user"]) {
+				t.Errorf("Expected owner to be set on every item, got %v", item)
+			}
+		}
+
+		if _, err := run(t, map[string]interface{}{"path": "$.a", "value": 1}, "not a document"); err == nil {
+			t.Error("Expected error for non-document input")
+		}
+
+		_, err = (&JSONPathNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name:   "test-jsonpath",
+			Config: map[string]interface{}{"mode": "set", "path": "$.a"},
+		})
+		if err == nil {
+			t.Error("Expected error when set mode has no value")
+		}
+	})
+
 	t.Run("metadata", func(t *testing.T) {
 		builder := &JSONPathNodeBuilder{}
 		meta := builder.Metadata()
@@ -1095,6 +1975,92 @@ func TestAggregateNode(t *testing.T) {
 		}
 	})
 
+	t.Run("collect across runs", func(t *testing.T) {
+		node, err := (&AggregateNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-collect",
+			Config: map[string]interface{}{
+				"mode":           "object",
+				"key":            "{{.source}}",
+				"count":          uint64(2),
+				"correlation_id": "{{.order}}",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build aggregate node: %v", err)
+		}
+
+		shared := pocket.NewStore()
+		post := func(input map[string]interface{}) (map[string]interface{}, string) {
+			t.Helper()
+			output, next, err := node.Post(ctx, shared, input, nil, nil)
+			if err != nil {
+				t.Fatalf("Post failed: %v", err)
+			}
+			return output.(map[string]interface{}), next
+		}
+
+		if res, next := post(map[string]interface{}{"order": "a", "source": "billing"}); next != "pending" || res["count"] != 1 {
+			t.Errorf("Expected first input to be pending, got %s %v", next, res)
+		}
+		// Inputs for another order are collected separately
+		if _, next := post(map[string]interface{}{"order": "b", "source": "billing"}); next != "pending" {
+			t.Errorf("Expected other batch to be pending, got %s", next)
+		}
+
+		res, next := post(map[string]interface{}{"order": "a", "source": "shipping"})
+		if next != "default" || res["complete"] != true {
+			t.Fatalf("Expected batch a to complete, got %s %v", next, res)
+		}
+		data := res["data"].(map[string]interface{})
+		if len(data) != 2 || data["billing"] == nil || data["shipping"] == nil {
+			t.Errorf("Expected billing and shipping inputs, got %v", data)
+		}
+		if _, ok := shared.Get(ctx, "aggregate:test-collect:a"); ok {
+			t.Error("Expected completed batch to be removed from the store")
+		}
+	})
+
+	t.Run("collect timeout", func(t *testing.T) {
+		build := func(partial bool) pocket.Node {
+			node, err := (&AggregateNodeBuilder{}).Build(&yaml.NodeDefinition{
+				Name: "test-timeout",
+				Config: map[string]interface{}{
+					"count":   uint64(3),
+					"timeout": "50ms",
+					"partial": partial,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to build aggregate node: %v", err)
+			}
+			return node
+		}
+
+		for _, partial := range []bool{true, false} {
+			node := build(partial)
+			shared := pocket.NewStore()
+			if _, _, err := node.Post(ctx, shared, "first", nil, nil); err != nil {
+				t.Fatalf("Post failed: %v", err)
+			}
+			time.Sleep(100 * time.Millisecond)
+
+			output, next, err := node.Post(ctx, shared, "late", nil, nil)
+			if !partial {
+				if err == nil || !strings.Contains(err.Error(), "timed out") {
+					t.Errorf("Expected timeout error, got %v", err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("Post failed: %v", err)
+			}
+			res := output.(map[string]interface{})
+			if next != "default" || res["complete"] != false || res["count"] != 2 {
+				t.Errorf("Expected partial result, got %s %v", next, res)
+			}
+		}
+	})
+
 	t.Run("invalid mode", func(t *testing.T) {
 		builder := &AggregateNodeBuilder{}
 		def := &yaml.NodeDefinition{
@@ -1433,11 +2399,290 @@ func TestFileNode(t *testing.T) {
 		}
 	})
 
-	t.Run("metadata", func(t *testing.T) {
-		builder := &FileNodeBuilder{}
-		meta := builder.Metadata()
+	t.Run("artifact operations", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, content := range map[string]string{
+			"build/app.log":        "started",
+			"build/nested/err.log": "failed",
+			"build/app.bin":        "binary",
+		} {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
 
-		if meta.Type != "file" {
+		run := func(t *testing.T, config map[string]interface{}) map[string]interface{} {
+			t.Helper()
+			config["base_dir"] = dir
+			node, err := (&FileNodeBuilder{}).Build(&yaml.NodeDefinition{Name: "test-file", Config: config})
+			if err != nil {
+				t.Fatalf("Failed to build file node: %v", err)
+			}
+			result, err := pocket.NewGraph(node, store).Run(ctx, nil)
+			if err != nil {
+				t.Fatalf("Failed to run graph: %v", err)
+			}
+			return result.(map[string]interface{})
+		}
+
+		t.Run("glob", func(t *testing.T) {
+			if res := run(t, map[string]interface{}{"operation": "glob", "path": "build/*.log"}); res["count"] != 1 {
+				t.Errorf("Expected 1 match, got %v", res["files"])
+			}
+			if res := run(t, map[string]interface{}{"operation": "glob", "path": "build/**/*.log"}); res["count"] != 2 {
+				t.Errorf("Expected 2 matches, got %v", res["files"])
+			}
+		})
+
+		t.Run("checksum", func(t *testing.T) {
+			res := run(t, map[string]interface{}{"operation": "checksum", "path": "build/app.bin"})
+			// sha256 of "binary"
+			expected := "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd"
+			if res["checksum"] != expected {
+				t.Errorf("Expected checksum %s, got %v", expected, res["checksum"])
+			}
+		})
+
+		t.Run("copy and move", func(t *testing.T) {
+			run(t, map[string]interface{}{"operation": "copy", "path": "build", "destination": "backup"})
+			if data, err := os.ReadFile(filepath.Join(dir, "backup/nested/err.log")); err != nil || string(data) != "failed" {
+				t.Errorf("Expected directory to be copied, got %q, %v", data, err)
+			}
+
+			res := run(t, map[string]interface{}{
+				"operation":   "move",
+				"path":        "build/app.bin",
+				"destination": "release/app.bin",
+				"create_dirs": true,
+			})
+			if res["size"] != int64(6) {
+				t.Errorf("Expected size 6, got %v", res["size"])
+			}
+			if _, err := os.Stat(filepath.Join(dir, "build/app.bin")); !os.IsNotExist(err) {
+				t.Error("Expected source to be moved")
+			}
+
+			// Existing destinations are kept unless overwrite is set
+			node, err := (&FileNodeBuilder{}).Build(&yaml.NodeDefinition{
+				Name: "test-file",
+				Config: map[string]interface{}{
+					"operation":   "copy",
+					"path":        "build/app.log",
+					"destination": "backup/app.log",
+					"base_dir":    dir,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to build file node: %v", err)
+			}
+			if _, err := pocket.NewGraph(node, store).Run(ctx, nil); err == nil {
+				t.Error("Expected error for existing destination")
+			}
+		})
+
+		t.Run("mkdir and delete", func(t *testing.T) {
+			run(t, map[string]interface{}{"operation": "mkdir", "path": "tmp/a/b"})
+			if info, err := os.Stat(filepath.Join(dir, "tmp/a/b")); err != nil || !info.IsDir() {
+				t.Fatal("Expected directory to be created")
+			}
+
+			if res := run(t, map[string]interface{}{"operation": "delete", "path": "tmp", "recursive": true}); res["deleted"] != true {
+				t.Errorf("Expected deleted, got %v", res)
+			}
+			if res := run(t, map[string]interface{}{"operation": "delete", "path": "tmp"}); res["deleted"] != false {
+				t.Errorf("Expected nothing to delete, got %v", res)
+			}
+		})
+
+		t.Run("destination outside sandbox", func(t *testing.T) {
+			node, err := (&FileNodeBuilder{}).Build(&yaml.NodeDefinition{
+				Name: "test-file",
+				Config: map[string]interface{}{
+					"operation":   "copy",
+					"path":        "build/app.log",
+					"destination": "../escape.log",
+					"base_dir":    dir,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to build file node: %v", err)
+			}
+			if _, err := pocket.NewGraph(node, store).Run(ctx, nil); err == nil {
+				t.Error("Expected error for destination outside base_dir")
+			}
+		})
+	})
+
+	t.Run("binary content", func(t *testing.T) {
+		dir := t.TempDir()
+		binary := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe, 0x01}
+
+		run := func(t *testing.T, config map[string]interface{}, input any) map[string]interface{} {
+			t.Helper()
+			config["base_dir"] = dir
+			node, err := (&FileNodeBuilder{}).Build(&yaml.NodeDefinition{Name: "test-file", Config: config})
+			if err != nil {
+				t.Fatalf("Failed to build file node: %v", err)
+			}
+			result, err := pocket.NewGraph(node, store).Run(ctx, input)
+			if err != nil {
+				t.Fatalf("Failed to run graph: %v", err)
+			}
+			return result.(map[string]interface{})
+		}
+
+		t.Run("base64 round trip", func(t *testing.T) {
+			encoded := base64.StdEncoding.EncodeToString(binary)
+			run(t, map[string]interface{}{
+				"operation": "write",
+				"path":      "image.png",
+				"content":   "{{.data}}",
+				"encoding":  "base64",
+			}, map[string]interface{}{"data": encoded})
+
+			if data, _ := os.ReadFile(filepath.Join(dir, "image.png")); !bytes.Equal(data, binary) {
+				t.Fatalf("Expected binary content to be written intact, got %v", data)
+			}
+
+			res := run(t, map[string]interface{}{"operation": "read", "path": "image.png", "encoding": "auto"}, nil)
+			if res["encoding"] != "base64" || res["content"] != encoded {
+				t.Errorf("Expected base64 content %s, got %v (%v)", encoded, res["content"], res["encoding"])
+			}
+		})
+
+		t.Run("reference", func(t *testing.T) {
+			res := run(t, map[string]interface{}{"operation": "read", "path": "image.png", "reference": true}, nil)
+			if _, ok := res["content"]; ok {
+				t.Error("Expected no content for reference read")
+			}
+			if res["content_type"] != "image/png" || res["size"] != int64(len(binary)) {
+				t.Errorf("Expected image/png metadata, got %v", res)
+			}
+		})
+
+		t.Run("chunks", func(t *testing.T) {
+			if err := os.WriteFile(filepath.Join(dir, "data.txt"), []byte("0123456789"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			config := func() map[string]interface{} {
+				return map[string]interface{}{
+					"operation":  "read",
+					"path":       "data.txt",
+					"chunk_size": 4,
+					"offset":     "{{.next_offset}}",
+				}
+			}
+
+			var chunks []string
+			input := map[string]interface{}{"next_offset": 0}
+			for i := 0; i < 5; i++ {
+				res := run(t, config(), input)
+				chunks = append(chunks, res["content"].(string))
+				if res["eof"] == true {
+					break
+				}
+				input = res
+			}
+			if !reflect.DeepEqual(chunks, []string{"0123", "4567", "89"}) {
+				t.Errorf("Expected three chunks, got %q", chunks)
+			}
+		})
+
+		t.Run("invalid base64", func(t *testing.T) {
+			node, err := (&FileNodeBuilder{}).Build(&yaml.NodeDefinition{
+				Name: "test-file",
+				Config: map[string]interface{}{
+					"operation": "write",
+					"path":      "bad.bin",
+					"content":   "not base64!",
+					"encoding":  "base64",
+					"base_dir":  dir,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to build file node: %v", err)
+			}
+			if _, err := pocket.NewGraph(node, store).Run(ctx, nil); err == nil {
+				t.Error("Expected error for invalid base64 content")
+			}
+		})
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "old.csv"), []byte("a,b"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		run := func(t *testing.T, config map[string]interface{}) map[string]interface{} {
+			t.Helper()
+			config["operation"] = "watch"
+			config["path"] = "."
+			config["base_dir"] = dir
+			config["debounce"] = "50ms"
+			node, err := (&FileNodeBuilder{}).Build(&yaml.NodeDefinition{Name: "test-file", Config: config})
+			if err != nil {
+				t.Fatalf("Failed to build file node: %v", err)
+			}
+			result, err := pocket.NewGraph(node, store).Run(ctx, nil)
+			if err != nil {
+				t.Fatalf("Failed to run graph: %v", err)
+			}
+			return result.(map[string]interface{})
+		}
+
+		t.Run("new files", func(t *testing.T) {
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				_ = os.WriteFile(filepath.Join(dir, "ignored.txt"), []byte("x"), 0o644)
+				_ = os.WriteFile(filepath.Join(dir, "new.csv"), []byte("c,d"), 0o644)
+			}()
+
+			res := run(t, map[string]interface{}{"pattern": "*.csv", "timeout": "5s"})
+			files := res["files"].([]interface{})
+			if len(files) != 1 {
+				t.Fatalf("Expected 1 changed file, got %v", files)
+			}
+			file := files[0].(map[string]interface{})
+			if file["name"] != "new.csv" || file["event"] != "create" {
+				t.Errorf("Expected new.csv to be created, got %v", file)
+			}
+		})
+
+		t.Run("include existing", func(t *testing.T) {
+			res := run(t, map[string]interface{}{"pattern": "old.*", "include_existing": true})
+			if res["count"] != 1 {
+				t.Errorf("Expected existing file, got %v", res["files"])
+			}
+		})
+
+		t.Run("timeout", func(t *testing.T) {
+			res := run(t, map[string]interface{}{"pattern": "*.xml", "timeout": "100ms"})
+			if res["timed_out"] != true || res["count"] != 0 {
+				t.Errorf("Expected timeout without changes, got %v", res)
+			}
+		})
+
+		t.Run("unknown event", func(t *testing.T) {
+			_, err := (&FileNodeBuilder{}).Build(&yaml.NodeDefinition{
+				Name:   "test-file",
+				Config: map[string]interface{}{"operation": "watch", "path": ".", "events": []interface{}{"chmod"}},
+			})
+			if err == nil || !strings.Contains(err.Error(), "unknown watch event") {
+				t.Errorf("Expected unknown watch event error, got %v", err)
+			}
+		})
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		builder := &FileNodeBuilder{}
+		meta := builder.Metadata()
+
+		if meta.Type != "file" {
 			t.Errorf("Expected type 'file', got %s", meta.Type)
 		}
 		if meta.Category != "io" {
@@ -1492,6 +2737,206 @@ func TestExecNode(t *testing.T) {
 		}
 	})
 
+	t.Run("stdin", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			stdin    interface{}
+			input    interface{}
+			expected string
+		}{
+			{name: "raw string input", stdin: true, input: "hello\nworld", expected: "hello\nworld"},
+			{name: "json input", stdin: true, input: map[string]interface{}{"a": 1}, expected: `{"a":1}`},
+			{name: "template", stdin: "Dear {{.name}}", input: map[string]interface{}{"name": "Ada"}, expected: "Dear Ada"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				node, err := (&ExecNodeBuilder{}).Build(&yaml.NodeDefinition{
+					Name:   "test-exec",
+					Config: map[string]interface{}{"command": "cat", "stdin": tt.stdin},
+				})
+				if err != nil {
+					t.Fatalf("Failed to build exec node: %v", err)
+				}
+
+				result, err := pocket.NewGraph(node, store).Run(ctx, tt.input)
+				if err != nil {
+					t.Fatalf("Failed to run graph: %v", err)
+				}
+				if stdout := result.(map[string]interface{})["stdout"]; stdout != tt.expected {
+					t.Errorf("Expected stdout %q, got %q", tt.expected, stdout)
+				}
+			})
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		node, err := (&ExecNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-stream",
+			Config: map[string]interface{}{
+				"command": "sh",
+				"args":    []interface{}{"-c", "echo one; echo two >&2; echo three"},
+				"stream":  true,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build exec node: %v", err)
+		}
+
+		var lines []string
+		streamCtx := WithLineHandler(ctx, func(node, line string) {
+			lines = append(lines, node+": "+line)
+		})
+
+		result, err := pocket.NewGraph(node, store).Run(streamCtx, nil)
+		if err != nil {
+			t.Fatalf("Failed to run graph: %v", err)
+		}
+
+		expected := []string{"test-stream: one", "test-stream: three"}
+		if !reflect.DeepEqual(lines, expected) {
+			t.Errorf("Expected lines %v, got %v", expected, lines)
+		}
+		res := result.(map[string]interface{})
+		if res["stdout"] != "one\nthree\n" {
+			t.Errorf("Expected full stdout, got %q", res["stdout"])
+		}
+		if res["stderr"] != "two\n" {
+			t.Errorf("Expected stderr 'two', got %q", res["stderr"])
+		}
+	})
+
+	t.Run("resource limits", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("resource limits require a Unix shell")
+		}
+
+		node, err := (&ExecNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-limits",
+			Config: map[string]interface{}{
+				"command": "sh",
+				"args":    []interface{}{"-c", "ulimit -t; ulimit -v; ulimit -n"},
+				"limits": map[string]interface{}{
+					"cpu_seconds": uint64(5),
+					"memory":      "512MB",
+					"open_files":  uint64(32),
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build exec node: %v", err)
+		}
+
+		result, err := pocket.NewGraph(node, store).Run(ctx, nil)
+		if err != nil {
+			t.Fatalf("Failed to run graph: %v", err)
+		}
+		if stdout := result.(map[string]interface{})["stdout"]; stdout != "5\n524288\n32\n" {
+			t.Errorf("Expected limits to be applied, got %q", stdout)
+		}
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("POCKET_TEST_TOKEN", "secret")
+		t.Setenv("POCKET_TEST_VISIBLE", "shown")
+
+		tests := []struct {
+			name     string
+			config   map[string]interface{}
+			expected string
+		}{
+			{
+				name:     "deny patterns",
+				config:   map[string]interface{}{"env_deny": []interface{}{"*_TOKEN"}},
+				expected: "[][shown][set]",
+			},
+			{
+				name:     "no inheritance",
+				config:   map[string]interface{}{"inherit_env": false},
+				expected: "[][][set]",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				config := map[string]interface{}{
+					"command": "/bin/sh",
+					"args":    []interface{}{"-c", `printf '[%s][%s][%s]' "$POCKET_TEST_TOKEN" "$POCKET_TEST_VISIBLE" "$EXTRA"`},
+					"env":     map[string]interface{}{"EXTRA": "set"},
+				}
+				for k, v := range tt.config {
+					config[k] = v
+				}
+
+				node, err := (&ExecNodeBuilder{}).Build(&yaml.NodeDefinition{Name: "test-env", Config: config})
+				if err != nil {
+					t.Fatalf("Failed to build exec node: %v", err)
+				}
+
+				result, err := pocket.NewGraph(node, store).Run(ctx, nil)
+				if err != nil {
+					t.Fatalf("Failed to run graph: %v", err)
+				}
+				if stdout := result.(map[string]interface{})["stdout"]; stdout != tt.expected {
+					t.Errorf("Expected %q, got %q", tt.expected, stdout)
+				}
+			})
+		}
+	})
+
+	t.Run("temp dir sandbox", func(t *testing.T) {
+		node, err := (&ExecNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-sandbox",
+			Config: map[string]interface{}{
+				"command": "pwd",
+				"sandbox": map[string]interface{}{"temp_dir": true},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build exec node: %v", err)
+		}
+
+		result, err := pocket.NewGraph(node, store).Run(ctx, nil)
+		if err != nil {
+			t.Fatalf("Failed to run graph: %v", err)
+		}
+
+		dir := strings.TrimSpace(result.(map[string]interface{})["stdout"].(string))
+		if !strings.Contains(filepath.Base(dir), "pocket-exec-") {
+			t.Errorf("Expected a pocket-exec temp dir, got %q", dir)
+		}
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("Expected temp dir %s to be removed", dir)
+		}
+	})
+
+	t.Run("invalid limits", func(t *testing.T) {
+		_, err := (&ExecNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-limits",
+			Config: map[string]interface{}{
+				"command": "true",
+				"limits":  map[string]interface{}{"memory": "lots"},
+			},
+		})
+		if err == nil {
+			t.Error("Expected error for invalid memory limit")
+		}
+	})
+
+	t.Run("limits in a chroot without a shell", func(t *testing.T) {
+		_, err := (&ExecNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "test-limits",
+			Config: map[string]interface{}{
+				"command": "true",
+				"limits":  map[string]interface{}{"open_files": uint64(32)},
+				"sandbox": map[string]interface{}{"chroot": t.TempDir()},
+			},
+		})
+		if err == nil || !strings.Contains(err.Error(), "/bin/sh") {
+			t.Errorf("Expected an error naming the missing shell, got %v", err)
+		}
+	})
+
 	t.Run("command with timeout", func(t *testing.T) {
 		builder := &ExecNodeBuilder{}
 		def := &yaml.NodeDefinition{
@@ -1653,32 +3098,55 @@ func TestParallelNode(t *testing.T) {
 	ctx := context.Background()
 	store := pocket.NewStore()
 
-	t.Run("basic parallel execution", func(t *testing.T) {
-		builder := &ParallelNodeBuilder{}
-		def := &yaml.NodeDefinition{
-			Name: "test-parallel",
-			Config: map[string]interface{}{
-				"tasks": []interface{}{
-					map[string]interface{}{
-						"name":      "task1",
-						"operation": "transform",
-					},
-					map[string]interface{}{
-						"name":      "task2",
-						"operation": "transform",
-					},
-					map[string]interface{}{
-						"name":      "task3",
-						"operation": "transform",
-					},
-				},
-			},
-		}
+	registry := NewRegistry()
+	registry.Register(&DelayNodeBuilder{})
+	registry.Register(&EchoNodeBuilder{})
+	registry.Register(&stubNodeBuilder{
+		nodeType: "fail",
+		exec: func(input any) (any, error) {
+			return nil, fmt.Errorf("task failed")
+		},
+	})
+	registry.Register(&stubNodeBuilder{
+		nodeType: "save",
+		exec: func(input any) (any, error) {
+			return input, nil
+		},
+		storeKey: "saved",
+	})
 
-		node, err := builder.Build(def)
+	build := func(t *testing.T, config map[string]interface{}) pocket.Node {
+		t.Helper()
+		node, err := (&ParallelNodeBuilder{Registry: registry}).Build(&yaml.NodeDefinition{
+			Name:   "test-parallel",
+			Config: config,
+		})
 		if err != nil {
 			t.Fatalf("Failed to build parallel node: %v", err)
 		}
+		return node
+	}
+
+	delayTask := func(name string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":   name,
+			"type":   "delay",
+			"config": map[string]interface{}{"duration": "100ms"},
+		}
+	}
+
+	t.Run("basic parallel execution", func(t *testing.T) {
+		node := build(t, map[string]interface{}{
+			"tasks": []interface{}{
+				delayTask("task1"),
+				delayTask("task2"),
+				map[string]interface{}{
+					"name":   "task3",
+					"type":   "echo",
+					"config": map[string]interface{}{"message": "hello"},
+				},
+			},
+		})
 
 		graph := pocket.NewGraph(node, store)
 		result, err := graph.Run(ctx, "test input")
@@ -1698,43 +3166,25 @@ func TestParallelNode(t *testing.T) {
 		if summary["successful"].(int) != 3 {
 			t.Errorf("Expected 3 successful tasks, got %d", summary["successful"])
 		}
+
+		for _, r := range res["results"].([]interface{}) {
+			r := r.(map[string]interface{})
+			if r["task"] == "task3" && r["result"].(map[string]interface{})["message"] != "hello" {
+				t.Errorf("Expected echo node output, got %v", r["result"])
+			}
+		}
 	})
 
 	t.Run("with concurrency limit", func(t *testing.T) {
-		builder := &ParallelNodeBuilder{Verbose: true}
-		def := &yaml.NodeDefinition{
-			Name: "test-parallel",
-			Config: map[string]interface{}{
-				"tasks": []interface{}{
-					map[string]interface{}{
-						"name":      "task1",
-						"operation": "http_get",
-					},
-					map[string]interface{}{
-						"name":      "task2",
-						"operation": "http_get",
-					},
-					map[string]interface{}{
-						"name":      "task3",
-						"operation": "http_get",
-					},
-					map[string]interface{}{
-						"name":      "task4",
-						"operation": "http_get",
-					},
-					map[string]interface{}{
-						"name":      "task5",
-						"operation": "http_get",
-					},
-				},
-				"max_concurrency": 2,
+		node := build(t, map[string]interface{}{
+			"tasks": []interface{}{
+				delayTask("task1"),
+				delayTask("task2"),
+				delayTask("task3"),
+				delayTask("task4"),
 			},
-		}
-
-		node, err := builder.Build(def)
-		if err != nil {
-			t.Fatalf("Failed to build parallel node: %v", err)
-		}
+			"max_concurrency": uint64(2),
+		})
 
 		start := time.Now()
 		graph := pocket.NewGraph(node, store)
@@ -1744,52 +3194,32 @@ func TestParallelNode(t *testing.T) {
 		}
 		duration := time.Since(start)
 
-		// With concurrency limit of 2, tasks should still run efficiently
-		// The semaphore ensures no more than 2 run at once, but with
-		// goroutines they can still complete quickly
-		if duration > 500*time.Millisecond {
-			t.Errorf("Expected execution to complete within 500ms, got %v", duration)
+		// Two batches of two 100ms delays
+		if duration < 200*time.Millisecond || duration > 500*time.Millisecond {
+			t.Errorf("Expected execution to take about 200ms, got %v", duration)
 		}
 
 		res := result.(map[string]interface{})
 		summary := res["summary"].(map[string]interface{})
-		if summary["successful"].(int) != 5 {
-			t.Errorf("Expected 5 successful tasks, got %d", summary["successful"])
+		if summary["successful"].(int) != 4 {
+			t.Errorf("Expected 4 successful tasks, got %d", summary["successful"])
 		}
 	})
 
 	t.Run("fail fast", func(t *testing.T) {
-		builder := &ParallelNodeBuilder{}
-		def := &yaml.NodeDefinition{
-			Name: "test-parallel",
-			Config: map[string]interface{}{
-				"tasks": []interface{}{
-					map[string]interface{}{
-						"name":      "task1",
-						"operation": "transform",
-					},
-					map[string]interface{}{
-						"name":      "task2",
-						"operation": "error", // This will fail
-					},
-					map[string]interface{}{
-						"name":      "task3",
-						"operation": "transform",
-					},
-				},
-				"fail_fast": true,
+		node := build(t, map[string]interface{}{
+			"tasks": []interface{}{
+				delayTask("task1"),
+				map[string]interface{}{"name": "task2", "type": "fail"},
+				delayTask("task3"),
 			},
-		}
-
-		node, err := builder.Build(def)
-		if err != nil {
-			t.Fatalf("Failed to build parallel node: %v", err)
-		}
+			"fail_fast": true,
+		})
 
 		graph := pocket.NewGraph(node, store)
-		_, err = graph.Run(ctx, nil)
+		_, err := graph.Run(ctx, nil)
 		if err == nil {
-			t.Error("Expected error with fail-fast")
+			t.Fatal("Expected error with fail-fast")
 		}
 		if !strings.Contains(err.Error(), "fail-fast") {
 			t.Errorf("Expected fail-fast error, got: %v", err)
@@ -1797,32 +3227,14 @@ func TestParallelNode(t *testing.T) {
 	})
 
 	t.Run("continue on error", func(t *testing.T) {
-		builder := &ParallelNodeBuilder{}
-		def := &yaml.NodeDefinition{
-			Name: "test-parallel",
-			Config: map[string]interface{}{
-				"tasks": []interface{}{
-					map[string]interface{}{
-						"name":      "task1",
-						"operation": "transform",
-					},
-					map[string]interface{}{
-						"name":      "task2",
-						"operation": "error", // This will fail
-					},
-					map[string]interface{}{
-						"name":      "task3",
-						"operation": "transform",
-					},
-				},
-				"fail_fast": false,
+		node := build(t, map[string]interface{}{
+			"tasks": []interface{}{
+				delayTask("task1"),
+				map[string]interface{}{"name": "task2", "type": "fail"},
+				delayTask("task3"),
 			},
-		}
-
-		node, err := builder.Build(def)
-		if err != nil {
-			t.Fatalf("Failed to build parallel node: %v", err)
-		}
+			"fail_fast": false,
+		})
 
 		graph := pocket.NewGraph(node, store)
 		result, err := graph.Run(ctx, nil)
@@ -1845,6 +3257,78 @@ func TestParallelNode(t *testing.T) {
 		}
 	})
 
+	t.Run("node references and shared store", func(t *testing.T) {
+		graphDef := &yaml.GraphDefinition{
+			Nodes: []yaml.NodeDefinition{
+				{Name: "saver", Type: "save"},
+			},
+		}
+		node, err := (&ParallelNodeBuilder{Registry: registry}).Build(&yaml.NodeDefinition{
+			Name: "test-parallel",
+			Config: map[string]interface{}{
+				"tasks": []interface{}{
+					map[string]interface{}{
+						"name":  "save",
+						"node":  "saver",
+						"input": "user-{{.id}}",
+					},
+				},
+			},
+			Graph: graphDef,
+		})
+		if err != nil {
+			t.Fatalf("Failed to build parallel node: %v", err)
+		}
+
+		shared := pocket.NewStore()
+		if _, err := pocket.NewGraph(node, shared).Run(ctx, map[string]interface{}{"id": 7}); err != nil {
+			t.Fatalf("Failed to run graph: %v", err)
+		}
+		if saved, _ := shared.Get(ctx, "saved"); saved != "user-7" {
+			t.Errorf("Expected task to write to the parent store, got %v", saved)
+		}
+	})
+
+	t.Run("accepts nodes as the former name of tasks", func(t *testing.T) {
+		node, err := (&ParallelNodeBuilder{Registry: registry}).Build(&yaml.NodeDefinition{
+			Name: "test-parallel",
+			Config: map[string]interface{}{
+				"nodes": []interface{}{
+					map[string]interface{}{"name": "save", "type": "save"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build parallel node: %v", err)
+		}
+
+		shared := pocket.NewStore()
+		if _, err := pocket.NewGraph(node, shared).Run(ctx, "value"); err != nil {
+			t.Fatalf("Failed to run graph: %v", err)
+		}
+		if saved, _ := shared.Get(ctx, "saved"); saved != "value" {
+			t.Errorf("Expected task to run, got %v", saved)
+		}
+	})
+
+	t.Run("invalid tasks", func(t *testing.T) {
+		tests := map[string]map[string]interface{}{
+			"unknown reference": {"name": "a", "node": "missing"},
+			"no node":           {"name": "a", "operation": "transform"},
+			"unknown type":      {"name": "a", "type": "missing"},
+		}
+		for name, task := range tests {
+			_, err := (&ParallelNodeBuilder{Registry: registry}).Build(&yaml.NodeDefinition{
+				Name:   "test-parallel",
+				Config: map[string]interface{}{"tasks": []interface{}{task}},
+				Graph:  &yaml.GraphDefinition{},
+			})
+			if err == nil {
+				t.Errorf("%s: expected build error", name)
+			}
+		}
+	})
+
 	t.Run("metadata", func(t *testing.T) {
 		builder := &ParallelNodeBuilder{}
 		meta := builder.Metadata()
@@ -1861,6 +3345,1268 @@ func TestParallelNode(t *testing.T) {
 	})
 }
 
+func TestObjectNode(t *testing.T) {
+	ctx := context.Background()
+
+	builder := &ObjectNodeBuilder{}
+	node, err := builder.Build(&yaml.NodeDefinition{
+		Name: "test-object",
+		Config: map[string]interface{}{
+			"operations": []interface{}{
+				map[string]interface{}{"merge": []interface{}{"$.defaults", "$.user"}},
+				map[string]interface{}{"rename": map[string]interface{}{"name": "profile.display_name"}},
+				map[string]interface{}{"omit": []interface{}{"password", "defaults", "user"}},
+				map[string]interface{}{"delete": "settings.debug"},
+				map[string]interface{}{"set": map[string]interface{}{
+					"profile.source":   "signup",
+					"profile.greeting": "Hello {{.user.name}}",
+				}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build object node: %v", err)
+	}
+
+	input := map[string]interface{}{
+		"defaults": map[string]interface{}{
+			"settings": map[string]interface{}{"theme": "light", "debug": true},
+		},
+		"user": map[string]interface{}{
+			"name":     "Ada",
+			"password": "secret",
+			"settings": map[string]interface{}{"theme": "dark"},
+		},
+	}
+
+	result, err := node.Exec(ctx, input)
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"settings": map[string]interface{}{"theme": "dark"},
+		"profile": map[string]interface{}{
+			"display_name": "Ada",
+			"source":       "signup",
+			"greeting":     "Hello Ada",
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	defaults := input["defaults"].(map[string]interface{})["settings"].(map[string]interface{})
+	if defaults["theme"] != "light" || defaults["debug"] != true {
+		t.Error("Expected input to be left unchanged")
+	}
+
+	t.Run("pick", func(t *testing.T) {
+		node, err := builder.Build(&yaml.NodeDefinition{
+			Name: "pick",
+			Config: map[string]interface{}{
+				"operations": []interface{}{
+					map[string]interface{}{"pick": []interface{}{"user.name", "missing"}},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build object node: %v", err)
+		}
+
+		result, err := node.Exec(ctx, input)
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		expected := map[string]interface{}{"user": map[string]interface{}{"name": "Ada"}}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("unknown operation", func(t *testing.T) {
+		_, err := builder.Build(&yaml.NodeDefinition{
+			Name: "bad-object",
+			Config: map[string]interface{}{
+				"operations": []interface{}{map[string]interface{}{"explode": "x"}},
+			},
+		})
+		if err == nil {
+			t.Error("Expected error for unknown operation")
+		}
+	})
+}
+
+// stoppedClock is a pocket.Clock stopped at a time.
+type stoppedClock struct{ now time.Time }
+
+func (c stoppedClock) Now() time.Time                         { return c.now }
+func (c stoppedClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func TestGenerateNode(t *testing.T) {
+	builder := &GenerateNodeBuilder{}
+	def := &yaml.NodeDefinition{
+		Name: "test-generate",
+		Config: map[string]interface{}{
+			"fields": map[string]interface{}{
+				"id":      map[string]interface{}{"type": "uuid"},
+				"count":   map[string]interface{}{"type": "int", "min": uint64(5), "max": uint64(10)},
+				"ratio":   map[string]interface{}{"type": "float", "min": 0.5, "max": 1.0},
+				"created": map[string]interface{}{"type": "timestamp", "format": "unix"},
+			},
+		},
+	}
+
+	node, err := builder.Build(def)
+	if err != nil {
+		t.Fatalf("Failed to build generate node: %v", err)
+	}
+
+	ctx := context.Background()
+	input := map[string]interface{}{"name": "order"}
+	result, err := node.Exec(ctx, input)
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	output := result.(map[string]interface{})
+	if output["name"] != "order" {
+		t.Errorf("Expected input fields to be kept, got %v", output)
+	}
+
+	id, _ := output["id"].(string)
+	if len(id) != 36 || id[14] != '4' {
+		t.Errorf("Expected version 4 UUID, got %q", id)
+	}
+
+	count := output["count"].(int64)
+	if count < 5 || count > 10 {
+		t.Errorf("Expected count in [5, 10], got %d", count)
+	}
+
+	ratio := output["ratio"].(float64)
+	if ratio < 0.5 || ratio >= 1 {
+		t.Errorf("Expected ratio in [0.5, 1), got %v", ratio)
+	}
+
+	if created := output["created"].(int64); created < time.Now().Add(-time.Minute).Unix() {
+		t.Errorf("Expected current unix timestamp, got %d", created)
+	}
+
+	t.Run("unique ids", func(t *testing.T) {
+		again, err := node.Exec(ctx, input)
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		if again.(map[string]interface{})["id"] == id {
+			t.Error("Expected a new UUID on each run")
+		}
+	})
+
+	t.Run("deterministic runs", func(t *testing.T) {
+		now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+		graph := pocket.NewGraph(node, pocket.NewStore(), pocket.WithDeterminism(7, stoppedClock{now}))
+		first, err := graph.Run(ctx, input)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		second, err := graph.Run(ctx, input)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("Expected the same values on each run, got %v and %v", first, second)
+		}
+		if created := first.(map[string]interface{})["created"]; created != now.Unix() {
+			t.Errorf("Expected timestamp from the run's clock, got %v", created)
+		}
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		_, err := builder.Build(&yaml.NodeDefinition{
+			Name: "bad-generate",
+			Config: map[string]interface{}{
+				"fields": map[string]interface{}{
+					"n": map[string]interface{}{"type": "int", "min": 10, "max": 1},
+				},
+			},
+		})
+		if err == nil {
+			t.Error("Expected error for max below min")
+		}
+	})
+}
+
+func TestDatetimeNode(t *testing.T) {
+	ctx := context.Background()
+	input := map[string]interface{}{
+		"created_at": "2024-03-01T09:00:00Z",
+		"closed_at":  "2024-03-04T21:00:00Z",
+	}
+
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		expected interface{}
+	}{
+		{
+			name: "add days",
+			config: map[string]interface{}{
+				"operation": "add",
+				"value":     "{{.created_at}}",
+				"duration":  "7d",
+				"format":    "2006-01-02",
+			},
+			expected: "2024-03-08",
+		},
+		{
+			name: "subtract duration",
+			config: map[string]interface{}{
+				"operation": "subtract",
+				"value":     "{{.created_at}}",
+				"duration":  "90m",
+			},
+			expected: "2024-03-01T07:30:00Z",
+		},
+		{
+			name: "convert timezone",
+			config: map[string]interface{}{
+				"operation": "convert",
+				"value":     "{{.created_at}}",
+				"timezone":  "Asia/Tokyo",
+			},
+			expected: "2024-03-01T18:00:00+09:00",
+		},
+		{
+			name: "format as unix",
+			config: map[string]interface{}{
+				"operation": "format",
+				"value":     "{{.created_at}}",
+				"format":    "unix",
+			},
+			expected: int64(1709283600),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := &DatetimeNodeBuilder{}
+			node, err := builder.Build(&yaml.NodeDefinition{Name: "test-datetime", Config: tt.config})
+			if err != nil {
+				t.Fatalf("Failed to build datetime node: %v", err)
+			}
+
+			result, err := node.Exec(ctx, input)
+			if err != nil {
+				t.Fatalf("Exec failed: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("diff", func(t *testing.T) {
+		builder := &DatetimeNodeBuilder{}
+		node, err := builder.Build(&yaml.NodeDefinition{
+			Name: "test-datetime",
+			Config: map[string]interface{}{
+				"operation": "diff",
+				"value":     "{{.closed_at}}",
+				"other":     "{{.created_at}}",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build datetime node: %v", err)
+		}
+
+		result, err := node.Exec(ctx, input)
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		diff := result.(map[string]interface{})
+		if diff["days"] != 3.5 {
+			t.Errorf("Expected 3.5 days, got %v", diff["days"])
+		}
+		if diff["hours"] != 84.0 {
+			t.Errorf("Expected 84 hours, got %v", diff["hours"])
+		}
+	})
+
+	t.Run("parse unix input", func(t *testing.T) {
+		builder := &DatetimeNodeBuilder{}
+		node, err := builder.Build(&yaml.NodeDefinition{
+			Name:   "test-datetime",
+			Config: map[string]interface{}{"operation": "parse"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build datetime node: %v", err)
+		}
+
+		result, err := node.Exec(ctx, int64(1709283600))
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		parts := result.(map[string]interface{})
+		if parts["year"] != 2024 || parts["month"] != 3 || parts["weekday"] != "Friday" {
+			t.Errorf("Unexpected components: %v", parts)
+		}
+	})
+
+	t.Run("convert requires timezone", func(t *testing.T) {
+		builder := &DatetimeNodeBuilder{}
+		_, err := builder.Build(&yaml.NodeDefinition{
+			Name:   "bad-datetime",
+			Config: map[string]interface{}{"operation": "convert"},
+		})
+		if err == nil {
+			t.Error("Expected error for convert without timezone")
+		}
+	})
+}
+
+func TestHashNode(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sha256 of string input", func(t *testing.T) {
+		builder := &HashNodeBuilder{}
+		node, err := builder.Build(&yaml.NodeDefinition{Name: "test-hash", Config: map[string]interface{}{}})
+		if err != nil {
+			t.Fatalf("Failed to build hash node: %v", err)
+		}
+
+		result, err := node.Exec(ctx, "hello")
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		expected := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+		if result.(map[string]interface{})["hash"] != expected {
+			t.Errorf("Expected %s, got %v", expected, result.(map[string]interface{})["hash"])
+		}
+	})
+
+	t.Setenv("POCKET_TEST_WEBHOOK_KEY", "secret")
+
+	t.Run("hmac and verify", func(t *testing.T) {
+		signer, err := (&HashNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "sign",
+			Config: map[string]interface{}{
+				"operation": "hmac",
+				"value":     "{{.body}}",
+				"key_env":   "POCKET_TEST_WEBHOOK_KEY",
+				"prefix":    "sha256=",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build hash node: %v", err)
+		}
+
+		input := map[string]interface{}{"body": `{"event":"push"}`}
+		signed, err := signer.Exec(ctx, input)
+		if err != nil {
+			t.Fatalf("Exec failed: %v", err)
+		}
+		signature := signed.(map[string]interface{})["hash"].(string)
+		if !strings.HasPrefix(signature, "sha256=") {
+			t.Errorf("Expected prefixed signature, got %s", signature)
+		}
+
+		verifier, err := (&HashNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "verify",
+			Config: map[string]interface{}{
+				"operation":     "verify",
+				"value":         "{{.body}}",
+				"signature":     "{{.signature}}",
+				"key_env":       "POCKET_TEST_WEBHOOK_KEY",
+				"prefix":        "sha256=",
+				"fail_on_error": true,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build hash node: %v", err)
+		}
+
+		result, err := verifier.Exec(ctx, map[string]interface{}{"body": input["body"], "signature": signature})
+		if err != nil {
+			t.Fatalf("Expected valid signature: %v", err)
+		}
+		if result.(map[string]interface{})["valid"] != true {
+			t.Error("Expected valid to be true")
+		}
+
+		_, err = verifier.Exec(ctx, map[string]interface{}{"body": "tampered", "signature": signature})
+		if err == nil {
+			t.Error("Expected verification to fail for tampered body")
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		_, err := (&HashNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name:   "bad-hash",
+			Config: map[string]interface{}{"operation": "hmac", "key_env": "POCKET_TEST_UNSET_KEY"},
+		})
+		if err == nil {
+			t.Error("Expected error for unset key")
+		}
+	})
+}
+
+func TestCompressNode(t *testing.T) {
+	ctx := context.Background()
+	payload := strings.Repeat("pocket workflow ", 100)
+
+	for _, algorithm := range []string{"gzip", "zstd"} {
+		t.Run(algorithm, func(t *testing.T) {
+			compressor, err := (&CompressNodeBuilder{}).Build(&yaml.NodeDefinition{
+				Name:   "compress",
+				Config: map[string]interface{}{"algorithm": algorithm},
+			})
+			if err != nil {
+				t.Fatalf("Failed to build compress node: %v", err)
+			}
+
+			compressed, err := compressor.Exec(ctx, payload)
+			if err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+			if len(compressed.(string)) >= len(payload) {
+				t.Errorf("Expected compressed output to be smaller than %d bytes, got %d", len(payload), len(compressed.(string)))
+			}
+
+			decompressor, err := (&CompressNodeBuilder{}).Build(&yaml.NodeDefinition{
+				Name:   "decompress",
+				Config: map[string]interface{}{"operation": "decompress", "algorithm": algorithm},
+			})
+			if err != nil {
+				t.Fatalf("Failed to build compress node: %v", err)
+			}
+
+			result, err := decompressor.Exec(ctx, compressed)
+			if err != nil {
+				t.Fatalf("Decompress failed: %v", err)
+			}
+			if result != payload {
+				t.Error("Expected round trip to restore the payload")
+			}
+		})
+	}
+
+	t.Run("file input", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.txt")
+		if err := os.WriteFile(path, []byte(payload), 0o600); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+
+		node, err := (&CompressNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name:   "compress-file",
+			Config: map[string]interface{}{"file": path},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build compress node: %v", err)
+		}
+		if _, err := node.Exec(ctx, nil); err != nil {
+			t.Fatalf("Compress failed: %v", err)
+		}
+	})
+}
+
+func TestEncodeNode(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		encoding string
+		input    string
+		encoded  string
+	}{
+		{encoding: "base64", input: "alice:secret", encoded: "YWxpY2U6c2VjcmV0"},
+		{encoding: "base64url", input: "??>>", encoded: "Pz8-Pg=="},
+		{encoding: "hex", input: "pocket", encoded: "706f636b6574"},
+		{encoding: "url", input: "a&b c", encoded: "a%26b+c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			encoder, err := (&EncodeNodeBuilder{}).Build(&yaml.NodeDefinition{
+				Name:   "encode",
+				Config: map[string]interface{}{"encoding": tt.encoding, "value": "{{.text}}"},
+			})
+			if err != nil {
+				t.Fatalf("Failed to build encode node: %v", err)
+			}
+
+			encoded, err := encoder.Exec(ctx, map[string]interface{}{"text": tt.input})
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if encoded != tt.encoded {
+				t.Errorf("Expected %q, got %q", tt.encoded, encoded)
+			}
+
+			decoder, err := (&EncodeNodeBuilder{}).Build(&yaml.NodeDefinition{
+				Name:   "decode",
+				Config: map[string]interface{}{"operation": "decode", "encoding": tt.encoding},
+			})
+			if err != nil {
+				t.Fatalf("Failed to build encode node: %v", err)
+			}
+
+			decoded, err := decoder.Exec(ctx, encoded)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if decoded != tt.input {
+				t.Errorf("Expected %q, got %q", tt.input, decoded)
+			}
+		})
+	}
+
+	t.Run("invalid input", func(t *testing.T) {
+		decoder, err := (&EncodeNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name:   "decode",
+			Config: map[string]interface{}{"operation": "decode", "encoding": "hex"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build encode node: %v", err)
+		}
+		if _, err := decoder.Exec(ctx, "not hex"); err == nil {
+			t.Error("Expected error decoding invalid hex")
+		}
+	})
+}
+
+func TestJSONDiffAndPatchNodes(t *testing.T) {
+	ctx := context.Background()
+
+	before := map[string]interface{}{
+		"name":  "pocket",
+		"tags":  []interface{}{"go", "graph", "yaml"},
+		"owner": map[string]interface{}{"team": "core", "on/call": "alice"},
+		"stale": true,
+	}
+	after := map[string]interface{}{
+		"name":    "pocket",
+		"tags":    []interface{}{"go", "workflow"},
+		"owner":   map[string]interface{}{"team": "platform", "on/call": "alice"},
+		"version": uint64(2),
+	}
+
+	differ, err := (&JSONDiffNodeBuilder{}).Build(&yaml.NodeDefinition{
+		Name:   "diff",
+		Config: map[string]interface{}{"from": "$.before", "to": "$.after"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build jsondiff node: %v", err)
+	}
+
+	result, err := differ.Exec(ctx, map[string]interface{}{"before": before, "after": after})
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	diff := result.(map[string]interface{})
+	if diff["changed"] != true {
+		t.Fatal("Expected values to differ")
+	}
+
+	patcher, err := (&JSONPatchNodeBuilder{}).Build(&yaml.NodeDefinition{
+		Name:   "patch",
+		Config: map[string]interface{}{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build jsonpatch node: %v", err)
+	}
+
+	patched, err := patcher.Exec(ctx, map[string]interface{}{"document": before, "patch": diff["patch"]})
+	if err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+
+	expected, _ := normalizeJSON(after)
+	if !reflect.DeepEqual(patched, expected) {
+		t.Errorf("Expected patched document %v, got %v", expected, patched)
+	}
+	if before["stale"] != true {
+		t.Error("Expected original document to be left unchanged")
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		result, err := differ.Exec(ctx, map[string]interface{}{"before": before, "after": before})
+		if err != nil {
+			t.Fatalf("Diff failed: %v", err)
+		}
+		if result.(map[string]interface{})["changed"] != false {
+			t.Error("Expected identical values to be unchanged")
+		}
+	})
+
+	t.Run("inline patch", func(t *testing.T) {
+		node, err := (&JSONPatchNodeBuilder{}).Build(&yaml.NodeDefinition{
+			Name: "inline-patch",
+			Config: map[string]interface{}{
+				"document": "$",
+				"patch": []interface{}{
+					map[string]interface{}{"op": "test", "path": "/count", "value": uint64(1)},
+					map[string]interface{}{"op": "copy", "from": "/count", "path": "/previous"},
+					map[string]interface{}{"op": "replace", "path": "/count", "value": uint64(2)},
+					map[string]interface{}{"op": "add", "path": "/items/-", "value": "c"},
+					map[string]interface{}{"op": "move", "from": "/items/0", "path": "/first"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build jsonpatch node: %v", err)
+		}
+
+		result, err := node.Exec(ctx, map[string]interface{}{"count": 1, "items": []interface{}{"a", "b"}})
+		if err != nil {
+			t.Fatalf("Patch failed: %v", err)
+		}
+
+		expected := map[string]interface{}{
+			"count":    2.0,
+			"previous": 1.0,
+			"items":    []interface{}{"b", "c"},
+			"first":    "a",
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %v, got %v", expected, result)
+		}
+	})
+
+	t.Run("failed test op", func(t *testing.T) {
+		_, err := patcher.Exec(ctx, map[string]interface{}{
+			"document": map[string]interface{}{"a": 1},
+			"patch": []interface{}{
+				map[string]interface{}{"op": "test", "path": "/a", "value": 2},
+			},
+		})
+		if err == nil {
+			t.Error("Expected failed test operation to return an error")
+		}
+	})
+}
+
+func TestTryNode(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry()
+	registry.Register(&EchoNodeBuilder{})
+	registry.Register(&ValidateNodeBuilder{})
+	var finallyInputs []interface{}
+	registry.Register(&stubNodeBuilder{
+		nodeType: "record",
+		exec: func(input any) (any, error) {
+			finallyInputs = append(finallyInputs, input)
+			return input, nil
+		},
+	})
+
+	failing := map[string]interface{}{
+		"name": "check",
+		"type": "validate",
+		"config": map[string]interface{}{
+			"schema": map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"id"},
+			},
+			"fail_on_error": true,
+		},
+	}
+	t.Run("success routes default", func(t *testing.T) {
+		builder := &TryNodeBuilder{Registry: registry}
+		node, err := builder.Build(&yaml.NodeDefinition{
+			Name: "test-try",
+			Config: map[string]interface{}{
+				"node": map[string]interface{}{
+					"type":   "echo",
+					"config": map[string]interface{}{"message": "ok"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build try node: %v", err)
+		}
+
+		store := pocket.NewStore()
+		input := map[string]interface{}{"id": 1}
+		output, next, err := node.Post(ctx, store, input, input, input)
+		if err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+		if next != "default" {
+			t.Errorf("Expected route 'default', got '%s'", next)
+		}
+		if output.(map[string]interface{})["message"] != "ok" {
+			t.Errorf("Expected wrapped node output, got %v", output)
+		}
+	})
+
+	t.Run("failure routes catch", func(t *testing.T) {
+		builder := &TryNodeBuilder{Registry: registry}
+		node, err := builder.Build(&yaml.NodeDefinition{
+			Name: "test-try",
+			Config: map[string]interface{}{
+				"node":  failing,
+				"catch": "recover",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build try node: %v", err)
+		}
+
+		store := pocket.NewStore()
+		input := map[string]interface{}{"name": "missing id"}
+		output, next, err := node.Post(ctx, store, input, input, input)
+		if err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+		if next != "recover" {
+			t.Errorf("Expected route 'recover', got '%s'", next)
+		}
+
+		result := output.(map[string]interface{})
+		errInfo, ok := result["error"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected error in payload, got %v", output)
+		}
+		if errInfo["node"] != "check" {
+			t.Errorf("Expected failing node 'check', got %v", errInfo["node"])
+		}
+		if !strings.Contains(errInfo["message"].(string), "validation failed") {
+			t.Errorf("Unexpected error message: %v", errInfo["message"])
+		}
+		if result["input"].(map[string]interface{})["name"] != "missing id" {
+			t.Errorf("Expected original input in payload, got %v", result["input"])
+		}
+	})
+
+	t.Run("runs referenced node", func(t *testing.T) {
+		builder := &TryNodeBuilder{Registry: registry}
+		node, err := builder.Build(&yaml.NodeDefinition{
+			Name: "test-try",
+			Config: map[string]interface{}{
+				"node": "check",
+			},
+			Graph: &yaml.GraphDefinition{
+				Nodes: []yaml.NodeDefinition{{
+					Name:   "check",
+					Type:   failing["type"].(string),
+					Config: failing["config"].(map[string]interface{}),
+				}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build try node: %v", err)
+		}
+
+		input := map[string]interface{}{"name": "missing id"}
+		output, next, err := node.Post(ctx, pocket.NewStore(), input, input, input)
+		if err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+		if next != "catch" {
+			t.Errorf("Expected route 'catch', got '%s'", next)
+		}
+		if errInfo := output.(map[string]interface{})["error"].(map[string]interface{}); errInfo["node"] != "check" {
+			t.Errorf("Expected failing node 'check', got %v", errInfo["node"])
+		}
+	})
+
+	t.Run("finally route always runs", func(t *testing.T) {
+		builder := &TryNodeBuilder{Registry: registry}
+		recorder, err := registry.Build(&yaml.NodeDefinition{Name: "log", Type: "record"})
+		if err != nil {
+			t.Fatalf("Failed to build record node: %v", err)
+		}
+
+		for _, body := range []interface{}{failing, map[string]interface{}{"type": "echo"}} {
+			node, err := builder.Build(&yaml.NodeDefinition{
+				Name: "test-try",
+				Config: map[string]interface{}{
+					"node":    body,
+					"finally": "cleanup",
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to build try node: %v", err)
+			}
+			node.Connect("cleanup", recorder)
+
+			store := pocket.NewStore()
+			input := map[string]interface{}{"name": "test"}
+			if _, _, err := node.Post(ctx, store, input, input, input); err != nil {
+				t.Fatalf("Post failed: %v", err)
+			}
+		}
+
+		if len(finallyInputs) != 2 {
+			t.Fatalf("Expected finally to run twice, ran %d times", len(finallyInputs))
+		}
+		if _, ok := finallyInputs[0].(map[string]interface{})["error"]; !ok {
+			t.Error("Expected finally payload to include the error after a failure")
+		}
+		if _, ok := finallyInputs[1].(map[string]interface{})["error"]; ok {
+			t.Error("Expected no error in finally payload after success")
+		}
+	})
+
+	t.Run("workflow routes", func(t *testing.T) {
+		loader := yaml.NewLoader()
+		RegisterAll(loader, false)
+		record := &stubNodeBuilder{nodeType: "record", exec: func(input any) (any, error) {
+			return input, nil
+		}, storeKey: "finally"}
+		loader.RegisterNodeType("record", record.Build)
+
+		def := &yaml.GraphDefinition{
+			Name:  "safe-check",
+			Start: "safe",
+			Nodes: []yaml.NodeDefinition{
+				{Name: "check", Type: failing["type"].(string), Config: failing["config"].(map[string]interface{})},
+				{Name: "safe", Type: "try", Config: map[string]interface{}{"node": "check"}},
+				{Name: "handle", Type: "echo", Config: map[string]interface{}{"message": "handled"}},
+				{Name: "log", Type: "record"},
+			},
+			Connections: []yaml.Connection{
+				{From: "safe", To: "handle", Action: "catch"},
+				{From: "safe", To: "log", Action: "finally"},
+			},
+		}
+
+		store := pocket.NewStore()
+		graph, err := loader.LoadDefinition(def, store)
+		if err != nil {
+			t.Fatalf("Failed to load workflow: %v", err)
+		}
+		output, err := graph.Run(ctx, map[string]interface{}{"name": "missing id"})
+		if err != nil {
+			t.Fatalf("Failed to run workflow: %v", err)
+		}
+		if output.(map[string]interface{})["message"] != "handled" {
+			t.Errorf("Expected catch route to run, got %v", output)
+		}
+		if payload, ok := store.Get(ctx, "finally"); !ok || payload.(map[string]interface{})["error"] == nil {
+			t.Errorf("Expected finally route to run with the error, got %v", payload)
+		}
+	})
+
+	t.Run("invalid finally", func(t *testing.T) {
+		builder := &TryNodeBuilder{Registry: registry}
+		for _, finally := range []interface{}{map[string]interface{}{"type": "record"}, "catch", "default"} {
+			_, err := builder.Build(&yaml.NodeDefinition{
+				Name: "bad-try",
+				Config: map[string]interface{}{
+					"node":    map[string]interface{}{"type": "echo"},
+					"finally": finally,
+				},
+			})
+			if err == nil {
+				t.Errorf("Expected error for finally %v", finally)
+			}
+		}
+	})
+
+	t.Run("requires node", func(t *testing.T) {
+		builder := &TryNodeBuilder{Registry: registry}
+		_, err := builder.Build(&yaml.NodeDefinition{
+			Name:   "bad-try",
+			Config: map[string]interface{}{},
+		})
+		if err == nil {
+			t.Error("Expected error for missing node")
+		}
+	})
+}
+
+func TestExperimentNode(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry()
+	registry.Register(&EchoNodeBuilder{})
+	registry.Register(&stubNodeBuilder{
+		nodeType: "fail",
+		exec: func(input any) (any, error) {
+			return nil, fmt.Errorf("arm failed")
+		},
+	})
+
+	arm := func(name string, weight float64, message string) map[string]interface{} {
+		return map[string]interface{}{
+			"name":   name,
+			"weight": weight,
+			"cost":   0.5,
+			"node": map[string]interface{}{
+				"type":   "echo",
+				"config": map[string]interface{}{"message": message},
+			},
+		}
+	}
+
+	t.Run("records outcomes per arm", func(t *testing.T) {
+		builder := &ExperimentNodeBuilder{Registry: registry}
+		node, err := builder.Build(&yaml.NodeDefinition{
+			Name: "test-experiment",
+			Config: map[string]interface{}{
+				"experiment": "greeting",
+				"arms":       []interface{}{arm("short", 1, "hi"), arm("long", 1, "hello there")},
+				"assign_by":  "{{.user}}",
+				"score":      "{{len .output.message}}",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build experiment node: %v", err)
+		}
+
+		store := pocket.NewStore()
+		assigned := make(map[string]string)
+		for i := 0; i < 20; i++ {
+			user := fmt.Sprintf("user-%d", i%5)
+			input := map[string]interface{}{"user": user}
+			output, next, err := node.Post(ctx, store, input, input, input)
+			if err != nil {
+				t.Fatalf("Post failed: %v", err)
+			}
+			if next != "default" {
+				t.Errorf("Expected route 'default', got '%s'", next)
+			}
+			message := output.(map[string]interface{})["message"].(string)
+			if prev, ok := assigned[user]; ok && prev != message {
+				t.Errorf("Expected %s to stay on one arm, got %q and %q", user, prev, message)
+			}
+			assigned[user] = message
+		}
+
+		report, ok := ReadExperimentReport(ctx, store, "greeting")
+		if !ok {
+			t.Fatal("Expected an experiment report")
+		}
+		if len(report.Arms) != 2 || report.Arms[0].Name != "short" || report.Arms[1].Name != "long" {
+			t.Fatalf("Expected arms in config order, got %+v", report.Arms)
+		}
+		runs := 0
+		for _, a := range report.Arms {
+			runs += a.Runs
+			if a.Runs == 0 {
+				continue
+			}
+			if a.MeanCost != 0.5 || a.TotalCost != 0.5*float64(a.Runs) {
+				t.Errorf("Arm %s: expected cost 0.5 per run, got mean %v total %v", a.Name, a.MeanCost, a.TotalCost)
+			}
+			if a.Scored != a.Runs {
+				t.Errorf("Arm %s: expected every run scored, got %d of %d", a.Name, a.Scored, a.Runs)
+			}
+		}
+		if runs != 20 {
+			t.Errorf("Expected 20 runs recorded, got %d", runs)
+		}
+		if report.Arms[0].Runs > 0 && report.Arms[0].MeanScore != 2 {
+			t.Errorf("Expected short arm score 2, got %v", report.Arms[0].MeanScore)
+		}
+	})
+
+	t.Run("weights split traffic", func(t *testing.T) {
+		builder := &ExperimentNodeBuilder{Registry: registry}
+		node, err := builder.Build(&yaml.NodeDefinition{
+			Name: "weighted",
+			Config: map[string]interface{}{
+				"arms": []interface{}{arm("on", 1, "on"), arm("off", 0, "off")},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build experiment node: %v", err)
+		}
+
+		store := pocket.NewStore()
+		for i := 0; i < 10; i++ {
+			if _, _, err := node.Post(ctx, store, nil, nil, nil); err != nil {
+				t.Fatalf("Post failed: %v", err)
+			}
+		}
+		report, _ := ReadExperimentReport(ctx, store, "weighted")
+		if report.Arms[0].Runs != 10 || report.Arms[1].Runs != 0 {
+			t.Errorf("Expected all runs on the weighted arm, got %+v", report.Arms)
+		}
+	})
+
+	t.Run("failures are recorded", func(t *testing.T) {
+		builder := &ExperimentNodeBuilder{Registry: registry}
+		failing := map[string]interface{}{"name": "broken", "node": map[string]interface{}{"type": "fail"}}
+		node, err := builder.Build(&yaml.NodeDefinition{
+			Name: "failing",
+			Config: map[string]interface{}{
+				"arms": []interface{}{failing, arm("unused", 0, "")},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to build experiment node: %v", err)
+		}
+
+		store := pocket.NewStore()
+		if _, _, err := node.Post(ctx, store, nil, nil, nil); err == nil || !strings.Contains(err.Error(), "arm broken") {
+			t.Errorf("Expected arm error, got %v", err)
+		}
+		report, _ := ReadExperimentReport(ctx, store, "failing")
+		if report.Arms[0].Failures != 1 {
+			t.Errorf("Expected 1 failure, got %d", report.Arms[0].Failures)
+		}
+	})
+
+	t.Run("invalid configurations", func(t *testing.T) {
+		builder := &ExperimentNodeBuilder{Registry: registry}
+		for name, config := range map[string]map[string]interface{}{
+			"one arm":         {"arms": []interface{}{arm("a", 1, "")}},
+			"duplicate arm":   {"arms": []interface{}{arm("a", 1, ""), arm("a", 1, "")}},
+			"zero weights":    {"arms": []interface{}{arm("a", 0, ""), arm("b", 0, "")}},
+			"negative weight": {"arms": []interface{}{arm("a", -1, ""), arm("b", 1, "")}},
+		} {
+			if _, err := builder.Build(&yaml.NodeDefinition{Name: "bad", Config: config}); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+}
+
+func TestEvaluateNode(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry()
+	var judged []interface{}
+	registry.Register(&stubNodeBuilder{
+		nodeType: "judge",
+		exec: func(input any) (any, error) {
+			judged = append(judged, input)
+			return map[string]interface{}{"grade": 0.5, "why": "too vague"}, nil
+		},
+	})
+
+	evaluate := func(t *testing.T, config map[string]interface{}, input any) (map[string]interface{}, string) {
+		t.Helper()
+		builder := &EvaluateNodeBuilder{Registry: registry}
+		node, err := builder.Build(&yaml.NodeDefinition{Name: "test-evaluate", Config: config})
+		if err != nil {
+			t.Fatalf("Failed to build evaluate node: %v", err)
+		}
+		output, next, err := node.Post(ctx, pocket.NewStore(), input, input, input)
+		if err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+		return output.(map[string]interface{}), next
+	}
+
+	t.Run("heuristics", func(t *testing.T) {
+		config := map[string]interface{}{
+			"target": "{{.answer}}",
+			"checks": []interface{}{
+				map[string]interface{}{"json": true},
+				map[string]interface{}{"name": "polite", "not_regex": "(?i)sorry"},
+				map[string]interface{}{"length": map[string]interface{}{"min": 2, "max": 20}},
+			},
+		}
+
+		result, next := evaluate(t, config, map[string]interface{}{"answer": `{"ok": true}`})
+		if result["score"] != 1.0 || next != "pass" {
+			t.Errorf("Expected score 1 routed to pass, got %v via %s", result["score"], next)
+		}
+		if len(result["feedback"].([]interface{})) != 0 {
+			t.Errorf("Expected no feedback, got %v", result["feedback"])
+		}
+
+		result, next = evaluate(t, config, map[string]interface{}{"answer": "Sorry, I can't help with that"})
+		if result["score"] != 0.0 || next != "fail" {
+			t.Errorf("Expected score 0 routed to fail, got %v via %s", result["score"], next)
+		}
+		feedback := result["feedback"].([]interface{})
+		if len(feedback) != 3 || feedback[1] != "polite: matches (?i)sorry" {
+			t.Errorf("Unexpected feedback: %v", feedback)
+		}
+	})
+
+	t.Run("judge with tiers", func(t *testing.T) {
+		result, next := evaluate(t, map[string]interface{}{
+			"checks": []interface{}{
+				map[string]interface{}{
+					"name":   "quality",
+					"weight": 3,
+					"judge":  map[string]interface{}{"type": "judge"},
+					"score":  "{{.grade}}",
+					"reason": "{{.why}}",
+				},
+				map[string]interface{}{"regex": "answer"},
+			},
+			"thresholds": []interface{}{
+				map[string]interface{}{"min": 0.9, "route": "publish"},
+				map[string]interface{}{"min": 0.6, "route": "review"},
+			},
+			"else": "regenerate",
+		}, "an answer")
+
+		// (0.5*3 + 1) / 4
+		if result["score"] != 0.625 || next != "review" {
+			t.Errorf("Expected score 0.625 routed to review, got %v via %s", result["score"], next)
+		}
+		if feedback := result["feedback"].([]interface{}); len(feedback) != 1 || feedback[0] != "quality: too vague" {
+			t.Errorf("Unexpected feedback: %v", feedback)
+		}
+		if len(judged) != 1 || judged[0].(map[string]interface{})["text"] != "an answer" {
+			t.Errorf("Expected judge to receive the text, got %v", judged)
+		}
+	})
+
+	t.Run("invalid configurations", func(t *testing.T) {
+		builder := &EvaluateNodeBuilder{Registry: registry}
+		for name, checks := range map[string][]interface{}{
+			"no checks":     {},
+			"no kind":       {map[string]interface{}{"name": "empty"}},
+			"two kinds":     {map[string]interface{}{"json": true, "regex": "x"}},
+			"bad pattern":   {map[string]interface{}{"regex": "("}},
+			"unknown judge": {map[string]interface{}{"judge": map[string]interface{}{"type": "missing"}}},
+		} {
+			_, err := builder.Build(&yaml.NodeDefinition{Name: "bad", Config: map[string]interface{}{"checks": checks}})
+			if err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+}
+
+func TestGuardNode(t *testing.T) {
+	ctx := context.Background()
+	registry := NewRegistry()
+	registry.Register(&stubNodeBuilder{
+		nodeType: "moderate",
+		exec: func(input any) (any, error) {
+			text := input.(map[string]interface{})["text"].(string)
+			return map[string]interface{}{"flagged": strings.Contains(text, "attack"), "category": "violence"}, nil
+		},
+	})
+
+	guard := func(t *testing.T, config map[string]interface{}, input any) (any, string) {
+		t.Helper()
+		builder := &GuardNodeBuilder{Registry: registry}
+		node, err := builder.Build(&yaml.NodeDefinition{Name: "test-guard", Config: config})
+		if err != nil {
+			t.Fatalf("Failed to build guard node: %v", err)
+		}
+		output, next, err := node.Post(ctx, pocket.NewStore(), input, input, input)
+		if err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+		return output, next
+	}
+
+	t.Run("redacts PII", func(t *testing.T) {
+		output, next := guard(t, map[string]interface{}{
+			"checks": []interface{}{
+				map[string]interface{}{"pii": []interface{}{"email", "ssn"}},
+				map[string]interface{}{"name": "api-key", "pattern": `sk-[a-z0-9]{8}`, "replacement": "[KEY]"},
+			},
+		}, map[string]interface{}{
+			"prompt": "Mail jo@example.com, SSN 123-45-6789",
+			"notes":  []interface{}{"key sk-abcd1234", 42},
+		})
+
+		if next != "default" {
+			t.Errorf("Expected route 'default', got '%s'", next)
+		}
+		result := output.(map[string]interface{})
+		if result["prompt"] != "Mail [REDACTED], SSN [REDACTED]" {
+			t.Errorf("Unexpected prompt: %v", result["prompt"])
+		}
+		notes := result["notes"].([]interface{})
+		if notes[0] != "key [KEY]" || notes[1] != 42 {
+			t.Errorf("Unexpected notes: %v", notes)
+		}
+	})
+
+	t.Run("blocks and routes", func(t *testing.T) {
+		config := map[string]interface{}{
+			"checks": []interface{}{
+				map[string]interface{}{
+					"moderation": map[string]interface{}{"type": "moderate"},
+					"reason":     "{{.category}}",
+				},
+				map[string]interface{}{"max_length": 10},
+			},
+			"block_route": "refuse",
+		}
+
+		output, next := guard(t, config, "plan an attack")
+		if next != "refuse" {
+			t.Fatalf("Expected route 'refuse', got '%s'", next)
+		}
+		result := output.(map[string]interface{})
+		if result["input"] != "plan an attack" {
+			t.Errorf("Expected original input, got %v", result["input"])
+		}
+		violations := result["violations"].([]interface{})
+		if len(violations) != 2 || violations[0].(map[string]interface{})["message"] != "violence" {
+			t.Errorf("Unexpected violations: %v", violations)
+		}
+
+		output, next = guard(t, config, "a long but peaceful text")
+		if next != "default" || output != "a long but" {
+			t.Errorf("Expected truncated text via default, got %v via %s", output, next)
+		}
+	})
+
+	t.Run("annotates", func(t *testing.T) {
+		output, _ := guard(t, map[string]interface{}{
+			"action": "annotate",
+			"checks": []interface{}{
+				map[string]interface{}{"words": []interface{}{"darn"}},
+			},
+		}, map[string]interface{}{"text": "Darn it, darned thing"})
+
+		result := output.(map[string]interface{})
+		if result["text"] != "Darn it, darned thing" {
+			t.Errorf("Expected text unchanged, got %v", result["text"])
+		}
+		annotations := result["guard"].([]interface{})
+		if len(annotations) != 1 || annotations[0].(map[string]interface{})["count"] != 1 {
+			t.Errorf("Expected one whole-word match, got %v", annotations)
+		}
+	})
+
+	t.Run("invalid configurations", func(t *testing.T) {
+		builder := &GuardNodeBuilder{Registry: registry}
+		for name, checks := range map[string][]interface{}{
+			"no checks":           {},
+			"unknown PII":         {map[string]interface{}{"pii": []interface{}{"dna"}}},
+			"two kinds":           {map[string]interface{}{"pii": true, "max_length": 5}},
+			"unknown action":      {map[string]interface{}{"pii": true, "action": "shred"}},
+			"redacted moderation": {map[string]interface{}{"moderation": map[string]interface{}{"type": "moderate"}, "action": "redact"}},
+		} {
+			_, err := builder.Build(&yaml.NodeDefinition{Name: "bad", Config: map[string]interface{}{"checks": checks}})
+			if err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+}
+
+// stubNodeBuilder builds nodes from an exec function for tests.
+type stubNodeBuilder struct {
+	nodeType string
+	exec     func(input any) (any, error)
+	storeKey string // If set, Post saves the output under this key
+}
+
+func (b *stubNodeBuilder) Metadata() Metadata {
+	return Metadata{Type: b.nodeType}
+}
+
+func (b *stubNodeBuilder) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	return pocket.NewNode[any, any](def.Name, pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			return b.exec(input)
+		},
+		Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+			if b.storeKey != "" {
+				if err := store.Set(ctx, b.storeKey, exec); err != nil {
+					return nil, "", err
+				}
+			}
+			return exec, "default", nil
+		},
+	}), nil
+}
+
 func TestLuaNode(t *testing.T) {
 	ctx := context.Background()
 	store := pocket.NewStore()
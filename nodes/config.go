@@ -0,0 +1,339 @@
+package nodes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeConfig decodes a node's config into the struct out points to, so
+// builders can declare their config as a type instead of reading the map
+// by hand. Keys are matched to fields by their yaml tag, or the lowercased
+// field name. Fields keep their value when the key is missing, so set
+// defaults on the struct before decoding.
+//
+// Decoding is strict: keys that match no field are errors, so a misspelled
+// key fails the build instead of silently using a default. Fields are
+// checked against their validate tags, a comma-separated list of rules:
+//
+//   - required: the key must be present
+//   - min=N, max=N: bounds on numbers, or on the length of strings, slices,
+//     and maps
+//   - oneof=a b c: the value must be one of the space-separated values
+//
+// Strings decode into time.Duration fields with time.ParseDuration, and
+// nested structs, pointers, slices, and maps are decoded recursively. All
+// problems are reported together.
+//
+// Example:
+//
+//	cfg := struct {
+//		Operation string        `yaml:"operation" validate:"oneof=compress decompress"`
+//		Timeout   time.Duration `yaml:"timeout" validate:"min=1"`
+//		URL       string        `yaml:"url" validate:"required"`
+//	}{Operation: "compress", Timeout: 30 * time.Second}
+//	if err := DecodeConfig(def.Config, &cfg); err != nil {
+//		return nil, err
+//	}
+func DecodeConfig(config map[string]interface{}, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode config: want a pointer to a struct, got %T", out)
+	}
+
+	var errs []error
+	decodeStruct(config, v.Elem(), "", &errs)
+	return errors.Join(errs...)
+}
+
+// configField is a struct field and the config key it decodes from.
+type configField struct {
+	key   string
+	value reflect.Value
+	rules string
+}
+
+// configFields returns the fields of a struct that config keys decode into.
+func configFields(v reflect.Value) []configField {
+	t := v.Type()
+	fields := make([]configField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+		fields = append(fields, configField{key: key, value: v.Field(i), rules: field.Tag.Get("validate")})
+	}
+	return fields
+}
+
+func decodeStruct(config map[string]interface{}, v reflect.Value, path string, errs *[]error) {
+	fields := configFields(v)
+	known := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		known[f.key] = true
+	}
+
+	// Report unknown keys in a stable order
+	unknown := make([]string, 0)
+	for key := range config {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		err := fmt.Errorf("%s: unknown config key", configPath(path, key))
+		if suggestion := closestKey(key, fields); suggestion != "" {
+			err = fmt.Errorf("%w (did you mean %q?)", err, suggestion)
+		}
+		*errs = append(*errs, err)
+	}
+
+	for _, f := range fields {
+		fieldPath := configPath(path, f.key)
+		raw, present := config[f.key]
+		if present && raw != nil {
+			if err := decodeValue(raw, f.value, fieldPath, errs); err != nil {
+				*errs = append(*errs, err)
+				continue
+			}
+		}
+		if err := checkRules(f.value, f.rules, present && raw != nil); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", fieldPath, err))
+		}
+	}
+}
+
+// decodeValue stores raw in v. Errors in nested values are added to errs;
+// an error for raw itself is returned.
+func decodeValue(raw interface{}, v reflect.Value, path string, errs *[]error) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := decodeValue(raw, elem.Elem(), path, errs); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("%s: want a duration string like \"30s\", got %T", path, raw)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		rv := reflect.ValueOf(raw)
+		if !rv.Type().AssignableTo(v.Type()) {
+			return fmt.Errorf("%s: want %v, got %T", path, v.Type(), raw)
+		}
+		v.Set(rv)
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("%s: want a string, got %T", path, raw)
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return fmt.Errorf("%s: want a boolean, got %T", path, raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := toFloat(raw)
+		if !ok || f != math.Trunc(f) {
+			return fmt.Errorf("%s: want an integer, got %v", path, raw)
+		}
+		if v.OverflowInt(int64(f)) {
+			return fmt.Errorf("%s: %v is out of range", path, raw)
+		}
+		v.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, ok := toFloat(raw)
+		if !ok || f != math.Trunc(f) || f < 0 {
+			return fmt.Errorf("%s: want a non-negative integer, got %v", path, raw)
+		}
+		if v.OverflowUint(uint64(f)) {
+			return fmt.Errorf("%s: %v is out of range", path, raw)
+		}
+		v.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat(raw)
+		if !ok {
+			return fmt.Errorf("%s: want a number, got %T", path, raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items, ok := raw.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want a list, got %T", path, raw)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				*errs = append(*errs, err)
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%s: unsupported map key type %v", path, v.Type().Key())
+		}
+		entries, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want an object, got %T", path, raw)
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(entries))
+		for key, entry := range entries {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeValue(entry, elem, configPath(path, key), errs); err != nil {
+				*errs = append(*errs, err)
+				continue
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		v.Set(m)
+	case reflect.Struct:
+		entries, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want an object, got %T", path, raw)
+		}
+		decodeStruct(entries, v, path, errs)
+	default:
+		return fmt.Errorf("%s: unsupported field type %v", path, v.Type())
+	}
+	return nil
+}
+
+// checkRules checks a decoded value against its validate tag.
+func checkRules(v reflect.Value, rules string, present bool) error {
+	if rules == "" {
+		return nil
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			if !present {
+				return errors.New("is required")
+			}
+		case "min", "max":
+			if !present {
+				continue
+			}
+			bound, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s rule %q", name, arg)
+			}
+			size, unit := ruleSize(v)
+			if name == "min" && size < bound {
+				return fmt.Errorf("must be at least %s%s, got %v", arg, unit, formatSize(size))
+			}
+			if name == "max" && size > bound {
+				return fmt.Errorf("must be at most %s%s, got %v", arg, unit, formatSize(size))
+			}
+		case "oneof":
+			if !present {
+				continue
+			}
+			value := fmt.Sprint(reflect.Indirect(v).Interface())
+			options := strings.Fields(arg)
+			found := false
+			for _, option := range options {
+				if option == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("must be one of %s, got %q", strings.Join(options, ", "), value)
+			}
+		default:
+			return fmt.Errorf("unknown validate rule %q", name)
+		}
+	}
+	return nil
+}
+
+// ruleSize returns the number that min and max compare: a number's value,
+// or the length of a string, slice, or map.
+func ruleSize(v reflect.Value) (size float64, unit string) {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " in length"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	return 0, ""
+}
+
+func formatSize(size float64) string {
+	return strconv.FormatFloat(size, 'f', -1, 64)
+}
+
+func configPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closestKey returns the field key nearest to a misspelled key, if any is
+// close enough to be a likely typo.
+func closestKey(key string, fields []configField) string {
+	best, bestDistance := "", len(key)/2+1
+	for _, f := range fields {
+		if d := editDistance(strings.ToLower(key), f.key); d < bestDistance {
+			best, bestDistance = f.key, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
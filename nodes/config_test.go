@@ -0,0 +1,150 @@
+package nodes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket/yaml"
+)
+
+type testRetryConfig struct {
+	Attempts int           `yaml:"attempts" validate:"min=1,max=10"`
+	Delay    time.Duration `yaml:"delay"`
+}
+
+type testConfig struct {
+	URL     string            `yaml:"url" validate:"required"`
+	Method  string            `yaml:"method" validate:"oneof=GET POST"`
+	Timeout time.Duration     `yaml:"timeout"`
+	Verbose bool              `yaml:"verbose"`
+	Ratio   float64           `yaml:"ratio"`
+	Tags    []string          `yaml:"tags" validate:"max=2"`
+	Headers map[string]string `yaml:"headers"`
+	Retry   *testRetryConfig  `yaml:"retry"`
+	Extra   interface{}       `yaml:"extra"`
+	Skipped string            `yaml:"-"`
+}
+
+func TestDecodeConfig(t *testing.T) {
+	t.Run("decodes values", func(t *testing.T) {
+		cfg := testConfig{Method: "GET", Timeout: time.Second}
+		err := DecodeConfig(map[string]interface{}{
+			"url":     "https://example.com",
+			"method":  "POST",
+			"verbose": true,
+			"ratio":   1,
+			"tags":    []interface{}{"a", "b"},
+			"headers": map[string]interface{}{"Accept": "text/plain"},
+			"retry":   map[string]interface{}{"attempts": uint64(3), "delay": "2s"},
+			"extra":   []interface{}{1, "two"},
+		}, &cfg)
+		if err != nil {
+			t.Fatalf("DecodeConfig failed: %v", err)
+		}
+
+		want := testConfig{
+			URL:     "https://example.com",
+			Method:  "POST",
+			Timeout: time.Second,
+			Verbose: true,
+			Ratio:   1,
+			Tags:    []string{"a", "b"},
+			Headers: map[string]string{"Accept": "text/plain"},
+			Retry:   &testRetryConfig{Attempts: 3, Delay: 2 * time.Second},
+			Extra:   []interface{}{1, "two"},
+		}
+		if !reflect.DeepEqual(cfg, want) {
+			t.Errorf("Expected %+v, got %+v", want, cfg)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		errs   []string
+	}{
+		{
+			name:   "misspelled key",
+			config: map[string]interface{}{"url": "x", "timout": "5s"},
+			errs:   []string{`timout: unknown config key (did you mean "timeout"?)`},
+		},
+		{
+			name:   "missing required key",
+			config: map[string]interface{}{"method": "GET"},
+			errs:   []string{"url: is required"},
+		},
+		{
+			name:   "value not allowed",
+			config: map[string]interface{}{"url": "x", "method": "DELETE"},
+			errs:   []string{`method: must be one of GET, POST, got "DELETE"`},
+		},
+		{
+			name: "wrong types",
+			config: map[string]interface{}{
+				"url":     42,
+				"timeout": 5,
+				"tags":    []interface{}{"a", 1},
+			},
+			errs: []string{
+				"url: want a string, got int",
+				`timeout: want a duration string like "30s", got int`,
+				"tags[1]: want a string, got int",
+			},
+		},
+		{
+			name: "nested problems",
+			config: map[string]interface{}{
+				"url":   "x",
+				"retry": map[string]interface{}{"attempts": 20, "delya": "1s"},
+			},
+			errs: []string{
+				`retry.delya: unknown config key (did you mean "delay"?)`,
+				"retry.attempts: must be at most 10, got 20",
+			},
+		},
+		{
+			name:   "length bounds",
+			config: map[string]interface{}{"url": "x", "tags": []interface{}{"a", "b", "c"}},
+			errs:   []string{"tags: must be at most 2 in length, got 3"},
+		},
+		{
+			name:   "skipped fields are unknown keys",
+			config: map[string]interface{}{"url": "x", "-": "y"},
+			errs:   []string{"-: unknown config key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig{Method: "GET"}
+			err := DecodeConfig(tt.config, &cfg)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			for _, want := range tt.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error containing %q, got %q", want, err)
+				}
+			}
+		})
+	}
+
+	t.Run("requires a struct pointer", func(t *testing.T) {
+		var cfg testConfig
+		if err := DecodeConfig(nil, cfg); err == nil {
+			t.Error("Expected error for non-pointer")
+		}
+	})
+}
+
+func TestCompressNodeConfig(t *testing.T) {
+	_, err := (&CompressNodeBuilder{}).Build(&yaml.NodeDefinition{
+		Name:   "compress",
+		Config: map[string]interface{}{"algoritm": "zstd"},
+	})
+	if err == nil || !strings.Contains(err.Error(), `did you mean "algorithm"`) {
+		t.Errorf("Expected misspelled key error, got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/agentstation/pocket"
+)
+
+// evaluateCheck scores the text under evaluation between 0 and 1. A
+// message explains a score below 1.
+type evaluateCheck struct {
+	name   string
+	weight float64
+	score  func(ctx context.Context, store pocket.StoreWriter, input any, text string) (float64, string, error)
+}
+
+// evaluateThreshold routes scores of at least min.
+type evaluateThreshold struct {
+	min   float64
+	route string
+}
+
+// evaluateCheckKinds are the kinds of check; each check has exactly one.
+var evaluateCheckKinds = []string{"regex", "not_regex", "length", "json", "judge"}
+
+// newEvaluateCheck compiles a check from its config. Judges are built with
+// the registry.
+func newEvaluateCheck(node string, i int, spec map[string]interface{}, registry *Registry) (evaluateCheck, error) {
+	check := evaluateCheck{weight: 1}
+
+	var kind string
+	for _, k := range evaluateCheckKinds {
+		if _, ok := spec[k]; ok {
+			if kind != "" {
+				return check, fmt.Errorf("check %d has both %s and %s", i, kind, k)
+			}
+			kind = k
+		}
+	}
+	if kind == "" {
+		return check, fmt.Errorf("check %d needs one of %v", i, evaluateCheckKinds)
+	}
+
+	check.name, _ = spec["name"].(string)
+	if check.name == "" {
+		check.name = kind
+	}
+	if w, ok := spec["weight"]; ok {
+		weight, ok := toFloat(w)
+		if !ok || weight < 0 {
+			return check, fmt.Errorf("check %s weight must be a non-negative number", check.name)
+		}
+		check.weight = weight
+	}
+
+	var err error
+	switch kind {
+	case "regex", "not_regex":
+		check.score, err = regexCheck(spec[kind], kind == "not_regex")
+	case "length":
+		check.score, err = lengthCheck(spec[kind])
+	case "json":
+		check.score = jsonCheck
+	case "judge":
+		check.score, err = judgeCheck(node+"."+check.name, spec, registry)
+	}
+	if err != nil {
+		return check, fmt.Errorf("check %s: %w", check.name, err)
+	}
+	return check, nil
+}
+
+func regexCheck(raw interface{}, negate bool) (func(context.Context, pocket.StoreWriter, any, string) (float64, string, error), error) {
+	pattern, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("pattern must be a string")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	return func(ctx context.Context, store pocket.StoreWriter, input any, text string) (float64, string, error) {
+		switch matched := re.MatchString(text); {
+		case matched && negate:
+			return 0, fmt.Sprintf("matches %s", pattern), nil
+		case !matched && !negate:
+			return 0, fmt.Sprintf("doesn't match %s", pattern), nil
+		}
+		return 1, "", nil
+	}, nil
+}
+
+func lengthCheck(raw interface{}) (func(context.Context, pocket.StoreWriter, any, string) (float64, string, error), error) {
+	bounds, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("length must be an object with min and/or max")
+	}
+	minLen, hasMin := toFloat(bounds["min"])
+	maxLen, hasMax := toFloat(bounds["max"])
+	if !hasMin && !hasMax {
+		return nil, fmt.Errorf("length needs min or max")
+	}
+
+	return func(ctx context.Context, store pocket.StoreWriter, input any, text string) (float64, string, error) {
+		n := float64(utf8.RuneCountInString(text))
+		if hasMin && n < minLen {
+			return 0, fmt.Sprintf("length %v is below the minimum of %v", n, minLen), nil
+		}
+		if hasMax && n > maxLen {
+			return 0, fmt.Sprintf("length %v is above the maximum of %v", n, maxLen), nil
+		}
+		return 1, "", nil
+	}, nil
+}
+
+func jsonCheck(ctx context.Context, store pocket.StoreWriter, input any, text string) (float64, string, error) {
+	if !json.Valid([]byte(text)) {
+		return 0, "not valid JSON", nil
+	}
+	return 1, "", nil
+}
+
+// judgeCheck runs a judge node, such as an HTTP call to an LLM, with the
+// text and the original input, and reads the score and reason from its
+// output.
+func judgeCheck(name string, spec map[string]interface{}, registry *Registry) (func(context.Context, pocket.StoreWriter, any, string) (float64, string, error), error) {
+	if registry == nil {
+		return nil, fmt.Errorf("judge requires a registry to build the judge node")
+	}
+	judgeDef, err := nestedDefinition(name, "judge", spec["judge"])
+	if err != nil {
+		return nil, err
+	}
+	judge, err := registry.Build(judgeDef)
+	if err != nil {
+		return nil, fmt.Errorf("build judge: %w", err)
+	}
+
+	scoreText, _ := spec["score"].(string)
+	if scoreText == "" {
+		scoreText = "{{.score}}"
+	}
+	scoreTmpl, err := newTemplate("score").Parse(scoreText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid score template: %w", err)
+	}
+	reasonTmpl, err := optionalTemplate("reason", spec["reason"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid reason template: %w", err)
+	}
+
+	return func(ctx context.Context, store pocket.StoreWriter, input any, text string) (float64, string, error) {
+		output, err := pocket.NewGraph(judge, store).Run(ctx, map[string]interface{}{
+			"input": input,
+			"text":  text,
+		})
+		if err != nil {
+			return 0, "", fmt.Errorf("judge failed: %w", err)
+		}
+
+		score, err := templateNumber(scoreTmpl, output)
+		if err != nil {
+			return 0, "", fmt.Errorf("judge score: %w", err)
+		}
+		if score < 0 || score > 1 {
+			return 0, "", fmt.Errorf("judge score %v is outside 0 to 1", score)
+		}
+		if score == 1 || reasonTmpl == nil {
+			return score, "", nil
+		}
+
+		var buf bytes.Buffer
+		if err := reasonTmpl.Execute(&buf, output); err != nil {
+			return 0, "", fmt.Errorf("judge reason: %w", err)
+		}
+		return score, buf.String(), nil
+	}, nil
+}
+
+// evaluateText returns the text a node evaluates: the target template's
+// output, or the input, with values other than strings as JSON.
+func evaluateText(target *template.Template, input any) (string, error) {
+	if target != nil {
+		var buf bytes.Buffer
+		if err := target.Execute(&buf, input); err != nil {
+			return "", fmt.Errorf("target template failed: %w", err)
+		}
+		return buf.String(), nil
+	}
+	if s, ok := input.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("marshal input: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,149 @@
+package nodes
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// execLimits are per-process resource limits for exec nodes.
+type execLimits struct {
+	cpuSeconds int64
+	memory     int64 // Bytes of address space
+	openFiles  int64
+}
+
+func newExecLimits(config map[string]interface{}) (*execLimits, error) {
+	limits := &execLimits{}
+
+	if n, ok := toFloat(config["cpu_seconds"]); ok {
+		limits.cpuSeconds = int64(n)
+	}
+	if n, ok := toFloat(config["open_files"]); ok {
+		limits.openFiles = int64(n)
+	}
+	if raw, ok := config["memory"]; ok {
+		size, err := parseByteSize(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid memory limit: %w", err)
+		}
+		limits.memory = size
+	}
+
+	if limits.cpuSeconds < 0 || limits.openFiles < 0 || limits.memory < 0 {
+		return nil, fmt.Errorf("limits must not be negative")
+	}
+	if !rlimitsSupported {
+		return nil, fmt.Errorf("resource limits are not supported on this platform")
+	}
+	return limits, nil
+}
+
+// limitShell is the shell whose ulimit builtin applies the limits.
+const limitShell = "/bin/sh"
+
+// set reports whether any limit is set, so commands run through the shell.
+func (l *execLimits) set() bool {
+	return l.cpuSeconds > 0 || l.memory > 0 || l.openFiles > 0
+}
+
+// checkChroot reports an error if commands run in chroot can't have their
+// limits applied, as the shell applying them is missing from it.
+func (l *execLimits) checkChroot(chroot string) error {
+	if !l.set() {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(chroot, limitShell)); err != nil {
+		return fmt.Errorf("limits are applied with %s, which sandbox.chroot %s must contain: %w", limitShell, chroot, err)
+	}
+	return nil
+}
+
+// wrap returns a command line that applies the limits with the shell's
+// ulimit builtin and then execs the command, so the limits are in place
+// before it starts. Both soft and hard limits are set so the command can't
+// raise them.
+func (l *execLimits) wrap(command string, args []string) (string, []string) {
+	var ulimits []string
+	if l.cpuSeconds > 0 {
+		ulimits = append(ulimits, "ulimit -t "+strconv.FormatInt(l.cpuSeconds, 10))
+	}
+	if l.memory > 0 {
+		// ulimit -v takes kilobytes
+		ulimits = append(ulimits, "ulimit -v "+strconv.FormatInt((l.memory+1023)/1024, 10))
+	}
+	if l.openFiles > 0 {
+		ulimits = append(ulimits, "ulimit -n "+strconv.FormatInt(l.openFiles, 10))
+	}
+	if len(ulimits) == 0 {
+		return command, args
+	}
+
+	script := strings.Join(ulimits, " && ") + ` && exec "$0" "$@"`
+	return limitShell, append([]string{"-c", script, command}, args...)
+}
+
+// parseByteSize parses a byte count or a size such as "512MB" or "1GB".
+func parseByteSize(raw interface{}) (int64, error) {
+	if n, ok := toFloat(raw); ok {
+		return int64(n), nil
+	}
+
+	s, ok := raw.(string)
+	if !ok {
+		return 0, fmt.Errorf("expected a size such as 512MB, got %v", raw)
+	}
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	}
+	for _, unit := range units {
+		if number, found := strings.CutSuffix(s, unit.suffix); found {
+			n, err := strconv.ParseInt(strings.TrimSpace(number), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid size %q", raw)
+			}
+			return n * unit.size, nil
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", raw)
+	}
+	return n, nil
+}
+
+// commandEnv builds a command's environment. Inherited variables matching
+// a deny pattern are dropped; configured variables are always set.
+func commandEnv(inherit bool, deny []string, env map[string]string) []string {
+	var result []string
+	if inherit {
+		for _, kv := range os.Environ() {
+			name, _, _ := strings.Cut(kv, "=")
+			if !envDenied(name, deny) {
+				result = append(result, kv)
+			}
+		}
+	}
+	for k, v := range env {
+		result = append(result, k+"="+v)
+	}
+	return result
+}
+
+func envDenied(name string, deny []string) bool {
+	for _, pattern := range deny {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
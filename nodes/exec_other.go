@@ -0,0 +1,15 @@
+//go:build !unix
+
+package nodes
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// rlimitsSupported reports whether exec nodes can apply resource limits.
+const rlimitsSupported = false
+
+func setChroot(_ *exec.Cmd, _ string) error {
+	return fmt.Errorf("chroot is not supported on this platform")
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package nodes
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// rlimitsSupported reports whether exec nodes can apply resource limits.
+const rlimitsSupported = true
+
+// setChroot runs cmd with dir as its root directory. This requires root or
+// CAP_SYS_CHROOT.
+func setChroot(cmd *exec.Cmd, dir string) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Chroot = dir
+	return nil
+}
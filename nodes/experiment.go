@@ -0,0 +1,229 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/agentstation/pocket"
+)
+
+// ExperimentReport summarizes the outcomes of each arm of an experiment
+// node, as recorded in the store.
+type ExperimentReport struct {
+	Experiment string                `json:"experiment"`
+	Arms       []ExperimentArmReport `json:"arms"`
+}
+
+// ExperimentArmReport summarizes the runs assigned to one arm.
+type ExperimentArmReport struct {
+	Name        string        `json:"name"`
+	Weight      float64       `json:"weight"`
+	Runs        int           `json:"runs"`
+	Failures    int           `json:"failures"`
+	MeanLatency time.Duration `json:"mean_latency"`
+	TotalCost   float64       `json:"total_cost"`
+	MeanCost    float64       `json:"mean_cost"`
+	Scored      int           `json:"scored"`     // Successful runs with a score
+	MeanScore   float64       `json:"mean_score"` // Mean over scored runs
+}
+
+// ReadExperimentReport reads the report of an experiment from the store of
+// the graph running it. It reports false if the experiment hasn't run.
+func ReadExperimentReport(ctx context.Context, store pocket.StoreReader, experiment string) (*ExperimentReport, bool) {
+	stored, ok := store.Get(ctx, experimentKey(experiment))
+	if !ok {
+		return nil, false
+	}
+	state, ok := stored.(experimentState)
+	if !ok {
+		return nil, false
+	}
+
+	report := &ExperimentReport{Experiment: experiment, Arms: make([]ExperimentArmReport, len(state.Arms))}
+	for i, totals := range state.Arms {
+		arm := ExperimentArmReport{
+			Name:      totals.Name,
+			Weight:    totals.Weight,
+			Runs:      totals.Runs,
+			Failures:  totals.Failures,
+			TotalCost: totals.Cost,
+			Scored:    totals.Scored,
+		}
+		if totals.Runs > 0 {
+			arm.MeanLatency = totals.Latency / time.Duration(totals.Runs)
+			arm.MeanCost = totals.Cost / float64(totals.Runs)
+		}
+		if totals.Scored > 0 {
+			arm.MeanScore = totals.Score / float64(totals.Scored)
+		}
+		report.Arms[i] = arm
+	}
+	return report, true
+}
+
+func experimentKey(experiment string) string {
+	return "experiment:" + experiment
+}
+
+// experimentState is the running totals of an experiment, kept in the
+// store between invocations.
+type experimentState struct {
+	Arms []experimentTotals
+}
+
+type experimentTotals struct {
+	Name     string
+	Weight   float64
+	Runs     int
+	Failures int
+	Latency  time.Duration
+	Cost     float64
+	Scored   int
+	Score    float64
+}
+
+// experimentArm is a configured arm of an experiment.
+type experimentArm struct {
+	name   string
+	weight float64
+	node   pocket.Node
+	cost   *template.Template // Nil for a fixed cost
+	fixed  float64
+}
+
+// experimentOutcome is the result of running an input through an arm.
+type experimentOutcome struct {
+	arm     int
+	failed  bool
+	latency time.Duration
+	cost    float64
+	score   float64
+	scored  bool
+}
+
+// experiment assigns inputs to arms and records their outcomes.
+type experiment struct {
+	name     string
+	arms     []experimentArm
+	total    float64
+	assignBy *template.Template
+	score    *template.Template
+
+	// Serializes read-modify-write of the totals by concurrent runs
+	mu sync.Mutex
+}
+
+// assign picks the arm for an input, in proportion to the arm weights.
+// With assign_by, the same key is always assigned to the same arm.
+func (e *experiment) assign(ctx context.Context, input any) (int, error) {
+	var point float64
+	if e.assignBy != nil {
+		var buf bytes.Buffer
+		if err := e.assignBy.Execute(&buf, input); err != nil {
+			return 0, fmt.Errorf("assign_by template failed: %w", err)
+		}
+		h := fnv.New64a()
+		_, _ = h.Write(buf.Bytes())
+		point = float64(h.Sum64()%1_000_000) / 1_000_000 * e.total
+	} else {
+		point = randFloat64(ctx) * e.total
+	}
+
+	for i, arm := range e.arms {
+		if point < arm.weight {
+			return i, nil
+		}
+		point -= arm.weight
+	}
+	return len(e.arms) - 1, nil
+}
+
+// measure computes the cost and score of a run. Templates see the input,
+// the output, the arm name and the latency in milliseconds.
+func (e *experiment) measure(outcome *experimentOutcome, input, output any) error {
+	arm := e.arms[outcome.arm]
+	data := map[string]interface{}{
+		"input":      input,
+		"output":     output,
+		"arm":        arm.name,
+		"latency_ms": float64(outcome.latency) / float64(time.Millisecond),
+	}
+
+	outcome.cost = arm.fixed
+	if arm.cost != nil {
+		cost, err := templateNumber(arm.cost, data)
+		if err != nil {
+			return fmt.Errorf("arm %s cost: %w", arm.name, err)
+		}
+		outcome.cost = cost
+	}
+
+	if e.score != nil {
+		score, err := templateNumber(e.score, data)
+		if err != nil {
+			return fmt.Errorf("score: %w", err)
+		}
+		outcome.score, outcome.scored = score, true
+	}
+	return nil
+}
+
+// record adds an outcome to the experiment's totals in the store.
+func (e *experiment) record(ctx context.Context, store pocket.StoreWriter, outcome experimentOutcome) error {
+	key := experimentKey(e.name)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var state experimentState
+	if stored, ok := store.Get(ctx, key); ok {
+		state, _ = stored.(experimentState)
+	}
+
+	// Keep totals by arm name, so reconfigured arms start afresh
+	totals := make([]experimentTotals, len(e.arms))
+	for i, arm := range e.arms {
+		totals[i] = experimentTotals{Name: arm.name}
+		for _, prev := range state.Arms {
+			if prev.Name == arm.name {
+				totals[i] = prev
+			}
+		}
+		totals[i].Weight = arm.weight
+	}
+
+	t := &totals[outcome.arm]
+	t.Runs++
+	t.Latency += outcome.latency
+	t.Cost += outcome.cost
+	if outcome.failed {
+		t.Failures++
+	}
+	if outcome.scored {
+		t.Scored++
+		t.Score += outcome.score
+	}
+
+	return store.Set(ctx, key, experimentState{Arms: totals})
+}
+
+// templateNumber executes a template and parses its output as a number.
+func templateNumber(tmpl *template.Template, data any) (float64, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(buf.String())
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,251 @@
+package nodes
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+// fileEntry describes a file in list and glob output.
+func fileEntry(path string, info fs.FileInfo) map[string]interface{} {
+	return map[string]interface{}{
+		"name":     info.Name(),
+		"path":     path,
+		"size":     info.Size(),
+		"modified": info.ModTime().Format(time.RFC3339),
+		"isDir":    info.IsDir(),
+	}
+}
+
+// globFiles returns the files matching a resolved pattern. Besides the
+// filepath.Match syntax, a "**" segment matches any number of directories.
+func globFiles(pattern string) ([]interface{}, error) {
+	var matches []string
+	if strings.Contains(pattern, "**") {
+		// Walk from the deepest directory without wildcards
+		root := pattern
+		for strings.ContainsAny(root, "*?[") {
+			root = filepath.Dir(root)
+		}
+		err := filepath.WalkDir(root, func(path string, _ fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if matchSegments(splitPath(pattern), splitPath(path)) {
+				matches = append(matches, path)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	} else {
+		var err error
+		if matches, err = filepath.Glob(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	files := []interface{}{}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			continue
+		}
+		files = append(files, fileEntry(match, info))
+	}
+	return files, nil
+}
+
+func splitPath(path string) []string {
+	return strings.Split(filepath.ToSlash(path), "/")
+}
+
+// matchSegments matches path segments against pattern segments, where a
+// "**" segment matches zero or more path segments.
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// checkDestination fails if dst exists and overwrite is not allowed.
+func checkDestination(dst string, overwrite bool) error {
+	if overwrite {
+		return nil
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("destination %s already exists", dst)
+	}
+	return nil
+}
+
+// copyPath copies a file or directory tree and returns the bytes copied.
+func copyPath(src, dst string) (int64, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return copyFile(src, dst, info.Mode())
+	}
+
+	var total int64
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		n, err := copyFile(path, target, info.Mode())
+		total += n
+		return err
+	})
+	return total, err
+}
+
+func copyFile(src, dst string, mode fs.FileMode) (int64, error) {
+	in, err := os.Open(src) // #nosec G304 - Path is validated and sandboxed
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm()) // #nosec G304 - Path is validated and sandboxed
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// movePath renames src to dst, falling back to copy and delete when they
+// are on different filesystems.
+func movePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	if _, err := copyPath(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// checksumFile returns the hex digest of a file.
+func checksumFile(path, algorithm string) (string, int64, error) {
+	newHash, err := hashFunc(algorithm)
+	if err != nil {
+		return "", 0, err
+	}
+
+	f, err := os.Open(path) // #nosec G304 - Path is validated and sandboxed
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := newHash()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// readChunk reads up to length bytes of a file starting at offset, so large
+// files can be processed a piece at a time.
+func readChunk(path string, offset, length int64) ([]byte, error) {
+	f, err := os.Open(path) // #nosec G304 - Path is validated and sandboxed
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(f, length))
+}
+
+// encodeContent renders file data as a string in the given encoding and
+// reports the encoding used. "auto" falls back to base64 for data that is
+// not valid UTF-8, which would otherwise be corrupted.
+func encodeContent(data []byte, encoding string) (string, string) {
+	if encoding == "base64" || (encoding == "auto" && !utf8.Valid(data)) {
+		return base64.StdEncoding.EncodeToString(data), "base64"
+	}
+	return string(data), "utf-8"
+}
+
+// decodeContent converts content to write back to bytes.
+func decodeContent(content, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 content: %w", err)
+		}
+		return data, nil
+	}
+	return []byte(content), nil
+}
+
+// detectContentType guesses a file's media type from its extension, or from its
+// first bytes when the extension is unknown.
+func detectContentType(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+
+	f, err := os.Open(path) // #nosec G304 - Path is validated and sandboxed
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer func() { _ = f.Close() }()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return http.DetectContentType(head[:n])
+}
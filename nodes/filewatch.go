@@ -0,0 +1,194 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fileWatchEvents maps watch event names to fsnotify operations.
+var fileWatchEvents = map[string]fsnotify.Op{
+	"create": fsnotify.Create,
+	"write":  fsnotify.Write,
+	"remove": fsnotify.Remove,
+	"rename": fsnotify.Rename,
+}
+
+// fileWatch waits for files in a directory to appear or change.
+type fileWatch struct {
+	pattern         string
+	ops             fsnotify.Op
+	debounce        time.Duration
+	timeout         time.Duration
+	includeExisting bool
+}
+
+func newFileWatch(config map[string]interface{}) (*fileWatch, error) {
+	w := &fileWatch{
+		pattern:  "*",
+		ops:      fsnotify.Create | fsnotify.Write,
+		debounce: 500 * time.Millisecond,
+	}
+
+	if pattern, ok := config["pattern"].(string); ok && pattern != "" {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		w.pattern = pattern
+	}
+
+	if raw, ok := config["events"]; ok {
+		events, err := stringList(raw)
+		if err != nil {
+			return nil, fmt.Errorf("events: %w", err)
+		}
+		w.ops = 0
+		for _, event := range events {
+			op, ok := fileWatchEvents[event]
+			if !ok {
+				return nil, fmt.Errorf("unknown watch event %q", event)
+			}
+			w.ops |= op
+		}
+	}
+
+	for key, dst := range map[string]*time.Duration{"debounce": &w.debounce, "timeout": &w.timeout} {
+		if s, ok := config[key].(string); ok && s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			*dst = d
+		}
+	}
+
+	w.includeExisting, _ = config["include_existing"].(bool)
+	return w, nil
+}
+
+// wait blocks until matching files change in dir, then returns them once no
+// further changes arrive for the debounce period, so files still being
+// written are not picked up half-finished. With a timeout, it returns an
+// empty result with timed_out set if nothing changes in time.
+func (w *fileWatch) wait(ctx context.Context, dir string) (map[string]interface{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = watcher.Close() }()
+
+	if err := watcher.Add(dir); err != nil {
+		return nil, err
+	}
+
+	// Latest event per file
+	changed := make(map[string]string)
+
+	settle := time.NewTimer(w.debounce)
+	settle.Stop()
+
+	if w.includeExisting {
+		existing, _ := filepath.Glob(filepath.Join(dir, w.pattern))
+		for _, path := range existing {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				changed[path] = "existing"
+			}
+		}
+		if len(changed) > 0 {
+			settle.Reset(w.debounce)
+		}
+	}
+
+	var timeout <-chan time.Time
+	if w.timeout > 0 {
+		timer := time.NewTimer(w.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-timeout:
+			if len(changed) == 0 {
+				return w.result(dir, changed, true), nil
+			}
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil, fmt.Errorf("watcher closed")
+			}
+			if !w.matches(event) {
+				continue
+			}
+			// Writes to a new file are still reported as its creation
+			name := eventName(event.Op)
+			if prev := changed[event.Name]; name != "write" || (prev != "create" && prev != "existing") {
+				changed[event.Name] = name
+			}
+			settle.Reset(w.debounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil, fmt.Errorf("watcher closed")
+			}
+			return nil, fmt.Errorf("watch failed: %w", err)
+
+		case <-settle.C:
+			return w.result(dir, changed, false), nil
+		}
+	}
+}
+
+func (w *fileWatch) matches(event fsnotify.Event) bool {
+	if event.Op&w.ops == 0 {
+		return false
+	}
+	matched, _ := filepath.Match(w.pattern, filepath.Base(event.Name))
+	return matched
+}
+
+func (w *fileWatch) result(dir string, changed map[string]string, timedOut bool) map[string]interface{} {
+	paths := make([]string, 0, len(changed))
+	for path := range changed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	files := []interface{}{}
+	for _, path := range paths {
+		var entry map[string]interface{}
+		if info, err := os.Stat(path); err == nil {
+			entry = fileEntry(path, info)
+		} else {
+			entry = map[string]interface{}{"name": filepath.Base(path), "path": path}
+		}
+		entry["event"] = changed[path]
+		files = append(files, entry)
+	}
+
+	return map[string]interface{}{
+		"path":      dir,
+		"files":     files,
+		"count":     len(files),
+		"timed_out": timedOut,
+	}
+}
+
+// eventName reports the most significant operation in op.
+func eventName(op fsnotify.Op) string {
+	for _, name := range []string{"remove", "rename", "create", "write"} {
+		if op.Has(fileWatchEvents[name]) {
+			return name
+		}
+	}
+	return strings.ToLower(op.String())
+}
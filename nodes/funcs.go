@@ -0,0 +1,348 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+
+	goyaml "github.com/goccy/go-yaml"
+)
+
+var (
+	customFuncsMu sync.RWMutex
+	customFuncs   = template.FuncMap{}
+)
+
+// RegisterTemplateFunc makes a function available to every template
+// evaluated by built-in nodes, such as template, conditional, and switch.
+// Custom functions override built-in ones with the same name. Register
+// functions before loading workflows; templates are compiled at build time.
+func RegisterTemplateFunc(name string, fn any) error {
+	if name == "" {
+		return fmt.Errorf("template function name is required")
+	}
+
+	t := reflect.TypeOf(fn)
+	if t == nil || t.Kind() != reflect.Func {
+		return fmt.Errorf("template function %s must be a function, got %T", name, fn)
+	}
+	// text/template allows one result, or two with the second an error
+	if t.NumOut() == 0 || t.NumOut() > 2 ||
+		(t.NumOut() == 2 && t.Out(1) != reflect.TypeOf((*error)(nil)).Elem()) {
+		return fmt.Errorf("template function %s must return a value and an optional error", name)
+	}
+
+	customFuncsMu.Lock()
+	defer customFuncsMu.Unlock()
+
+	customFuncs[name] = fn
+	return nil
+}
+
+// TemplateFuncs returns the functions available to node templates: the
+// built-in library plus any registered with RegisterTemplateFunc.
+func TemplateFuncs() template.FuncMap {
+	funcs := builtinFuncs()
+
+	customFuncsMu.RLock()
+	defer customFuncsMu.RUnlock()
+
+	for name, fn := range customFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// newTemplate creates a template with the node function library.
+func newTemplate(name string) *template.Template {
+	return template.New(name).Funcs(TemplateFuncs())
+}
+
+// builtinFuncs returns the built-in template function library.
+func builtinFuncs() template.FuncMap {
+	return template.FuncMap{
+		// Strings
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      titleCase,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       joinList,
+		"repeat":     func(n int, s string) string { return strings.Repeat(s, max(n, 0)) },
+		"trunc":      truncate,
+		"quote":      strconv.Quote,
+
+		// Math
+		"add":   func(a, b any) (any, error) { return arith(a, b, '+') },
+		"sub":   func(a, b any) (any, error) { return arith(a, b, '-') },
+		"mul":   func(a, b any) (any, error) { return arith(a, b, '*') },
+		"div":   func(a, b any) (any, error) { return arith(a, b, '/') },
+		"mod":   func(a, b any) (any, error) { return arith(a, b, '%') },
+		"max":   func(a, b any) (any, error) { return pick(a, b, func(x, y float64) bool { return x >= y }) },
+		"min":   func(a, b any) (any, error) { return pick(a, b, func(x, y float64) bool { return x <= y }) },
+		"floor": func(v any) (float64, error) { return unary(v, math.Floor) },
+		"ceil":  func(v any) (float64, error) { return unary(v, math.Ceil) },
+		"round": func(v any) (float64, error) { return unary(v, math.Round) },
+		"toInt": func(v any) (int64, error) {
+			f, err := toNumber(v)
+			return int64(f), err
+		},
+		"toFloat": toNumber,
+
+		// Dates
+		"now":  time.Now,
+		"date": formatDate,
+		"unix": func(v any) (int64, error) {
+			t, err := parseTime(v, "")
+			return t.Unix(), err
+		},
+
+		// Defaults and logic
+		"default":  func(def, v any) any { return ternary(isEmpty(v), def, v) },
+		"empty":    isEmpty,
+		"coalesce": coalesce,
+		"ternary":  func(yes, no any, cond bool) any { return ternary(cond, yes, no) },
+
+		// Data
+		"toJson": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"toPrettyJson": func(v any) (string, error) {
+			data, err := json.MarshalIndent(v, "", "  ")
+			return string(data), err
+		},
+		"fromJson": func(s string) (any, error) {
+			var v any
+			err := json.Unmarshal([]byte(s), &v)
+			return v, err
+		},
+		"toYaml": func(v any) (string, error) {
+			data, err := goyaml.Marshal(v)
+			return strings.TrimSuffix(string(data), "\n"), err
+		},
+		"list":   func(items ...any) []any { return items },
+		"dict":   dict,
+		"keys":   keys,
+		"hasKey": func(m map[string]any, key string) bool { _, ok := m[key]; return ok },
+	}
+}
+
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
+
+// joinList joins any list, formatting each element.
+func joinList(sep string, list any) (string, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: expected a list, got %T", list)
+	}
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// truncate shortens s to at most n runes.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// toNumber converts numbers and numeric strings to float64.
+func toNumber(v any) (float64, error) {
+	if f, ok := toFloat(v); ok {
+		return f, nil
+	}
+
+	switch n := v.(type) {
+	case int32:
+		return float64(n), nil
+	case uint:
+		return float64(n), nil
+	case uint32:
+		return float64(n), nil
+	case float32:
+		return float64(n), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil {
+			return 0, fmt.Errorf("not a number: %q", n)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("not a number: %v (%T)", v, v)
+	}
+}
+
+// arith applies an operator, returning an int64 when both operands are
+// whole numbers so results compare cleanly with integer literals.
+func arith(a, b any, op byte) (any, error) {
+	x, err := toNumber(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := toNumber(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if (op == '/' || op == '%') && y == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+
+	if x == math.Trunc(x) && y == math.Trunc(y) {
+		if result, ok := intArith(int64(x), int64(y), op); ok {
+			return result, nil
+		}
+	}
+
+	switch op {
+	case '+':
+		return x + y, nil
+	case '-':
+		return x - y, nil
+	case '*':
+		return x * y, nil
+	case '%':
+		return math.Mod(x, y), nil
+	default:
+		return x / y, nil
+	}
+}
+
+// intArith applies an operator to whole numbers. It reports false for
+// division that doesn't come out even.
+func intArith(i, j int64, op byte) (int64, bool) {
+	switch op {
+	case '+':
+		return i + j, true
+	case '-':
+		return i - j, true
+	case '*':
+		return i * j, true
+	case '%':
+		return i % j, true
+	default:
+		return i / j, i%j == 0
+	}
+}
+
+// pick returns whichever operand better satisfies cmp.
+func pick(a, b any, cmp func(x, y float64) bool) (any, error) {
+	x, err := toNumber(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := toNumber(b)
+	if err != nil {
+		return nil, err
+	}
+	if cmp(x, y) {
+		return a, nil
+	}
+	return b, nil
+}
+
+func unary(v any, fn func(float64) float64) (float64, error) {
+	f, err := toNumber(v)
+	if err != nil {
+		return 0, err
+	}
+	return fn(f), nil
+}
+
+// formatDate formats a time.Time, time string, or unix timestamp with a Go
+// layout or one of the datetime node's format names.
+func formatDate(layout string, v any) (any, error) {
+	t, ok := v.(time.Time)
+	if !ok {
+		var err error
+		if t, err = parseTime(v, ""); err != nil {
+			return nil, err
+		}
+	}
+	return formatTime(t, layout), nil
+}
+
+// isEmpty reports whether a value is nil or its type's zero value, or an
+// empty collection.
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+func coalesce(values ...any) any {
+	for _, v := range values {
+		if !isEmpty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+func ternary(cond bool, yes, no any) any {
+	if cond {
+		return yes
+	}
+	return no
+}
+
+// dict builds a map from alternating keys and values.
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: expected key/value pairs")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v must be a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// keys returns a map's keys in sorted order.
+func keys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
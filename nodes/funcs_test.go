@@ -0,0 +1,125 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/yaml"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	data := map[string]interface{}{
+		"name":    "ada lovelace",
+		"count":   uint64(7),
+		"price":   2.5,
+		"tags":    []interface{}{"go", "yaml"},
+		"created": "2024-03-01T09:00:00Z",
+		"empty":   "",
+		"nested":  map[string]interface{}{"b": 2, "a": 1},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{name: "upper", template: `{{upper .name}}`, expected: "ADA LOVELACE"},
+		{name: "title", template: `{{title .name}}`, expected: "Ada Lovelace"},
+		{name: "replace pipeline", template: `{{.name | replace " " "_"}}`, expected: "ada_lovelace"},
+		{name: "trunc", template: `{{trunc 3 .name}}`, expected: "ada"},
+		{name: "join", template: `{{join ", " .tags}}`, expected: "go, yaml"},
+		{name: "integer math", template: `{{add .count 3}}`, expected: "10"},
+		{name: "float math", template: `{{mul .price 3}}`, expected: "7.5"},
+		{name: "uneven division", template: `{{div .count 2}}`, expected: "3.5"},
+		{name: "compare math result", template: `{{if eq (sub .count 2) 5}}yes{{end}}`, expected: "yes"},
+		{name: "round", template: `{{round 2.6}}`, expected: "3"},
+		{name: "default", template: `{{.empty | default "n/a"}}`, expected: "n/a"},
+		{name: "default keeps value", template: `{{.name | default "n/a"}}`, expected: "ada lovelace"},
+		{name: "coalesce", template: `{{coalesce .missing .empty "first"}}`, expected: "first"},
+		{name: "ternary", template: `{{ternary "big" "small" (gt .price 2.0)}}`, expected: "big"},
+		{name: "date", template: `{{date "2006-01-02" .created}}`, expected: "2024-03-01"},
+		{name: "toJson", template: `{{toJson .tags}}`, expected: `["go","yaml"]`},
+		{name: "fromJson", template: `{{(fromJson "{\"a\": 1}").a}}`, expected: "1"},
+		{name: "toYaml", template: `{{toYaml .tags}}`, expected: "- go\n- yaml"},
+		{name: "dict and keys", template: `{{keys (dict "z" 1 "y" 2)}}`, expected: "[y z]"},
+		{name: "hasKey", template: `{{hasKey .nested "a"}}`, expected: "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := newTemplate(tt.name).Parse(tt.template)
+			if err != nil {
+				t.Fatalf("Failed to parse template: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				t.Fatalf("Failed to execute template: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+
+	t.Run("division by zero", func(t *testing.T) {
+		tmpl, err := newTemplate("div").Parse(`{{div 1 0}}`)
+		if err != nil {
+			t.Fatalf("Failed to parse template: %v", err)
+		}
+		if err := tmpl.Execute(&bytes.Buffer{}, nil); err == nil {
+			t.Error("Expected division by zero to fail")
+		}
+	})
+}
+
+func TestRegisterTemplateFunc(t *testing.T) {
+	if err := RegisterTemplateFunc("shout", func(s string) string {
+		return strings.ToUpper(s) + "!"
+	}); err != nil {
+		t.Fatalf("Failed to register function: %v", err)
+	}
+	t.Cleanup(func() {
+		customFuncsMu.Lock()
+		delete(customFuncs, "shout")
+		customFuncsMu.Unlock()
+	})
+
+	// Custom functions are available to node templates
+	node, err := (&ConditionalNodeBuilder{}).Build(&yaml.NodeDefinition{
+		Name: "test-conditional",
+		Config: map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{
+					"if":   `{{eq (shout .word) "HI!"}}`,
+					"then": "matched",
+				},
+			},
+			"else": "fallback",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build conditional node: %v", err)
+	}
+
+	input := map[string]interface{}{"word": "hi"}
+	_, next, err := node.Post(context.Background(), pocket.NewStore(), input, input, input)
+	if err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	if next != "matched" {
+		t.Errorf("Expected route 'matched', got '%s'", next)
+	}
+
+	t.Run("rejects invalid functions", func(t *testing.T) {
+		if err := RegisterTemplateFunc("bad", "not a function"); err == nil {
+			t.Error("Expected error for non-function")
+		}
+		if err := RegisterTemplateFunc("bad", func() {}); err == nil {
+			t.Error("Expected error for function without results")
+		}
+	})
+}
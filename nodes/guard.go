@@ -0,0 +1,358 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/agentstation/pocket"
+)
+
+// Guard actions.
+const (
+	guardRedact   = "redact"
+	guardBlock    = "block"
+	guardAnnotate = "annotate"
+)
+
+// piiPatterns are the built-in PII detectors of the guard node.
+var piiPatterns = map[string]*regexp.Regexp{
+	"email":       regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	"phone":       regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?\(?\d{3}\)?[ .-]?\d{3}[ .-]?\d{4}\b`),
+	"ssn":         regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	"credit_card": regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+	"ip":          regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+}
+
+// piiTypes lists the built-in PII types in order.
+var piiTypes = []string{"credit_card", "email", "ip", "phone", "ssn"}
+
+// guardPattern is a named pattern a guard check looks for.
+type guardPattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+// guardCheck is a configured check of a guard node.
+type guardCheck struct {
+	name        string
+	action      string
+	replacement string
+
+	patterns  []guardPattern // pii, pattern and words checks
+	maxLength int            // max_length checks
+	moderate  func(ctx context.Context, store pocket.StoreWriter, input any, text string) (bool, string, error)
+}
+
+// guardViolation is a check that found something in the payload.
+type guardViolation struct {
+	check   string
+	kind    string
+	action  string
+	count   int
+	message string
+}
+
+func (v guardViolation) toMap() map[string]interface{} {
+	m := map[string]interface{}{
+		"check":  v.check,
+		"type":   v.kind,
+		"action": v.action,
+	}
+	if v.count > 0 {
+		m["count"] = v.count
+	}
+	if v.message != "" {
+		m["message"] = v.message
+	}
+	return m
+}
+
+// guardCheckKinds are the kinds of check; each check has exactly one.
+var guardCheckKinds = []string{"pii", "pattern", "words", "max_length", "moderation"}
+
+// newGuardCheck compiles a check from its config. Checks without an action
+// use the node's, or else block for moderation and redact for the rest.
+// Moderation nodes are built with the registry.
+func newGuardCheck(node string, i int, spec map[string]interface{}, action string, registry *Registry) (guardCheck, error) {
+	check := guardCheck{replacement: "[REDACTED]"}
+
+	var kind string
+	for _, k := range guardCheckKinds {
+		if _, ok := spec[k]; ok {
+			if kind != "" {
+				return check, fmt.Errorf("check %d has both %s and %s", i, kind, k)
+			}
+			kind = k
+		}
+	}
+	if kind == "" {
+		return check, fmt.Errorf("check %d needs one of %v", i, guardCheckKinds)
+	}
+
+	check.name, _ = spec["name"].(string)
+	if check.name == "" {
+		check.name = kind
+	}
+	if r, ok := spec["replacement"].(string); ok {
+		check.replacement = r
+	}
+
+	check.action = guardRedact
+	if kind == "moderation" {
+		check.action = guardBlock
+	}
+	if action != "" {
+		check.action = action
+	}
+	if a, ok := spec["action"].(string); ok && a != "" {
+		check.action = a
+	}
+	switch check.action {
+	case guardRedact, guardBlock, guardAnnotate:
+	default:
+		return check, fmt.Errorf("check %s: unknown action %q", check.name, check.action)
+	}
+
+	var err error
+	switch kind {
+	case "pii":
+		check.patterns, err = piiCheck(spec[kind])
+	case "pattern":
+		check.patterns, err = patternCheck(check.name, spec[kind])
+	case "words":
+		check.patterns, err = wordsCheck(spec[kind])
+	case "max_length":
+		n, ok := toFloat(spec[kind])
+		if !ok || n < 1 {
+			err = fmt.Errorf("max_length must be a positive integer")
+		}
+		check.maxLength = int(n)
+	case "moderation":
+		if check.action == guardRedact {
+			err = fmt.Errorf("moderation can't redact; use block or annotate")
+			break
+		}
+		check.moderate, err = moderationCheck(node+"."+check.name, spec, registry)
+	}
+	if err != nil {
+		return check, fmt.Errorf("check %s: %w", check.name, err)
+	}
+	return check, nil
+}
+
+func piiCheck(raw interface{}) ([]guardPattern, error) {
+	kinds := piiTypes
+	if b, ok := raw.(bool); !ok || !b {
+		var err error
+		if kinds, err = stringList(raw); err != nil {
+			return nil, fmt.Errorf("pii must be true or a list of types: %w", err)
+		}
+	}
+
+	patterns := make([]guardPattern, len(kinds))
+	for i, kind := range kinds {
+		re, ok := piiPatterns[kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown PII type %q", kind)
+		}
+		patterns[i] = guardPattern{kind: kind, re: re}
+	}
+	return patterns, nil
+}
+
+func patternCheck(name string, raw interface{}) ([]guardPattern, error) {
+	pattern, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("pattern must be a string")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return []guardPattern{{kind: name, re: re}}, nil
+}
+
+// wordsCheck matches whole words from a list, or from a file with one word
+// per line, ignoring case.
+func wordsCheck(raw interface{}) ([]guardPattern, error) {
+	var words []string
+	if m, ok := raw.(map[string]interface{}); ok {
+		file, _ := m["file"].(string)
+		if file == "" {
+			return nil, fmt.Errorf("words must be a list or an object with a file")
+		}
+		data, err := os.ReadFile(file) // #nosec G304 - Word lists are user-configured
+		if err != nil {
+			return nil, fmt.Errorf("read words file: %w", err)
+		}
+		words = strings.Split(string(data), "\n")
+	} else {
+		var err error
+		if words, err = stringList(raw); err != nil {
+			return nil, fmt.Errorf("words must be a list or an object with a file: %w", err)
+		}
+	}
+
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" && !strings.HasPrefix(word, "#") {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil, fmt.Errorf("words list is empty")
+	}
+	re := regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+	return []guardPattern{{kind: "words", re: re}}, nil
+}
+
+// moderationCheck runs a moderation node, such as an HTTP call to a
+// moderation API, with the payload text, and reads whether it was flagged
+// and why from its output.
+func moderationCheck(name string, spec map[string]interface{}, registry *Registry) (func(context.Context, pocket.StoreWriter, any, string) (bool, string, error), error) {
+	if registry == nil {
+		return nil, fmt.Errorf("moderation requires a registry to build the moderation node")
+	}
+	moderationDef, err := nestedDefinition(name, "moderation", spec["moderation"])
+	if err != nil {
+		return nil, err
+	}
+	moderation, err := registry.Build(moderationDef)
+	if err != nil {
+		return nil, fmt.Errorf("build moderation node: %w", err)
+	}
+
+	flaggedText, _ := spec["flagged"].(string)
+	if flaggedText == "" {
+		flaggedText = "{{.flagged}}"
+	}
+	flaggedTmpl, err := newTemplate("flagged").Parse(flaggedText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid flagged template: %w", err)
+	}
+	reasonTmpl, err := optionalTemplate("reason", spec["reason"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid reason template: %w", err)
+	}
+
+	return func(ctx context.Context, store pocket.StoreWriter, input any, text string) (bool, string, error) {
+		output, err := pocket.NewGraph(moderation, store).Run(ctx, map[string]interface{}{
+			"input": input,
+			"text":  text,
+		})
+		if err != nil {
+			return false, "", fmt.Errorf("moderation failed: %w", err)
+		}
+
+		flagged, err := executeString(flaggedTmpl, output)
+		if err != nil {
+			return false, "", fmt.Errorf("moderation flagged: %w", err)
+		}
+		if flagged != "true" {
+			return false, "", nil
+		}
+		if reasonTmpl == nil {
+			return true, "", nil
+		}
+		reason, err := executeString(reasonTmpl, output)
+		if err != nil {
+			return false, "", fmt.Errorf("moderation reason: %w", err)
+		}
+		return true, reason, nil
+	}, nil
+}
+
+// run applies the check to the payload, returning the payload, redacted if
+// the action is redact, and what it found.
+func (c *guardCheck) run(ctx context.Context, store pocket.StoreWriter, input, payload any) (any, []guardViolation, error) {
+	if c.moderate != nil {
+		text, err := evaluateText(nil, payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		flagged, reason, err := c.moderate(ctx, store, input, text)
+		if err != nil || !flagged {
+			return payload, nil, err
+		}
+		return payload, []guardViolation{{check: c.name, kind: "moderation", action: c.action, message: reason}}, nil
+	}
+
+	if c.maxLength > 0 {
+		count := 0
+		payload = mapStrings(payload, func(s string) string {
+			runes := []rune(s)
+			if len(runes) <= c.maxLength {
+				return s
+			}
+			count++
+			if c.action == guardRedact {
+				return string(runes[:c.maxLength])
+			}
+			return s
+		})
+		if count == 0 {
+			return payload, nil, nil
+		}
+		return payload, []guardViolation{{
+			check:   c.name,
+			kind:    "max_length",
+			action:  c.action,
+			count:   count,
+			message: fmt.Sprintf("longer than %d characters", c.maxLength),
+		}}, nil
+	}
+
+	var violations []guardViolation
+	for _, p := range c.patterns {
+		count := 0
+		payload = mapStrings(payload, func(s string) string {
+			matches := p.re.FindAllStringIndex(s, -1)
+			count += len(matches)
+			if len(matches) == 0 || c.action != guardRedact {
+				return s
+			}
+			return p.re.ReplaceAllLiteralString(s, c.replacement)
+		})
+		if count > 0 {
+			violations = append(violations, guardViolation{check: c.name, kind: p.kind, action: c.action, count: count})
+		}
+	}
+	return payload, violations, nil
+}
+
+// mapStrings returns a copy of v with fn applied to every string in it,
+// including those nested in maps and lists.
+func mapStrings(v any, fn func(string) string) any {
+	switch val := v.(type) {
+	case string:
+		return fn(val)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = mapStrings(item, fn)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = mapStrings(item, fn)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// executeString executes a template and trims its output.
+func executeString(tmpl *template.Template, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
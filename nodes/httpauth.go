@@ -0,0 +1,188 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryDelta refreshes OAuth2 tokens slightly early so they don't
+// expire in flight.
+const tokenExpiryDelta = 30 * time.Second
+
+// httpAuth adds credentials to outgoing requests.
+type httpAuth interface {
+	apply(ctx context.Context, req *http.Request) error
+	// invalidate discards cached credentials after the server rejects them,
+	// reporting whether a retry could use fresh ones.
+	invalidate() bool
+}
+
+// newHTTPAuth creates the authenticator for an http node's auth config.
+// Secrets are read from the environment or files so they stay out of
+// workflow files. OAuth2 token requests use the node's client.
+func newHTTPAuth(config map[string]interface{}, client *http.Client) (httpAuth, error) {
+	authType, _ := config["type"].(string)
+	switch authType {
+	case "bearer":
+		token, err := secretValue(config, "token")
+		if err != nil {
+			return nil, err
+		}
+		return &bearerAuth{token: string(token)}, nil
+
+	case "basic":
+		username, _ := config["username"].(string)
+		if username == "" {
+			return nil, fmt.Errorf("username is required")
+		}
+		password, err := secretValue(config, "password")
+		if err != nil {
+			return nil, err
+		}
+		return &basicAuth{username: username, password: string(password)}, nil
+
+	case "oauth2":
+		tokenURL, _ := config["token_url"].(string)
+		clientID, _ := config["client_id"].(string)
+		if tokenURL == "" || clientID == "" {
+			return nil, fmt.Errorf("token_url and client_id are required")
+		}
+		secret, err := secretValue(config, "client_secret")
+		if err != nil {
+			return nil, err
+		}
+		var scopes []string
+		if raw, ok := config["scopes"]; ok {
+			if scopes, err = stringList(raw); err != nil {
+				return nil, fmt.Errorf("scopes: %w", err)
+			}
+		}
+		return &oauth2Auth{
+			tokenURL:     tokenURL,
+			clientID:     clientID,
+			clientSecret: string(secret),
+			scopes:       scopes,
+			client:       client,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown auth type %q", authType)
+	}
+}
+
+type bearerAuth struct {
+	token string
+}
+
+func (a *bearerAuth) apply(_ context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+func (a *bearerAuth) invalidate() bool { return false }
+
+type basicAuth struct {
+	username string
+	password string
+}
+
+func (a *basicAuth) apply(_ context.Context, req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+func (a *basicAuth) invalidate() bool { return false }
+
+// oauth2Auth fetches tokens with the OAuth2 client credentials grant and
+// caches them until shortly before they expire.
+type oauth2Auth struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	client       *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time // Zero if the server didn't say
+}
+
+func (a *oauth2Auth) apply(ctx context.Context, req *http.Request) error {
+	token, err := a.accessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("oauth2 token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *oauth2Auth) invalidate() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	hadToken := a.token != ""
+	a.token = ""
+	return hadToken
+}
+
+// accessToken returns the cached token, fetching a new one if needed.
+func (a *oauth2Auth) accessToken(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && (a.expiry.IsZero() || time.Now().Before(a.expiry)) {
+		return a.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.scopes) > 0 {
+		form.Set("scope", strings.Join(a.scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.clientID), url.QueryEscape(a.clientSecret))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		AccessToken string  `json:"access_token"`
+		ExpiresIn   float64 `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("decode token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("token response has no access_token")
+	}
+
+	a.token = token.AccessToken
+	a.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		a.expiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenExpiryDelta)
+	}
+	return a.token, nil
+}
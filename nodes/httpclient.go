@@ -0,0 +1,131 @@
+package nodes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// httpTransports holds transports by configuration so http nodes with the
+// same settings share a connection pool.
+var httpTransports sync.Map // map[string]*http.Transport
+
+// newHTTPClient returns a client for an http node. The proxy, tls, and pool
+// settings select a shared transport; the timeout applies per client.
+func newHTTPClient(config map[string]interface{}, timeout time.Duration) (*http.Client, error) {
+	settings := map[string]interface{}{
+		"proxy": config["proxy"],
+		"tls":   config["tls"],
+		"pool":  config["pool"],
+	}
+	key, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	if transport, ok := httpTransports.Load(string(key)); ok {
+		return &http.Client{Timeout: timeout, Transport: transport.(*http.Transport)}, nil
+	}
+
+	transport, err := newHTTPTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := httpTransports.LoadOrStore(string(key), transport)
+	return &http.Client{Timeout: timeout, Transport: actual.(*http.Transport)}, nil
+}
+
+// newHTTPTransport creates a transport from the proxy, tls, and pool config.
+// Unset options keep the defaults of http.DefaultTransport, including proxy
+// settings from the environment.
+func newHTTPTransport(config map[string]interface{}) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxy, ok := config["proxy"].(string); ok && proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if tlsConfig, ok := config["tls"].(map[string]interface{}); ok {
+		var err error
+		if transport.TLSClientConfig, err = newTLSConfig(tlsConfig); err != nil {
+			return nil, fmt.Errorf("invalid tls: %w", err)
+		}
+	}
+
+	if pool, ok := config["pool"].(map[string]interface{}); ok {
+		if n, ok := toFloat(pool["max_idle_conns"]); ok {
+			transport.MaxIdleConns = int(n)
+		}
+		if n, ok := toFloat(pool["max_idle_conns_per_host"]); ok {
+			transport.MaxIdleConnsPerHost = int(n)
+		}
+		if n, ok := toFloat(pool["max_conns_per_host"]); ok {
+			transport.MaxConnsPerHost = int(n)
+		}
+		if s, ok := pool["idle_conn_timeout"].(string); ok {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid idle_conn_timeout: %w", err)
+			}
+			transport.IdleConnTimeout = d
+		}
+		if disable, ok := pool["disable_keep_alives"].(bool); ok {
+			transport.DisableKeepAlives = disable
+		}
+	}
+
+	return transport, nil
+}
+
+// newTLSConfig builds a client TLS config with an optional CA bundle and
+// client certificate for mTLS.
+func newTLSConfig(config map[string]interface{}) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile, ok := config["ca_file"].(string); ok && caFile != "" {
+		pem, err := os.ReadFile(caFile) // #nosec G304 - CA bundles are user-configured
+		if err != nil {
+			return nil, fmt.Errorf("read ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certFile, _ := config["cert_file"].(string)
+	keyFile, _ := config["key_file"].(string)
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if serverName, ok := config["server_name"].(string); ok {
+		tlsConfig.ServerName = serverName
+	}
+	if skip, ok := config["insecure_skip_verify"].(bool); ok {
+		tlsConfig.InsecureSkipVerify = skip // #nosec G402 - Explicit opt-in for test environments
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,205 @@
+package nodes
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ohler55/ojg/jp"
+)
+
+// defaultMaxPages bounds pagination when max_pages is not configured.
+const defaultMaxPages = 100
+
+// httpPaginator follows paginated APIs and concatenates their items.
+type httpPaginator struct {
+	mode      string // "link", "cursor", or "page"
+	items     jp.Expr
+	cursor    jp.Expr
+	param     string
+	startPage int
+	maxPages  int
+	maxItems  int
+}
+
+func newHTTPPaginator(config map[string]interface{}) (*httpPaginator, error) {
+	p := &httpPaginator{startPage: 1, maxPages: defaultMaxPages}
+
+	p.mode, _ = config["type"].(string)
+	switch p.mode {
+	case "link":
+	case "cursor":
+		p.param = "cursor"
+		path, _ := config["cursor_path"].(string)
+		if path == "" {
+			return nil, fmt.Errorf("cursor_path is required for cursor pagination")
+		}
+		var err error
+		if p.cursor, err = jp.ParseString(path); err != nil {
+			return nil, fmt.Errorf("invalid cursor_path: %w", err)
+		}
+	case "page":
+		p.param = "page"
+	default:
+		return nil, fmt.Errorf("unknown pagination type %q", p.mode)
+	}
+
+	var err error
+	if p.items, err = jsonPathConfig(config, "items", "$.body"); err != nil {
+		return nil, err
+	}
+
+	if param, ok := config["param"].(string); ok && param != "" {
+		p.param = param
+	}
+	if n, ok := toFloat(config["start_page"]); ok {
+		p.startPage = int(n)
+	}
+	if n, ok := toFloat(config["max_pages"]); ok && n > 0 {
+		p.maxPages = int(n)
+	}
+	if n, ok := toFloat(config["max_items"]); ok && n > 0 {
+		p.maxItems = int(n)
+	}
+
+	return p, nil
+}
+
+// run fetches pages starting at firstURL until there is no next page or a
+// limit is reached. It stops early on a non-2xx response, whose status and
+// headers are returned with the items collected so far.
+func (p *httpPaginator) run(firstURL string, fetch func(pageURL string) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	pageURL := firstURL
+	page := p.startPage
+	if p.mode == "page" {
+		pageURL = withQueryParam(firstURL, p.param, strconv.Itoa(page))
+	}
+
+	items := []interface{}{}
+	var last map[string]interface{}
+	pages := 0
+	for pageURL != "" && pages < p.maxPages {
+		result, err := fetch(pageURL)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pages+1, err)
+		}
+		pages++
+		last = result
+
+		if status, _ := result["status"].(int); status < 200 || status >= 300 {
+			break
+		}
+
+		pageItems := p.pageItems(result)
+		items = append(items, pageItems...)
+		if p.maxItems > 0 && len(items) >= p.maxItems {
+			items = items[:p.maxItems]
+			break
+		}
+
+		switch p.mode {
+		case "link":
+			headers, _ := result["headers"].(http.Header)
+			pageURL = nextLink(headers.Get("Link"), pageURL)
+		case "cursor":
+			pageURL = ""
+			if cursor := cursorString(firstMatch(p.cursor, result)); cursor != "" {
+				pageURL = withQueryParam(firstURL, p.param, cursor)
+			}
+		case "page":
+			pageURL = ""
+			if len(pageItems) > 0 {
+				page++
+				pageURL = withQueryParam(firstURL, p.param, strconv.Itoa(page))
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"status":  last["status"],
+		"headers": last["headers"],
+		"items":   items,
+		"count":   len(items),
+		"pages":   pages,
+	}, nil
+}
+
+// pageItems selects a page's items. A single list match is flattened so
+// paths like $.body.data work without a trailing [*].
+func (p *httpPaginator) pageItems(result map[string]interface{}) []interface{} {
+	matches := p.items.Get(result)
+	if len(matches) == 1 {
+		if list, ok := matches[0].([]interface{}); ok {
+			return list
+		}
+	}
+	return matches
+}
+
+// nextLink returns the rel="next" target of an RFC 8288 Link header,
+// resolved against the current URL, or "" if there is none.
+func nextLink(header, current string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		target = strings.Trim(strings.TrimSpace(target), "<>")
+
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if !strings.EqualFold(key, "rel") {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+				if strings.EqualFold(rel, "next") {
+					return resolveURL(current, target)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func resolveURL(base, ref string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// withQueryParam returns rawURL with a query parameter set.
+func withQueryParam(rawURL, key, value string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	query.Set(key, value)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// cursorString formats a cursor value, keeping whole numbers out of
+// exponent notation. It returns "" for a missing or empty cursor.
+func cursorString(v interface{}) string {
+	switch c := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return c
+	case float64:
+		if c == math.Trunc(c) {
+			return strconv.FormatInt(int64(c), 10)
+		}
+	}
+	return fmt.Sprint(v)
+}
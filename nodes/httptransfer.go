@@ -0,0 +1,257 @@
+package nodes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// fileSandbox resolves paths for nodes that read or write files, using the
+// same rules as the file node.
+type fileSandbox struct {
+	baseDir       string
+	allowAbsolute bool
+}
+
+func newFileSandbox(config map[string]interface{}) (fileSandbox, error) {
+	baseDir, _ := config["base_dir"].(string)
+	if baseDir == "" {
+		var err error
+		if baseDir, err = os.Getwd(); err != nil {
+			return fileSandbox{}, fmt.Errorf("failed to get working directory: %w", err)
+		}
+	}
+	allowAbsolute, _ := config["allow_absolute"].(bool)
+	return fileSandbox{baseDir: baseDir, allowAbsolute: allowAbsolute}, nil
+}
+
+// resolve renders a path template and resolves it within the sandbox.
+func (s fileSandbox) resolve(tmpl *template.Template, input any) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, input); err != nil {
+		return "", fmt.Errorf("path template execution failed: %w", err)
+	}
+	return resolvePath(buf.String(), s.baseDir, s.allowAbsolute)
+}
+
+// quoteEscaper escapes Content-Disposition parameters, as mime/multipart does.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// multipartPart is a form field or file in a multipart request body.
+type multipartPart struct {
+	name        string
+	value       *template.Template // Field value, or file path for files
+	isFile      bool
+	filename    string
+	contentType string
+}
+
+// multipartBody builds multipart/form-data request bodies.
+type multipartBody struct {
+	parts   []multipartPart
+	sandbox fileSandbox
+}
+
+func newMultipartBody(config map[string]interface{}, sandbox fileSandbox) (*multipartBody, error) {
+	body := &multipartBody{sandbox: sandbox}
+
+	fields, _ := config["fields"].(map[string]interface{})
+	for _, name := range keys(fields) {
+		tmpl, err := newTemplate(name).Parse(fmt.Sprint(fields[name]))
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+		body.parts = append(body.parts, multipartPart{name: name, value: tmpl})
+	}
+
+	files, _ := config["files"].(map[string]interface{})
+	for _, name := range keys(files) {
+		part := multipartPart{name: name, isFile: true}
+
+		var path string
+		switch f := files[name].(type) {
+		case string:
+			path = f
+		case map[string]interface{}:
+			path, _ = f["path"].(string)
+			part.filename, _ = f["filename"].(string)
+			part.contentType, _ = f["content_type"].(string)
+		}
+		if path == "" {
+			return nil, fmt.Errorf("file %q: path is required", name)
+		}
+
+		tmpl, err := newTemplate(name).Parse(path)
+		if err != nil {
+			return nil, fmt.Errorf("file %q: %w", name, err)
+		}
+		part.value = tmpl
+		body.parts = append(body.parts, part)
+	}
+
+	return body, nil
+}
+
+// open returns a reader that streams the form, so files are not buffered in
+// memory, along with its content type. Templates are rendered and files
+// opened up front so errors are reported before the request is sent.
+func (m *multipartBody) open(input any) (io.ReadCloser, string, error) {
+	type resolvedPart struct {
+		multipartPart
+		value string
+		file  *os.File
+	}
+
+	parts := make([]resolvedPart, 0, len(m.parts))
+	closeFiles := func() {
+		for _, p := range parts {
+			if p.file != nil {
+				_ = p.file.Close()
+			}
+		}
+	}
+
+	for _, part := range m.parts {
+		resolved := resolvedPart{multipartPart: part}
+		if part.isFile {
+			path, err := m.sandbox.resolve(part.value, input)
+			if err != nil {
+				closeFiles()
+				return nil, "", fmt.Errorf("file %q: %w", part.name, err)
+			}
+			if resolved.file, err = os.Open(path); err != nil { // #nosec G304 - Path is validated and sandboxed
+				closeFiles()
+				return nil, "", fmt.Errorf("file %q: %w", part.name, err)
+			}
+			if resolved.filename == "" {
+				resolved.filename = filepath.Base(path)
+			}
+		} else {
+			var buf bytes.Buffer
+			if err := part.value.Execute(&buf, input); err != nil {
+				closeFiles()
+				return nil, "", fmt.Errorf("field %q: %w", part.name, err)
+			}
+			resolved.value = buf.String()
+		}
+		parts = append(parts, resolved)
+	}
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	go func() {
+		defer closeFiles()
+
+		for _, part := range parts {
+			if !part.isFile {
+				if err := writer.WriteField(part.name, part.value); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+				continue
+			}
+
+			header := make(textproto.MIMEHeader)
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+				quoteEscaper.Replace(part.name), quoteEscaper.Replace(part.filename)))
+			contentType := part.contentType
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			header.Set("Content-Type", contentType)
+
+			w, err := writer.CreatePart(header)
+			if err == nil {
+				_, err = io.Copy(w, part.file)
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(writer.Close())
+	}()
+
+	return pr, writer.FormDataContentType(), nil
+}
+
+// httpDownload streams response bodies to a file in the sandbox.
+type httpDownload struct {
+	path       *template.Template
+	sandbox    fileSandbox
+	createDirs bool
+}
+
+func newHTTPDownload(config map[string]interface{}, sandbox fileSandbox) (*httpDownload, error) {
+	path, _ := config["path"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	tmpl, err := newTemplate("download").Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path: %w", err)
+	}
+	createDirs, _ := config["create_dirs"].(bool)
+	return &httpDownload{path: tmpl, sandbox: sandbox, createDirs: createDirs}, nil
+}
+
+// save writes the response body to the download path and returns progress
+// metadata. The file is written to a temporary name and renamed on success
+// so partial downloads never replace existing files.
+func (d *httpDownload) save(resp *http.Response, input any) (map[string]interface{}, error) {
+	path, err := d.sandbox.resolve(d.path, input)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	if d.createDirs {
+		if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:gosec // Standard directory permissions
+			return nil, fmt.Errorf("failed to create directories: %w", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return nil, fmt.Errorf("create download file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	start := time.Now()
+	size, err := io.Copy(tmp, resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("download failed after %d bytes: %w", size, err)
+	}
+	if resp.ContentLength >= 0 && size != resp.ContentLength {
+		return nil, fmt.Errorf("download incomplete: got %d of %d bytes", size, resp.ContentLength)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil { //nolint:gosec // Standard file permissions
+		return nil, fmt.Errorf("save download: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("save download: %w", err)
+	}
+
+	elapsed := time.Since(start)
+	return map[string]interface{}{
+		"path":             path,
+		"size":             size,
+		"expected_size":    resp.ContentLength,
+		"content_type":     resp.Header.Get("Content-Type"),
+		"duration":         elapsed.String(),
+		"bytes_per_second": float64(size) / max(elapsed.Seconds(), 1e-9),
+	}, nil
+}
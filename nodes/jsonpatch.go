@@ -0,0 +1,314 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// normalizeJSON round-trips a value through JSON so values decoded from YAML,
+// JSON, or built in Go compare equal and can be mutated without touching the
+// caller's data.
+func normalizeJSON(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// diffJSON returns the RFC 6902 operations that turn from into to. Both
+// values must already be normalized.
+func diffJSON(path string, from, to any) []any {
+	if reflect.DeepEqual(from, to) {
+		return nil
+	}
+
+	switch f := from.(type) {
+	case map[string]any:
+		t, ok := to.(map[string]any)
+		if !ok {
+			break
+		}
+
+		// Sort keys so the patch is deterministic
+		keys := make([]string, 0, len(f)+len(t))
+		for k := range f {
+			keys = append(keys, k)
+		}
+		for k := range t {
+			if _, exists := f[k]; !exists {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		var ops []any
+		for _, k := range keys {
+			child := path + "/" + escapePointer(k)
+			fv, inFrom := f[k]
+			tv, inTo := t[k]
+			switch {
+			case !inTo:
+				ops = append(ops, patchOp("remove", child, nil))
+			case !inFrom:
+				ops = append(ops, patchOp("add", child, tv))
+			default:
+				ops = append(ops, diffJSON(child, fv, tv)...)
+			}
+		}
+		return ops
+
+	case []any:
+		t, ok := to.([]any)
+		if !ok {
+			break
+		}
+
+		var ops []any
+		common := min(len(f), len(t))
+		for i := 0; i < common; i++ {
+			ops = append(ops, diffJSON(path+"/"+strconv.Itoa(i), f[i], t[i])...)
+		}
+		// Remove from the end so earlier indexes stay valid
+		for i := len(f) - 1; i >= common; i-- {
+			ops = append(ops, patchOp("remove", path+"/"+strconv.Itoa(i), nil))
+		}
+		for i := common; i < len(t); i++ {
+			ops = append(ops, patchOp("add", path+"/"+strconv.Itoa(i), t[i]))
+		}
+		return ops
+	}
+
+	return []any{patchOp("replace", path, to)}
+}
+
+func patchOp(op, path string, value any) map[string]any {
+	m := map[string]any{"op": op, "path": path}
+	if op != "remove" {
+		m["value"] = value
+	}
+	return m
+}
+
+// applyPatch applies RFC 6902 operations to a normalized document and
+// returns the result. The document may be modified in place.
+//
+//nolint:gocyclo // One case per RFC 6902 operation
+func applyPatch(doc any, ops []any) (any, error) {
+	for i, raw := range ops {
+		op, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("operation %d must be an object", i)
+		}
+
+		name, _ := op["op"].(string)
+		pathStr, ok := op["path"].(string)
+		if !ok {
+			return nil, fmt.Errorf("operation %d: path is required", i)
+		}
+		path, err := parsePointer(pathStr)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+
+		switch name {
+		case "add":
+			doc, err = pointerSet(doc, path, op["value"], true)
+		case "remove":
+			doc, _, err = pointerRemove(doc, path)
+		case "replace":
+			doc, err = pointerSet(doc, path, op["value"], false)
+		case "move", "copy":
+			var from []string
+			fromStr, _ := op["from"].(string)
+			if from, err = parsePointer(fromStr); err != nil {
+				break
+			}
+			var value any
+			if name == "move" {
+				doc, value, err = pointerRemove(doc, from)
+			} else if value, err = pointerGet(doc, from); err == nil {
+				value, err = normalizeJSON(value)
+			}
+			if err == nil {
+				doc, err = pointerSet(doc, path, value, true)
+			}
+		case "test":
+			var actual any
+			if actual, err = pointerGet(doc, path); err == nil && !reflect.DeepEqual(actual, op["value"]) {
+				err = fmt.Errorf("test failed at %s", pathStr)
+			}
+		default:
+			err = fmt.Errorf("unknown op %q", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, name, pathStr, err)
+		}
+	}
+	return doc, nil
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into unescaped tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, tok := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// arrayIndex parses an array index token. limit is the largest valid index.
+func arrayIndex(tok string, limit int) (int, error) {
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || i > limit {
+		return 0, fmt.Errorf("invalid array index %q", tok)
+	}
+	return i, nil
+}
+
+func pointerGet(doc any, path []string) (any, error) {
+	for _, tok := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[tok]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", tok)
+			}
+			doc = value
+		case []any:
+			i, err := arrayIndex(tok, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("cannot index %T with %q", doc, tok)
+		}
+	}
+	return doc, nil
+}
+
+// pointerSet sets the value at path. With insert, it adds map keys and
+// inserts into arrays ("-" appends); otherwise the target must exist.
+func pointerSet(doc any, path []string, value any, insert bool) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	tok, rest := path[0], path[1:]
+
+	switch node := doc.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			if _, ok := node[tok]; !ok && !insert {
+				return nil, fmt.Errorf("key %q not found", tok)
+			}
+			node[tok] = value
+			return node, nil
+		}
+		child, ok := node[tok]
+		if !ok {
+			return nil, fmt.Errorf("key %q not found", tok)
+		}
+		updated, err := pointerSet(child, rest, value, insert)
+		if err != nil {
+			return nil, err
+		}
+		node[tok] = updated
+		return node, nil
+
+	case []any:
+		if len(rest) == 0 && insert {
+			i := len(node)
+			if tok != "-" {
+				var err error
+				if i, err = arrayIndex(tok, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		i, err := arrayIndex(tok, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) == 0 {
+			node[i] = value
+			return node, nil
+		}
+		updated, err := pointerSet(node[i], rest, value, insert)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = updated
+		return node, nil
+
+	default:
+		return nil, fmt.Errorf("cannot index %T with %q", doc, tok)
+	}
+}
+
+// pointerRemove removes the value at path and returns it.
+func pointerRemove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+	tok, rest := path[0], path[1:]
+
+	switch node := doc.(type) {
+	case map[string]any:
+		child, ok := node[tok]
+		if !ok {
+			return nil, nil, fmt.Errorf("key %q not found", tok)
+		}
+		if len(rest) == 0 {
+			delete(node, tok)
+			return node, child, nil
+		}
+		updated, removed, err := pointerRemove(child, rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[tok] = updated
+		return node, removed, nil
+
+	case []any:
+		i, err := arrayIndex(tok, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rest) == 0 {
+			removed := node[i]
+			return append(node[:i], node[i+1:]...), removed, nil
+		}
+		updated, removed, err := pointerRemove(node[i], rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		node[i] = updated
+		return node, removed, nil
+
+	default:
+		return nil, nil, fmt.Errorf("cannot index %T with %q", doc, tok)
+	}
+}
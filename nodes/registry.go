@@ -42,8 +42,36 @@ func (r *Registry) All() map[string]NodeBuilder {
 	return r.builders
 }
 
+// Build validates a definition's config and builds it with the registered
+// builder for its type. Nodes that embed other nodes use it to build them,
+// since the loader only validates the configs of top-level nodes.
+func (r *Registry) Build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	builder, exists := r.builders[def.Type]
+	if !exists {
+		return nil, fmt.Errorf("unknown node type: %s", def.Type)
+	}
+	return createValidatingBuilder(builder)(def)
+}
+
 // RegisterAll registers all built-in nodes with a YAML loader.
 func RegisterAll(loader *yaml.Loader, verbose bool) *Registry {
+	registry := NewBuiltinRegistry(verbose)
+
+	// Register all with YAML loader, which validates configs at load
+	for _, builder := range registry.All() {
+		meta := builder.Metadata()
+		if len(meta.ConfigSchema) > 0 {
+			loader.RegisterConfigSchema(meta.Type, meta.ConfigSchema)
+		}
+		loader.RegisterNodeType(meta.Type, builder.Build)
+	}
+
+	return registry
+}
+
+// NewBuiltinRegistry creates a registry of all built-in nodes, such as for
+// yaml.LoadGraph.
+func NewBuiltinRegistry(verbose bool) *Registry {
 	registry := NewRegistry()
 
 	// Register core nodes
@@ -51,13 +79,22 @@ func RegisterAll(loader *yaml.Loader, verbose bool) *Registry {
 	registry.Register(&DelayNodeBuilder{Verbose: verbose})
 	registry.Register(&RouterNodeBuilder{Verbose: verbose})
 	registry.Register(&ConditionalNodeBuilder{Verbose: verbose})
+	registry.Register(&SwitchNodeBuilder{Verbose: verbose})
 
 	// Register data nodes
 	registry.Register(&TransformNodeBuilder{Verbose: verbose})
 	registry.Register(&TemplateNodeBuilder{Verbose: verbose})
 	registry.Register(&JSONPathNodeBuilder{Verbose: verbose})
+	registry.Register(&JSONDiffNodeBuilder{Verbose: verbose})
+	registry.Register(&JSONPatchNodeBuilder{Verbose: verbose})
 	registry.Register(&ValidateNodeBuilder{Verbose: verbose})
 	registry.Register(&AggregateNodeBuilder{Verbose: verbose})
+	registry.Register(&ObjectNodeBuilder{Verbose: verbose})
+	registry.Register(&GenerateNodeBuilder{Verbose: verbose})
+	registry.Register(&DatetimeNodeBuilder{Verbose: verbose})
+	registry.Register(&HashNodeBuilder{Verbose: verbose})
+	registry.Register(&CompressNodeBuilder{Verbose: verbose})
+	registry.Register(&EncodeNodeBuilder{Verbose: verbose})
 
 	// Register I/O nodes
 	registry.Register(&HTTPNodeBuilder{Verbose: verbose})
@@ -65,19 +102,15 @@ func RegisterAll(loader *yaml.Loader, verbose bool) *Registry {
 	registry.Register(&ExecNodeBuilder{Verbose: verbose})
 
 	// Register flow nodes
-	registry.Register(&ParallelNodeBuilder{Verbose: verbose})
+	registry.Register(&ParallelNodeBuilder{Verbose: verbose, Registry: registry})
+	registry.Register(&TryNodeBuilder{Verbose: verbose, Registry: registry})
+	registry.Register(&ExperimentNodeBuilder{Verbose: verbose, Registry: registry})
+	registry.Register(&EvaluateNodeBuilder{Verbose: verbose, Registry: registry})
+	registry.Register(&GuardNodeBuilder{Verbose: verbose, Registry: registry})
 
 	// Register script nodes
 	registry.Register(&LuaNodeBuilder{Verbose: verbose})
 
-	// Register all with YAML loader with validation
-	for _, builder := range registry.All() {
-		meta := builder.Metadata()
-		// Wrap the builder with validation
-		wrappedBuilder := createValidatingBuilder(builder)
-		loader.RegisterNodeType(meta.Type, wrappedBuilder)
-	}
-
 	return registry
 }
 
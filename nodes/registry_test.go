@@ -0,0 +1,51 @@
+package nodes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/yaml"
+)
+
+func TestLoadGraph(t *testing.T) {
+	newSummarize := func() pocket.Node {
+		return pocket.NewNode[any, any]("summarize", pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return input.(map[string]interface{})["message"], nil
+			},
+		})
+	}
+
+	// Export a graph built in Go, then load it back as an operator would
+	greet := pocket.NewNode[any, any]("greet", pocket.Steps{},
+		pocket.WithSpec("echo", map[string]any{"message": "hello"}))
+	greet.Connect("default", newSummarize())
+	data, err := pocket.Marshal(pocket.NewGraph(greet, pocket.NewStore()))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "workflow.yaml")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := yaml.CodeNodes(NewBuiltinRegistry(false), newSummarize())
+	graph, err := yaml.LoadGraph(path, registry, pocket.NewStore())
+	if err != nil {
+		t.Fatalf("LoadGraph() error = %v", err)
+	}
+	output, err := graph.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if output != "hello" {
+		t.Errorf("Run() = %v, want hello", output)
+	}
+
+	if _, err := yaml.LoadGraph(path, NewBuiltinRegistry(false), pocket.NewStore()); err == nil {
+		t.Error("LoadGraph() without the code node succeeded, want error")
+	}
+}
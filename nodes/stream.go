@@ -0,0 +1,57 @@
+package nodes
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+)
+
+// maxStreamLine bounds the length of a single streamed output line.
+const maxStreamLine = 1024 * 1024
+
+// LineHandler receives output lines from a node as they are produced.
+type LineHandler func(node, line string)
+
+type lineHandlerKey struct{}
+
+// WithLineHandler returns a context that delivers lines streamed by nodes,
+// such as exec nodes with stream enabled, to fn while the graph runs. Nodes
+// may run concurrently, so fn must be safe for concurrent use.
+func WithLineHandler(ctx context.Context, fn LineHandler) context.Context {
+	return context.WithValue(ctx, lineHandlerKey{}, fn)
+}
+
+// lineHandlerFrom returns the context's line handler, or nil.
+func lineHandlerFrom(ctx context.Context) LineHandler {
+	fn, _ := ctx.Value(lineHandlerKey{}).(LineHandler)
+	return fn
+}
+
+// runStreaming runs cmd, passing each line it writes to stdout to emit as
+// soon as it is written.
+func runStreaming(cmd *exec.Cmd, emit func(line string)) error {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLine)
+	for scanner.Scan() {
+		emit(scanner.Text())
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		// Drain the rest so the process isn't blocked writing to a full pipe
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return err
+	}
+	return scanErr
+}
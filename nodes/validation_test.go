@@ -1,8 +1,14 @@
 package nodes
 
 import (
+	"context"
+	"errors"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/yaml"
 )
 
 func TestValidateNodeConfig(t *testing.T) {
@@ -218,3 +224,109 @@ func TestHTTPNodeValidation(t *testing.T) {
 		}
 	})
 }
+
+func TestLoaderValidatesConfigs(t *testing.T) {
+	loader := yaml.NewLoader()
+	RegisterAll(loader, false)
+
+	def := &yaml.GraphDefinition{
+		Name:  "bad-configs",
+		Start: "fetch",
+		Nodes: []yaml.NodeDefinition{
+			{Name: "fetch", Type: "http", Config: map[string]interface{}{"method": "INVALID"}},
+			{Name: "greet", Type: "echo", Config: map[string]interface{}{"message": "hi"}},
+			{Name: "pack", Type: "compress", Config: map[string]interface{}{"algorithm": "lz4"}},
+		},
+	}
+
+	_, err := loader.LoadDefinition(def, pocket.NewStore())
+	var configErr *yaml.ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Expected ConfigError, got %v", err)
+	}
+
+	nodes := make(map[string]int)
+	for _, v := range configErr.Violations {
+		nodes[v.Node]++
+	}
+	if nodes["fetch"] != 2 || nodes["pack"] != 1 || nodes["greet"] != 0 {
+		t.Errorf("Expected violations for the url and method of fetch and the algorithm of pack, got %v", configErr.Violations)
+	}
+	if !strings.Contains(err.Error(), "node pack: config.algorithm:") {
+		t.Errorf("Expected violation with node and field, got %v", err)
+	}
+}
+
+func TestLoaderInputMapping(t *testing.T) {
+	loader := yaml.NewLoader()
+	RegisterAll(loader, false)
+
+	def := &yaml.GraphDefinition{
+		Name:  "mapped",
+		Start: "notify",
+		Nodes: []yaml.NodeDefinition{
+			{
+				Name: "notify",
+				Type: "echo",
+				Input: map[string]interface{}{
+					"to":       "$.customer.email",
+					"skus":     "$.items[*].sku",
+					"subject":  `Order {{.id}} for {{store "tenant"}}`,
+					"priority": "high",
+					"missing":  "$.nothing",
+				},
+			},
+		},
+	}
+
+	store := pocket.NewStore()
+	_ = store.Set(context.Background(), "tenant", "acme")
+	graph, err := loader.LoadDefinition(def, store)
+	if err != nil {
+		t.Fatalf("LoadDefinition failed: %v", err)
+	}
+
+	output, err := graph.Run(context.Background(), map[string]interface{}{
+		"id":       "o-1",
+		"customer": map[string]interface{}{"email": "ada@example.com"},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a"},
+			map[string]interface{}{"sku": "b"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"to":       "ada@example.com",
+		"skus":     []interface{}{"a", "b"},
+		"subject":  "Order o-1 for acme",
+		"priority": "high",
+		"missing":  nil,
+	}
+	// Echo nodes output the input they receive
+	if got := output.(map[string]interface{})["input"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	t.Run("invalid expressions fail the load", func(t *testing.T) {
+		bad := *def
+		bad.Nodes = []yaml.NodeDefinition{{Name: "notify", Type: "echo", Input: map[string]interface{}{"x": "{{.id"}}}
+		_, err := loader.LoadDefinition(&bad, pocket.NewStore())
+		if err == nil || !strings.Contains(err.Error(), "input.x: invalid template") {
+			t.Errorf("Expected invalid template error, got %v", err)
+		}
+	})
+
+	t.Run("missing store keys fail the node", func(t *testing.T) {
+		graph, err := loader.LoadDefinition(def, pocket.NewStore())
+		if err != nil {
+			t.Fatalf("LoadDefinition failed: %v", err)
+		}
+		_, err = graph.Run(context.Background(), map[string]interface{}{"id": "o-2"})
+		if err == nil || !strings.Contains(err.Error(), `store key "tenant" not found`) {
+			t.Errorf("Expected missing store key error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package pocket
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// PipelineOption configures a ConcurrentPipeline.
+type PipelineOption func(*pipelineOptions)
+
+type pipelineOptions struct {
+	buffer int
+}
+
+// WithStageBuffer sets how many values may wait between two stages. When a
+// buffer is full the upstream stage blocks, so a slow stage applies
+// backpressure to everything before it. The default is 1.
+func WithStageBuffer(n int) PipelineOption {
+	return func(o *pipelineOptions) {
+		o.buffer = n
+	}
+}
+
+// StageMetrics describes the work done by one pipeline stage.
+type StageMetrics struct {
+	// Name is the stage's node name.
+	Name string
+
+	// Processed is the number of values the stage completed.
+	Processed int64
+
+	// Errors is the number of values the stage failed on.
+	Errors int64
+
+	// Busy is the total time spent executing the stage's node.
+	Busy time.Duration
+
+	// Blocked is the total time spent waiting for the next stage to accept
+	// output. High values point at a downstream bottleneck.
+	Blocked time.Duration
+
+	// Queued is the number of values waiting in the stage's input buffer.
+	Queued int
+}
+
+// stageCounters holds live metrics for a stage.
+type stageCounters struct {
+	processed atomic.Int64
+	errors    atomic.Int64
+	busy      atomic.Int64
+	blocked   atomic.Int64
+	input     atomic.Value // <-chan any
+}
+
+// ConcurrentPipeline runs each stage in its own goroutine, connected by
+// bounded channels, so different values can be in different stages at the
+// same time. Each stage handles one value at a time, which keeps outputs in
+// input order. Use Pipeline to pass a single value through the stages.
+type ConcurrentPipeline struct {
+	stages   []Node
+	store    Store
+	opts     pipelineOptions
+	counters []*stageCounters
+}
+
+// NewConcurrentPipeline creates a pipeline from the given stages.
+func NewConcurrentPipeline(nodes []Node, store Store, opts ...PipelineOption) *ConcurrentPipeline {
+	options := pipelineOptions{buffer: 1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.buffer < 0 {
+		options.buffer = 0
+	}
+
+	counters := make([]*stageCounters, len(nodes))
+	for i := range counters {
+		counters[i] = &stageCounters{}
+	}
+
+	return &ConcurrentPipeline{
+		stages:   nodes,
+		store:    store,
+		opts:     options,
+		counters: counters,
+	}
+}
+
+// Stream feeds values from in through the pipeline. Outputs are sent on the
+// returned channel, which is closed once in is closed and drained or the
+// pipeline fails. The error channel receives at most one error and is closed
+// when the pipeline stops.
+func (p *ConcurrentPipeline) Stream(ctx context.Context, in <-chan any) (out <-chan any, errc <-chan error) {
+	errs := make(chan error, 1)
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	current := in
+	for i, node := range p.stages {
+		next := make(chan any, p.opts.buffer)
+		p.counters[i].input.Store(current)
+		g.Go(p.runStage(ctx, node, p.counters[i], current, next))
+		current = next
+	}
+
+	go func() {
+		if err := g.Wait(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	return current, errs
+}
+
+// runStage returns the goroutine body for one stage.
+func (p *ConcurrentPipeline) runStage(
+	ctx context.Context,
+	node Node,
+	counters *stageCounters,
+	in <-chan any,
+	out chan<- any,
+) func() error {
+	return func() error {
+		defer close(out)
+
+		graph := NewGraph(node, p.store)
+		for {
+			var (
+				value any
+				ok    bool
+			)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case value, ok = <-in:
+			}
+			if !ok {
+				return nil
+			}
+
+			start := time.Now()
+			result, err := graph.Run(ctx, value)
+			counters.busy.Add(int64(time.Since(start)))
+			if err != nil {
+				counters.errors.Add(1)
+				return fmt.Errorf("pipeline failed at %s: %w", node.Name(), err)
+			}
+			counters.processed.Add(1)
+
+			start = time.Now()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case out <- result:
+			}
+			counters.blocked.Add(int64(time.Since(start)))
+		}
+	}
+}
+
+// Run pushes every input through the pipeline and returns the outputs in
+// input order.
+func (p *ConcurrentPipeline) Run(ctx context.Context, inputs []any) ([]any, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	in := make(chan any)
+	go func() {
+		defer close(in)
+		for _, input := range inputs {
+			select {
+			case <-ctx.Done():
+				return
+			case in <- input:
+			}
+		}
+	}()
+
+	out, errc := p.Stream(ctx, in)
+
+	results := make([]any, 0, len(inputs))
+	for result := range out {
+		results = append(results, result)
+	}
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Metrics returns a snapshot of per-stage metrics in stage order.
+func (p *ConcurrentPipeline) Metrics() []StageMetrics {
+	metrics := make([]StageMetrics, len(p.stages))
+	for i, node := range p.stages {
+		c := p.counters[i]
+		metrics[i] = StageMetrics{
+			Name:      node.Name(),
+			Processed: c.processed.Load(),
+			Errors:    c.errors.Load(),
+			Busy:      time.Duration(c.busy.Load()),
+			Blocked:   time.Duration(c.blocked.Load()),
+		}
+		if in, ok := c.input.Load().(<-chan any); ok {
+			metrics[i].Queued = len(in)
+		}
+	}
+	return metrics
+}
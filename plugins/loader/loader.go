@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
 
 	"github.com/goccy/go-yaml"
@@ -33,13 +34,65 @@ func DefaultPluginPaths() []string {
 type loader struct {
 	// Cache of discovered plugins
 	discovered map[string]plugins.Metadata
+
+	// Pocket version checked against plugin requirements
+	pocketVersion string
+
+	// Options for loaded WebAssembly plugins
+	pluginOptions []wasm.Option
+}
+
+// Option configures a loader.
+type Option func(*loader)
+
+// WithPocketVersion sets the Pocket version that plugins' requirements.pocket
+// constraints are checked against. It defaults to the version of the pocket
+// module the program was built with. Versions that aren't semantic
+// versions, such as development builds, aren't checked.
+func WithPocketVersion(version string) Option {
+	return func(l *loader) {
+		l.pocketVersion = version
+	}
+}
+
+// WithPluginOptions sets options for the WebAssembly plugins the loader
+// loads, such as their instance pool size.
+func WithPluginOptions(opts ...wasm.Option) Option {
+	return func(l *loader) {
+		l.pluginOptions = append(l.pluginOptions, opts...)
+	}
 }
 
 // New creates a new plugin loader.
-func New() plugins.Loader {
-	return &loader{
-		discovered: make(map[string]plugins.Metadata),
+func New(opts ...Option) plugins.Loader {
+	l := &loader{
+		discovered:    make(map[string]plugins.Metadata),
+		pocketVersion: moduleVersion(),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// moduleVersion returns the version of the pocket module in the running
+// program's build info.
+func moduleVersion() string {
+	const modulePath = "github.com/agentstation/pocket"
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
 	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return ""
 }
 
 // Discover finds all plugins in the given paths.
@@ -155,6 +208,11 @@ func (l *loader) LoadFromMetadata(ctx context.Context, metadata plugins.Metadata
 		return nil, fmt.Errorf("invalid metadata: %w", err)
 	}
 
+	// Check version requirements before loading, rather than failing later
+	if err := l.checkRequirements(metadata); err != nil {
+		return nil, fmt.Errorf("plugin %s is incompatible: %w", metadata.Name, err)
+	}
+
 	// Only support WASM for now
 	if metadata.Runtime != "wasm" {
 		return nil, fmt.Errorf("unsupported runtime: %s", metadata.Runtime)
@@ -184,7 +242,29 @@ func (l *loader) LoadFromMetadata(ctx context.Context, metadata plugins.Metadata
 	}
 
 	// Create WASM plugin
-	return wasm.NewPlugin(ctx, wasmBytes, &metadata)
+	return wasm.NewPlugin(ctx, wasmBytes, &metadata, l.pluginOptions...)
+}
+
+// checkRequirements checks a plugin's requirements against the Pocket
+// version and discovered plugins, discovering plugins in the default paths
+// if a dependency hasn't been seen.
+//
+//nolint:gocritic // hugeParam: metadata is copied intentionally for validation
+func (l *loader) checkRequirements(metadata plugins.Metadata) error {
+	for name := range metadata.Requirements.Plugins {
+		if _, ok := l.discovered[name]; !ok {
+			if _, err := l.Discover(); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	available := make([]plugins.Metadata, 0, len(l.discovered))
+	for _, discovered := range l.discovered {
+		available = append(available, discovered)
+	}
+	return plugins.CheckRequirements(metadata, l.pocketVersion, available)
 }
 
 // loadManifest loads a plugin manifest from a file.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/goccy/go-yaml"
@@ -426,3 +427,76 @@ func TestUnsupportedRuntime(t *testing.T) {
 		t.Errorf("Expected 'unsupported runtime' error, got: %v", err)
 	}
 }
+
+func TestLoadRequirements(t *testing.T) {
+	ctx := context.Background()
+
+	// A discovered plugin for dependencies to resolve against
+	baseDir := t.TempDir()
+	baseManifest := `name: base
+version: 2.1.0
+runtime: wasm
+binary: base.wasm
+nodes:
+  - type: base
+    category: test
+    description: Base node
+`
+	if err := os.WriteFile(filepath.Join(baseDir, "manifest.yaml"), []byte(baseManifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		version      string
+		requirements plugins.Requirements
+		wantErr      string
+	}{
+		{
+			name:         "pocket version too old",
+			version:      "0.9.0",
+			requirements: plugins.Requirements{Pocket: ">=1.0.0"},
+			wantErr:      "requires pocket >=1.0.0, found 0.9.0",
+		},
+		{
+			name:         "dependency version mismatch",
+			version:      "1.0.0",
+			requirements: plugins.Requirements{Plugins: map[string]string{"base": "^3.0"}},
+			wantErr:      "requires plugin base ^3.0, found 2.1.0",
+		},
+		{
+			name:         "missing dependency",
+			version:      "1.0.0",
+			requirements: plugins.Requirements{Plugins: map[string]string{"other": ">=1.0"}},
+			wantErr:      "requires plugin other >=1.0, which is not installed",
+		},
+		{
+			name:         "requirements met",
+			version:      "1.2.0",
+			requirements: plugins.Requirements{Pocket: ">=1.0.0", Plugins: map[string]string{"base": "^2.0"}},
+			// Requirements pass, so loading fails on the missing binary instead
+			wantErr: "failed to read WASM binary",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(WithPocketVersion(tt.version))
+			if _, err := l.Discover(baseDir); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := l.LoadFromMetadata(ctx, plugins.Metadata{
+				Name:         "dependent",
+				Version:      "1.0.0",
+				Runtime:      "wasm",
+				Binary:       filepath.Join(t.TempDir(), "missing.wasm"),
+				Nodes:        []plugins.NodeDefinition{{Type: "dependent", Category: "test", Description: "Test"}},
+				Requirements: tt.requirements,
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadFromMetadata() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
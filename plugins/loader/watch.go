@@ -0,0 +1,339 @@
+package loader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/plugins"
+	"github.com/agentstation/pocket/plugins/wasm"
+	"github.com/agentstation/pocket/yaml"
+)
+
+// defaultReloadDebounce is how long a Watcher waits for changes to settle
+// before reloading, so a binary still being written isn't loaded.
+const defaultReloadDebounce = 200 * time.Millisecond
+
+// Reload reports a plugin that a Watcher loaded, or failed to load.
+type Reload struct {
+	Plugin  string
+	Version string
+	Err     error
+}
+
+// Watcher is a loader mode for plugin development. It loads the plugins in
+// a set of directories and reloads a plugin whenever its manifest or binary
+// changes, so a long-running process picks up new builds without
+// restarting.
+//
+// Node types are registered once, and nodes built from them call the latest
+// version of their plugin, including nodes in graphs loaded before the
+// reload. A plugin that fails to reload keeps running its previous version.
+type Watcher struct {
+	loader   *loader
+	paths    []string
+	debounce time.Duration
+
+	mu           sync.Mutex
+	plugins      map[string]*livePlugin // by plugin name
+	providers    map[string]*livePlugin // by node type
+	fingerprints map[string]string      // by plugin name
+	registrars   []func(string, yaml.NodeBuilder)
+	onReload     func(Reload)
+
+	fsw    *fsnotify.Watcher
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatcher creates a Watcher for plugins in paths, or in the default
+// plugin paths if none are given.
+func NewWatcher(paths []string, opts ...Option) *Watcher {
+	if len(paths) == 0 {
+		paths = DefaultPluginPaths()
+	}
+	return &Watcher{
+		loader:       New(opts...).(*loader),
+		paths:        paths,
+		debounce:     defaultReloadDebounce,
+		plugins:      make(map[string]*livePlugin),
+		providers:    make(map[string]*livePlugin),
+		fingerprints: make(map[string]string),
+	}
+}
+
+// OnReload sets a function called each time a plugin is loaded or fails to
+// load. Call it before Start.
+func (w *Watcher) OnReload(fn func(Reload)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onReload = fn
+}
+
+// Start loads the plugins and watches for changes until ctx is done or the
+// Watcher is closed.
+func (w *Watcher) Start(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch plugins: %w", err)
+	}
+	for _, path := range w.paths {
+		w.addDirs(fsw, path)
+	}
+
+	if err := w.sync(ctx); err != nil {
+		_ = fsw.Close()
+		return err
+	}
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.fsw = fsw
+	w.done = make(chan struct{})
+	go w.watch(ctx)
+	return nil
+}
+
+// Register registers the node types of the watched plugins with a YAML
+// loader, including types added by later reloads.
+func (w *Watcher) Register(l *yaml.Loader) {
+	w.mu.Lock()
+	w.registrars = append(w.registrars, l.RegisterNodeType)
+	nodeTypes := slices.Sorted(maps.Keys(w.providers))
+	w.mu.Unlock()
+
+	for _, nodeType := range nodeTypes {
+		l.RegisterNodeType(nodeType, w.build)
+	}
+}
+
+// Plugin returns the latest version of a loaded plugin. The returned plugin
+// forwards to newer versions as they're loaded.
+func (w *Watcher) Plugin(name string) (plugins.Plugin, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	p, ok := w.plugins[name]
+	return p, ok
+}
+
+// Close stops watching and closes the loaded plugins.
+func (w *Watcher) Close(ctx context.Context) error {
+	var errs []error
+	if w.cancel != nil {
+		w.cancel()
+		errs = append(errs, w.fsw.Close())
+		<-w.done
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, p := range w.plugins {
+		errs = append(errs, p.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// watch reloads plugins once changes to the watched directories settle.
+func (w *Watcher) watch(ctx context.Context) {
+	defer close(w.done)
+
+	settle := time.NewTimer(w.debounce)
+	settle.Stop()
+	defer settle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			// Watch new plugin directories too
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					w.addDirs(w.fsw, event.Name)
+				}
+			}
+			settle.Reset(w.debounce)
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.report(Reload{Err: err})
+
+		case <-settle.C:
+			if err := w.sync(ctx); err != nil {
+				w.report(Reload{Err: err})
+			}
+		}
+	}
+}
+
+// addDirs watches a directory and its subdirectories.
+func (w *Watcher) addDirs(fsw *fsnotify.Watcher, root string) {
+	_ = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			_ = fsw.Add(path)
+		}
+		return nil
+	})
+}
+
+// sync loads the plugins that are new or have changed since they were
+// last loaded.
+func (w *Watcher) sync(ctx context.Context) error {
+	discovered, err := w.loader.Discover(w.paths...)
+	if err != nil {
+		return err
+	}
+
+	for i := range discovered {
+		metadata := discovered[i]
+		fingerprint, err := pluginFingerprint(&metadata)
+		if err != nil {
+			w.report(Reload{Plugin: metadata.Name, Version: metadata.Version, Err: err})
+			continue
+		}
+
+		w.mu.Lock()
+		unchanged := w.fingerprints[metadata.Name] == fingerprint
+		w.mu.Unlock()
+		if unchanged {
+			continue
+		}
+
+		p, err := w.loader.LoadFromMetadata(ctx, metadata)
+		if err != nil {
+			w.report(Reload{Plugin: metadata.Name, Version: metadata.Version, Err: err})
+			continue
+		}
+		w.install(p, fingerprint)
+		w.report(Reload{Plugin: metadata.Name, Version: metadata.Version})
+	}
+	return nil
+}
+
+// install makes a loaded plugin the latest version of its name, registering
+// any node types it adds, then closes the version it replaces.
+func (w *Watcher) install(p plugins.Plugin, fingerprint string) {
+	metadata := p.Metadata()
+
+	w.mu.Lock()
+	live, exists := w.plugins[metadata.Name]
+	var previous plugins.Plugin
+	if exists {
+		previous = live.swap(p)
+	} else {
+		live = &livePlugin{current: p}
+		w.plugins[metadata.Name] = live
+	}
+	w.fingerprints[metadata.Name] = fingerprint
+
+	var added []string
+	for _, node := range metadata.Nodes {
+		if _, ok := w.providers[node.Type]; !ok {
+			added = append(added, node.Type)
+		}
+		w.providers[node.Type] = live
+	}
+	registrars := slices.Clone(w.registrars)
+	w.mu.Unlock()
+
+	for _, nodeType := range added {
+		for _, register := range registrars {
+			register(nodeType, w.build)
+		}
+	}
+
+	if previous != nil {
+		_ = previous.Close(context.Background())
+	}
+}
+
+// build builds a node with the plugin currently providing its type.
+func (w *Watcher) build(def *yaml.NodeDefinition) (pocket.Node, error) {
+	w.mu.Lock()
+	live, ok := w.providers[def.Type]
+	w.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown node type: %s", def.Type)
+	}
+
+	metadata := live.Metadata()
+	for i := range metadata.Nodes {
+		if metadata.Nodes[i].Type == def.Type {
+			return wasm.NewPluginNodeBuilder(live, &metadata.Nodes[i]).Build(def)
+		}
+	}
+	return nil, fmt.Errorf("plugin %s no longer provides node type %s", metadata.Name, def.Type)
+}
+
+func (w *Watcher) report(r Reload) {
+	w.mu.Lock()
+	fn := w.onReload
+	w.mu.Unlock()
+	if fn != nil {
+		fn(r)
+	}
+}
+
+// pluginFingerprint identifies a version of a plugin by its manifest and
+// the size and modification time of its binary.
+func pluginFingerprint(metadata *plugins.Metadata) (string, error) {
+	info, err := os.Stat(metadata.Binary)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat WASM binary: %w", err)
+	}
+	manifest, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x-%d-%d", sha256.Sum256(manifest), info.Size(), info.ModTime().UnixNano()), nil
+}
+
+// livePlugin forwards to the latest version of a plugin. Swapping waits for
+// calls in progress, so a replaced version can be closed safely.
+type livePlugin struct {
+	mu      sync.RWMutex
+	current plugins.Plugin
+}
+
+func (p *livePlugin) Metadata() plugins.Metadata {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.current.Metadata()
+}
+
+func (p *livePlugin) Call(ctx context.Context, function string, input []byte) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.current.Call(ctx, function, input)
+}
+
+func (p *livePlugin) Close(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current.Close(ctx)
+}
+
+// swap replaces the current version and returns the previous one.
+func (p *livePlugin) swap(next plugins.Plugin) plugins.Plugin {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	previous := p.current
+	p.current = next
+	return previous
+}
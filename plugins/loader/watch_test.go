@@ -0,0 +1,142 @@
+package loader
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket"
+	pocketyaml "github.com/agentstation/pocket/yaml"
+)
+
+// buildGreeter builds the plugintest greeter fixture into dir/greeter.
+func buildGreeter(t *testing.T, dir string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping plugin build in short mode")
+	}
+
+	src := filepath.Join("..", "plugintest", "testdata", "greeter")
+	pluginDir := filepath.Join(dir, "greeter")
+	if err := os.MkdirAll(pluginDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", filepath.Join(pluginDir, "plugin.wasm"), ".")
+	cmd.Dir = src
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("Cannot build test plugin: %v\n%s", err, out)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(src, "manifest.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(pluginDir, "manifest.yaml"), string(manifest))
+	return pluginDir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// graphOf returns a graph with a single node of a type.
+func graphOf(nodeType string, config map[string]interface{}) *pocketyaml.GraphDefinition {
+	return &pocketyaml.GraphDefinition{
+		Name:  "greet",
+		Start: "greet",
+		Nodes: []pocketyaml.NodeDefinition{{Name: "greet", Type: nodeType, Config: config}},
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	pluginDir := buildGreeter(t, dir)
+	manifestPath := filepath.Join(pluginDir, "manifest.yaml")
+	manifest, _ := os.ReadFile(manifestPath)
+
+	ctx := context.Background()
+	w := NewWatcher([]string{dir}, WithPocketVersion("1.0.0"))
+	w.debounce = 20 * time.Millisecond
+
+	reloads := make(chan Reload, 10)
+	w.OnReload(func(r Reload) { reloads <- r })
+
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() { _ = w.Close(ctx) }()
+
+	waitReload := func(t *testing.T) Reload {
+		t.Helper()
+		select {
+		case r := <-reloads:
+			return r
+		case <-time.After(30 * time.Second):
+			t.Fatal("Timed out waiting for reload")
+		}
+		return Reload{}
+	}
+
+	if r := waitReload(t); r.Plugin != "greeter" || r.Version != "0.1.0" || r.Err != nil {
+		t.Fatalf("Initial load = %+v", r)
+	}
+
+	l := pocketyaml.NewLoader()
+	w.Register(l)
+	graph, err := l.LoadDefinition(graphOf("greeter", map[string]interface{}{"greeting": "Hi"}), pocket.NewStore())
+	if err != nil {
+		t.Fatalf("LoadDefinition() error = %v", err)
+	}
+
+	run := func(t *testing.T) {
+		t.Helper()
+		result, err := graph.Run(ctx, "world")
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if result != "Hi, world!" {
+			t.Errorf("Run() = %v, want Hi, world!", result)
+		}
+	}
+	run(t)
+
+	t.Run("reloads changed manifest", func(t *testing.T) {
+		updated := strings.Replace(string(manifest), "version: 0.1.0", "version: 0.2.0", 1)
+		updated = strings.Replace(updated, "nodes:\n", "nodes:\n  - type: greeter-v2\n    category: custom\n    description: Greets again\n", 1)
+		writeFile(t, manifestPath, updated)
+
+		if r := waitReload(t); r.Version != "0.2.0" || r.Err != nil {
+			t.Fatalf("Reload = %+v, want version 0.2.0", r)
+		}
+		p, _ := w.Plugin("greeter")
+		if v := p.Metadata().Version; v != "0.2.0" {
+			t.Errorf("Plugin version = %s, want 0.2.0", v)
+		}
+
+		// The graph loaded before the reload calls the new version
+		run(t)
+
+		// Node types added by the reload are registered
+		if _, err := l.LoadDefinition(graphOf("greeter-v2", nil), pocket.NewStore()); err != nil {
+			t.Errorf("LoadDefinition() with added node type error = %v", err)
+		}
+	})
+
+	t.Run("keeps previous version when reload fails", func(t *testing.T) {
+		writeFile(t, filepath.Join(pluginDir, "plugin.wasm"), "not wasm")
+
+		if r := waitReload(t); r.Err == nil {
+			t.Fatalf("Reload = %+v, want error", r)
+		}
+		run(t)
+	})
+}
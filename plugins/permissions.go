@@ -0,0 +1,80 @@
+package plugins
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+)
+
+// Permission kinds reported in a PermissionError.
+const (
+	PermissionNetwork    = "network"
+	PermissionFilesystem = "filesystem"
+	PermissionEnv        = "env"
+)
+
+// PermissionError reports an attempt by a plugin to use something its
+// manifest doesn't permit.
+type PermissionError struct {
+	// Plugin is the name of the plugin.
+	Plugin string `json:"plugin"`
+
+	// Permission is the kind of access: network, filesystem, or env.
+	Permission string `json:"permission"`
+
+	// Resource is what the plugin tried to use, such as a host or path.
+	Resource string `json:"resource"`
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("plugin %s is not permitted %s access to %s", e.Plugin, e.Permission, e.Resource)
+}
+
+// AllowsHost reports whether the network permissions allow connecting to
+// a host, given as host or host:port. Patterns are host names, where a
+// leading *. matches any subdomain, optionally with a port; * allows any
+// host.
+func (p *Permissions) AllowsHost(hostport string) bool {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = hostport, ""
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, pattern := range p.Network {
+		if pattern == "*" {
+			return true
+		}
+		patternHost, patternPort, err := net.SplitHostPort(pattern)
+		if err != nil {
+			patternHost, patternPort = pattern, ""
+		}
+		if patternPort != "" && patternPort != port {
+			continue
+		}
+		patternHost = strings.ToLower(patternHost)
+		if suffix, ok := strings.CutPrefix(patternHost, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == patternHost {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsEnv reports whether the environment permissions allow reading a
+// variable. Entries are variable names or path.Match patterns such as
+// MYPLUGIN_*.
+func (p *Permissions) AllowsEnv(name string) bool {
+	for _, pattern := range p.Env {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
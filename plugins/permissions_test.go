@@ -0,0 +1,61 @@
+package plugins
+
+import "testing"
+
+func TestAllowsHost(t *testing.T) {
+	p := Permissions{Network: []string{"api.example.com", "*.internal.dev", "localhost:8080"}}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"api.example.com", true},
+		{"API.example.com:443", true},
+		{"example.com", false},
+		{"svc.internal.dev", true},
+		{"a.b.internal.dev:9000", true},
+		{"internal.dev", false},
+		{"localhost:8080", true},
+		{"localhost:9090", false},
+		{"localhost", false},
+	}
+	for _, tt := range tests {
+		if got := p.AllowsHost(tt.host); got != tt.want {
+			t.Errorf("AllowsHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	if (&Permissions{}).AllowsHost("example.com") {
+		t.Error("Expected no network permissions to deny every host")
+	}
+	if !(&Permissions{Network: []string{"*"}}).AllowsHost("example.com") {
+		t.Error("Expected * to allow every host")
+	}
+}
+
+func TestAllowsEnv(t *testing.T) {
+	p := Permissions{Env: []string{"HOME", "MYPLUGIN_*"}}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"HOME", true},
+		{"MYPLUGIN_TOKEN", true},
+		{"PATH", false},
+		{"HOMEDIR", false},
+	}
+	for _, tt := range tests {
+		if got := p.AllowsEnv(tt.name); got != tt.want {
+			t.Errorf("AllowsEnv(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPermissionError(t *testing.T) {
+	err := &PermissionError{Plugin: "fetcher", Permission: PermissionNetwork, Resource: "example.com"}
+	want := "plugin fetcher is not permitted network access to example.com"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
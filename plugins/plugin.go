@@ -66,13 +66,13 @@ type Example struct {
 // Permissions defines what the plugin is allowed to access.
 type Permissions struct {
 	// Network access
-	Network []string `json:"network,omitempty" yaml:"network,omitempty"` // Allowed domains/patterns
+	Network []string `json:"network,omitempty" yaml:"network,omitempty"` // Allowed hosts, e.g. api.example.com, *.example.com:443, or *
 
 	// Environment variables
-	Env []string `json:"env,omitempty" yaml:"env,omitempty"` // Allowed env var names
+	Env []string `json:"env,omitempty" yaml:"env,omitempty"` // Allowed env var names or patterns, e.g. MYPLUGIN_*
 
 	// File system access
-	Filesystem []string `json:"filesystem,omitempty" yaml:"filesystem,omitempty"` // Allowed paths
+	Filesystem []string `json:"filesystem,omitempty" yaml:"filesystem,omitempty"` // Allowed directories, read-only with a :ro suffix
 
 	// Resource limits
 	Memory  string        `json:"memory,omitempty" yaml:"memory,omitempty"`   // Max memory (e.g., "100MB")
@@ -82,8 +82,12 @@ type Permissions struct {
 
 // Requirements specifies plugin dependencies.
 type Requirements struct {
-	Pocket string `json:"pocket,omitempty" yaml:"pocket,omitempty"` // Min Pocket version
+	Pocket string `json:"pocket,omitempty" yaml:"pocket,omitempty"` // Pocket version constraint (e.g., ">=1.2, <2")
 	Memory string `json:"memory,omitempty" yaml:"memory,omitempty"` // Required memory
+
+	// Plugins maps the names of plugins this plugin depends on to version
+	// constraints.
+	Plugins map[string]string `json:"plugins,omitempty" yaml:"plugins,omitempty"`
 }
 
 // Request is sent to plugin functions.
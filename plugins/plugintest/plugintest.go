@@ -0,0 +1,362 @@
+// Package plugintest tests WebAssembly plugins without building a workflow.
+//
+// Load a plugin from its directory, then drive its nodes through prep, exec,
+// and post with table-driven cases:
+//
+//	func TestPlugin(t *testing.T) {
+//		p := plugintest.Load(t, ".")
+//		p.CheckMetadata(t)
+//		p.RunExamples(t)
+//		p.RunCases(t, []plugintest.Case{
+//			{Name: "greets", Node: "greeter", Input: "world", Want: "Hello, world!"},
+//			{Name: "golden", Node: "greeter", Input: "you", Golden: "greet-you"},
+//		})
+//	}
+//
+// Cases with a Golden name compare every step's result against
+// testdata/<name>.golden. Run go test with -plugintest.update to write them.
+package plugintest
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/agentstation/pocket/plugins"
+	"github.com/agentstation/pocket/plugins/loader"
+)
+
+// update rewrites golden files instead of comparing against them.
+var update = flag.Bool("plugintest.update", false, "update plugin golden files")
+
+// Plugin is a plugin loaded for testing.
+type Plugin struct {
+	plugin plugins.Plugin
+}
+
+// Load loads the plugin at path, a plugin directory, manifest, or .wasm
+// file, and closes it when the test finishes.
+func Load(t testing.TB, path string) *Plugin {
+	t.Helper()
+
+	ctx := context.Background()
+	p, err := loader.New().Load(ctx, path)
+	if err != nil {
+		t.Fatalf("Failed to load plugin %s: %v", path, err)
+	}
+	t.Cleanup(func() {
+		_ = p.Close(ctx)
+	})
+	return &Plugin{plugin: p}
+}
+
+// Metadata returns the plugin's metadata.
+func (p *Plugin) Metadata() plugins.Metadata {
+	return p.plugin.Metadata()
+}
+
+// Result holds the output of each lifecycle step.
+type Result struct {
+	Prep   interface{} `json:"prep"`
+	Exec   interface{} `json:"exec"`
+	Output interface{} `json:"output"`
+	Next   string      `json:"next"`
+}
+
+// Call sends a request to a plugin function and returns its response.
+func (p *Plugin) Call(ctx context.Context, req *plugins.Request) (*plugins.Response, error) {
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	respJSON, err := p.plugin.Call(ctx, req.Function, reqJSON)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w", req.Function, err)
+	}
+
+	var resp plugins.Response
+	if err := json.Unmarshal(respJSON, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s response: %w", req.Function, err)
+	}
+	return &resp, nil
+}
+
+// Run runs a node through prep, exec, and post the way a workflow would.
+func (p *Plugin) Run(ctx context.Context, node string, config map[string]interface{}, input interface{}) (*Result, error) {
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	prep, err := p.step(ctx, &plugins.Request{Node: node, Function: "prep", Config: config, Input: inputJSON})
+	if err != nil {
+		return nil, err
+	}
+	exec, err := p.step(ctx, &plugins.Request{Node: node, Function: "exec", Config: config, PrepResult: prep.Output})
+	if err != nil {
+		return nil, err
+	}
+	post, err := p.step(ctx, &plugins.Request{
+		Node:       node,
+		Function:   "post",
+		Config:     config,
+		Input:      inputJSON,
+		PrepResult: prep.Output,
+		ExecResult: exec.Output,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Next: post.Next}
+	if result.Next == "" {
+		result.Next = "done"
+	}
+	for _, step := range []struct {
+		raw json.RawMessage
+		dst *interface{}
+	}{
+		{prep.Output, &result.Prep},
+		{exec.Output, &result.Exec},
+		{post.Output, &result.Output},
+	} {
+		if len(step.raw) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(step.raw, step.dst); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal output: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// step calls one lifecycle function and turns an unsuccessful response
+// into an error.
+func (p *Plugin) step(ctx context.Context, req *plugins.Request) (*plugins.Response, error) {
+	resp, err := p.Call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("plugin %s error: %s", req.Function, resp.Error)
+	}
+	return resp, nil
+}
+
+// Case is a table-driven test of one node run.
+type Case struct {
+	// Name names the subtest.
+	Name string
+
+	// Node is the node type to run.
+	Node string
+
+	// Config and Input are passed to the node.
+	Config map[string]interface{}
+	Input  interface{}
+
+	// Want is the expected output, compared after a JSON round trip.
+	Want interface{}
+
+	// WantNext is the expected route. It is not checked if empty.
+	WantNext string
+
+	// WantErr, if set, expects a step to fail with an error containing it.
+	WantErr string
+
+	// Golden names a file in testdata, without its .golden extension, that
+	// holds the expected Result.
+	Golden string
+}
+
+// RunCases runs each case as a subtest.
+func (p *Plugin) RunCases(t *testing.T, cases []Case) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			p.runCase(t, &tc)
+		})
+	}
+}
+
+func (p *Plugin) runCase(t *testing.T, tc *Case) {
+	t.Helper()
+
+	result, err := p.Run(context.Background(), tc.Node, tc.Config, tc.Input)
+	if tc.WantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), tc.WantErr) {
+			t.Errorf("Expected error containing %q, got %v", tc.WantErr, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if tc.Want != nil {
+		want, err := normalize(tc.Want)
+		if err != nil {
+			t.Fatalf("Invalid Want: %v", err)
+		}
+		if !reflect.DeepEqual(result.Output, want) {
+			t.Errorf("Output = %#v, want %#v", result.Output, want)
+		}
+	}
+	if tc.WantNext != "" && result.Next != tc.WantNext {
+		t.Errorf("Next = %q, want %q", result.Next, tc.WantNext)
+	}
+	if tc.Golden != "" {
+		checkGolden(t, tc.Golden, result)
+	}
+}
+
+// RunExamples runs the examples in the plugin's metadata, checking each
+// example's output when it has one.
+func (p *Plugin) RunExamples(t *testing.T) {
+	t.Helper()
+
+	var cases []Case
+	for _, node := range p.Metadata().Nodes {
+		for _, example := range node.Examples {
+			cases = append(cases, Case{
+				Name:   node.Type + "/" + example.Name,
+				Node:   node.Type,
+				Config: example.Config,
+				Input:  example.Input,
+				Want:   example.Output,
+			})
+		}
+	}
+	p.RunCases(t, cases)
+}
+
+// CheckMetadata reports metadata that doesn't conform to the plugin schema:
+// missing node fields, duplicate node types, schemas that aren't valid JSON
+// Schema, and examples that don't match their node's schemas.
+func (p *Plugin) CheckMetadata(t testing.TB) {
+	t.Helper()
+	CheckMetadata(t, p.Metadata())
+}
+
+// CheckMetadata checks plugin metadata without loading the plugin. See
+// Plugin.CheckMetadata.
+//
+//nolint:gocritic // hugeParam: matches Plugin.Metadata
+func CheckMetadata(t testing.TB, metadata plugins.Metadata) {
+	t.Helper()
+
+	if metadata.Name == "" || metadata.Version == "" {
+		t.Errorf("Plugin name and version are required")
+	}
+	if len(metadata.Nodes) == 0 {
+		t.Errorf("Plugin %s has no nodes", metadata.Name)
+	}
+
+	seen := make(map[string]bool)
+	for i := range metadata.Nodes {
+		node := &metadata.Nodes[i]
+		if node.Type == "" || node.Category == "" || node.Description == "" {
+			t.Errorf("Node %d: type, category, and description are required", i)
+		}
+		if seen[node.Type] {
+			t.Errorf("Node %s: duplicate type", node.Type)
+		}
+		seen[node.Type] = true
+
+		checkNode(t, node)
+	}
+}
+
+// checkNode checks a node's schemas and its examples against them.
+func checkNode(t testing.TB, node *plugins.NodeDefinition) {
+	t.Helper()
+
+	schemas := make(map[string]*gojsonschema.Schema)
+	for name, schema := range map[string]map[string]interface{}{
+		"configSchema": node.ConfigSchema,
+		"inputSchema":  node.InputSchema,
+		"outputSchema": node.OutputSchema,
+	} {
+		if len(schema) == 0 {
+			continue
+		}
+		compiled, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema))
+		if err != nil {
+			t.Errorf("Node %s: invalid %s: %v", node.Type, name, err)
+			continue
+		}
+		schemas[name] = compiled
+	}
+
+	for _, example := range node.Examples {
+		for name, value := range map[string]interface{}{
+			"configSchema": example.Config,
+			"inputSchema":  example.Input,
+			"outputSchema": example.Output,
+		} {
+			schema, ok := schemas[name]
+			if !ok || value == nil {
+				continue
+			}
+			result, err := schema.Validate(gojsonschema.NewGoLoader(value))
+			if err != nil {
+				t.Errorf("Node %s example %q: %v", node.Type, example.Name, err)
+				continue
+			}
+			for _, resultErr := range result.Errors() {
+				t.Errorf("Node %s example %q does not match %s: %s", node.Type, example.Name, name, resultErr)
+			}
+		}
+	}
+}
+
+// checkGolden compares a result with testdata/<name>.golden, or writes it
+// when -plugintest.update is set.
+func checkGolden(t *testing.T, name string, result *Result) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path) //nolint:gosec // Golden file in the test's testdata
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -plugintest.update to create it): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Result does not match %s:\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// normalize converts a value to the form JSON decoding produces.
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
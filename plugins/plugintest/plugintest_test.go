@@ -0,0 +1,164 @@
+package plugintest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/pocket/plugins"
+)
+
+// buildGreeter builds the plugin in testdata/greeter and returns the
+// directory holding its manifest and binary.
+func buildGreeter(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping plugin build in short mode")
+	}
+
+	dir := t.TempDir()
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", filepath.Join(dir, "plugin.wasm"), ".")
+	cmd.Dir = filepath.Join("testdata", "greeter")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("Cannot build test plugin: %v\n%s", err, out)
+	}
+
+	manifest, err := os.ReadFile(filepath.Join("testdata", "greeter", "manifest.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), manifest, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestPlugin(t *testing.T) {
+	p := Load(t, buildGreeter(t))
+
+	t.Run("metadata", func(t *testing.T) {
+		p.CheckMetadata(t)
+	})
+
+	t.Run("examples", func(t *testing.T) {
+		p.RunExamples(t)
+	})
+
+	t.Run("cases", func(t *testing.T) {
+		p.RunCases(t, []Case{
+			{Name: "default greeting", Node: "greeter", Input: "world", Want: "Hello, world!", WantNext: "default"},
+			{Name: "configured", Node: "greeter", Config: map[string]interface{}{"greeting": "Hey"}, Input: "you", Want: "Hey, you!"},
+			{Name: "invalid input", Node: "greeter", Input: 42, WantErr: "input must be a string"},
+			{Name: "golden", Node: "greeter", Config: map[string]interface{}{"greeting": "Hi"}, Input: "there", Golden: "greeter"},
+		})
+	})
+
+	t.Run("run", func(t *testing.T) {
+		result, err := p.Run(context.Background(), "greeter", nil, "world")
+		if err != nil {
+			t.Fatal(err)
+		}
+		prep, ok := result.Prep.(map[string]interface{})
+		if !ok || prep["name"] != "world" || result.Exec != "Hello, world!" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+}
+
+// recorder records errors instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Helper() {}
+
+func TestCheckMetadata(t *testing.T) {
+	valid := plugins.Metadata{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes: []plugins.NodeDefinition{
+			{
+				Type:        "greeter",
+				Category:    "custom",
+				Description: "Greets its input",
+				ConfigSchema: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"greeting": map[string]interface{}{"type": "string"},
+					},
+				},
+				InputSchema: map[string]interface{}{"type": "string"},
+				Examples: []plugins.Example{
+					{Name: "greet", Config: map[string]interface{}{"greeting": "Hi"}, Input: "world"},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*plugins.Metadata)
+		want   []string
+	}{
+		{name: "valid", modify: func(*plugins.Metadata) {}},
+		{
+			name:   "missing version",
+			modify: func(m *plugins.Metadata) { m.Version = "" },
+			want:   []string{"name and version are required"},
+		},
+		{
+			name: "duplicate type",
+			modify: func(m *plugins.Metadata) {
+				m.Nodes = append(m.Nodes, m.Nodes[0])
+			},
+			want: []string{"duplicate type"},
+		},
+		{
+			name: "invalid schema",
+			modify: func(m *plugins.Metadata) {
+				m.Nodes[0].OutputSchema = map[string]interface{}{"type": 42}
+			},
+			want: []string{"invalid outputSchema"},
+		},
+		{
+			name: "example does not match schema",
+			modify: func(m *plugins.Metadata) {
+				m.Nodes[0].Examples = []plugins.Example{
+					{Name: "bad", Config: map[string]interface{}{"greeting": 1}, Input: true},
+				}
+			},
+			want: []string{"does not match configSchema", "does not match inputSchema"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := valid
+			metadata.Nodes = append([]plugins.NodeDefinition(nil), valid.Nodes...)
+			tt.modify(&metadata)
+
+			r := &recorder{TB: t}
+			CheckMetadata(r, metadata)
+
+			got := strings.Join(r.errors, "\n")
+			if len(r.errors) != len(tt.want) {
+				t.Fatalf("Expected %d errors, got %d:\n%s", len(tt.want), len(r.errors), got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Expected error containing %q, got:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
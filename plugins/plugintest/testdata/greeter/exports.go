@@ -0,0 +1,56 @@
+//go:build wasip1
+
+package main
+
+import (
+	"encoding/json"
+	"unsafe"
+)
+
+// allocations keeps buffers shared with the host alive until it frees them.
+var allocations = map[uint32][]byte{}
+
+func alloc(size uint32) uint32 {
+	if size == 0 {
+		size = 1
+	}
+	buf := make([]byte, size)
+	ptr := uint32(uintptr(unsafe.Pointer(&buf[0])))
+	allocations[ptr] = buf
+	return ptr
+}
+
+//go:wasmexport __pocket_alloc
+func pocketAlloc(size uint32) uint32 {
+	return alloc(size)
+}
+
+//go:wasmexport __pocket_free
+func pocketFree(ptr, size uint32) {
+	delete(allocations, ptr)
+}
+
+// pocketCall handles a request and returns the response location, with the
+// pointer in the high 32 bits and the length in the low 32 bits.
+//
+//go:wasmexport __pocket_call
+func pocketCall(ptr, size uint32) uint64 {
+	var req Request
+	resp := Response{}
+	if err := json.Unmarshal(allocations[ptr][:size], &req); err != nil {
+		resp = failure(err)
+	} else {
+		resp = handle(&req)
+	}
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(failure(err))
+	}
+
+	out := alloc(uint32(len(data)))
+	copy(allocations[out], data)
+	return uint64(out)<<32 | uint64(len(data))
+}
+
+func main() {}
@@ -0,0 +1,118 @@
+// Package main implements the greeter Pocket plugin.
+//
+// The node logic lives in this file and can be tested with go test. The
+// WebAssembly exports the host calls are in exports.go.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Request is sent by the host for each lifecycle step.
+type Request struct {
+	Node       string                 `json:"node"`
+	Function   string                 `json:"function"`
+	Config     map[string]interface{} `json:"config,omitempty"`
+	Input      json.RawMessage        `json:"input,omitempty"`
+	PrepResult json.RawMessage        `json:"prepResult,omitempty"`
+	ExecResult json.RawMessage        `json:"execResult,omitempty"`
+}
+
+// Response is returned to the host.
+type Response struct {
+	Success bool            `json:"success"`
+	Error   string          `json:"error,omitempty"`
+	Output  json.RawMessage `json:"output,omitempty"`
+	Next    string          `json:"next,omitempty"`
+}
+
+// NodeDefinition describes a node type the plugin provides. Keep it in step
+// with manifest.yaml, which the host reads without loading the plugin.
+type NodeDefinition struct {
+	Type         string                 `json:"type"`
+	Category     string                 `json:"category"`
+	Description  string                 `json:"description"`
+	ConfigSchema map[string]interface{} `json:"configSchema,omitempty"`
+}
+
+// nodeDefinitions is returned by the metadata function.
+var nodeDefinitions = []NodeDefinition{
+	{
+		Type:        "greeter",
+		Category:    "custom",
+		Description: "Greets its input",
+		ConfigSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"greeting": map[string]interface{}{
+					"type":        "string",
+					"description": "Greeting to use",
+					"default":     "Hello",
+				},
+			},
+		},
+	},
+}
+
+// handle runs one lifecycle step.
+func handle(req *Request) Response {
+	switch req.Function {
+	case "metadata":
+		return success(nodeDefinitions, "")
+	case "prep":
+		return prep(req)
+	case "exec":
+		return exec(req)
+	case "post":
+		return post(req)
+	}
+	return failure(fmt.Errorf("unknown function: %s", req.Function))
+}
+
+// prep validates the input and prepares the data exec needs.
+func prep(req *Request) Response {
+	var name string
+	if err := json.Unmarshal(req.Input, &name); err != nil {
+		return failure(fmt.Errorf("input must be a string: %w", err))
+	}
+
+	greeting := "Hello"
+	if g, ok := req.Config["greeting"].(string); ok && g != "" {
+		greeting = g
+	}
+
+	return success(map[string]string{"greeting": greeting, "name": name}, "")
+}
+
+// exec does the node's work. It only sees the prep result.
+func exec(req *Request) Response {
+	var data map[string]string
+	if err := json.Unmarshal(req.PrepResult, &data); err != nil {
+		return failure(fmt.Errorf("invalid prep result: %w", err))
+	}
+
+	return success(fmt.Sprintf("%s, %s!", data["greeting"], data["name"]), "")
+}
+
+// post returns the node's output and chooses the next route.
+func post(req *Request) Response {
+	var result string
+	if err := json.Unmarshal(req.ExecResult, &result); err != nil {
+		return failure(fmt.Errorf("invalid exec result: %w", err))
+	}
+
+	return success(result, "default")
+}
+
+func success(output interface{}, next string) Response {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return failure(err)
+	}
+	return Response{Success: true, Output: data, Next: next}
+}
+
+func failure(err error) Response {
+	return Response{Success: false, Error: err.Error()}
+}
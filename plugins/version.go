@@ -0,0 +1,269 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version.
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          string
+}
+
+// ParseVersion parses a semantic version such as 1.2.3, v1.2, or
+// 2.0.0-beta.1. Missing minor and patch numbers are zero, and build
+// metadata is ignored.
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	var v Version
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		s, v.Prerelease = s[:i], s[i+1:]
+		if v.Prerelease == "" {
+			return Version{}, fmt.Errorf("invalid version %q: empty prerelease", s)
+		}
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	fields := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		*fields[i] = n
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0, or 1 as v is less than, equal to, or greater than
+// other. A prerelease sorts before its release.
+func (v Version) Compare(other Version) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// comparePrerelease compares dot-separated prerelease identifiers, with
+// numeric identifiers compared numerically and sorting first.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// Constraint is a set of version ranges, any one of which must match.
+type Constraint struct {
+	raw    string
+	ranges [][]comparator
+}
+
+// comparator is a single comparison such as >=1.2.0.
+type comparator struct {
+	op      string
+	version Version
+}
+
+// ParseConstraint parses a version constraint. A constraint is one or more
+// ranges separated by ||, and a range is comparators separated by commas or
+// spaces, all of which must match. Comparators are =, !=, >, >=, <, <=,
+// ^ (same major version, or same minor version before 1.0.0), and ~ (same
+// minor version). A bare version means =.
+//
+//	>=1.0.0
+//	>=1.2, <2
+//	^1.4 || ^2.0
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	for _, part := range strings.Split(s, "||") {
+		var r []comparator
+		for _, field := range strings.FieldsFunc(part, func(r rune) bool { return r == ',' || r == ' ' }) {
+			cmp, err := parseComparator(field)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("invalid constraint %q: %w", s, err)
+			}
+			r = append(r, cmp)
+		}
+		if len(r) == 0 {
+			return Constraint{}, fmt.Errorf("invalid constraint %q: empty range", s)
+		}
+		c.ranges = append(c.ranges, r)
+	}
+	return c, nil
+}
+
+func parseComparator(s string) (comparator, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(s, candidate) {
+			op = candidate
+			break
+		}
+	}
+	v, err := ParseVersion(s[len(op):])
+	if err != nil {
+		return comparator{}, err
+	}
+	if op == "" {
+		op = "="
+	}
+	return comparator{op: op, version: v}, nil
+}
+
+// Check reports whether a version satisfies the constraint.
+func (c Constraint) Check(v Version) bool {
+	for _, r := range c.ranges {
+		ok := true
+		for _, cmp := range r {
+			if !cmp.check(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Constraint) String() string {
+	return c.raw
+}
+
+func (cmp comparator) check(v Version) bool {
+	d := v.Compare(cmp.version)
+	switch cmp.op {
+	case "=":
+		return d == 0
+	case "!=":
+		return d != 0
+	case ">":
+		return d > 0
+	case ">=":
+		return d >= 0
+	case "<":
+		return d < 0
+	case "<=":
+		return d <= 0
+	case "^":
+		if d < 0 {
+			return false
+		}
+		if cmp.version.Major == 0 {
+			return v.Major == 0 && v.Minor == cmp.version.Minor
+		}
+		return v.Major == cmp.version.Major
+	case "~":
+		return d >= 0 && v.Major == cmp.version.Major && v.Minor == cmp.version.Minor
+	}
+	return false
+}
+
+// CheckRequirements reports the requirements in metadata that aren't met
+// by a Pocket version and the plugins available alongside the plugin. An
+// empty or non-semantic Pocket version, such as a development build, skips
+// the Pocket check.
+//
+//nolint:gocritic // hugeParam: metadata is passed by value like the rest of the plugins API
+func CheckRequirements(metadata Metadata, pocketVersion string, available []Metadata) error {
+	var errs []error
+
+	if metadata.Requirements.Pocket != "" {
+		c, err := ParseConstraint(metadata.Requirements.Pocket)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pocket requirement: %w", err))
+		} else if v, err := ParseVersion(pocketVersion); err == nil && !c.Check(v) {
+			errs = append(errs, fmt.Errorf("requires pocket %s, found %s", c, v))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(metadata.Requirements.Plugins)) {
+		if err := checkPluginRequirement(name, metadata.Requirements.Plugins[name], available); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkPluginRequirement checks that a plugin matching a constraint is
+// available.
+func checkPluginRequirement(name, constraint string, available []Metadata) error {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return fmt.Errorf("plugin %s requirement: %w", name, err)
+	}
+
+	for i := range available {
+		if available[i].Name != name {
+			continue
+		}
+		v, err := ParseVersion(available[i].Version)
+		if err != nil {
+			return fmt.Errorf("requires plugin %s %s, found invalid version %q", name, c, available[i].Version)
+		}
+		if !c.Check(v) {
+			return fmt.Errorf("requires plugin %s %s, found %s", name, c, v)
+		}
+		return nil
+	}
+	return fmt.Errorf("requires plugin %s %s, which is not installed", name, c)
+}
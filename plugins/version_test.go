@@ -0,0 +1,184 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"1.2.3", "1.2.3", false},
+		{"v1.2", "1.2.0", false},
+		{"2", "2.0.0", false},
+		{"1.0.0-beta.1+build.5", "1.0.0-beta.1", false},
+		{"1.2.3.4", "", true},
+		{"1.x", "", true},
+		{"dev", "", true},
+		{"1.0.0-", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseVersion(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVersion(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ParseVersion(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	ordered := []string{
+		"0.9.0",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0",
+		"1.0.1",
+		"1.10.0",
+		"2.0.0",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := ParseVersion(ordered[i])
+			b, _ := ParseVersion(ordered[j])
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := a.Compare(b); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{">=1.0.0", []string{"1.0.0", "1.5.0", "2.0.0"}, []string{"0.9.9", "1.0.0-rc.1"}},
+		{">=1.2, <2", []string{"1.2.0", "1.9.9"}, []string{"1.1.0", "2.0.0"}},
+		{">1.0.0 <=1.5.0", []string{"1.0.1", "1.5.0"}, []string{"1.0.0", "1.5.1"}},
+		{"^1.4", []string{"1.4.0", "1.9.0"}, []string{"1.3.9", "2.0.0"}},
+		{"^0.3.1", []string{"0.3.1", "0.3.9"}, []string{"0.3.0", "0.4.0"}},
+		{"~1.4.2", []string{"1.4.2", "1.4.9"}, []string{"1.4.1", "1.5.0"}},
+		{"1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"!=1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"^1.0 || ^3.0", []string{"1.2.0", "3.1.0"}, []string{"2.0.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint() error = %v", err)
+			}
+			for _, s := range tt.match {
+				v, _ := ParseVersion(s)
+				if !c.Check(v) {
+					t.Errorf("Expected %s to match %s", s, tt.constraint)
+				}
+			}
+			for _, s := range tt.noMatch {
+				v, _ := ParseVersion(s)
+				if c.Check(v) {
+					t.Errorf("Expected %s not to match %s", s, tt.constraint)
+				}
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", ">=", "=>1.0", "^1.0 ||", ">=x"} {
+		if _, err := ParseConstraint(invalid); err == nil {
+			t.Errorf("Expected ParseConstraint(%q) to fail", invalid)
+		}
+	}
+}
+
+func TestCheckRequirements(t *testing.T) {
+	available := []Metadata{
+		{Name: "base", Version: "2.1.0"},
+		{Name: "legacy", Version: "0.4.0"},
+	}
+
+	tests := []struct {
+		name          string
+		requirements  Requirements
+		pocketVersion string
+		want          []string
+	}{
+		{
+			name:          "all met",
+			requirements:  Requirements{Pocket: ">=1.0.0", Plugins: map[string]string{"base": "^2.0"}},
+			pocketVersion: "v1.3.0",
+		},
+		{
+			name:          "development build skips pocket check",
+			requirements:  Requirements{Pocket: ">=1.0.0"},
+			pocketVersion: "dev",
+		},
+		{
+			name:          "pocket too old",
+			requirements:  Requirements{Pocket: ">=1.0.0"},
+			pocketVersion: "0.9.0",
+			want:          []string{"requires pocket >=1.0.0, found 0.9.0"},
+		},
+		{
+			name:         "invalid pocket constraint",
+			requirements: Requirements{Pocket: "latest"},
+			want:         []string{"pocket requirement"},
+		},
+		{
+			name: "dependency problems",
+			requirements: Requirements{Plugins: map[string]string{
+				"base":    "^3.0",
+				"legacy":  ">=0.4",
+				"missing": ">=1.0",
+			}},
+			want: []string{
+				"requires plugin base ^3.0, found 2.1.0",
+				"requires plugin missing >=1.0, which is not installed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRequirements(Metadata{Name: "test", Requirements: tt.requirements}, tt.pocketVersion, available)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("CheckRequirements() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("Expected %d problems, got %q", len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("Problem %d = %q, want %q", i, lines[i], want)
+				}
+			}
+		})
+	}
+}
@@ -27,14 +27,22 @@ func NewPluginNodeBuilder(p plugins.Plugin, nodeType *plugins.NodeDefinition) *P
 
 // Metadata returns the node metadata.
 func (b *PluginNodeBuilder) Metadata() nodes.Metadata {
-	// Convert plugin node definition to nodes metadata
+	return NodeMetadata(b.plugin.Metadata(), &b.nodeType)
+}
+
+// NodeMetadata converts a node type declared in a plugin's manifest to node
+// metadata, so plugin nodes can be listed and documented alongside builtin
+// ones without loading the plugin.
+func NodeMetadata(plugin plugins.Metadata, nodeType *plugins.NodeDefinition) nodes.Metadata {
 	return nodes.Metadata{
-		Type:         b.nodeType.Type,
-		Category:     b.nodeType.Category,
-		Description:  b.nodeType.Description,
-		ConfigSchema: b.nodeType.ConfigSchema,
-		Examples:     convertExamples(b.nodeType.Examples),
-		Since:        b.plugin.Metadata().Version,
+		Type:         nodeType.Type,
+		Category:     nodeType.Category,
+		Description:  nodeType.Description,
+		InputSchema:  nodeType.InputSchema,
+		OutputSchema: nodeType.OutputSchema,
+		ConfigSchema: nodeType.ConfigSchema,
+		Examples:     convertExamples(nodeType.Examples),
+		Since:        plugin.Version,
 	}
 }
 
@@ -214,6 +222,8 @@ func convertExamples(examples []plugins.Example) []nodes.Example {
 			Name:        ex.Name,
 			Description: ex.Description,
 			Config:      ex.Config,
+			Input:       ex.Input,
+			Output:      ex.Output,
 		}
 	}
 	return result
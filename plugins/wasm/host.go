@@ -0,0 +1,385 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/experimental/sysfs"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/agentstation/pocket/plugins"
+)
+
+const (
+	// hostModule is the import module name of Pocket's host functions.
+	hostModule = "pocket"
+
+	// defaultHTTPTimeout bounds plugin HTTP requests when the manifest
+	// doesn't set a timeout.
+	defaultHTTPTimeout = 30 * time.Second
+
+	// maxHTTPResponse bounds the size of a response body passed to a plugin.
+	maxHTTPResponse = 10 << 20
+)
+
+// httpRequest is the request a plugin passes to http_request.
+type httpRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// httpResponse is the response returned to a plugin by http_request.
+type httpResponse struct {
+	Status  int                      `json:"status,omitempty"`
+	Headers map[string]string        `json:"headers,omitempty"`
+	Body    string                   `json:"body,omitempty"`
+	Error   string                   `json:"error,omitempty"`
+	Denied  *plugins.PermissionError `json:"denied,omitempty"`
+}
+
+// host provides host functions to a plugin's instances.
+type host struct {
+	plugin      string
+	permissions plugins.Permissions
+	client      *http.Client
+}
+
+// sandbox is the host state of one plugin instance: the pending HTTP
+// response and the permission violations recorded during a call. Calls pass
+// it to host functions in their context.
+type sandbox struct {
+	plugin string
+
+	mu         sync.Mutex
+	pending    []byte
+	violations []error
+}
+
+type sandboxKey struct{}
+
+func withSandbox(ctx context.Context, sb *sandbox) context.Context {
+	return context.WithValue(ctx, sandboxKey{}, sb)
+}
+
+func sandboxFrom(ctx context.Context) *sandbox {
+	sb, _ := ctx.Value(sandboxKey{}).(*sandbox)
+	return sb
+}
+
+// deny records and returns a permission violation.
+func (sb *sandbox) deny(permission, resource string) error {
+	err := &plugins.PermissionError{
+		Plugin:     sb.plugin,
+		Permission: permission,
+		Resource:   resource,
+	}
+	sb.mu.Lock()
+	sb.violations = append(sb.violations, err)
+	sb.mu.Unlock()
+	return err
+}
+
+// takeViolations returns and clears the violations recorded since the
+// last call.
+func (sb *sandbox) takeViolations() error {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	err := errors.Join(sb.violations...)
+	sb.violations = nil
+	return err
+}
+
+func newHost(metadata *plugins.Metadata) *host {
+	timeout := metadata.Permissions.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+
+	h := &host{
+		plugin:      metadata.Name,
+		permissions: metadata.Permissions,
+	}
+	h.client = &http.Client{
+		Timeout: timeout,
+		// Check redirects against the allowlist too
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return h.checkHost(req.Context(), req.URL)
+		},
+	}
+	return h
+}
+
+// instantiate registers the host functions with a runtime. Plugins import
+// them from the pocket module:
+//
+//	http_request(ptr, len i32) i32  sends the JSON request at ptr and
+//	                                returns the length of the JSON response
+//	http_response(ptr i32)          copies that response to ptr
+func (h *host) instantiate(ctx context.Context, r wazero.Runtime) error {
+	_, err := r.NewHostModuleBuilder(hostModule).
+		NewFunctionBuilder().WithFunc(h.httpRequest).Export("http_request").
+		NewFunctionBuilder().WithFunc(h.httpResponse).Export("http_response").
+		Instantiate(ctx)
+	return err
+}
+
+// httpRequest performs an HTTP request for a plugin if its network
+// permissions allow the host.
+func (h *host) httpRequest(ctx context.Context, m api.Module, ptr, size uint32) uint32 {
+	sb := sandboxFrom(ctx)
+	if sb == nil {
+		return 0
+	}
+
+	resp := h.doHTTP(ctx, m, ptr, size)
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(httpResponse{Error: err.Error()})
+	}
+
+	sb.mu.Lock()
+	sb.pending = data
+	sb.mu.Unlock()
+
+	if len(data) > math.MaxUint32 {
+		return 0
+	}
+	return uint32(len(data)) //nolint:gosec // length is checked above
+}
+
+func (h *host) doHTTP(ctx context.Context, m api.Module, ptr, size uint32) *httpResponse {
+	data, ok := m.Memory().Read(ptr, size)
+	if !ok {
+		return &httpResponse{Error: "request out of range of memory"}
+	}
+
+	var req httpRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return &httpResponse{Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return &httpResponse{Error: fmt.Sprintf("invalid URL %q", req.URL)}
+	}
+	if err := h.checkHost(ctx, u); err != nil {
+		var denied *plugins.PermissionError
+		errors.As(err, &denied)
+		return &httpResponse{Error: err.Error(), Denied: denied}
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, u.String(), strings.NewReader(req.Body))
+	if err != nil {
+		return &httpResponse{Error: err.Error()}
+	}
+	for name, value := range req.Headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	httpResp, err := h.client.Do(httpReq)
+	if err != nil {
+		var denied *plugins.PermissionError
+		errors.As(err, &denied)
+		return &httpResponse{Error: err.Error(), Denied: denied}
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxHTTPResponse))
+	if err != nil {
+		return &httpResponse{Error: err.Error()}
+	}
+
+	resp := &httpResponse{
+		Status:  httpResp.StatusCode,
+		Headers: make(map[string]string, len(httpResp.Header)),
+		Body:    string(body),
+	}
+	for name := range httpResp.Header {
+		resp.Headers[name] = httpResp.Header.Get(name)
+	}
+	return resp
+}
+
+// httpResponse copies the last HTTP response into plugin memory.
+func (h *host) httpResponse(ctx context.Context, m api.Module, ptr uint32) {
+	sb := sandboxFrom(ctx)
+	if sb == nil {
+		return
+	}
+
+	sb.mu.Lock()
+	data := sb.pending
+	sb.pending = nil
+	sb.mu.Unlock()
+
+	m.Memory().Write(ptr, data)
+}
+
+// checkHost returns a PermissionError, and records the violation for the
+// calling instance, if the network permissions don't allow a URL's host.
+func (h *host) checkHost(ctx context.Context, u *url.URL) error {
+	if h.permissions.AllowsHost(u.Host) {
+		return nil
+	}
+	if sb := sandboxFrom(ctx); sb != nil {
+		return sb.deny(plugins.PermissionNetwork, u.Host)
+	}
+	return &plugins.PermissionError{Plugin: h.plugin, Permission: plugins.PermissionNetwork, Resource: u.Host}
+}
+
+// fsConfig mounts the directories a plugin may access. Each entry is a
+// directory, mounted at its absolute path so paths in node config work
+// unchanged; a :ro suffix mounts it read-only. Everything else is backed by
+// a deniedFS, so access outside the mounts is reported as a violation of
+// the instance's sandbox.
+func fsConfig(sb *sandbox, entries []string) (wazero.FSConfig, error) {
+	config := wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(&deniedFS{sandbox: sb}, "/")
+	for _, entry := range entries {
+		dir, readOnly := strings.CutSuffix(entry, ":ro")
+
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filesystem permission %q: %w", entry, err)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filesystem permission %q: %w", entry, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("invalid filesystem permission %q: not a directory", entry)
+		}
+
+		guest := filepath.ToSlash(abs)
+		if readOnly {
+			config = config.WithReadOnlyDirMount(abs, guest)
+		} else {
+			config = config.WithDirMount(abs, guest)
+		}
+	}
+	return config, nil
+}
+
+// deniedFS is mounted at the guest root. WASI resolves paths against the
+// most specific mount, so only paths outside the permitted directories reach
+// it, and it records each as a violation.
+type deniedFS struct {
+	experimentalsys.UnimplementedFS
+	sandbox *sandbox
+}
+
+func (d *deniedFS) deny(name string) experimentalsys.Errno {
+	_ = d.sandbox.deny(plugins.PermissionFilesystem, path.Join("/", name))
+	return experimentalsys.EACCES
+}
+
+func (d *deniedFS) OpenFile(name string, _ experimentalsys.Oflag, _ fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	// WASI opens the root itself to list it as a preopen
+	if name == "." {
+		return &deniedDir{fs: d}, 0
+	}
+	return nil, d.deny(name)
+}
+
+func (d *deniedFS) Lstat(name string) (sys.Stat_t, experimentalsys.Errno) {
+	return sys.Stat_t{}, d.deny(name)
+}
+
+func (d *deniedFS) Stat(name string) (sys.Stat_t, experimentalsys.Errno) {
+	return sys.Stat_t{}, d.deny(name)
+}
+
+func (d *deniedFS) Readlink(name string) (string, experimentalsys.Errno) {
+	return "", d.deny(name)
+}
+
+func (d *deniedFS) Mkdir(name string, _ fs.FileMode) experimentalsys.Errno {
+	return d.deny(name)
+}
+
+func (d *deniedFS) Chmod(name string, _ fs.FileMode) experimentalsys.Errno {
+	return d.deny(name)
+}
+
+func (d *deniedFS) Rename(from, _ string) experimentalsys.Errno {
+	return d.deny(from)
+}
+
+func (d *deniedFS) Rmdir(name string) experimentalsys.Errno {
+	return d.deny(name)
+}
+
+func (d *deniedFS) Link(oldName, _ string) experimentalsys.Errno {
+	return d.deny(oldName)
+}
+
+func (d *deniedFS) Symlink(_, newName string) experimentalsys.Errno {
+	return d.deny(newName)
+}
+
+func (d *deniedFS) Unlink(name string) experimentalsys.Errno {
+	return d.deny(name)
+}
+
+func (d *deniedFS) Utimens(name string, _, _ int64) experimentalsys.Errno {
+	return d.deny(name)
+}
+
+// deniedDir is the root directory of a deniedFS. It can be opened but not
+// listed.
+type deniedDir struct {
+	experimentalsys.UnimplementedFile
+	fs *deniedFS
+}
+
+func (d *deniedDir) IsDir() (bool, experimentalsys.Errno) {
+	return true, 0
+}
+
+func (d *deniedDir) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	return sys.Stat_t{Mode: fs.ModeDir}, 0
+}
+
+func (d *deniedDir) Readdir(int) ([]experimentalsys.Dirent, experimentalsys.Errno) {
+	return nil, d.fs.deny(".")
+}
+
+// allowedEnv returns the environment variables a plugin may read.
+func allowedEnv(permissions *plugins.Permissions) map[string]string {
+	env := make(map[string]string)
+	if len(permissions.Env) == 0 {
+		return env
+	}
+	for _, kv := range os.Environ() {
+		name, value, ok := strings.Cut(kv, "=")
+		if ok && permissions.AllowsEnv(name) {
+			env[name] = value
+		}
+	}
+	return env
+}
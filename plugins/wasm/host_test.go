@@ -0,0 +1,202 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/pocket/plugins"
+)
+
+// buildSandbox builds the plugin in testdata/sandbox, which reads the files,
+// environment variables, and URLs named in its input.
+func buildSandbox(t *testing.T) []byte {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("Skipping plugin build in short mode")
+	}
+
+	out := filepath.Join(t.TempDir(), "sandbox.wasm")
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", out, ".")
+	cmd.Dir = filepath.Join("testdata", "sandbox")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("Cannot build test plugin: %v\n%s", err, output)
+	}
+
+	wasmBytes, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wasmBytes
+}
+
+func TestPermissions(t *testing.T) {
+	wasmBytes := buildSandbox(t)
+	ctx := context.Background()
+
+	allowed := t.TempDir()
+	if err := os.WriteFile(filepath.Join(allowed, "data.txt"), []byte("allowed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	denied := t.TempDir()
+	if err := os.WriteFile(filepath.Join(denied, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "pong")
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	t.Setenv("SANDBOX_ALLOWED", "visible")
+	t.Setenv("SANDBOX_HIDDEN", "hidden")
+
+	metadata := plugins.Metadata{
+		Name:    "sandbox",
+		Version: "1.0.0",
+		Runtime: "wasm",
+		Permissions: plugins.Permissions{
+			Filesystem: []string{allowed + ":ro"},
+			Env:        []string{"SANDBOX_ALLOWED"},
+			Network:    []string{serverURL.Host},
+		},
+	}
+	p, err := NewPlugin(ctx, wasmBytes, &metadata)
+	if err != nil {
+		t.Fatalf("NewPlugin() error = %v", err)
+	}
+	defer func() { _ = p.Close(ctx) }()
+
+	call := func(input map[string]string) (map[string]any, error) {
+		data, _ := json.Marshal(map[string]any{"node": "sandbox", "function": "exec", "input": input})
+		out, err := p.Call(ctx, "call", data)
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Output map[string]any `json:"output"`
+		}
+		if err := json.Unmarshal(out, &resp); err != nil {
+			t.Fatalf("Invalid response %s: %v", out, err)
+		}
+		return resp.Output, nil
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		out, err := call(map[string]string{
+			"file": filepath.Join(allowed, "data.txt"),
+			"env":  "SANDBOX_ALLOWED",
+			"url":  server.URL,
+		})
+		if err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+		if out["file"] != "allowed" {
+			t.Errorf("file = %v, want allowed", out["file"])
+		}
+		if out["env"] != "visible" {
+			t.Errorf("env = %v, want visible", out["env"])
+		}
+		resp, _ := out["http"].(map[string]any)
+		if resp["body"] != "pong" {
+			t.Errorf("http = %v, want body pong", resp)
+		}
+	})
+
+	t.Run("hidden environment", func(t *testing.T) {
+		out, err := call(map[string]string{"env": "SANDBOX_HIDDEN"})
+		if err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+		if out["env"] != "" {
+			t.Errorf("env = %v, want empty", out["env"])
+		}
+	})
+
+	tests := []struct {
+		name       string
+		input      map[string]string
+		permission string
+		resource   string
+	}{
+		{
+			name:       "file outside mounts",
+			input:      map[string]string{"file": filepath.Join(denied, "secret.txt")},
+			permission: plugins.PermissionFilesystem,
+			resource:   filepath.ToSlash(denied),
+		},
+		{
+			name:       "host not allowed",
+			input:      map[string]string{"url": "http://example.com/"},
+			permission: plugins.PermissionNetwork,
+			resource:   "example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := call(tt.input)
+			var denied *plugins.PermissionError
+			if !errors.As(err, &denied) {
+				t.Fatalf("Call() error = %v, want PermissionError", err)
+			}
+			if denied.Plugin != "sandbox" || denied.Permission != tt.permission {
+				t.Errorf("PermissionError = %+v, want %s access", denied, tt.permission)
+			}
+			if !strings.HasPrefix(denied.Resource, tt.resource) {
+				t.Errorf("Resource = %q, want prefix %q", denied.Resource, tt.resource)
+			}
+		})
+	}
+
+	t.Run("violations are cleared between calls", func(t *testing.T) {
+		if _, err := call(map[string]string{"env": "SANDBOX_ALLOWED"}); err != nil {
+			t.Errorf("Call() error = %v", err)
+		}
+	})
+}
+
+func TestFSConfig(t *testing.T) {
+	sb := &sandbox{plugin: "test"}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fsConfig(sb, []string{dir, dir + ":ro"}); err != nil {
+		t.Errorf("fsConfig() error = %v", err)
+	}
+	for _, entry := range []string{file, filepath.Join(dir, "missing")} {
+		if _, err := fsConfig(sb, []string{entry}); err == nil {
+			t.Errorf("Expected fsConfig(%q) to fail", entry)
+		}
+	}
+}
+
+func TestAllowedEnv(t *testing.T) {
+	t.Setenv("POCKET_TEST_ONE", "1")
+	t.Setenv("POCKET_TEST_TWO", "2")
+	t.Setenv("OTHER_TEST", "3")
+
+	env := allowedEnv(&plugins.Permissions{Env: []string{"POCKET_TEST_*"}})
+	if env["POCKET_TEST_ONE"] != "1" || env["POCKET_TEST_TWO"] != "2" {
+		t.Errorf("allowedEnv() = %v, want POCKET_TEST_ variables", env)
+	}
+	if _, ok := env["OTHER_TEST"]; ok {
+		t.Error("allowedEnv() included a variable that isn't permitted")
+	}
+	if env := allowedEnv(&plugins.Permissions{}); len(env) != 0 {
+		t.Errorf("allowedEnv() with no permissions = %v, want empty", env)
+	}
+}
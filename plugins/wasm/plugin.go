@@ -8,32 +8,44 @@ import (
 	"math"
 	"os"
 	"path/filepath"
-	"sync"
+	"sync/atomic"
 
 	"github.com/tetratelabs/wazero"
-	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 
 	"github.com/agentstation/pocket/plugins"
 )
 
+// compilationCache is shared by all plugins, so a binary is compiled once
+// per process however many times it's loaded.
+var compilationCache = wazero.NewCompilationCache()
+
 // wasmPlugin implements the Plugin interface for WebAssembly plugins.
 type wasmPlugin struct {
 	metadata plugins.Metadata
 	runtime  wazero.Runtime
-	module   api.Module
+	compiled wazero.CompiledModule
+	config   wazero.ModuleConfig
+	host     *host
 
-	// Exported functions
-	callFunc api.Function
+	// Instances of the compiled module
+	pool *pool
 
-	// Mutex for thread safety
-	mu sync.Mutex
+	// Number of instances created, used to name them
+	instances atomic.Uint64
 }
 
-// NewPlugin creates a new WebAssembly plugin from bytes.
-func NewPlugin(ctx context.Context, wasmBytes []byte, metadata *plugins.Metadata) (plugins.Plugin, error) {
+// NewPlugin creates a new WebAssembly plugin from bytes. The module is
+// compiled once, and calls are served by a pool of instances, so they run
+// concurrently without instantiating the module each time.
+func NewPlugin(ctx context.Context, wasmBytes []byte, metadata *plugins.Metadata, opts ...Option) (plugins.Plugin, error) {
+	o := options{poolSize: defaultPoolSize, idleTimeout: defaultIdleTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	// Create runtime with configuration
-	runtimeConfig := wazero.NewRuntimeConfig()
+	runtimeConfig := wazero.NewRuntimeConfig().WithCompilationCache(compilationCache)
 
 	// Set memory limit if specified
 	if metadata.Permissions.Memory != "" {
@@ -54,6 +66,14 @@ func NewPlugin(ctx context.Context, wasmBytes []byte, metadata *plugins.Metadata
 	// Initialize WASI if needed
 	wasi_snapshot_preview1.MustInstantiate(ctx, r)
 
+	// Host functions for access WASI doesn't provide, checked against the
+	// plugin's permissions
+	h := newHost(metadata)
+	if err := h.instantiate(ctx, r); err != nil {
+		_ = r.Close(ctx)
+		return nil, fmt.Errorf("failed to register host functions: %w", err)
+	}
+
 	// Compile the module
 	compiled, err := r.CompileModule(ctx, wasmBytes)
 	if err != nil {
@@ -63,65 +83,74 @@ func NewPlugin(ctx context.Context, wasmBytes []byte, metadata *plugins.Metadata
 
 	// Configure module with sandboxing
 	moduleConfig := wazero.NewModuleConfig().
-		WithName(metadata.Name).
-		WithStartFunctions() // Don't auto-call _start
+		WithStartFunctions("_initialize") // Initialize WASI reactors, but don't auto-call _start
 
-	// Add allowed environment variables
-	for _, envVar := range metadata.Permissions.Env {
-		if value := os.Getenv(envVar); value != "" {
-			moduleConfig = moduleConfig.WithEnv(envVar, value)
-		}
+	// Only pass the environment variables the plugin may read
+	for name, value := range allowedEnv(&metadata.Permissions) {
+		moduleConfig = moduleConfig.WithEnv(name, value)
 	}
 
-	// Configure filesystem access if permitted
-	if len(metadata.Permissions.Filesystem) > 0 {
-		// For now, we'll mount allowed directories
-		// In production, we'd want more fine-grained control
-		for _, path := range metadata.Permissions.Filesystem {
-			if stat, err := os.Stat(path); err == nil && stat.IsDir() {
-				moduleConfig = moduleConfig.WithFS(os.DirFS(path))
-				break // wazero only supports one FS mount currently
-			}
-		}
+	p := &wasmPlugin{
+		metadata: *metadata,
+		runtime:  r,
+		compiled: compiled,
+		config:   moduleConfig,
+		host:     h,
 	}
 
-	// Instantiate the module
-	module, err := r.InstantiateModule(ctx, compiled, moduleConfig)
+	// Instantiate the first instance now, so a broken plugin fails to load
+	// and the first call is warm
+	p.pool = newPool(metadata.Name, &o, instanceReserve(compiled, metadata), p.instantiate)
+	inst, err := p.pool.create(ctx)
 	if err != nil {
+		p.pool.close(ctx)
 		_ = r.Close(ctx)
-		return nil, fmt.Errorf("failed to instantiate WASM module: %w", err)
+		return nil, err
 	}
+	p.pool.warm(inst)
 
-	// Get the main call function
-	callFunc := module.ExportedFunction("__pocket_call")
-	if callFunc == nil {
-		_ = module.Close(ctx)
-		_ = r.Close(ctx)
-		return nil, fmt.Errorf("plugin does not export required function: __pocket_call")
+	return p, nil
+}
+
+// instanceReserve returns the memory charged to a new instance: the
+// plugin's memory limit if it has one, or the module's initial memory.
+func instanceReserve(compiled wazero.CompiledModule, metadata *plugins.Metadata) uint64 {
+	if limit, err := parseMemoryLimit(metadata.Permissions.Memory); err == nil {
+		return limit
+	}
+	if def, ok := compiled.ExportedMemories()["memory"]; ok {
+		return uint64(def.Min()) * 65536
 	}
+	return 0
+}
 
-	// Get memory exports for passing data
-	memory := module.ExportedMemory("memory")
-	if memory == nil {
-		_ = module.Close(ctx)
-		_ = r.Close(ctx)
-		return nil, fmt.Errorf("plugin does not export memory")
+// instantiate creates an instance of the plugin's module with its own
+// sandbox.
+func (p *wasmPlugin) instantiate(ctx context.Context) (*instance, error) {
+	sb := &sandbox{plugin: p.metadata.Name}
+
+	// Only mount the directories the plugin may access
+	mounts, err := fsConfig(sb, p.metadata.Permissions.Filesystem)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get allocation functions
-	allocFunc := module.ExportedFunction("__pocket_alloc")
-	if allocFunc == nil {
+	name := fmt.Sprintf("%s-%d", p.metadata.Name, p.instances.Add(1))
+	module, err := p.runtime.InstantiateModule(ctx, p.compiled, p.config.WithName(name).WithFSConfig(mounts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate WASM module: %w", err)
+	}
+	if err := sb.takeViolations(); err != nil {
 		_ = module.Close(ctx)
-		_ = r.Close(ctx)
-		return nil, fmt.Errorf("plugin does not export required function: __pocket_alloc")
+		return nil, err
 	}
 
-	return &wasmPlugin{
-		metadata: *metadata,
-		runtime:  r,
-		module:   module,
-		callFunc: callFunc,
-	}, nil
+	inst, err := newInstance(module, sb)
+	if err != nil {
+		_ = module.Close(ctx)
+		return nil, err
+	}
+	return inst, nil
 }
 
 // Metadata returns the plugin's metadata.
@@ -129,11 +158,9 @@ func (p *wasmPlugin) Metadata() plugins.Metadata {
 	return p.metadata
 }
 
-// Call invokes a function exported by the plugin.
+// Call invokes a function exported by the plugin, on an idle instance if
+// there is one.
 func (p *wasmPlugin) Call(ctx context.Context, function string, input []byte) ([]byte, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	// Apply timeout if configured
 	if p.metadata.Permissions.Timeout > 0 {
 		var cancel context.CancelFunc
@@ -141,78 +168,36 @@ func (p *wasmPlugin) Call(ctx context.Context, function string, input []byte) ([
 		defer cancel()
 	}
 
-	// Get memory and allocation functions
-	memory := p.module.ExportedMemory("memory")
-	allocFunc := p.module.ExportedFunction("__pocket_alloc")
-	freeFunc := p.module.ExportedFunction("__pocket_free")
-
-	// Allocate memory for input
-	if len(input) > math.MaxUint32 {
-		return nil, fmt.Errorf("input too large: %d bytes", len(input))
-	}
-	inputLen := uint32(len(input)) //nolint:gosec // length is checked above
-	results, err := allocFunc.Call(ctx, uint64(inputLen))
-	if err != nil {
-		return nil, fmt.Errorf("failed to allocate memory: %w", err)
-	}
-
-	if results[0] > math.MaxUint32 {
-		return nil, fmt.Errorf("input pointer overflow")
-	}
-	inputPtr := uint32(results[0]) //nolint:gosec // pointer is checked above
-
-	// Write input to WASM memory
-	if !memory.Write(inputPtr, input) {
-		return nil, fmt.Errorf("failed to write input to memory")
-	}
-
-	// Call the function
-	results, err = p.callFunc.Call(ctx, uint64(inputPtr), uint64(inputLen))
+	inst, err := p.pool.get(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("plugin call failed: %w", err)
-	}
-
-	// Free input memory
-	if freeFunc != nil {
-		_, _ = freeFunc.Call(ctx, uint64(inputPtr), uint64(inputLen))
-	}
-
-	// Read the result
-	if results[0] > math.MaxUint32 || results[1] > math.MaxUint32 {
-		return nil, fmt.Errorf("result pointer/length overflow")
-	}
-	resultPtr := uint32(results[0]) //nolint:gosec // pointers are checked above
-	resultLen := uint32(results[1]) //nolint:gosec // pointers are checked above
-
-	if resultLen == 0 {
-		return nil, nil
-	}
-
-	output, ok := memory.Read(resultPtr, resultLen)
-	if !ok {
-		return nil, fmt.Errorf("failed to read output from memory")
+		return nil, err
 	}
+	output, err := inst.invoke(ctx, input)
+	p.pool.put(ctx, inst, err != nil)
+	return output, err
+}
 
-	// Free output memory
-	if freeFunc != nil {
-		_, _ = freeFunc.Call(ctx, uint64(resultPtr), uint64(resultLen))
+// callResult returns the location of a response. __pocket_call either
+// returns the pointer and length, or a single value with the pointer in the
+// high 32 bits and the length in the low 32 bits, since Go and TinyGo
+// exports can only return one value.
+func callResult(results []uint64) (ptr, size uint32, err error) {
+	switch len(results) {
+	case 1:
+		return uint32(results[0] >> 32), uint32(results[0]), nil //nolint:gosec // halves of a packed value
+	case 2:
+		if results[0] > math.MaxUint32 || results[1] > math.MaxUint32 {
+			return 0, 0, fmt.Errorf("result pointer/length overflow")
+		}
+		return uint32(results[0]), uint32(results[1]), nil //nolint:gosec // values are checked above
 	}
-
-	return output, nil
+	return 0, 0, fmt.Errorf("__pocket_call returned %d values, expected 1 or 2", len(results))
 }
 
-// Close releases plugin resources.
+// Close waits for calls in progress and releases plugin resources.
 func (p *wasmPlugin) Close(ctx context.Context) error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	if p.module != nil {
-		_ = p.module.Close(ctx)
-	}
-	if p.runtime != nil {
-		return p.runtime.Close(ctx)
-	}
-	return nil
+	p.pool.close(ctx)
+	return p.runtime.Close(ctx)
 }
 
 // LoadPlugin loads a WebAssembly plugin from a file.
@@ -73,6 +73,33 @@ func TestParseMemoryLimit(t *testing.T) {
 	}
 }
 
+func TestCallResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  []uint64
+		wantPtr  uint32
+		wantSize uint32
+		wantErr  bool
+	}{
+		{"pointer and length", []uint64{1024, 42}, 1024, 42, false},
+		{"packed", []uint64{1024<<32 | 42}, 1024, 42, false},
+		{"overflow", []uint64{1 << 32, 42}, 0, 0, true},
+		{"no results", nil, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ptr, size, err := callResult(tt.results)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("callResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ptr != tt.wantPtr || size != tt.wantSize {
+				t.Errorf("callResult() = (%d, %d), want (%d, %d)", ptr, size, tt.wantPtr, tt.wantSize)
+			}
+		})
+	}
+}
+
 func TestLoadPlugin(t *testing.T) {
 	// Create a temporary directory for test files
 	tmpDir := t.TempDir()
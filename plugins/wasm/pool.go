@@ -0,0 +1,393 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Pool defaults.
+const (
+	defaultPoolSize    = 4
+	defaultIdleTimeout = 5 * time.Minute
+)
+
+// errPluginClosed is returned by calls to a closed plugin.
+var errPluginClosed = errors.New("plugin is closed")
+
+// Option configures a WebAssembly plugin.
+type Option func(*options)
+
+type options struct {
+	poolSize    int
+	idleTimeout time.Duration
+	resources   *Resources
+}
+
+// WithPoolSize sets the maximum number of instances of a plugin, which is
+// how many calls it runs concurrently. Further calls wait for an instance.
+// The default is 4.
+func WithPoolSize(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.poolSize = n
+		}
+	}
+}
+
+// WithIdleTimeout sets how long an unused instance is kept before it's
+// closed. One instance is always kept warm. Zero keeps instances until the
+// plugin is closed. The default is 5 minutes.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = d
+	}
+}
+
+// instance is an instantiated plugin module. An instance runs one call at
+// a time.
+type instance struct {
+	module   api.Module
+	memory   api.Memory
+	alloc    api.Function
+	free     api.Function
+	call     api.Function
+	sandbox  *sandbox
+	lastUsed time.Time
+
+	// charge is the memory accounted to the instance
+	charge uint64
+}
+
+// newInstance checks a module exports the plugin ABI.
+func newInstance(module api.Module, sb *sandbox) (*instance, error) {
+	inst := &instance{
+		module:  module,
+		memory:  module.ExportedMemory("memory"),
+		alloc:   module.ExportedFunction("__pocket_alloc"),
+		free:    module.ExportedFunction("__pocket_free"),
+		call:    module.ExportedFunction("__pocket_call"),
+		sandbox: sb,
+	}
+	switch {
+	case inst.call == nil:
+		return nil, fmt.Errorf("plugin does not export required function: __pocket_call")
+	case inst.memory == nil:
+		return nil, fmt.Errorf("plugin does not export memory")
+	case inst.alloc == nil:
+		return nil, fmt.Errorf("plugin does not export required function: __pocket_alloc")
+	}
+	return inst, nil
+}
+
+// invoke passes input to __pocket_call and returns the response.
+func (inst *instance) invoke(ctx context.Context, input []byte) ([]byte, error) {
+	ctx = withSandbox(ctx, inst.sandbox)
+
+	// Allocate memory for input
+	if len(input) > math.MaxUint32 {
+		return nil, fmt.Errorf("input too large: %d bytes", len(input))
+	}
+	inputLen := uint32(len(input)) //nolint:gosec // length is checked above
+	results, err := inst.alloc.Call(ctx, uint64(inputLen))
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate memory: %w", err)
+	}
+
+	if results[0] > math.MaxUint32 {
+		return nil, fmt.Errorf("input pointer overflow")
+	}
+	inputPtr := uint32(results[0]) //nolint:gosec // pointer is checked above
+
+	// Write input to WASM memory
+	if !inst.memory.Write(inputPtr, input) {
+		return nil, fmt.Errorf("failed to write input to memory")
+	}
+
+	// Call the function
+	results, err = inst.call.Call(ctx, uint64(inputPtr), uint64(inputLen))
+	if violations := inst.sandbox.takeViolations(); violations != nil {
+		return nil, violations
+	}
+	if err != nil {
+		return nil, fmt.Errorf("plugin call failed: %w", err)
+	}
+
+	// Free input memory
+	if inst.free != nil {
+		_, _ = inst.free.Call(ctx, uint64(inputPtr), uint64(inputLen))
+	}
+
+	// Read the result
+	resultPtr, resultLen, err := callResult(results)
+	if err != nil {
+		return nil, err
+	}
+
+	if resultLen == 0 {
+		return nil, nil
+	}
+
+	output, ok := inst.memory.Read(resultPtr, resultLen)
+	if !ok {
+		return nil, fmt.Errorf("failed to read output from memory")
+	}
+	// Copy the output, since the memory is reused by later calls
+	output = append([]byte(nil), output...)
+
+	// Free output memory
+	if inst.free != nil {
+		_, _ = inst.free.Call(ctx, uint64(resultPtr), uint64(resultLen))
+	}
+
+	return output, nil
+}
+
+// pool holds the instances of a plugin. Idle instances are reused, so calls
+// don't pay for instantiation, and instances idle for longer than the idle
+// timeout are closed, keeping one warm.
+type pool struct {
+	plugin      string
+	instantiate func(context.Context) (*instance, error)
+	idleTimeout time.Duration
+
+	// resources accounts for the pool's instances, charging each at least
+	// reserve bytes
+	resources *Resources
+	reserve   uint64
+
+	// slots bounds the number of instances
+	slots chan struct{}
+
+	mu       sync.Mutex
+	idle     []*instance // least recently used first
+	closed   bool
+	calls    sync.WaitGroup
+	done     chan struct{}
+	stopped  chan struct{}
+	evicting bool
+}
+
+func newPool(plugin string, o *options, reserve uint64, instantiate func(context.Context) (*instance, error)) *pool {
+	p := &pool{
+		plugin:      plugin,
+		instantiate: instantiate,
+		idleTimeout: o.idleTimeout,
+		resources:   o.resources,
+		reserve:     reserve,
+		slots:       make(chan struct{}, o.poolSize),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	if p.resources != nil {
+		p.resources.register(p)
+	}
+	if p.idleTimeout > 0 {
+		p.evicting = true
+		go p.evictIdle()
+	}
+	return p
+}
+
+// get returns an idle instance, or a new one if none is idle, waiting while
+// the pool is full.
+func (p *pool) get(ctx context.Context) (*instance, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errPluginClosed
+	}
+	p.calls.Add(1)
+	p.mu.Unlock()
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		p.calls.Done()
+		return nil, ctx.Err()
+	case <-p.done:
+		p.calls.Done()
+		return nil, errPluginClosed
+	}
+
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		inst := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return inst, nil
+	}
+	p.mu.Unlock()
+
+	inst, err := p.create(ctx)
+	if err != nil {
+		p.release()
+		return nil, err
+	}
+	return inst, nil
+}
+
+// create instantiates a new instance, charging it to the resources.
+func (p *pool) create(ctx context.Context) (*instance, error) {
+	if p.resources != nil {
+		if err := p.resources.acquire(ctx, p.plugin, p.reserve); err != nil {
+			return nil, err
+		}
+	}
+
+	inst, err := p.instantiate(ctx)
+	if err != nil {
+		if p.resources != nil {
+			p.resources.release(p.reserve)
+		}
+		return nil, err
+	}
+	inst.charge = p.reserve
+	return inst, nil
+}
+
+// discard closes an instance and releases its charge.
+func (p *pool) discard(ctx context.Context, inst *instance) {
+	_ = inst.module.Close(ctx)
+	if p.resources != nil {
+		p.resources.release(inst.charge)
+	}
+}
+
+// put returns an instance to the pool. Instances whose call failed are
+// closed rather than reused, since a trap can leave them in a bad state.
+func (p *pool) put(ctx context.Context, inst *instance, failed bool) {
+	defer p.release()
+
+	if failed {
+		p.discard(ctx, inst)
+		return
+	}
+
+	// Charge what the call left the instance using
+	if p.resources != nil {
+		charge := max(p.reserve, uint64(inst.memory.Size()))
+		p.resources.resize(inst.charge, charge)
+		inst.charge = charge
+	}
+
+	inst.lastUsed = time.Now()
+	p.mu.Lock()
+	p.idle = append(p.idle, inst)
+	p.mu.Unlock()
+
+	// Let plugins waiting for resources reclaim the instance
+	if p.resources != nil {
+		p.resources.notify()
+	}
+}
+
+// warm adds an instance to the pool without a call.
+func (p *pool) warm(inst *instance) {
+	inst.lastUsed = time.Now()
+	p.mu.Lock()
+	p.idle = append(p.idle, inst)
+	p.mu.Unlock()
+}
+
+func (p *pool) release() {
+	<-p.slots
+	p.calls.Done()
+}
+
+// evictIdle periodically closes instances idle for longer than the idle
+// timeout.
+func (p *pool) evictIdle() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(max(p.idleTimeout/2, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.evict(now)
+		}
+	}
+}
+
+// evict closes instances idle since before now minus the idle timeout,
+// keeping the most recently used one.
+func (p *pool) evict(now time.Time) {
+	p.mu.Lock()
+	var stale []*instance
+	for len(p.idle) > 1 && now.Sub(p.idle[0].lastUsed) > p.idleTimeout {
+		stale = append(stale, p.idle[0])
+		p.idle = p.idle[1:]
+	}
+	p.mu.Unlock()
+
+	for _, inst := range stale {
+		p.discard(context.Background(), inst)
+	}
+}
+
+// oldestIdle returns when the least recently used idle instance was used.
+func (p *pool) oldestIdle() (time.Time, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle) == 0 {
+		return time.Time{}, false
+	}
+	return p.idle[0].lastUsed, true
+}
+
+// takeOldestIdle removes and returns the least recently used idle instance.
+func (p *pool) takeOldestIdle() *instance {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle) == 0 {
+		return nil
+	}
+	inst := p.idle[0]
+	p.idle = p.idle[1:]
+	return inst
+}
+
+// size returns the number of idle instances.
+func (p *pool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle)
+}
+
+// close waits for calls in progress and closes the instances.
+func (p *pool) close(ctx context.Context) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	close(p.done)
+	p.calls.Wait()
+	if p.evicting {
+		<-p.stopped
+	}
+
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, inst := range idle {
+		p.discard(ctx, inst)
+	}
+	if p.resources != nil {
+		p.resources.unregister(p)
+	}
+}
@@ -0,0 +1,108 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket/plugins"
+)
+
+func TestPool(t *testing.T) {
+	wasmBytes := buildSandbox(t)
+	ctx := context.Background()
+	t.Setenv("POOL_TEST", "pooled")
+
+	metadata := plugins.Metadata{
+		Name:        "sandbox",
+		Version:     "1.0.0",
+		Runtime:     "wasm",
+		Permissions: plugins.Permissions{Env: []string{"POOL_TEST"}},
+	}
+	loaded, err := NewPlugin(ctx, wasmBytes, &metadata, WithPoolSize(3), WithIdleTimeout(time.Minute))
+	if err != nil {
+		t.Fatalf("NewPlugin() error = %v", err)
+	}
+	p := loaded.(*wasmPlugin)
+
+	if n := p.pool.size(); n != 1 {
+		t.Errorf("Warm instances = %d, want 1", n)
+	}
+
+	request, _ := json.Marshal(map[string]any{"node": "sandbox", "function": "exec", "input": map[string]string{"env": "POOL_TEST"}})
+
+	t.Run("concurrent calls", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				out, err := p.Call(ctx, "exec", request)
+				if err != nil {
+					errs <- err
+					return
+				}
+				var resp struct {
+					Output map[string]any `json:"output"`
+				}
+				if err := json.Unmarshal(out, &resp); err != nil || resp.Output["env"] != "pooled" {
+					errs <- errors.New("unexpected response: " + string(out))
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+
+		if n := p.pool.size(); n < 1 || n > 3 {
+			t.Errorf("Idle instances = %d, want 1 to 3", n)
+		}
+	})
+
+	t.Run("evicts idle instances", func(t *testing.T) {
+		p.pool.evict(time.Now().Add(2 * time.Minute))
+		if n := p.pool.size(); n != 1 {
+			t.Errorf("Idle instances after eviction = %d, want 1", n)
+		}
+		if _, err := p.Call(ctx, "exec", request); err != nil {
+			t.Errorf("Call() after eviction error = %v", err)
+		}
+	})
+
+	t.Run("waits for a free instance", func(t *testing.T) {
+		// Hold every instance so the next call has to wait
+		var held []*instance
+		for range 3 {
+			inst, err := p.pool.get(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			held = append(held, inst)
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if _, err := p.Call(waitCtx, "exec", request); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Call() with a full pool error = %v, want deadline exceeded", err)
+		}
+
+		for _, inst := range held {
+			p.pool.put(ctx, inst, false)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		if err := p.Close(ctx); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if _, err := p.Call(ctx, "exec", request); !errors.Is(err, errPluginClosed) {
+			t.Errorf("Call() after Close() error = %v, want %v", err, errPluginClosed)
+		}
+	})
+}
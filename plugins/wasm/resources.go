@@ -0,0 +1,177 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Resources accounts for the memory and instances of all the plugins that
+// share it, and caps their totals, so a workflow that fans out into plugin
+// nodes can't exhaust the host.
+//
+// When a plugin needs a new instance and the caps are reached, idle
+// instances of any plugin sharing the Resources are closed, least recently
+// used first. If none are idle, the call waits for an instance to be
+// returned or for its context to be done.
+//
+// An instance is charged its memory limit if the plugin's manifest sets
+// one, or its current memory size otherwise, updated after each call.
+// Without a memory limit an instance can grow past the cap during a call,
+// so set permissions.memory where the cap matters.
+type Resources struct {
+	maxMemory    uint64
+	maxInstances int
+
+	mu        sync.Mutex
+	memory    uint64
+	instances int
+	pools     map[*pool]struct{}
+
+	// changed is closed, and replaced, when resources are released or
+	// instances become idle
+	changed chan struct{}
+}
+
+// Usage is a snapshot of the resources used by plugins.
+type Usage struct {
+	Memory       uint64 `json:"memory"`
+	Instances    int    `json:"instances"`
+	MaxMemory    uint64 `json:"maxMemory,omitempty"`
+	MaxInstances int    `json:"maxInstances,omitempty"`
+}
+
+// NewResources creates Resources with caps on total memory in bytes and
+// total instances. Zero means no cap.
+func NewResources(maxMemory uint64, maxInstances int) *Resources {
+	return &Resources{
+		maxMemory:    maxMemory,
+		maxInstances: maxInstances,
+		pools:        make(map[*pool]struct{}),
+		changed:      make(chan struct{}),
+	}
+}
+
+// WithResources makes a plugin share resource accounting and caps with
+// other plugins using the same Resources.
+func WithResources(r *Resources) Option {
+	return func(o *options) {
+		o.resources = r
+	}
+}
+
+// Usage returns the resources currently used.
+func (r *Resources) Usage() Usage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Usage{
+		Memory:       r.memory,
+		Instances:    r.instances,
+		MaxMemory:    r.maxMemory,
+		MaxInstances: r.maxInstances,
+	}
+}
+
+// acquire charges a new instance of a plugin, closing idle instances or
+// waiting if the caps are reached.
+func (r *Resources) acquire(ctx context.Context, plugin string, charge uint64) error {
+	if r.maxMemory > 0 && charge > r.maxMemory {
+		return fmt.Errorf("plugin %s needs %d bytes of memory, more than the %d byte limit", plugin, charge, r.maxMemory)
+	}
+
+	for {
+		r.mu.Lock()
+		if r.fits(charge) {
+			r.memory += charge
+			r.instances++
+			r.mu.Unlock()
+			return nil
+		}
+		changed := r.changed
+		pools := make([]*pool, 0, len(r.pools))
+		for p := range r.pools {
+			pools = append(pools, p)
+		}
+		r.mu.Unlock()
+
+		if reclaimIdle(pools) {
+			continue
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("plugin %s: waiting for resources: %w", plugin, ctx.Err())
+		}
+	}
+}
+
+func (r *Resources) fits(charge uint64) bool {
+	if r.maxInstances > 0 && r.instances+1 > r.maxInstances {
+		return false
+	}
+	return r.maxMemory == 0 || r.memory+charge <= r.maxMemory
+}
+
+// release removes the charge of a closed instance.
+func (r *Resources) release(charge uint64) {
+	r.mu.Lock()
+	r.memory -= min(charge, r.memory)
+	r.instances--
+	r.signal()
+	r.mu.Unlock()
+}
+
+// resize updates the charge of an instance.
+func (r *Resources) resize(from, to uint64) {
+	r.mu.Lock()
+	r.memory = r.memory - min(from, r.memory) + to
+	r.mu.Unlock()
+}
+
+// notify wakes waiting acquires after an instance becomes idle.
+func (r *Resources) notify() {
+	r.mu.Lock()
+	r.signal()
+	r.mu.Unlock()
+}
+
+// signal wakes waiting acquires. r.mu must be held.
+func (r *Resources) signal() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+func (r *Resources) register(p *pool) {
+	r.mu.Lock()
+	r.pools[p] = struct{}{}
+	r.mu.Unlock()
+}
+
+func (r *Resources) unregister(p *pool) {
+	r.mu.Lock()
+	delete(r.pools, p)
+	r.mu.Unlock()
+}
+
+// reclaimIdle closes the least recently used idle instance in any of the
+// pools, and reports whether there was one.
+func reclaimIdle(pools []*pool) bool {
+	var (
+		oldest   *pool
+		lastUsed time.Time
+	)
+	for _, p := range pools {
+		if t, ok := p.oldestIdle(); ok && (oldest == nil || t.Before(lastUsed)) {
+			oldest, lastUsed = p, t
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	if inst := oldest.takeOldestIdle(); inst != nil {
+		oldest.discard(context.Background(), inst)
+	}
+	return true
+}
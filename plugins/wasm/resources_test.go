@@ -0,0 +1,103 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentstation/pocket/plugins"
+)
+
+func TestResources(t *testing.T) {
+	wasmBytes := buildSandbox(t)
+	ctx := context.Background()
+	request := []byte(`{"node":"sandbox","function":"exec","input":{}}`)
+
+	resources := NewResources(0, 2)
+	load := func(name string) *wasmPlugin {
+		t.Helper()
+		metadata := plugins.Metadata{Name: name, Version: "1.0.0", Runtime: "wasm"}
+		p, err := NewPlugin(ctx, wasmBytes, &metadata, WithResources(resources), WithPoolSize(3))
+		if err != nil {
+			t.Fatalf("NewPlugin(%s) error = %v", name, err)
+		}
+		t.Cleanup(func() { _ = p.Close(ctx) })
+		return p.(*wasmPlugin)
+	}
+	a, b := load("a"), load("b")
+
+	usage := resources.Usage()
+	if usage.Instances != 2 || usage.MaxInstances != 2 || usage.Memory == 0 {
+		t.Errorf("Usage() after loading = %+v, want 2 instances with memory", usage)
+	}
+
+	// Hold a's warm instance, so its next call needs another
+	held, err := a.pool.get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("reclaims idle instances of other plugins", func(t *testing.T) {
+		if _, err := a.Call(ctx, "exec", request); err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+		if n := b.pool.size(); n != 0 {
+			t.Errorf("Idle instances of b = %d, want 0 after reclaim", n)
+		}
+		if n := resources.Usage().Instances; n != 2 {
+			t.Errorf("Instances = %d, want 2", n)
+		}
+	})
+
+	t.Run("waits when every instance is busy", func(t *testing.T) {
+		second, err := a.pool.get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer a.pool.put(ctx, second, false)
+
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if _, err := b.Call(waitCtx, "exec", request); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Call() error = %v, want deadline exceeded", err)
+		}
+	})
+
+	a.pool.put(ctx, held, false)
+
+	t.Run("runs once an instance is idle", func(t *testing.T) {
+		if _, err := b.Call(ctx, "exec", request); err != nil {
+			t.Errorf("Call() error = %v", err)
+		}
+	})
+
+	t.Run("releases on close", func(t *testing.T) {
+		if err := a.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Close(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if usage := resources.Usage(); usage.Instances != 0 || usage.Memory != 0 {
+			t.Errorf("Usage() after close = %+v, want none", usage)
+		}
+	})
+}
+
+func TestResourcesMemoryCap(t *testing.T) {
+	wasmBytes := buildSandbox(t)
+	ctx := context.Background()
+
+	metadata := plugins.Metadata{
+		Name:        "big",
+		Version:     "1.0.0",
+		Runtime:     "wasm",
+		Permissions: plugins.Permissions{Memory: "64MB"},
+	}
+	_, err := NewPlugin(ctx, wasmBytes, &metadata, WithResources(NewResources(32<<20, 0)))
+	if err == nil || !strings.Contains(err.Error(), "more than the") {
+		t.Errorf("NewPlugin() error = %v, want memory cap error", err)
+	}
+}
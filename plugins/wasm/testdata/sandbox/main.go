@@ -0,0 +1,102 @@
+//go:build wasip1
+
+// Package main is a test plugin that reads files, environment variables,
+// and URLs named in its input, to check the host enforces permissions.
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"unsafe"
+)
+
+type request struct {
+	Input struct {
+		File string `json:"file,omitempty"`
+		Env  string `json:"env,omitempty"`
+		URL  string `json:"url,omitempty"`
+	} `json:"input"`
+}
+
+type response struct {
+	Success bool           `json:"success"`
+	Error   string         `json:"error,omitempty"`
+	Output  map[string]any `json:"output,omitempty"`
+}
+
+//go:wasmimport pocket http_request
+func httpRequest(ptr, size uint32) uint32
+
+//go:wasmimport pocket http_response
+func httpResponse(ptr uint32)
+
+func handle(req *request) response {
+	out := map[string]any{}
+	if req.Input.File != "" {
+		data, err := os.ReadFile(req.Input.File)
+		if err != nil {
+			out["fileError"] = err.Error()
+		} else {
+			out["file"] = string(data)
+		}
+	}
+	if req.Input.Env != "" {
+		out["env"] = os.Getenv(req.Input.Env)
+	}
+	if req.Input.URL != "" {
+		data, _ := json.Marshal(map[string]string{"url": req.Input.URL})
+		buf := make([]byte, httpRequest(ptrOf(data), uint32(len(data))))
+		if len(buf) > 0 {
+			httpResponse(ptrOf(buf))
+		}
+		var resp map[string]any
+		_ = json.Unmarshal(buf, &resp)
+		out["http"] = resp
+	}
+	return response{Success: true, Output: out}
+}
+
+func ptrOf(b []byte) uint32 {
+	return uint32(uintptr(unsafe.Pointer(&b[0])))
+}
+
+// allocations keeps buffers shared with the host alive until it frees them.
+var allocations = map[uint32][]byte{}
+
+func alloc(size uint32) uint32 {
+	if size == 0 {
+		size = 1
+	}
+	buf := make([]byte, size)
+	ptr := ptrOf(buf)
+	allocations[ptr] = buf
+	return ptr
+}
+
+//go:wasmexport __pocket_alloc
+func pocketAlloc(size uint32) uint32 {
+	return alloc(size)
+}
+
+//go:wasmexport __pocket_free
+func pocketFree(ptr, size uint32) {
+	delete(allocations, ptr)
+}
+
+//go:wasmexport __pocket_call
+func pocketCall(ptr, size uint32) uint64 {
+	var req request
+	resp := response{}
+	if err := json.Unmarshal(allocations[ptr][:size], &req); err != nil {
+		resp = response{Error: err.Error()}
+	} else {
+		resp = handle(&req)
+	}
+
+	data, _ := json.Marshal(resp)
+	out := alloc(uint32(len(data)))
+	copy(allocations[out], data)
+	return uint64(out)<<32 | uint64(len(data))
+}
+
+func main() {}
@@ -23,7 +23,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -37,6 +40,17 @@ var (
 
 	// ErrInvalidInput is returned when input type doesn't match expected type.
 	ErrInvalidInput = errors.New("pocket: invalid input type")
+
+	// ErrBulkheadFull is returned when a node's bulkhead pool and its queue
+	// are both full.
+	ErrBulkheadFull = errors.New("pocket: bulkhead full")
+
+	// ErrReadOnlyStore is returned when writing to a read-only store view.
+	ErrReadOnlyStore = errors.New("pocket: store is read-only")
+
+	// ErrNotListable is returned when listing the keys of a store that
+	// can't enumerate them.
+	ErrNotListable = errors.New("pocket: store can't list keys")
 )
 
 // PrepFunc prepares data before execution with read-only store access.
@@ -137,11 +151,21 @@ type node struct {
 	inputType  reflect.Type
 	outputType reflect.Type
 
-	// Successors maps action names to next nodes.
+	// Successors maps action names to next nodes. Connect replaces the map
+	// rather than modifying it, under mu, so nodes can be connected while
+	// graphs run them.
+	mu         sync.Mutex
 	successors map[string]Node
 
+	// connections counts calls to Connect, so graphs know when routes
+	// resolved from the node's successors are stale.
+	connections atomic.Uint64
+
 	// Options
 	opts nodeOptions
+
+	// sem limits concurrent executions when WithMaxConcurrency is set.
+	sem chan struct{}
 }
 
 // nodeOptions holds configuration for a Node.
@@ -152,18 +176,40 @@ type nodeOptions struct {
 	post PostFunc
 
 	// Retry and timeout
-	maxRetries int
-	retryDelay time.Duration
-	timeout    time.Duration
+	maxRetries  int
+	retryDelay  time.Duration
+	timeout     time.Duration
+	prepTimeout time.Duration
+	execTimeout time.Duration
+	postTimeout time.Duration
+	prepRetry   *retryPolicy
+	postRetry   *retryPolicy
+
+	// Concurrency
+	maxConcurrency int
+	bulkhead       *bulkhead
+
+	// Memoization
+	memoize bool
+	memoTTL time.Duration
+	memoKey func(prepResult any) string
 
 	// Error handling
-	onError  func(error)
-	fallback func(ctx context.Context, prepResult any, err error) (any, error)
+	onError      func(error)
+	fallback     func(ctx context.Context, prepResult any, err error) (any, error)
+	fallbackNode Node
 
 	// Cleanup hooks
 	onSuccess  func(ctx context.Context, store StoreWriter, output any)
 	onFailure  func(ctx context.Context, store StoreWriter, err error)
 	onComplete func(ctx context.Context, store StoreWriter)
+
+	// Schemas
+	inputSchema  map[string]any
+	outputSchema map[string]any
+
+	// Workflow definition, for Marshal
+	spec *nodeSpec
 }
 
 // Option configures a Node.
@@ -265,7 +311,9 @@ func WithPost[In, Out any](fn func(ctx context.Context, store StoreWriter, input
 	}
 }
 
-// WithRetry configures retry behavior.
+// WithRetry retries the Prep and Exec steps when they fail, waiting delay
+// between attempts. WithPrepRetry gives Prep its own policy, and
+// WithPostRetry retries Post.
 func WithRetry(maxRetries int, delay time.Duration) Option {
 	return func(o *nodeOptions) {
 		o.maxRetries = maxRetries
@@ -273,13 +321,107 @@ func WithRetry(maxRetries int, delay time.Duration) Option {
 	}
 }
 
-// WithTimeout sets execution timeout.
+// WithTimeout limits the node's whole lifecycle: Prep, Exec with its
+// retries, and Post, along with any wait for a concurrency slot. The
+// context of the steps is canceled at the deadline, so it bounds steps that
+// honor cancellation. Use WithPhaseTimeouts to limit the steps separately.
 func WithTimeout(timeout time.Duration) Option {
 	return func(o *nodeOptions) {
 		o.timeout = timeout
 	}
 }
 
+// WithPhaseTimeouts limits each attempt of the Prep, Exec, and Post steps
+// separately; a zero duration leaves that step unlimited. A step that
+// overruns its limit fails with an error wrapping
+// context.DeadlineExceeded even if it ignores its context, so a hung store
+// backend in Prep or Post can't stall the run. The overrunning step keeps
+// running in the background until it returns, so steps should still honor
+// cancellation. A limited Post stages its writes as with WithPostRetry and
+// applies them once it returns in time, so an overrunning Post's writes are
+// dropped. WithTimeout still bounds the lifecycle as a whole.
+func WithPhaseTimeouts(prep, exec, post time.Duration) Option {
+	return func(o *nodeOptions) {
+		o.prepTimeout = prep
+		o.execTimeout = exec
+		o.postTimeout = post
+	}
+}
+
+// WithFallbackNode runs fallback in place of the node when the node fails,
+// after its retries and any Fallback step, such as a cheaper model or a
+// graph turned into a node with AsNode. The fallback node gets the failed
+// node's input, runs with its own lifecycle, and routes by its own
+// connections, so the run continues from its successors. It reads the
+// failure with FallbackError. Fallbacks don't run once the run's context is
+// canceled.
+func WithFallbackNode(fallback Node) Option {
+	return func(o *nodeOptions) {
+		o.fallbackNode = fallback
+	}
+}
+
+type fallbackErrorKey struct{}
+
+// FallbackError returns the error of the node a fallback node from
+// WithFallbackNode is running in place of, or nil outside fallbacks.
+func FallbackError(ctx context.Context) error {
+	err, _ := ctx.Value(fallbackErrorKey{}).(error)
+	return err
+}
+
+// FallbackNode returns the node set with WithFallbackNode, or nil. Adapters
+// use it to walk the graph without running it.
+func FallbackNode(n Node) Node {
+	return fallbackNodeOf(n)
+}
+
+// fallbackNodeOf returns the fallback node of n, if it has one.
+func fallbackNodeOf(n Node) Node {
+	if m, ok := n.(*mappedNode); ok {
+		n = m.Node
+	}
+	if simple, ok := n.(*node); ok {
+		return simple.opts.fallbackNode
+	}
+	return nil
+}
+
+// WithMaxConcurrency limits how many executions of the node run at once,
+// across all graphs and FanOut workers that share it. Further executions wait
+// for a slot; the wait counts toward WithTimeout and ends if the context is
+// canceled.
+func WithMaxConcurrency(n int) Option {
+	return func(o *nodeOptions) {
+		o.maxConcurrency = n
+	}
+}
+
+// WithBulkhead runs the node in a named isolation pool shared with every
+// other node using the same name, so one slow dependency cannot tie up all
+// goroutines. At most size executions run at once and up to queue more wait
+// for a slot; beyond that executions fail immediately with ErrBulkheadFull.
+// The first size and queue registered for a name are used.
+func WithBulkhead(poolName string, size, queue int) Option {
+	return func(o *nodeOptions) {
+		o.bulkhead = bulkheadPool(poolName, size, queue)
+	}
+}
+
+// WithMemoization caches the node's Exec result in the graph's store, keyed
+// by its prep result, and skips Exec when the same prep result is seen again
+// within ttl. A zero ttl caches results indefinitely. keyFn derives the cache
+// key from the prep result; when nil, the prep result's JSON encoding is
+// hashed. Only use it for nodes whose Exec is a pure function of its prep
+// result. Fallback results are not cached.
+func WithMemoization(ttl time.Duration, keyFn func(prepResult any) string) Option {
+	return func(o *nodeOptions) {
+		o.memoize = true
+		o.memoTTL = ttl
+		o.memoKey = keyFn
+	}
+}
+
 // WithErrorHandler sets a custom error handler.
 func WithErrorHandler(handler func(error)) Option {
 	return func(o *nodeOptions) {
@@ -354,13 +496,27 @@ func (n *node) Post(ctx context.Context, store StoreWriter, input, prepResult, e
 
 // Connect adds a successor node for the given action.
 func (n *node) Connect(action string, next Node) Node {
-	n.successors[action] = next
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	successors := maps.Clone(n.successors)
+	successors[action] = next
+	n.successors = successors
+	n.connections.Add(1)
 	return n
 }
 
-// Successors returns all connected nodes.
+// Successors returns all connected nodes. The map must not be modified.
 func (n *node) Successors() map[string]Node {
-	return n.successors
+	successors, _ := n.connected()
+	return successors
+}
+
+// connected returns the node's successors along with the connection count
+// they reflect.
+func (n *node) connected() (map[string]Node, uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.successors, n.connections.Load()
 }
 
 // InputType returns the expected input type for validation.
@@ -440,6 +596,10 @@ func newNodeBase(name string, opts ...Option) *node {
 		n.post = n.opts.post
 	}
 
+	if n.opts.maxConcurrency > 0 {
+		n.sem = make(chan struct{}, n.opts.maxConcurrency)
+	}
+
 	return n
 }
 
@@ -526,12 +686,19 @@ func NewNode[In, Out any](name string, steps Steps, opts ...Option) Node {
 	outType := reflect.TypeOf((*Out)(nil)).Elem()
 
 	// Set type information on node if types are not 'any'
-	// This enables ValidateFlow to check type compatibility between nodes
+	// This enables ValidateFlow to check type compatibility between nodes,
+	// and infer schemas for nodes that don't declare them
 	if !isAnyType(inType) {
 		n.inputType = inType
+		if n.opts.inputSchema == nil {
+			n.opts.inputSchema = schemaFor(inType, make(map[reflect.Type]bool))
+		}
 	}
 	if !isAnyType(outType) {
 		n.outputType = outType
+		if n.opts.outputSchema == nil {
+			n.opts.outputSchema = schemaFor(outType, make(map[reflect.Type]bool))
+		}
 	}
 
 	return n
@@ -544,11 +711,21 @@ func Default(n, next Node) Node {
 
 // graph is the private implementation of Node for composite execution.
 type graph struct {
-	name       string
-	start      Node
-	store      Store
+	name     string
+	start    Node
+	store    Store
+	opts     graphOptions
+	runs     runRegistry
+	inflight inflightRuns
+	routes   *routes // Successor table; nil for graphs helpers run nodes with
+
+	// overrides replace nodes by name when they run
+	overrides map[string]Node
+
+	// successors are the graph's own, for when it is used as a node.
+	// Connect replaces the map rather than modifying it, under mu.
+	mu         sync.Mutex
 	successors map[string]Node
-	opts       graphOptions
 }
 
 // Graph is the public handle to a graph for backward compatibility.
@@ -558,17 +735,31 @@ type Graph struct {
 
 // graphOptions holds configuration for a Graph.
 type graphOptions struct {
-	logger Logger
-	tracer Tracer
+	logger      Logger
+	tracer      Tracer
+	snapshot    bool
+	chaos       []*chaos
+	determinism *determinism
+	clock       Clock
+	tenants     *Tenants
+	inputLimits *InputLimits
+	events      []EventHandler
+	runOutcomes bool
+
+	contextValues map[any]any
 }
 
 // GraphOption configures a Graph.
 type GraphOption func(*graphOptions)
 
-// WithLogger adds logging to the graph.
+// WithLogger adds logging to the graph. Entries of runs for a tenant
+// include it.
 func WithLogger(logger Logger) GraphOption {
 	return func(o *graphOptions) {
 		o.logger = logger
+		if logger != nil {
+			o.logger = tenantLogger{logger}
+		}
 	}
 }
 
@@ -579,6 +770,19 @@ func WithTracer(tracer Tracer) GraphOption {
 	}
 }
 
+// WithSnapshotIsolation gives each Prep step a consistent, read-only view
+// of the store taken when the step starts. Writes by other nodes or
+// goroutines while Prep runs are not visible, and values are deep-copied on
+// read, so Prep cannot mutate maps or slices shared through the store.
+// Taking the view copies the store's key table, so it costs time
+// proportional to the number of entries. Stores other than those from
+// NewStore and NewShardedStore only get the copy on read.
+func WithSnapshotIsolation() GraphOption {
+	return func(o *graphOptions) {
+		o.snapshot = true
+	}
+}
+
 // Implementation of Node interface for graph struct
 
 // Name returns the graph's identifier.
@@ -592,11 +796,24 @@ func (g *graph) Prep(ctx context.Context, store StoreReader, input any) (any, er
 	return input, nil
 }
 
-// Exec runs the graph workflow.
-func (g *graph) Exec(ctx context.Context, input any) (any, error) {
-	// Create a new Graph wrapper to use existing Run logic
-	wrapper := &Graph{graph: g}
-	return wrapper.Run(ctx, input)
+// Exec runs the graph workflow. A graph nested as a node applies its input
+// limits and tenant limits like Run, but its runs are part of the outer
+// graph's run: the outer graph's run ID, Cancel, Pause, and Shutdown apply
+// to them, not its own.
+func (g *graph) Exec(ctx context.Context, input any) (output any, err error) {
+	if g.opts.inputLimits != nil {
+		if err := g.opts.inputLimits.check(input); err != nil {
+			return nil, err
+		}
+	}
+	if g.opts.tenants != nil {
+		var done func()
+		if ctx, done, err = g.admitTenant(ctx); err != nil {
+			return nil, err
+		}
+		defer done()
+	}
+	return g.run(ctx, g.start, input)
 }
 
 // Post handles the graph execution results.
@@ -607,15 +824,21 @@ func (g *graph) Post(ctx context.Context, store StoreWriter, input, prepResult,
 
 // Connect adds a successor node for when the graph is used as a node.
 func (g *graph) Connect(action string, next Node) Node {
-	if g.successors == nil {
-		g.successors = make(map[string]Node)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	successors := maps.Clone(g.successors)
+	if successors == nil {
+		successors = make(map[string]Node)
 	}
-	g.successors[action] = next
+	successors[action] = next
+	g.successors = successors
 	return g
 }
 
-// Successors returns all connected nodes.
+// Successors returns all connected nodes. The map must not be modified.
 func (g *graph) Successors() map[string]Node {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	return g.successors
 }
 
@@ -636,11 +859,12 @@ func NewGraph(start Node, store Store, opts ...GraphOption) *Graph {
 		name = "graph-" + start.Name()
 	}
 
+	// Successors are allocated by Connect when the graph is used as a node
 	g := &graph{
-		name:       name,
-		start:      start,
-		store:      store,
-		successors: make(map[string]Node),
+		name:   name,
+		start:  start,
+		store:  store,
+		routes: &routes{},
 	}
 
 	for _, opt := range opts {
@@ -670,6 +894,10 @@ func NewGraph(start Node, store Store, opts ...GraphOption) *Graph {
 //   - Any type: any -> ConcreteType ✓ (but loses compile-time safety)
 //   - Assignability: Uses Go's reflect.Type.AssignableTo for compatibility
 //
+// Nodes that declare schemas with WithOutputSchema and WithInputSchema are
+// also checked structurally: the types must overlap, and an output that
+// lists its properties must include every property the input requires.
+//
 // Example:
 //
 //	// Build your workflow
@@ -698,20 +926,11 @@ func validateNode(node Node, visited map[string]bool) error {
 	}
 	visited[node.Name()] = true
 
-	// If this node has no type information, skip validation
-	if node.OutputType() == nil {
-		// Still validate successors
-		for _, successor := range node.Successors() {
-			if err := validateNode(successor, visited); err != nil {
-				return err
-			}
-		}
-		return nil
-	}
+	_, outputSchema := Schemas(node)
 
 	// Check each successor
 	for action, successor := range node.Successors() {
-		if successor.InputType() != nil {
+		if node.OutputType() != nil && successor.InputType() != nil {
 			// Both types are specified, check compatibility
 			if !isTypeCompatible(node.OutputType(), successor.InputType()) {
 				return fmt.Errorf("type mismatch: node %q outputs %v but node %q expects %v (via action %q)",
@@ -719,6 +938,13 @@ func validateNode(node Node, visited map[string]bool) error {
 			}
 		}
 
+		// Both schemas are declared, check structural compatibility
+		inputSchema, _ := Schemas(successor)
+		if err := checkSchemas(outputSchema, inputSchema, ""); err != nil {
+			return fmt.Errorf("schema mismatch: node %q output doesn't match node %q input (via action %q): %w",
+				node.Name(), successor.Name(), action, err)
+		}
+
 		// Recursively validate successor
 		if err := validateNode(successor, visited); err != nil {
 			return err
@@ -751,34 +977,207 @@ func isTypeCompatible(outputType, inputType reflect.Type) bool {
 	return outputType.AssignableTo(inputType)
 }
 
-// Run executes the graph with the given input.
+// Run executes the graph with the given input. If ctx carries a run ID from
+// WithRunID, the run can be canceled with Cancel or paused with Pause while
+// it executes. A graph run from a node of another run, as by builders, is
+// part of that run: it isn't tracked by the run ID and records no outcome.
 func (g *Graph) Run(ctx context.Context, input any) (output any, err error) {
-	if g.start == nil {
+	if g.opts.inputLimits != nil {
+		if err := g.opts.inputLimits.check(input); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only the outermost run is managed by its ID
+	nested := runContextFrom(ctx) != nil
+
+	rc, err := g.inflight.begin(ctx, g.graph)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { g.inflight.end(rc, err) }()
+	ctx = rc
+
+	if g.opts.tenants != nil {
+		var done func()
+		if ctx, done, err = g.admitTenant(ctx); err != nil {
+			return nil, err
+		}
+		defer done()
+	}
+
+	runID, ok := RunIDFrom(ctx)
+	if !ok || nested {
+		return g.run(ctx, g.start, input)
+	}
+	return g.runTracked(ctx, runID, g.start, input)
+}
+
+// runTracked executes the workflow from start as a run that can be managed
+// by ID while it executes, recording its outcome when it ends if the graph
+// records outcomes.
+func (g *Graph) runTracked(ctx context.Context, runID string, start Node, input any) (output any, err error) {
+	ctx, run, err := g.runs.start(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer run.finish()
+
+	output, err = g.execute(ctx, start, input, run)
+	if err != nil && errors.Is(context.Cause(ctx), ErrRunCanceled) && !errors.Is(err, ErrRunCanceled) {
+		err = fmt.Errorf("%w: %w", ErrRunCanceled, err)
+	}
+
+	// Record how the run ended before Cancel returns
+	if g.opts.runOutcomes {
+		g.recordOutcome(context.WithoutCancel(ctx), runID, outcomeOf(err))
+	}
+	return output, err
+}
+
+// run executes the workflow from start. Helpers that run many nodes against
+// the same store call it directly on a graph without options instead of
+// building a Graph per node, so Run's input limits, tenant limits, run IDs,
+// and Shutdown don't apply to them; graphs they run as nodes apply their own
+// limits through Exec.
+func (g *graph) run(ctx context.Context, start Node, input any) (output any, err error) {
+	return g.execute(ctx, start, input, nil)
+}
+
+// execute runs nodes from start until one has no successor for its action.
+// For tracked runs it stops before the next node when a pause is requested.
+func (g *graph) execute(ctx context.Context, start Node, input any, run *activeRun) (output any, err error) {
+	if start == nil {
 		return nil, ErrNoStartNode
 	}
 
-	current := g.start
+	// Give each run a fresh seeded source, so runs repeat
+	if g.opts.determinism != nil {
+		ctx = g.opts.determinism.context(ctx)
+	}
+	// One context serves the whole run, carrying the clock and context
+	// values. Run makes it along with the run's cancellation; helpers
+	// running nodes directly get one here.
+	if rc := runContextFrom(ctx); rc == nil || !rc.claim(g) {
+		ctx = newRunContext(ctx, g)
+	}
+
 	currentInput := input
 	var lastOutput any
 
-	for current != nil {
+	// Resolve once per run whether its events are observed, so unobserved
+	// runs don't read the clock per node
+	observed := g.observed(ctx)
+
+	if run != nil {
+		run.begin(ClockFrom(ctx))
+	}
+
+	var scratch route
+	for r := g.lookup(start, &scratch); r != nil; {
+		current := r.node
+
+		// Don't start another node once the run is canceled
+		if err := ctx.Err(); err != nil {
+			if run != nil && errors.Is(context.Cause(ctx), ErrShuttingDown) {
+				return nil, g.interrupt(ctx, run, current, currentInput)
+			}
+			return nil, fmt.Errorf("node %s: %w", current.Name(), err)
+		}
+
+		// Stop at the node boundary, saving where to resume
+		if run != nil && run.pauseRequested() {
+			return nil, g.pause(ctx, run, current, currentInput)
+		}
+
 		// Log node execution
 		if g.opts.logger != nil {
 			g.opts.logger.Debug(ctx, "executing node", "name", current.Name())
 		}
+		if run != nil {
+			run.enter(current.Name())
+		}
+		var nodeStart time.Time
+		if observed {
+			g.emit(ctx, Event{Type: EventNodeStart, Node: current.Name()})
+			nodeStart = ClockFrom(ctx).Now()
+		}
 
-		// Execute node with lifecycle
-		output, next, err := g.executeNode(ctx, current, currentInput)
+		// Execute node with lifecycle, or its override's
+		output, next, err := g.executeNode(ctx, r.exec, currentInput)
 		if err != nil {
-			return nil, fmt.Errorf("node %s: %w", current.Name(), err)
+			if observed {
+				g.emit(ctx, Event{
+					Type:     EventError,
+					Node:     current.Name(),
+					Duration: ClockFrom(ctx).Now().Sub(nodeStart),
+					Err:      err,
+				})
+			}
+
+			// Nodes stopped by a shutdown run again on resume
+			if run != nil && errors.Is(context.Cause(ctx), ErrShuttingDown) {
+				return nil, g.interrupt(ctx, run, current, currentInput)
+			}
+
+			fallback := fallbackNodeOf(r.exec)
+			if fallback == nil || ctx.Err() != nil {
+				return nil, fmt.Errorf("node %s: %w", current.Name(), err)
+			}
+
+			// Continue from the fallback node, which routes by its own
+			// connections
+			if g.opts.logger != nil {
+				g.opts.logger.Debug(ctx, "executing fallback node", "name", current.Name(), "fallback", fallback.Name(), "error", err)
+			}
+			if run != nil {
+				run.enter(fallback.Name())
+			}
+			if observed {
+				g.emit(ctx, Event{Type: EventNodeStart, Node: fallback.Name()})
+				nodeStart = ClockFrom(ctx).Now()
+			}
+
+			var fallbackErr error
+			output, next, fallbackErr = g.executeNode(context.WithValue(ctx, fallbackErrorKey{}, err), fallback, currentInput)
+			if fallbackErr != nil {
+				if observed {
+					g.emit(ctx, Event{
+						Type:     EventError,
+						Node:     fallback.Name(),
+						Duration: ClockFrom(ctx).Now().Sub(nodeStart),
+						Err:      fallbackErr,
+					})
+				}
+				return nil, fmt.Errorf("node %s failed and fallback node %s failed: primary=%w, fallback=%w",
+					current.Name(), fallback.Name(), err, fallbackErr)
+			}
+			g.emit(ctx, Event{Type: EventFallback, Node: fallback.Name(), Err: err})
+			current = fallback
+			r = g.lookup(fallback, &scratch)
 		}
 
 		// Save the output
 		lastOutput = output
+		if run != nil {
+			run.exit(current.Name(), output)
+		}
 
-		// Move to next node
-		successors := current.Successors()
-		current = successors[next]
+		// Move to next node, routing by the original node's connections
+		r = g.follow(r, next, &scratch)
+		if observed {
+			g.emit(ctx, Event{
+				Type:     EventNodeEnd,
+				Node:     current.Name(),
+				Duration: ClockFrom(ctx).Now().Sub(nodeStart),
+				Output:   output,
+			})
+			route := Event{Type: EventRoute, Node: current.Name(), Action: next}
+			if r != nil {
+				route.Next = r.node.Name()
+			}
+			g.emit(ctx, route)
+		}
 		currentInput = output
 	}
 
@@ -795,7 +1194,16 @@ func (g *Graph) Run(ctx context.Context, input any) (output any, err error) {
 // This is where runtime type checking occurs, complementing compile-time and init-time checks.
 // For typed nodes using generic options like WithExec, type assertions are handled
 // automatically through Go's type inference.
-func (g *Graph) executeNode(ctx context.Context, n Node, input any) (output any, next string, err error) {
+func (g *graph) executeNode(ctx context.Context, n Node, input any) (output any, next string, err error) {
+	// Map the input of nodes wrapped with MapInput, then run the node itself
+	if m, ok := n.(*mappedNode); ok {
+		input, err = m.mapInput(ctx, g.store, input)
+		if err != nil {
+			return nil, "", fmt.Errorf("map input failed: %w", err)
+		}
+		n = m.Node
+	}
+
 	// Runtime type check: Validate input matches node's expected type
 	// This catches any type mismatches that slipped through earlier checks
 	if n.InputType() != nil && input != nil {
@@ -806,19 +1214,42 @@ func (g *Graph) executeNode(ctx context.Context, n Node, input any) (output any,
 		}
 	}
 
-	// Check if this is a simple node with options
-	if simpleNode, ok := n.(*node); ok && simpleNode.opts.timeout > 0 {
+	// Name the node for NodeName and for store history
+	ctx = withNode(ctx, n.Name())
+
+	// Nodes created with NewNode carry options; resolve them once per run
+	simpleNode, _ := n.(*node)
+
+	if simpleNode != nil && simpleNode.opts.timeout > 0 {
 		// Apply timeout to entire lifecycle if configured
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, simpleNode.opts.timeout)
 		defer cancel()
 	}
 
+	// Wait for a concurrency slot if the node is limited
+	if simpleNode != nil && simpleNode.sem != nil {
+		select {
+		case simpleNode.sem <- struct{}{}:
+			defer func() { <-simpleNode.sem }()
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+	}
+
+	// Enter the node's bulkhead pool, failing fast when it is saturated
+	if simpleNode != nil && simpleNode.opts.bulkhead != nil {
+		if err := simpleNode.opts.bulkhead.acquire(ctx); err != nil {
+			return nil, "", err
+		}
+		defer simpleNode.opts.bulkhead.release()
+	}
+
 	// Execute lifecycle with retry support for each step
-	output, next, err = g.executeLifecycle(ctx, n, input)
+	output, next, err = g.executeLifecycle(ctx, n, simpleNode, input)
 	if err != nil {
 		// Check if this is a simple node with error handler
-		if simpleNode, ok := n.(*node); ok && simpleNode.opts.onError != nil {
+		if simpleNode != nil && simpleNode.opts.onError != nil {
 			simpleNode.opts.onError(err)
 		}
 		return nil, "", err
@@ -827,14 +1258,9 @@ func (g *Graph) executeNode(ctx context.Context, n Node, input any) (output any,
 	return output, next, nil
 }
 
-// executeLifecycle runs the Prep/Exec/Post steps.
-func (g *Graph) executeLifecycle(ctx context.Context, n Node, input any) (output any, next string, err error) {
-	// Check if this is a simple node with hooks
-	var simpleNode *node
-	if sn, ok := n.(*node); ok {
-		simpleNode = sn
-	}
-
+// executeLifecycle runs the Prep/Exec/Post steps. simpleNode is n when it
+// was created with NewNode, or nil.
+func (g *graph) executeLifecycle(ctx context.Context, n Node, simpleNode *node, input any) (output any, next string, err error) {
 	// Ensure cleanup hooks run
 	defer func() {
 		if simpleNode == nil {
@@ -857,18 +1283,18 @@ func (g *Graph) executeLifecycle(ctx context.Context, n Node, input any) (output
 		}
 	}()
 
+	e := newExecution(g, n, simpleNode, input)
+	defer e.release()
+
 	// Prep step with retry
-	prepResult, err := g.executeWithRetry(ctx, n, func() (any, error) {
-		return n.Prep(ctx, g.store, input)
-	})
+	prepResult, err := runStep(ctx, e, "prep", stepRetries(simpleNode, stepPrep), e.timeouts.prep, prepStep)
 	if err != nil {
 		return nil, "", fmt.Errorf("prep failed: %w", err)
 	}
+	e.prepResult = prepResult
 
-	// Exec step with retry
-	execResult, err := g.executeWithRetry(ctx, n, func() (any, error) {
-		return n.Exec(ctx, prepResult)
-	})
+	// Exec step with retry, served from the memo cache when enabled
+	execResult, err := g.executeExec(ctx, e)
 	if err != nil {
 		// Check if node has a fallback
 		if simpleNode != nil && simpleNode.opts.fallback != nil {
@@ -883,62 +1309,336 @@ func (g *Graph) executeLifecycle(ctx context.Context, n Node, input any) (output
 			}
 
 			// Continue with fallback result
+			g.emit(ctx, Event{Type: EventFallback, Node: n.Name(), Err: err})
 			execResult = fallbackResult
 		} else {
 			return nil, "", fmt.Errorf("exec failed: %w", err)
 		}
 	}
-
-	// Post step (no retry for routing decisions)
-	output, next, err = n.Post(ctx, g.store, input, prepResult, execResult)
+	e.execResult = execResult
+
+	// Post step, retried only when configured. Retried attempts, and those
+	// that may be abandoned at their timeout, stage their writes, so only a
+	// successful attempt's writes and route take effect. Unlike Prep and
+	// Exec, a Post step that isn't retried reports its error as is.
+	postRetries := stepRetries(simpleNode, stepPost)
+	e.stagePost = postRetries.maxRetries > 0 || e.timeouts.post > 0
+	var result postResult
+	switch {
+	case postRetries.maxRetries > 0:
+		result, err = executeWithRetry(ctx, e, "post", postRetries, 0, commitPost)
+	case e.stagePost:
+		result, err = commitPost(e.attempt(ctx, 1), e)
+	default:
+		result, err = postStep(e.attempt(ctx, 1), e)
+	}
 	if err != nil {
 		return nil, "", fmt.Errorf("post failed: %w", err)
 	}
 
-	return output, next, nil
+	return result.output, result.next, nil
 }
 
-// executeWithRetry handles retry logic for lifecycle steps.
-func (g *Graph) executeWithRetry(ctx context.Context, n Node, fn func() (any, error)) (any, error) {
-	attempts := 0
-	maxAttempts := 1 // default no retry
-	var retryDelay time.Duration
+// executeExec runs the Exec step with retry. For memoized nodes it returns a
+// cached result for a previously seen prep result and caches new results.
+func (g *graph) executeExec(ctx context.Context, e *execution) (any, error) {
+	retries := stepRetries(e.simple, stepExec)
+	if e.simple == nil || !e.simple.opts.memoize {
+		return runStep(ctx, e, "exec", retries, e.timeouts.exec, execStep)
+	}
+
+	key := memoStoreKey(e.simple, e.prepResult)
+	if cached, ok := memoLookup(ctx, g.store, key); ok {
+		return cached, nil
+	}
+
+	execResult, err := runStep(ctx, e, "exec", retries, e.timeouts.exec, execStep)
+	if err != nil {
+		return nil, err
+	}
+	if err := memoSave(ctx, g.store, key, execResult, e.simple.opts.memoTTL); err != nil {
+		return nil, fmt.Errorf("memoize: %w", err)
+	}
+	return execResult, nil
+}
+
+// execution is the state of a node's lifecycle that its steps read. Steps
+// are plain functions of it rather than closures, and executions are
+// pooled, so a step run in its own goroutine for a timeout shares the
+// node's state without allocating it.
+type execution struct {
+	g          *graph
+	n          Node
+	simple     *node // n when it was created with NewNode, or nil
+	input      any
+	prepResult any
+	execResult any
+	stagePost  bool // Post writes through a staged store, as it's retried or timed
+	timeouts   struct{ prep, exec, post time.Duration }
+
+	// first is the context of the first attempt at each step, which steps
+	// share as it names the same node and attempt
+	first context.Context
+
+	// abandoned is set when a step outlives its timeout and may still read
+	// the execution, so it isn't reused
+	abandoned bool
+}
+
+var executions = sync.Pool{
+	New: func() any { return new(execution) },
+}
+
+// newExecution returns an execution of n's lifecycle from the pool.
+func newExecution(g *graph, n Node, simpleNode *node, input any) *execution {
+	e := executions.Get().(*execution)
+	e.g, e.n, e.simple, e.input = g, n, simpleNode, input
+	if simpleNode != nil {
+		e.timeouts.prep = simpleNode.opts.prepTimeout
+		e.timeouts.exec = simpleNode.opts.execTimeout
+		e.timeouts.post = simpleNode.opts.postTimeout
+	}
+	return e
+}
+
+// release returns the execution to the pool, unless a step abandoned at
+// its timeout may still use it.
+func (e *execution) release() {
+	if e.abandoned {
+		return
+	}
+	*e = execution{}
+	executions.Put(e)
+}
+
+// attempt returns the context of an attempt at one of e's steps, where ctx
+// is the context of the node's lifecycle.
+func (e *execution) attempt(ctx context.Context, attempt int) context.Context {
+	if attempt > 1 {
+		return withAttempt(ctx, attempt)
+	}
+	if e.first == nil {
+		e.first = withAttempt(ctx, 1)
+	}
+	return e.first
+}
+
+// stepFunc runs a lifecycle step of an execution.
+type stepFunc[T any] func(ctx context.Context, e *execution) (T, error)
+
+type postResult struct {
+	output any
+	next   string
+	staged *stagedStore // Holds the writes of a staged Post
+}
+
+func prepStep(ctx context.Context, e *execution) (any, error) {
+	return e.g.prep(ctx, e.n, e.input)
+}
+
+func execStep(ctx context.Context, e *execution) (any, error) {
+	if len(e.g.opts.chaos) == 0 {
+		return e.n.Exec(ctx, e.prepResult)
+	}
+	step := func(ctx context.Context) (any, error) {
+		return e.n.Exec(ctx, e.prepResult)
+	}
+	for _, c := range e.g.opts.chaos {
+		step = c.wrap(e.n.Name(), e.g.opts.logger, step)
+	}
+	return step(ctx)
+}
 
-	// Check if this is a simple node with retry options
-	if simpleNode, ok := n.(*node); ok {
-		maxAttempts = simpleNode.opts.maxRetries + 1
-		retryDelay = simpleNode.opts.retryDelay
+// postStep runs Post, through a staged store when e stages its writes.
+func postStep(ctx context.Context, e *execution) (postResult, error) {
+	if !e.stagePost {
+		output, next, err := e.n.Post(ctx, e.g.store, e.input, e.prepResult, e.execResult)
+		return postResult{output: output, next: next}, err
 	}
+	staged := newStagedStore(e.g.store)
+	output, next, err := e.n.Post(ctx, staged, e.input, e.prepResult, e.execResult)
+	return postResult{output: output, next: next, staged: staged}, err
+}
+
+// commitPost runs an attempt at a staged Post within its timeout and then
+// applies its writes. A Post abandoned at its timeout writes to a staged
+// store that is never applied, so its late writes are dropped.
+func commitPost(ctx context.Context, e *execution) (postResult, error) {
+	result, err := runPhase(ctx, e, "post", e.timeouts.post, postStep)
+	if err != nil {
+		return postResult{}, err
+	}
+	if err := result.staged.commit(ctx); err != nil {
+		return postResult{}, fmt.Errorf("commit writes: %w", err)
+	}
+	return result, nil
+}
 
+// prep runs n's Prep step against the graph's store, or a snapshot of it.
+func (g *graph) prep(ctx context.Context, n Node, input any) (any, error) {
+	if g.opts.snapshot {
+		return n.Prep(ctx, newSnapshot(g.store), input)
+	}
+	return n.Prep(ctx, g.store, input)
+}
+
+// runStep runs a lifecycle step with retry and its timeout, calling it
+// directly when it has neither.
+func runStep[T any](ctx context.Context, e *execution, phase string, policy retryPolicy, timeout time.Duration, fn stepFunc[T]) (T, error) {
+	if policy.maxRetries > 0 || timeout > 0 {
+		return executeWithRetry(ctx, e, phase, policy, timeout, fn)
+	}
+	result, err := fn(e.attempt(ctx, 1), e)
+	if err != nil {
+		return result, attemptsError(1, err)
+	}
+	return result, nil
+}
+
+// runPhase runs a lifecycle step, failing it once it overruns the timeout
+// without waiting for it to return. A zero timeout runs the step as is.
+func runPhase[T any](ctx context.Context, e *execution, phase string, timeout time.Duration, fn stepFunc[T]) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx, e)
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1) // Buffered so an abandoned step can finish
+	go func() {
+		value, err := fn(ctx, e)
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		e.abandoned = true
+		var zero T
+		if err := parent.Err(); err != nil {
+			return zero, err
+		}
+		return zero, fmt.Errorf("%s timed out after %v: %w", phase, timeout, ctx.Err())
+	}
+}
+
+// executeWithRetry runs a lifecycle step, retrying it by the policy. Each
+// attempt's context carries its number for Attempt.
+func executeWithRetry[T any](ctx context.Context, e *execution, phase string, policy retryPolicy, timeout time.Duration, fn stepFunc[T]) (T, error) {
+	attempts := 0
+	maxAttempts := policy.maxRetries + 1
+	retryDelay := policy.delay
+
+	var zero T
 	var lastErr error
 
 	for attempts < maxAttempts {
 		if attempts > 0 {
 			select {
 			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(retryDelay):
+				return zero, ctx.Err()
+			case <-ClockFrom(ctx).After(retryDelay):
 			}
 		}
 
-		result, err := fn()
+		result, err := runPhase(e.attempt(ctx, attempts+1), e, phase, timeout, fn)
 		if err == nil {
 			return result, nil
 		}
 
 		lastErr = err
 		attempts++
+
+		// Writes partly applied would be applied twice by another attempt
+		var partial *partialCommitError
+		if errors.As(err, &partial) {
+			break
+		}
 		if attempts < maxAttempts {
-			if g.opts.logger != nil {
-				g.opts.logger.Debug(ctx, "retrying node step",
-					"name", n.Name(),
+			e.g.emit(ctx, Event{Type: EventRetry, Node: e.n.Name(), Attempt: attempts, Err: err})
+			if e.g.opts.logger != nil {
+				e.g.opts.logger.Debug(ctx, "retrying node step",
+					"name", e.n.Name(),
 					"attempt", attempts,
 					"error", err)
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("failed after %d attempts: %w", attempts, lastErr)
+	return zero, attemptsError(attempts, lastErr)
+}
+
+// attemptsError reports a step that failed every attempt.
+func attemptsError(attempts int, err error) error {
+	return fmt.Errorf("failed after %d attempts: %w", attempts, err)
+}
+
+// Start returns the graph's start node.
+// Adapters use it to walk the graph without running it.
+func (g *Graph) Start() Node {
+	return g.start
+}
+
+// WithStore returns a copy of the graph that runs against a different store.
+// The copy shares nodes and options with the original but has no successors.
+func (g *Graph) WithStore(store Store) *Graph {
+	return &Graph{graph: &graph{
+		name:      g.name,
+		start:     g.start,
+		store:     store,
+		opts:      g.opts,
+		routes:    &routes{},
+		overrides: g.overrides,
+	}}
+}
+
+// WithOverride returns a copy of the graph that runs replacement in place
+// of the node named name, so tests and staging environments can swap nodes
+// that call HTTP services, LLMs, or databases for fakes without rebuilding
+// the graph. The replacement runs with its own lifecycle and options, but
+// routing still follows the original node's connections, so the action it
+// returns selects among the original's successors. Nodes inside nested
+// graphs are overridden on those graphs. The copy shares nodes and options
+// with the original but has no successors.
+//
+// Example:
+//
+//	mock := pocket.NewNode[any, any]("fetch", pocket.Steps{
+//		Exec: func(ctx context.Context, input any) (any, error) {
+//			return fixture, nil
+//		},
+//	})
+//	result, err := graph.WithOverride("fetch", mock).Run(ctx, input)
+func (g *Graph) WithOverride(name string, replacement Node) *Graph {
+	overrides := make(map[string]Node, len(g.overrides)+1)
+	for k, v := range g.overrides {
+		overrides[k] = v
+	}
+	overrides[name] = replacement
+
+	return &Graph{graph: &graph{
+		name:      g.name,
+		start:     g.start,
+		store:     g.store,
+		opts:      g.opts,
+		routes:    &routes{},
+		overrides: overrides,
+	}}
+}
+
+// override returns the node to run in place of n.
+func (g *graph) override(n Node) Node {
+	if replacement, ok := g.overrides[n.Name()]; ok && replacement != nil {
+		return replacement
+	}
+	return n
 }
 
 // AsNode returns the graph as a Node interface.
@@ -1,10 +1,15 @@
 package pocket_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -437,36 +442,1765 @@ func TestWithRetry(t *testing.T) {
 	}
 }
 
+func TestWithPrepRetry(t *testing.T) {
+	prepAttempts, execAttempts := 0, 0
+	node := pocket.NewNode[any, any]("load",
+		pocket.Steps{
+			Prep: func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+				prepAttempts++
+				if prepAttempts < 3 {
+					return nil, errors.New("store unavailable")
+				}
+				return input, nil
+			},
+			Exec: func(ctx context.Context, input any) (any, error) {
+				execAttempts++
+				return nil, errors.New("exec failed")
+			},
+		},
+		pocket.WithPrepRetry(2, 0),
+	)
+
+	_, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "exec failed") {
+		t.Fatalf("Expected exec to fail, got %v", err)
+	}
+	if prepAttempts != 3 {
+		t.Errorf("Expected 3 prep attempts, got %d", prepAttempts)
+	}
+	if execAttempts != 1 {
+		t.Errorf("Expected exec to use its own policy without retries, got %d attempts", execAttempts)
+	}
+}
+
+func TestWithPostRetry(t *testing.T) {
+	attempts := 0
+	save := pocket.NewNode[any, any]("save",
+		pocket.Steps{
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+				attempts++
+				if err := store.Set(ctx, "attempt", attempts); err != nil {
+					return nil, "", err
+				}
+				if attempts == 1 {
+					_ = store.Scope("audit").Set(ctx, "partial", true)
+					return nil, "", errors.New("flaky write")
+				}
+				// Attempts read their own writes
+				if v, _ := store.Get(ctx, "attempt"); v != attempts {
+					return nil, "", fmt.Errorf("expected to read attempt %d, got %v", attempts, v)
+				}
+				return "saved", "done", nil
+			},
+		},
+		pocket.WithPostRetry(2, 0),
+	)
+	routed := 0
+	done := pocket.NewNode[any, any]("done",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				routed++
+				return input, nil
+			},
+		},
+	)
+	save.Connect("done", done)
+
+	store := pocket.NewStore()
+	result, err := pocket.NewGraph(save, store).Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Expected success after retrying post, got %v", err)
+	}
+	if result != "saved" || routed != 1 {
+		t.Errorf("Expected one route to done with saved, got %v after %d routes", result, routed)
+	}
+
+	ctx := context.Background()
+	if v, _ := store.Get(ctx, "attempt"); v != 2 {
+		t.Errorf("Expected the successful attempt's write, got %v", v)
+	}
+	if _, ok := store.Scope("audit").Get(ctx, "partial"); ok {
+		t.Error("Expected the failed attempt's writes to be discarded")
+	}
+}
+
+// keyFailingStore is a store whose writes of one key fail.
+type keyFailingStore struct {
+	pocket.Store
+	key string
+}
+
+func (s keyFailingStore) Set(ctx context.Context, key string, value any) error {
+	if key == s.key {
+		return errors.New("store unavailable")
+	}
+	return s.Store.Set(ctx, key, value)
+}
+
+func TestWithPostRetryPartialCommit(t *testing.T) {
+	attempts := 0
+	save := pocket.NewNode[any, any]("save",
+		pocket.Steps{
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+				attempts++
+				_ = store.Set(ctx, "a", attempts)
+				_ = store.Set(ctx, "b", attempts)
+				return "saved", "done", nil
+			},
+		},
+		pocket.WithPostRetry(2, 0),
+	)
+
+	ctx := context.Background()
+	tests := []struct {
+		failKey      string
+		wantAttempts int
+	}{
+		{"a", 3}, // Nothing applied, so retrying is safe
+		{"b", 1}, // a was applied, so another attempt would apply it twice
+	}
+	for _, tt := range tests {
+		attempts = 0
+		store := keyFailingStore{pocket.NewStore(), tt.failKey}
+		if _, err := pocket.NewGraph(save, store).Run(ctx, nil); err == nil {
+			t.Errorf("failing %s: expected the commit to fail", tt.failKey)
+		}
+		if attempts != tt.wantAttempts {
+			t.Errorf("failing %s: expected %d attempts, got %d", tt.failKey, tt.wantAttempts, attempts)
+		}
+	}
+}
+
+func TestWithFallbackNode(t *testing.T) {
+	var primaryErr error
+	backup := pocket.NewNode[any, any]("backup",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				primaryErr = pocket.FallbackError(ctx)
+				return "backup:" + input.(string), nil
+			},
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+				return exec, "degraded", nil
+			},
+		},
+	)
+	notify := pocket.NewNode[any, any]("notify",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return input.(string) + " (degraded)", nil
+			},
+		},
+	)
+	backup.Connect("degraded", notify)
+
+	primary := pocket.NewNode[any, any]("primary",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return nil, errors.New("model unavailable")
+			},
+		},
+		pocket.WithFallbackNode(backup),
+	)
+	primary.Connect("default", pocket.NewNode[any, any]("unreachable", pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			t.Error("Expected routing to follow the fallback node")
+			return input, nil
+		},
+	}))
+
+	result, err := pocket.NewGraph(primary, pocket.NewStore()).Run(context.Background(), "request")
+	if err != nil {
+		t.Fatalf("Expected the fallback to recover, got %v", err)
+	}
+	if result != "backup:request (degraded)" {
+		t.Errorf("Expected backup:request (degraded), got %v", result)
+	}
+	if primaryErr == nil || !strings.Contains(primaryErr.Error(), "model unavailable") {
+		t.Errorf("Expected the fallback to see the primary error, got %v", primaryErr)
+	}
+
+	// Both errors are reported when the fallback fails too
+	broken := pocket.NewNode[any, any]("broken",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return nil, errors.New("backup unavailable")
+			},
+		},
+	)
+	failing := pocket.NewNode[any, any]("failing",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return nil, errors.New("model unavailable")
+			},
+		},
+		pocket.WithFallbackNode(broken),
+	)
+	_, err = pocket.NewGraph(failing, pocket.NewStore()).Run(context.Background(), "request")
+	if err == nil || !strings.Contains(err.Error(), "model unavailable") || !strings.Contains(err.Error(), "backup unavailable") {
+		t.Errorf("Expected both errors, got %v", err)
+	}
+}
+
 func TestWithTimeout(t *testing.T) {
 	node := pocket.NewNode[any, any]("slow",
 		pocket.Steps{
 			Exec: func(ctx context.Context, input any) (any, error) {
-				select {
-				case <-time.After(100 * time.Millisecond):
-					return "completed", nil
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				}
+				select {
+				case <-time.After(100 * time.Millisecond):
+					return "completed", nil
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			},
+		},
+		pocket.WithTimeout(10*time.Millisecond),
+	)
+
+	store := pocket.NewStore()
+	graph := pocket.NewGraph(node, store)
+
+	_, err := graph.Run(context.Background(), nil)
+	if err == nil {
+		t.Error("Expected timeout error, got nil")
+	}
+
+	// The error will be wrapped, so check if it contains deadline exceeded
+	if !strings.Contains(err.Error(), "context deadline exceeded") &&
+		!strings.Contains(err.Error(), "failed after 1 attempts") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+}
+
+func TestWithPhaseTimeouts(t *testing.T) {
+	hung := make(chan struct{})
+	defer close(hung)
+
+	tests := []struct {
+		name  string
+		steps pocket.Steps
+		want  string
+	}{
+		{
+			name: "prep",
+			steps: pocket.Steps{
+				Prep: func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+					<-hung // Ignores its context, like a hung store backend
+					return input, nil
+				},
+			},
+			want: "prep failed",
+		},
+		{
+			name: "exec",
+			steps: pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				},
+			},
+			want: "exec timed out",
+		},
+		{
+			name: "post",
+			steps: pocket.Steps{
+				Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+					<-hung
+					return exec, "default", nil
+				},
+			},
+			want: "post failed: post timed out after 20ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := pocket.NewNode[any, any](tt.name, tt.steps,
+				pocket.WithPhaseTimeouts(20*time.Millisecond, 20*time.Millisecond, 20*time.Millisecond),
+			)
+			graph := pocket.NewGraph(node, pocket.NewStore())
+
+			start := time.Now()
+			_, err := graph.Run(context.Background(), nil)
+			if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected %q wrapping context.DeadlineExceeded, got %v", tt.want, err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Expected the step to be abandoned at its timeout, took %v", elapsed)
+			}
+		})
+	}
+
+	// Steps within their limits are unaffected
+	fast := pocket.NewNode[any, any]("fast",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return "done", nil
+			},
+		},
+		pocket.WithPhaseTimeouts(time.Second, time.Second, time.Second),
+	)
+	result, err := pocket.NewGraph(fast, pocket.NewStore()).Run(context.Background(), nil)
+	if err != nil || result != "done" {
+		t.Errorf("Expected done, got %v, %v", result, err)
+	}
+
+	// A Post abandoned at its timeout can't write to the store afterwards
+	release := make(chan struct{})
+	written := make(chan error, 1)
+	late := pocket.NewNode[any, any]("late",
+		pocket.Steps{
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+				<-release
+				written <- store.Set(ctx, "late", true)
+				return exec, "default", nil
+			},
+		},
+		pocket.WithPhaseTimeouts(0, 0, 10*time.Millisecond),
+	)
+	store := pocket.NewStore()
+	if _, err := pocket.NewGraph(late, store).Run(context.Background(), nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected post to time out, got %v", err)
+	}
+	close(release)
+	if err := <-written; err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, ok := store.Get(context.Background(), "late"); ok {
+		t.Error("Expected the abandoned Post's write to be dropped")
+	}
+}
+
+func TestWithMaxConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+
+	node := pocket.NewNode[any, any]("limited",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				mu.Lock()
+				running++
+				peak = max(peak, running)
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return input, nil
+			},
+		},
+		pocket.WithMaxConcurrency(2),
+	)
+
+	// Separate graphs and FanOut workers share the node's limit
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), nil)
+		}()
+	}
+	if _, err := pocket.FanOut(context.Background(), node, pocket.NewStore(), []int{1, 2, 3, 4, 5}); err != nil {
+		t.Fatalf("FanOut failed: %v", err)
+	}
+	wg.Wait()
+
+	if peak != 2 {
+		t.Errorf("Expected at most 2 concurrent executions, got %d", peak)
+	}
+
+	t.Run("wait respects timeout", func(t *testing.T) {
+		block := make(chan struct{})
+		slow := pocket.NewNode[any, any]("slow",
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					<-block
+					return input, nil
+				},
+			},
+			pocket.WithMaxConcurrency(1),
+			pocket.WithTimeout(20*time.Millisecond),
+		)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = pocket.NewGraph(slow, pocket.NewStore()).Run(context.Background(), nil)
+		}()
+		time.Sleep(5 * time.Millisecond)
+
+		_, err := pocket.NewGraph(slow, pocket.NewStore()).Run(context.Background(), nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded while waiting for a slot, got %v", err)
+		}
+		close(block)
+		<-done
+	})
+}
+
+func TestWithBulkhead(t *testing.T) {
+	block := make(chan struct{})
+	started := make(chan struct{}, 2)
+	steps := pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			started <- struct{}{}
+			<-block
+			return input, nil
+		},
+	}
+
+	// Different nodes share the pool by name
+	llmA := pocket.NewNode[any, any]("llm-a", steps, pocket.WithBulkhead("test-llm", 1, 1))
+	llmB := pocket.NewNode[any, any]("llm-b", steps, pocket.WithBulkhead("test-llm", 1, 1))
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := pocket.NewGraph(llmA, pocket.NewStore()).Run(context.Background(), nil)
+		errs <- err
+	}()
+	<-started
+
+	// Queued behind the running execution
+	go func() {
+		_, err := pocket.NewGraph(llmB, pocket.NewStore()).Run(context.Background(), nil)
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// Pool and queue are full, so this is rejected without waiting
+	_, err := pocket.NewGraph(llmA, pocket.NewStore()).Run(context.Background(), nil)
+	if !errors.Is(err, pocket.ErrBulkheadFull) {
+		t.Errorf("Expected ErrBulkheadFull, got %v", err)
+	}
+
+	close(block)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Expected admitted runs to succeed, got %v", err)
+		}
+	}
+
+	t.Run("queued wait respects timeout", func(t *testing.T) {
+		hold := make(chan struct{})
+		busy := pocket.NewNode[any, any]("busy",
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					<-hold
+					return input, nil
+				},
+			},
+			pocket.WithBulkhead("test-timeout", 1, 1),
+			pocket.WithTimeout(20*time.Millisecond),
+		)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = pocket.NewGraph(busy, pocket.NewStore()).Run(context.Background(), nil)
+		}()
+		time.Sleep(5 * time.Millisecond)
+
+		_, err := pocket.NewGraph(busy, pocket.NewStore()).Run(context.Background(), nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected deadline exceeded while queued, got %v", err)
+		}
+		close(hold)
+		<-done
+	})
+}
+
+func TestWithMemoization(t *testing.T) {
+	calls := 0
+	newNode := func(ttl time.Duration, keyFn func(any) string) pocket.Node {
+		return pocket.NewNode[string, string]("upper",
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					calls++
+					return strings.ToUpper(input.(string)), nil
+				},
+			},
+			pocket.WithMemoization(ttl, keyFn),
+		)
+	}
+
+	t.Run("caches by prep result", func(t *testing.T) {
+		calls = 0
+		graph := pocket.NewGraph(newNode(0, nil), pocket.NewStore())
+
+		for _, input := range []string{"hello", "hello", "world"} {
+			result, err := graph.Run(context.Background(), input)
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			if result != strings.ToUpper(input) {
+				t.Errorf("Expected %q, got %v", strings.ToUpper(input), result)
+			}
+		}
+		if calls != 2 {
+			t.Errorf("Expected 2 exec calls, got %d", calls)
+		}
+	})
+
+	t.Run("entries expire after ttl", func(t *testing.T) {
+		calls = 0
+		graph := pocket.NewGraph(newNode(10*time.Millisecond, nil), pocket.NewStore())
+
+		_, _ = graph.Run(context.Background(), "hello")
+		_, _ = graph.Run(context.Background(), "hello")
+		time.Sleep(20 * time.Millisecond)
+		_, _ = graph.Run(context.Background(), "hello")
+
+		if calls != 2 {
+			t.Errorf("Expected 2 exec calls, got %d", calls)
+		}
+	})
+
+	t.Run("custom key", func(t *testing.T) {
+		calls = 0
+		caseInsensitive := func(prep any) string { return strings.ToLower(prep.(string)) }
+		graph := pocket.NewGraph(newNode(0, caseInsensitive), pocket.NewStore())
+
+		_, _ = graph.Run(context.Background(), "hello")
+		result, _ := graph.Run(context.Background(), "HELLO")
+
+		if calls != 1 {
+			t.Errorf("Expected 1 exec call, got %d", calls)
+		}
+		if result != "HELLO" {
+			t.Errorf("Expected cached result HELLO, got %v", result)
+		}
+	})
+}
+
+func TestWithEventHandler(t *testing.T) {
+	attempts := 0
+	flaky := pocket.NewNode[any, any]("flaky",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				attempts++
+				if attempts < 2 {
+					return nil, errors.New("temporary error")
+				}
+				return "ok", nil
+			},
+		},
+		pocket.WithRetry(1, 0),
+	)
+	broken := pocket.NewNode[any, any]("broken",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return nil, errors.New("broken")
+			},
+		},
+	)
+	flaky.Connect("default", broken)
+
+	var events []string
+	graph := pocket.NewGraph(flaky, pocket.NewStore(), pocket.WithEventHandler(func(ctx context.Context, event pocket.Event) {
+		if event.RunID != "run-1" || event.Time.IsZero() {
+			t.Errorf("Expected run ID and time on %+v", event)
+		}
+		switch event.Type {
+		case pocket.EventRoute:
+			events = append(events, fmt.Sprintf("%s %s %s->%s", event.Type, event.Node, event.Action, event.Next))
+		case pocket.EventRetry:
+			events = append(events, fmt.Sprintf("%s %s %d", event.Type, event.Node, event.Attempt))
+		case pocket.EventNodeEnd:
+			events = append(events, fmt.Sprintf("%s %s %v", event.Type, event.Node, event.Output))
+		default:
+			events = append(events, fmt.Sprintf("%s %s", event.Type, event.Node))
+		}
+	}))
+
+	if _, err := graph.Run(pocket.WithRunID(context.Background(), "run-1"), nil); err == nil {
+		t.Fatal("Expected the broken node to fail the run")
+	}
+
+	want := []string{
+		"node_start flaky",
+		"retry flaky 1",
+		"node_end flaky ok",
+		"route flaky default->broken",
+		"node_start broken",
+		"error broken",
+	}
+	if strings.Join(events, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected events:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(events, "\n"))
+	}
+}
+
+func TestRunWithReport(t *testing.T) {
+	attempts := 0
+	fetch := pocket.NewNode[any, any]("fetch",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				attempts++
+				if attempts == 1 {
+					return nil, errors.New("timeout")
+				}
+				return "data", nil
+			},
+		},
+		pocket.WithRetry(1, 0),
+	)
+	enrich := pocket.NewNode[any, any]("enrich",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return nil, errors.New("enrichment unavailable")
+			},
+			Fallback: func(ctx context.Context, prepResult any, err error) (any, error) {
+				return prepResult, nil
+			},
+		},
+	)
+	fetch.Connect("default", enrich)
+
+	graph := pocket.NewGraph(fetch, pocket.NewStore())
+	output, report, err := graph.RunWithReport(pocket.WithRunID(context.Background(), "run-1"), nil)
+	if err != nil {
+		t.Fatalf("RunWithReport failed: %v", err)
+	}
+	if output != "data" {
+		t.Errorf("Expected data, got %v", output)
+	}
+
+	if report.RunID != "run-1" || report.Duration() < 0 || report.End.IsZero() {
+		t.Errorf("Expected run ID and timings, got %+v", report)
+	}
+	if len(report.Nodes) != 2 || report.Nodes[0].Name != "fetch" || report.Nodes[0].Attempts != 2 ||
+		report.Nodes[1].Name != "enrich" || report.Nodes[1].Attempts != 1 {
+		t.Errorf("Expected fetch with 2 attempts then enrich, got %+v", report.Nodes)
+	}
+	wantRoutes := []pocket.RouteReport{
+		{From: "fetch", Action: "default", To: "enrich"},
+		{From: "enrich", Action: "default"},
+	}
+	if len(report.Routes) != 2 || report.Routes[0] != wantRoutes[0] || report.Routes[1] != wantRoutes[1] {
+		t.Errorf("Expected routes %+v, got %+v", wantRoutes, report.Routes)
+	}
+	if len(report.Suppressed) != 1 || report.Suppressed[0].Node != "enrich" ||
+		!strings.Contains(report.Suppressed[0].Err.Error(), "enrichment unavailable") {
+		t.Errorf("Expected the enrich error to be suppressed, got %+v", report.Suppressed)
+	}
+
+	// Failed runs are reported too
+	broken := pocket.NewNode[any, any]("broken",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return nil, errors.New("broken")
+			},
+		},
+	)
+	_, report, err = pocket.NewGraph(broken, pocket.NewStore()).RunWithReport(context.Background(), nil)
+	if err == nil {
+		t.Fatal("Expected the run to fail")
+	}
+	if len(report.Nodes) != 1 || report.Nodes[0].Err == nil {
+		t.Errorf("Expected the failed node in the report, got %+v", report.Nodes)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	clock := pocket.NewSimulatedClock(time.Unix(1700000000, 0))
+	step := func(name string, d time.Duration, fail bool) pocket.Node {
+		return pocket.NewNode[any, any](name,
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					clock.Advance(d)
+					if fail {
+						return nil, errors.New("upstream unavailable")
+					}
+					return input, nil
+				},
+			},
+		)
+	}
+	fetch := step("fetch", 2*time.Second, false)
+	summarize := step("summarize", 500*time.Millisecond, true)
+	fetch.Connect("default", summarize)
+
+	timeline := pocket.NewTimeline()
+	graph := pocket.NewGraph(fetch, pocket.NewStore(),
+		pocket.WithClock(clock),
+		pocket.WithEventHandler(timeline.Handle),
+	)
+	if _, err := graph.Run(pocket.WithRunID(context.Background(), "run-1"), nil); err == nil {
+		t.Fatal("Expected summarize to fail the run")
+	}
+
+	spans := timeline.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	if d := spans[0].End.Sub(spans[0].Start); spans[0].Node != "fetch" || d != 2*time.Second {
+		t.Errorf("Expected fetch to take 2s, got %s for %v", spans[0].Node, d)
+	}
+	if spans[1].Node != "summarize" || spans[1].Err == "" || spans[1].RunID != "run-1" {
+		t.Errorf("Expected failed summarize span of run-1, got %+v", spans[1])
+	}
+
+	var chrome struct {
+		TraceEvents []struct {
+			Name string         `json:"name"`
+			Ph   string         `json:"ph"`
+			Ts   float64        `json:"ts"`
+			Dur  float64        `json:"dur"`
+			Args map[string]any `json:"args"`
+		} `json:"traceEvents"`
+	}
+	var buf bytes.Buffer
+	if err := timeline.WriteChromeTrace(&buf); err != nil {
+		t.Fatalf("WriteChromeTrace failed: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &chrome); err != nil {
+		t.Fatalf("Invalid Chrome trace: %v", err)
+	}
+	if len(chrome.TraceEvents) != 3 || chrome.TraceEvents[0].Args["name"] != "run run-1" {
+		t.Fatalf("Expected a named thread and 2 spans, got %+v", chrome.TraceEvents)
+	}
+	if e := chrome.TraceEvents[2]; e.Name != "summarize" || e.Ph != "X" || e.Dur != 500000 || e.Args["error"] == nil {
+		t.Errorf("Expected 500ms summarize span with error, got %+v", e)
+	}
+
+	var otlp struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+					Status       *struct {
+						Code int `json:"code"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	buf.Reset()
+	if err := timeline.WriteOTLP(&buf); err != nil {
+		t.Fatalf("WriteOTLP failed: %v", err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &otlp); err != nil {
+		t.Fatalf("Invalid OTLP: %v", err)
+	}
+	otlpSpans := otlp.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(otlpSpans) != 3 {
+		t.Fatalf("Expected a run span and 2 node spans, got %d", len(otlpSpans))
+	}
+	root := otlpSpans[0]
+	for _, span := range otlpSpans[1:] {
+		if span.TraceID != root.TraceID || span.ParentSpanID != root.SpanID || len(span.TraceID) != 32 || len(span.SpanID) != 16 {
+			t.Errorf("Expected %s to be a child of the run span, got %+v", span.Name, span)
+		}
+	}
+	if otlpSpans[2].Status == nil || otlpSpans[2].Status.Code != 2 {
+		t.Errorf("Expected summarize to have error status, got %+v", otlpSpans[2].Status)
+	}
+}
+
+func TestWithContextValues(t *testing.T) {
+	type userKey struct{}
+
+	var seen []string
+	flaky := pocket.NewNode[any, any]("flaky",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				seen = append(seen, fmt.Sprintf("%s %s %d %v",
+					pocket.RunID(ctx), pocket.NodeName(ctx), pocket.Attempt(ctx), ctx.Value(userKey{})))
+				if pocket.Attempt(ctx) < 2 {
+					return nil, errors.New("temporary error")
+				}
+				return "ok", nil
+			},
+		},
+		pocket.WithRetry(1, 0),
+	)
+
+	graph := pocket.NewGraph(flaky, pocket.NewStore(),
+		pocket.WithContextValues(map[any]any{userKey{}: "alice"}))
+	if _, err := graph.Run(pocket.WithRunID(context.Background(), "run-1"), nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	expected := []string{"run-1 flaky 1 alice", "run-1 flaky 2 alice"}
+	if fmt.Sprint(seen) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, seen)
+	}
+	if ctx := context.Background(); pocket.RunID(ctx) != "" || pocket.NodeName(ctx) != "" || pocket.Attempt(ctx) != 0 {
+		t.Error("Expected no metadata outside of a run")
+	}
+}
+
+func TestAbandonedStepMetadata(t *testing.T) {
+	release := make(chan struct{})
+	reported := make(chan string, 1)
+	slow := pocket.NewNode[any, any]("slow",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				// Outlive the timeout until the next node is running
+				<-release
+				reported <- fmt.Sprintf("%s %d", pocket.NodeName(ctx), pocket.Attempt(ctx))
+				return nil, nil
+			},
+			Fallback: func(ctx context.Context, prep any, err error) (any, error) {
+				return "recovered", nil
+			},
+		},
+		pocket.WithPhaseTimeouts(0, 10*time.Millisecond, 0),
+	)
+	next := pocket.NewNode[any, any]("next",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				close(release)
+				return <-reported, nil
+			},
+		},
+	)
+	slow.Connect("default", next)
+
+	result, err := pocket.NewGraph(slow, pocket.NewStore()).Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result != "slow 1" {
+		t.Errorf("Expected the abandoned step to report slow 1, got %v", result)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	steps := pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) { return input, nil },
+	}
+	greet := pocket.NewNode[any, any]("greet", steps,
+		pocket.WithSpec("echo", map[string]any{
+			"message": "hello: world",
+			"tags":    []any{"a", true},
+		}))
+	score := pocket.NewNode[any, any]("score", steps)
+	done := pocket.NewNode[any, any]("done", steps, pocket.WithSpec("echo", nil))
+	greet.Connect("default", score)
+	score.Connect("high", done)
+	score.Connect("low", greet)
+
+	data, err := pocket.Marshal(pocket.NewGraph(greet, pocket.NewStore()))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := `name: graph-greet
+nodes:
+  - name: greet
+    type: echo
+    config:
+      message: "hello: world"
+      tags:
+        - a
+        - true
+  - name: score
+    type: custom
+  - name: done
+    type: echo
+connections:
+  - from: greet
+    to: score
+  - from: score
+    to: done
+    action: high
+  - from: score
+    to: greet
+    action: low
+start: greet
+`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+	}
+
+	// Distinct nodes with one name can't be told apart in YAML
+	duplicate := pocket.NewNode[any, any]("greet", steps)
+	done.Connect("default", duplicate)
+	if _, err := pocket.Marshal(pocket.NewGraph(greet, pocket.NewStore())); err == nil {
+		t.Error("Expected an error for duplicate node names")
+	}
+}
+
+func TestGraphCancel(t *testing.T) {
+	started := make(chan struct{})
+	var cleanedUp, secondRan atomic.Bool
+
+	first := pocket.NewNode[any, any]("wait",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				close(started)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+		pocket.WithOnComplete(func(ctx context.Context, store pocket.StoreWriter) {
+			time.Sleep(10 * time.Millisecond)
+			cleanedUp.Store(true)
+		}),
+	)
+	second := pocket.NewNode[any, any]("next",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				secondRan.Store(true)
+				return input, nil
+			},
+		},
+	)
+	first.Connect("default", second)
+	store := pocket.NewStore(pocket.WithHistory(5))
+	graph := pocket.NewGraph(first, store, pocket.WithRunOutcomes())
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := graph.Run(pocket.WithRunID(context.Background(), "run-1"), nil)
+		errs <- err
+	}()
+	<-started
+
+	// A second run can't reuse an active ID
+	if _, err := graph.Run(pocket.WithRunID(context.Background(), "run-1"), nil); err == nil {
+		t.Error("Expected error for duplicate run ID")
+	}
+
+	if err := graph.Cancel(context.Background(), "run-1"); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if !cleanedUp.Load() {
+		t.Error("Expected Cancel to wait for cleanup hooks")
+	}
+
+	err := <-errs
+	if !errors.Is(err, pocket.ErrRunCanceled) {
+		t.Errorf("Expected ErrRunCanceled, got %v", err)
+	}
+	if secondRan.Load() {
+		t.Error("Expected no nodes to start after cancellation")
+	}
+
+	// The run's history ends with its canceled status
+	history := store.(pocket.HistoryReader).History(context.Background(), pocket.RunOutcomeKey("run-1"))
+	if len(history) != 1 || history[0].Value != pocket.RunCanceled || history[0].RunID != "run-1" {
+		t.Errorf("Expected canceled outcome recorded for run-1, got %+v", history)
+	}
+
+	if err := graph.Cancel(context.Background(), "run-1"); !errors.Is(err, pocket.ErrRunNotFound) {
+		t.Errorf("Expected ErrRunNotFound for finished run, got %v", err)
+	}
+}
+
+func TestGraphPauseResume(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	step := func(name string) pocket.Node {
+		return pocket.NewNode[any, any](name,
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					if name == "a" {
+						close(started)
+						<-release
+					}
+					return input.(string) + name, nil
+				},
+			},
+		)
+	}
+	a, b, c := step("a"), step("b"), step("c")
+	a.Connect("default", b)
+	b.Connect("default", c)
+
+	store := pocket.NewStore(pocket.WithHistory(5))
+	graph := pocket.NewGraph(a, store, pocket.WithRunOutcomes())
+	ctx := context.Background()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := graph.Run(pocket.WithRunID(ctx, "run-1"), "")
+		errs <- err
+	}()
+	<-started
+
+	// Pause waits for the current node, so release it concurrently
+	paused := make(chan error, 1)
+	go func() { paused <- graph.Pause(ctx, "run-1") }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if err := <-paused; err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+	if err := <-errs; !errors.Is(err, pocket.ErrRunPaused) {
+		t.Fatalf("Expected ErrRunPaused, got %v", err)
+	}
+
+	value, ok := store.Get(ctx, pocket.CheckpointKey("run-1"))
+	if !ok {
+		t.Fatal("Expected checkpoint in store")
+	}
+	checkpoint := value.(pocket.Checkpoint)
+	if checkpoint.Node != "b" || checkpoint.Input != "a" {
+		t.Errorf("Expected checkpoint before b with input \"a\", got %+v", checkpoint)
+	}
+
+	result, err := graph.Resume(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if result != "abc" {
+		t.Errorf("Expected abc, got %v", result)
+	}
+	if _, ok := store.Get(ctx, pocket.CheckpointKey("run-1")); ok {
+		t.Error("Expected checkpoint to be removed after resuming")
+	}
+
+	var outcomes []any
+	for _, change := range store.(pocket.HistoryReader).History(ctx, pocket.RunOutcomeKey("run-1")) {
+		outcomes = append(outcomes, change.Value)
+	}
+	if want := []any{pocket.RunPaused, pocket.RunCompleted}; !reflect.DeepEqual(outcomes, want) {
+		t.Errorf("Expected outcomes %v, got %v", want, outcomes)
+	}
+
+	if err := graph.Pause(ctx, "run-1"); !errors.Is(err, pocket.ErrRunNotFound) {
+		t.Errorf("Expected ErrRunNotFound, got %v", err)
+	}
+}
+
+func TestGraphNestedRunOutcome(t *testing.T) {
+	store := pocket.NewStore(pocket.WithHistory(5))
+	failing := pocket.NewNode[any, any]("failing",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return nil, errors.New("nested failure")
+			},
+		},
+	)
+	outer := pocket.NewNode[any, any]("outer",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				// A nested run carrying the outer run's ID
+				if _, err := pocket.NewGraph(failing, store, pocket.WithRunOutcomes()).Run(ctx, input); err == nil {
+					return nil, errors.New("expected nested run to fail")
+				}
+				return "recovered", nil
+			},
+		},
+	)
+
+	ctx := pocket.WithRunID(context.Background(), "run-1")
+	if _, err := pocket.NewGraph(outer, store, pocket.WithRunOutcomes()).Run(ctx, nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	history := store.(pocket.HistoryReader).History(ctx, pocket.RunOutcomeKey("run-1"))
+	if len(history) != 1 || history[0].Value != pocket.RunCompleted {
+		t.Errorf("Expected only the outer run's completed outcome, got %+v", history)
+	}
+}
+
+// failingSetStore is a store whose writes fail.
+type failingSetStore struct{ pocket.Store }
+
+func (failingSetStore) Set(ctx context.Context, key string, value any) error {
+	return errors.New("store unavailable")
+}
+
+func TestGraphRunOutcomes(t *testing.T) {
+	ctx := pocket.WithRunID(context.Background(), "run-1")
+	done := pocket.NewNode[any, any]("done",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return "done", nil
+			},
+		},
+	)
+
+	// Outcomes are only recorded when enabled
+	store := pocket.NewStore()
+	if _, err := pocket.NewGraph(done, store).Run(ctx, nil); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if _, exists := store.Get(ctx, pocket.RunOutcomeKey("run-1")); exists {
+		t.Error("Expected no outcome recorded without WithRunOutcomes")
+	}
+
+	// Failing to record an outcome doesn't fail the run
+	graph := pocket.NewGraph(done, failingSetStore{pocket.NewStore()}, pocket.WithRunOutcomes())
+	if result, err := graph.Run(ctx, nil); err != nil || result != "done" {
+		t.Errorf("Expected done, got %v, %v", result, err)
+	}
+}
+
+func TestGraphRunStatus(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	clock := pocket.NewSimulatedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	step := func(name string) pocket.Node {
+		return pocket.NewNode[any, any](name,
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					if name == "c" {
+						close(started)
+						<-release
+					}
+					return input.(string) + name, nil
+				},
+			},
+		)
+	}
+	a, b, c := step("a"), step("b"), step("c")
+	a.Connect("default", b)
+	b.Connect("default", c)
+
+	graph := pocket.NewGraph(a, pocket.NewStore(), pocket.WithClock(clock))
+	ctx := context.Background()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := graph.Run(pocket.WithRunID(ctx, "run-1"), "")
+		errs <- err
+	}()
+	<-started
+	clock.Advance(5 * time.Second)
+
+	status, err := graph.RunStatus("run-1")
+	if err != nil {
+		t.Fatalf("RunStatus failed: %v", err)
+	}
+	if status.Node != "c" {
+		t.Errorf("Expected current node c, got %q", status.Node)
+	}
+	if status.Elapsed != 5*time.Second {
+		t.Errorf("Expected 5s elapsed, got %v", status.Elapsed)
+	}
+	if len(status.Path) != 2 || status.Path[0] != "a" || status.Path[1] != "b" {
+		t.Errorf("Expected path [a b], got %v", status.Path)
+	}
+	if status.Outputs["a"] != "a" || status.Outputs["b"] != "ab" {
+		t.Errorf("Expected outputs a and ab, got %v", status.Outputs)
+	}
+
+	close(release)
+	if err := <-errs; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if _, err := graph.RunStatus("run-1"); !errors.Is(err, pocket.ErrRunNotFound) {
+		t.Errorf("Expected ErrRunNotFound after the run ends, got %v", err)
+	}
+}
+
+func TestGraphShutdown(t *testing.T) {
+	var restarted atomic.Bool
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+
+	// Fast runs finish when released; slow ones wait for cancellation
+	work := pocket.NewNode[any, any]("work",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				started <- struct{}{}
+				switch {
+				case input == "fast":
+					<-release
+				case !restarted.Load():
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return input.(string) + "!", nil
+			},
+		},
+	)
+	store := pocket.NewStore()
+	graph := pocket.NewGraph(work, store)
+	ctx := context.Background()
+
+	type result struct {
+		output any
+		err    error
+	}
+	run := func(ctx context.Context, input string) chan result {
+		ch := make(chan result, 1)
+		go func() {
+			output, err := graph.Run(ctx, input)
+			ch <- result{output, err}
+		}()
+		return ch
+	}
+	// Runs whose context can be canceled are tracked apart from those
+	// whose context can't
+	cancelable, cancelRuns := context.WithCancel(ctx)
+	defer cancelRuns()
+	fast := run(ctx, "fast")
+	tracked := run(pocket.WithRunID(ctx, "run-1"), "slow")
+	untracked := run(cancelable, "slow")
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+
+	shutdownCtx, cancel := context.WithCancel(ctx)
+	type shutdown struct {
+		report pocket.ShutdownReport
+		err    error
+	}
+	done := make(chan shutdown, 1)
+	go func() {
+		report, err := graph.Shutdown(shutdownCtx)
+		done <- shutdown{report, err}
+	}()
+
+	// New runs are rejected once shutdown starts; until then, a canceled
+	// context stops them
+	canceled, cancelNew := context.WithCancel(ctx)
+	cancelNew()
+	for {
+		if _, err := graph.Run(canceled, "new"); errors.Is(err, pocket.ErrShuttingDown) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	if r := <-fast; r.err != nil || r.output != "fast!" {
+		t.Fatalf("Expected fast run to drain, got %v, %v", r.output, r.err)
+	}
+
+	// The deadline cancels the slow runs
+	cancel()
+	got := <-done
+
+	if got.report.Drained != 1 || got.report.Interrupted != 1 ||
+		len(got.report.Checkpointed) != 1 || got.report.Checkpointed[0] != "run-1" {
+		t.Errorf("Expected 1 drained, run-1 checkpointed and 1 interrupted, got %+v", got.report)
+	}
+	if !errors.Is(got.err, pocket.ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown from Shutdown, got %v", got.err)
+	}
+	if r := <-tracked; !errors.Is(r.err, pocket.ErrRunPaused) || !errors.Is(r.err, pocket.ErrShuttingDown) {
+		t.Errorf("Expected tracked run to pause for shutdown, got %v", r.err)
+	}
+	if r := <-untracked; !errors.Is(r.err, context.Canceled) {
+		t.Errorf("Expected untracked run to be canceled, got %v", r.err)
+	}
+
+	value, ok := store.Get(ctx, pocket.CheckpointKey("run-1"))
+	if !ok {
+		t.Fatal("Expected checkpoint in store")
+	}
+	if checkpoint := value.(pocket.Checkpoint); checkpoint.Node != "work" || checkpoint.Input != "slow" {
+		t.Errorf("Expected checkpoint at work with input \"slow\", got %+v", checkpoint)
+	}
+
+	if _, err := graph.Resume(ctx, "run-1"); !errors.Is(err, pocket.ErrShuttingDown) {
+		t.Errorf("Expected Resume to fail with ErrShuttingDown, got %v", err)
+	}
+
+	// Another process resumes the interrupted node
+	restarted.Store(true)
+	output, err := pocket.NewGraph(work, store).Resume(ctx, "run-1")
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if output != "slow!" {
+		t.Errorf("Expected slow!, got %v", output)
+	}
+}
+
+func TestMapInput(t *testing.T) {
+	type Order struct{ ID, Email string }
+	type Email struct{ To, Subject string }
+
+	order := pocket.NewNode[any, Order]("order",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return Order{ID: input.(string), Email: "ada@example.com"}, nil
 			},
 		},
-		pocket.WithTimeout(10*time.Millisecond),
 	)
+	send := pocket.NewNode[Email, string]("send",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				email := input.(Email)
+				return email.To + ": " + email.Subject, nil
+			},
+		},
+		pocket.WithRetry(1, 0),
+	)
+	notify := pocket.MapInput(send, func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+		prefix, _ := store.Get(ctx, "prefix")
+		o := input.(Order)
+		return Email{To: o.Email, Subject: prefix.(string) + o.ID}, nil
+	})
+	order.Connect("default", notify)
+
+	// The mapping adapts Order to Email, so the graph validates
+	if err := pocket.ValidateGraph(order); err != nil {
+		t.Fatalf("ValidateGraph() error = %v", err)
+	}
+	if notify.Name() != "send" {
+		t.Errorf("Name() = %q, want %q", notify.Name(), "send")
+	}
 
 	store := pocket.NewStore()
-	graph := pocket.NewGraph(node, store)
+	_ = store.Set(context.Background(), "prefix", "Order ")
+	output, err := pocket.NewGraph(order, store).Run(context.Background(), "o-1")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if output != "ada@example.com: Order o-1" {
+		t.Errorf("Output = %v, want %v", output, "ada@example.com: Order o-1")
+	}
 
-	_, err := graph.Run(context.Background(), nil)
-	if err == nil {
-		t.Error("Expected timeout error, got nil")
+	t.Run("mapping errors fail the node", func(t *testing.T) {
+		failing := pocket.MapInput(send, func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+			return nil, errors.New("no email")
+		})
+		_, err := pocket.NewGraph(failing, pocket.NewStore()).Run(context.Background(), nil)
+		if err == nil || !strings.Contains(err.Error(), "map input failed: no email") {
+			t.Errorf("Run() error = %v, want map input error", err)
+		}
+	})
+}
+
+func TestGraphReconnectAfterRun(t *testing.T) {
+	constant := func(name string) pocket.Node {
+		return pocket.NewNode[any, any](name,
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					return name, nil
+				},
+			},
+		)
+	}
+	start := constant("start")
+	start.Connect("default", constant("first"))
+	graph := pocket.NewGraph(start, pocket.NewStore())
+
+	for _, want := range []string{"first", "second"} {
+		result, err := graph.Run(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if result != want {
+			t.Errorf("Expected %s, got %v", want, result)
+		}
+		// Routes resolved by the run follow new connections
+		start.Connect("default", constant("second"))
 	}
+}
 
-	// The error will be wrapped, so check if it contains deadline exceeded
-	if !strings.Contains(err.Error(), "context deadline exceeded") &&
-		!strings.Contains(err.Error(), "failed after 1 attempts") {
-		t.Errorf("Expected timeout error, got %v", err)
+func TestGraphConnectWhileRunning(t *testing.T) {
+	constant := func(name string) pocket.Node {
+		return pocket.NewNode[any, any](name,
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					return name, nil
+				},
+			},
+		)
+	}
+	// start routes through a nested graph, whose own successor is the last
+	// node
+	inner := pocket.NewGraph(constant("inner"), pocket.NewStore())
+	inner.Connect("default", constant("first"))
+	start := constant("start")
+	start.Connect("default", inner)
+	graph := pocket.NewGraph(start, pocket.NewStore())
+
+	// Connecting nodes while runs route through them must not race;
+	// run with -race
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	last := []pocket.Node{constant("first"), constant("second")}
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			start.Connect("default", inner)
+			inner.Connect("default", last[i%2])
+		}
+	}()
+
+	for range 1000 {
+		result, err := graph.Run(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if result != "first" && result != "second" {
+			t.Errorf("Expected first or second, got %v", result)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestGraphWithOverride(t *testing.T) {
+	router := pocket.NewNode[any, any]("router",
+		pocket.Steps{
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, result any) (any, string, error) {
+				return input, "fetch", nil
+			},
+		},
+	)
+	fetch := pocket.NewNode[any, any]("fetch",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return nil, errors.New("network unavailable")
+			},
+		},
+	)
+	ok := pocket.NewNode[any, any]("ok",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return "ok: " + input.(string), nil
+			},
+		},
+	)
+	cached := pocket.NewNode[any, any]("cached",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return "cached: " + input.(string), nil
+			},
+		},
+	)
+	router.Connect("fetch", fetch)
+	fetch.Connect("default", ok)
+	fetch.Connect("cached", cached)
+
+	ctx := context.Background()
+	graph := pocket.NewGraph(router, pocket.NewStore())
+
+	if _, err := graph.Run(ctx, "x"); err == nil {
+		t.Fatal("Expected error from real fetch node")
+	}
+
+	mock := func(action string) pocket.Node {
+		return pocket.NewNode[any, any]("mock",
+			pocket.Steps{
+				Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, result any) (any, string, error) {
+					return "data", action, nil
+				},
+			},
+		)
+	}
+
+	tests := []struct {
+		action string
+		want   string
+	}{
+		{"default", "ok: data"},
+		{"cached", "cached: data"},
+	}
+	for _, tt := range tests {
+		result, err := graph.WithOverride("fetch", mock(tt.action)).Run(ctx, "x")
+		if err != nil {
+			t.Fatalf("Run() with override error = %v", err)
+		}
+		if result != tt.want {
+			t.Errorf("Run() with override = %v, want %v", result, tt.want)
+		}
+	}
+
+	// Overrides accumulate and are kept by WithStore
+	overridden := graph.WithOverride("fetch", mock("default")).WithOverride("ok", mock("")).WithStore(pocket.NewStore())
+	if result, err := overridden.Run(ctx, "x"); err != nil || result != "data" {
+		t.Errorf("Run() with two overrides = %v, %v, want data", result, err)
+	}
+
+	// The original graph is unchanged
+	if _, err := graph.Run(ctx, "x"); err == nil {
+		t.Error("Expected original graph to keep the real fetch node")
+	}
+}
+
+func TestWithChaos(t *testing.T) {
+	ctx := context.Background()
+
+	newStep := func(name string, calls *atomic.Int32, opts ...pocket.Option) pocket.Node {
+		return pocket.NewNode[any, any](name,
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					calls.Add(1)
+					if err := ctx.Err(); err != nil {
+						return nil, err
+					}
+					return name, nil
+				},
+			},
+			opts...,
+		)
+	}
+
+	t.Run("errors on chosen nodes", func(t *testing.T) {
+		var aCalls, bCalls atomic.Int32
+		a, b := newStep("a", &aCalls), newStep("b", &bCalls)
+		a.Connect("default", b)
+
+		injected := errors.New("service unavailable")
+		graph := pocket.NewGraph(a, pocket.NewStore(), pocket.WithChaos(pocket.ChaosConfig{
+			Nodes:     []string{"b"},
+			ErrorRate: 1,
+			Error:     injected,
+		}))
+		_, err := graph.Run(ctx, nil)
+		if !errors.Is(err, injected) || !strings.Contains(err.Error(), "node b") {
+			t.Fatalf("Expected injected error from b, got %v", err)
+		}
+		if aCalls.Load() != 1 || bCalls.Load() != 0 {
+			t.Errorf("Expected a to run and b not to, got %d and %d calls", aCalls.Load(), bCalls.Load())
+		}
+	})
+
+	t.Run("exercises retries and fallbacks", func(t *testing.T) {
+		var calls atomic.Int32
+		node := newStep("flaky", &calls, pocket.WithRetry(50, 0))
+		graph := pocket.NewGraph(node, pocket.NewStore(), pocket.WithChaos(pocket.ChaosConfig{
+			ErrorRate: 0.5,
+			Seed:      1,
+		}))
+		result, err := graph.Run(ctx, nil)
+		if err != nil || result != "flaky" {
+			t.Fatalf("Expected retries to get past injected errors, got %v, %v", result, err)
+		}
+
+		fallback := pocket.NewNode[any, any]("fallback",
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					return "primary", nil
+				},
+				Fallback: func(ctx context.Context, input any, err error) (any, error) {
+					if !errors.Is(err, pocket.ErrChaos) {
+						t.Errorf("Expected ErrChaos in fallback, got %v", err)
+					}
+					return "fallback", nil
+				},
+			},
+		)
+		graph = pocket.NewGraph(fallback, pocket.NewStore(), pocket.WithChaos(pocket.ChaosConfig{ErrorRate: 1}))
+		if result, err := graph.Run(ctx, nil); err != nil || result != "fallback" {
+			t.Errorf("Expected fallback result, got %v, %v", result, err)
+		}
+	})
+
+	t.Run("cancels context", func(t *testing.T) {
+		var calls atomic.Int32
+		graph := pocket.NewGraph(newStep("a", &calls), pocket.NewStore(), pocket.WithChaos(pocket.ChaosConfig{CancelRate: 1}))
+		if _, err := graph.Run(ctx, nil); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("Expected Exec to run with the canceled context, got %d calls", calls.Load())
+		}
+	})
+
+	t.Run("injects latency", func(t *testing.T) {
+		var calls atomic.Int32
+		graph := pocket.NewGraph(newStep("a", &calls), pocket.NewStore(), pocket.WithChaos(pocket.ChaosConfig{
+			LatencyRate: 1,
+			Latency:     time.Hour,
+		}))
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		if _, err := graph.Run(timeout, nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected injected latency to outlast the deadline, got %v", err)
+		}
+		if calls.Load() != 0 {
+			t.Errorf("Expected Exec not to run after the deadline, got %d calls", calls.Load())
+		}
+	})
+
+	t.Run("seeded faults repeat", func(t *testing.T) {
+		outcomes := func() string {
+			var calls atomic.Int32
+			graph := pocket.NewGraph(newStep("a", &calls), pocket.NewStore(), pocket.WithChaos(pocket.ChaosConfig{
+				ErrorRate: 0.5,
+				Seed:      7,
+			}))
+			var b strings.Builder
+			for i := 0; i < 20; i++ {
+				_, err := graph.Run(ctx, nil)
+				b.WriteString(fmt.Sprint(err != nil))
+			}
+			return b.String()
+		}
+		if first, second := outcomes(), outcomes(); first != second {
+			t.Errorf("Expected the same faults for the same seed, got %s and %s", first, second)
+		}
+	})
+}
+
+// fixedClock is a Clock stopped at a time.
+type fixedClock struct{ now time.Time }
+
+func (c fixedClock) Now() time.Time                         { return c.now }
+func (c fixedClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func TestWithDeterminism(t *testing.T) {
+	ctx := context.Background()
+
+	sample := pocket.NewNode[any, any]("sample",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				r, ok := pocket.RandFrom(ctx)
+				if !ok {
+					return nil, errors.New("no random source")
+				}
+				return fmt.Sprintf("%d %s", r.Int63(), pocket.ClockFrom(ctx).Now().Format(time.RFC3339)), nil
+			},
+		},
+	)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	run := func(graph *pocket.Graph) string {
+		t.Helper()
+		result, err := graph.Run(ctx, nil)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		return result.(string)
+	}
+
+	graph := pocket.NewGraph(sample, pocket.NewStore(), pocket.WithDeterminism(42, fixedClock{now}))
+	first := run(graph)
+	if !strings.HasSuffix(first, " 2024-01-02T03:04:05Z") {
+		t.Errorf("Expected the run's clock time, got %s", first)
+	}
+	if second := run(graph); second != first {
+		t.Errorf("Expected repeated runs to match, got %s and %s", first, second)
+	}
+	if other := run(pocket.NewGraph(sample, pocket.NewStore(), pocket.WithDeterminism(43, fixedClock{now}))); other == first {
+		t.Errorf("Expected a different seed to give different results, got %s", other)
+	}
+
+	// Nested graphs inherit the source
+	outer := pocket.NewGraph(pocket.NewGraph(sample, pocket.NewStore()).AsNode("inner"), pocket.NewStore(), pocket.WithDeterminism(42, fixedClock{now}))
+	if nested := run(outer); nested != first {
+		t.Errorf("Expected nested graph to use the seeded source, got %s, want %s", nested, first)
+	}
+
+	// Without determinism there's no source and the system clock is used
+	if _, err := pocket.NewGraph(sample, pocket.NewStore()).Run(ctx, nil); err == nil {
+		t.Error("Expected no random source without WithDeterminism")
+	}
+	if since := time.Since(pocket.ClockFrom(ctx).Now()); since < 0 || since > time.Minute {
+		t.Errorf("Expected system clock by default, off by %v", since)
+	}
+}
+
+func TestSimulatedClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := pocket.NewSimulatedClock(start)
+
+	short, long := clock.After(time.Minute), clock.After(time.Hour)
+	if clock.Waiters() != 2 {
+		t.Fatalf("Expected 2 waiters, got %d", clock.Waiters())
+	}
+
+	clock.Advance(30 * time.Minute)
+	select {
+	case at := <-short:
+		if !at.Equal(start.Add(30 * time.Minute)) {
+			t.Errorf("Expected wait to complete at the clock's time, got %v", at)
+		}
+	default:
+		t.Error("Expected the minute wait to complete")
+	}
+	select {
+	case <-long:
+		t.Error("Expected the hour wait to be pending")
+	default:
+	}
+
+	clock.Set(start.Add(2 * time.Hour))
+	<-long
+	if clock.Waiters() != 0 {
+		t.Errorf("Expected no waiters, got %d", clock.Waiters())
+	}
+
+	// The clock doesn't move backwards
+	clock.Set(start)
+	if got := clock.Now(); !got.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("Expected clock to stay at 2h, got %v", got)
+	}
+
+	clock.AutoAdvance(true)
+	<-clock.After(24 * time.Hour)
+	if got := clock.Now(); !got.Equal(start.Add(26 * time.Hour)) {
+		t.Errorf("Expected auto advance to 26h, got %v", got)
 	}
 }
 
+func TestWithClock(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("retry delays", func(t *testing.T) {
+		clock := pocket.NewSimulatedClock(start)
+		clock.AutoAdvance(true)
+
+		var attempts int
+		node := pocket.NewNode[any, any]("flaky",
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					attempts++
+					if attempts < 3 {
+						return nil, errors.New("unavailable")
+					}
+					return pocket.ClockFrom(ctx).Now(), nil
+				},
+			},
+			pocket.WithRetry(3, time.Hour),
+		)
+
+		began := time.Now()
+		result, err := pocket.NewGraph(node, pocket.NewStore(), pocket.WithClock(clock)).Run(ctx, nil)
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if elapsed := time.Since(began); elapsed > time.Second {
+			t.Errorf("Expected simulated retry delays, took %v", elapsed)
+		}
+		if !result.(time.Time).Equal(start.Add(2 * time.Hour)) {
+			t.Errorf("Expected two hours of retry delays, clock at %v", result)
+		}
+	})
+
+	t.Run("memoization TTL", func(t *testing.T) {
+		clock := pocket.NewSimulatedClock(start)
+		var calls int
+		node := pocket.NewNode[any, any]("lookup",
+			pocket.Steps{
+				Exec: func(ctx context.Context, input any) (any, error) {
+					calls++
+					return calls, nil
+				},
+			},
+			pocket.WithMemoization(time.Minute, nil),
+		)
+		graph := pocket.NewGraph(node, pocket.NewStore(), pocket.WithClock(clock))
+
+		for _, advance := range []time.Duration{0, 30 * time.Second, 2 * time.Minute} {
+			clock.Advance(advance)
+			if _, err := graph.Run(ctx, "key"); err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+		}
+		if calls != 2 {
+			t.Errorf("Expected the cached result to expire after a minute, got %d calls", calls)
+		}
+	})
+}
+
 func TestBuilder(t *testing.T) {
 	store := pocket.NewStore()
 
@@ -755,6 +2489,100 @@ func TestValidateGraph(t *testing.T) {
 	}
 }
 
+func TestValidateGraphSchemas(t *testing.T) {
+	user := map[string]any{
+		"type":     "object",
+		"required": []string{"id"},
+		"properties": map[string]any{
+			"id":   map[string]any{"type": "integer"},
+			"name": map[string]any{"type": "string"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		output map[string]any
+		input  map[string]any
+		errMsg string
+	}{
+		{
+			name:   "compatible objects",
+			output: user,
+			input: map[string]any{
+				"type":       "object",
+				"required":   []any{"id"},
+				"properties": map[string]any{"id": map[string]any{"type": "number"}},
+			},
+		},
+		{
+			name:   "undeclared input",
+			output: user,
+		},
+		{
+			name:   "open output properties",
+			output: map[string]any{"type": "object"},
+			input:  user,
+		},
+		{
+			name:   "type mismatch",
+			output: map[string]any{"type": "string"},
+			input:  user,
+			errMsg: "output is string, want object",
+		},
+		{
+			name: "missing required property",
+			output: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"name": map[string]any{"type": "string"}},
+			},
+			input:  user,
+			errMsg: `missing required property "id"`,
+		},
+		{
+			name: "nested property mismatch",
+			output: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"id": map[string]any{"type": "string"}},
+			},
+			input:  user,
+			errMsg: "output.id is string, want integer",
+		},
+		{
+			name:   "array items",
+			output: map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			input:  map[string]any{"type": "array", "items": map[string]any{"type": []any{"integer", "null"}}},
+			errMsg: "output[] is string, want integer or null",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := pocket.NewNode[any, any]("producer", pocket.Steps{}, pocket.WithOutputSchema(tt.output))
+			consumer := pocket.NewNode[any, any]("consumer", pocket.Steps{}, pocket.WithInputSchema(tt.input))
+			producer.Connect("default", consumer)
+
+			err := pocket.ValidateGraph(producer)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("ValidateGraph() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("ValidateGraph() error = %v, want error containing %q", err, tt.errMsg)
+			}
+		})
+	}
+
+	t.Run("graph takes start input schema", func(t *testing.T) {
+		start := pocket.NewNode[any, any]("start", pocket.Steps{}, pocket.WithInputSchema(user))
+		input, output := pocket.Schemas(pocket.NewGraph(start, pocket.NewStore()).AsNode("sub"))
+		if input["type"] != "object" || output != nil {
+			t.Errorf("Schemas() = %v, %v, want the start node's input schema", input, output)
+		}
+	})
+}
+
 func TestLifecycleSteps(t *testing.T) {
 	ctx := context.Background()
 	store := pocket.NewStore()
@@ -890,3 +2718,142 @@ func TestErrorHandler(t *testing.T) {
 		t.Errorf("Wrong error captured: %v", capturedError)
 	}
 }
+
+func TestTenantLimits(t *testing.T) {
+	clock := pocket.NewSimulatedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	tenants := pocket.NewTenants(pocket.TenantLimits{RunsPerSecond: 1, Burst: 2})
+	tenants.SetLimits("trial", pocket.TenantLimits{Budget: 1})
+
+	var logged []any
+	logger := &recordingLogger{debug: func(keysAndValues []any) { logged = keysAndValues }}
+
+	node := pocket.NewNode[any, any]("llm", pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			return input, pocket.ChargeTenant(ctx, 0.6)
+		},
+	})
+	graph := pocket.NewGraph(node, pocket.NewStore(),
+		pocket.WithTenants(tenants), pocket.WithClock(clock), pocket.WithLogger(logger))
+
+	if _, err := graph.Run(context.Background(), nil); !errors.Is(err, pocket.ErrNoTenant) {
+		t.Errorf("Expected ErrNoTenant, got %v", err)
+	}
+
+	t.Run("rate", func(t *testing.T) {
+		ctx := pocket.WithTenant(context.Background(), "acme")
+		for i := 0; i < 2; i++ {
+			if _, err := graph.Run(ctx, i); err != nil {
+				t.Fatalf("Run %d within burst failed: %v", i, err)
+			}
+		}
+		if _, err := graph.Run(ctx, 2); !errors.Is(err, pocket.ErrTenantRateLimited) {
+			t.Errorf("Expected ErrTenantRateLimited, got %v", err)
+		}
+
+		// Other tenants have their own limits
+		if _, err := graph.Run(pocket.WithTenant(context.Background(), "globex"), 0); err != nil {
+			t.Errorf("Expected other tenant to run, got %v", err)
+		}
+
+		clock.Advance(time.Second)
+		if _, err := graph.Run(ctx, 3); err != nil {
+			t.Errorf("Expected run after refill, got %v", err)
+		}
+
+		usage := tenants.Usage("acme")
+		if usage.Runs != 3 || usage.Active != 0 {
+			t.Errorf("Expected 3 runs and none active, got %+v", usage)
+		}
+		if len(logged) < 2 || logged[len(logged)-2] != "tenant" || logged[len(logged)-1] != "acme" {
+			t.Errorf("Expected logs tagged with the tenant, got %v", logged)
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		ctx := pocket.WithTenant(context.Background(), "trial")
+		if _, err := graph.Run(ctx, nil); err != nil {
+			t.Fatalf("First run failed: %v", err)
+		}
+		if _, err := graph.Run(ctx, nil); !errors.Is(err, pocket.ErrTenantBudgetExceeded) {
+			t.Errorf("Expected charge over budget to fail, got %v", err)
+		}
+		if _, err := graph.Run(ctx, nil); !errors.Is(err, pocket.ErrTenantBudgetExceeded) {
+			t.Errorf("Expected run after budget spent to be rejected, got %v", err)
+		}
+		if spent := tenants.Usage("trial").Spent; spent != 1.2 {
+			t.Errorf("Expected 1.2 spent, got %v", spent)
+		}
+
+		tenants.ResetUsage("trial")
+		if _, err := graph.Run(ctx, nil); err != nil {
+			t.Errorf("Expected run after reset, got %v", err)
+		}
+	})
+}
+
+// recordingLogger passes the key-value pairs of debug entries to a function.
+type recordingLogger struct {
+	debug func(keysAndValues []any)
+}
+
+func (l *recordingLogger) Debug(ctx context.Context, msg string, keysAndValues ...any) {
+	l.debug(keysAndValues)
+}
+func (l *recordingLogger) Info(ctx context.Context, msg string, keysAndValues ...any)  {}
+func (l *recordingLogger) Error(ctx context.Context, msg string, keysAndValues ...any) {}
+
+func TestInputLimits(t *testing.T) {
+	echo := pocket.NewNode[any, any]("echo", pocket.Steps{
+		Exec: func(ctx context.Context, input any) (any, error) {
+			return input, nil
+		},
+	})
+	graph := pocket.NewGraph(echo, pocket.NewStore(), pocket.WithInputLimits(pocket.InputLimits{
+		MaxBytes:        100,
+		MaxStringLength: 20,
+		MaxArrayLength:  3,
+		MaxDepth:        3,
+	}))
+
+	type message struct {
+		Text string
+		Tags []string
+	}
+
+	tests := []struct {
+		name  string
+		input any
+		want  string // Empty if the input is accepted
+	}{
+		{"small map", map[string]any{"text": "hello", "tags": []any{"a", "b"}}, ""},
+		{"struct", &message{Text: "hello", Tags: []string{"x"}}, ""},
+		{"long string", map[string]any{"text": strings.Repeat("x", 21)}, "input.text is 21 bytes, limit 20"},
+		{"long array", []int{1, 2, 3, 4}, "input has 4 items, limit 3"},
+		{"deep", map[string]any{"a": map[string]any{"b": map[string]any{"c": []any{1}}}}, "input.a.b.c is nested deeper than 3"},
+		{"struct field", message{Tags: []string{"a", "b", "c", "d"}}, "input.Tags has 4 items"},
+		{"total size", []string{strings.Repeat("x", 20), strings.Repeat("x", 20), strings.Repeat("x", 20)}, ""},
+		{"too large", map[string]any{
+			"a": strings.Repeat("x", 20), "b": strings.Repeat("x", 20), "c": []any{strings.Repeat("x", 20), strings.Repeat("x", 20), strings.Repeat("x", 20)},
+		}, "past 100 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := graph.Run(context.Background(), tt.input)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Run() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, pocket.ErrInputTooLarge) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Run() error = %v, want ErrInputTooLarge with %q", err, tt.want)
+			}
+		})
+	}
+
+	// Nested as a node, the graph still applies its limits
+	outer := pocket.NewGraph(graph.AsNode("limited"), pocket.NewStore())
+	if _, err := outer.Run(context.Background(), []int{1, 2, 3, 4}); !errors.Is(err, pocket.ErrInputTooLarge) {
+		t.Errorf("Expected nested graph to reject the input, got %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+// Package pockettest provides helpers for testing Pocket nodes and graphs.
+//
+// Store is an in-memory store that records writes and has assertion
+// helpers. RunNode drives a single node through Prep, Exec, and Post
+// without running its successors:
+//
+//	store := pockettest.NewStore(map[string]any{"user:1": user})
+//	result, err := pockettest.RunNode(ctx, validator, store, input)
+//	result.AssertNext(t, "valid")
+//	store.AssertValue(t, "validated", true)
+//
+// Run runs a graph and captures the path it took, and RunScenarios runs
+// table-driven inputs through a graph asserting the path and output:
+//
+//	pockettest.RunScenarios(t, router, []pockettest.Scenario{
+//		{Name: "approved", Input: 50, WantPath: []string{"router", "approve"}},
+//		{Name: "escalated", Input: 5000, WantPath: []string{"router", "escalate"}},
+//	})
+package pockettest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/agentstation/pocket"
+)
+
+// Result holds the result of each lifecycle step of a node.
+type Result struct {
+	Prep   any
+	Exec   any
+	Output any
+	Next   string
+}
+
+// AssertNext checks that the node routed to action next.
+func (r *Result) AssertNext(t testing.TB, want string) {
+	t.Helper()
+	if r.Next != want {
+		t.Errorf("Next = %q, want %q", r.Next, want)
+	}
+}
+
+// RunNode runs a node's Prep, Exec, and Post against store, without
+// following its successors. It calls the lifecycle methods directly, so
+// options applied by a graph, such as retries, timeouts, and fallbacks,
+// don't apply. With a Store, writes are attributed to the node, and writes
+// during Prep fail it, since Prep should only read.
+func RunNode(ctx context.Context, node pocket.Node, store pocket.Store, input any) (*Result, error) {
+	fake, _ := store.(*Store)
+	if fake != nil {
+		fake.setNode(node.Name(), true)
+		defer fake.setNode("", false)
+	}
+
+	prep, err := node.Prep(ctx, store, input)
+	if err != nil {
+		return nil, fmt.Errorf("prep failed: %w", err)
+	}
+
+	if fake != nil {
+		fake.setNode(node.Name(), false)
+	}
+
+	exec, err := node.Exec(ctx, prep)
+	if err != nil {
+		return &Result{Prep: prep}, fmt.Errorf("exec failed: %w", err)
+	}
+
+	output, next, err := node.Post(ctx, store, input, prep, exec)
+	if err != nil {
+		return &Result{Prep: prep, Exec: exec}, fmt.Errorf("post failed: %w", err)
+	}
+
+	return &Result{Prep: prep, Exec: exec, Output: output, Next: next}, nil
+}
+
+// Step is one node visited during a run, and the action that led to the
+// next node.
+type Step struct {
+	Node string
+
+	// Action is the action whose successor ran next, or empty for the
+	// last node. If several actions lead to the same node, it's the first
+	// in sorted order.
+	Action string
+}
+
+// Trace is the result of a graph run.
+type Trace struct {
+	Steps  []Step
+	Output any
+	Err    error
+}
+
+// Path returns the names of the nodes visited, in order.
+func (tr *Trace) Path() []string {
+	path := make([]string, len(tr.Steps))
+	for i, step := range tr.Steps {
+		path[i] = step.Node
+	}
+	return path
+}
+
+// AssertPath checks that the run visited exactly the named nodes, in order.
+func (tr *Trace) AssertPath(t testing.TB, want ...string) {
+	t.Helper()
+	if got := tr.Path(); !slices.Equal(got, want) {
+		t.Errorf("Path = %s, want %s", strings.Join(got, " -> "), strings.Join(want, " -> "))
+	}
+}
+
+// AssertVisited checks that the run visited a node.
+func (tr *Trace) AssertVisited(t testing.TB, node string) {
+	t.Helper()
+	if !slices.Contains(tr.Path(), node) {
+		t.Errorf("Node %s was not visited (path %s)", node, strings.Join(tr.Path(), " -> "))
+	}
+}
+
+// AssertNotVisited checks that the run didn't visit a node.
+func (tr *Trace) AssertNotVisited(t testing.TB, node string) {
+	t.Helper()
+	if slices.Contains(tr.Path(), node) {
+		t.Errorf("Node %s was visited (path %s)", node, strings.Join(tr.Path(), " -> "))
+	}
+}
+
+// AssertRoute checks that node routed on action at least once.
+func (tr *Trace) AssertRoute(t testing.TB, node, action string) {
+	t.Helper()
+	var taken []string
+	for _, step := range tr.Steps {
+		if step.Node != node {
+			continue
+		}
+		if step.Action == action {
+			return
+		}
+		taken = append(taken, step.Action)
+	}
+	if taken == nil {
+		t.Errorf("Node %s was not visited, want it to route on %q", node, action)
+		return
+	}
+	t.Errorf("Node %s routed on %q, want %q", node, taken, action)
+}
+
+// Run runs the graph from start against store and records the nodes it
+// visits. A nil store gets an empty Store. Writes to a Store are attributed
+// to the node running.
+func Run(ctx context.Context, start pocket.Node, store pocket.Store, input any) *Trace {
+	if store == nil {
+		store = NewStore(nil)
+	}
+
+	rec := &recorder{store: store}
+	graph := pocket.NewGraph(start, store, pocket.WithLogger(rec))
+	output, err := graph.Run(ctx, input)
+	if fake, ok := store.(*Store); ok {
+		fake.setNode("", false)
+	}
+
+	return &Trace{
+		Steps:  steps(start, rec.path()),
+		Output: output,
+		Err:    err,
+	}
+}
+
+// recorder is a pocket.Logger that records the nodes a graph executes.
+type recorder struct {
+	store pocket.Store
+
+	mu    sync.Mutex
+	nodes []string
+}
+
+func (r *recorder) Debug(ctx context.Context, msg string, keysAndValues ...any) {
+	// The graph logs each node before executing it
+	if msg != "executing node" {
+		return
+	}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if keysAndValues[i] != "name" {
+			continue
+		}
+		name, _ := keysAndValues[i+1].(string)
+		r.mu.Lock()
+		r.nodes = append(r.nodes, name)
+		r.mu.Unlock()
+		if fake, ok := r.store.(*Store); ok {
+			fake.setNode(name, false)
+		}
+	}
+}
+
+func (r *recorder) Info(ctx context.Context, msg string, keysAndValues ...any) {}
+
+func (r *recorder) Error(ctx context.Context, msg string, keysAndValues ...any) {}
+
+func (r *recorder) path() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.nodes)
+}
+
+// steps pairs each visited node with the action that led to the next one,
+// found from the graph's connections.
+func steps(start pocket.Node, path []string) []Step {
+	nodes := make(map[string]pocket.Node)
+	collect(start, nodes)
+
+	result := make([]Step, len(path))
+	for i, name := range path {
+		result[i].Node = name
+		if i+1 == len(path) {
+			continue
+		}
+		n, ok := nodes[name]
+		if !ok {
+			continue
+		}
+		successors := n.Successors()
+		actions := make([]string, 0, len(successors))
+		for action := range successors {
+			actions = append(actions, action)
+		}
+		slices.Sort(actions)
+		for _, action := range actions {
+			if next := successors[action]; next != nil && next.Name() == path[i+1] {
+				result[i].Action = action
+				break
+			}
+		}
+	}
+	return result
+}
+
+// collect adds the nodes reachable from n by name.
+func collect(n pocket.Node, nodes map[string]pocket.Node) {
+	if n == nil {
+		return
+	}
+	if _, seen := nodes[n.Name()]; seen {
+		return
+	}
+	nodes[n.Name()] = n
+	for _, next := range n.Successors() {
+		collect(next, nodes)
+	}
+}
+
+// Scenario is an input to run through a graph and what to expect.
+type Scenario struct {
+	Name  string
+	Input any
+
+	// Store holds the values the store starts with.
+	Store map[string]any
+
+	// WantPath is the nodes the run should visit, in order, if set.
+	WantPath []string
+
+	// Want is the expected output, compared with reflect.DeepEqual, if set.
+	Want any
+
+	// WantErr is a substring of the expected error. Without it, the run
+	// must succeed.
+	WantErr string
+
+	// Check makes further assertions about the run.
+	Check func(t *testing.T, trace *Trace, store *Store)
+}
+
+// RunScenarios runs each scenario through the graph from start as a
+// subtest, with a fresh Store.
+func RunScenarios(t *testing.T, start pocket.Node, scenarios []Scenario) {
+	t.Helper()
+	for _, sc := range scenarios {
+		t.Run(sc.Name, func(t *testing.T) {
+			store := NewStore(sc.Store)
+			trace := Run(context.Background(), start, store, sc.Input)
+
+			switch {
+			case sc.WantErr != "":
+				if trace.Err == nil {
+					t.Errorf("Run() succeeded, want error containing %q", sc.WantErr)
+				} else if !strings.Contains(trace.Err.Error(), sc.WantErr) {
+					t.Errorf("Run() error = %v, want it to contain %q", trace.Err, sc.WantErr)
+				}
+			case trace.Err != nil:
+				t.Errorf("Run() error = %v", trace.Err)
+			case sc.Want != nil && !reflect.DeepEqual(trace.Output, sc.Want):
+				t.Errorf("Output = %#v, want %#v", trace.Output, sc.Want)
+			}
+
+			if sc.WantPath != nil {
+				trace.AssertPath(t, sc.WantPath...)
+			}
+			if sc.Check != nil {
+				sc.Check(t, trace, store)
+			}
+		})
+	}
+}
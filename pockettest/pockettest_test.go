@@ -0,0 +1,178 @@
+package pockettest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/pocket"
+)
+
+// newRouter returns a graph that routes amounts over 1000 to review.
+func newRouter() pocket.Node {
+	router := pocket.NewNode[int, int]("router",
+		pocket.Steps{
+			Prep: func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+				if input.(int) < 0 {
+					return nil, errors.New("negative amount")
+				}
+				return input, nil
+			},
+			Exec: func(ctx context.Context, prep any) (any, error) {
+				return prep, nil
+			},
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, result any) (any, string, error) {
+				if err := store.Set(ctx, "amount", result); err != nil {
+					return nil, "", err
+				}
+				if result.(int) > 1000 {
+					return result, "review", nil
+				}
+				return result, "approve", nil
+			},
+		},
+	)
+	approve := pocket.NewNode[int, string]("approve",
+		pocket.Steps{
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, result any) (any, string, error) {
+				return "approved", "done", store.Set(ctx, "status", "approved")
+			},
+		},
+	)
+	review := pocket.NewNode[int, string]("review",
+		pocket.Steps{
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, result any) (any, string, error) {
+				return "reviewed", "done", store.Set(ctx, "status", "reviewed")
+			},
+		},
+	)
+	router.Connect("approve", approve)
+	router.Connect("review", review)
+	return router
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(map[string]any{"seed": 1})
+
+	store.AssertValue(t, "seed", 1)
+	store.AssertNotWritten(t, "seed")
+
+	_ = store.Set(ctx, "a", []string{"x"})
+	scoped := store.Scope("user")
+	_ = scoped.Set(ctx, "name", "alice")
+	_ = store.Delete(ctx, "seed")
+
+	store.AssertValue(t, "a", []string{"x"})
+	store.AssertValue(t, "user:name", "alice")
+	store.AssertWritten(t, "seed")
+	store.AssertMissing(t, "seed")
+	scoped.(*Store).AssertValue(t, "name", "alice")
+
+	if got := strings.Join(store.Keys(), ","); got != "a,user:name" {
+		t.Errorf("Keys() = %s, want a,user:name", got)
+	}
+	if got := scoped.(*Store).Keys(); len(got) != 1 || got[0] != "name" {
+		t.Errorf("Scoped Keys() = %v, want [name]", got)
+	}
+
+	writes := store.Writes()
+	if len(writes) != 3 {
+		t.Fatalf("Writes() = %d, want 3", len(writes))
+	}
+	if writes[1].Key != "user:name" || !writes[2].Deleted {
+		t.Errorf("Writes() = %+v", writes)
+	}
+}
+
+func TestRunNode(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("runs lifecycle", func(t *testing.T) {
+		store := NewStore(nil)
+		result, err := RunNode(ctx, newRouter(), store, 5000)
+		if err != nil {
+			t.Fatalf("RunNode() error = %v", err)
+		}
+		result.AssertNext(t, "review")
+		if result.Prep != 5000 || result.Exec != 5000 || result.Output != 5000 {
+			t.Errorf("RunNode() = %+v", result)
+		}
+		store.AssertWrittenBy(t, "amount", "router")
+		// Successors don't run
+		store.AssertMissing(t, "status")
+	})
+
+	t.Run("fails on error", func(t *testing.T) {
+		_, err := RunNode(ctx, newRouter(), NewStore(nil), -1)
+		if err == nil || !strings.Contains(err.Error(), "prep failed: negative amount") {
+			t.Errorf("RunNode() error = %v, want prep failure", err)
+		}
+	})
+
+	t.Run("rejects writes in prep", func(t *testing.T) {
+		node := pocket.NewNode[any, any]("writer",
+			pocket.Steps{
+				Prep: func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+					return nil, store.Scope("x").Set(ctx, "key", 1)
+				},
+			},
+		)
+		store := NewStore(nil)
+		_, err := RunNode(ctx, node, store, nil)
+		if err == nil || !strings.Contains(err.Error(), "during Prep") {
+			t.Errorf("RunNode() error = %v, want Prep write error", err)
+		}
+		store.AssertNotWritten(t, "x:key")
+	})
+}
+
+func TestRun(t *testing.T) {
+	store := NewStore(nil)
+	trace := Run(context.Background(), newRouter(), store, 50)
+	if trace.Err != nil {
+		t.Fatalf("Run() error = %v", trace.Err)
+	}
+
+	trace.AssertPath(t, "router", "approve")
+	trace.AssertVisited(t, "approve")
+	trace.AssertNotVisited(t, "review")
+	trace.AssertRoute(t, "router", "approve")
+	if trace.Output != "approved" {
+		t.Errorf("Output = %v, want approved", trace.Output)
+	}
+	if trace.Steps[1].Action != "" {
+		t.Errorf("Last step action = %q, want none", trace.Steps[1].Action)
+	}
+	store.AssertWrittenBy(t, "amount", "router")
+	store.AssertWrittenBy(t, "status", "approve")
+
+	// Failed assertions are reported
+	ft := &testing.T{}
+	trace.AssertRoute(ft, "router", "review")
+	trace.AssertPath(ft, "router", "review")
+	if !ft.Failed() {
+		t.Error("Failing assertions did not fail the test")
+	}
+}
+
+func TestRunScenarios(t *testing.T) {
+	RunScenarios(t, newRouter(), []Scenario{
+		{Name: "approved", Input: 50, WantPath: []string{"router", "approve"}, Want: "approved"},
+		{Name: "reviewed", Input: 5000, WantPath: []string{"router", "review"}, Want: "reviewed"},
+		{Name: "invalid", Input: -1, WantPath: []string{"router"}, WantErr: "negative amount"},
+		{
+			Name:  "fresh store",
+			Input: 50,
+			Store: map[string]any{"status": "pending"},
+			Check: func(t *testing.T, trace *Trace, store *Store) {
+				trace.AssertRoute(t, "router", "approve")
+				store.AssertValue(t, "status", "approved")
+				if n := len(store.Writes()); n != 2 {
+					t.Errorf("Writes() = %d, want 2", n)
+				}
+			},
+		},
+	})
+}
@@ -0,0 +1,193 @@
+package pockettest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/agentstation/pocket"
+)
+
+// Write records one change to a Store.
+type Write struct {
+	// Key is the full key, including any scope prefixes.
+	Key string
+
+	// Value is the value written, or nil for a deletion.
+	Value any
+
+	// Deleted reports whether the write removed the key.
+	Deleted bool
+
+	// Node is the node that made the write, if it was made during RunNode
+	// or a Run.
+	Node string
+}
+
+// Store is an in-memory pocket.Store that records every write, with
+// assertion helpers for tests. Scoped stores share the data and record
+// writes with their full keys.
+type Store struct {
+	state  *storeState
+	prefix string
+}
+
+type storeState struct {
+	mu     sync.Mutex
+	data   map[string]any
+	writes []Write
+
+	// node is the node running, for attributing writes
+	node string
+
+	// readOnly rejects writes, while RunNode runs Prep
+	readOnly bool
+}
+
+// NewStore creates a Store holding values.
+func NewStore(values map[string]any) *Store {
+	data := make(map[string]any, len(values))
+	for key, value := range values {
+		data[key] = value
+	}
+	return &Store{state: &storeState{data: data}}
+}
+
+// Get retrieves a value by key.
+func (s *Store) Get(ctx context.Context, key string) (any, bool) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	value, ok := s.state.data[s.prefix+key]
+	return value, ok
+}
+
+// Set stores a value and records the write.
+func (s *Store) Set(ctx context.Context, key string, value any) error {
+	return s.write(s.prefix+key, value, false)
+}
+
+// Delete removes a key and records the write.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.write(s.prefix+key, nil, true)
+}
+
+// Scope returns a store whose keys are prefixed with prefix, as
+// pocket.NewStore does.
+func (s *Store) Scope(prefix string) pocket.Store {
+	return &Store{state: s.state, prefix: s.prefix + prefix + ":"}
+}
+
+func (s *Store) write(key string, value any, deleted bool) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	if s.state.readOnly {
+		return fmt.Errorf("pockettest: node %s wrote %q during Prep, which is read-only", s.state.node, key)
+	}
+
+	if deleted {
+		delete(s.state.data, key)
+	} else {
+		s.state.data[key] = value
+	}
+	s.state.writes = append(s.state.writes, Write{Key: key, Value: value, Deleted: deleted, Node: s.state.node})
+	return nil
+}
+
+// setNode attributes later writes to a node.
+func (s *Store) setNode(name string, readOnly bool) {
+	s.state.mu.Lock()
+	s.state.node = name
+	s.state.readOnly = readOnly
+	s.state.mu.Unlock()
+}
+
+// Writes returns the recorded writes, oldest first.
+func (s *Store) Writes() []Write {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	return slices.Clone(s.state.writes)
+}
+
+// Keys returns the keys in the store under the store's scope, sorted.
+func (s *Store) Keys() []string {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	var keys []string
+	for key := range s.state.data {
+		if rest, ok := strings.CutPrefix(key, s.prefix); ok {
+			keys = append(keys, rest)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// AssertValue checks that key holds want, compared with reflect.DeepEqual.
+func (s *Store) AssertValue(t testing.TB, key string, want any) {
+	t.Helper()
+	got, ok := s.Get(context.Background(), key)
+	if !ok {
+		t.Errorf("Store key %q is missing, want %#v", key, want)
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Store key %q = %#v, want %#v", key, got, want)
+	}
+}
+
+// AssertMissing checks that key isn't in the store.
+func (s *Store) AssertMissing(t testing.TB, key string) {
+	t.Helper()
+	if got, ok := s.Get(context.Background(), key); ok {
+		t.Errorf("Store key %q = %#v, want it missing", key, got)
+	}
+}
+
+// AssertWritten checks that key was set or deleted at least once.
+func (s *Store) AssertWritten(t testing.TB, key string) {
+	t.Helper()
+	if len(s.writesTo(key)) == 0 {
+		t.Errorf("Store key %q was never written", key)
+	}
+}
+
+// AssertNotWritten checks that key was never set or deleted.
+func (s *Store) AssertNotWritten(t testing.TB, key string) {
+	t.Helper()
+	if writes := s.writesTo(key); len(writes) > 0 {
+		t.Errorf("Store key %q was written %d times, want none (first by %q)", key, len(writes), writes[0].Node)
+	}
+}
+
+// AssertWrittenBy checks that key was written by node.
+func (s *Store) AssertWrittenBy(t testing.TB, key, node string) {
+	t.Helper()
+	writes := s.writesTo(key)
+	for _, w := range writes {
+		if w.Node == node {
+			return
+		}
+	}
+	writers := make([]string, len(writes))
+	for i, w := range writes {
+		writers[i] = w.Node
+	}
+	t.Errorf("Store key %q was not written by %s (written by %v)", key, node, writers)
+}
+
+// writesTo returns the writes to a key under the store's scope.
+func (s *Store) writesTo(key string) []Write {
+	var writes []Write
+	for _, w := range s.Writes() {
+		if w.Key == s.prefix+key {
+			writes = append(writes, w)
+		}
+	}
+	return writes
+}
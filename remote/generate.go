@@ -0,0 +1,3 @@
+package remote
+
+//go:generate gomarkdoc -o README.md -e . --repository.url https://github.com/agentstation/pocket --repository.default-branch master --repository.path /remote
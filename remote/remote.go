@@ -0,0 +1,281 @@
+// Package remote runs node Exec steps on remote pocket workers over HTTP, so
+// heavy steps can be farmed out to a pool of workers while the graph, its
+// store, and each node's Prep and Post steps stay with the coordinator.
+//
+// Exec has no store access, so only the prep result travels to the worker
+// and only the exec result travels back, both encoded as JSON.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/agentstation/pocket"
+)
+
+// maxBodySize bounds request and response bodies.
+const maxBodySize = 32 << 20
+
+// ErrUnknownNode is returned when a worker has no node with the requested name.
+var ErrUnknownNode = errors.New("remote: unknown node")
+
+// Error is a failure reported by a remote worker.
+type Error struct {
+	// Node is the name of the node that failed.
+	Node string
+
+	// StatusCode is the worker's HTTP status code.
+	StatusCode int
+
+	// Message describes the failure.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("remote node %s: %s (status %d)", e.Node, e.Message, e.StatusCode)
+}
+
+// Is reports whether the error matches ErrUnknownNode for missing nodes.
+func (e *Error) Is(target error) bool {
+	return target == ErrUnknownNode && e.StatusCode == http.StatusNotFound
+}
+
+// Option configures a remote node or a worker registration.
+type Option func(*options)
+
+type options struct {
+	client     *http.Client
+	header     http.Header
+	prepType   reflect.Type
+	resultType reflect.Type
+}
+
+// WithHTTPClient sets the client used to call the worker.
+// Defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithHeader adds a header, such as an authorization token, to every
+// request sent to the worker.
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		o.header.Add(key, value)
+	}
+}
+
+// WithPrepType makes a worker decode prep results into T before calling the
+// node's Exec. Use it for nodes whose Exec expects a concrete type. Without
+// it, prep results are decoded into generic JSON values.
+func WithPrepType[T any]() Option {
+	return func(o *options) {
+		o.prepType = reflect.TypeOf((*T)(nil)).Elem()
+	}
+}
+
+// WithResultType makes a remote node decode exec results into T before
+// calling Post. Without it, results are decoded into generic JSON values.
+func WithResultType[T any]() Option {
+	return func(o *options) {
+		o.resultType = reflect.TypeOf((*T)(nil)).Elem()
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{client: http.DefaultClient, header: make(http.Header)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// decode unmarshals data into a new value of typ, or a generic JSON value
+// when typ is nil.
+func decode(data []byte, typ reflect.Type) (any, error) {
+	if typ == nil {
+		var v any
+		err := json.Unmarshal(data, &v)
+		return v, err
+	}
+	ptr := reflect.New(typ)
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// response is the body a worker returns.
+type response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// remoteNode runs Prep and Post locally and Exec on a worker.
+type remoteNode struct {
+	pocket.Node
+	endpoint string
+	opts     *options
+}
+
+// Node wraps node so its Exec runs on the worker at baseURL, which must
+// have a node with the same name registered. Prep, Post, routing, and the
+// store stay local. Failures reported by the worker are returned as *Error.
+//
+// The wrapper is not a node created by pocket.NewNode, so options such as
+// WithRetry and WithTimeout on node don't apply; use the middleware and
+// fallback packages, or a context deadline, to add them around it.
+func Node(node pocket.Node, baseURL string, opts ...Option) pocket.Node {
+	return &remoteNode{
+		Node:     node,
+		endpoint: strings.TrimSuffix(baseURL, "/") + "/nodes/" + url.PathEscape(node.Name()) + "/exec",
+		opts:     newOptions(opts),
+	}
+}
+
+// Exec sends the prep result to the worker and returns its exec result.
+func (n *remoteNode) Exec(ctx context.Context, prepResult any) (any, error) {
+	body, err := json.Marshal(prepResult)
+	if err != nil {
+		return nil, fmt.Errorf("encode prep result: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range n.opts.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.opts.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var r response
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, &Error{Node: n.Name(), StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &Error{Node: n.Name(), StatusCode: resp.StatusCode, Message: r.Error}
+	}
+
+	if len(r.Result) == 0 {
+		return nil, nil
+	}
+	result, err := decode(r.Result, n.opts.resultType)
+	if err != nil {
+		return nil, fmt.Errorf("decode exec result: %w", err)
+	}
+	return result, nil
+}
+
+// Connect adds a successor to the remote node itself, so routing from it
+// works like any other node.
+func (n *remoteNode) Connect(action string, next pocket.Node) pocket.Node {
+	n.Node.Connect(action, next)
+	return n
+}
+
+// Worker is an http.Handler that runs Exec for registered nodes. Serve it
+// with net/http; it handles POST /nodes/{name}/exec.
+type Worker struct {
+	mu    sync.RWMutex
+	nodes map[string]workerNode
+	mux   *http.ServeMux
+}
+
+type workerNode struct {
+	node     pocket.Node
+	prepType reflect.Type
+}
+
+// NewWorker creates a worker serving the given nodes.
+func NewWorker(nodes ...pocket.Node) *Worker {
+	w := &Worker{nodes: make(map[string]workerNode)}
+	w.mux = http.NewServeMux()
+	w.mux.HandleFunc("POST /nodes/{name}/exec", w.handleExec)
+
+	for _, node := range nodes {
+		w.Register(node)
+	}
+	return w
+}
+
+// Register adds a node to the worker, replacing any node with the same name.
+// Only WithPrepType applies to registrations.
+func (w *Worker) Register(node pocket.Node, opts ...Option) {
+	o := newOptions(opts)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.nodes[node.Name()] = workerNode{node: node, prepType: o.prepType}
+}
+
+// ServeHTTP implements http.Handler.
+func (w *Worker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mux.ServeHTTP(rw, r)
+}
+
+func (w *Worker) handleExec(rw http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	w.mu.RLock()
+	registered, ok := w.nodes[name]
+	w.mu.RUnlock()
+	if !ok {
+		writeResponse(rw, http.StatusNotFound, response{Error: fmt.Sprintf("unknown node %q", name)})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxBodySize))
+	if err != nil {
+		writeResponse(rw, http.StatusRequestEntityTooLarge, response{Error: err.Error()})
+		return
+	}
+
+	var prepResult any
+	if len(bytes.TrimSpace(body)) > 0 {
+		if prepResult, err = decode(body, registered.prepType); err != nil {
+			writeResponse(rw, http.StatusBadRequest, response{Error: fmt.Sprintf("decode prep result: %v", err)})
+			return
+		}
+	}
+
+	result, err := registered.node.Exec(r.Context(), prepResult)
+	if err != nil {
+		writeResponse(rw, http.StatusInternalServerError, response{Error: err.Error()})
+		return
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		writeResponse(rw, http.StatusInternalServerError, response{Error: fmt.Sprintf("encode exec result: %v", err)})
+		return
+	}
+	writeResponse(rw, http.StatusOK, response{Result: data})
+}
+
+func writeResponse(rw http.ResponseWriter, status int, r response) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(r)
+}
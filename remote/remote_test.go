@@ -0,0 +1,117 @@
+package remote_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/pocket"
+	"github.com/agentstation/pocket/remote"
+)
+
+type wordCount struct {
+	Text string `json:"text"`
+}
+
+type wordCountResult struct {
+	Words int `json:"words"`
+}
+
+func newWordCounter(execs *int) pocket.Node {
+	return pocket.NewNode[string, int]("count",
+		pocket.Steps{
+			Prep: func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+				return wordCount{Text: input.(string)}, nil
+			},
+			Exec: func(ctx context.Context, prep any) (any, error) {
+				*execs++
+				req := prep.(wordCount)
+				if req.Text == "" {
+					return nil, errors.New("empty text")
+				}
+				return wordCountResult{Words: len(strings.Fields(req.Text))}, nil
+			},
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, result any) (any, string, error) {
+				words := result.(wordCountResult).Words
+				if err := store.Set(ctx, "words", words); err != nil {
+					return nil, "", err
+				}
+				return words, "done", nil
+			},
+		},
+	)
+}
+
+func TestRemoteNode(t *testing.T) {
+	var workerExecs, localExecs int
+
+	worker := remote.NewWorker()
+	worker.Register(newWordCounter(&workerExecs), remote.WithPrepType[wordCount]())
+	server := httptest.NewServer(worker)
+	defer server.Close()
+
+	node := remote.Node(newWordCounter(&localExecs), server.URL,
+		remote.WithResultType[wordCountResult](),
+	)
+
+	t.Run("runs exec on the worker", func(t *testing.T) {
+		store := pocket.NewStore()
+		result, err := pocket.NewGraph(node, store).Run(context.Background(), "one two three")
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		if result != 3 {
+			t.Errorf("Expected 3 words, got %v", result)
+		}
+		if workerExecs != 1 || localExecs != 0 {
+			t.Errorf("Expected exec only on the worker, got worker=%d local=%d", workerExecs, localExecs)
+		}
+
+		// Post ran locally against the coordinator's store
+		if words, _ := store.Get(context.Background(), "words"); words != 3 {
+			t.Errorf("Expected words=3 in local store, got %v", words)
+		}
+	})
+
+	t.Run("returns worker errors", func(t *testing.T) {
+		_, err := pocket.NewGraph(node, pocket.NewStore()).Run(context.Background(), "")
+
+		var remoteErr *remote.Error
+		if !errors.As(err, &remoteErr) {
+			t.Fatalf("Expected *remote.Error, got %v", err)
+		}
+		if remoteErr.Node != "count" || !strings.Contains(remoteErr.Message, "empty text") {
+			t.Errorf("Unexpected error: %+v", remoteErr)
+		}
+	})
+
+	t.Run("unknown node", func(t *testing.T) {
+		missing := remote.Node(pocket.NewNode[any, any]("missing", pocket.Steps{}), server.URL)
+
+		_, err := pocket.NewGraph(missing, pocket.NewStore()).Run(context.Background(), "x")
+		if !errors.Is(err, remote.ErrUnknownNode) {
+			t.Errorf("Expected ErrUnknownNode, got %v", err)
+		}
+	})
+
+	t.Run("untyped values", func(t *testing.T) {
+		echo := pocket.NewNode[any, any]("echo", pocket.Steps{
+			Exec: func(ctx context.Context, prep any) (any, error) {
+				return prep, nil
+			},
+		})
+		worker.Register(echo)
+
+		result, err := pocket.NewGraph(remote.Node(echo, server.URL), pocket.NewStore()).
+			Run(context.Background(), map[string]any{"n": 1})
+		if err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if m, ok := result.(map[string]any); !ok || m["n"] != float64(1) {
+			t.Errorf("Expected decoded JSON map, got %#v", result)
+		}
+	})
+}
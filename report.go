@@ -0,0 +1,133 @@
+package pocket
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RunReport describes what happened during a run.
+type RunReport struct {
+	// RunID is the run's ID from WithRunID, if any.
+	RunID string
+
+	// Start and End are when the run started and ended.
+	Start time.Time
+	End   time.Time
+
+	// Nodes lists each node execution in order, including repeats and
+	// fallback nodes.
+	Nodes []NodeReport
+
+	// Routes lists the routes taken, in order.
+	Routes []RouteReport
+
+	// Suppressed lists the errors recovered from by Fallback steps and
+	// fallback nodes, which the run's error doesn't include.
+	Suppressed []SuppressedError
+}
+
+// Duration returns how long the run took.
+func (r *RunReport) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// NodeReport describes one execution of a node.
+type NodeReport struct {
+	Name     string
+	Duration time.Duration
+
+	// Attempts counts the attempts of the node's steps: one, plus one for
+	// each retry.
+	Attempts int
+
+	// Err is the error the node failed with, if any.
+	Err error
+}
+
+// RouteReport is a route a run took.
+type RouteReport struct {
+	From   string
+	Action string
+
+	// To is the next node, or empty when the route ended the run.
+	To string
+}
+
+// SuppressedError is an error a fallback recovered from.
+type SuppressedError struct {
+	// Node is the node whose Fallback step recovered, or the fallback node
+	// that ran in place of the failed node.
+	Node string
+	Err  error
+}
+
+// RunWithReport runs the graph like Run and also returns a report of the
+// run: each node's duration and attempts, the routes taken, and the errors
+// suppressed by fallbacks. The report is returned even when the run fails.
+func (g *Graph) RunWithReport(ctx context.Context, input any) (any, *RunReport, error) {
+	reporter := &runReporter{graph: g.graph}
+	reporter.report.RunID, _ = RunIDFrom(ctx)
+
+	clock := g.opts.clock
+	if clock == nil {
+		clock = ClockFrom(ctx)
+	}
+	reporter.report.Start = clock.Now()
+	output, err := g.Run(context.WithValue(ctx, reporterKey{}, reporter), input)
+	reporter.report.End = clock.Now()
+
+	return output, reporter.result(), err
+}
+
+type reporterKey struct{}
+
+// runReporter builds the report of a run from its events.
+type runReporter struct {
+	graph *graph
+
+	mu     sync.Mutex
+	report RunReport
+}
+
+func (r *runReporter) handle(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &r.report
+	last := func() *NodeReport {
+		if n := len(report.Nodes); n > 0 && report.Nodes[n-1].Name == event.Node {
+			return &report.Nodes[n-1]
+		}
+		return nil
+	}
+
+	switch event.Type {
+	case EventNodeStart:
+		report.Nodes = append(report.Nodes, NodeReport{Name: event.Node, Attempts: 1})
+	case EventRetry:
+		if node := last(); node != nil {
+			node.Attempts++
+		}
+	case EventNodeEnd:
+		if node := last(); node != nil {
+			node.Duration = event.Duration
+		}
+	case EventError:
+		if node := last(); node != nil {
+			node.Duration = event.Duration
+			node.Err = event.Err
+		}
+	case EventFallback:
+		report.Suppressed = append(report.Suppressed, SuppressedError{Node: event.Node, Err: event.Err})
+	case EventRoute:
+		report.Routes = append(report.Routes, RouteReport{From: event.Node, Action: event.Action, To: event.Next})
+	}
+}
+
+func (r *runReporter) result() *RunReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.report
+	return &report
+}
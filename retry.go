@@ -0,0 +1,168 @@
+package pocket
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// retryPolicy is how a lifecycle step is retried.
+type retryPolicy struct {
+	maxRetries int
+	delay      time.Duration
+}
+
+// WithPrepRetry retries the Prep step with its own policy, such as for
+// transient store backend errors, in place of WithRetry's.
+func WithPrepRetry(maxRetries int, delay time.Duration) Option {
+	return func(o *nodeOptions) {
+		o.prepRetry = &retryPolicy{maxRetries: maxRetries, delay: delay}
+	}
+}
+
+// WithPostRetry retries the Post step, such as for flaky store writes,
+// which isn't retried otherwise. Each attempt's writes are staged and only
+// applied to the store, in order, once an attempt succeeds, and the route
+// is taken from that attempt alone, so a failed attempt leaves no writes
+// behind and the node routes exactly once. Applying the writes isn't
+// atomic: if a write fails after others were applied, those stay and the
+// step fails without further attempts, so no write is applied twice.
+func WithPostRetry(maxRetries int, delay time.Duration) Option {
+	return func(o *nodeOptions) {
+		o.postRetry = &retryPolicy{maxRetries: maxRetries, delay: delay}
+	}
+}
+
+// Lifecycle steps, for retry policies.
+const (
+	stepPrep = "prep"
+	stepExec = "exec"
+	stepPost = "post"
+)
+
+// stepRetries returns the retry policy of a step of simpleNode, which is
+// nil for nodes not created with NewNode.
+func stepRetries(simpleNode *node, step string) retryPolicy {
+	if simpleNode == nil {
+		return retryPolicy{}
+	}
+	opts := &simpleNode.opts
+	switch {
+	case step == stepPrep && opts.prepRetry != nil:
+		return *opts.prepRetry
+	case step == stepPost:
+		if opts.postRetry != nil {
+			return *opts.postRetry
+		}
+		return retryPolicy{}
+	}
+	return retryPolicy{maxRetries: opts.maxRetries, delay: opts.retryDelay}
+}
+
+// stagedWrite is a write held by a stagedStore.
+type stagedWrite struct {
+	store   Store // The scope written to
+	key     string
+	value   any
+	deleted bool
+}
+
+// stagedWrites are the writes of one Post attempt, shared by its scopes.
+type stagedWrites struct {
+	mu      sync.Mutex
+	writes  []stagedWrite
+	pending map[string]stagedWrite // Latest write by full key, for reads
+}
+
+// stagedStore holds writes until they are committed, reading its own
+// writes over the store's.
+type stagedStore struct {
+	store  Store
+	staged *stagedWrites
+	prefix string // Of nested scopes, for reads of pending writes
+}
+
+func newStagedStore(store Store) *stagedStore {
+	return &stagedStore{
+		store:  store,
+		staged: &stagedWrites{pending: make(map[string]stagedWrite)},
+	}
+}
+
+// Get retrieves the value staged for a key, or else the store's.
+func (s *stagedStore) Get(ctx context.Context, key string) (any, bool) {
+	s.staged.mu.Lock()
+	w, ok := s.staged.pending[s.prefix+key]
+	s.staged.mu.Unlock()
+	if ok {
+		return w.value, !w.deleted
+	}
+	return s.store.Get(ctx, key)
+}
+
+// Set stages a value for a key.
+func (s *stagedStore) Set(ctx context.Context, key string, value any) error {
+	s.stage(stagedWrite{store: s.store, key: key, value: value})
+	return nil
+}
+
+// Delete stages the removal of a key.
+func (s *stagedStore) Delete(ctx context.Context, key string) error {
+	s.stage(stagedWrite{store: s.store, key: key, deleted: true})
+	return nil
+}
+
+// Scope returns a staged view of the store with the given prefix, sharing
+// the staged writes.
+func (s *stagedStore) Scope(prefix string) Store {
+	return &stagedStore{
+		store:  s.store.Scope(prefix),
+		staged: s.staged,
+		prefix: s.prefix + prefix + ":",
+	}
+}
+
+func (s *stagedStore) stage(w stagedWrite) {
+	s.staged.mu.Lock()
+	defer s.staged.mu.Unlock()
+	s.staged.writes = append(s.staged.writes, w)
+	s.staged.pending[s.prefix+w.key] = w
+}
+
+// partialCommitError reports staged writes that were only partly applied,
+// so the step that staged them mustn't be retried.
+type partialCommitError struct {
+	applied int
+	err     error
+}
+
+func (e *partialCommitError) Error() string {
+	return fmt.Sprintf("%d writes applied before: %v", e.applied, e.err)
+}
+
+func (e *partialCommitError) Unwrap() error {
+	return e.err
+}
+
+// commit applies the staged writes to the store in order. A failure after
+// some writes were applied is a partialCommitError.
+func (s *stagedStore) commit(ctx context.Context) error {
+	s.staged.mu.Lock()
+	defer s.staged.mu.Unlock()
+	for i, w := range s.staged.writes {
+		var err error
+		if w.deleted {
+			err = w.store.Delete(ctx, w.key)
+		} else {
+			err = w.store.Set(ctx, w.key, w.value)
+		}
+		if err != nil && i > 0 {
+			return &partialCommitError{applied: i, err: err}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
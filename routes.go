@@ -0,0 +1,103 @@
+package pocket
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// routes is a graph's successor table. Each node a run reaches is resolved
+// once into a route, holding the node that runs in its place and the route
+// each of its actions leads to, so later runs follow pointers from node to
+// node instead of resolving overrides per node. Routes are resolved again
+// when their node is connected again, which may happen while graphs run:
+// nodes swap in a new successor map under their lock on Connect, so routes
+// read a consistent snapshot. Only successors of nodes created with NewNode
+// are pre-resolved; other nodes' successors are looked up per hop.
+type routes struct {
+	mu      sync.Mutex // Serializes resolving
+	entries atomic.Pointer[map[Node]*route]
+}
+
+// route is a node of a graph resolved for routing.
+type route struct {
+	node Node // As connected; routing follows its successors
+	exec Node // Runs in its place
+
+	// simple is node when it was created with NewNode, and connections its
+	// connection count when next was resolved
+	simple      *node
+	connections uint64
+	next        map[string]*route
+}
+
+// current reports whether the route still matches its node's connections.
+func (r *route) current() bool {
+	return r.simple == nil || r.simple.connections.Load() == r.connections
+}
+
+// lookup returns the route of n, resolving it if needed. Graphs without a
+// successor table, such as those helpers run nodes with, resolve n into
+// scratch instead.
+func (g *graph) lookup(n Node, scratch *route) *route {
+	if n == nil {
+		return nil
+	}
+	if g.routes == nil {
+		*scratch = route{node: n, exec: g.override(n)}
+		return scratch
+	}
+	if entries := g.routes.entries.Load(); entries != nil {
+		if r := (*entries)[n]; r != nil && r.current() {
+			return r
+		}
+	}
+	return g.resolve(n)
+}
+
+// follow returns the route that r's action leads to, or nil.
+func (g *graph) follow(r *route, action string, scratch *route) *route {
+	if r.next == nil {
+		return g.lookup(r.node.Successors()[action], scratch)
+	}
+	next := r.next[action]
+	if next == nil || next.current() {
+		return next
+	}
+	return g.resolve(next.node)
+}
+
+// resolve adds n, and the nodes reachable from it that aren't resolved yet,
+// to the successor table.
+func (g *graph) resolve(n Node) *route {
+	g.routes.mu.Lock()
+	defer g.routes.mu.Unlock()
+
+	entries := make(map[Node]*route)
+	if current := g.routes.entries.Load(); current != nil {
+		entries = maps.Clone(*current)
+	}
+	r := g.resolveInto(entries, n)
+	g.routes.entries.Store(&entries)
+	return r
+}
+
+func (g *graph) resolveInto(entries map[Node]*route, n Node) *route {
+	if r := entries[n]; r != nil && r.current() {
+		return r
+	}
+
+	r := &route{node: n, exec: g.override(n)}
+	entries[n] = r // Before its successors, which may lead back to it
+	if simple, ok := n.(*node); ok {
+		successors, connections := simple.connected()
+		r.simple, r.connections = simple, connections
+		r.next = make(map[string]*route, len(successors))
+		for action, successor := range successors {
+			if successor != nil {
+				r.next[action] = g.resolveInto(entries, successor)
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,402 @@
+package pocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRunCanceled is returned by a run stopped with Graph.Cancel.
+	ErrRunCanceled = errors.New("pocket: run canceled")
+
+	// ErrRunNotFound is returned when no active run has the given ID.
+	ErrRunNotFound = errors.New("pocket: run not found")
+
+	// ErrRunPaused is returned by a run stopped with Graph.Pause.
+	ErrRunPaused = errors.New("pocket: run paused")
+)
+
+// RunOutcome is how a run with an ID ended.
+type RunOutcome string
+
+// Outcomes recorded under RunOutcomeKey.
+const (
+	RunCompleted RunOutcome = "completed"
+	RunFailed    RunOutcome = "failed"
+	RunCanceled  RunOutcome = "canceled"
+	RunPaused    RunOutcome = "paused"
+)
+
+// RunOutcomeKey returns the store key where the outcome of a run with an ID
+// is recorded each time it ends, for graphs with WithRunOutcomes. With
+// WithHistory, the key's history is the run's: a run paused and then
+// resumed to completion records paused, then completed.
+func RunOutcomeKey(runID string) string {
+	return "pocket:outcome:" + runID
+}
+
+// WithRunOutcomes records how each run with an ID ends under RunOutcomeKey
+// in the graph's store. Outcomes are kept until deleted, one key per run ID,
+// so stores bounded with WithMaxEntries or WithTTL may drop them. A failure
+// to record an outcome is logged and doesn't fail the run.
+func WithRunOutcomes() GraphOption {
+	return func(o *graphOptions) {
+		o.runOutcomes = true
+	}
+}
+
+// outcomeOf returns the outcome of a run that ended with err.
+func outcomeOf(err error) RunOutcome {
+	switch {
+	case err == nil:
+		return RunCompleted
+	case errors.Is(err, ErrRunCanceled):
+		return RunCanceled
+	case errors.Is(err, ErrRunPaused):
+		return RunPaused
+	default:
+		return RunFailed
+	}
+}
+
+// recordOutcome records how a run ended, logging rather than returning a
+// failure so bookkeeping doesn't fail the run.
+func (g *graph) recordOutcome(ctx context.Context, runID string, outcome RunOutcome) {
+	err := g.store.Set(ctx, RunOutcomeKey(runID), outcome)
+	if err != nil && g.opts.logger != nil {
+		g.opts.logger.Error(ctx, "failed to record run outcome", "run", runID, "outcome", outcome, "error", err)
+	}
+}
+
+type runIDKey struct{}
+
+// WithRunID returns a context that identifies a run, so it can be managed
+// with Graph methods such as Cancel while it executes. IDs must be unique
+// among a graph's active runs.
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// RunIDFrom returns the run ID set with WithRunID, if any.
+func RunIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(runIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// activeRun tracks a run started with a run ID.
+type activeRun struct {
+	id     string
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+	finish func()
+
+	mu      sync.Mutex
+	pause   bool
+	paused  bool
+	clock   Clock
+	started time.Time
+	node    string
+	path    []string
+	outputs map[string]any
+}
+
+// begin records that the run started executing at the clock's time.
+func (r *activeRun) begin(clock Clock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clock = clock
+	r.started = clock.Now()
+}
+
+// enter records that the run started a node.
+func (r *activeRun) enter(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.node = node
+}
+
+// exit records that the run finished a node with an output.
+func (r *activeRun) exit(node string, output any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.node = ""
+	r.path = append(r.path, node)
+	if r.outputs == nil {
+		r.outputs = make(map[string]any)
+	}
+	r.outputs[node] = output
+}
+
+func (r *activeRun) requestPause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pause = true
+}
+
+func (r *activeRun) pauseRequested() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pause
+}
+
+// runRegistry tracks a graph's active runs by ID.
+type runRegistry struct {
+	mu   sync.Mutex
+	runs map[string]*activeRun
+}
+
+// start registers a run and returns its cancelable context. The run's
+// finish function must be called when it ends.
+func (r *runRegistry) start(ctx context.Context, runID string) (context.Context, *activeRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.runs[runID]; exists {
+		return nil, nil, fmt.Errorf("pocket: run %q is already active", runID)
+	}
+	if r.runs == nil {
+		r.runs = make(map[string]*activeRun)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	run := &activeRun{id: runID, cancel: cancel, done: make(chan struct{})}
+	run.finish = func() {
+		r.mu.Lock()
+		delete(r.runs, runID)
+		r.mu.Unlock()
+
+		cancel(nil)
+		close(run.done)
+	}
+	r.runs[runID] = run
+	return ctx, run, nil
+}
+
+func (r *runRegistry) get(runID string) (*activeRun, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[runID]
+	return run, ok
+}
+
+// Cancel stops an active run started with a context from WithRunID. The
+// run's context is canceled, no further nodes start, and Cancel waits until
+// the current node's cleanup hooks have run and Run has returned. The run's
+// error wraps ErrRunCanceled, and with WithRunOutcomes, RunCanceled is
+// recorded under RunOutcomeKey. Canceling is cooperative: Exec functions should honor
+// context cancellation so the current node stops promptly.
+// Cancel returns ErrRunNotFound if no such run is active, or the context's
+// error if ctx ends before the run finishes.
+func (g *Graph) Cancel(ctx context.Context, runID string) error {
+	run, ok := g.runs.get(runID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	}
+
+	run.cancel(ErrRunCanceled)
+
+	select {
+	case <-run.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunStatus describes the progress of an active run.
+type RunStatus struct {
+	// RunID identifies the run.
+	RunID string
+
+	// Node is the name of the node executing, or empty between nodes.
+	Node string
+
+	// StartedAt is when the run started, or was last resumed.
+	StartedAt time.Time
+
+	// Elapsed is the time since StartedAt.
+	Elapsed time.Duration
+
+	// Path lists the nodes the run has finished, in order, including
+	// repeats.
+	Path []string
+
+	// Outputs holds the latest output of each finished node by name.
+	Outputs map[string]any
+}
+
+// RunStatus returns the progress of an active run started with a context
+// from WithRunID, so UIs can show where a long workflow is. It returns
+// ErrRunNotFound once the run has ended. Outputs are shared with the run,
+// so they must not be modified.
+func (g *Graph) RunStatus(runID string) (RunStatus, error) {
+	run, ok := g.runs.get(runID)
+	if !ok {
+		return RunStatus{}, fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	status := RunStatus{
+		RunID:     runID,
+		Node:      run.node,
+		StartedAt: run.started,
+		Path:      slices.Clone(run.path),
+		Outputs:   maps.Clone(run.outputs),
+	}
+	if run.clock != nil {
+		status.Elapsed = run.clock.Now().Sub(run.started)
+	}
+	return status, nil
+}
+
+// Checkpoint records where a paused run stopped.
+type Checkpoint struct {
+	// RunID identifies the paused run.
+	RunID string
+
+	// Node is the name of the next node to run.
+	Node string
+
+	// Input is the input for that node.
+	Input any
+
+	// PausedAt is when the run paused.
+	PausedAt time.Time
+}
+
+// CheckpointKey returns the store key holding a paused run's Checkpoint.
+func CheckpointKey(runID string) string {
+	return "pocket:checkpoint:" + runID
+}
+
+// Pause stops an active run started with a context from WithRunID before
+// its next node starts. The node currently executing finishes first. The
+// run's position is saved as a Checkpoint in the graph's store under
+// CheckpointKey, and Run returns an error wrapping ErrRunPaused. Pause
+// waits until the run has stopped; if it completes instead, because no
+// node was left to run, Pause returns an error.
+func (g *Graph) Pause(ctx context.Context, runID string) error {
+	run, ok := g.runs.get(runID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	}
+
+	run.requestPause()
+
+	select {
+	case <-run.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if !run.paused {
+		return fmt.Errorf("pocket: run %q ended before it could pause", runID)
+	}
+	return nil
+}
+
+// pause saves a checkpoint for the run before next and returns the error
+// that ends it.
+func (g *graph) pause(ctx context.Context, run *activeRun, next Node, input any) error {
+	checkpoint := Checkpoint{
+		RunID:    run.id,
+		Node:     next.Name(),
+		Input:    input,
+		PausedAt: ClockFrom(ctx).Now(),
+	}
+	if err := g.store.Set(ctx, CheckpointKey(run.id), checkpoint); err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+
+	run.mu.Lock()
+	run.paused = true
+	run.mu.Unlock()
+
+	return fmt.Errorf("%w before node %s", ErrRunPaused, next.Name())
+}
+
+// interrupt checkpoints a run canceled by Shutdown at next, so resuming it
+// runs next again.
+func (g *graph) interrupt(ctx context.Context, run *activeRun, next Node, input any) error {
+	err := g.pause(context.WithoutCancel(ctx), run, next, input)
+	if !errors.Is(err, ErrRunPaused) {
+		return fmt.Errorf("%w: node %s: %w", ErrShuttingDown, next.Name(), err)
+	}
+	return fmt.Errorf("%w: %w", ErrShuttingDown, err)
+}
+
+// Resume continues a paused run from its checkpoint, with the same run ID,
+// and returns its result like Run. The checkpoint is removed once the run
+// ends without pausing again. Resume works with any graph built from the
+// same nodes and store, so a run paused in one process can be resumed in
+// another when the store is shared.
+func (g *Graph) Resume(ctx context.Context, runID string) (output any, err error) {
+	rc, err := g.inflight.begin(ctx, g.graph)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { g.inflight.end(rc, err) }()
+	ctx = rc
+
+	if g.opts.tenants != nil {
+		var done func()
+		if ctx, done, err = g.admitTenant(ctx); err != nil {
+			return nil, err
+		}
+		defer done()
+	}
+
+	value, ok := g.store.Get(ctx, CheckpointKey(runID))
+	if !ok {
+		return nil, fmt.Errorf("pocket: no checkpoint for run %q", runID)
+	}
+	checkpoint, ok := value.(Checkpoint)
+	if !ok {
+		return nil, fmt.Errorf("pocket: invalid checkpoint for run %q: %T", runID, value)
+	}
+
+	next := findNode(g.start, checkpoint.Node)
+	if next == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, checkpoint.Node)
+	}
+
+	output, err = g.runTracked(WithRunID(ctx, runID), runID, next, checkpoint.Input)
+	if errors.Is(err, ErrRunPaused) {
+		return nil, err
+	}
+	if deleteErr := g.store.Delete(ctx, CheckpointKey(runID)); deleteErr != nil && err == nil {
+		err = fmt.Errorf("remove checkpoint: %w", deleteErr)
+	}
+	return output, err
+}
+
+// findNode returns the node with the given name reachable from start.
+func findNode(start Node, name string) Node {
+	visited := make(map[Node]bool)
+	var walk func(n Node) Node
+	walk = func(n Node) Node {
+		if n == nil || visited[n] {
+			return nil
+		}
+		visited[n] = true
+		if n.Name() == name {
+			return n
+		}
+		successors := n.Successors()
+		for _, action := range slices.Sorted(maps.Keys(successors)) {
+			if found := walk(successors[action]); found != nil {
+				return found
+			}
+		}
+		return walk(fallbackNodeOf(n))
+	}
+	return walk(start)
+}
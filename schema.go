@@ -0,0 +1,297 @@
+package pocket
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WithInputSchema declares the JSON schema of the input a node accepts. A
+// typed node created with NewNode has one inferred from its input type;
+// this replaces it.
+// ValidateGraph checks it against the output schemas of the nodes that
+// route to this one, and tools describing the node, as they do for the
+// built-in node types, read it with Schemas. It isn't enforced at runtime.
+//
+// Example:
+//
+//	node := pocket.NewNode[any, any]("summarize", steps,
+//		pocket.WithInputSchema(map[string]any{
+//			"type":     "object",
+//			"required": []string{"text"},
+//			"properties": map[string]any{
+//				"text": map[string]any{"type": "string"},
+//			},
+//		}),
+//	)
+func WithInputSchema(schema map[string]any) Option {
+	return func(o *nodeOptions) {
+		o.inputSchema = schema
+	}
+}
+
+// WithOutputSchema declares the JSON schema of the output a node produces,
+// replacing one inferred from its output type. See WithInputSchema.
+func WithOutputSchema(schema map[string]any) Option {
+	return func(o *nodeOptions) {
+		o.outputSchema = schema
+	}
+}
+
+// Schemas returns the input and output schemas declared for a node, or nil
+// for those it doesn't declare. A graph used as a node takes the input
+// schema of its start node, and a node wrapped with MapInput has no input
+// schema.
+func Schemas(n Node) (input, output map[string]any) {
+	switch n := n.(type) {
+	case *node:
+		return n.opts.inputSchema, n.opts.outputSchema
+	case *graph:
+		input, _ = Schemas(n.start)
+		return input, nil
+	case *Graph:
+		return Schemas(n.graph)
+	case *mappedNode:
+		// The mapping decides what input it accepts
+		_, output = Schemas(n.Node)
+		return nil, output
+	}
+	return nil, nil
+}
+
+// checkSchemas reports where an output schema can't satisfy an input
+// schema. Only what both schemas declare is compared, so schemas that leave
+// a type or properties open are compatible.
+func checkSchemas(output, input map[string]any, path string) error {
+	if len(output) == 0 || len(input) == 0 {
+		return nil
+	}
+
+	outTypes, inTypes := schemaTypes(output), schemaTypes(input)
+	if len(outTypes) > 0 && len(inTypes) > 0 && !typesOverlap(outTypes, inTypes) {
+		return fmt.Errorf("%s is %s, want %s", schemaPath(path), strings.Join(outTypes, " or "), strings.Join(inTypes, " or "))
+	}
+
+	outProps, _ := output["properties"].(map[string]any)
+	inProps, _ := input["properties"].(map[string]any)
+	if outProps != nil {
+		for _, name := range schemaRequired(input) {
+			if _, ok := outProps[name]; !ok {
+				return fmt.Errorf("%s is missing required property %q", schemaPath(path), name)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(inProps))
+	for name := range inProps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		outProp, _ := outProps[name].(map[string]any)
+		inProp, _ := inProps[name].(map[string]any)
+		if err := checkSchemas(outProp, inProp, path+"."+name); err != nil {
+			return err
+		}
+	}
+
+	outItems, _ := output["items"].(map[string]any)
+	inItems, _ := input["items"].(map[string]any)
+	return checkSchemas(outItems, inItems, path+"[]")
+}
+
+// schemaTypes returns the types a schema allows, from a string or a list.
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// typesOverlap reports whether a value of one of the output types can be
+// one of the input types. Integers are numbers.
+func typesOverlap(output, input []string) bool {
+	for _, out := range output {
+		if slices.Contains(input, out) || (out == "integer" && slices.Contains(input, "number")) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaRequired returns the required properties of a schema.
+func schemaRequired(schema map[string]any) []string {
+	switch r := schema["required"].(type) {
+	case []string:
+		return r
+	case []any:
+		required := make([]string, 0, len(r))
+		for _, v := range r {
+			if s, ok := v.(string); ok {
+				required = append(required, s)
+			}
+		}
+		return required
+	}
+	return nil
+}
+
+func schemaPath(path string) string {
+	if path == "" {
+		return "output"
+	}
+	return "output" + path
+}
+
+// SchemaOf returns the JSON schema of values of type T, as NewNode infers
+// for typed nodes. Struct fields are named by their json tag, or yaml tag
+// without one, and are required unless tagged omitempty or a pointer.
+// Types with custom JSON marshaling get an empty schema, which allows any
+// value, except text marshalers, which are strings.
+//
+// Example:
+//
+//	type Order struct {
+//		ID    string   `json:"id"`
+//		Items []string `json:"items,omitempty"`
+//	}
+//
+//	pocket.SchemaOf[Order]()
+//	// {"type": "object", "required": ["id"], "properties": {
+//	//     "id": {"type": "string"},
+//	//     "items": {"type": "array", "items": {"type": "string"}}}}
+func SchemaOf[T any]() map[string]any {
+	return schemaFor(reflect.TypeOf((*T)(nil)).Elem(), make(map[reflect.Type]bool))
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaFor builds the schema of t. Types already being built are
+// recursive, and are left as objects to stop the recursion.
+func schemaFor(t reflect.Type, building map[reflect.Type]bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return map[string]any{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		// Byte slices encode as base64 strings
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), building)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), building)}
+	case reflect.Struct:
+		if building[t] {
+			return map[string]any{"type": "object"}
+		}
+		building[t] = true
+		defer delete(building, t)
+
+		properties := make(map[string]any)
+		required := []string{}
+		addFields(t, properties, &required, building)
+
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	// Interfaces, and types that don't encode, allow any value
+	return map[string]any{}
+}
+
+// addFields adds the schemas of a struct's fields, including those of
+// embedded structs, which encoding/json promotes.
+func addFields(t reflect.Type, properties map[string]any, required *[]string, building map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty, skip := fieldName(field)
+		if skip {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addFields(embedded, properties, required, building)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = schemaFor(field.Type, building)
+		if !omitempty && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// fieldName returns the name a field's json tag, or yaml tag without one,
+// gives it, and whether it's omitted when empty or skipped.
+func fieldName(field reflect.StructField) (name string, omitempty, skip bool) {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		tag = field.Tag.Get("yaml")
+	}
+	if tag == "-" {
+		return "", false, true
+	}
+
+	name, options, _ := strings.Cut(tag, ",")
+	for _, option := range strings.Split(options, ",") {
+		if option == "omitempty" || option == "omitzero" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
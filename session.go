@@ -0,0 +1,258 @@
+package pocket
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// SessionOption configures a Sessions.
+type SessionOption func(*sessionConfig)
+
+type sessionConfig struct {
+	idleTTL     time.Duration
+	maxSessions int
+	onEvict     func(id string)
+	clock       Clock
+}
+
+// WithSessionIdleTTL ends sessions that haven't been used for the duration.
+// Idle sessions are ended as other sessions are opened, or by Sweep.
+func WithSessionIdleTTL(ttl time.Duration) SessionOption {
+	return func(c *sessionConfig) {
+		c.idleTTL = ttl
+	}
+}
+
+// WithMaxSessions limits the number of open sessions. Opening another ends
+// the least recently used session.
+func WithMaxSessions(maxSessions int) SessionOption {
+	return func(c *sessionConfig) {
+		c.maxSessions = maxSessions
+	}
+}
+
+// WithSessionEvictionCallback sets a callback for when a session ends,
+// whether it was idle, evicted to make room, or ended with End. The
+// callback runs with the sessions locked, so it must not use them.
+func WithSessionEvictionCallback(fn func(id string)) SessionOption {
+	return func(c *sessionConfig) {
+		c.onEvict = fn
+	}
+}
+
+// WithSessionClock sets the clock used to find idle sessions. The default
+// is the system clock.
+func WithSessionClock(clock Clock) SessionOption {
+	return func(c *sessionConfig) {
+		c.clock = clock
+	}
+}
+
+// Sessions gives each conversation, user, or other session its own scope
+// of a store, and removes the state of sessions when they end.
+//
+// Example:
+//
+//	sessions := pocket.NewSessions(pocket.NewStore(),
+//		pocket.WithSessionIdleTTL(30*time.Minute),
+//		pocket.WithMaxSessions(10000),
+//	)
+//
+//	// For each message in a chat
+//	graph := pocket.NewGraph(start, sessions.Session(userID))
+//	reply, err := graph.Run(ctx, message)
+type Sessions struct {
+	store  Store
+	config sessionConfig
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	lru      *list.List // Session IDs, most recently used first
+}
+
+// session tracks the keys a session has written, so they can be removed
+// when it ends.
+type session struct {
+	id       string
+	accessed time.Time
+	element  *list.Element
+	keys     map[string]struct{}
+}
+
+// NewSessions creates sessions over a store. Each session's keys are
+// scoped under session:<id>.
+func NewSessions(store Store, opts ...SessionOption) *Sessions {
+	s := &Sessions{
+		store:    store,
+		sessions: make(map[string]*session),
+		lru:      list.New(),
+	}
+	for _, opt := range opts {
+		opt(&s.config)
+	}
+	return s
+}
+
+func (s *Sessions) now() time.Time {
+	if s.config.clock != nil {
+		return s.config.clock.Now()
+	}
+	return time.Now()
+}
+
+// Session returns the store of a session, starting the session if it isn't
+// open. Using the store keeps the session from going idle. Don't use the
+// store of a session after it ends.
+func (s *Sessions) Session(id string) Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(context.Background(), now)
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		sess = &session{id: id, keys: make(map[string]struct{})}
+		sess.element = s.lru.PushFront(id)
+		s.sessions[id] = sess
+
+		if s.config.maxSessions > 0 && s.lru.Len() > s.config.maxSessions {
+			s.end(context.Background(), s.lru.Back().Value.(string))
+		}
+	}
+	s.touch(sess, now)
+
+	return &sessionStore{Store: s.scope(id), sessions: s, session: sess}
+}
+
+// End ends a session, deleting the keys it wrote.
+func (s *Sessions) End(ctx context.Context, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.end(ctx, id)
+}
+
+// Sweep ends the sessions idle for longer than the idle TTL and returns
+// how many it ended.
+func (s *Sessions) Sweep(ctx context.Context) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sweep(ctx, s.now())
+}
+
+// Len returns the number of open sessions.
+func (s *Sessions) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+func (s *Sessions) scope(id string) Store {
+	return s.store.Scope("session:" + id)
+}
+
+// sweep ends idle sessions. Must be called with the lock held.
+func (s *Sessions) sweep(ctx context.Context, now time.Time) int {
+	if s.config.idleTTL <= 0 {
+		return 0
+	}
+	ended := 0
+	for e := s.lru.Back(); e != nil; {
+		sess := s.sessions[e.Value.(string)]
+		if now.Sub(sess.accessed) <= s.config.idleTTL {
+			break // The rest were used more recently
+		}
+		e = e.Prev()
+		s.end(ctx, sess.id)
+		ended++
+	}
+	return ended
+}
+
+// end removes a session and its keys. Must be called with the lock held.
+func (s *Sessions) end(ctx context.Context, id string) {
+	sess, ok := s.sessions[id]
+	if !ok {
+		return
+	}
+	delete(s.sessions, id)
+	s.lru.Remove(sess.element)
+
+	scoped := s.scope(id)
+	for key := range sess.keys {
+		_ = scoped.Delete(ctx, key)
+	}
+
+	if s.config.onEvict != nil {
+		s.config.onEvict(id)
+	}
+}
+
+// touch marks a session used. Must be called with the lock held.
+func (s *Sessions) touch(sess *session, now time.Time) {
+	sess.accessed = now
+	s.lru.MoveToFront(sess.element)
+}
+
+// sessionStore is the store of a session, recording the keys it writes
+// relative to the session's scope.
+type sessionStore struct {
+	Store
+	sessions *Sessions
+	session  *session
+	prefix   string // Of nested scopes
+}
+
+func (st *sessionStore) use(key string, written, deleted bool) {
+	s := st.sessions
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Ended sessions aren't revived by stale stores
+	if s.sessions[st.session.id] != st.session {
+		return
+	}
+	s.touch(st.session, s.now())
+	switch {
+	case written:
+		st.session.keys[st.prefix+key] = struct{}{}
+	case deleted:
+		delete(st.session.keys, st.prefix+key)
+	}
+}
+
+// Get retrieves a value by key.
+func (st *sessionStore) Get(ctx context.Context, key string) (any, bool) {
+	st.use(key, false, false)
+	return st.Store.Get(ctx, key)
+}
+
+// Set stores a value with the given key.
+func (st *sessionStore) Set(ctx context.Context, key string, value any) error {
+	if err := st.Store.Set(ctx, key, value); err != nil {
+		return err
+	}
+	st.use(key, true, false)
+	return nil
+}
+
+// Delete removes a key from the store.
+func (st *sessionStore) Delete(ctx context.Context, key string) error {
+	if err := st.Store.Delete(ctx, key); err != nil {
+		return err
+	}
+	st.use(key, false, true)
+	return nil
+}
+
+// Scope returns a new store with the given prefix, within the session.
+func (st *sessionStore) Scope(prefix string) Store {
+	return &sessionStore{
+		Store:    st.Store.Scope(prefix),
+		sessions: st.sessions,
+		session:  st.session,
+		prefix:   st.prefix + prefix + ":",
+	}
+}
@@ -0,0 +1,152 @@
+package pocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrShuttingDown is returned by runs started after Shutdown, and wrapped
+// by the errors of runs it interrupts.
+var ErrShuttingDown = errors.New("pocket: graph is shutting down")
+
+// ShutdownReport describes how a graph's runs ended during Shutdown.
+type ShutdownReport struct {
+	// Drained counts the runs that finished, successfully or not, before
+	// the deadline.
+	Drained int
+
+	// Checkpointed lists the IDs of runs stopped at the deadline with a
+	// checkpoint, to continue with Resume.
+	Checkpointed []string
+
+	// Interrupted counts the runs stopped at the deadline without a
+	// checkpoint, because they had no run ID or saving it failed.
+	Interrupted int
+}
+
+// inflightRuns tracks every run of a graph so Shutdown can drain them.
+// Runs whose context can't be canceled, such as those started with
+// context.Background, end with a context shared by the graph, so tracking
+// them allocates nothing; other runs get a context of their own to cancel.
+type inflightRuns struct {
+	mu          sync.Mutex
+	closed      bool // No more runs start
+	interrupted bool // The deadline passed and remaining runs were canceled
+	count       int  // Runs in progress
+	shutdown    context.Context
+	cancel      context.CancelCauseFunc
+	runs        map[*runContext]struct{} // Runs with a context of their own
+	idle        chan struct{}            // Closed when the last run ends after closing
+	report      ShutdownReport
+}
+
+// begin registers a run of g, returning its context.
+func (r *inflightRuns) begin(ctx context.Context, g *graph) (*runContext, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, ErrShuttingDown
+	}
+	r.count++
+
+	if ctx.Done() == nil {
+		if r.shutdown == nil {
+			r.shutdown, r.cancel = context.WithCancelCause(context.Background())
+		}
+		rc := newRunContext(ctx, g)
+		rc.shutdown = r.shutdown
+		return rc, nil
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	rc := newRunContext(ctx, g)
+	rc.cancel = cancel
+	if r.runs == nil {
+		r.runs = make(map[*runContext]struct{})
+	}
+	r.runs[rc] = struct{}{}
+	return rc, nil
+}
+
+// end unregisters a run that ended with err.
+func (r *inflightRuns) end(rc *runContext, err error) {
+	if rc.cancel != nil {
+		rc.cancel(nil)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count--
+	if rc.cancel != nil {
+		delete(r.runs, rc)
+	}
+	if !r.closed {
+		return
+	}
+
+	id, _ := RunIDFrom(rc)
+	switch {
+	case !r.interrupted || err == nil:
+		r.report.Drained++
+	case errors.Is(err, ErrRunPaused) && id != "":
+		r.report.Checkpointed = append(r.report.Checkpointed, id)
+	default:
+		r.report.Interrupted++
+	}
+	if r.count == 0 {
+		close(r.idle)
+	}
+}
+
+// Shutdown stops the graph from starting runs and waits for those in
+// progress to finish, such as before a deploy replaces the process. Runs
+// started afterwards, including with Resume, fail with ErrShuttingDown.
+//
+// If ctx ends first, the remaining runs are canceled. Runs with an ID from
+// WithRunID save a Checkpoint of the node they were on, which runs again
+// when the run is resumed, and return an error wrapping ErrRunPaused and
+// ErrShuttingDown; other runs are interrupted. Shutdown then waits for the
+// canceled runs to return, which is prompt when nodes honor cancellation,
+// and reports how the runs in progress ended. It returns an error wrapping
+// ErrShuttingDown if any run didn't drain.
+func (g *Graph) Shutdown(ctx context.Context) (ShutdownReport, error) {
+	r := &g.inflight
+	r.mu.Lock()
+	r.closed = true
+	if r.idle == nil {
+		r.idle = make(chan struct{})
+		if r.count == 0 {
+			close(r.idle)
+		}
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.idle:
+	case <-ctx.Done():
+		r.mu.Lock()
+		r.interrupted = true
+		if r.cancel != nil {
+			r.cancel(ErrShuttingDown)
+		}
+		for rc := range r.runs {
+			rc.cancel(ErrShuttingDown)
+		}
+		r.mu.Unlock()
+		<-r.idle
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.report
+	report.Checkpointed = slices.Clone(r.report.Checkpointed)
+	if report.Interrupted > 0 || len(report.Checkpointed) > 0 {
+		return report, fmt.Errorf("%w: %d runs checkpointed, %d interrupted",
+			ErrShuttingDown, len(report.Checkpointed), report.Interrupted)
+	}
+	return report, nil
+}
@@ -0,0 +1,112 @@
+package pocket
+
+import (
+	"context"
+	"strings"
+)
+
+// snapshotter is implemented by stores that can capture a point-in-time
+// view of their entries.
+type snapshotter interface {
+	// snapshot returns the live entries visible to the store, keyed
+	// relative to its scope.
+	snapshot() map[string]any
+}
+
+// snapshotStore is a read-only, point-in-time view of a store. Values are
+// deep-copied on read so callers cannot mutate shared state through them.
+type snapshotStore struct {
+	data   map[string]any
+	prefix string
+}
+
+// newSnapshot returns a consistent read-only view of store. Stores that
+// cannot capture a snapshot are wrapped so reads are still copied.
+func newSnapshot(store Store) StoreReader {
+	if s, ok := store.(snapshotter); ok {
+		return &snapshotStore{data: s.snapshot()}
+	}
+	return copyOnReadStore{store}
+}
+
+// Get retrieves a copy of the value the key had when the snapshot was taken.
+func (s *snapshotStore) Get(ctx context.Context, key string) (any, bool) {
+	v, ok := s.data[s.prefix+key]
+	if !ok {
+		return nil, false
+	}
+	return deepCopy(v), true
+}
+
+// Set fails because snapshots are read-only.
+func (s *snapshotStore) Set(ctx context.Context, key string, value any) error {
+	return ErrReadOnlyStore
+}
+
+// Delete fails because snapshots are read-only.
+func (s *snapshotStore) Delete(ctx context.Context, key string) error {
+	return ErrReadOnlyStore
+}
+
+// Scope returns a view of the snapshot with the given prefix.
+func (s *snapshotStore) Scope(prefix string) Store {
+	return &snapshotStore{data: s.data, prefix: s.prefix + prefix + ":"}
+}
+
+// copyOnReadStore deep-copies values read from a store that does not
+// support snapshots.
+type copyOnReadStore struct {
+	Store
+}
+
+// Get retrieves a copy of the value for a key.
+func (s copyOnReadStore) Get(ctx context.Context, key string) (any, bool) {
+	v, ok := s.Store.Get(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	return deepCopy(v), true
+}
+
+// Scope returns a scoped store that also copies on read.
+func (s copyOnReadStore) Scope(prefix string) Store {
+	return copyOnReadStore{s.Store.Scope(prefix)}
+}
+
+func (s *store) snapshot() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.collect(make(map[string]any), s.prefix)
+}
+
+// collect adds the unexpired entries under prefix to data, keyed without
+// the prefix. Must be called with lock held.
+func (s *store) collect(data map[string]any, prefix string) map[string]any {
+	for key, e := range s.data {
+		if s.config.expired(e) {
+			continue
+		}
+		if rel, ok := strings.CutPrefix(key, prefix); ok {
+			data[rel] = e.value
+		}
+	}
+	return data
+}
+
+func (s *shardedStore) snapshot() map[string]any {
+	// Hold every shard so the view is consistent across them
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+	}
+	defer func() {
+		for _, shard := range s.shards {
+			shard.mu.Unlock()
+		}
+	}()
+
+	data := make(map[string]any)
+	for _, shard := range s.shards {
+		shard.collect(data, s.prefix)
+	}
+	return data
+}
@@ -4,6 +4,9 @@ import (
 	"container/list"
 	"context"
 	"fmt"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,9 +16,13 @@ type StoreOption func(*storeConfig)
 
 // storeConfig holds store configuration.
 type storeConfig struct {
-	maxEntries int
-	ttl        time.Duration
-	onEvict    func(key string, value any)
+	maxEntries   int
+	ttl          time.Duration
+	onEvict      func(key string, value any)
+	copyOnWrite  bool
+	historyLimit int
+	clock        Clock
+	onOperation  func(ctx context.Context, op StoreOperation)
 }
 
 // WithMaxEntries sets the maximum number of entries in the store.
@@ -34,6 +41,15 @@ func WithTTL(ttl time.Duration) StoreOption {
 	}
 }
 
+// WithStoreClock sets the clock used to age entries for WithTTL and to
+// time history, so tests can expire entries with a SimulatedClock instead
+// of waiting. The default is the system clock.
+func WithStoreClock(clock Clock) StoreOption {
+	return func(c *storeConfig) {
+		c.clock = clock
+	}
+}
+
 // WithEvictionCallback sets a callback for when entries are evicted.
 func WithEvictionCallback(fn func(key string, value any)) StoreOption {
 	return func(c *storeConfig) {
@@ -41,13 +57,37 @@ func WithEvictionCallback(fn func(key string, value any)) StoreOption {
 	}
 }
 
+// WithStoreCopyOnWrite makes Set store a deep copy of the value, so
+// mutating a map, slice, or pointer after storing it doesn't change what
+// other nodes read. Unexported struct fields are copied shallowly.
+func WithStoreCopyOnWrite() StoreOption {
+	return func(c *storeConfig) {
+		c.copyOnWrite = true
+	}
+}
+
+// now returns the time on the store's clock.
+func (c *storeConfig) now() time.Time {
+	if c.clock != nil {
+		return c.clock.Now()
+	}
+	return time.Now()
+}
+
+// expired reports whether an entry has outlived the TTL.
+func (c *storeConfig) expired(e *entry) bool {
+	return c.ttl > 0 && c.now().Sub(e.created) > c.ttl
+}
+
 // store is the internal implementation with a mutex.
 type store struct {
-	mu       sync.RWMutex
+	mu       *sync.RWMutex // shared with scopes
 	data     map[string]*entry
 	prefix   string
 	config   storeConfig
 	eviction *list.List // LRU list
+	history  map[string][]KeyChange
+	counters *storeCounters // shared with scopes
 }
 
 // entry holds a value with metadata.
@@ -62,9 +102,12 @@ type entry struct {
 // NewStore creates a new thread-safe store with optional configuration.
 func NewStore(opts ...StoreOption) Store {
 	s := &store{
+		mu:       &sync.RWMutex{},
 		data:     make(map[string]*entry),
 		eviction: list.New(),
 		config:   storeConfig{},
+		history:  make(map[string][]KeyChange),
+		counters: &storeCounters{},
 	}
 
 	// Apply options
@@ -76,39 +119,113 @@ func NewStore(opts ...StoreOption) Store {
 }
 
 // Get retrieves a value by key.
-func (s *store) Get(ctx context.Context, key string) (any, bool) {
+func (s *store) Get(ctx context.Context, key string) (value any, exists bool) {
+	fullKey := s.prefix + key
+	expired := false
+	defer func() { // After unlocking
+		if expired {
+			s.observe(ctx, StoreOperation{Op: StoreOpExpire, Key: fullKey})
+		}
+		s.observe(ctx, StoreOperation{Op: StoreOpGet, Key: fullKey, Hit: exists})
+	}()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	fullKey := s.prefix + key
+	var e *entry
+	e, expired = s.lookup(fullKey)
+	if e == nil {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// lookup returns the entry for a full key, removing it if it has expired,
+// and marks it as recently used. Must be called with lock held.
+func (s *store) lookup(fullKey string) (e *entry, expired bool) {
 	e, exists := s.data[fullKey]
 	if !exists {
 		return nil, false
 	}
 
 	// Check TTL if configured
-	if s.config.ttl > 0 && time.Since(e.created) > s.config.ttl {
+	if s.config.expired(e) {
 		// Entry expired, remove it
 		s.removeEntry(fullKey)
-		return nil, false
+		delete(s.history, fullKey)
+		return nil, true
 	}
 
 	// Update access time and move to front (most recently used)
-	e.accessed = time.Now()
+	e.accessed = s.config.now()
 	if s.config.maxEntries > 0 && e.element != nil {
 		s.eviction.MoveToFront(e.element)
 	}
 
-	return e.value, true
+	return e, false
 }
 
 // Set stores a value with the given key.
 func (s *store) Set(ctx context.Context, key string, value any) error {
+	// Copy before locking; large values shouldn't block other callers
+	if s.config.copyOnWrite {
+		value = deepCopy(value)
+	}
+
+	fullKey := s.prefix + key
+	var evicted string
+	defer func() { s.observeSet(ctx, fullKey, evicted) }() // After unlocking
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	evicted = s.put(ctx, fullKey, value)
+	return nil
+}
+
+// update calls fn with the value of a key and, if fn asks to write, stores
+// the value it returns, all under the lock so no write comes between. fn
+// must not use the store.
+func (s *store) update(ctx context.Context, key string, fn func(current any, exists bool) (value any, write bool, err error)) error {
 	fullKey := s.prefix + key
-	now := time.Now()
+	var expired, exists, wrote bool
+	var evicted string
+	defer func() { // After unlocking
+		if expired {
+			s.observe(ctx, StoreOperation{Op: StoreOpExpire, Key: fullKey})
+		}
+		s.observe(ctx, StoreOperation{Op: StoreOpGet, Key: fullKey, Hit: exists})
+		if wrote {
+			s.observeSet(ctx, fullKey, evicted)
+		}
+	}()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var e *entry
+	var current any
+	e, expired = s.lookup(fullKey)
+	if e != nil {
+		current, exists = e.value, true
+	}
+
+	value, write, err := fn(current, exists)
+	if err != nil || !write {
+		return err
+	}
+	if s.config.copyOnWrite {
+		value = deepCopy(value)
+	}
+	evicted, wrote = s.put(ctx, fullKey, value), true
+	return nil
+}
+
+// put stores a value for a full key, returning the key it evicted, if any.
+// Must be called with lock held.
+func (s *store) put(ctx context.Context, fullKey string, value any) (evicted string) {
+	now := s.config.now()
+	s.record(ctx, fullKey, KeyChange{Value: value})
 
 	// Check if key already exists
 	if e, exists := s.data[fullKey]; exists {
@@ -118,7 +235,7 @@ func (s *store) Set(ctx context.Context, key string, value any) error {
 		if s.config.maxEntries > 0 && e.element != nil {
 			s.eviction.MoveToFront(e.element)
 		}
-		return nil
+		return ""
 	}
 
 	// Create new entry
@@ -138,22 +255,28 @@ func (s *store) Set(ctx context.Context, key string, value any) error {
 			// Remove least recently used
 			oldest := s.eviction.Back()
 			if oldest != nil {
-				oldKey := oldest.Value.(string)
-				s.removeEntry(oldKey)
+				evicted = oldest.Value.(string)
+				s.removeEntry(evicted)
+				delete(s.history, evicted)
 			}
 		}
 	}
 
 	s.data[fullKey] = e
-	return nil
+	return evicted
 }
 
 // Delete removes a key from the store.
 func (s *store) Delete(ctx context.Context, key string) error {
+	fullKey := s.prefix + key
+	defer s.observe(ctx, StoreOperation{Op: StoreOpDelete, Key: fullKey}) // After unlocking
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	fullKey := s.prefix + key
+	if _, exists := s.data[fullKey]; exists {
+		s.record(ctx, fullKey, KeyChange{Deleted: true})
+	}
 	s.removeEntry(fullKey)
 	return nil
 }
@@ -182,46 +305,104 @@ func (s *store) removeEntry(key string) {
 // Scope returns a new store with the given prefix.
 func (s *store) Scope(prefix string) Store {
 	return &store{
+		mu:       s.mu,   // shared lock guarding the shared data
 		data:     s.data, // shared data
 		prefix:   s.prefix + prefix + ":",
 		config:   s.config,
 		eviction: s.eviction, // shared eviction list
-		// Note: mutex is not shared, each scope has its own
-		// This is safe because we're using a shared map with proper locking
+		history:  s.history,  // shared history
+		counters: s.counters, // shared metrics
 	}
 }
 
-// TypedStore provides type-safe storage operations.
+// TypedStore provides type-safe storage operations. GetOrSet, Update, List,
+// and Put extend it.
 type TypedStore[T any] interface {
 	Get(ctx context.Context, key string) (T, bool, error)
 	Set(ctx context.Context, key string, value T) error
 	Delete(ctx context.Context, key string) error
 }
 
+// updater is implemented by stores that can read and write a key
+// atomically. Updates of other stores read and then write.
+type updater interface {
+	update(ctx context.Context, key string, fn func(current any, exists bool) (value any, write bool, err error)) error
+}
+
+// TypedStoreOption configures a TypedStore.
+type TypedStoreOption func(*typedStoreConfig)
+
+type typedStoreConfig struct {
+	prefix string
+}
+
+// WithKeyPrefix namespaces a typed store's keys, so typed stores of
+// different types sharing one store don't collide. Keys are stored as
+// "prefix:key", like those of a store's Scope.
+func WithKeyPrefix(prefix string) TypedStoreOption {
+	return func(c *typedStoreConfig) {
+		c.prefix = prefix
+	}
+}
+
 // NewTypedStore creates a type-safe wrapper around a Store.
-func NewTypedStore[T any](store Store) TypedStore[T] {
-	return &typedStore[T]{store: store}
+func NewTypedStore[T any](store Store, opts ...TypedStoreOption) TypedStore[T] {
+	var config typedStoreConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.prefix != "" {
+		store = store.Scope(config.prefix)
+	}
+	return &typedStore[T]{store: store, keyField: keyFieldOf(reflect.TypeFor[T]())}
 }
 
 type typedStore[T any] struct {
-	store Store
+	store    Store
+	keyField []int // Index of the field tagged `pocket:"key"`, if any
+}
+
+// keyFieldOf returns the index of the field of a struct, or pointer to
+// struct, tagged `pocket:"key"`, or nil if there is none.
+func keyFieldOf(t reflect.Type) []int {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for _, field := range reflect.VisibleFields(t) {
+		if field.Tag.Get("pocket") == "key" {
+			return field.Index
+		}
+	}
+	return nil
 }
 
 func (t *typedStore[T]) Get(ctx context.Context, key string) (value T, exists bool, err error) {
-	var zero T
 	val, ok := t.store.Get(ctx, key)
 	if !ok {
-		return zero, false, nil
+		return value, false, nil
 	}
 
-	typed, ok := val.(T)
-	if !ok {
-		return zero, false, fmt.Errorf("type mismatch: expected %T, got %T", zero, val)
+	typed, err := t.assert(val)
+	if err != nil {
+		return value, false, err
 	}
 
 	return typed, true, nil
 }
 
+// assert converts a stored value to T.
+func (t *typedStore[T]) assert(val any) (T, error) {
+	typed, ok := val.(T)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("type mismatch: expected %T, got %T", zero, val)
+	}
+	return typed, nil
+}
+
 func (t *typedStore[T]) Set(ctx context.Context, key string, value T) error {
 	return t.store.Set(ctx, key, value)
 }
@@ -229,3 +410,163 @@ func (t *typedStore[T]) Set(ctx context.Context, key string, value T) error {
 func (t *typedStore[T]) Delete(ctx context.Context, key string) error {
 	return t.store.Delete(ctx, key)
 }
+
+// GetOrSet returns the value of a key, creating and storing it with factory
+// if the key is missing. Concurrent callers may each run factory, but only
+// the first value stored is kept and returned to all, for typed stores
+// created with NewTypedStore. It is a function rather than a TypedStore
+// method so that existing TypedStore implementations still satisfy the
+// interface.
+func GetOrSet[T any](ctx context.Context, ts TypedStore[T], key string, factory func() (T, error)) (T, error) {
+	if value, exists, err := ts.Get(ctx, key); err != nil || exists {
+		return value, err
+	}
+
+	// Create the value without holding the store, then keep whichever
+	// value was stored first
+	created, err := factory()
+	if err != nil {
+		return created, err
+	}
+	t, ok := ts.(*typedStore[T])
+	if !ok {
+		return created, ts.Set(ctx, key, created)
+	}
+	result := created
+	err = t.update(ctx, key, func(current any, exists bool) (any, bool, error) {
+		if !exists {
+			return created, true, nil
+		}
+		typed, err := t.assert(current)
+		result = typed
+		return nil, false, err
+	})
+	return result, err
+}
+
+// Update replaces the value of a key with the one fn returns and returns the
+// new value. fn receives the zero value when the key is missing, and must
+// not use the store. If fn fails, the key is left unchanged. Updates of
+// typed stores created with NewTypedStore have no writes from other callers
+// in between; updates of other typed stores read and then write. Like
+// GetOrSet, it takes the typed store as an argument instead of extending
+// the TypedStore interface.
+func Update[T any](ctx context.Context, ts TypedStore[T], key string, fn func(current T, exists bool) (T, error)) (T, error) {
+	t, ok := ts.(*typedStore[T])
+	if !ok {
+		current, exists, err := ts.Get(ctx, key)
+		if err != nil {
+			return current, err
+		}
+		updated, err := fn(current, exists)
+		if err != nil {
+			return updated, err
+		}
+		return updated, ts.Set(ctx, key, updated)
+	}
+
+	var updated T
+	err := t.update(ctx, key, func(current any, exists bool) (any, bool, error) {
+		var typed T
+		if exists {
+			var err error
+			if typed, err = t.assert(current); err != nil {
+				return nil, false, err
+			}
+		}
+		value, err := fn(typed, exists)
+		if err != nil {
+			return nil, false, err
+		}
+		updated = value
+		return value, true, nil
+	})
+	return updated, err
+}
+
+// update updates a key atomically when the store supports it.
+func (t *typedStore[T]) update(ctx context.Context, key string, fn func(current any, exists bool) (any, bool, error)) error {
+	if u, ok := t.store.(updater); ok {
+		return u.update(ctx, key, fn)
+	}
+
+	current, exists := t.store.Get(ctx, key)
+	value, write, err := fn(current, exists)
+	if err != nil || !write {
+		return err
+	}
+	return t.store.Set(ctx, key, value)
+}
+
+// List returns the values of the keys that start with prefix, in key order.
+// It fails with ErrNotListable for typed stores that can't enumerate their
+// keys, including those not created with NewTypedStore. It is not a
+// TypedStore method, as adding one would break other implementations.
+func List[T any](ctx context.Context, ts TypedStore[T], prefix string) ([]T, error) {
+	t, ok := ts.(*typedStore[T])
+	if !ok {
+		return nil, ErrNotListable
+	}
+	s, ok := t.store.(snapshotter)
+	if !ok {
+		return nil, ErrNotListable
+	}
+
+	entries := s.snapshot()
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	values := make([]T, len(keys))
+	for i, key := range keys {
+		typed, err := t.assert(entries[key])
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", key, err)
+		}
+		values[i] = typed
+	}
+	return values, nil
+}
+
+// Put stores a struct under the key in its field tagged `pocket:"key"`, so
+// callers don't build keys by hand:
+//
+//	type User struct {
+//		ID   string `pocket:"key"`
+//		Name string
+//	}
+//
+// Put is a package function, not a TypedStore method, so the TypedStore
+// interface stays the same for stores that implement it.
+func Put[T any](ctx context.Context, ts TypedStore[T], value T) error {
+	var keyField []int
+	if t, ok := ts.(*typedStore[T]); ok {
+		keyField = t.keyField
+	} else {
+		keyField = keyFieldOf(reflect.TypeFor[T]())
+	}
+	if keyField == nil {
+		return fmt.Errorf("type %T has no field tagged `pocket:\"key\"`", value)
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return fmt.Errorf("nil %T has no key", value)
+		}
+		v = v.Elem()
+	}
+	field, err := v.FieldByIndexErr(keyField)
+	if err != nil {
+		return fmt.Errorf("%T key: %w", value, err)
+	}
+	key := fmt.Sprint(field)
+	if key == "" {
+		return fmt.Errorf("%T has an empty key", value)
+	}
+	return ts.Set(ctx, key, value)
+}
@@ -0,0 +1,149 @@
+package pocket
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// StoreMetrics counts a store's operations, for tuning caches such as
+// those of RAG workflows.
+type StoreMetrics struct {
+	// Gets is the number of lookups, and Hits the number that found a
+	// value.
+	Gets int64
+	Hits int64
+
+	// Sets and Deletes are the numbers of writes and deletions.
+	Sets    int64
+	Deletes int64
+
+	// Evictions is the number of entries removed to stay within
+	// WithMaxEntries, and Expirations the number removed by WithTTL.
+	Evictions   int64
+	Expirations int64
+}
+
+// Misses returns the number of lookups that found no value.
+func (m StoreMetrics) Misses() int64 {
+	return m.Gets - m.Hits
+}
+
+// HitRate returns the fraction of lookups that found a value, or 0 before
+// any lookups.
+func (m StoreMetrics) HitRate() float64 {
+	if m.Gets == 0 {
+		return 0
+	}
+	return float64(m.Hits) / float64(m.Gets)
+}
+
+// MetricsReader is implemented by stores that count their operations. Use a
+// type assertion to reach it from a Store created with NewStore or
+// NewShardedStore.
+type MetricsReader interface {
+	// Metrics returns the counts of operations on the whole store,
+	// including all of its scopes.
+	Metrics() StoreMetrics
+}
+
+// StoreOp is the kind of a store operation.
+type StoreOp string
+
+// Store operations.
+const (
+	StoreOpGet    StoreOp = "get"
+	StoreOpSet    StoreOp = "set"
+	StoreOpDelete StoreOp = "delete"
+	StoreOpEvict  StoreOp = "evict"
+	StoreOpExpire StoreOp = "expire"
+)
+
+// StoreOperation describes an operation on a store.
+type StoreOperation struct {
+	Op StoreOp
+
+	// Key is the full key, including the prefixes of scopes.
+	Key string
+
+	// Hit reports whether a get found a value.
+	Hit bool
+}
+
+// WithOnOperation calls fn after each operation on the store, such as to
+// export store metrics to a monitoring system. It is called after the
+// store's lock is released, so it may use the store, but it may be called
+// concurrently.
+func WithOnOperation(fn func(ctx context.Context, op StoreOperation)) StoreOption {
+	return func(c *storeConfig) {
+		c.onOperation = fn
+	}
+}
+
+// storeCounters holds the live metrics of a store, shared by its scopes.
+type storeCounters struct {
+	gets        atomic.Int64
+	hits        atomic.Int64
+	sets        atomic.Int64
+	deletes     atomic.Int64
+	evictions   atomic.Int64
+	expirations atomic.Int64
+}
+
+// observe counts an operation and passes it to the hook, if any.
+func (s *store) observe(ctx context.Context, op StoreOperation) {
+	c := s.counters
+	switch op.Op {
+	case StoreOpGet:
+		c.gets.Add(1)
+		if op.Hit {
+			c.hits.Add(1)
+		}
+	case StoreOpSet:
+		c.sets.Add(1)
+	case StoreOpDelete:
+		c.deletes.Add(1)
+	case StoreOpEvict:
+		c.evictions.Add(1)
+	case StoreOpExpire:
+		c.expirations.Add(1)
+	}
+	if s.config.onOperation != nil {
+		s.config.onOperation(ctx, op)
+	}
+}
+
+// observeSet observes a write and the eviction it caused, if any.
+func (s *store) observeSet(ctx context.Context, fullKey, evicted string) {
+	s.observe(ctx, StoreOperation{Op: StoreOpSet, Key: fullKey})
+	if evicted != "" {
+		s.observe(ctx, StoreOperation{Op: StoreOpEvict, Key: evicted})
+	}
+}
+
+// Metrics returns the counts of operations on the store.
+func (s *store) Metrics() StoreMetrics {
+	c := s.counters
+	return StoreMetrics{
+		Gets:        c.gets.Load(),
+		Hits:        c.hits.Load(),
+		Sets:        c.sets.Load(),
+		Deletes:     c.deletes.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+	}
+}
+
+// Metrics returns the counts of operations on all shards.
+func (s *shardedStore) Metrics() StoreMetrics {
+	var total StoreMetrics
+	for _, shard := range s.shards {
+		m := shard.Metrics()
+		total.Gets += m.Gets
+		total.Hits += m.Hits
+		total.Sets += m.Sets
+		total.Deletes += m.Deletes
+		total.Evictions += m.Evictions
+		total.Expirations += m.Expirations
+	}
+	return total
+}
@@ -0,0 +1,92 @@
+package pocket
+
+import "context"
+
+// DefaultShardCount is the number of shards NewShardedStore uses when given
+// a non-positive count.
+const DefaultShardCount = 32
+
+// shardedStore spreads keys over independently locked stores so concurrent
+// writers to different keys rarely contend for the same lock.
+type shardedStore struct {
+	shards []*store
+	prefix string
+}
+
+// NewShardedStore creates a store that splits keys across shards, each with
+// its own lock. It suits workloads where many goroutines, such as large
+// FanOut calls, write to the store at once.
+//
+// It accepts the same options as NewStore, with these differences:
+//   - WithMaxEntries bounds each shard to its share of the limit, so
+//     eviction is least recently used per shard rather than store-wide and
+//     may remove an entry before the store as a whole is full.
+//   - The eviction callback may run concurrently for different shards.
+//
+// Operations on a single key are still atomic, but there is no ordering
+// between writes to keys in different shards.
+func NewShardedStore(shards int, opts ...StoreOption) Store {
+	if shards <= 0 {
+		shards = DefaultShardCount
+	}
+
+	var config storeConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.maxEntries > 0 {
+		// Round up so the shards together hold at least maxEntries
+		config.maxEntries = (config.maxEntries + shards - 1) / shards
+	}
+
+	s := &shardedStore{shards: make([]*store, shards)}
+	for i := range s.shards {
+		shard := NewStore().(*store)
+		shard.config = config
+		s.shards[i] = shard
+	}
+	return s
+}
+
+// shard returns the shard holding a full key, chosen by its FNV-1a hash.
+// The hash is computed inline to keep lookups allocation-free.
+func (s *shardedStore) shard(fullKey string) *store {
+	h := uint32(2166136261)
+	for i := 0; i < len(fullKey); i++ {
+		h ^= uint32(fullKey[i])
+		h *= 16777619
+	}
+	return s.shards[h%uint32(len(s.shards))] // #nosec G115 - Shard count is positive and fits in uint32
+}
+
+// Get retrieves a value by key.
+func (s *shardedStore) Get(ctx context.Context, key string) (any, bool) {
+	fullKey := s.prefix + key
+	return s.shard(fullKey).Get(ctx, fullKey)
+}
+
+// Set stores a value with the given key.
+func (s *shardedStore) Set(ctx context.Context, key string, value any) error {
+	fullKey := s.prefix + key
+	return s.shard(fullKey).Set(ctx, fullKey, value)
+}
+
+// Delete removes a key from the store.
+func (s *shardedStore) Delete(ctx context.Context, key string) error {
+	fullKey := s.prefix + key
+	return s.shard(fullKey).Delete(ctx, fullKey)
+}
+
+// update atomically updates a key in its shard.
+func (s *shardedStore) update(ctx context.Context, key string, fn func(current any, exists bool) (value any, write bool, err error)) error {
+	fullKey := s.prefix + key
+	return s.shard(fullKey).update(ctx, fullKey, fn)
+}
+
+// Scope returns a new store with the given prefix that shares the shards.
+func (s *shardedStore) Scope(prefix string) Store {
+	return &shardedStore{
+		shards: s.shards,
+		prefix: s.prefix + prefix + ":",
+	}
+}
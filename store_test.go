@@ -2,8 +2,11 @@ package pocket_test
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/agentstation/pocket"
 )
@@ -142,6 +145,136 @@ func TestTypedStore(t *testing.T) {
 	}
 }
 
+func TestTypedStoreReadModifyWrite(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range map[string]pocket.Store{
+		"default": pocket.NewStore(),
+		"sharded": pocket.NewShardedStore(4),
+	} {
+		t.Run(name, func(t *testing.T) {
+			counters := pocket.NewTypedStore[int](store)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, _ = pocket.Update(ctx, counters, "count:hits", func(current int, exists bool) (int, error) {
+						return current + 1, nil
+					})
+				}()
+			}
+			wg.Wait()
+			if count, _, _ := counters.Get(ctx, "count:hits"); count != 50 {
+				t.Errorf("Expected 50 after concurrent updates, got %d", count)
+			}
+
+			if _, err := pocket.Update(ctx, counters, "count:hits", func(current int, exists bool) (int, error) {
+				return 0, errors.New("rejected")
+			}); err == nil {
+				t.Error("Expected Update to return fn's error")
+			}
+			if count, _, _ := counters.Get(ctx, "count:hits"); count != 50 {
+				t.Errorf("Expected a failed update to keep 50, got %d", count)
+			}
+
+			calls := 0
+			factory := func() (int, error) {
+				calls++
+				return 7, nil
+			}
+			for i := 0; i < 2; i++ {
+				if value, err := pocket.GetOrSet(ctx, counters, "count:misses", factory); err != nil || value != 7 {
+					t.Errorf("Expected GetOrSet to return 7, got %d, %v", value, err)
+				}
+			}
+			if calls != 1 {
+				t.Errorf("Expected factory to run once, got %d", calls)
+			}
+
+			_ = store.Set(ctx, "other", "ignored")
+			values, err := pocket.List(ctx, counters, "count:")
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if len(values) != 2 || values[0] != 50 || values[1] != 7 {
+				t.Errorf("Expected [50 7], got %v", values)
+			}
+		})
+	}
+
+	t.Run("unlistable", func(t *testing.T) {
+		wrapped := struct{ pocket.Store }{pocket.NewStore()}
+		counters := pocket.NewTypedStore[int](wrapped)
+		if value, err := pocket.Update(ctx, counters, "count", func(current int, exists bool) (int, error) {
+			return current + 1, nil
+		}); err != nil || value != 1 {
+			t.Errorf("Expected Update to work on any store, got %d, %v", value, err)
+		}
+		if _, err := pocket.List(ctx, counters, ""); !errors.Is(err, pocket.ErrNotListable) {
+			t.Errorf("Expected ErrNotListable, got %v", err)
+		}
+	})
+
+	t.Run("other typed stores", func(t *testing.T) {
+		counters := struct{ pocket.TypedStore[int] }{pocket.NewTypedStore[int](pocket.NewStore())}
+		if value, err := pocket.Update(ctx, counters, "count", func(current int, exists bool) (int, error) {
+			return current + 1, nil
+		}); err != nil || value != 1 {
+			t.Errorf("Expected Update to work on any typed store, got %d, %v", value, err)
+		}
+		if value, err := pocket.GetOrSet(ctx, counters, "count", func() (int, error) { return 7, nil }); err != nil || value != 1 {
+			t.Errorf("Expected GetOrSet to return the stored 1, got %d, %v", value, err)
+		}
+		if _, err := pocket.List(ctx, counters, ""); !errors.Is(err, pocket.ErrNotListable) {
+			t.Errorf("Expected ErrNotListable, got %v", err)
+		}
+	})
+}
+
+func TestTypedStoreKeys(t *testing.T) {
+	type User struct {
+		ID   string `pocket:"key"`
+		Name string
+	}
+	type Order struct {
+		Number int `pocket:"key"`
+	}
+
+	ctx := context.Background()
+	store := pocket.NewStore()
+	users := pocket.NewTypedStore[*User](store, pocket.WithKeyPrefix("user"))
+	orders := pocket.NewTypedStore[Order](store, pocket.WithKeyPrefix("order"))
+
+	if err := pocket.Put(ctx, users, &User{ID: "1", Name: testUserName}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := pocket.Put(ctx, orders, Order{Number: 1}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	if user, ok, err := users.Get(ctx, "1"); err != nil || !ok || user.Name != testUserName {
+		t.Errorf("Expected %s under key 1, got %v, %v, %v", testUserName, user, ok, err)
+	}
+	if _, ok := store.Get(ctx, "user:1"); !ok {
+		t.Error("Expected user stored under user:1")
+	}
+	if order, ok, err := orders.Get(ctx, "1"); err != nil || !ok || order.Number != 1 {
+		t.Errorf("Expected order 1 not to collide with user 1, got %v, %v, %v", order, ok, err)
+	}
+
+	if err := pocket.Put(ctx, users, &User{Name: "Bob"}); err == nil {
+		t.Error("Expected an error for an empty key")
+	}
+	if err := pocket.Put(ctx, users, nil); err == nil {
+		t.Error("Expected an error for a nil value")
+	}
+	if err := pocket.Put(ctx, pocket.NewTypedStore[string](store), "untagged"); err == nil {
+		t.Error("Expected an error for a type without a key field")
+	}
+}
+
 func TestScopedStore(t *testing.T) {
 	baseStore := pocket.NewStore()
 	userStore := baseStore.Scope("user")
@@ -183,6 +316,257 @@ func TestScopedStore(t *testing.T) {
 	}
 }
 
+func TestScopedStoreConcurrency(t *testing.T) {
+	for name, base := range map[string]pocket.Store{
+		"default": pocket.NewStore(),
+		"sharded": pocket.NewShardedStore(8),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var wg sync.WaitGroup
+
+			// Scopes share data, so concurrent writers must share locking
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(n int) {
+					defer wg.Done()
+					scope := base.Scope("item-" + strconv.Itoa(n))
+					for j := 0; j < 100; j++ {
+						_ = scope.Set(ctx, strconv.Itoa(j), n)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			val, ok := base.Get(ctx, "item-3:42")
+			if !ok || val != 3 {
+				t.Errorf("Get(item-3:42) = %v, %v; want 3, true", val, ok)
+			}
+		})
+	}
+}
+
+func TestShardedStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("basic operations", func(t *testing.T) {
+		store := pocket.NewShardedStore(4)
+
+		_ = store.Set(ctx, "name", testUserName)
+		if val, ok := store.Get(ctx, "name"); !ok || val != testUserName {
+			t.Errorf("Get(name) = %v, %v; want Alice, true", val, ok)
+		}
+
+		_ = store.Delete(ctx, "name")
+		if _, ok := store.Get(ctx, "name"); ok {
+			t.Error("Get(name) after delete returned true, want false")
+		}
+	})
+
+	t.Run("scopes", func(t *testing.T) {
+		store := pocket.NewShardedStore(4)
+		_ = store.Scope("user").Set(ctx, "name", testUserName)
+
+		if val, ok := store.Get(ctx, "user:name"); !ok || val != testUserName {
+			t.Errorf("Get(user:name) = %v, %v; want Alice, true", val, ok)
+		}
+		if _, ok := store.Scope("admin").Get(ctx, "name"); ok {
+			t.Error("admin scope sees user value")
+		}
+	})
+
+	t.Run("max entries bounds each shard", func(t *testing.T) {
+		var evicted int
+		var mu sync.Mutex
+		store := pocket.NewShardedStore(4,
+			pocket.WithMaxEntries(8),
+			pocket.WithEvictionCallback(func(key string, value any) {
+				mu.Lock()
+				evicted++
+				mu.Unlock()
+			}),
+		)
+
+		for i := 0; i < 100; i++ {
+			_ = store.Set(ctx, strconv.Itoa(i), i)
+		}
+
+		// Each of the 4 shards holds at most 2 entries
+		present := 0
+		for i := 0; i < 100; i++ {
+			if _, ok := store.Get(ctx, strconv.Itoa(i)); ok {
+				present++
+			}
+		}
+		if present > 8 {
+			t.Errorf("Expected at most 8 entries, got %d", present)
+		}
+		if evicted != 100-present {
+			t.Errorf("Expected %d evictions, got %d", 100-present, evicted)
+		}
+	})
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range map[string]pocket.Store{
+		"default": pocket.NewStore(),
+		"sharded": pocket.NewShardedStore(4),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_ = store.Set(ctx, "inventory", map[string]int{"apple": 5})
+			_ = store.Scope("user").Set(ctx, "name", testUserName)
+
+			var writeErr error
+			node := pocket.NewNode[any, any]("reserve",
+				pocket.Steps{
+					Prep: func(ctx context.Context, reader pocket.StoreReader, input any) (any, error) {
+						first, _ := reader.Get(ctx, "inventory")
+
+						// Mutating a read value doesn't change the store
+						first.(map[string]int)["apple"]--
+
+						// Writes made while Prep runs are not visible
+						_ = store.Set(ctx, "inventory", map[string]int{"apple": 0})
+						second, _ := reader.Get(ctx, "inventory")
+
+						// Snapshots are read-only
+						writeErr = reader.(pocket.Store).Set(ctx, "inventory", nil)
+
+						name, _ := reader.Scope("user").Get(ctx, "name")
+						return []any{second.(map[string]int)["apple"], name}, nil
+					},
+				},
+			)
+
+			result, err := pocket.NewGraph(node, store, pocket.WithSnapshotIsolation()).Run(ctx, nil)
+			if err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+
+			got := result.([]any)
+			if got[0] != 5 {
+				t.Errorf("Expected Prep to see 5 apples, got %v", got[0])
+			}
+			if got[1] != testUserName {
+				t.Errorf("Expected scoped read to return Alice, got %v", got[1])
+			}
+			if !errors.Is(writeErr, pocket.ErrReadOnlyStore) {
+				t.Errorf("Expected ErrReadOnlyStore, got %v", writeErr)
+			}
+		})
+	}
+}
+
+func TestStoreCopyOnWrite(t *testing.T) {
+	ctx := context.Background()
+
+	type Order struct {
+		Items []string
+		Meta  map[string]any
+	}
+
+	for name, store := range map[string]pocket.Store{
+		"default": pocket.NewStore(pocket.WithStoreCopyOnWrite()),
+		"sharded": pocket.NewShardedStore(4, pocket.WithStoreCopyOnWrite()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			inventory := map[string]int{"apple": 5}
+			order := &Order{Items: []string{"apple"}, Meta: map[string]any{"tags": []string{"new"}}}
+			_ = store.Set(ctx, "inventory", inventory)
+			_ = store.Scope("orders").Set(ctx, "1", order)
+
+			// Mutate the caller's values after storing them
+			inventory["apple"] = 0
+			order.Items[0] = "pear"
+			order.Meta["tags"].([]string)[0] = "old"
+
+			stored, _ := store.Get(ctx, "inventory")
+			if stored.(map[string]int)["apple"] != 5 {
+				t.Errorf("Expected stored inventory to keep 5 apples, got %v", stored)
+			}
+
+			storedOrder, _ := store.Get(ctx, "orders:1")
+			got := storedOrder.(*Order)
+			if got == order {
+				t.Fatal("Expected a copy of the pointer value")
+			}
+			if got.Items[0] != "apple" || got.Meta["tags"].([]string)[0] != "new" {
+				t.Errorf("Expected stored order to be unchanged, got %+v", got)
+			}
+		})
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		store := pocket.NewStore()
+		inventory := map[string]int{"apple": 5}
+		_ = store.Set(ctx, "inventory", inventory)
+		inventory["apple"] = 0
+
+		stored, _ := store.Get(ctx, "inventory")
+		if stored.(map[string]int)["apple"] != 0 {
+			t.Error("Expected the default store to keep the caller's reference")
+		}
+	})
+}
+
+func TestStoreHistory(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range map[string]pocket.Store{
+		"default": pocket.NewStore(pocket.WithHistory(3)),
+		"sharded": pocket.NewShardedStore(4, pocket.WithHistory(3)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			writer := func(nodeName string, count int) pocket.Node {
+				return pocket.NewNode[any, any](nodeName,
+					pocket.Steps{
+						Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+							return input, "default", store.Set(ctx, "inventory", count)
+						},
+					},
+				)
+			}
+			reserve := writer("reserve", 4)
+			reserve.Connect("default", writer("restock", 10))
+
+			_ = store.Set(ctx, "inventory", 5)
+			if _, err := pocket.NewGraph(reserve, store).Run(pocket.WithRunID(ctx, "order-1"), nil); err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+
+			history := store.(pocket.HistoryReader).History(ctx, "inventory")
+			if len(history) != 3 {
+				t.Fatalf("Expected 3 changes, got %d", len(history))
+			}
+			if history[0].Value != 5 || history[0].Node != "" || history[0].RunID != "" {
+				t.Errorf("Unexpected first change: %+v", history[0])
+			}
+			if history[1].Value != 4 || history[1].Node != "reserve" || history[1].RunID != "order-1" {
+				t.Errorf("Unexpected second change: %+v", history[1])
+			}
+			if history[2].Value != 10 || history[2].Node != "restock" {
+				t.Errorf("Unexpected third change: %+v", history[2])
+			}
+
+			// Only the last 3 changes are kept, including deletions
+			_ = store.Delete(ctx, "inventory")
+			history = store.(pocket.HistoryReader).History(ctx, "inventory")
+			if len(history) != 3 || history[0].Value != 4 || !history[2].Deleted {
+				t.Errorf("Unexpected history after delete: %+v", history)
+			}
+
+			// Scopes see their own keys' history
+			_ = store.Scope("user").Set(ctx, "name", testUserName)
+			scoped := store.Scope("user").(pocket.HistoryReader).History(ctx, "name")
+			if len(scoped) != 1 || scoped[0].Value != testUserName {
+				t.Errorf("Unexpected scoped history: %+v", scoped)
+			}
+		})
+	}
+}
+
 func BenchmarkStore(b *testing.B) {
 	ctx := context.Background()
 
@@ -218,3 +602,213 @@ func BenchmarkStore(b *testing.B) {
 		})
 	})
 }
+
+func BenchmarkShardedStore(b *testing.B) {
+	ctx := context.Background()
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "history:" + strconv.Itoa(i)
+	}
+
+	for name, newStore := range map[string]func() pocket.Store{
+		"Default": func() pocket.Store { return pocket.NewStore() },
+		"Sharded": func() pocket.Store { return pocket.NewShardedStore(0) },
+	} {
+		b.Run(name, func(b *testing.B) {
+			store := newStore()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					_ = store.Set(ctx, keys[i%len(keys)], i)
+					i++
+				}
+			})
+		})
+	}
+}
+
+func TestStoreClock(t *testing.T) {
+	ctx := context.Background()
+	clock := pocket.NewSimulatedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	stores := map[string]pocket.Store{
+		"default": pocket.NewStore(pocket.WithTTL(time.Hour), pocket.WithStoreClock(clock)),
+		"sharded": pocket.NewShardedStore(4, pocket.WithTTL(time.Hour), pocket.WithStoreClock(clock)),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Set(ctx, "session", "abc"); err != nil {
+				t.Fatal(err)
+			}
+
+			clock.Advance(59 * time.Minute)
+			if _, ok := store.Get(ctx, "session"); !ok {
+				t.Error("Expected entry before the TTL")
+			}
+
+			clock.Advance(2 * time.Minute)
+			if _, ok := store.Get(ctx, "session"); ok {
+				t.Error("Expected entry to expire after the TTL")
+			}
+		})
+	}
+}
+
+func TestStoreMetrics(t *testing.T) {
+	ctx := context.Background()
+	clock := pocket.NewSimulatedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	for name, newStore := range map[string]func(...pocket.StoreOption) pocket.Store{
+		"default": pocket.NewStore,
+		"sharded": func(opts ...pocket.StoreOption) pocket.Store { return pocket.NewShardedStore(1, opts...) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var ops []pocket.StoreOp
+			store := newStore(
+				pocket.WithMaxEntries(2),
+				pocket.WithTTL(time.Hour),
+				pocket.WithStoreClock(clock),
+				pocket.WithOnOperation(func(ctx context.Context, op pocket.StoreOperation) {
+					mu.Lock()
+					defer mu.Unlock()
+					ops = append(ops, op.Op)
+				}),
+			)
+
+			scoped := store.Scope("cache")
+			_ = scoped.Set(ctx, "a", 1)
+			_ = scoped.Set(ctx, "b", 2)
+			_ = scoped.Set(ctx, "c", 3) // Evicts a
+			scoped.Get(ctx, "a")
+			scoped.Get(ctx, "b")
+			_ = store.Delete(ctx, "cache:b")
+			clock.Advance(2 * time.Hour)
+			scoped.Get(ctx, "c") // Expired
+
+			metrics := store.(pocket.MetricsReader).Metrics()
+			expected := pocket.StoreMetrics{Gets: 3, Hits: 1, Sets: 3, Deletes: 1, Evictions: 1, Expirations: 1}
+			if metrics != expected {
+				t.Errorf("Expected %+v, got %+v", expected, metrics)
+			}
+			if metrics.Misses() != 2 || metrics.HitRate() != 1.0/3 {
+				t.Errorf("Expected 2 misses and a hit rate of 1/3, got %d and %v", metrics.Misses(), metrics.HitRate())
+			}
+			if len(ops) != 9 || ops[3] != pocket.StoreOpEvict || ops[7] != pocket.StoreOpExpire {
+				t.Errorf("Unexpected operations: %v", ops)
+			}
+		})
+	}
+}
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	clock := pocket.NewSimulatedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := pocket.NewStore()
+
+	var ended []string
+	sessions := pocket.NewSessions(store,
+		pocket.WithSessionIdleTTL(time.Hour),
+		pocket.WithMaxSessions(2),
+		pocket.WithSessionClock(clock),
+		pocket.WithSessionEvictionCallback(func(id string) { ended = append(ended, id) }),
+	)
+
+	t.Run("isolation", func(t *testing.T) {
+		alice := sessions.Session("alice")
+		bob := sessions.Session("bob")
+		if err := alice.Set(ctx, "name", testUserName); err != nil {
+			t.Fatal(err)
+		}
+		if err := alice.Scope("memory").Set(ctx, "turns", 3); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := bob.Get(ctx, "name"); ok {
+			t.Error("Expected sessions to be isolated")
+		}
+		if val, ok := sessions.Session("alice").Get(ctx, "name"); !ok || val != testUserName {
+			t.Errorf("Expected resumed session to see %s, got %v", testUserName, val)
+		}
+		if val, ok := store.Get(ctx, "session:alice:memory:turns"); !ok || val != 3 {
+			t.Errorf("Expected session keys under session:alice, got %v", val)
+		}
+	})
+
+	t.Run("idle sessions end", func(t *testing.T) {
+		clock.Advance(30 * time.Minute)
+		_, _ = sessions.Session("alice").Get(ctx, "name")
+
+		clock.Advance(45 * time.Minute)
+		if n := sessions.Sweep(ctx); n != 1 {
+			t.Errorf("Expected 1 idle session ended, got %d", n)
+		}
+		if len(ended) != 1 || ended[0] != "bob" {
+			t.Errorf("Expected bob to end, got %v", ended)
+		}
+		if sessions.Len() != 1 {
+			t.Errorf("Expected 1 open session, got %d", sessions.Len())
+		}
+	})
+
+	t.Run("max sessions", func(t *testing.T) {
+		sessions.Session("carol")
+		sessions.Session("dave")
+
+		if len(ended) != 2 || ended[1] != "alice" {
+			t.Fatalf("Expected alice evicted as least recently used, got %v", ended)
+		}
+		for _, key := range []string{"session:alice:name", "session:alice:memory:turns"} {
+			if _, ok := store.Get(ctx, key); ok {
+				t.Errorf("Expected %s deleted with the session", key)
+			}
+		}
+		if val, ok := sessions.Session("alice").Get(ctx, "name"); ok {
+			t.Errorf("Expected a new session to start empty, got %v", val)
+		}
+	})
+
+	t.Run("end", func(t *testing.T) {
+		dave := sessions.Session("dave")
+		_ = dave.Set(ctx, "draft", "hello")
+		sessions.End(ctx, "dave")
+
+		if _, ok := store.Get(ctx, "session:dave:draft"); ok {
+			t.Error("Expected End to delete the session's keys")
+		}
+		if ended[len(ended)-1] != "dave" {
+			t.Errorf("Expected callback for dave, got %v", ended)
+		}
+	})
+}
+
+func TestTenantStore(t *testing.T) {
+	store := pocket.NewStore()
+	tenants := pocket.TenantStore(store)
+	acme := pocket.WithTenant(context.Background(), "acme")
+	globex := pocket.WithTenant(context.Background(), "globex")
+
+	if err := tenants.Set(acme, "plan", "pro"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tenants.Scope("cache").Set(acme, "answer", 42); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tenants.Get(globex, "plan"); ok {
+		t.Error("Expected tenants to be isolated")
+	}
+	if val, ok := tenants.Get(acme, "plan"); !ok || val != "pro" {
+		t.Errorf("Expected pro, got %v", val)
+	}
+	if val, ok := store.Get(context.Background(), "cache:tenant:acme:answer"); !ok || val != 42 {
+		t.Errorf("Expected scoped key namespaced by tenant, got %v", val)
+	}
+
+	if err := tenants.Set(context.Background(), "plan", "free"); !errors.Is(err, pocket.ErrNoTenant) {
+		t.Errorf("Expected ErrNoTenant, got %v", err)
+	}
+	if _, ok := tenants.Get(context.Background(), "plan"); ok {
+		t.Error("Expected nothing without a tenant")
+	}
+}
@@ -0,0 +1,340 @@
+// Package temporal runs pocket graphs as Temporal workflows, giving existing
+// node logic durable execution without rewriting it.
+//
+// Each node in the graph executes as a Temporal activity that runs the node as
+// Graph.Run would, with its retries, timeouts, fallbacks, and hooks. The pocket store is mapped to workflow state: the
+// workflow passes a snapshot of the state to every activity, and the activity
+// returns the writes the node made so the workflow can apply them. Routing
+// decisions are made inside the workflow, so a crashed worker resumes at the
+// last completed node.
+//
+// Inputs, outputs, and stored values cross the activity boundary through
+// Temporal's data converter, so they must be JSON-serializable. Temporal's
+// activity options retry and time out a node's whole activity, on top of the
+// node's own WithRetry and WithTimeout options.
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/agentstation/pocket"
+)
+
+// ErrMaxSteps is returned when a workflow visits more nodes than allowed.
+var ErrMaxSteps = errors.New("temporal: maximum node steps exceeded")
+
+// Input is the workflow input.
+type Input struct {
+	// Input is passed to the start node.
+	Input any `json:"input,omitempty"`
+
+	// State seeds the workflow store.
+	State map[string]any `json:"state,omitempty"`
+}
+
+// Result is the workflow result.
+type Result struct {
+	// Output is the output of the last node that ran.
+	Output any `json:"output,omitempty"`
+
+	// State is the final workflow store.
+	State map[string]any `json:"state,omitempty"`
+
+	// Path lists the nodes in the order they ran.
+	Path []string `json:"path"`
+}
+
+// NodeRequest is the input to the node activity.
+type NodeRequest struct {
+	Node  string         `json:"node"`
+	Input any            `json:"input,omitempty"`
+	State map[string]any `json:"state,omitempty"`
+}
+
+// NodeResponse is the result of the node activity.
+type NodeResponse struct {
+	Output any    `json:"output,omitempty"`
+	Next   string `json:"next"`
+
+	// Node is the node whose connections Next selects among: the requested
+	// node, or its fallback node when that ran in its place.
+	Node string `json:"node,omitempty"`
+
+	Sets    map[string]any `json:"sets,omitempty"`
+	Deletes []string       `json:"deletes,omitempty"`
+}
+
+// Option configures an Adapter.
+type Option func(*options)
+
+type options struct {
+	activityOptions workflow.ActivityOptions
+	maxSteps        int
+}
+
+// WithActivityOptions sets the options used for every node activity.
+func WithActivityOptions(ao workflow.ActivityOptions) Option {
+	return func(o *options) {
+		o.activityOptions = ao
+	}
+}
+
+// WithRetryPolicy sets the retry policy used for every node activity.
+func WithRetryPolicy(policy *temporal.RetryPolicy) Option {
+	return func(o *options) {
+		o.activityOptions.RetryPolicy = policy
+	}
+}
+
+// WithMaxSteps bounds the number of nodes a single workflow run may visit.
+// This protects agent loops from growing the workflow history without limit.
+func WithMaxSteps(n int) Option {
+	return func(o *options) {
+		o.maxSteps = n
+	}
+}
+
+// Adapter exposes a pocket graph as a Temporal workflow and activity.
+type Adapter struct {
+	name  string
+	start pocket.Node
+	nodes map[string]pocket.Node
+	opts  options
+}
+
+// New creates an adapter for the graph. The name is used as the registered
+// workflow type; the node activity is registered as name + ".node".
+func New(name string, graph *pocket.Graph, opts ...Option) (*Adapter, error) {
+	if graph == nil || graph.Start() == nil {
+		return nil, pocket.ErrNoStartNode
+	}
+
+	a := &Adapter{
+		name:  name,
+		start: graph.Start(),
+		nodes: make(map[string]pocket.Node),
+		opts: options{
+			activityOptions: workflow.ActivityOptions{
+				StartToCloseTimeout: 5 * time.Minute,
+			},
+			maxSteps: 1000,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&a.opts)
+	}
+
+	if err := a.index(a.start); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// index records every reachable node by name.
+func (a *Adapter) index(n pocket.Node) error {
+	if n == nil {
+		return nil
+	}
+	if existing, ok := a.nodes[n.Name()]; ok {
+		if existing != n {
+			return fmt.Errorf("temporal: duplicate node name %q", n.Name())
+		}
+		return nil
+	}
+	a.nodes[n.Name()] = n
+
+	for _, next := range n.Successors() {
+		if err := a.index(next); err != nil {
+			return err
+		}
+	}
+	return a.index(pocket.FallbackNode(n))
+}
+
+// WorkflowName returns the registered workflow type name.
+func (a *Adapter) WorkflowName() string {
+	return a.name
+}
+
+// ActivityName returns the registered node activity name.
+func (a *Adapter) ActivityName() string {
+	return a.name + ".node"
+}
+
+// Register registers the workflow and node activity with a worker.
+func (a *Adapter) Register(w worker.Registry) {
+	w.RegisterWorkflowWithOptions(a.Workflow, workflow.RegisterOptions{Name: a.WorkflowName()})
+	w.RegisterActivityWithOptions(a.RunNode, activity.RegisterOptions{Name: a.ActivityName()})
+}
+
+// Workflow walks the graph, executing each node as an activity.
+func (a *Adapter) Workflow(ctx workflow.Context, in Input) (*Result, error) {
+	ctx = workflow.WithActivityOptions(ctx, a.opts.activityOptions)
+
+	state := make(map[string]any, len(in.State))
+	for k, v := range in.State {
+		state[k] = v
+	}
+
+	result := &Result{State: state}
+	current := a.start
+	input := in.Input
+
+	for current != nil {
+		if a.opts.maxSteps > 0 && len(result.Path) >= a.opts.maxSteps {
+			return nil, fmt.Errorf("%w: %d", ErrMaxSteps, a.opts.maxSteps)
+		}
+		result.Path = append(result.Path, current.Name())
+
+		var resp NodeResponse
+		req := NodeRequest{Node: current.Name(), Input: input, State: state}
+		if err := workflow.ExecuteActivity(ctx, a.ActivityName(), req).Get(ctx, &resp); err != nil {
+			return nil, fmt.Errorf("node %s: %w", current.Name(), err)
+		}
+
+		// Apply the node's store writes to workflow state
+		for _, key := range resp.Deletes {
+			delete(state, key)
+		}
+		for key, value := range resp.Sets {
+			state[key] = value
+		}
+
+		// Route by the fallback node's connections when it ran instead
+		if resp.Node != "" && resp.Node != current.Name() {
+			current = a.nodes[resp.Node]
+			result.Path = append(result.Path, current.Name())
+		}
+
+		result.Output = resp.Output
+		input = resp.Output
+		current = current.Successors()[resp.Next]
+	}
+
+	return result, nil
+}
+
+// RunNode is the node activity. It runs a single node with pocket.RunNode
+// against a store seeded from the workflow state and reports the writes it
+// made.
+func (a *Adapter) RunNode(ctx context.Context, req NodeRequest) (*NodeResponse, error) {
+	n, ok := a.nodes[req.Node]
+	if !ok {
+		return nil, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("node %q not found", req.Node), "NodeNotFound", pocket.ErrNodeNotFound)
+	}
+
+	store := newStateStore(req.State)
+
+	output, next, routedBy, err := pocket.RunNode(ctx, n, store, req.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	sets, deletes := store.changes()
+	return &NodeResponse{
+		Output:  output,
+		Next:    next,
+		Node:    routedBy.Name(),
+		Sets:    sets,
+		Deletes: deletes,
+	}, nil
+}
+
+// stateStore is a pocket.Store backed by a workflow state snapshot that
+// records the writes made against it.
+type stateStore struct {
+	shared *stateData
+	prefix string
+}
+
+type stateData struct {
+	mu      sync.RWMutex
+	values  map[string]any
+	sets    map[string]any
+	deletes map[string]bool
+}
+
+func newStateStore(state map[string]any) *stateStore {
+	values := make(map[string]any, len(state))
+	for k, v := range state {
+		values[k] = v
+	}
+	return &stateStore{
+		shared: &stateData{
+			values:  values,
+			sets:    make(map[string]any),
+			deletes: make(map[string]bool),
+		},
+	}
+}
+
+// Get retrieves a value by key.
+func (s *stateStore) Get(ctx context.Context, key string) (any, bool) {
+	s.shared.mu.RLock()
+	defer s.shared.mu.RUnlock()
+
+	value, ok := s.shared.values[s.prefix+key]
+	return value, ok
+}
+
+// Set stores a value with the given key.
+func (s *stateStore) Set(ctx context.Context, key string, value any) error {
+	s.shared.mu.Lock()
+	defer s.shared.mu.Unlock()
+
+	fullKey := s.prefix + key
+	s.shared.values[fullKey] = value
+	s.shared.sets[fullKey] = value
+	delete(s.shared.deletes, fullKey)
+	return nil
+}
+
+// Delete removes a key from the store.
+func (s *stateStore) Delete(ctx context.Context, key string) error {
+	s.shared.mu.Lock()
+	defer s.shared.mu.Unlock()
+
+	fullKey := s.prefix + key
+	delete(s.shared.values, fullKey)
+	delete(s.shared.sets, fullKey)
+	s.shared.deletes[fullKey] = true
+	return nil
+}
+
+// Scope returns a new store with the given prefix.
+func (s *stateStore) Scope(prefix string) pocket.Store {
+	return &stateStore{
+		shared: s.shared,
+		prefix: s.prefix + strings.TrimSuffix(prefix, ":") + ":",
+	}
+}
+
+// changes returns the writes recorded against the store.
+func (s *stateStore) changes() (sets map[string]any, deletes []string) {
+	s.shared.mu.RLock()
+	defer s.shared.mu.RUnlock()
+
+	if len(s.shared.sets) > 0 {
+		sets = make(map[string]any, len(s.shared.sets))
+		for k, v := range s.shared.sets {
+			sets[k] = v
+		}
+	}
+	for k := range s.shared.deletes {
+		deletes = append(deletes, k)
+	}
+	return sets, deletes
+}
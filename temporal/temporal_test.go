@@ -0,0 +1,220 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/agentstation/pocket"
+)
+
+func newTestGraph() *pocket.Graph {
+	classify := pocket.NewNode[any, any]("classify",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				n, _ := input.(float64)
+				return n, nil
+			},
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, result any) (any, string, error) {
+				if err := store.Set(ctx, "classified", true); err != nil {
+					return nil, "", err
+				}
+				if result.(float64) > 10 {
+					return result, "big", nil
+				}
+				return result, "small", nil
+			},
+		},
+	)
+
+	big := pocket.NewNode[any, any]("big",
+		pocket.Steps{
+			Prep: func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+				if _, ok := store.Get(ctx, "classified"); !ok {
+					return nil, errors.New("classified not set")
+				}
+				return input, nil
+			},
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return "big", nil
+			},
+		},
+	)
+
+	small := pocket.NewNode[any, any]("small",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return "small", nil
+			},
+		},
+	)
+
+	classify.Connect("big", big)
+	classify.Connect("small", small)
+
+	return pocket.NewGraph(classify, pocket.NewStore())
+}
+
+func TestWorkflow(t *testing.T) {
+	adapter, err := New("classify", newTestGraph())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    any
+		wantOut  string
+		wantPath []string
+	}{
+		{name: "big", input: 42, wantOut: "big", wantPath: []string{"classify", "big"}},
+		{name: "small", input: 3, wantOut: "small", wantPath: []string{"classify", "small"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			adapter.Register(env)
+
+			env.ExecuteWorkflow(adapter.WorkflowName(), Input{Input: tt.input})
+			if !env.IsWorkflowCompleted() {
+				t.Fatal("workflow did not complete")
+			}
+			if err := env.GetWorkflowError(); err != nil {
+				t.Fatalf("workflow error = %v", err)
+			}
+
+			var result Result
+			if err := env.GetWorkflowResult(&result); err != nil {
+				t.Fatalf("GetWorkflowResult() error = %v", err)
+			}
+			if result.Output != tt.wantOut {
+				t.Errorf("Output = %v, want %v", result.Output, tt.wantOut)
+			}
+			if len(result.Path) != len(tt.wantPath) {
+				t.Fatalf("Path = %v, want %v", result.Path, tt.wantPath)
+			}
+			for i := range tt.wantPath {
+				if result.Path[i] != tt.wantPath[i] {
+					t.Errorf("Path[%d] = %s, want %s", i, result.Path[i], tt.wantPath[i])
+				}
+			}
+			if result.State["classified"] != true {
+				t.Errorf("State[classified] = %v, want true", result.State["classified"])
+			}
+		})
+	}
+}
+
+func TestWorkflowNodeOptions(t *testing.T) {
+	notify := pocket.NewNode[any, any]("notify",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return input.(string) + " (degraded)", nil
+			},
+		},
+	)
+	backup := pocket.NewNode[any, any]("backup",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return "backup", nil
+			},
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, exec any) (any, string, error) {
+				return exec, "degraded", nil
+			},
+		},
+	)
+	backup.Connect("degraded", notify)
+
+	primary := pocket.NewNode[any, any]("primary",
+		pocket.Steps{
+			Exec: func(ctx context.Context, input any) (any, error) {
+				return nil, errors.New("model unavailable")
+			},
+		},
+		pocket.WithOnFailure(func(ctx context.Context, store pocket.StoreWriter, err error) {
+			_ = store.Set(ctx, "failed", pocket.NodeName(ctx))
+		}),
+		pocket.WithFallbackNode(backup),
+	)
+
+	adapter, err := New("fallback", pocket.NewGraph(primary, pocket.NewStore()))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	adapter.Register(env)
+
+	env.ExecuteWorkflow(adapter.WorkflowName(), Input{})
+	if err := env.GetWorkflowError(); err != nil {
+		t.Fatalf("workflow error = %v", err)
+	}
+	var result Result
+	if err := env.GetWorkflowResult(&result); err != nil {
+		t.Fatalf("GetWorkflowResult() error = %v", err)
+	}
+	if result.Output != "backup (degraded)" {
+		t.Errorf("Output = %v, want backup (degraded)", result.Output)
+	}
+	if want := []string{"primary", "backup", "notify"}; fmt.Sprint(result.Path) != fmt.Sprint(want) {
+		t.Errorf("Path = %v, want %v", result.Path, want)
+	}
+	if result.State["failed"] != "primary" {
+		t.Errorf("State[failed] = %v, want primary", result.State["failed"])
+	}
+}
+
+func TestWorkflowMaxSteps(t *testing.T) {
+	loop := pocket.NewNode[any, any]("loop",
+		pocket.Steps{
+			Post: func(ctx context.Context, store pocket.StoreWriter, input, prep, result any) (any, string, error) {
+				return input, "again", nil
+			},
+		},
+	)
+	loop.Connect("again", loop)
+
+	adapter, err := New("loop", pocket.NewGraph(loop, pocket.NewStore()), WithMaxSteps(3))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	adapter.Register(env)
+
+	env.ExecuteWorkflow(adapter.WorkflowName(), Input{})
+	if err := env.GetWorkflowError(); err == nil {
+		t.Fatal("expected max steps error")
+	}
+}
+
+func TestStateStoreScope(t *testing.T) {
+	ctx := context.Background()
+	store := newStateStore(map[string]any{"user:name": "alice", "gone": 1})
+
+	scoped := store.Scope("user")
+	if v, ok := scoped.Get(ctx, "name"); !ok || v != "alice" {
+		t.Errorf("Get(name) = %v, %v; want alice, true", v, ok)
+	}
+	if err := scoped.Set(ctx, "age", 30); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Delete(ctx, "gone"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	sets, deletes := store.changes()
+	if sets["user:age"] != 30 {
+		t.Errorf("sets = %v, want user:age=30", sets)
+	}
+	if len(deletes) != 1 || deletes[0] != "gone" {
+		t.Errorf("deletes = %v, want [gone]", deletes)
+	}
+}
@@ -0,0 +1,313 @@
+package pocket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoTenant is returned when a tenant is required but the context
+	// has none.
+	ErrNoTenant = errors.New("pocket: no tenant in context")
+
+	// ErrTenantRateLimited is returned by runs over their tenant's rate.
+	ErrTenantRateLimited = errors.New("pocket: tenant rate limit exceeded")
+
+	// ErrTenantConcurrency is returned by runs over their tenant's limit
+	// on concurrent runs.
+	ErrTenantConcurrency = errors.New("pocket: tenant concurrent run limit exceeded")
+
+	// ErrTenantBudgetExceeded is returned once a tenant has spent its
+	// budget.
+	ErrTenantBudgetExceeded = errors.New("pocket: tenant budget exceeded")
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a context for work done on behalf of a tenant. Stores
+// from TenantStore keep each tenant's keys apart, graphs with WithTenants
+// apply the tenant's limits, and graph logs include the tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant set with WithTenant, if any.
+func TenantFrom(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// TenantStore returns a store that namespaces keys by the tenant in each
+// call's context, under tenant:<id>, so tenants sharing a store can't read
+// each other's keys. Calls without a tenant find nothing and fail to
+// write.
+func TenantStore(store Store) Store {
+	return &tenantStore{store: store}
+}
+
+type tenantStore struct {
+	store Store
+}
+
+func (s *tenantStore) scope(ctx context.Context) (Store, error) {
+	tenant, ok := TenantFrom(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+	return s.store.Scope("tenant:" + tenant), nil
+}
+
+// Get retrieves a value by key for the context's tenant.
+func (s *tenantStore) Get(ctx context.Context, key string) (any, bool) {
+	scoped, err := s.scope(ctx)
+	if err != nil {
+		return nil, false
+	}
+	return scoped.Get(ctx, key)
+}
+
+// Set stores a value with the given key for the context's tenant.
+func (s *tenantStore) Set(ctx context.Context, key string, value any) error {
+	scoped, err := s.scope(ctx)
+	if err != nil {
+		return err
+	}
+	return scoped.Set(ctx, key, value)
+}
+
+// Delete removes a key for the context's tenant.
+func (s *tenantStore) Delete(ctx context.Context, key string) error {
+	scoped, err := s.scope(ctx)
+	if err != nil {
+		return err
+	}
+	return scoped.Delete(ctx, key)
+}
+
+// Scope returns a new store with the given prefix within each tenant's
+// namespace.
+func (s *tenantStore) Scope(prefix string) Store {
+	return &tenantStore{store: s.store.Scope(prefix)}
+}
+
+// TenantLimits are the limits on a tenant's runs. Zero values are
+// unlimited.
+type TenantLimits struct {
+	// RunsPerSecond is the rate at which runs may start, with up to Burst
+	// starting at once.
+	RunsPerSecond float64
+	Burst         int
+
+	// MaxConcurrentRuns limits the runs in progress at once.
+	MaxConcurrentRuns int
+
+	// Budget is the total a tenant may spend through ChargeTenant until its
+	// usage is reset.
+	Budget float64
+}
+
+// TenantUsage is what a tenant has used.
+type TenantUsage struct {
+	Runs   int     // Runs started
+	Active int     // Runs in progress
+	Spent  float64 // Charged with ChargeTenant
+}
+
+// Tenants applies per-tenant limits to the runs of graphs configured with
+// WithTenants and tracks each tenant's usage. It is safe for concurrent
+// use.
+type Tenants struct {
+	defaults TenantLimits
+
+	mu      sync.Mutex
+	limits  map[string]TenantLimits
+	tenants map[string]*tenantState
+}
+
+type tenantState struct {
+	usage  TenantUsage
+	tokens float64
+	last   time.Time
+}
+
+// NewTenants creates a Tenants that applies the default limits to tenants
+// without their own.
+func NewTenants(defaults TenantLimits) *Tenants {
+	return &Tenants{
+		defaults: defaults,
+		limits:   make(map[string]TenantLimits),
+		tenants:  make(map[string]*tenantState),
+	}
+}
+
+// SetLimits sets the limits of a tenant.
+func (t *Tenants) SetLimits(tenant string, limits TenantLimits) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limits[tenant] = limits
+}
+
+// Usage returns what a tenant has used.
+func (t *Tenants) Usage(tenant string) TenantUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state, ok := t.tenants[tenant]; ok {
+		return state.usage
+	}
+	return TenantUsage{}
+}
+
+// ResetUsage clears a tenant's run count and spending, such as at the start
+// of a billing period. Runs in progress are still counted as active.
+func (t *Tenants) ResetUsage(tenant string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state, ok := t.tenants[tenant]; ok {
+		state.usage = TenantUsage{Active: state.usage.Active}
+	}
+}
+
+// Charge adds to the spending of the tenant in the context. It returns
+// ErrTenantBudgetExceeded once the tenant has spent more than its budget,
+// though the charge is still recorded.
+func (t *Tenants) Charge(ctx context.Context, amount float64) error {
+	tenant, ok := TenantFrom(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.state(tenant)
+	state.usage.Spent += amount
+	if budget := t.limitsOf(tenant).Budget; budget > 0 && state.usage.Spent > budget {
+		return fmt.Errorf("%w: tenant %s spent %g of %g", ErrTenantBudgetExceeded, tenant, state.usage.Spent, budget)
+	}
+	return nil
+}
+
+// admit starts a run for a tenant at now if its limits allow, returning
+// the function that ends it.
+func (t *Tenants) admit(tenant string, now time.Time) (func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limits := t.limitsOf(tenant)
+	state := t.state(tenant)
+
+	if limits.Budget > 0 && state.usage.Spent >= limits.Budget {
+		return nil, fmt.Errorf("%w: tenant %s spent %g of %g", ErrTenantBudgetExceeded, tenant, state.usage.Spent, limits.Budget)
+	}
+	if limits.MaxConcurrentRuns > 0 && state.usage.Active >= limits.MaxConcurrentRuns {
+		return nil, fmt.Errorf("%w: tenant %s has %d runs in progress", ErrTenantConcurrency, tenant, state.usage.Active)
+	}
+	if limits.RunsPerSecond > 0 {
+		burst := float64(max(limits.Burst, 1))
+		if state.last.IsZero() {
+			state.tokens = burst
+		} else if now.After(state.last) {
+			state.tokens = min(burst, state.tokens+now.Sub(state.last).Seconds()*limits.RunsPerSecond)
+		}
+		state.last = now
+		if state.tokens < 1 {
+			return nil, fmt.Errorf("%w: tenant %s", ErrTenantRateLimited, tenant)
+		}
+		state.tokens--
+	}
+
+	state.usage.Runs++
+	state.usage.Active++
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		state.usage.Active--
+	}, nil
+}
+
+// limitsOf returns a tenant's limits. Must be called with the lock held.
+func (t *Tenants) limitsOf(tenant string) TenantLimits {
+	if limits, ok := t.limits[tenant]; ok {
+		return limits
+	}
+	return t.defaults
+}
+
+// state returns a tenant's state, creating it. Must be called with the
+// lock held.
+func (t *Tenants) state(tenant string) *tenantState {
+	state, ok := t.tenants[tenant]
+	if !ok {
+		state = &tenantState{}
+		t.tenants[tenant] = state
+	}
+	return state
+}
+
+type tenantsKey struct{}
+
+// WithTenants requires each run of the graph to have a tenant, set with
+// WithTenant, and applies the tenant's limits to it. Runs over a limit
+// fail at once with ErrTenantRateLimited, ErrTenantConcurrency, or
+// ErrTenantBudgetExceeded. Nodes charge the tenant with ChargeTenant.
+func WithTenants(tenants *Tenants) GraphOption {
+	return func(o *graphOptions) {
+		o.tenants = tenants
+	}
+}
+
+// ChargeTenant adds to the spending of the run's tenant, such as the cost
+// of an LLM call, against its budget. It does nothing outside graphs with
+// WithTenants.
+func ChargeTenant(ctx context.Context, amount float64) error {
+	tenants, ok := ctx.Value(tenantsKey{}).(*Tenants)
+	if !ok {
+		return nil
+	}
+	return tenants.Charge(ctx, amount)
+}
+
+// admitTenant applies the graph's tenant limits to a run, returning the
+// run's context and the function that ends it.
+func (g *graph) admitTenant(ctx context.Context) (context.Context, func(), error) {
+	tenant, ok := TenantFrom(ctx)
+	if !ok {
+		return nil, nil, ErrNoTenant
+	}
+	clock := g.opts.clock
+	if clock == nil {
+		clock = ClockFrom(ctx)
+	}
+	done, err := g.opts.tenants.admit(tenant, clock.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	return context.WithValue(ctx, tenantsKey{}, g.opts.tenants), done, nil
+}
+
+// tenantLogger adds the tenant of the context to log entries.
+type tenantLogger struct {
+	Logger
+}
+
+func (l tenantLogger) with(ctx context.Context, keysAndValues []any) []any {
+	if tenant, ok := TenantFrom(ctx); ok {
+		return append(keysAndValues[:len(keysAndValues):len(keysAndValues)], "tenant", tenant)
+	}
+	return keysAndValues
+}
+
+func (l tenantLogger) Debug(ctx context.Context, msg string, keysAndValues ...any) {
+	l.Logger.Debug(ctx, msg, l.with(ctx, keysAndValues)...)
+}
+
+func (l tenantLogger) Info(ctx context.Context, msg string, keysAndValues ...any) {
+	l.Logger.Info(ctx, msg, l.with(ctx, keysAndValues)...)
+}
+
+func (l tenantLogger) Error(ctx context.Context, msg string, keysAndValues ...any) {
+	l.Logger.Error(ctx, msg, l.with(ctx, keysAndValues)...)
+}
@@ -0,0 +1,317 @@
+package pocket
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TimelineSpan is the time a run spent in one node.
+type TimelineSpan struct {
+	RunID string
+	Node  string
+	Start time.Time
+	End   time.Time
+
+	// Retries counts the failed attempts of the node's steps that were
+	// retried.
+	Retries int
+
+	// Err is the error that failed the node, if any.
+	Err string
+}
+
+// Timeline records when each node of a graph's runs started and ended, to
+// export as a trace for timeline and flame views of where a slow workflow
+// spent its time. Add it to a graph with WithEventHandler(timeline.Handle).
+// It is safe for concurrent use. Runs are told apart by their IDs from
+// WithRunID; the spans of runs without one are grouped as a single run.
+//
+// Example:
+//
+//	timeline := pocket.NewTimeline()
+//	graph := pocket.NewGraph(start, store, pocket.WithEventHandler(timeline.Handle))
+//	_, err := graph.Run(ctx, input)
+//
+//	f, _ := os.Create("trace.json")
+//	defer f.Close()
+//	timeline.WriteChromeTrace(f) // Open in chrome://tracing or Perfetto
+type Timeline struct {
+	mu    sync.Mutex
+	spans []TimelineSpan
+	open  map[timelineKey]int // Index in spans of nodes running, by run and node
+}
+
+type timelineKey struct {
+	runID string
+	node  string
+}
+
+// NewTimeline creates an empty timeline.
+func NewTimeline() *Timeline {
+	return &Timeline{open: make(map[timelineKey]int)}
+}
+
+// Handle records a run event. It is an EventHandler.
+func (t *Timeline) Handle(_ context.Context, event Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := timelineKey{runID: event.RunID, node: event.Node}
+	switch event.Type {
+	case EventNodeStart:
+		t.open[key] = len(t.spans)
+		t.spans = append(t.spans, TimelineSpan{RunID: event.RunID, Node: event.Node, Start: event.Time})
+	case EventRetry:
+		if i, ok := t.open[key]; ok {
+			t.spans[i].Retries++
+		}
+	case EventNodeEnd, EventError:
+		i, ok := t.open[key]
+		if !ok {
+			return
+		}
+		delete(t.open, key)
+		t.spans[i].End = event.Time
+		if event.Err != nil {
+			t.spans[i].Err = event.Err.Error()
+		}
+	}
+}
+
+// Spans returns the finished spans in the order their nodes started.
+func (t *Timeline) Spans() []TimelineSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]TimelineSpan, 0, len(t.spans))
+	for _, span := range t.spans {
+		if !span.End.IsZero() {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// timelineRun is the spans of one run.
+type timelineRun struct {
+	id         string
+	start, end time.Time
+	spans      []TimelineSpan
+}
+
+// runs groups the finished spans by run, in the order the runs started.
+func (t *Timeline) runs() []*timelineRun {
+	var runs []*timelineRun
+	byID := make(map[string]*timelineRun)
+	for _, span := range t.Spans() {
+		run, ok := byID[span.RunID]
+		if !ok {
+			run = &timelineRun{id: span.RunID, start: span.Start, end: span.End}
+			byID[span.RunID] = run
+			runs = append(runs, run)
+		}
+		if span.Start.Before(run.start) {
+			run.start = span.Start
+		}
+		if span.End.After(run.end) {
+			run.end = span.End
+		}
+		run.spans = append(run.spans, span)
+	}
+	return runs
+}
+
+// chromeEvent is an event of the Chrome trace event format.
+type chromeEvent struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes the timeline in the Chrome trace event format,
+// which chrome://tracing and ui.perfetto.dev open. Each run is a thread of
+// node spans.
+func (t *Timeline) WriteChromeTrace(w io.Writer) error {
+	events := []chromeEvent{}
+	for i, run := range t.runs() {
+		name := "run"
+		if run.id != "" {
+			name = "run " + run.id
+		}
+		events = append(events, chromeEvent{
+			Name: "thread_name",
+			Ph:   "M",
+			Pid:  1,
+			Tid:  i + 1,
+			Args: map[string]any{"name": name},
+		})
+		for _, span := range run.spans {
+			event := chromeEvent{
+				Name: span.Node,
+				Cat:  "node",
+				Ph:   "X",
+				Ts:   float64(span.Start.UnixNano()) / 1e3,
+				Dur:  float64(span.End.Sub(span.Start).Nanoseconds()) / 1e3,
+				Pid:  1,
+				Tid:  i + 1,
+			}
+			if span.Retries > 0 || span.Err != "" {
+				event.Args = map[string]any{}
+				if span.Retries > 0 {
+					event.Args["retries"] = span.Retries
+				}
+				if span.Err != "" {
+					event.Args["error"] = span.Err
+				}
+			}
+			events = append(events, event)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(map[string]any{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	})
+}
+
+// OTLP JSON types, as written by OpenTelemetry file exporters.
+type (
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// OTLP span kind and status codes.
+const (
+	otlpKindInternal = 1
+	otlpStatusError  = 2
+)
+
+func otlpString(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int) otlpAttribute {
+	s := strconv.Itoa(value)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpID derives a trace or span ID of n bytes, so the same timeline
+// always exports the same IDs.
+func otlpID(n int, parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		_ = binary.Write(h, binary.BigEndian, int64(len(part)))
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:n])
+}
+
+// WriteOTLP writes the timeline as OTLP JSON, the format of OpenTelemetry
+// file exporters, for tracing backends and viewers that import it. Each
+// run is a trace with a root span for the run and a child span per node.
+func (t *Timeline) WriteOTLP(w io.Writer) error {
+	spans := []otlpSpan{}
+	for _, run := range t.runs() {
+		runKey := run.id
+		if runKey == "" {
+			runKey = "@" + otlpTime(run.start)
+		}
+		traceID := otlpID(16, "trace", runKey)
+		rootID := otlpID(8, "span", runKey)
+
+		root := otlpSpan{
+			TraceID:           traceID,
+			SpanID:            rootID,
+			Name:              "pocket.run",
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: otlpTime(run.start),
+			EndTimeUnixNano:   otlpTime(run.end),
+		}
+		if run.id != "" {
+			root.Attributes = []otlpAttribute{otlpString("pocket.run_id", run.id)}
+		}
+		spans = append(spans, root)
+
+		for j, span := range run.spans {
+			s := otlpSpan{
+				TraceID:           traceID,
+				SpanID:            otlpID(8, "span", runKey, strconv.Itoa(j)),
+				ParentSpanID:      rootID,
+				Name:              span.Node,
+				Kind:              otlpKindInternal,
+				StartTimeUnixNano: otlpTime(span.Start),
+				EndTimeUnixNano:   otlpTime(span.End),
+				Attributes:        []otlpAttribute{otlpString("pocket.node", span.Node)},
+			}
+			if span.Retries > 0 {
+				s.Attributes = append(s.Attributes, otlpInt("pocket.retries", span.Retries))
+			}
+			if span.Err != "" {
+				s.Status = &otlpStatus{Code: otlpStatusError, Message: span.Err}
+			}
+			spans = append(spans, s)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{otlpString("service.name", "pocket")}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/agentstation/pocket"},
+			Spans: spans,
+		}},
+	}}})
+}
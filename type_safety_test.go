@@ -2,7 +2,9 @@ package pocket_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -523,3 +525,74 @@ func TestAutoTypeWrapping(t *testing.T) {
 	})
 
 }
+
+// TestSchemaInference tests schemas inferred from node types.
+func TestSchemaInference(t *testing.T) {
+	type Address struct {
+		City string `yaml:"city"`
+	}
+	type Customer struct {
+		ID       int               `json:"id"`
+		Name     string            `json:"name,omitempty"`
+		Tags     []string          `json:"tags"`
+		Address  *Address          `json:"address"`
+		Labels   map[string]string `json:"labels,omitempty"`
+		Created  time.Time         `json:"created"`
+		Internal string            `json:"-"`
+		secret   string
+	}
+
+	schema := pocket.SchemaOf[Customer]()
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := `{"properties":{` +
+		`"address":{"properties":{"city":{"type":"string"}},"required":["city"],"type":"object"},` +
+		`"created":{"format":"date-time","type":"string"},` +
+		`"id":{"type":"integer"},` +
+		`"labels":{"additionalProperties":{"type":"string"},"type":"object"},` +
+		`"name":{"type":"string"},` +
+		`"tags":{"items":{"type":"string"},"type":"array"}},` +
+		`"required":["id","tags","created"],"type":"object"}`
+	if string(data) != want {
+		t.Errorf("SchemaOf() = %s, want %s", data, want)
+	}
+
+	t.Run("typed nodes", func(t *testing.T) {
+		node := pocket.NewNode[Customer, string]("greet", pocket.Steps{})
+		input, output := pocket.Schemas(node)
+		if !reflect.DeepEqual(input, schema) {
+			t.Errorf("Input schema = %v, want %v", input, schema)
+		}
+		if output["type"] != "string" {
+			t.Errorf("Output schema = %v, want a string schema", output)
+		}
+	})
+
+	t.Run("declared schemas win", func(t *testing.T) {
+		declared := map[string]any{"type": "object"}
+		node := pocket.NewNode[Customer, string]("greet", pocket.Steps{}, pocket.WithInputSchema(declared))
+		if input, _ := pocket.Schemas(node); !reflect.DeepEqual(input, declared) {
+			t.Errorf("Input schema = %v, want %v", input, declared)
+		}
+	})
+
+	t.Run("untyped nodes", func(t *testing.T) {
+		input, output := pocket.Schemas(pocket.NewNode[any, any]("any", pocket.Steps{}))
+		if input != nil || output != nil {
+			t.Errorf("Schemas() = %v, %v, want none", input, output)
+		}
+	})
+
+	t.Run("recursive types", func(t *testing.T) {
+		type Tree struct {
+			Children []Tree `json:"children"`
+		}
+		children := pocket.SchemaOf[Tree]()["properties"].(map[string]any)["children"].(map[string]any)
+		if items := children["items"].(map[string]any); items["type"] != "object" || items["properties"] != nil {
+			t.Errorf("Children items = %v, want an open object", items)
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package yaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// ConfigViolation is a node config that doesn't match its type's schema.
+type ConfigViolation struct {
+	Node    string
+	Field   string
+	Message string
+}
+
+func (v ConfigViolation) String() string {
+	if v.Field == "" {
+		return fmt.Sprintf("node %s: %s", v.Node, v.Message)
+	}
+	return fmt.Sprintf("node %s: %s: %s", v.Node, v.Field, v.Message)
+}
+
+// ConfigError reports every node config in a definition that doesn't match
+// its type's schema.
+type ConfigError struct {
+	Violations []ConfigViolation
+}
+
+func (e *ConfigError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.String()
+	}
+	return fmt.Sprintf("invalid node config: %s", strings.Join(messages, "; "))
+}
+
+// configSchema is a compiled config schema, or the error compiling it.
+type configSchema struct {
+	schema *gojsonschema.Schema
+	err    error
+}
+
+// RegisterConfigSchema registers the JSON schema that configs of a node
+// type must match. LoadDefinition checks every node's config against the
+// schema of its type before building any node, and reports all violations
+// together in a ConfigError.
+func (l *Loader) RegisterConfigSchema(nodeType string, schema map[string]interface{}) {
+	compiled := &configSchema{}
+	data, err := json.Marshal(schema)
+	if err == nil {
+		compiled.schema, err = gojsonschema.NewSchema(gojsonschema.NewBytesLoader(data))
+	}
+	if err != nil {
+		compiled.err = fmt.Errorf("invalid config schema for type %s: %w", nodeType, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.configSchemas == nil {
+		l.configSchemas = make(map[string]*configSchema)
+	}
+	l.configSchemas[nodeType] = compiled
+}
+
+// ValidateConfigs checks the config of each node in a definition against
+// the schema registered for its type. Nodes of types without a schema are
+// skipped.
+func (l *Loader) ValidateConfigs(def *GraphDefinition) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var violations []ConfigViolation
+	for _, node := range def.Nodes {
+		compiled, ok := l.configSchemas[node.Type]
+		if !ok {
+			continue
+		}
+		if compiled.err != nil {
+			violations = append(violations, ConfigViolation{Node: node.Name, Message: compiled.err.Error()})
+			continue
+		}
+
+		config := node.Config
+		if config == nil {
+			config = map[string]interface{}{}
+		}
+		data, err := json.Marshal(config)
+		if err != nil {
+			violations = append(violations, ConfigViolation{Node: node.Name, Message: err.Error()})
+			continue
+		}
+		result, err := compiled.schema.Validate(gojsonschema.NewBytesLoader(data))
+		if err != nil {
+			violations = append(violations, ConfigViolation{Node: node.Name, Message: err.Error()})
+			continue
+		}
+		for _, resultErr := range result.Errors() {
+			violation := ConfigViolation{Node: node.Name, Message: resultErr.Description()}
+			if field := resultErr.Field(); field != gojsonschema.STRING_CONTEXT_ROOT {
+				violation.Field = "config." + field
+			}
+			violations = append(violations, violation)
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ConfigError{Violations: violations}
+	}
+	return nil
+}
+
+// configRef matches a template that reads a workflow config value.
+var configRef = regexp.MustCompile(`{{-?\s*\.workflow\.config((?:\.[A-Za-z_][A-Za-z0-9_-]*)+)\s*-?}}`)
+
+// MergeConfig deep-merges overrides onto the workflow config, so maps
+// merge key by key and other values replace what's there.
+func (gd *GraphDefinition) MergeConfig(overrides map[string]interface{}) {
+	if gd.Config == nil {
+		gd.Config = make(map[string]interface{}, len(overrides))
+	}
+	mergeMaps(gd.Config, overrides)
+}
+
+// SetConfig sets the workflow config value at a dotted path, such as
+// api.timeout, creating maps along the way.
+func (gd *GraphDefinition) SetConfig(path string, value interface{}) {
+	if gd.Config == nil {
+		gd.Config = make(map[string]interface{})
+	}
+	m := gd.Config
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+// mergeMaps deep-merges src into dst.
+func mergeMaps(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcOK := value.(map[string]interface{})
+		dstMap, dstOK := dst[key].(map[string]interface{})
+		if srcOK && dstOK {
+			mergeMaps(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
+
+// withConfig returns a copy of the definition whose node configs and input
+// mappings have their workflow config references replaced by the values.
+// A reference that makes up a whole string is replaced by the value itself,
+// keeping its type.
+func (gd *GraphDefinition) withConfig() (*GraphDefinition, error) {
+	resolved := *gd
+	resolved.Nodes = make([]NodeDefinition, len(gd.Nodes))
+	for i, node := range gd.Nodes {
+		config, err := gd.resolveConfigRefs(node.Config)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", node.Name, err)
+		}
+		input, err := gd.resolveConfigRefs(node.Input)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", node.Name, err)
+		}
+		node.Config, _ = config.(map[string]interface{})
+		node.Input = input
+		resolved.Nodes[i] = node
+	}
+	return &resolved, nil
+}
+
+func (gd *GraphDefinition) resolveConfigRefs(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if match := configRef.FindStringSubmatch(v); match != nil && match[0] == v {
+			return gd.configValue(match[1])
+		}
+		var firstErr error
+		s := configRef.ReplaceAllStringFunc(v, func(ref string) string {
+			value, err := gd.configValue(configRef.FindStringSubmatch(ref)[1])
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return ref
+			}
+			return formatConfigValue(value)
+		})
+		return s, firstErr
+	case map[string]interface{}:
+		if v == nil {
+			return v, nil
+		}
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			resolved, err := gd.resolveConfigRefs(value)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = resolved
+		}
+		return m, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			resolved, err := gd.resolveConfigRefs(value)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = resolved
+		}
+		return list, nil
+	}
+	return v, nil
+}
+
+// configValue looks up a config value by a path like .api.url.
+func (gd *GraphDefinition) configValue(path string) (interface{}, error) {
+	path = strings.TrimPrefix(path, ".")
+	var value interface{} = gd.Config
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("workflow config %s is not defined", path)
+		}
+		if value, ok = m[key]; !ok {
+			return nil, fmt.Errorf("workflow config %s is not defined", path)
+		}
+	}
+	return value, nil
+}
+
+// formatConfigValue formats a config value embedded in a longer string.
+// Maps and lists are written as JSON.
+func formatConfigValue(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
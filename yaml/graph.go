@@ -0,0 +1,72 @@
+package yaml
+
+import (
+	"fmt"
+
+	"github.com/agentstation/pocket"
+)
+
+// NodeRegistry builds nodes from their definitions. The nodes package's
+// Registry implements it with the built-in node types.
+type NodeRegistry interface {
+	Build(def *NodeDefinition) (pocket.Node, error)
+}
+
+// LoadGraph loads a workflow file into a graph, the way `pocket run` does,
+// so applications can run operator-editable workflows without the CLI.
+// Nodes are built with registry, and the graph is created with the options.
+//
+// Example:
+//
+//	graph, err := yaml.LoadGraph("workflow.yaml",
+//		yaml.CodeNodes(nodes.NewBuiltinRegistry(false), summarize),
+//		pocket.NewStore())
+func LoadGraph(path string, registry NodeRegistry, store pocket.Store, opts ...pocket.GraphOption) (*pocket.Graph, error) {
+	loader := NewLoader().WithNodeFactory(registryFactory{registry})
+	return loader.LoadFile(path, store, opts...)
+}
+
+// registryFactory creates nodes with a NodeRegistry.
+type registryFactory struct {
+	registry NodeRegistry
+}
+
+// CreateNode implements NodeFactory.
+func (f registryFactory) CreateNode(def *NodeDefinition) (pocket.Node, error) {
+	return f.registry.Build(def)
+}
+
+// codeNodes resolves custom nodes to nodes defined in Go.
+type codeNodes struct {
+	registry NodeRegistry
+	nodes    map[string]pocket.Node
+}
+
+// CodeNodes returns a registry that resolves workflow nodes of type
+// pocket.CustomNodeType, such as those pocket.Marshal exports for nodes
+// without a spec, to the given nodes by name, and builds other nodes with
+// registry, which may be nil. Loading connects the nodes in place, so each
+// should be loaded into one graph.
+func CodeNodes(registry NodeRegistry, nodes ...pocket.Node) NodeRegistry {
+	byName := make(map[string]pocket.Node, len(nodes))
+	for _, n := range nodes {
+		byName[n.Name()] = n
+	}
+	return &codeNodes{registry: registry, nodes: byName}
+}
+
+// Build returns the node defined in Go for a custom node, or builds the
+// node with the registry.
+func (c *codeNodes) Build(def *NodeDefinition) (pocket.Node, error) {
+	if def.Type == pocket.CustomNodeType {
+		n, ok := c.nodes[def.Name]
+		if !ok {
+			return nil, fmt.Errorf("no node defined in code named %s", def.Name)
+		}
+		return n, nil
+	}
+	if c.registry == nil {
+		return nil, fmt.Errorf("unknown node type: %s", def.Type)
+	}
+	return c.registry.Build(def)
+}
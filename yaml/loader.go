@@ -5,6 +5,7 @@ package yaml
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/agentstation/pocket"
@@ -21,6 +22,7 @@ type NodeFactory interface {
 
 // defaultNodeFactory provides basic node creation.
 type defaultNodeFactory struct {
+	mu       sync.RWMutex
 	registry map[string]NodeBuilder
 }
 
@@ -31,6 +33,9 @@ type NodeBuilder func(def *NodeDefinition) (pocket.Node, error)
 type Loader struct {
 	parser  *Parser
 	factory NodeFactory
+
+	mu            sync.RWMutex
+	configSchemas map[string]*configSchema
 }
 
 // NewLoader creates a new YAML graph loader.
@@ -49,46 +54,84 @@ func (l *Loader) WithNodeFactory(factory NodeFactory) *Loader {
 	return l
 }
 
-// RegisterNodeType registers a builder for a node type.
+// RegisterNodeType registers a builder for a node type. It's safe to call
+// while graphs are being loaded, such as when plugins are reloaded.
 func (l *Loader) RegisterNodeType(nodeType string, builder NodeBuilder) {
 	if df, ok := l.factory.(*defaultNodeFactory); ok {
+		df.mu.Lock()
 		df.registry[nodeType] = builder
+		df.mu.Unlock()
 	}
 }
 
-// LoadFile loads a graph from a YAML file.
-func (l *Loader) LoadFile(filename string, store pocket.Store) (*pocket.Graph, error) {
+// LoadFile loads a graph from a YAML file, created with the options.
+func (l *Loader) LoadFile(filename string, store pocket.Store, opts ...pocket.GraphOption) (*pocket.Graph, error) {
 	def, err := l.parser.ParseFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("parse file: %w", err)
 	}
 
-	return l.LoadDefinition(def, store)
+	return l.LoadDefinition(def, store, opts...)
 }
 
-// LoadString loads a graph from a YAML string.
-func (l *Loader) LoadString(yamlStr string, store pocket.Store) (*pocket.Graph, error) {
+// LoadString loads a graph from a YAML string, created with the options.
+func (l *Loader) LoadString(yamlStr string, store pocket.Store, opts ...pocket.GraphOption) (*pocket.Graph, error) {
 	def, err := l.parser.ParseString(yamlStr)
 	if err != nil {
 		return nil, fmt.Errorf("parse string: %w", err)
 	}
 
-	return l.LoadDefinition(def, store)
+	return l.LoadDefinition(def, store, opts...)
 }
 
-// LoadDefinition creates a graph from a parsed definition.
-func (l *Loader) LoadDefinition(def *GraphDefinition, store pocket.Store) (*pocket.Graph, error) {
+// LoadDefinition creates a graph from a parsed definition, with the options.
+//
+// A node's input block maps the output of the node before it, and the
+// store, to the node's input. A string starting with $ is a JSONPath over
+// the upstream output; paths with wildcards or filters produce a list of
+// the matches. A string with {{ }} is a Go template with the upstream
+// output as dot and a store function that reads a key. Objects and lists
+// map each of their values, and other values are used as they are:
+//
+//	nodes:
+//	  - name: notify
+//	    type: http
+//	    input:
+//	      to: $.customer.email
+//	      skus: $.items[*].sku
+//	      subject: "Order {{.id}} for {{store \"tenant\"}}"
+//	      priority: high
+func (l *Loader) LoadDefinition(def *GraphDefinition, store pocket.Store, opts ...pocket.GraphOption) (*pocket.Graph, error) {
 	if err := def.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid graph definition: %w", err)
 	}
 
+	// Fill in workflow config values before anything reads node configs
+	def, err := def.withConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Check every config before building, so all problems are reported
+	if err := l.ValidateConfigs(def); err != nil {
+		return nil, err
+	}
+
 	// Create all nodes
 	nodes := make(map[string]pocket.Node)
 	for _, nodeDef := range def.Nodes {
+		nodeDef.Graph = def
 		node, err := l.factory.CreateNode(&nodeDef)
 		if err != nil {
 			return nil, fmt.Errorf("create node %s: %w", nodeDef.Name, err)
 		}
+		if nodeDef.Input != nil {
+			mapInput, err := compileInputMapping(nodeDef.Name, nodeDef.Input)
+			if err != nil {
+				return nil, fmt.Errorf("node %s: %w", nodeDef.Name, err)
+			}
+			node = pocket.MapInput(node, mapInput)
+		}
 		nodes[nodeDef.Name] = node
 	}
 
@@ -118,12 +161,14 @@ func (l *Loader) LoadDefinition(def *GraphDefinition, store pocket.Store) (*pock
 		}
 	}
 
-	return pocket.NewGraph(startNode, store), nil
+	return pocket.NewGraph(startNode, store, opts...), nil
 }
 
 // CreateNode implements NodeFactory for defaultNodeFactory.
 func (f *defaultNodeFactory) CreateNode(def *NodeDefinition) (pocket.Node, error) {
+	f.mu.RLock()
 	builder, exists := f.registry[def.Type]
+	f.mu.RUnlock()
 	if !exists {
 		// Fall back to generic node creation
 		return f.createGenericNode(def)
@@ -159,7 +204,7 @@ func (f *defaultNodeFactory) createGenericNode(def *NodeDefinition) (pocket.Node
 					select {
 					case <-ctx.Done():
 						return nil, ctx.Err()
-					case <-time.After(delay * time.Duration(attempt)):
+					case <-pocket.ClockFrom(ctx).After(delay * time.Duration(attempt)):
 						// Exponential backoff
 					}
 				}
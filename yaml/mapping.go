@@ -0,0 +1,150 @@
+package yaml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/ohler55/ojg/jp"
+
+	"github.com/agentstation/pocket"
+)
+
+// mapper computes one value of an input mapping.
+type mapper func(ctx context.Context, store pocket.StoreReader, input any) (any, error)
+
+// compileInputMapping compiles the input mapping of a node definition.
+//
+// A string starting with $ is a JSONPath over the upstream output; paths
+// with wildcards or filters produce a list of the matches. A string with
+// {{ }} is a Go template executed with the upstream output as dot, and a
+// store function that reads a key from the store. Objects and lists map
+// each of their values, and other values are used as they are.
+func compileInputMapping(name string, spec any) (pocket.MapInputFunc, error) {
+	m, err := compileMapper(name, "input", spec)
+	if err != nil {
+		return nil, err
+	}
+	return pocket.MapInputFunc(m), nil
+}
+
+func compileMapper(name, path string, spec any) (mapper, error) {
+	switch v := spec.(type) {
+	case string:
+		switch {
+		case strings.HasPrefix(v, "$"):
+			return compilePath(path, v)
+		case strings.Contains(v, "{{"):
+			return compileTemplate(name, path, v)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		fields := make(map[string]mapper, len(v))
+		for key, value := range v {
+			m, err := compileMapper(name, path+"."+key, value)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+			fields[key] = m
+		}
+		sort.Strings(keys)
+		return func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+			result := make(map[string]any, len(keys))
+			for _, key := range keys {
+				value, err := fields[key](ctx, store, input)
+				if err != nil {
+					return nil, err
+				}
+				result[key] = value
+			}
+			return result, nil
+		}, nil
+	case []any:
+		items := make([]mapper, len(v))
+		for i, value := range v {
+			m, err := compileMapper(name, fmt.Sprintf("%s[%d]", path, i), value)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = m
+		}
+		return func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+			result := make([]any, len(items))
+			for i, m := range items {
+				value, err := m(ctx, store, input)
+				if err != nil {
+					return nil, err
+				}
+				result[i] = value
+			}
+			return result, nil
+		}, nil
+	}
+
+	// Literals are used as they are
+	return func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+		return spec, nil
+	}, nil
+}
+
+// compilePath compiles a JSONPath mapping.
+func compilePath(path, expr string) (mapper, error) {
+	x, err := jp.ParseString(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid JSONPath %q: %w", path, expr, err)
+	}
+
+	// Paths that can match several values always produce a list
+	multiple := strings.ContainsAny(expr, "*?:,") || strings.Contains(expr, "..")
+
+	return func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+		results := x.Get(input)
+		if multiple {
+			if results == nil {
+				results = []any{}
+			}
+			return results, nil
+		}
+		if len(results) == 0 {
+			return nil, nil
+		}
+		return results[0], nil
+	}, nil
+}
+
+// compileTemplate compiles a template mapping.
+func compileTemplate(name, path, text string) (mapper, error) {
+	// The store function is bound to each run's store when executed
+	tmpl, err := template.New(name + "." + path).Funcs(template.FuncMap{
+		"store": func(string) (any, error) { return nil, nil },
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid template: %w", path, err)
+	}
+
+	return func(ctx context.Context, store pocket.StoreReader, input any) (any, error) {
+		run, err := tmpl.Clone()
+		if err != nil {
+			return nil, err
+		}
+		run.Funcs(template.FuncMap{
+			"store": func(key string) (any, error) {
+				value, ok := store.Get(ctx, key)
+				if !ok {
+					return nil, fmt.Errorf("store key %q not found", key)
+				}
+				return value, nil
+			},
+		})
+
+		var buf bytes.Buffer
+		if err := run.Execute(&buf, input); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	}, nil
+}
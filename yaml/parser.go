@@ -5,12 +5,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	yaml "github.com/goccy/go-yaml"
 )
 
 // Parser handles parsing YAML graph definitions.
-type Parser struct {
-	// Future: Add schema validation, custom marshalers, etc.
-}
+type Parser struct{}
 
 // NewParser creates a new YAML parser.
 func NewParser() *Parser {
@@ -19,10 +19,16 @@ func NewParser() *Parser {
 
 // Parse reads and parses a YAML graph definition from a reader.
 func (p *Parser) Parse(r io.Reader) (*GraphDefinition, error) {
-	// Note: We're not importing yaml.v3 to keep the package dependency-free.
-	// This is a placeholder that would need a YAML library in practice.
-	// For now, we'll return an error indicating YAML support needs to be enabled.
-	return nil, fmt.Errorf("YAML parsing requires importing a YAML library (e.g., github.com/goccy/go-yaml)")
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	var def GraphDefinition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("parse YAML: %w", err)
+	}
+	return &def, nil
 }
 
 // ParseFile reads and parses a YAML graph definition from a file.
@@ -45,8 +51,7 @@ func (p *Parser) ParseString(s string) (*GraphDefinition, error) {
 
 // Marshal converts a graph definition to YAML format.
 func (p *Parser) Marshal(gd *GraphDefinition) ([]byte, error) {
-	// Placeholder - would use YAML library
-	return nil, fmt.Errorf("YAML marshaling requires importing a YAML library")
+	return yaml.Marshal(gd)
 }
 
 // MarshalToFile writes a graph definition to a YAML file.
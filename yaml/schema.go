@@ -2,16 +2,31 @@
 package yaml
 
 import (
+	_ "embed"
 	"fmt"
 	"time"
 )
 
-// GraphDefinition represents a complete graph defined in YAML.
+//go:embed workflow.schema.json
+var workflowSchema []byte
+
+// WorkflowSchema returns the JSON Schema for workflow YAML files. Editors
+// and CI can use it to check workflows before they run; `pocket validate`
+// checks files against it.
+func WorkflowSchema() []byte {
+	return append([]byte(nil), workflowSchema...)
+}
+
+// GraphDefinition represents a complete graph defined in YAML. Config
+// holds workflow-wide values that node configs and input mappings read
+// with {{.workflow.config.key}}; see MergeConfig and SetConfig to override
+// them.
 type GraphDefinition struct {
 	Name        string                 `yaml:"name"`
 	Description string                 `yaml:"description,omitempty"`
 	Version     string                 `yaml:"version,omitempty"`
 	Metadata    map[string]interface{} `yaml:"metadata,omitempty"`
+	Config      map[string]interface{} `yaml:"config,omitempty"`
 	Nodes       []NodeDefinition       `yaml:"nodes"`
 	Connections []Connection           `yaml:"connections,omitempty"`
 	Start       string                 `yaml:"start"`
@@ -27,6 +42,24 @@ type NodeDefinition struct {
 	Timeout     string                 `yaml:"timeout,omitempty"`
 	InputType   string                 `yaml:"input_type,omitempty"`
 	OutputType  string                 `yaml:"output_type,omitempty"`
+
+	// Input maps the output of the previous node, and the store, to the
+	// input of this node. See Loader.LoadDefinition for the expressions.
+	Input interface{} `yaml:"input,omitempty"`
+
+	// Graph is the definition the node belongs to. The loader sets it so
+	// builders can look up other nodes by name.
+	Graph *GraphDefinition `yaml:"-" json:"-"`
+}
+
+// Node returns the definition of the node with the given name.
+func (gd *GraphDefinition) Node(name string) (*NodeDefinition, bool) {
+	for i := range gd.Nodes {
+		if gd.Nodes[i].Name == name {
+			return &gd.Nodes[i], true
+		}
+	}
+	return nil, false
 }
 
 // Connection represents a connection between nodes.